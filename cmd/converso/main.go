@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
 
 	"github.com/converso-empire/cli/internal/commands"
 	"github.com/converso-empire/cli/pkg/config"
 	"github.com/converso-empire/cli/pkg/telemetry"
-	"github.com/spf13/cobra"
+	"github.com/converso-empire/cli/pkg/terminal"
 )
 
 var (
@@ -19,8 +22,9 @@ var (
 )
 
 func main() {
-	// Initialize configuration
-	cfg, err := config.Load()
+	// Start from the built-in defaults; the root command loads the full
+	// configuration only for commands that need it
+	cfg, err := config.Defaults()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
 		os.Exit(1)
@@ -29,13 +33,40 @@ func main() {
 	// Initialize telemetry
 	logger := telemetry.NewLogger(cfg.Debug)
 
+	// Pick the output theme now so help text is covered too; commands that
+	// load the config apply its setting
+	if output, err := terminal.FromConfig(cfg.Output); err == nil {
+		terminal.Configure(output)
+	}
+
 	// Create root command
 	rootCmd := commands.NewRootCmd(version, commit, date, cfg, logger)
 
-	// Execute command
-	if err := rootCmd.Execute(); err != nil {
+	// Ctrl-C and SIGTERM cancel the command's context, which asks running
+	// modules to stop and clean up; a second signal exits right away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// Execute command, then write the error and any --profile and
+	// --explain output
+	err = rootCmd.ExecuteContext(ctx)
+	if err != nil && ctx.Err() != nil {
+		err = &commands.ExitError{Code: commands.ExitCodeInterrupted, Err: err}
+	}
+	if err != nil {
+		commands.PrintError(os.Stderr, cfg, err)
+	}
+	commands.FinishProfile(logger)
+	commands.FinishExplain(err)
+	commands.FinishRun(logger, err)
+	terminal.Close()
+	if err != nil {
 		logger.Error("Command failed", "error", err)
-		os.Exit(1)
+		os.Exit(commands.ExitCode(err))
 	}
 }
 
@@ -4,6 +4,7 @@ go 1.21
 
 require (
 	github.com/spf13/cobra v1.8.0
+	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.18.2
 	github.com/golang/oauth2 v0.0.0-20220308205901-0712a531a117
 	github.com/keybase/go-keychain v0.0.0-20190712172121-9d36a160192d
@@ -14,6 +15,7 @@ require (
 	github.com/inconshreveable/mousetrap v1.1.0
 	github.com/hashicorp/go-retryablehttp v0.7.5
 	github.com/google/uuid v1.6.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -37,5 +39,4 @@ require (
 	golang.org/x/sys v0.21.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/sbom"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/spf13/cobra"
+)
+
+// NewAboutCmd creates the about command
+func NewAboutCmd(version string, cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	aboutCmd := &cobra.Command{
+		Use:   "about",
+		Short: "Show information about this build",
+	}
+
+	// Licenses command
+	licensesCmd := &cobra.Command{
+		Use:   "licenses",
+		Short: "List the licenses of the CLI, its dependencies and plugins",
+		Long: `List the software in this binary (the CLI, the Go standard library and the Go
+modules compiled in) and the installed plugins, with their licenses.
+Dependencies come from the build information embedded in the binary and
+plugin details from their manifests.
+
+With --spdx, print an SPDX 2.3 JSON document (SBOM) instead, for compliance
+audits.`,
+		Example: `  converso about licenses
+  converso about licenses --spdx > converso.spdx.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAboutLicenses(cmd, version, cfg, logger)
+		},
+	}
+	licensesCmd.Flags().Bool("spdx", false, "Print an SPDX 2.3 JSON SBOM")
+	aboutCmd.AddCommand(licensesCmd)
+
+	return aboutCmd
+}
+
+// runAboutLicenses prints the license report or SPDX document
+func runAboutLicenses(cmd *cobra.Command, version string, cfg *config.Config, logger telemetry.Logger) error {
+	spdx, _ := cmd.Flags().GetBool("spdx")
+
+	// Without a usable plugins directory the report covers the binary only
+	var manifests []*bridge.ModuleManifest
+	if registry, err := newPluginRegistry(cfg, logger); err != nil {
+		logger.Warn("Plugins not included in license report", "error", err)
+	} else {
+		for _, module := range registry.ListModules() {
+			manifests = append(manifests, module.Manifest)
+		}
+	}
+
+	components := sbom.Components(version, manifests)
+
+	if spdx {
+		data, err := json.MarshalIndent(sbom.SPDX(components, "converso-"+version), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal SBOM: %w", err)
+		}
+		_, err = os.Stdout.Write(append(data, '\n'))
+		return err
+	}
+
+	list := &listOutput{
+		Columns:  []string{"name", "version", "license", "kind", "purl"},
+		Defaults: []string{"name", "version", "license", "kind"},
+	}
+	for _, c := range components {
+		list.Add(c, c.Name, c.Version, c.License, c.Kind, c.PURL)
+	}
+	return printList(cmd, list)
+}
@@ -1,12 +1,19 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
 	"time"
 
 	"github.com/converso-empire/cli/pkg/auth"
 	"github.com/converso-empire/cli/pkg/config"
 	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
 	"github.com/spf13/cobra"
 )
 
@@ -21,17 +28,21 @@ This command will:
   • Generate a device code and user code
   • Display instructions for completing authentication
   • Open your default browser automatically
-  • Poll for authentication completion
+  • Show a QR code to scan with your phone on machines without a display
+  • Poll for authentication completion until approved, expired or Ctrl-C
   • Store authentication tokens securely`,
 		
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runLogin(cmd, cfg, logger)
 		},
+		Annotations: changesSetupAnnotations(""),
 	}
 
 	// Add flags
 	loginCmd.Flags().String("device-name", "", "Custom device name (default: hostname)")
 	loginCmd.Flags().Bool("force", false, "Force re-authentication even if already logged in")
+	loginCmd.Flags().Bool("no-browser", false, "Do not open the verification page in a browser")
+	loginCmd.Flags().Bool("qr", false, "Show the verification URL as a QR code (default on machines without a display)")
 
 	return loginCmd
 }
@@ -51,6 +62,7 @@ This command will:
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runLogout(cmd, cfg, logger)
 		},
+		Annotations: changesSetupAnnotations(""),
 	}
 
 	// Add flags
@@ -61,8 +73,12 @@ This command will:
 
 // runLogin executes the login process
 func runLogin(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) error {
+	if cfg.Headless {
+		return fmt.Errorf("login is not available in headless mode. Set CONVERSO_ACCESS_TOKEN or CONVERSO_ACCESS_TOKEN_FILE instead")
+	}
+
 	// Check if already authenticated
-	authManager := auth.NewAuthManager(auth.NewFileStorage(cfg, logger), logger)
+	authManager := auth.NewAuthManager(auth.NewStorage(cfg, logger), logger)
 	if authManager.IsAuthenticated(cfg) {
 		force, _ := cmd.Flags().GetBool("force")
 		if !force {
@@ -78,6 +94,19 @@ func runLogin(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) e
 	// Create OAuth2 client
 	oauthClient := auth.NewOAuth2Client(cfg, logger)
 
+	// Prompt with a browser where there is a display, otherwise a QR code
+	noBrowser, _ := cmd.Flags().GetBool("no-browser")
+	showQR, _ := cmd.Flags().GetBool("qr")
+	display := hasDisplay()
+	if !cmd.Flags().Changed("qr") {
+		showQR = !display
+	}
+	oauthClient.SetDeviceFlowOptions(auth.DeviceFlowOptions{
+		OpenBrowser: !noBrowser && display,
+		ShowQRCode:  showQR,
+		Progress:    terminal.IsTerminal(os.Stdout) && !terminal.Plain(),
+	})
+
 	// Get device name
 	deviceName, _ := cmd.Flags().GetString("device-name")
 	if deviceName == "" {
@@ -87,14 +116,23 @@ func runLogin(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) e
 	fmt.Printf("Device: %s\n", deviceName)
 	fmt.Println()
 
-	// Perform device authentication flow
-	tokens, err := oauthClient.DeviceAuthFlow()
+	// Perform device authentication flow; Ctrl-C cancels it
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	tokens, err := oauthClient.DeviceAuthFlow(ctx)
+	if errors.Is(err, context.Canceled) {
+		fmt.Println()
+		fmt.Println("🚫 Login cancelled")
+		cmd.SilenceUsage = true
+		return &ExitError{Code: ExitCodeInterrupted, Err: errors.New("login cancelled")}
+	}
 	if err != nil {
 		return fmt.Errorf("authentication failed: %w", err)
 	}
 
 	// Store tokens securely
-	storage := auth.NewFileStorage(cfg, logger)
+	storage := auth.NewStorage(cfg, logger)
 	if err := storage.StoreTokens(tokens); err != nil {
 		return fmt.Errorf("failed to store tokens: %w", err)
 	}
@@ -107,7 +145,7 @@ func runLogin(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) e
 	fmt.Println()
 	fmt.Printf("Device: %s\n", deviceName)
 	fmt.Printf("Device ID: %s\n", tokens.DeviceID)
-	fmt.Printf("Expires: %s\n", tokens.ExpiresAt.Format("2006-01-02 15:04:05"))
+	fmt.Printf("Expires: %s\n", terminal.FormatTimestamp(tokens.ExpiresAt))
 	fmt.Println()
 	fmt.Println("You can now use Converso CLI commands.")
 	fmt.Println("Try: 'converso youtube list-formats <url>'")
@@ -115,10 +153,27 @@ func runLogin(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) e
 	return nil
 }
 
+// hasDisplay reports whether a browser can be shown to the user. SSH
+// sessions and Linux machines without X11 or Wayland are treated as
+// headless.
+func hasDisplay() bool {
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" {
+		return false
+	}
+	if runtime.GOOS == "linux" {
+		return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+	}
+	return true
+}
+
 // runLogout executes the logout process
 func runLogout(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) error {
+	if cfg.Headless {
+		return fmt.Errorf("logout is not available in headless mode. Remove the credentials from the environment instead")
+	}
+
 	// Check if authenticated
-	authManager := auth.NewAuthManager(auth.NewFileStorage(cfg, logger), logger)
+	authManager := auth.NewAuthManager(auth.NewStorage(cfg, logger), logger)
 	if !authManager.IsAuthenticated(cfg) {
 		fmt.Println("ℹ️  You are not currently logged in.")
 		return nil
@@ -170,7 +225,7 @@ This command displays:
 
 // runStatus shows authentication status
 func runStatus(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) error {
-	authManager := auth.NewAuthManager(auth.NewFileStorage(cfg, logger), logger)
+	authManager := auth.NewAuthManager(auth.NewStorage(cfg, logger), logger)
 	status, err := authManager.GetAuthStatus(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to get authentication status: %w", err)
@@ -186,10 +241,19 @@ func runStatus(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger)
 		if status.Email != "" {
 			fmt.Printf("Email: %s\n", status.Email)
 		}
-		fmt.Printf("Expires: %s\n", status.ExpiresAt.Format("2006-01-02 15:04:05"))
+		if status.Scope != "" {
+			fmt.Printf("Scopes: %s\n", status.Scope)
+		}
+		fmt.Printf("Expires: %s\n", terminal.FormatTimestamp(status.ExpiresAt))
 		
-		// Show time until expiration
-		timeUntil := status.ExpiresAt.Sub(time.Now())
+		if status.ClockSkew > 0 {
+			fmt.Printf("Clock skew: server is %s ahead of this machine\n", status.ClockSkew.Round(time.Second))
+		} else if status.ClockSkew < 0 {
+			fmt.Printf("Clock skew: server is %s behind this machine\n", (-status.ClockSkew).Round(time.Second))
+		}
+
+		// Show time until expiration, in server time
+		timeUntil := status.ExpiresAt.Sub(time.Now().Add(status.ClockSkew))
 		if timeUntil > 0 {
 			fmt.Printf("Time remaining: %s\n", formatDuration(timeUntil))
 		} else {
@@ -206,10 +270,10 @@ func runStatus(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger)
 // Helper function to format duration
 func formatDuration(d time.Duration) string {
 	if d < time.Minute {
-		return d.Round(time.Second).String()
+		return terminal.FormatDuration(d.Round(time.Second))
 	}
 	if d < time.Hour {
-		return d.Round(time.Minute).String()
+		return terminal.FormatDuration(d.Round(time.Minute))
 	}
-	return d.Round(time.Hour).String()
+	return terminal.FormatDuration(d.Round(time.Hour))
 }
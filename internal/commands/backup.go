@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/converso-empire/cli/pkg/backup"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+// NewBackupCmd creates the backup command
+func NewBackupCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up and restore the CLI's state",
+		Long: `Archive the config file, download history, download archive, subscriptions,
+plugin statistics and run index together with a lockfile of the installed
+plugins, to move to a new machine or recover after reinstalling the OS.
+
+Credentials (tokens, device registration, module secrets and the worker
+control token) are only included with --include-tokens, encrypted with a
+passphrase read from CONVERSO_BACKUP_PASSPHRASE or prompted for. Tokens
+and the cookie key kept in the OS keyring (credential_store: keyring) are
+left out.`,
+	}
+
+	createCmd := &cobra.Command{
+		Use:   "create <file>",
+		Short: "Write a backup of the CLI's state",
+		Example: `  converso backup create converso-backup.tar.gz
+  converso backup create converso-backup.tar.gz --include-tokens`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBackupCreate(cmd, cfg, logger, args[0])
+		},
+	}
+	createCmd.Flags().Bool("include-tokens", false, "Include credentials, encrypted with a passphrase")
+	backupCmd.AddCommand(createCmd)
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore <file>",
+		Short: "Replace the CLI's state with a backup",
+		Example: `  converso backup restore converso-backup.tar.gz
+  CONVERSO_BACKUP_PASSPHRASE_FILE=/run/secrets/backup converso backup restore converso-backup.tar.gz --force`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBackupRestore(cmd, cfg, logger, args[0])
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+	restoreCmd.Flags().Bool("force", false, "Replace the current state without confirmation")
+	restoreCmd.Flags().Bool("skip-tokens", false, "Leave the current credentials in place")
+	backupCmd.AddCommand(restoreCmd)
+
+	return backupCmd
+}
+
+// runBackupCreate writes a backup
+func runBackupCreate(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger, dest string) error {
+	includeTokens, _ := cmd.Flags().GetBool("include-tokens")
+
+	var passphrase string
+	if includeTokens {
+		var err error
+		if passphrase, err = backupPassphrase(cfg, true); err != nil {
+			return err
+		}
+	}
+
+	// Record the installed plugins in the lockfile
+	plugins := []backup.Plugin{}
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		logger.Warn("Plugin lockfile left empty", "error", err)
+	} else {
+		for _, module := range registry.ListModules() {
+			plugins = append(plugins, backup.Plugin{
+				Name:      module.Manifest.Name,
+				Version:   module.Manifest.Version,
+				Signature: module.Signature,
+			})
+		}
+	}
+
+	manifest, err := backup.Create(cfg, dest, backup.Options{Plugins: plugins, Passphrase: passphrase})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Backup written to %s\n", dest)
+	fmt.Printf("📁 Files: %d\n", len(manifest.Files))
+	fmt.Printf("🧩 Plugins: %d\n", len(manifest.Plugins))
+	if manifest.Credentials {
+		fmt.Println("🔐 Credentials included (encrypted)")
+	} else {
+		fmt.Println("💡 Credentials not included; log in again after restoring, or use --include-tokens")
+	}
+	return nil
+}
+
+// runBackupRestore restores a backup
+func runBackupRestore(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger, src string) error {
+	force, _ := cmd.Flags().GetBool("force")
+	skipTokens, _ := cmd.Flags().GetBool("skip-tokens")
+
+	manifest, err := backup.Inspect(src)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("📦 Backup of %s from %s\n", valueOrDash(manifest.Host), terminal.FormatDateTime(manifest.CreatedAt.Local()))
+	for _, name := range manifest.Files {
+		fmt.Printf("   %s\n", name)
+	}
+
+	var passphrase string
+	if manifest.Credentials && !skipTokens {
+		if passphrase, err = backupPassphrase(cfg, false); err != nil {
+			return err
+		}
+	}
+
+	if !force {
+		fmt.Print("This replaces your current config and state. Continue? [y/N]: ")
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" && response != "yes" {
+			fmt.Println("Restore cancelled.")
+			return nil
+		}
+	}
+
+	restored, err := backup.Restore(cfg, src, passphrase)
+	if err != nil {
+		return err
+	}
+	logger.Info("Backup restored", "backup", src, "files", restored)
+	fmt.Printf("✅ Restored %d files\n", len(restored))
+	if !manifest.Credentials || skipTokens {
+		fmt.Println("💡 Run 'converso login' if this machine is not logged in yet")
+	}
+
+	// Point out plugins of the lockfile that are missing here
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return nil
+	}
+	var missing []backup.Plugin
+	for _, locked := range manifest.Plugins {
+		module, err := registry.GetModuleInfo(locked.Name)
+		if err != nil || module.Manifest.Version != locked.Version {
+			missing = append(missing, locked)
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Println("🧩 Install these plugins to match the backup:")
+		for _, locked := range missing {
+			fmt.Printf("   %s %s\n", locked.Name, locked.Version)
+		}
+	}
+	return nil
+}
+
+// backupPassphrase returns CONVERSO_BACKUP_PASSPHRASE or prompts for the
+// passphrase, twice when it is new
+func backupPassphrase(cfg *config.Config, confirm bool) (string, error) {
+	passphrase, err := config.Secret("backup_passphrase")
+	if err != nil || passphrase != "" {
+		return passphrase, err
+	}
+	if cfg.Headless || !terminal.IsTerminal(os.Stdin) {
+		return "", fmt.Errorf("set CONVERSO_BACKUP_PASSPHRASE or CONVERSO_BACKUP_PASSPHRASE_FILE for the credentials")
+	}
+
+	if passphrase, err = readSecretValue("Backup passphrase: "); err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("the passphrase cannot be empty")
+	}
+	if confirm {
+		again, err := readSecretValue("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
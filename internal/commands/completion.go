@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"context"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/spf13/cobra"
+)
+
+// completionFunc is the signature cobra uses for dynamic completions
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// moduleArgsCompletion completes positional arguments from a module's
+// complete command. argNames names the arguments by position, as declared
+// in the module manifest's completions.
+func moduleArgsCompletion(cfg *config.Config, logger telemetry.Logger, module, command string, argNames ...string) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= len(argNames) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeFromModule(cmd.Context(), cfg, logger, module, command, argNames[len(args)], args, toComplete)
+	}
+}
+
+// registerModuleFlagCompletions completes flag values from a module's
+// complete command. Flags are named as in the module manifest's completions.
+func registerModuleFlagCompletions(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger, module, command string, flags ...string) {
+	for _, flag := range flags {
+		flag := flag
+		cmd.RegisterFlagCompletionFunc(flag, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completeFromModule(cmd.Context(), cfg, logger, module, command, flag, args, toComplete)
+		})
+	}
+}
+
+// completeFromModule runs a module's complete command. Completion must never
+// get in the way of typing, so failures only produce no suggestions.
+func completeFromModule(ctx context.Context, cfg *config.Config, logger telemetry.Logger, module, command, arg string, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// Load authentication
+	tokens, err := loadAuthTokens(ctx, cfg, logger)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), false)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), false)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	// Completions must never wait for input
+	registry.SetPrompter(nil)
+
+	completions, err := registry.Complete(ctx, module, command, arg, args, toComplete, tokens)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), false)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	values := make([]string, 0, len(completions))
+	for _, c := range completions {
+		if c.Description != "" {
+			values = append(values, c.Value+"\t"+c.Description)
+		} else {
+			values = append(values, c.Value)
+		}
+	}
+	return values, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePresets completes conversion preset names from the configuration
+func completePresets(cfg *config.Config) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var values []string
+		for _, name := range cfg.PresetNames() {
+			if !strings.HasPrefix(name, toComplete) {
+				continue
+			}
+			preset := cfg.Presets[name]
+			var options []string
+			for _, option := range []string{preset.Codec, preset.Bitrate, preset.Resolution, preset.Container} {
+				if option != "" {
+					options = append(options, option)
+				}
+			}
+			values = append(values, name+"\t"+strings.Join(options, " "))
+		}
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -0,0 +1,189 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/terminal"
+)
+
+// keyHint lists the keys controlling a foreground command
+const keyHint = "💡 Keys: p pause/resume, c cancel, 1-9 limit to N MB/s, 0 remove the limit"
+
+// activeKeys is the key watcher of the foreground command, which module
+// prompts hold while they read an answer
+var (
+	activeKeys   *keyWatcher
+	activeKeysMu sync.Mutex
+)
+
+// keyWatcher turns keys pressed during a foreground command into control
+// messages for the module running it
+type keyWatcher struct {
+	controls chan *bridge.Control
+	saved    string
+	hold     chan bool
+	ack      chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	paused   bool
+}
+
+// watchKeys lets the user pause, resume, cancel and rate limit a foreground
+// command from the keyboard until the returned function is called. The
+// controls are nil when nobody can press keys, such as in headless mode, on
+// Windows or when output is read by a script.
+func watchKeys(cfg *config.Config) (<-chan *bridge.Control, func()) {
+	if !canPrompt(cfg) || terminal.Plain() || !terminal.IsTerminal(os.Stdout) || runtime.GOOS == "windows" {
+		return nil, func() {}
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, func() {}
+	}
+
+	k := &keyWatcher{
+		controls: make(chan *bridge.Control, 16),
+		saved:    strings.TrimSpace(saved),
+		hold:     make(chan bool),
+		ack:      make(chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if err := k.cbreak(); err != nil {
+		return nil, func() {}
+	}
+	fmt.Println(keyHint)
+
+	activeKeysMu.Lock()
+	activeKeys = k
+	activeKeysMu.Unlock()
+	go k.run()
+
+	return k.controls, func() {
+		activeKeysMu.Lock()
+		activeKeys = nil
+		activeKeysMu.Unlock()
+		close(k.stop)
+		<-k.done
+	}
+}
+
+// holdKeys puts the terminal back in line mode for a module prompt until
+// the returned function is called
+func holdKeys() (release func()) {
+	activeKeysMu.Lock()
+	k := activeKeys
+	activeKeysMu.Unlock()
+	if k == nil {
+		return func() {}
+	}
+
+	k.setHeld(true)
+	return func() { k.setHeld(false) }
+}
+
+// run reads keys until stopped, restoring the terminal when done
+func (k *keyWatcher) run() {
+	defer close(k.done)
+	defer k.restore()
+
+	// Restore the terminal if interrupted; the command itself is cancelled
+	// through its context, so keys are no longer read meanwhile
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	defer signal.Stop(interrupted)
+
+	input := terminalInput()
+	held := false
+	for {
+		keys := input
+		if held {
+			keys = nil
+		}
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				input = nil
+				continue
+			}
+			k.press(key)
+		case held = <-k.hold:
+			if held {
+				k.restore()
+			} else {
+				k.cbreak()
+			}
+			k.ack <- struct{}{}
+		case <-interrupted:
+			k.restore()
+			fmt.Println()
+			signal.Stop(interrupted)
+			interrupted, input = nil, nil
+		case <-k.stop:
+			return
+		}
+	}
+}
+
+// press sends the control bound to key, if any
+func (k *keyWatcher) press(key byte) {
+	var control *bridge.Control
+	switch {
+	case key == 'p' || key == 'P':
+		action := bridge.ControlPause
+		if k.paused {
+			action = bridge.ControlResume
+		}
+		k.paused = !k.paused
+		control = &bridge.Control{Action: action}
+	case key == 'c' || key == 'C':
+		control = &bridge.Control{Action: bridge.ControlCancel}
+	case key >= '0' && key <= '9':
+		control = &bridge.Control{Action: bridge.ControlSetRateLimit, RateLimit: int64(key-'0') << 20}
+	default:
+		return
+	}
+
+	select {
+	case k.controls <- control:
+	default:
+		// The module is not keeping up; drop the key
+	}
+}
+
+// setHeld asks the watcher to stop or resume reading keys, unless it
+// already stopped
+func (k *keyWatcher) setHeld(held bool) {
+	select {
+	case k.hold <- held:
+		<-k.ack
+	case <-k.done:
+	}
+}
+
+// cbreak passes keys to the CLI as they are pressed, without echo
+func (k *keyWatcher) cbreak() error {
+	_, err := stty("-icanon", "-echo")
+	return err
+}
+
+// restore puts the terminal back the way it was
+func (k *keyWatcher) restore() {
+	stty(k.saved)
+}
+
+// stty runs stty on the terminal on stdin and returns its output
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,330 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/hwaccel"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/spf13/cobra"
+)
+
+// NewConvertCmd creates the convert command
+func NewConvertCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	convertCmd := &cobra.Command{
+		Use:   "convert <file>",
+		Short: "Convert media files",
+		Long: `Convert audio and video files with FFmpeg using a named preset or
+explicit codec, bitrate, resolution and container options. Explicit options
+take precedence over the preset.
+
+Examples:
+  converso convert video.mkv --preset web-720p
+  converso convert talk.wav --codec mp3 --bitrate 128k --container mp3
+  converso convert video.mkv --preset web-720p --estimate
+  converso convert video.mkv --codec h265 --no-hwaccel
+  converso convert preset list`,
+
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConvert(cmd, args, cfg, logger)
+		},
+	}
+
+	// Add flags
+	convertCmd.Flags().String("preset", "", "Named conversion preset (see 'converso convert preset list')")
+	addConversionFlags(convertCmd)
+	convertCmd.Flags().String("output-dir", "", "Output directory (default: next to the input file)")
+	addEstimateFlag(convertCmd)
+	addHWAccelFlag(convertCmd)
+	convertCmd.RegisterFlagCompletionFunc("preset", completePresets(cfg))
+	registerModuleFlagCompletions(convertCmd, cfg, logger, "convert", "convert", "codec", "container", "resolution")
+
+	convertCmd.AddCommand(newPresetCmd(cfg))
+
+	return convertCmd
+}
+
+// newPresetCmd creates the preset management command
+func newPresetCmd(cfg *config.Config) *cobra.Command {
+	presetCmd := &cobra.Command{
+		Use:   "preset",
+		Short: "Manage conversion presets",
+		Long:  "Add, list and remove named conversion presets stored in the configuration file",
+	}
+
+	// Add command
+	addCmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: "Add or replace a conversion preset",
+		Long: `Add a named conversion preset to the configuration file.
+
+Example:
+  converso convert preset add web-1080p --codec h264 --bitrate 5000k --resolution 1920x1080 --container mp4`,
+
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPresetAdd(cmd, args, cfg)
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+	addConversionFlags(addCmd)
+	addCmd.Flags().Bool("force", false, "Replace an existing preset with the same name")
+	presetCmd.AddCommand(addCmd)
+
+	// List command
+	presetCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List conversion presets",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPresetList(cmd, cfg)
+		},
+	})
+
+	// Remove command
+	presetCmd.AddCommand(&cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove a conversion preset",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPresetRemove(args[0], cfg)
+		},
+		Annotations: changesSetupAnnotations(""),
+	})
+
+	return presetCmd
+}
+
+// addConversionFlags adds the conversion option flags shared by convert and preset add
+func addConversionFlags(cmd *cobra.Command) {
+	cmd.Flags().String("codec", "", "Codec (e.g. h264, vp9, aac, mp3)")
+	cmd.Flags().String("bitrate", "", "Target bitrate (e.g. 2500k)")
+	cmd.Flags().String("resolution", "", "Output resolution (e.g. 1280x720 or 720p)")
+	cmd.Flags().String("container", "", "Output container (e.g. mp4, mkv, mp3)")
+}
+
+// addHWAccelFlag adds --no-hwaccel to a command that converts media
+func addHWAccelFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-hwaccel", false, "Use software encoders even where hardware encoders were detected")
+}
+
+// addHWEncoders adds the hardware encoders setup and doctor detected to
+// conversion arguments, unless hwaccel is off or --no-hwaccel is given.
+// Modules fall back to software encoders for codecs without one.
+func addHWEncoders(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger, args map[string]interface{}) {
+	if disabled, _ := cmd.Flags().GetBool("no-hwaccel"); disabled || !cfg.HWAccel {
+		return
+	}
+	caps, err := hwaccel.Load(hwaccel.DefaultPath(cfg))
+	if err != nil {
+		logger.Warn("Using software encoders", "error", err)
+		return
+	}
+	if encoders := caps.Args(); encoders != nil {
+		args[hwaccel.ArgEncoders] = encoders
+	}
+}
+
+// presetFromFlags builds a preset from the conversion option flags
+func presetFromFlags(cmd *cobra.Command) config.ConversionPreset {
+	codec, _ := cmd.Flags().GetString("codec")
+	bitrate, _ := cmd.Flags().GetString("bitrate")
+	resolution, _ := cmd.Flags().GetString("resolution")
+	container, _ := cmd.Flags().GetString("container")
+
+	return config.ConversionPreset{
+		Codec:      codec,
+		Bitrate:    bitrate,
+		Resolution: resolution,
+		Container:  container,
+	}
+}
+
+// presetFlag returns --preset, or the configured profile when the flag is
+// not given; --preset "" skips the profile
+func presetFlag(cmd *cobra.Command, cfg *config.Config) string {
+	if cmd.Flags().Changed("preset") {
+		name, _ := cmd.Flags().GetString("preset")
+		return name
+	}
+	return cfg.ConversionProfile
+}
+
+// runConvert executes the convert command
+func runConvert(cmd *cobra.Command, args []string, cfg *config.Config, logger telemetry.Logger) error {
+	input, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve input path: %w", err)
+	}
+	if _, err := os.Stat(input); err != nil {
+		return fmt.Errorf("input file not found: %w", err)
+	}
+
+	// Resolve conversion options, explicit flags override the preset
+	var options config.ConversionPreset
+	presetName := presetFlag(cmd, cfg)
+	if presetName != "" {
+		if options, err = cfg.Preset(presetName); err != nil {
+			return err
+		}
+	}
+	options = options.Merge(presetFromFlags(cmd))
+	if err := options.Validate(); err != nil {
+		return fmt.Errorf("invalid conversion options: %w", err)
+	}
+
+	// Default to writing next to the input file
+	outputDir, err := expandedFlag(cmd, "output-dir")
+	if err != nil {
+		return err
+	}
+	if outputDir == "" {
+		outputDir = filepath.Dir(input)
+	}
+	if !estimating(cmd) {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	// Load authentication
+	tokens, err := loadAuthTokens(cmd.Context(), cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	// Initialize plugin system
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	if _, err := registry.GetModuleInfo("convert"); err != nil {
+		return fmt.Errorf("convert module not found: %w", err)
+	}
+	if estimating(cmd) {
+		return runConvertEstimate(cmd, cfg, registry, tokens, input, options, outputDir)
+	}
+
+	logger.Info("Starting conversion",
+		"input", input,
+		"preset", presetName,
+		"output_dir", outputDir,
+	)
+
+	// Prepare arguments
+	argsMap := options.Args()
+	argsMap["input"] = input
+	argsMap["output_dir"] = outputDir
+	addHWEncoders(cmd, cfg, logger, argsMap)
+
+	// Execute with progress tracking
+	progressChan := make(chan *bridge.ProgressEvent, 100)
+	controls, stopKeys := watchKeys(cfg)
+	progressDone := watchProgress(progressChan)
+
+	resp, err := registry.ExecuteCommandWithControl(cmd.Context(), "convert", "convert", argsMap, tokens, progressChan, controls)
+	stopKeys()
+	close(progressChan)
+	<-progressDone
+
+	if err != nil {
+		return fmt.Errorf("conversion failed: %w", err)
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("conversion failed: %s", resp.Error)
+	}
+
+	if handled, err := printTemplate(cmd, resp.Data); handled || err != nil {
+		return err
+	}
+
+	// Print results
+	fmt.Printf("\n✅ Conversion completed successfully!\n")
+	if filePath, ok := resp.Data["file_path"].(string); ok {
+		fmt.Printf("📁 File: %s\n", filePath)
+	}
+	if fileSize, ok := resp.Data["file_size"].(string); ok {
+		fmt.Printf("📊 Size: %s\n", fileSize)
+	}
+
+	return nil
+}
+
+// runPresetAdd executes the preset add command
+func runPresetAdd(cmd *cobra.Command, args []string, cfg *config.Config) error {
+	name := args[0]
+	if err := config.ValidatePresetName(name); err != nil {
+		return err
+	}
+
+	preset := presetFromFlags(cmd)
+	if err := preset.Validate(); err != nil {
+		return err
+	}
+
+	force, _ := cmd.Flags().GetBool("force")
+	if _, exists := cfg.Presets[name]; exists && !force {
+		return fmt.Errorf("preset %s already exists. Use --force to replace it", name)
+	}
+
+	if cfg.Presets == nil {
+		cfg.Presets = make(map[string]config.ConversionPreset)
+	}
+	cfg.Presets[name] = preset
+
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	fmt.Printf("✅ Preset %s saved\n", name)
+	return nil
+}
+
+// runPresetList executes the preset list command
+func runPresetList(cmd *cobra.Command, cfg *config.Config) error {
+	names := cfg.PresetNames()
+	if len(names) == 0 {
+		fmt.Println("No presets configured. Add one with 'converso convert preset add'")
+		return nil
+	}
+
+	list := &listOutput{Columns: []string{"name", "codec", "bitrate", "resolution", "container"}}
+	for _, name := range names {
+		preset := cfg.Presets[name]
+		list.Add(struct {
+			Name string
+			config.ConversionPreset
+		}{name, preset}, name, preset.Codec, preset.Bitrate, preset.Resolution, preset.Container)
+	}
+	return printList(cmd, list)
+}
+
+// runPresetRemove executes the preset remove command
+func runPresetRemove(name string, cfg *config.Config) error {
+	if _, err := cfg.Preset(name); err != nil {
+		return err
+	}
+
+	delete(cfg.Presets, name)
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	fmt.Printf("✅ Preset %s removed\n", name)
+	return nil
+}
+
+// valueOrDash returns "-" for empty table cells
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
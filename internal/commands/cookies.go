@@ -0,0 +1,205 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/auth"
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+// moduleCookie is a cookie as shown by cookies list; values are never shown
+type moduleCookie struct {
+	Module   string
+	Domain   string
+	Path     string
+	Name     string
+	Expires  time.Time
+	Secure   bool
+	HTTPOnly bool
+}
+
+// NewCookiesCmd creates the cookies command
+func NewCookiesCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	cookiesCmd := &cobra.Command{
+		Use:   "cookies",
+		Short: "Manage the site sessions modules keep",
+		Long: `Manage the cookies modules keep between runs, such as the YouTube login
+that members-only or age-restricted videos need.
+
+Each module has its own cookie jar, kept encrypted in the data directory
+and sent only to that module. Modules read and update their jar through the
+bridge, so a session a module signs in to, or one imported once from a
+browser, keeps working across invocations without cookie files. Cookie
+values are never shown.`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:          "list [module]",
+		Short:        "List stored cookies without their values",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCookiesList(cmd, cfg, args)
+		},
+	}
+
+	importCmd := &cobra.Command{
+		Use:   "import <module> <cookies.txt|->",
+		Short: "Import cookies from a cookies.txt file",
+		Long: `Add the cookies of a Netscape cookies.txt file, as exported by browser
+extensions or yt-dlp, to a module's jar, replacing cookies with the same
+domain, path and name. The file is no longer needed afterwards.`,
+		Example: `  converso cookies import youtube ~/Downloads/youtube.com_cookies.txt
+  converso cookies import youtube - < cookies.txt`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCookiesImport(cmd, cfg, logger, args[0], args[1])
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+
+	clearCmd := &cobra.Command{
+		Use:          "clear <module>",
+		Short:        "Delete a module's cookies, signing it out of its sites",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			domain, _ := cmd.Flags().GetString("domain")
+			return runCookiesClear(cfg, args[0], domain)
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+	clearCmd.Flags().String("domain", "", "Only delete the cookies of this domain and its subdomains")
+
+	cookiesCmd.AddCommand(listCmd, importCmd, clearCmd)
+	return cookiesCmd
+}
+
+// runCookiesList prints the stored cookies per module
+func runCookiesList(cmd *cobra.Command, cfg *config.Config, args []string) error {
+	jars, err := auth.NewCookieStore(cfg).ListCookies()
+	if err != nil {
+		return fmt.Errorf("failed to list cookies: %w", err)
+	}
+
+	modules := make([]string, 0, len(jars))
+	for module := range jars {
+		if len(args) == 0 || args[0] == module {
+			modules = append(modules, module)
+		}
+	}
+	sort.Strings(modules)
+
+	list := &listOutput{
+		Columns:  []string{"module", "domain", "path", "name", "expires", "secure", "http_only"},
+		Defaults: []string{"module", "domain", "name", "expires"},
+	}
+	for _, module := range modules {
+		for _, cookie := range jars[module] {
+			row := moduleCookie{
+				Module:   module,
+				Domain:   cookie.Domain,
+				Path:     cookie.Path,
+				Name:     cookie.Name,
+				Secure:   cookie.Secure,
+				HTTPOnly: cookie.HTTPOnly,
+			}
+			expires := "session"
+			if cookie.Expires > 0 {
+				row.Expires = time.Unix(cookie.Expires, 0)
+				expires = terminal.FormatDateTime(row.Expires.Local())
+			}
+			list.Add(row, module, cookie.Domain, cookie.Path, cookie.Name, expires,
+				fmt.Sprint(cookie.Secure), fmt.Sprint(cookie.HTTPOnly))
+		}
+	}
+
+	if len(list.Rows) == 0 && !outputFlagsSet(cmd) {
+		fmt.Println("No cookies stored. Modules add them as they sign in, or import some with 'converso cookies import'.")
+		return nil
+	}
+	return printList(cmd, list)
+}
+
+// runCookiesImport adds the cookies of a cookies.txt file to a module's jar
+func runCookiesImport(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger, module, path string) error {
+	if err := auth.ValidateSecretName(module); err != nil {
+		return err
+	}
+
+	var in io.Reader = cmd.InOrStdin()
+	if path != stdinArg {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open cookie file: %w", err)
+		}
+		defer file.Close()
+		in = file
+	}
+	imported, err := auth.ParseCookieFile(in)
+	if err != nil {
+		return fmt.Errorf("failed to read cookie file: %w", err)
+	}
+	if len(imported) == 0 {
+		return fmt.Errorf("no cookies in %s", path)
+	}
+
+	// Cookies may be imported before the module is installed
+	if registry, err := newPluginRegistry(cfg, logger); err == nil {
+		if _, err := registry.GetModuleInfo(module); err != nil {
+			fmt.Printf("⚠️  Module %s is not installed; the cookies are used once it is\n", module)
+		}
+	}
+
+	store := auth.NewCookieStore(cfg)
+	cookies, err := store.Cookies(module)
+	if err != nil {
+		return fmt.Errorf("failed to load cookies: %w", err)
+	}
+	if err := store.SetCookies(module, auth.MergeCookies(cookies, imported)); err != nil {
+		return fmt.Errorf("failed to store cookies: %w", err)
+	}
+
+	fmt.Printf("✅ Imported %d cookie(s) for module %s\n", len(imported), module)
+	if path != stdinArg {
+		fmt.Printf("💡 The cookies are stored encrypted; you can delete %s\n", path)
+	}
+	return nil
+}
+
+// runCookiesClear deletes a module's cookies, or those of one domain
+func runCookiesClear(cfg *config.Config, module, domain string) error {
+	store := auth.NewCookieStore(cfg)
+	cookies, err := store.Cookies(module)
+	if err != nil {
+		return fmt.Errorf("failed to load cookies: %w", err)
+	}
+
+	var kept []bridge.Cookie
+	if domain != "" {
+		for _, cookie := range cookies {
+			if !cookie.Matches(domain) {
+				kept = append(kept, cookie)
+			}
+		}
+	}
+	removed := len(cookies) - len(kept)
+	if removed == 0 {
+		return fmt.Errorf("no cookies stored for module %s", module)
+	}
+	if err := store.SetCookies(module, kept); err != nil {
+		return fmt.Errorf("failed to delete cookies: %w", err)
+	}
+
+	fmt.Printf("🗑️  Deleted %d cookie(s) of module %s\n", removed, module)
+	return nil
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/converso-empire/cli/pkg/archive"
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/events"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+// addForceFlag adds the flag downloading content the download archive
+// already has
+func addForceFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("force", false, "Download again even if the download archive has the content")
+}
+
+// downloadArchive returns the download archive to skip downloaded content
+// with, or nil when --force was given or it cannot be read
+func downloadArchive(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) *archive.Archive {
+	if force, _ := cmd.Flags().GetBool("force"); force {
+		return nil
+	}
+	downloads, err := archive.Open(archive.DefaultPath(cfg))
+	if err != nil {
+		logger.Warn("Failed to read the download archive, not skipping duplicates", "error", err)
+		return nil
+	}
+	return downloads
+}
+
+// skipDownloaded reports whether the archive has the content url points
+// to, saying so and recording the item in batch when it does. A nil archive
+// has nothing.
+func skipDownloaded(downloads *archive.Archive, batch *batchRun, module, url string) bool {
+	id := archive.ItemID(url)
+	if downloads == nil || !downloads.Has(module, id) {
+		return false
+	}
+	result := duplicateResult(url, id)
+	batch.Add(result)
+	// Scripts reading --output json or yaml get the result of the skip
+	if format := terminal.Format(); format != "" {
+		fmt.Fprintf(os.Stderr, "⏭️  Already downloaded %s (%s %s); use --force to download it again\n", url, module, id)
+		if err := writeFormatted(os.Stdout, format, result.Data); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+		}
+		return true
+	}
+	fmt.Printf("⏭️  Already downloaded %s (%s %s); use --force to download it again\n", url, module, id)
+	return true
+}
+
+// duplicateResult is the result of a download skipped because the archive
+// has its content ID
+func duplicateResult(url, id string) bridge.ItemResult {
+	return bridge.ItemResult{ID: url, Success: true, Data: map[string]interface{}{"duplicate": id}}
+}
+
+// archiveDownload returns the handler adding completed downloads to the
+// download archive under their content IDs, so the same video is detected
+// under another URL. Like the history it is best effort.
+func archiveDownload(cfg *config.Config, logger telemetry.Logger) events.Handler {
+	return func(e events.Event) {
+		download := e.Payload.(events.Download)
+		if download.Command != "download" || download.Err != nil || download.Response == nil {
+			return
+		}
+
+		var urls []string
+		switch resp := download.Response; {
+		case resp.HasItems():
+			for _, item := range resp.Items {
+				if item.Success {
+					urls = append(urls, item.ID)
+				}
+			}
+		case resp.Success:
+			urls = append(urls, download.URL)
+		}
+
+		downloads, err := archive.Open(archive.DefaultPath(cfg))
+		if err != nil {
+			logger.Warn("Failed to open the download archive", "error", err)
+			return
+		}
+		for _, url := range urls {
+			if err := downloads.Add(download.Module, archive.ItemID(url)); err != nil {
+				logger.Warn("Failed to record download in the archive", "url", url, "error", err)
+			}
+		}
+	}
+}
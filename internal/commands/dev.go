@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/mockapi"
+	"github.com/converso-empire/cli/pkg/plugin"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/worker"
+	"github.com/spf13/cobra"
+)
+
+// NewDevCmd creates the dev command
+func NewDevCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	devCmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Tools for plugin and CLI development",
+	}
+
+	// Mock API command
+	mockAPICmd := &cobra.Command{
+		Use:   "mock-api",
+		Short: "Run a local fake of the Converso backend",
+		Long: `Run an in-memory fake of the Converso backend for offline development.
+It implements device authorization, token issue and refresh, device
+registration and the worker job endpoints. Device codes are approved
+automatically.
+
+Point the CLI at it with the printed environment variables, then use
+login, worker start and friends as usual. Queue jobs for the worker with
+--jobs or by POSTing a job to /api/v1/jobs; GET /api/v1/jobs lists all jobs
+with their reported status.`,
+		Example: `  converso dev mock-api --jobs jobs.json
+  curl -X POST -H 'Authorization: Bearer dev' localhost:8788/api/v1/jobs \
+    -d '{"module":"youtube","command":"info","args":{"url":"https://youtu.be/x"}}'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMockAPI(cmd, logger)
+		},
+	}
+	mockAPICmd.Flags().String("addr", "127.0.0.1:8788", "Address to listen on")
+	mockAPICmd.Flags().String("jobs", "", "JSON file with an array of jobs to queue at startup")
+	mockAPICmd.Flags().Bool("strict", false, "Only accept access tokens issued by the mock")
+	mockAPICmd.Flags().Int("approve-after", 0, "Answer this many token polls with authorization_pending before approving")
+	devCmd.AddCommand(mockAPICmd)
+
+	// Signing key command
+	signingKeyCmd := &cobra.Command{
+		Use:   "signing-key <file>",
+		Short: "Generate a key pair for signing modules",
+		Long: `Generate an Ed25519 key pair for signing modules. The private key is written
+to the file, readable only by you. Trust the printed public key with
+'converso plugin keys add', or for all users in the plugin_signing_keys list
+of the policy file.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSigningKey(args[0])
+		},
+	}
+	devCmd.AddCommand(signingKeyCmd)
+
+	// Sign module command
+	signModuleCmd := &cobra.Command{
+		Use:   "sign-module <dir>",
+		Short: "Sign a module directory",
+		Long: fmt.Sprintf(`Sign a module directory with a key from 'converso dev signing-key'. The
+signature covers every file of the module and is written to %s, so sign
+after the last change. Only modules signed by a trusted key, added with
+'converso plugin keys add' or in the policy, load without --allow-unsigned.`, plugin.SignatureFile),
+		Example: `  converso dev signing-key ~/keys/modules.key
+  converso dev sign-module ./my-module --key ~/keys/modules.key`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keyFile, _ := cmd.Flags().GetString("key")
+			return runSignModule(args[0], keyFile)
+		},
+	}
+	signModuleCmd.Flags().String("key", "", "Private key file from 'converso dev signing-key'")
+	signModuleCmd.MarkFlagRequired("key")
+	devCmd.AddCommand(signModuleCmd)
+
+	return devCmd
+}
+
+// runSigningKey writes a new private signing key and prints its public key
+func runSigningKey(file string) error {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(private) + "\n"
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create key file: %w", err)
+	}
+	if _, err := f.WriteString(encoded); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+
+	fmt.Printf("🔑 Private key written to %s\n", file)
+	fmt.Println("\nTrust it with:")
+	fmt.Printf("  converso plugin keys add %s\n", base64.StdEncoding.EncodeToString(public))
+	fmt.Println("\nor for all users in the policy file:")
+	fmt.Println("  plugin_signing_keys:")
+	fmt.Printf("    - %s\n", base64.StdEncoding.EncodeToString(public))
+	return nil
+}
+
+// runSignModule signs a module directory with a private key file
+func runSignModule(dir, keyFile string) error {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(raw) != ed25519.PrivateKeySize {
+		return fmt.Errorf("%s is not a signing key from 'converso dev signing-key'", keyFile)
+	}
+	key := ed25519.PrivateKey(raw)
+
+	if err := plugin.SignModule(dir, key); err != nil {
+		return err
+	}
+
+	public := key.Public().(ed25519.PublicKey)
+	fmt.Printf("✅ Signed %s with key %s\n", dir, base64.StdEncoding.EncodeToString(public))
+	return nil
+}
+
+// runMockAPI serves the mock backend until interrupted
+func runMockAPI(cmd *cobra.Command, logger telemetry.Logger) error {
+	addr, _ := cmd.Flags().GetString("addr")
+	jobsFile, _ := cmd.Flags().GetString("jobs")
+	strict, _ := cmd.Flags().GetBool("strict")
+	approveAfter, _ := cmd.Flags().GetInt("approve-after")
+
+	server := mockapi.NewServer(mockapi.Options{Strict: strict, ApproveAfter: approveAfter}, logger)
+
+	// Queue initial jobs
+	if jobsFile != "" {
+		data, err := os.ReadFile(jobsFile)
+		if err != nil {
+			return fmt.Errorf("failed to read jobs file: %w", err)
+		}
+		var jobs []*worker.Job
+		if err := json.Unmarshal(data, &jobs); err != nil {
+			return fmt.Errorf("failed to parse jobs file: %w", err)
+		}
+		for _, job := range jobs {
+			server.AddJob(job)
+		}
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to start mock API: %w", err)
+	}
+	baseURL := "http://" + listener.Addr().String()
+
+	fmt.Printf("🧪 Mock Converso API listening on %s\n", baseURL)
+	if jobsFile != "" {
+		fmt.Printf("   %d job(s) queued\n", len(server.Jobs()))
+	}
+	fmt.Println("\nPoint the CLI at it with:")
+	fmt.Printf("  export CONVERSO_API_ENDPOINT=%s\n", baseURL)
+	fmt.Printf("  export CONVERSO_AUTH_URL=%s%s\n", baseURL, mockapi.AuthPath)
+	fmt.Printf("  export CONVERSO_TOKEN_URL=%s%s\n", baseURL, mockapi.TokenPath)
+	fmt.Println("\nPress Ctrl+C to stop")
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	httpServer := &http.Server{Handler: server.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("mock API failed: %w", err)
+	}
+	return nil
+}
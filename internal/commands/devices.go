@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/sdk"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/worker"
+	"github.com/spf13/cobra"
+)
+
+// NewDevicesCmd creates the devices command
+func NewDevicesCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	devicesCmd := &cobra.Command{
+		Use:   "devices",
+		Short: "See what your registered devices are processing",
+		Long: `Every machine you log in on is registered as a device under the name given
+to 'converso login --device-name' (the hostname by default). Jobs can be
+routed to one of them with 'converso download <url> --device <name>'.`,
+	}
+
+	jobsCmd := &cobra.Command{
+		Use:   "jobs <name>",
+		Short: "List the jobs a device is processing",
+		Long: `List the jobs routed to or taken by a device. Finished jobs are only shown
+with --all.
+
+Examples:
+  converso devices jobs home-server
+  converso devices jobs home-server --all --columns id,status,created`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDevicesJobs(cmd, cfg, logger, args[0])
+		},
+	}
+	jobsCmd.Flags().Bool("all", false, "Include completed, failed and cancelled jobs")
+	devicesCmd.AddCommand(jobsCmd)
+
+	return devicesCmd
+}
+
+// runDevicesJobs prints the backend's jobs for a device
+func runDevicesJobs(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger, device string) error {
+	all, _ := cmd.Flags().GetBool("all")
+
+	client, err := sdk.New(sdk.Options{Config: cfg, Logger: logger})
+	if err != nil {
+		return err
+	}
+	jobs, err := client.DeviceJobs(device)
+	if err != nil {
+		return err
+	}
+
+	if !all {
+		active := jobs[:0]
+		for _, job := range jobs {
+			if status := worker.JobStatus(job.Status); status == worker.JobStatusPending || status == worker.JobStatusRunning || status == worker.JobStatusPaused {
+				active = append(active, job)
+			}
+		}
+		jobs = active
+	}
+	return printJobs(cmd, jobs)
+}
+
+// queueDownload queues a download job on the backend for a registered
+// device instead of downloading here
+func queueDownload(cfg *config.Config, logger telemetry.Logger, device, module, target string, args map[string]interface{}) error {
+	client, err := sdk.New(sdk.Options{Config: cfg, Logger: logger})
+	if err != nil {
+		return err
+	}
+
+	job, err := client.EnqueueJob(&worker.Job{
+		Type:    "download",
+		Module:  module,
+		Command: "download",
+		Args:    args,
+		Device:  device,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("📤 Queued %s for %s (job %s)\n", target, device, job.ID)
+	fmt.Printf("   Follow it with 'converso devices jobs %s'\n", device)
+	return nil
+}
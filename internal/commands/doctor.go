@@ -0,0 +1,258 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/auth"
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fips"
+	"github.com/converso-empire/cli/pkg/hwaccel"
+	"github.com/converso-empire/cli/pkg/mediaserver"
+	"github.com/converso-empire/cli/pkg/plugin"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/spf13/cobra"
+)
+
+// Doctor check results
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// doctorCheck is the result of a doctor check
+type doctorCheck struct {
+	Name   string
+	Status string
+	Detail string
+}
+
+// NewDoctorCmd creates the doctor command
+func NewDoctorCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the installation for problems",
+		Long: `Check the Python runtime, FFmpeg, hardware encoders, installed modules,
+authentication and crypto mode, and suggest fixes. Exits non-zero when a
+check fails. The hardware encoders found are stored for conversions.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor(cmd, cfg, logger)
+		},
+	}
+}
+
+// runDoctor runs the checks and prints their results
+func runDoctor(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) error {
+	checks := []doctorCheck{
+		checkConfig(cfg),
+		checkPython(),
+		checkFFmpegInstalled(),
+		checkHWAccel(cmd, cfg),
+		checkModules(cfg, logger),
+		checkDependencies(cfg, logger),
+		checkAuth(cfg, logger),
+		checkCrypto(cfg),
+	}
+	if cfg.Integrations.MediaServer.Enabled() {
+		checks = append(checks, checkMediaServer(cfg, logger))
+	}
+
+	list := &listOutput{Columns: []string{"check", "status", "detail"}}
+	failed := false
+	for _, check := range checks {
+		list.Add(check, check.Name, check.Status, check.Detail)
+		failed = failed || check.Status == checkFail
+	}
+
+	if outputFlagsSet(cmd) {
+		if err := printList(cmd, list); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println("🩺 Converso Doctor")
+		fmt.Println("=================")
+		for _, check := range checks {
+			icon := "✅"
+			switch check.Status {
+			case checkWarn:
+				icon = "⚠️ "
+			case checkFail:
+				icon = "❌"
+			}
+			fmt.Printf("%s %-15s %s\n", icon, check.Name+":", check.Detail)
+		}
+	}
+
+	if failed {
+		cmd.SilenceUsage = true
+		return &ExitError{Code: ExitCodeFailure, Err: errors.New("some checks failed")}
+	}
+	return nil
+}
+
+// checkConfig reports the configuration in use
+func checkConfig(cfg *config.Config) doctorCheck {
+	detail := fmt.Sprintf("data in %s", cfg.DataDir)
+	if cfg.Policy != nil {
+		detail += fmt.Sprintf("; policy %s", cfg.Policy.Path)
+	}
+	if cfg.Project != nil {
+		detail += fmt.Sprintf("; project %s", cfg.Project.Path)
+	}
+	if cfg.ReadOnly {
+		detail += "; read-only"
+	}
+	return doctorCheck{Name: "Configuration", Status: checkOK, Detail: detail}
+}
+
+// checkPython checks the Python runtime modules run on
+func checkPython() doctorCheck {
+	if err := bridge.CheckPythonAvailability(); err != nil {
+		return doctorCheck{Name: "Python", Status: checkFail, Detail: fmt.Sprintf("%v; install Python 3.8 or later", err)}
+	}
+	return doctorCheck{Name: "Python", Status: checkOK, Detail: bridge.GetPythonPath()}
+}
+
+// checkFFmpegInstalled checks for FFmpeg on the PATH
+func checkFFmpegInstalled() doctorCheck {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return doctorCheck{Name: "FFmpeg", Status: checkWarn, Detail: "not found; media processing needs it (https://ffmpeg.org/download.html)"}
+	}
+	return doctorCheck{Name: "FFmpeg", Status: checkOK, Detail: path}
+}
+
+// checkHWAccel detects and stores the hardware encoders conversions use
+func checkHWAccel(cmd *cobra.Command, cfg *config.Config) doctorCheck {
+	caps, err := detectHWAccel(cmd.Context(), cfg)
+	if err != nil {
+		return doctorCheck{Name: "HW encoders", Status: checkWarn, Detail: err.Error()}
+	}
+	detail := describeHWAccel(caps)
+	if len(caps.Encoders) > 0 && !cfg.HWAccel {
+		detail += "; not used, hwaccel is off"
+	}
+	return doctorCheck{Name: "HW encoders", Status: checkOK, Detail: detail}
+}
+
+// detectHWAccel detects the hardware encoders and stores them for
+// conversions
+func detectHWAccel(ctx context.Context, cfg *config.Config) (*hwaccel.Capabilities, error) {
+	caps, err := hwaccel.Detect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := caps.Save(hwaccel.DefaultPath(cfg)); err != nil {
+		return nil, fmt.Errorf("failed to save hardware encoders: %w", err)
+	}
+	return caps, nil
+}
+
+// describeHWAccel lists the hardware encoders found by backend
+func describeHWAccel(caps *hwaccel.Capabilities) string {
+	if len(caps.Encoders) == 0 {
+		detail := "none found; conversions use software encoders"
+		if len(caps.Failed) > 0 {
+			detail += fmt.Sprintf(" (%d built into FFmpeg but unusable, e.g. without drivers)", len(caps.Failed))
+		}
+		return detail
+	}
+	var backends []string
+	for _, backend := range caps.Backends() {
+		var names []string
+		for _, encoder := range caps.Encoders {
+			if encoder.Backend == backend {
+				names = append(names, encoder.Name)
+			}
+		}
+		backends = append(backends, fmt.Sprintf("%s (%s)", backend, strings.Join(names, ", ")))
+	}
+	return strings.Join(backends, ", ")
+}
+
+// checkModules loads the installed modules
+func checkModules(cfg *config.Config, logger telemetry.Logger) doctorCheck {
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return doctorCheck{Name: "Modules", Status: checkFail, Detail: err.Error()}
+	}
+	count := len(registry.ListModules())
+	if count == 0 {
+		return doctorCheck{Name: "Modules", Status: checkWarn, Detail: fmt.Sprintf("none loaded from %s; run 'converso setup'", cfg.PluginsDir)}
+	}
+	return doctorCheck{Name: "Modules", Status: checkOK, Detail: fmt.Sprintf("%d loaded from %s", count, cfg.PluginsDir)}
+}
+
+// checkDependencies checks the Python dependencies of the installed
+// modules for newer releases
+func checkDependencies(cfg *config.Config, logger telemetry.Logger) doctorCheck {
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return doctorCheck{Name: "Dependencies", Status: checkWarn, Detail: err.Error()}
+	}
+
+	checker := plugin.NewDependencyChecker(cfg, logger)
+	var outdated []string
+	checked := 0
+	for _, module := range registry.ListModules() {
+		deps, err := checker.Check(module, false)
+		if err != nil {
+			return doctorCheck{Name: "Dependencies", Status: checkWarn, Detail: err.Error()}
+		}
+		for _, dep := range deps {
+			checked++
+			if dep.Outdated() {
+				outdated = append(outdated, fmt.Sprintf("%s %s < %s (converso plugin deps upgrade %s)", dep.Package, dep.Installed, dep.Latest, dep.Module))
+			}
+		}
+	}
+	if len(outdated) > 0 {
+		return doctorCheck{Name: "Dependencies", Status: checkWarn, Detail: "outdated: " + strings.Join(outdated, ", ")}
+	}
+	return doctorCheck{Name: "Dependencies", Status: checkOK, Detail: fmt.Sprintf("%d up to date", checked)}
+}
+
+// checkAuth checks for usable credentials
+func checkAuth(cfg *config.Config, logger telemetry.Logger) doctorCheck {
+	keyring := !cfg.Headless && cfg.CredentialStore == config.CredentialStoreKeyring
+	if keyring {
+		if err := auth.KeyringAvailable(); err != nil {
+			return doctorCheck{Name: "Authentication", Status: checkFail, Detail: fmt.Sprintf("keyring unavailable: %v", err)}
+		}
+	}
+	if !auth.NewAuthManager(auth.NewStorage(cfg, logger), logger).IsAuthenticated(cfg) {
+		return doctorCheck{Name: "Authentication", Status: checkWarn, Detail: "not logged in; run 'converso login'"}
+	}
+	if keyring {
+		return doctorCheck{Name: "Authentication", Status: checkOK, Detail: "logged in, tokens in the keyring"}
+	}
+	return doctorCheck{Name: "Authentication", Status: checkOK, Detail: "logged in"}
+}
+
+// checkMediaServer checks that the configured media server is reachable
+func checkMediaServer(cfg *config.Config, logger telemetry.Logger) doctorCheck {
+	server := cfg.Integrations.MediaServer
+	if err := mediaserver.NewNotifier(cfg, logger).Ping(); err != nil {
+		return doctorCheck{Name: "Media server", Status: checkWarn, Detail: fmt.Sprintf("%v; new downloads are not scanned", err)}
+	}
+	return doctorCheck{Name: "Media server", Status: checkOK, Detail: fmt.Sprintf("%s at %s", server.Type, server.URL)}
+}
+
+// checkCrypto reports the crypto mode
+func checkCrypto(cfg *config.Config) doctorCheck {
+	check := doctorCheck{Name: "Crypto", Status: checkOK, Detail: fips.Mode()}
+	if fips.Enabled() {
+		check.Detail += "; TLS 1.2 with AES-GCM suites and P-256/P-384"
+	}
+	if cfg.FIPS && !fips.Validated() {
+		check.Status = checkWarn
+		check.Detail += "; build with 'make build-fips' for a validated module"
+	}
+	return check
+}
@@ -0,0 +1,248 @@
+package commands
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/events"
+	"github.com/converso-empire/cli/pkg/plugin"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/spf13/cobra"
+)
+
+// NewDownloadCmd creates the generic download command
+func NewDownloadCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	downloadCmd := &cobra.Command{
+		Use:   "download <url>",
+		Short: "Download from any supported site",
+		Long: `Download from any URL handled by an installed module. The module is chosen
+from the url_patterns declared in module manifests, so new downloaders work
+without dedicated commands.
+
+Examples:
+  converso download https://youtube.com/watch?v=example
+  converso download "magnet:?xt=urn:btih:..." --output-dir ./torrents
+  converso download https://example.com/video --module youtube
+  converso download https://youtube.com/watch?v=example --device home-server
+  converso download https://youtube.com/watch?v=example --estimate`,
+
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batch := newBatchRun(cmd)
+			return batch.Finish(runDownload(cmd, args, cfg, logger, batch))
+		},
+	}
+
+	// Add flags
+	downloadCmd.Flags().String("module", "", "Module to use instead of matching the URL")
+	downloadCmd.Flags().String("output-dir", "", "Output directory (default: output_dir or ~/Downloads/Converso)")
+	downloadCmd.Flags().String("preset", "", "Conversion preset applied after download (see 'converso convert preset list')")
+	downloadCmd.Flags().String("device", "", "Queue the download for this registered device instead of downloading here (see 'converso devices')")
+	addEstimateFlag(downloadCmd)
+	addHWAccelFlag(downloadCmd)
+	downloadCmd.MarkFlagsMutuallyExclusive("estimate", "device")
+	addBatchFlags(downloadCmd)
+	addGeoFlags(downloadCmd)
+	addForceFlag(downloadCmd)
+
+	return downloadCmd
+}
+
+// runDownload executes the generic download command
+func runDownload(cmd *cobra.Command, args []string, cfg *config.Config, logger telemetry.Logger, batch *batchRun) error {
+	moduleName, _ := cmd.Flags().GetString("module")
+	presetName := presetFlag(cmd, cfg)
+	device, _ := cmd.Flags().GetString("device")
+
+	outputDir, err := expandedFlag(cmd, "output-dir")
+	if err != nil {
+		return err
+	}
+	target, err := plugin.ParseModuleURL(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Resolve post-processing preset
+	var postprocess map[string]interface{}
+	if presetName != "" {
+		preset, err := cfg.Preset(presetName)
+		if err != nil {
+			return err
+		}
+		postprocess = preset.Args()
+	}
+
+	if device != "" {
+		return runDownloadOnDevice(cfg, logger, device, target, moduleName, outputDir, postprocess)
+	}
+	if postprocess != nil {
+		addHWEncoders(cmd, cfg, logger, postprocess)
+	}
+
+	// Set default output directory
+	if outputDir == "" {
+		if outputDir, err = downloadDir(cfg, "Converso"); err != nil {
+			return err
+		}
+	}
+	if !estimating(cmd) {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	// Load authentication
+	tokens, err := loadAuthTokens(cmd.Context(), cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	// Initialize plugin system
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	// Route the URL to a module
+	module, err := routeURL(registry, target, "download", moduleName, canPrompt(cfg))
+	if err != nil {
+		return err
+	}
+	warnOutdatedDeps(cfg, logger, module)
+
+	options, err := geoOptions(cmd, cfg, module.Manifest)
+	if err != nil {
+		return err
+	}
+	if estimating(cmd) {
+		estimate := &downloadEstimate{
+			Registry:    registry,
+			Tokens:      tokens,
+			Module:      module,
+			Options:     options,
+			Postprocess: postprocess,
+			OutputDir:   outputDir,
+		}
+		return estimate.Run(cmd, cfg, []string{target.String()})
+	}
+	if skipDownloaded(downloadArchive(cmd, cfg, logger), batch, module.Manifest.Name, target.String()) {
+		return nil
+	}
+
+	logger.Info("Starting download",
+		"url", target.String(),
+		"module", module.Manifest.Name,
+		"module_version", module.Manifest.Version,
+		"output_dir", outputDir,
+	)
+
+	// Prepare arguments
+	argsMap := map[string]interface{}{
+		"url":        target.String(),
+		"output_dir": outputDir,
+		"fail_fast":  batch.FailFast,
+	}
+	if postprocess != nil {
+		argsMap["postprocess"] = postprocess
+	}
+	for name, value := range options {
+		argsMap[name] = value
+	}
+
+	download := events.Download{Module: module.Manifest.Name, Command: "download", URL: target.String()}
+	events.Publish(events.DownloadStarted, download)
+
+	// Execute with progress tracking
+	progressChan := make(chan *bridge.ProgressEvent, 100)
+	controls, stopKeys := watchKeys(cfg)
+	progressDone := watchProgress(progressChan)
+
+	resp, err := registry.ExecuteCommandWithControl(cmd.Context(), module.Manifest.Name, "download", argsMap, tokens, progressChan, controls)
+	stopKeys()
+	close(progressChan)
+	<-progressDone
+	download.Response, download.Err = resp, err
+	events.Publish(events.DownloadCompleted, download)
+	batch.Record(target.String(), resp, err)
+
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+
+	// Batch downloads report per-item results
+	if resp.HasItems() {
+		if err := printItemSummary(cmd, resp); err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("download failed: %s", resp.Error)
+		}
+		return itemResultsError(resp)
+	}
+
+	if !resp.Success {
+		return geoError(cfg, module.Manifest, options, fmt.Errorf("download failed: %s", resp.Error))
+	}
+
+	if handled, err := printTemplate(cmd, resp.Data); handled || err != nil {
+		return err
+	}
+
+	// Print results
+	result := bridge.DecodeDownloadResult(resp.Data)
+	fmt.Printf("\n✅ Download completed successfully!\n")
+	if result.FilePath != "" {
+		fmt.Printf("📁 File: %s\n", result.FilePath)
+	}
+	if result.FileSize > 0 {
+		fmt.Printf("📊 Size: %s\n", formatFileSize(result.FileSize))
+	}
+	fmt.Printf("📍 Output directory: %s\n", outputDir)
+
+	return nil
+}
+
+// downloadDir returns the configured output directory, or
+// ~/Downloads/<name> when none is set
+func downloadDir(cfg *config.Config, name string) (string, error) {
+	if cfg.OutputDir != "" {
+		return cfg.OutputDir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, "Downloads", name), nil
+}
+
+// runDownloadOnDevice queues the download for another device. The module is
+// chosen from the plugins installed here unless --module is given, and
+// --output-dir is a path on that device.
+func runDownloadOnDevice(cfg *config.Config, logger telemetry.Logger, device string, target *url.URL, moduleName, outputDir string, postprocess map[string]interface{}) error {
+	if moduleName == "" {
+		registry, err := newPluginRegistry(cfg, logger)
+		if err != nil {
+			return err
+		}
+		module, err := routeURL(registry, target, "download", "", canPrompt(cfg))
+		if err != nil {
+			return err
+		}
+		moduleName = module.Manifest.Name
+	}
+
+	argsMap := map[string]interface{}{"url": target.String()}
+	if outputDir != "" {
+		argsMap["output_dir"] = outputDir
+	}
+	if postprocess != nil {
+		argsMap["postprocess"] = postprocess
+	}
+	return queueDownload(cfg, logger, device, moduleName, target.String(), argsMap)
+}
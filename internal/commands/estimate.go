@@ -0,0 +1,244 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/auth"
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/estimate"
+	"github.com/converso-empire/cli/pkg/plugin"
+	"github.com/converso-empire/cli/pkg/stats"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+// addEstimateFlag adds --estimate to a command that downloads or converts
+func addEstimateFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("estimate", false, "Report the expected sizes, duration and disk space used without running")
+}
+
+// estimating reports whether --estimate was given
+func estimating(cmd *cobra.Command) bool {
+	enabled, _ := cmd.Flags().GetBool("estimate")
+	return enabled
+}
+
+// downloadEstimate estimates downloads from the metadata of their URLs:
+// the title, duration and size from the module's info command and the size
+// of the formats the mode picks from list_formats, where the module has
+// them
+type downloadEstimate struct {
+	Registry *plugin.PluginRegistry
+	Tokens   *auth.AuthTokens
+	Module   *plugin.ModuleInfo
+	// Options are passed to the metadata commands, such as a proxy
+	Options  map[string]interface{}
+	Mode     string
+	FormatID string
+	// Postprocess is the conversion applied after downloading, nil for
+	// none
+	Postprocess map[string]interface{}
+	OutputDir   string
+}
+
+// Run estimates downloading urls and prints the estimate
+func (d *downloadEstimate) Run(cmd *cobra.Command, cfg *config.Config, urls []string) error {
+	e := &estimate.Estimate{Module: d.Module.Manifest.Name, Command: "download"}
+	for _, url := range urls {
+		e.Add(d.item(cmd.Context(), url))
+	}
+	return finishEstimate(cmd, cfg, e, d.OutputDir)
+}
+
+// item estimates downloading a URL
+func (d *downloadEstimate) item(ctx context.Context, url string) estimate.Item {
+	item := estimate.Item{Source: url}
+	module := d.Module.Manifest.Name
+	if !d.Module.HasCommand("info") && !d.Module.HasCommand("list_formats") {
+		item.Error = fmt.Sprintf("%s has no info or list_formats command to estimate from", module)
+		return item
+	}
+
+	var duration time.Duration
+	if d.Module.HasCommand("info") {
+		data, err := d.query(ctx, "info", url)
+		if err != nil {
+			item.Error = err.Error()
+			return item
+		}
+		info := bridge.DecodeVideoInfo(data)
+		item.Title, duration = info.Title, info.Duration
+		item.DownloadBytes = bridge.Fields(data).Size("filesize")
+	}
+	if d.Module.HasCommand("list_formats") {
+		data, err := d.query(ctx, "list_formats", url)
+		if err != nil {
+			item.Error = err.Error()
+			return item
+		}
+		format, size := estimate.SelectFormats(bridge.DecodeFormats(data), d.Mode, d.FormatID)
+		item.Format = format
+		if size > 0 {
+			item.DownloadBytes = size
+		}
+	}
+
+	item.MediaSeconds = duration.Seconds()
+	item.OutputBytes = item.DownloadBytes
+	if d.Postprocess != nil {
+		item.OutputBytes = estimate.ConvertedSize(duration, bridge.Fields(d.Postprocess).String("bitrate"), item.DownloadBytes)
+	}
+	return item
+}
+
+// query runs a metadata command of the module for a URL
+func (d *downloadEstimate) query(ctx context.Context, command, url string) (map[string]interface{}, error) {
+	args := map[string]interface{}{"url": url}
+	for name, value := range d.Options {
+		args[name] = value
+	}
+
+	resp, err := d.Registry.ExecuteCommand(ctx, d.Module.Manifest.Name, command, args, d.Tokens)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", command, err)
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("%s failed: %s", command, resp.Error)
+	}
+	return resp.Data, nil
+}
+
+// runConvertEstimate estimates converting input with options into
+// outputDir, probing its duration with the media module when installed.
+// Without a bitrate the output is taken to be as large as the input.
+func runConvertEstimate(cmd *cobra.Command, cfg *config.Config, registry *plugin.PluginRegistry, tokens *auth.AuthTokens, input string, options config.ConversionPreset, outputDir string) error {
+	e := &estimate.Estimate{Module: "convert", Command: "convert"}
+	item := estimate.Item{Source: input}
+
+	info, err := os.Stat(input)
+	if err != nil {
+		return fmt.Errorf("input file not found: %w", err)
+	}
+	// Only a new bitrate makes the duration matter
+	var duration time.Duration
+	if media, err := registry.GetModuleInfo("media"); err == nil && media.HasCommand("probe") && options.Bitrate != "" {
+		resp, err := registry.ExecuteCommand(cmd.Context(), "media", "probe", map[string]interface{}{"file": input}, tokens)
+		switch {
+		case err != nil:
+			item.Error = fmt.Sprintf("probe failed: %v", err)
+		case !resp.Success:
+			item.Error = fmt.Sprintf("probe failed: %s", resp.Error)
+		default:
+			duration = bridge.Fields(resp.Data).Duration("duration")
+		}
+	}
+
+	item.MediaSeconds = duration.Seconds()
+	if item.Error == "" {
+		item.OutputBytes = estimate.ConvertedSize(duration, options.Bitrate, info.Size())
+	}
+	e.Add(item)
+	return finishEstimate(cmd, cfg, e, outputDir)
+}
+
+// finishEstimate works out the duration from the module's historical
+// throughput and the disk space used in outputDir, then prints the
+// estimate. It fails when no item could be estimated.
+func finishEstimate(cmd *cobra.Command, cfg *config.Config, e *estimate.Estimate, outputDir string) error {
+	var throughput float64
+	if all, err := stats.NewStore(stats.DefaultPath(cfg)).All(); err == nil && all[e.Module] != nil {
+		throughput = all[e.Module].Throughput(e.Command)
+	}
+	if err := e.Finish(throughput, outputDir); err != nil {
+		return fmt.Errorf("failed to check free disk space: %w", err)
+	}
+
+	if handled, err := printTemplate(cmd, e); handled || err != nil {
+		return err
+	}
+	printEstimate(cmd, e)
+
+	failed := e.Failed()
+	switch {
+	case failed == 0:
+		return nil
+	case failed == len(e.Items):
+		return fmt.Errorf("could not estimate any of %d item(s)", failed)
+	default:
+		return &ExitError{
+			Code: ExitCodePartialFailure,
+			Err:  fmt.Errorf("could not estimate %d of %d items", failed, len(e.Items)),
+		}
+	}
+}
+
+// printEstimate writes an estimate for people
+func printEstimate(cmd *cobra.Command, e *estimate.Estimate) {
+	fmt.Printf("\n📐 Estimate for '%s', nothing was run\n\n", cmd.CommandPath())
+
+	download := e.Command == "download"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if download {
+		fmt.Fprintln(w, "  ITEM\tFORMAT\tLENGTH\tDOWNLOAD\tOUTPUT")
+	} else {
+		fmt.Fprintln(w, "  ITEM\tLENGTH\tOUTPUT")
+	}
+	for _, item := range e.Items {
+		name := item.Title
+		if name == "" {
+			name = item.Source
+		}
+		if item.Error != "" {
+			fmt.Fprintf(w, "  %s\t⚠️  %s\n", name, item.Error)
+			continue
+		}
+		if !download {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", name, formatSeconds(int(item.MediaSeconds)), estimatedSize(item.OutputBytes))
+			continue
+		}
+		format := item.Format
+		if format == "" {
+			format = "-"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", name, format, formatSeconds(int(item.MediaSeconds)), estimatedSize(item.DownloadBytes), estimatedSize(item.OutputBytes))
+	}
+	w.Flush()
+
+	fmt.Println()
+	if download {
+		fmt.Printf("⬇️  Download: %s\n", estimatedSize(e.DownloadBytes))
+	}
+	fmt.Printf("💾 Output: %s\n", estimatedSize(e.OutputBytes))
+	switch {
+	case e.Throughput <= 0:
+		fmt.Printf("⏱️  Duration: unknown, %s has no past runs to measure its throughput\n", e.Module)
+	case e.OutputBytes <= 0:
+		fmt.Println("⏱️  Duration: unknown, the output size is unknown")
+	default:
+		fmt.Printf("⏱️  Duration: about %s at %s/s, the throughput of past runs\n",
+			terminal.FormatDuration(e.Duration().Round(time.Second)), terminal.FormatSize(int64(e.Throughput)))
+	}
+	if disk := e.Disk; disk != nil && e.OutputBytes <= 0 {
+		fmt.Printf("💽 Disk: %s free in %s\n", terminal.FormatSize(disk.Free), disk.Dir)
+	} else if disk != nil {
+		fmt.Printf("💽 Disk: %s of %s free in %s (%s%%)\n",
+			estimatedSize(e.OutputBytes), terminal.FormatSize(disk.Free), disk.Dir, terminal.FormatFloat(disk.Share*100, 1))
+		if !disk.Fits() {
+			fmt.Printf("⚠️  The output does not fit: free up %s first\n", terminal.FormatSize(e.OutputBytes-disk.Free))
+		}
+	}
+	fmt.Println("📊 Quota: not estimated, the API reports no account quotas")
+}
+
+// estimatedSize formats an estimated size, which is unknown when zero
+func estimatedSize(bytes int64) string {
+	if bytes <= 0 {
+		return "unknown"
+	}
+	return terminal.FormatSize(bytes)
+}
@@ -0,0 +1,18 @@
+package commands
+
+import (
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/events"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// subscribeEvents connects the features that react to events to the bus
+func subscribeEvents(cfg *config.Config, logger telemetry.Logger) {
+	events.Subscribe(func(e events.Event) {
+		logger.Debug("Event published", "type", string(e.Type))
+	})
+	events.Subscribe(recordDownload(cfg, logger), events.DownloadCompleted)
+	events.Subscribe(archiveDownload(cfg, logger), events.DownloadCompleted)
+	events.Subscribe(indexDownload(cfg, logger), events.DownloadCompleted)
+	events.Subscribe(notifyMediaServer(cfg, logger), events.DownloadCompleted)
+}
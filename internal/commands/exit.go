@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/errcode"
+)
+
+// Exit codes returned by the CLI, one per failure category
+const (
+	ExitCodeSuccess        = errcode.ExitSuccess
+	ExitCodeFailure        = errcode.ExitFailure
+	ExitCodePartialFailure = errcode.ExitPartialFailure
+	ExitCodeGeoBlocked     = errcode.ExitGeoBlocked
+	ExitCodeAuthRequired   = errcode.ExitAuthRequired
+	ExitCodeModuleNotFound = errcode.ExitModuleNotFound
+	ExitCodeTimeout        = errcode.ExitTimeout
+	ExitCodeNetwork        = errcode.ExitNetwork
+	ExitCodeInterrupted    = errcode.ExitInterrupted
+)
+
+// Error formats selected with --error-format
+const (
+	ErrorFormatText = "text"
+	ErrorFormatJSON = "json"
+)
+
+// ExitError wraps an error with the process exit code it should produce
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// Error implements the error interface
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the process exit code for an error returned by a command
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return errcode.Classify(err).ExitCode()
+}
+
+// PrintError writes the error a command failed with to w, as text or,
+// with --error-format json, as a JSON envelope scripts can branch on
+func PrintError(w io.Writer, cfg *config.Config, err error) {
+	if cfg.ErrorFormat == ErrorFormatJSON {
+		if errcode.WriteJSON(w, err, ExitCode(err)) == nil {
+			return
+		}
+	}
+	fmt.Fprintf(w, "Error: %v\n", err)
+}
@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/explain"
+	"github.com/converso-empire/cli/pkg/profiling"
+	"github.com/converso-empire/cli/pkg/redact"
+	"github.com/spf13/cobra"
+)
+
+// currentExplain is the invocation --explain describes, nil when the flag
+// is not given
+var currentExplain struct {
+	cmd *cobra.Command
+	cfg *config.Config
+}
+
+// explainedSetting is a setting --explain shows, with the flag that
+// overrides it, if any
+type explainedSetting struct {
+	key   string
+	flag  string
+	value func(cfg *config.Config) string
+}
+
+// explainedSettings are the settings that decide how most commands run
+var explainedSettings = []explainedSetting{
+	{key: "api_endpoint", value: func(cfg *config.Config) string { return cfg.APIEndpoint }},
+	{key: "data_dir", flag: "data-dir", value: func(cfg *config.Config) string { return cfg.DataDir }},
+	{key: "plugins_dir", flag: "plugins-dir", value: func(cfg *config.Config) string { return cfg.PluginsDir }},
+	{key: "output_dir", value: func(cfg *config.Config) string { return cfg.OutputDir }},
+	{key: "profile", value: func(cfg *config.Config) string { return cfg.ConversionProfile }},
+	{key: "concurrency", value: func(cfg *config.Config) string { return strconv.Itoa(cfg.Concurrency) }},
+	{key: "headless", flag: "headless", value: func(cfg *config.Config) string { return strconv.FormatBool(cfg.Headless) }},
+	{key: "scoped_module_tokens", value: func(cfg *config.Config) string { return strconv.FormatBool(cfg.ScopedModuleTokens) }},
+	{key: "strict_protocol", flag: "strict-protocol", value: func(cfg *config.Config) string { return strconv.FormatBool(cfg.StrictProtocol) }},
+}
+
+// startExplain records the module calls of this invocation for --explain
+func startExplain(cmd *cobra.Command, cfg *config.Config, masker *redact.Masker) {
+	currentExplain.cmd = cmd
+	currentExplain.cfg = cfg
+	explain.Enable(masker)
+}
+
+// FinishExplain prints the decision trace of an invocation that failed
+// with --explain to stderr: the settings that applied and where they came
+// from, the module calls with their requests, the phase timings and the
+// raw error
+func FinishExplain(runErr error) {
+	cmd, cfg := currentExplain.cmd, currentExplain.cfg
+	if runErr == nil || cmd == nil {
+		return
+	}
+	calls := explain.Calls()
+
+	fmt.Fprintf(os.Stderr, "\n🔍 Explanation of the failure of '%s'\n", cmd.CommandPath())
+
+	fmt.Fprintln(os.Stderr, "\nSettings:")
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  KEY\tVALUE\tSOURCE")
+	for _, setting := range explainedSettings {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", setting.key, explain.Mask(setting.value(cfg)), settingSource(cmd, cfg, setting.key, setting.flag))
+	}
+	seen := make(map[string]bool)
+	for _, call := range calls {
+		if seen[call.Module+" "+call.Command] {
+			continue
+		}
+		seen[call.Module+" "+call.Command] = true
+		total, idle := cfg.Timeouts.For(call.Module, call.Command)
+		totalKey, idleKey := timeoutKeys(cfg, call.Module, call.Command)
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", totalKey, total, settingSource(cmd, cfg, totalKey, "timeout"))
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", idleKey, idle, settingSource(cmd, cfg, idleKey, "idle-timeout"))
+	}
+	w.Flush()
+
+	fmt.Fprintln(os.Stderr, "\nModule calls:")
+	if len(calls) == 0 {
+		fmt.Fprintln(os.Stderr, "  No module command was run")
+	}
+	for _, call := range calls {
+		outcome := "succeeded"
+		if call.Error != "" {
+			outcome = "failed"
+		}
+		fmt.Fprintf(os.Stderr, "  %s %s (v%s, %s): %s after %s\n", call.Module, call.Command, call.Version, call.Path, outcome, formatLatency(call.Duration))
+		if call.Error != "" {
+			fmt.Fprintf(os.Stderr, "    Error: %s\n", call.Error)
+		}
+		fmt.Fprintln(os.Stderr, "    Request:")
+		for _, line := range strings.Split(string(call.Request), "\n") {
+			fmt.Fprintf(os.Stderr, "      %s\n", line)
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "\nTimings:")
+	w = tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  PHASE\tCALLS\tTIME")
+	for _, phase := range profiling.Timings() {
+		fmt.Fprintf(w, "  %s\t%d\t%s\n", phase.Name, phase.Count, formatLatency(phase.Total))
+	}
+	fmt.Fprintf(w, "  total\t-\t%s\n", formatLatency(profiling.Elapsed()))
+	w.Flush()
+
+	// Each wrapped error with its type, outermost first
+	fmt.Fprintln(os.Stderr, "\nError:")
+	for err := runErr; err != nil; err = errors.Unwrap(err) {
+		fmt.Fprintf(os.Stderr, "  %T: %s\n", err, explain.Mask(err.Error()))
+	}
+}
+
+// settingSource describes where the value of a setting came from, the
+// flag overriding it included
+func settingSource(cmd *cobra.Command, cfg *config.Config, key, flag string) string {
+	if flag != "" && cmd.Flags().Changed(flag) {
+		return "flag --" + flag
+	}
+	return cfg.Source(key)
+}
+
+// timeoutKeys returns the settings the total and idle timeouts of a module
+// command are taken from
+func timeoutKeys(cfg *config.Config, module, command string) (total, idle string) {
+	total, idle = "timeouts.total", "timeouts.idle"
+	override, ok := cfg.Timeouts.Modules[module][command]
+	if !ok {
+		return total, idle
+	}
+	prefix := fmt.Sprintf("timeouts.modules.%s.%s.", module, command)
+	if override.Total > 0 {
+		total = prefix + "total"
+	}
+	if override.Idle > 0 {
+		idle = prefix + "idle"
+	}
+	return total, idle
+}
@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"github.com/converso-empire/cli/pkg/query"
+	"github.com/spf13/cobra"
+)
+
+// filterFlagUsage documents the --filter expression syntax
+const filterFlagUsage = `Only show rows matching an expression, e.g. 'status=failed AND module=youtube'
+(operators: = != < <= > >= ~regex, combined with AND, OR, NOT and parentheses)`
+
+// addFilterFlag adds the --filter flag to a list command
+func addFilterFlag(cmd *cobra.Command) {
+	cmd.Flags().String("filter", "", filterFlagUsage)
+}
+
+// filterFromFlags parses the --filter flag. It returns nil when no filter
+// was given. When known is non-empty, fields outside it are rejected.
+func filterFromFlags(cmd *cobra.Command, known []string) (*query.Query, error) {
+	expr, _ := cmd.Flags().GetString("filter")
+	if expr == "" {
+		return nil, nil
+	}
+
+	q, err := query.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(known) > 0 {
+		if err := q.Validate(known); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// proxyEnv are the variables Python modules take a proxy from when none is
+// passed to them
+var proxyEnv = []string{"HTTPS_PROXY", "https_proxy", "ALL_PROXY", "all_proxy"}
+
+// addGeoFlags adds the flag passing a country to modules that can present
+// their requests as coming from it
+func addGeoFlags(cmd *cobra.Command) {
+	cmd.Flags().String("geo-bypass-country", "", "Two-letter country code to present to region-restricted sites (modules supporting it)")
+}
+
+// geoOptions returns the optional arguments that route a module's requests
+// around regional blocks: the configured proxy when the module accepts one,
+// and --geo-bypass-country
+func geoOptions(cmd *cobra.Command, cfg *config.Config, manifest *bridge.ModuleManifest) (map[string]interface{}, error) {
+	options := make(map[string]interface{})
+
+	country, _ := cmd.Flags().GetString("geo-bypass-country")
+	if country != "" {
+		if !manifest.Accepts(bridge.OptionGeoBypassCountry) {
+			return nil, fmt.Errorf("module %s does not support --geo-bypass-country", manifest.Name)
+		}
+		if len(country) != 2 {
+			return nil, fmt.Errorf("invalid country %q: use a two-letter code such as US or DE", country)
+		}
+		options[bridge.OptionGeoBypassCountry] = strings.ToUpper(country)
+	}
+
+	if cfg.Proxy != "" && manifest.Accepts(bridge.OptionProxy) {
+		options[bridge.OptionProxy] = cfg.Proxy
+	}
+	return options, nil
+}
+
+// geoError gives a module error saying the content is blocked in this
+// region the geo-blocked exit code, and prints how to get around the block
+// given the options the module ran with. Other errors are returned as is.
+func geoError(cfg *config.Config, manifest *bridge.ModuleManifest, options map[string]interface{}, err error) error {
+	if err == nil || !bridge.IsGeoRestricted(err.Error()) {
+		return err
+	}
+	for _, hint := range geoHints(cfg, manifest, options) {
+		fmt.Fprintf(os.Stderr, "💡 %s\n", hint)
+	}
+	return &ExitError{Code: ExitCodeGeoBlocked, Err: err}
+}
+
+// geoHints suggests how to reach content blocked in this region
+func geoHints(cfg *config.Config, manifest *bridge.ModuleManifest, options map[string]interface{}) []string {
+	var hints []string
+	_, proxied := options[bridge.OptionProxy]
+	switch envProxy := proxyFromEnv(); {
+	case proxied:
+		hints = append(hints, fmt.Sprintf("The request went through the proxy %s, which is blocked too; set 'proxy' to one in another country", redactProxy(cfg.Proxy)))
+	case cfg.Proxy != "":
+		hints = append(hints, fmt.Sprintf("A proxy is configured but module %s does not accept one, so the request went out directly; set %s=%s to route it through the proxy", manifest.Name, proxyEnv[0], redactProxy(cfg.Proxy)))
+	case envProxy != "":
+		hints = append(hints, fmt.Sprintf("The request went through the proxy in %s, which is blocked too; use one in another country", envProxy))
+	case manifest.Accepts(bridge.OptionProxy):
+		hints = append(hints, "No proxy is configured; set 'proxy' in the config or CONVERSO_PROXY to one in a country where the content is available")
+	}
+
+	if _, ok := options[bridge.OptionGeoBypassCountry]; !ok && manifest.Accepts(bridge.OptionGeoBypassCountry) {
+		hints = append(hints, "Retry with --geo-bypass-country <code> to present a country where the content is available")
+	}
+	if len(hints) == 0 {
+		hints = append(hints, fmt.Sprintf("Module %s takes neither a proxy nor --geo-bypass-country; run it behind a VPN or set %s", manifest.Name, proxyEnv[0]))
+	}
+	return hints
+}
+
+// proxyFromEnv returns the name of the proxy variable set in the
+// environment, or ""
+func proxyFromEnv() string {
+	for _, name := range proxyEnv {
+		if os.Getenv(name) != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// redactProxy hides the password of a proxy URL
+func redactProxy(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return proxy
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,336 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/events"
+	"github.com/converso-empire/cli/pkg/history"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+// NewHistoryCmd creates the history command
+func NewHistoryCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show, export and import download history",
+		Long: `Every download is recorded in ~/.converso/data/history.jsonl. History can be
+exported to CSV or JSON for spreadsheets or migration to another machine,
+and imported again there.`,
+	}
+
+	// List command
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List recorded downloads",
+		Long: `List recorded downloads, newest first.
+
+Examples:
+  converso history list
+  converso history list --since 2024-01-01 --limit 50
+  converso history list --filter "status=failed AND module=youtube"`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHistoryList(cmd, cfg)
+		},
+	}
+	listCmd.Flags().Int("limit", 20, "Maximum number of entries to show (0 for all)")
+	addHistoryRangeFlags(listCmd)
+	addFilterFlag(listCmd)
+	historyCmd.AddCommand(listCmd)
+
+	// Export command
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export download history to CSV or JSON",
+		Long: `Export download history to CSV or JSON.
+
+Available fields: ` + strings.Join(history.Fields, ", ") + `
+
+Examples:
+  converso history export --format csv > history.csv
+  converso history export --format json --file history.json
+  converso history export --fields created_at,title,file_path --since 7d`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHistoryExport(cmd, cfg)
+		},
+	}
+	exportCmd.Flags().String("format", history.FormatCSV, "Export format: csv, json")
+	exportCmd.Flags().String("fields", "", "Comma-separated fields to export (default: all)")
+	exportCmd.Flags().StringP("file", "f", "", "Write to a file instead of stdout")
+	addHistoryRangeFlags(exportCmd)
+	addFilterFlag(exportCmd)
+	historyCmd.AddCommand(exportCmd)
+
+	// Import command
+	importCmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import download history from CSV or JSON",
+		Long: `Import download history exported with 'converso history export'. Entries
+already present (by id) are skipped, so importing the same file twice is safe.
+
+Examples:
+  converso history import history.csv
+  converso history import backup.txt --format json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHistoryImport(cmd, args, cfg, logger)
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+	importCmd.Flags().String("format", "", "Import format: csv, json (default: from file extension)")
+	historyCmd.AddCommand(importCmd)
+
+	return historyCmd
+}
+
+// addHistoryRangeFlags adds the date-range filter flags
+func addHistoryRangeFlags(cmd *cobra.Command) {
+	cmd.Flags().String("since", "", "Only entries at or after this date (2006-01-02, RFC 3339, or age like 7d, 12h)")
+	cmd.Flags().String("until", "", "Only entries before this date (2006-01-02, RFC 3339, or age like 7d, 12h)")
+}
+
+// historyFilter builds a filter from the date-range flags
+func historyFilter(cmd *cobra.Command) (history.Filter, error) {
+	var filter history.Filter
+
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+
+	var err error
+	if since != "" {
+		if filter.Since, err = parseHistoryTime(since); err != nil {
+			return filter, fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if until != "" {
+		if filter.Until, err = parseHistoryTime(until); err != nil {
+			return filter, fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+// loadHistory reads the history entries selected by the date range and the
+// --filter expression
+func loadHistory(cmd *cobra.Command, cfg *config.Config, filter history.Filter) ([]history.Entry, error) {
+	q, err := filterFromFlags(cmd, history.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := history.NewStore(history.DefaultPath(cfg)).Entries(filter)
+	if err != nil || q == nil {
+		return entries, err
+	}
+
+	var matched []history.Entry
+	for _, e := range entries {
+		e := e
+		if q.Match(func(field string) (string, bool) { return e.Field(field), true }) {
+			matched = append(matched, e)
+		}
+	}
+	return matched, nil
+}
+
+// parseHistoryTime parses an absolute date or a relative age such as 7d
+func parseHistoryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	// Relative ages; Go durations have no day unit
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		var n int
+		if _, err := fmt.Sscanf(days, "%d", &n); err == nil && n >= 0 {
+			return time.Now().AddDate(0, 0, -n), nil
+		}
+	}
+	if age, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-age), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%q is not a date or age", value)
+}
+
+// runHistoryList prints recorded downloads, newest first
+func runHistoryList(cmd *cobra.Command, cfg *config.Config) error {
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	filter, err := historyFilter(cmd)
+	if err != nil {
+		return err
+	}
+
+	entries, err := loadHistory(cmd, cfg, filter)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No downloads recorded")
+		return nil
+	}
+
+	list := &listOutput{
+		Columns:  []string{"id", "created", "module", "command", "status", "title", "url", "source", "file", "size", "error", "run"},
+		Defaults: []string{"created", "module", "status", "title", "file"},
+	}
+	shown := 0
+	for i := len(entries) - 1; i >= 0 && (limit <= 0 || shown < limit); i-- {
+		e := entries[i]
+		title := e.Title
+		if title == "" {
+			title = e.URL
+		}
+		list.Add(e, e.ID, terminal.FormatDateTime(e.CreatedAt.Local()), e.Module, e.Command, e.Status,
+			title, e.URL, e.Source, e.FilePath, e.FileSize, e.Error, e.RunID)
+		shown++
+	}
+	if err := printList(cmd, list); err != nil {
+		return err
+	}
+
+	if shown < len(entries) && !outputFlagsSet(cmd) {
+		fmt.Printf("\n📋 Showing %d of %d entries (use --limit 0 for all)\n", shown, len(entries))
+	}
+	return nil
+}
+
+// runHistoryExport writes history in CSV or JSON
+func runHistoryExport(cmd *cobra.Command, cfg *config.Config) error {
+	format, _ := cmd.Flags().GetString("format")
+	fieldList, _ := cmd.Flags().GetString("fields")
+	file, _ := cmd.Flags().GetString("file")
+
+	fields, err := history.ParseFields(fieldList)
+	if err != nil {
+		return err
+	}
+
+	filter, err := historyFilter(cmd)
+	if err != nil {
+		return err
+	}
+
+	entries, err := loadHistory(cmd, cfg, filter)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := history.Export(w, entries, format, fields); err != nil {
+		return err
+	}
+
+	if file != "" {
+		fmt.Printf("✅ Exported %d entries to %s\n", len(entries), file)
+	}
+	return nil
+}
+
+// runHistoryImport merges an exported history file into the local history
+func runHistoryImport(cmd *cobra.Command, args []string, cfg *config.Config, logger telemetry.Logger) error {
+	path := args[0]
+	format, _ := cmd.Flags().GetString("format")
+
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer file.Close()
+
+	entries, err := history.Import(file, format)
+	if err != nil {
+		return err
+	}
+
+	added, err := history.NewStore(history.DefaultPath(cfg)).Import(entries)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Imported history", "file", path, "entries", len(entries), "added", added)
+	fmt.Printf("✅ Imported %d entries", added)
+	if skipped := len(entries) - added; skipped > 0 {
+		fmt.Printf(" (%d already present)", skipped)
+	}
+	fmt.Println()
+	return nil
+}
+
+// recordDownload returns the handler adding completed downloads to the
+// history. Recording is best effort: failures are logged and never fail the
+// download.
+func recordDownload(cfg *config.Config, logger telemetry.Logger) events.Handler {
+	return func(e events.Event) {
+		download := e.Payload.(events.Download)
+		entries := historyEntries(download.Module, download.URL, download.Response, download.Err)
+		for i := range entries {
+			entries[i].RunID = e.RunID
+			entries[i].Source = download.Source
+		}
+		if err := history.NewStore(history.DefaultPath(cfg)).Append(entries...); err != nil {
+			logger.Warn("Failed to record download history", "url", download.URL, "error", err)
+		}
+	}
+}
+
+// historyEntries returns the history entries for the outcome of a download,
+// one per item for batch downloads
+func historyEntries(module, url string, resp *bridge.ModuleResponse, downloadErr error) []history.Entry {
+	var entries []history.Entry
+
+	switch {
+	case downloadErr != nil:
+		entries = append(entries, history.Entry{Module: module, Command: "download", URL: url, Status: history.StatusFailed, Error: downloadErr.Error()})
+	case resp.HasItems():
+		for _, item := range resp.Items {
+			entry := history.Entry{Module: module, Command: "download", URL: item.ID, Status: history.StatusCompleted}
+			if item.Success {
+				entry.Title, _ = item.Data["title"].(string)
+				entry.FilePath, _ = item.Data["file_path"].(string)
+				entry.FileSize, _ = item.Data["file_size"].(string)
+			} else {
+				entry.Status = history.StatusFailed
+				entry.Error = item.Error
+			}
+			entries = append(entries, entry)
+		}
+	case !resp.Success:
+		entries = append(entries, history.Entry{Module: module, Command: "download", URL: url, Status: history.StatusFailed, Error: resp.Error})
+	default:
+		entry := history.Entry{Module: module, Command: "download", URL: url, Status: history.StatusCompleted}
+		entry.Title, _ = resp.Data["title"].(string)
+		entry.FilePath, _ = resp.Data["file_path"].(string)
+		entry.FileSize, _ = resp.Data["file_size"].(string)
+		entries = append(entries, entry)
+	}
+	return entries
+}
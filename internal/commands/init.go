@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/auth"
+	"github.com/converso-empire/cli/pkg/ci"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/spf13/cobra"
+)
+
+// NewInitCmd creates the init command
+func NewInitCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Generate files for running converso elsewhere",
+	}
+
+	ciCmd := &cobra.Command{
+		Use:   "ci <github|gitlab|shell>",
+		Short: "Generate a CI workflow that runs converso headless",
+		Long: `Generate a ready-to-use CI workflow for GitHub Actions, GitLab CI or a
+plain shell script. It installs converso, logs in headless with the
+credentials of a service account kept in CI secrets, checks the setup with
+'converso doctor' and runs a pipeline, the worker on a schedule, or both.
+
+The snippet follows the current config: endpoints, client ID, concurrency
+and profile that differ from the defaults are set in it, and every module
+secret becomes a CONVERSO_SECRET_<MODULE>_<KEY> variable to add to the CI
+secrets. Secret values are never written.
+
+The snippet is printed unless --write is given.`,
+		Example: `  converso init ci github --pipeline pipeline.yaml
+  converso init ci gitlab --pipeline pipeline.yaml --worker --write
+  converso init ci shell --worker > converso-ci.sh`,
+		Args:         cobra.ExactArgs(1),
+		ValidArgs:    ci.Platforms,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runInitCI(cmd, cfg, logger, args[0])
+		},
+	}
+	ciCmd.Flags().StringP("pipeline", "f", "", "Pipeline file to run (default: pipeline.yaml when present)")
+	ciCmd.Flags().Bool("worker", false, "Add a scheduled job running the worker")
+	ciCmd.Flags().Int("worker-minutes", 50, "How long each scheduled worker run lasts")
+	ciCmd.Flags().Bool("write", false, "Write the snippet where the platform expects it instead of printing it")
+	ciCmd.Flags().Bool("force", false, "Overwrite an existing file with --write")
+	initCmd.AddCommand(ciCmd)
+
+	return initCmd
+}
+
+// runInitCI executes the init ci command
+func runInitCI(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger, platform string) error {
+	pipelineFile, _ := cmd.Flags().GetString("pipeline")
+	worker, _ := cmd.Flags().GetBool("worker")
+	workerMinutes, _ := cmd.Flags().GetInt("worker-minutes")
+	write, _ := cmd.Flags().GetBool("write")
+	force, _ := cmd.Flags().GetBool("force")
+
+	if pipelineFile == "" {
+		for _, name := range []string{"pipeline.yaml", "pipeline.yml"} {
+			if _, err := os.Stat(name); err == nil {
+				pipelineFile = name
+				break
+			}
+		}
+		if pipelineFile == "" && !worker {
+			return fmt.Errorf("no pipeline.yaml here: give one with --pipeline or add the worker with --worker")
+		}
+	} else {
+		if _, err := os.Stat(pipelineFile); err != nil {
+			return fmt.Errorf("pipeline file %s not found", pipelineFile)
+		}
+		pipelineFile = filepath.ToSlash(pipelineFile)
+	}
+
+	secrets, err := ciSecrets(cfg, logger)
+	if err != nil {
+		return err
+	}
+	snippet, err := ci.Generate(ci.Options{
+		Platform:      platform,
+		Pipeline:      pipelineFile,
+		Worker:        worker,
+		WorkerMinutes: workerMinutes,
+		Env:           ciEnv(cfg),
+		Secrets:       secrets,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !write {
+		_, err := os.Stdout.Write(snippet)
+		return err
+	}
+
+	path := ci.DefaultPath(platform)
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	mode := os.FileMode(0644)
+	if platform == "shell" {
+		mode = 0755
+	}
+	if err := fileutil.WriteAtomic(path, snippet, mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	fmt.Printf("✅ Wrote %s\n", path)
+	fmt.Println("🔐 Add these secrets with the service account's credentials:")
+	for _, name := range secrets {
+		fmt.Printf("   %s\n", name)
+	}
+	return nil
+}
+
+// ciEnv returns the settings of the config that differ from the defaults,
+// as environment variables
+func ciEnv(cfg *config.Config) []ci.Var {
+	var env []ci.Var
+	add := func(name, value, def string) {
+		if value != "" && value != def {
+			env = append(env, ci.Var{Name: name, Value: value})
+		}
+	}
+	add("CONVERSO_API_ENDPOINT", cfg.APIEndpoint, config.DefaultAPIEndpoint)
+	add("CONVERSO_AUTH_URL", cfg.AuthURL, config.DefaultAuthURL)
+	add("CONVERSO_TOKEN_URL", cfg.TokenURL, config.DefaultTokenURL)
+	add("CONVERSO_JWKS_URL", cfg.JWKSURL, config.DefaultJWKSURL)
+	add("CONVERSO_CLIENT_ID", cfg.ClientID, config.DefaultClientID)
+	add("CONVERSO_CONCURRENCY", strconv.Itoa(cfg.Concurrency), strconv.Itoa(config.DefaultConcurrency))
+
+	// A profile from the project config comes with the checkout
+	if cfg.Project == nil || cfg.Project.Profile == "" {
+		add("CONVERSO_PROFILE", cfg.ConversionProfile, "")
+	}
+	return env
+}
+
+// ciSecrets returns the variables a CI job takes from its secrets: the
+// service account's tokens, the client secret when one is configured, and
+// every module secret
+func ciSecrets(cfg *config.Config, logger telemetry.Logger) ([]string, error) {
+	secrets := []string{"CONVERSO_ACCESS_TOKEN", "CONVERSO_REFRESH_TOKEN"}
+	if clientSecret, _ := config.Secret("client_secret"); clientSecret != "" || cfg.ClientSecret != "" {
+		secrets = append(secrets, "CONVERSO_CLIENT_SECRET")
+	}
+
+	keys, err := auth.NewStorage(cfg, logger).ListSecrets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list module secrets: %w", err)
+	}
+	var moduleSecrets []string
+	for module, names := range keys {
+		// Headless mode splits module and key at the first underscore
+		if strings.Contains(module, "_") {
+			logger.Warn("Module secrets cannot be passed through the environment", "module", module)
+			continue
+		}
+		for _, key := range names {
+			moduleSecrets = append(moduleSecrets, "CONVERSO_SECRET_"+strings.ToUpper(module+"_"+key))
+		}
+	}
+	sort.Strings(moduleSecrets)
+	return append(secrets, moduleSecrets...), nil
+}
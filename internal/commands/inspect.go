@@ -0,0 +1,288 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/history"
+	"github.com/converso-empire/cli/pkg/runs"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+// currentRun is the invocation being recorded, nil for commands that run
+// on the defaults
+var currentRun struct {
+	run   *runs.Run
+	store *runs.Store
+}
+
+// startRun begins recording this invocation and sends its logs to the log
+// file, so 'converso inspect' can assemble it later
+func startRun(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger, version string) {
+	currentRun.run = &runs.Run{
+		ID:        telemetry.RunID(),
+		Command:   cmd.CommandPath(),
+		Args:      os.Args[1:],
+		Version:   version,
+		StartedAt: time.Now(),
+	}
+	currentRun.store = runs.NewStore(runs.DefaultPath(cfg))
+
+	logFile, err := telemetry.OpenLogFile(telemetry.LogDir(cfg.DataDir))
+	if err != nil {
+		logger.Warn("Logs of this run are not kept", "error", err)
+		return
+	}
+	telemetry.SetLogFile(logger, logFile)
+}
+
+// FinishRun records the outcome of this invocation. Failed runs point to
+// 'converso inspect' for the details.
+func FinishRun(logger telemetry.Logger, runErr error) {
+	run := currentRun.run
+	if run == nil {
+		return
+	}
+
+	run.FinishedAt = time.Now()
+	run.ExitCode = ExitCode(runErr)
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+	if err := currentRun.store.Append(run); err != nil {
+		logger.Warn("Failed to record run", "run_id", run.ID, "error", err)
+		return
+	}
+
+	if runErr != nil {
+		fmt.Fprintf(os.Stderr, "💡 See what happened with 'converso inspect %s'\n", run.ID)
+	}
+}
+
+// profileDir returns where --profile writes the profiles of a run
+func profileDir(cfg *config.Config, runID string) string {
+	return filepath.Join(cfg.DataDir, "profiles", runID)
+}
+
+// runReport is everything recorded about a run
+type runReport struct {
+	Run *runs.Run
+	// Downloads are the history entries the run recorded
+	Downloads []history.Entry
+	// Jobs are the worker jobs the run processed, with their last log
+	// message
+	Jobs []runJob
+	Logs []telemetry.LogEntry
+	// ProfileDir is set when the run was profiled with --profile
+	ProfileDir string
+}
+
+// runJob is a worker job seen in a run's logs
+type runJob struct {
+	ID     string
+	Status string
+}
+
+// NewInspectCmd creates the inspect command
+func NewInspectCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inspect [run-id]",
+		Short: "Show what happened during a CLI run",
+		Long: `Every invocation gets a run ID, which is attached to its log lines, download
+history entries, worker jobs, batch summaries and profiles. Inspect
+assembles all of them for one run. A unique prefix of the run ID is enough.
+
+Without a run ID, list the most recent runs.`,
+		Example: `  converso inspect
+  converso inspect 20261016T130334-3f9a1c
+  converso inspect 20261016T1303 --template '{{.Run.ExitCode}}'`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return runInspectList(cmd, cfg)
+			}
+			return runInspect(cmd, cfg, args[0])
+		},
+	}
+	cmd.Flags().Int("limit", 20, "Number of runs to list (0 for all)")
+
+	return cmd
+}
+
+// runInspectList prints the most recent runs, newest first
+func runInspectList(cmd *cobra.Command, cfg *config.Config) error {
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	all, err := runs.NewStore(runs.DefaultPath(cfg)).Runs()
+	if err != nil {
+		return err
+	}
+	if len(all) == 0 {
+		fmt.Println("No runs recorded")
+		return nil
+	}
+
+	list := &listOutput{
+		Columns:  []string{"id", "started", "command", "duration", "exit", "error"},
+		Defaults: []string{"id", "started", "command", "exit"},
+	}
+	for i := len(all) - 1; i >= 0 && (limit <= 0 || len(all)-i <= limit); i-- {
+		run := all[i]
+		list.Add(run, run.ID, terminal.FormatTimestamp(run.StartedAt.Local()), run.Command,
+			formatLatency(run.Duration()), fmt.Sprintf("%d", run.ExitCode), run.Error)
+	}
+	return printList(cmd, list)
+}
+
+// runInspect prints everything recorded about a run
+func runInspect(cmd *cobra.Command, cfg *config.Config, id string) error {
+	report, err := assembleRun(cfg, id)
+	if err != nil {
+		return err
+	}
+	if ok, err := printTemplate(cmd, report); ok || err != nil {
+		return err
+	}
+
+	printRunSummary(report)
+
+	if len(report.Downloads) > 0 {
+		fmt.Printf("\n📦 Downloads (%d):\n", len(report.Downloads))
+		for _, e := range report.Downloads {
+			title := e.Title
+			if title == "" {
+				title = e.URL
+			}
+			if e.Status == history.StatusFailed {
+				fmt.Printf("   ❌ %s: %s\n", title, e.Error)
+			} else {
+				fmt.Printf("   ✅ %s → %s\n", title, valueOrDash(e.FilePath))
+			}
+		}
+	}
+
+	if len(report.Jobs) > 0 {
+		fmt.Printf("\n📋 Jobs (%d):\n", len(report.Jobs))
+		for _, job := range report.Jobs {
+			fmt.Printf("   • %s: %s\n", job.ID, job.Status)
+		}
+	}
+
+	if report.ProfileDir != "" {
+		fmt.Printf("\n⏱️  Profile: %s\n", report.ProfileDir)
+	}
+
+	fmt.Printf("\n📝 Log (%d entries):\n", len(report.Logs))
+	for _, entry := range report.Logs {
+		fmt.Printf("   %s\n", formatLogEntry(entry))
+	}
+	if len(report.Logs) == 0 {
+		fmt.Println("   No log lines kept for this run")
+	}
+	return nil
+}
+
+// printRunSummary prints the command and outcome of a run
+func printRunSummary(report *runReport) {
+	run := report.Run
+	fmt.Printf("🔎 Run %s\n", run.ID)
+	fmt.Println("=========================")
+	if run.Command == "" {
+		// Only logs remain, for example of a worker that is still running
+		fmt.Println("Result:   not recorded (still running or interrupted)")
+		return
+	}
+
+	fmt.Printf("Command:  %s\n", strings.Join(append([]string{"converso"}, run.Args...), " "))
+	fmt.Printf("Version:  %s\n", run.Version)
+	fmt.Printf("Started:  %s (took %s)\n", terminal.FormatTimestamp(run.StartedAt.Local()), formatLatency(run.Duration()))
+	if run.ExitCode == 0 {
+		fmt.Println("Result:   ✅ succeeded")
+	} else {
+		fmt.Printf("Result:   ❌ exit code %d: %s\n", run.ExitCode, run.Error)
+	}
+}
+
+// assembleRun collects the run record, history entries, jobs, logs and
+// profile of a run. Runs that were never recorded are assembled from their
+// logs alone.
+func assembleRun(cfg *config.Config, id string) (*runReport, error) {
+	run, err := runs.NewStore(runs.DefaultPath(cfg)).Find(id)
+	if err != nil {
+		run = &runs.Run{ID: id}
+	}
+
+	logs, logErr := telemetry.RunLogs(telemetry.LogDir(cfg.DataDir), run.ID)
+	if logErr != nil {
+		return nil, logErr
+	}
+	if run.Command == "" && len(logs) == 0 {
+		return nil, err
+	}
+
+	report := &runReport{Run: run, Logs: logs}
+
+	entries, err := history.NewStore(history.DefaultPath(cfg)).Entries(history.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.RunID == run.ID {
+			report.Downloads = append(report.Downloads, e)
+		}
+	}
+
+	// Jobs show up in the logs of the worker run that processed them
+	last := make(map[string]string)
+	for _, entry := range logs {
+		jobID, ok := entry.Fields["job_id"].(string)
+		if !ok {
+			continue
+		}
+		if _, seen := last[jobID]; !seen {
+			report.Jobs = append(report.Jobs, runJob{ID: jobID})
+		}
+		last[jobID] = entry.Message
+	}
+	for i := range report.Jobs {
+		report.Jobs[i].Status = last[report.Jobs[i].ID]
+	}
+
+	if dir := profileDir(cfg, run.ID); dirExists(dir) {
+		report.ProfileDir = dir
+	}
+	return report, nil
+}
+
+// formatLogEntry renders a log line with its time, level and fields
+func formatLogEntry(entry telemetry.LogEntry) string {
+	return fmt.Sprintf("%s %-5s %s%s", entry.Time.Local().Format("15:04:05"), strings.ToUpper(entry.Level), entry.Message, formatLogFields(entry.Fields))
+}
+
+// formatLogFields renders the structured fields of a log line as key=value
+func formatLogFields(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s=%v", key, fields[key])
+	}
+	return b.String()
+}
+
+// dirExists reports whether path is an existing directory
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/events"
+	"github.com/converso-empire/cli/pkg/mediaserver"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// notifyMediaServer returns the handler writing .nfo files for completed
+// downloads and asking the configured media server to scan them. Like the
+// history it is best effort: failures are logged and never fail the
+// download.
+func notifyMediaServer(cfg *config.Config, logger telemetry.Logger) events.Handler {
+	return func(e events.Event) {
+		server := cfg.Integrations.MediaServer
+		download := e.Payload.(events.Download)
+		if !server.Enabled() || download.Command != "download" || download.Err != nil || download.Response == nil {
+			return
+		}
+
+		results := []bridge.ItemResult{{ID: download.URL, Success: download.Response.Success, Data: download.Response.Data}}
+		if download.Response.HasItems() {
+			results = download.Response.Items
+		}
+
+		var paths []string
+		for _, result := range results {
+			path := bridge.Fields(result.Data).String("file_path")
+			if !result.Success || path == "" {
+				continue
+			}
+			if server.NFO {
+				if _, err := mediaserver.WriteNFO(download.Module, result.Data); err != nil {
+					logger.Warn("Failed to write .nfo file", "url", result.ID, "error", err)
+				}
+			}
+			paths = append(paths, path)
+		}
+		if len(paths) == 0 {
+			return
+		}
+		if err := mediaserver.NewNotifier(cfg, logger).Scan(paths); err != nil {
+			logger.Warn("Failed to notify media server", "type", server.Type, "error", err)
+			return
+		}
+		logger.Info("Media server notified", "type", server.Type, "files", len(paths))
+	}
+}
@@ -0,0 +1,500 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/sdk"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/converso-empire/cli/pkg/transfer"
+	"github.com/converso-empire/cli/pkg/worker"
+	"github.com/spf13/cobra"
+)
+
+// NewJobsCmd creates the jobs command
+func NewJobsCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	jobsCmd := &cobra.Command{
+		Use:   "jobs",
+		Short: "Inspect and pause the jobs of a running worker",
+		Long: `Show the jobs a running worker has queued, run or finished, and pause or
+resume running ones, through its control API (worker.control_addr). With
+--remote the worker can run on another machine:
+
+  converso --remote tcp://downloads.lan:8790 jobs list
+
+Remote workers authenticate clients with their control token, which
+clients pass in CONVERSO_REMOTE_TOKEN or CONVERSO_REMOTE_TOKEN_FILE.`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:          "list",
+		Short:        "List the worker's jobs",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runJobsList(cmd, cfg)
+		},
+	}
+	jobsCmd.AddCommand(listCmd)
+
+	showCmd := &cobra.Command{
+		Use:          "show <job-id>",
+		Short:        "Show a job with its progress and result",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runJobsShow(cmd, cfg, args[0])
+		},
+	}
+	jobsCmd.AddCommand(showCmd)
+
+	followCmd := &cobra.Command{
+		Use:   "follow <job-id>",
+		Short: "Show a job's progress until it finishes",
+		Long: `Show the progress of a job until it finishes, including the transcode
+chained to a download. The display starts from the job's latest progress, so
+following a job again after the CLI was interrupted or restarted picks up
+where the job is rather than at zero.
+
+Progress comes from the worker's control API when it is on, or with --remote,
+and otherwise from the progress the worker on this machine keeps in
+worker-progress.json in the data directory.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runJobsFollow(cmd, cfg, args[0])
+		},
+	}
+	jobsCmd.AddCommand(followCmd)
+
+	pauseCmd := &cobra.Command{
+		Use:   "pause <job-id>",
+		Short: "Pause a running job",
+		Long: `Pause a running job until it is resumed. The module running the job stops
+where it is, keeping what it has done so far; time spent paused counts
+towards the job's timeout.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runJobsControl(cmd, cfg, args[0], true)
+		},
+	}
+	jobsCmd.AddCommand(pauseCmd)
+
+	resumeCmd := &cobra.Command{
+		Use:          "resume <job-id>",
+		Short:        "Resume a paused job",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runJobsControl(cmd, cfg, args[0], false)
+		},
+	}
+	jobsCmd.AddCommand(resumeCmd)
+
+	fetchCmd := &cobra.Command{
+		Use:   "fetch <job-id>",
+		Short: "Copy the file a job downloaded to this machine",
+		Long: `Copy the file a completed job downloaded on another device to this machine.
+The worker must share its downloads with worker.sync: direct fetches from
+its control API, backend fetches the copy it uploaded to the backend.
+
+Interrupted fetches resume when run again, and files are verified against
+the worker's SHA-256 checksum before they are moved into place.
+
+Examples:
+  converso --remote tcp://downloads.lan:8790 jobs fetch job-1a2b
+  converso jobs fetch job-1a2b --from backend --output-dir ~/Videos`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runJobsFetch(cmd, cfg, logger, args[0])
+		},
+	}
+	fetchCmd.Flags().String("from", config.SyncDirect, "Fetch from the worker (direct) or the backend (backend)")
+	fetchCmd.Flags().String("output-dir", "", "Output directory (default: output_dir or ~/Downloads/Converso)")
+	jobsCmd.AddCommand(fetchCmd)
+
+	return jobsCmd
+}
+
+// runJobsList prints the worker's jobs, oldest first
+func runJobsList(cmd *cobra.Command, cfg *config.Config) error {
+	remote, err := workerControl(cfg)
+	if err != nil {
+		return err
+	}
+	jobs, err := remote.Jobs()
+	if err != nil {
+		return err
+	}
+	return printJobs(cmd, jobs)
+}
+
+// printJobs prints jobs as a list
+func printJobs(cmd *cobra.Command, jobs []*worker.Job) error {
+	if len(jobs) == 0 {
+		fmt.Println("No jobs")
+		return nil
+	}
+
+	list := &listOutput{
+		Columns:  []string{"id", "module", "command", "status", "progress", "created", "device", "run"},
+		Defaults: []string{"id", "module", "command", "status", "progress", "created"},
+	}
+	for _, job := range jobs {
+		progress := "-"
+		if job.Progress != nil {
+			progress = fmt.Sprintf("%.0f%%", jobProgress(job))
+		}
+		list.Add(job, job.ID, job.Module, job.Command, job.Status, progress,
+			terminal.FormatDateTime(job.CreatedAt.Local()), valueOrDash(job.Device), valueOrDash(job.RunID))
+	}
+	return printList(cmd, list)
+}
+
+// jobProgress returns the percentage done of a job, covering the jobs
+// chained to it, such as the transcode of a download
+func jobProgress(job *worker.Job) float64 {
+	if job.Next != "" {
+		return job.Progress.Overall
+	}
+	return job.Progress.Percentage
+}
+
+// runJobsShow prints a single job
+func runJobsShow(cmd *cobra.Command, cfg *config.Config, id string) error {
+	remote, err := workerControl(cfg)
+	if err != nil {
+		return err
+	}
+	job, err := remote.Job(id)
+	if err != nil {
+		return err
+	}
+	if printed, err := printTemplate(cmd, job); printed || err != nil {
+		return err
+	}
+
+	fmt.Printf("📋 Job %s\n", job.ID)
+	fmt.Println("=========================")
+	fmt.Printf("Command:  %s %s\n", job.Module, job.Command)
+	fmt.Printf("Status:   %s\n", job.Status)
+	fmt.Printf("Created:  %s\n", terminal.FormatTimestamp(job.CreatedAt.Local()))
+	if job.Device != "" {
+		fmt.Printf("Device:   %s\n", job.Device)
+	}
+	if job.RunID != "" {
+		fmt.Printf("Run:      %s\n", job.RunID)
+	}
+	if job.ParentID != "" {
+		fmt.Printf("After:    %s\n", job.ParentID)
+	}
+	if job.Next != "" {
+		fmt.Printf("Next:     %s\n", job.Next)
+	}
+	if job.Progress != nil {
+		fmt.Printf("Progress: %.0f%% %s\n", jobProgress(job), strings.TrimSpace(job.Progress.Message))
+	}
+	if job.Result != nil && !job.Result.Success {
+		fmt.Printf("❌ %s\n", job.Result.Error)
+	}
+	return nil
+}
+
+// runJobsControl pauses or resumes a running job
+func runJobsControl(cmd *cobra.Command, cfg *config.Config, id string, pause bool) error {
+	remote, err := workerControl(cfg)
+	if err != nil {
+		return err
+	}
+	control := remote.ResumeJob
+	if pause {
+		control = remote.PauseJob
+	}
+	job, err := control(id)
+	if err != nil {
+		return err
+	}
+	if printed, err := printTemplate(cmd, job); printed || err != nil {
+		return err
+	}
+
+	if pause {
+		fmt.Printf("⏸️  Paused job %s (%s %s)\n", job.ID, job.Module, job.Command)
+		fmt.Printf("💡 Resume it with: converso jobs resume %s\n", job.ID)
+	} else {
+		fmt.Printf("▶️  Resumed job %s (%s %s)\n", job.ID, job.Module, job.Command)
+	}
+	return nil
+}
+
+// runJobsFollow shows the progress of a job until it and the job chained to
+// it finish
+func runJobsFollow(cmd *cobra.Command, cfg *config.Config, id string) error {
+	source, err := followSource(cfg)
+	if err != nil {
+		return err
+	}
+	state, err := source(id)
+	if err != nil {
+		return err
+	}
+
+	progressChan := make(chan *bridge.ProgressEvent, 100)
+	progressDone := watchProgress(progressChan)
+	var shown *bridge.ProgressEvent
+	var next *worker.JobProgress
+	for {
+		// The first event is the job's latest, so the display resumes there
+		if progress := state.Progress; progress != nil && (shown == nil || !sameProgress(shown, progress)) {
+			event := *progress
+			if event.Overall == 0 {
+				event.Overall = event.Percentage
+			}
+			progressChan <- &event
+			shown = progress
+		}
+
+		if jobFinished(state.Status) {
+			if state.Next == "" {
+				break
+			}
+			// A download's progress covers its transcode until that finishes
+			if next, err = source(state.Next); err != nil || jobFinished(next.Status) {
+				err = nil
+				break
+			}
+		}
+
+		time.Sleep(followInterval)
+		if state, err = source(id); err != nil {
+			break
+		}
+	}
+	close(progressChan)
+	<-progressDone
+	if err != nil {
+		return err
+	}
+
+	if printed, err := printTemplate(cmd, state); printed || err != nil {
+		return err
+	}
+	if worker.JobStatus(state.Status) != worker.JobStatusCompleted {
+		return fmt.Errorf("job %s %s: %s", id, state.Status, valueOrDash(state.Error))
+	}
+	if next != nil && worker.JobStatus(next.Status) != worker.JobStatusCompleted {
+		return fmt.Errorf("job %s %s: %s", state.Next, next.Status, valueOrDash(next.Error))
+	}
+	fmt.Printf("✅ Job %s completed\n", id)
+	return nil
+}
+
+// followInterval is how often 'jobs follow' checks on the job
+const followInterval = time.Second
+
+// jobSource returns the latest state of a job
+type jobSource func(id string) (*worker.JobProgress, error)
+
+// followSource returns where 'jobs follow' reads jobs from: the control API
+// of the worker given by --remote or worker.control_addr, or else the
+// progress the worker on this machine persists
+func followSource(cfg *config.Config) (jobSource, error) {
+	if cfg.Remote.Addr != "" || cfg.Worker.ControlAddr != "" {
+		remote, err := workerControl(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return func(id string) (*worker.JobProgress, error) {
+			job, err := remote.Job(id)
+			if err != nil {
+				return nil, err
+			}
+			return worker.ProgressOf(job), nil
+		}, nil
+	}
+
+	return func(id string) (*worker.JobProgress, error) {
+		jobs, err := worker.ReadProgress(worker.ProgressPath(cfg))
+		if err != nil {
+			return nil, err
+		}
+		state, ok := jobs[id]
+		if !ok {
+			return nil, fmt.Errorf("the worker on this machine has no job %s", id)
+		}
+		if jobFinished(state.Status) {
+			return state, nil
+		}
+
+		// Jobs a stopped worker left unfinished never finish
+		status, err := worker.ReadStatus(worker.StatusPath(cfg))
+		if err != nil {
+			return nil, err
+		}
+		if status == nil || !status.Running || status.Stale(cfg.Worker.CheckInterval) {
+			return nil, fmt.Errorf("the worker stopped before job %s finished", id)
+		}
+		return state, nil
+	}, nil
+}
+
+// jobFinished reports whether a job with the given status is done
+func jobFinished(status string) bool {
+	switch worker.JobStatus(status) {
+	case worker.JobStatusCompleted, worker.JobStatusFailed, worker.JobStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// sameProgress reports whether two progress events of a job are the same
+// report
+func sameProgress(a, b *bridge.ProgressEvent) bool {
+	return a.Timestamp.Equal(b.Timestamp) && a.Stage == b.Stage && a.Overall == b.Overall &&
+		a.Percentage == b.Percentage && a.Message == b.Message
+}
+
+// runJobsFetch copies a job's file to the output directory
+func runJobsFetch(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger, id string) error {
+	from, _ := cmd.Flags().GetString("from")
+	outputDir, err := expandedFlag(cmd, "output-dir")
+	if err != nil {
+		return err
+	}
+
+	// Look up the job where its file is and fetch from there
+	var job *worker.Job
+	var fetch func(dest string, progress transfer.Progress) error
+	switch from {
+	case config.SyncDirect:
+		remote, err := workerControl(cfg)
+		if err != nil {
+			return err
+		}
+		if job, err = remote.Job(id); err != nil {
+			return err
+		}
+		fetch = func(dest string, progress transfer.Progress) error {
+			return remote.FetchFile(id, dest, progress)
+		}
+	case config.SyncBackend:
+		client, err := sdk.New(sdk.Options{Config: cfg, Logger: logger})
+		if err != nil {
+			return err
+		}
+		if job, err = client.Job(id); err != nil {
+			return err
+		}
+		fetch = func(dest string, progress transfer.Progress) error {
+			return client.FetchJobFile(id, dest, progress)
+		}
+	default:
+		return fmt.Errorf("invalid --from %q: use direct or backend", from)
+	}
+
+	path := worker.JobFile(job)
+	if path == "" {
+		return fmt.Errorf("job %s has no downloaded file (status: %s)", id, job.Status)
+	}
+
+	if outputDir == "" {
+		if outputDir, err = downloadDir(cfg, "Converso"); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	dest := filepath.Join(outputDir, filepath.Base(path))
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("%s already exists", dest)
+	}
+
+	progressChan := make(chan *bridge.ProgressEvent, 100)
+	progressDone := watchProgress(progressChan)
+	err = fetch(dest, transferProgress(progressChan))
+	close(progressChan)
+	<-progressDone
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Fetched %s\n", dest)
+	return nil
+}
+
+// transferProgress reports a transfer on progressChan once per percent
+func transferProgress(progressChan chan<- *bridge.ProgressEvent) transfer.Progress {
+	last := -1
+	return func(written, total int64) {
+		if total <= 0 {
+			return
+		}
+		percent := int(written * 100 / total)
+		if percent == last {
+			return
+		}
+		last = percent
+		progressChan <- &bridge.ProgressEvent{
+			Stage:      "fetching",
+			Current:    written,
+			Total:      total,
+			Percentage: float64(percent),
+			Overall:    float64(percent),
+			Message:    formatFileSize(written) + " / " + formatFileSize(total),
+			Timestamp:  time.Now(),
+		}
+	}
+}
+
+// workerControl connects to the control API of the worker given by
+// --remote, or else of the worker on this machine
+func workerControl(cfg *config.Config) (*worker.Remote, error) {
+	if cfg.Remote.Addr != "" {
+		token, err := config.Secret("remote_token")
+		if err != nil {
+			return nil, err
+		}
+		if token == "" {
+			return nil, fmt.Errorf("set CONVERSO_REMOTE_TOKEN to the control token of the worker at %s", cfg.Remote.Addr)
+		}
+		caFile := cfg.Remote.CAFile
+		if caFile != "" {
+			if caFile, err = config.ExpandPath(caFile); err != nil {
+				return nil, fmt.Errorf("invalid remote.ca_file: %w", err)
+			}
+		}
+		return worker.NewRemote(cfg.Remote.Addr, worker.RemoteOptions{
+			Token:    token,
+			CAFile:   caFile,
+			Insecure: cfg.Remote.Insecure,
+		})
+	}
+
+	if cfg.Worker.ControlAddr == "" {
+		return nil, fmt.Errorf("the worker control API is off. Set worker.control_addr, or use --remote for a worker on another machine")
+	}
+	token, err := worker.ControlToken(cfg)
+	if err != nil {
+		return nil, err
+	}
+	caFile := cfg.Worker.ControlTLSCert
+	if caFile != "" {
+		if caFile, err = config.ExpandPath(caFile); err != nil {
+			return nil, fmt.Errorf("invalid worker.control_tls_cert: %w", err)
+		}
+	}
+	return worker.NewRemote(worker.LocalAddr(cfg.Worker.ControlAddr), worker.RemoteOptions{
+		Token:    token,
+		CAFile:   caFile,
+		Insecure: caFile == "",
+	})
+}
@@ -0,0 +1,632 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/events"
+	"github.com/converso-empire/cli/pkg/fileutil"
+	"github.com/converso-empire/cli/pkg/history"
+	"github.com/converso-empire/cli/pkg/interpolate"
+	"github.com/converso-empire/cli/pkg/library"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/converso-empire/cli/pkg/worker"
+	"github.com/spf13/cobra"
+)
+
+// NewLibraryCmd creates the library command
+func NewLibraryCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	libraryCmd := &cobra.Command{
+		Use:   "library",
+		Short: "Find downloaded media by its metadata",
+		Long: `Each download gets a .info.json sidecar next to it with the metadata the
+module reported, and its title, uploader and tags are added to a local
+full-text index in ~/.converso/data/library.json. Turn sidecars off with
+'library.sidecars: false' in the config.`,
+	}
+
+	searchCmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search downloaded media by title, uploader and tags",
+		Long: `Search downloaded media by title, uploader and tags. Every word of the query
+must match the start of a word, so "weekly upd" finds "Weekly Update #3".
+Files no longer on disk are left out unless --all is given.`,
+		Example: `  converso library search weekly update
+  converso library search "@example" --columns title,file
+  converso library search tutorial --template '{{.FilePath}}' | xargs mpv`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLibrarySearch(cmd, cfg, strings.Join(args, " "))
+		},
+	}
+	searchCmd.Flags().Bool("all", false, "Include downloads whose files were moved or deleted")
+
+	indexCmd := &cobra.Command{
+		Use:   "index <dir>...",
+		Short: "Index the .info.json sidecars found in directories",
+		Long: `Add the downloads whose .info.json sidecars are found in the directories, and
+their subdirectories, to the index, and remove downloads no longer on disk.
+Use it after moving downloads together with their sidecars, or to rebuild
+the index.`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLibraryIndex(cfg, args)
+		},
+	}
+
+	organizeCmd := &cobra.Command{
+		Use:   "organize",
+		Short: "Move downloads into folders following the library template",
+		Long: `Move the indexed downloads, together with their sidecars, to the paths the
+library template gives them under the output directory, and update the
+index and the download history to match. The template is 'library.template'
+in the config, by default uploader/year/title:
+
+  ${uploader | slug ?? 'unknown'}/${year}/${title | slug ?? id}.${ext}
+
+It can use any field of the .info.json sidecar, as well as year, month and
+day of the upload date (the download date when the module reported none),
+ext and id. Downloads already in place and files no longer on disk are left
+alone. With --symlink a link is left at each old path, so players and
+scripts pointing there keep working.`,
+		Example: `  converso library organize --dry-run
+  converso library organize --symlink
+  converso library organize --pattern '${module}/${year}-${month}/${title | slug}.${ext}'`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLibraryOrganize(cmd, cfg)
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+	organizeCmd.Flags().Bool("dry-run", false, "Show where downloads would be moved without moving them")
+	organizeCmd.Flags().Bool("symlink", false, "Leave a symlink to the new place at the old path of each file")
+	organizeCmd.Flags().String("pattern", "", "Template of the new paths instead of library.template")
+	organizeCmd.Flags().String("dir", "", "Directory to organize into (default: the output directory)")
+
+	feedCmd := &cobra.Command{
+		Use:   "feed",
+		Short: "Publish downloaded audio as a podcast feed",
+		Long: `Build an RSS podcast feed of the audio files (mp3, m4a, aac, opus, ogg,
+flac, wav) in a directory and its subdirectories, so podcast apps can
+subscribe to your archive. Titles, descriptions, uploaders and dates come
+from the .info.json sidecars of the downloads.
+
+With --serve the feed and its audio files are served over HTTP until
+interrupted; the feed is rebuilt on every refresh, so new downloads show up
+in the app. Otherwise feed.xml is written to the directory, with links under
+--base-url for serving it with a web server of your own.`,
+		Example: `  converso library feed --dir ~/Podcasts --serve
+  converso library feed --dir ~/Podcasts --serve --addr 0.0.0.0:8788
+  converso library feed --dir ~/Podcasts --base-url https://media.example.com/podcasts`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLibraryFeed(cmd, cfg)
+		},
+	}
+	feedCmd.Flags().String("dir", "", "Directory with the audio files (default: the output directory)")
+	feedCmd.Flags().Bool("serve", false, "Serve the feed and the audio files over HTTP")
+	feedCmd.Flags().String("addr", "127.0.0.1:8788", "Address to serve on with --serve")
+	feedCmd.Flags().String("base-url", "", "URL the directory is served at, for the links of the written feed")
+	feedCmd.Flags().String("title", "", "Title of the podcast (default: the directory name)")
+
+	cleanupCmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Delete downloads the retention rules no longer keep",
+		Long: `Apply the retention rules under 'cleanup.rules' in the config. Each rule
+names a directory and deletes the downloads in it that are not among the
+newest keep_last of their channel, were downloaded more than older_than
+ago, or, with watched: true, were marked with 'converso library watched':
+
+  cleanup:
+    interval: 24h
+    rules:
+      - dir: "~/Downloads/Converso_YT"
+        keep_last: 10
+        older_than: 720h
+
+Only downloads with a .info.json sidecar are considered, and each is deleted
+together with its sidecar and .nfo file. Without --apply the command only
+reports what would be deleted. With 'cleanup.interval' set, 'converso worker
+start' applies the rules on that interval.`,
+		Example: `  converso library cleanup
+  converso library cleanup --apply
+  converso library cleanup --columns file,uploader,size`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLibraryCleanup(cmd, cfg)
+		},
+		Annotations: changesSetupAnnotations("apply"),
+	}
+	cleanupCmd.Flags().Bool("apply", false, "Delete the downloads instead of only reporting them")
+
+	watchedCmd := &cobra.Command{
+		Use:   "watched <file>...",
+		Short: "Mark downloads as watched for the cleanup rules",
+		Long: `Record in the .info.json sidecars of downloads that they were watched, so
+cleanup rules with 'watched: true' delete them.`,
+		Example: `  converso library watched ~/Downloads/Converso_YT/talk.mp4
+  converso library watched --unset ~/Downloads/Converso_YT/talk.mp4`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLibraryWatched(cmd, cfg, args)
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+	watchedCmd.Flags().Bool("unset", false, "Mark the downloads as not watched")
+
+	libraryCmd.AddCommand(searchCmd, indexCmd, organizeCmd, feedCmd, cleanupCmd, watchedCmd)
+	return libraryCmd
+}
+
+// runLibrarySearch prints the downloads matching query
+func runLibrarySearch(cmd *cobra.Command, cfg *config.Config, query string) error {
+	all, _ := cmd.Flags().GetBool("all")
+
+	results, err := library.NewIndex(library.DefaultPath(cfg)).Search(query)
+	if err != nil {
+		return err
+	}
+
+	list := &listOutput{
+		Columns:  []string{"title", "uploader", "tags", "file", "module", "url", "downloaded", "score", "missing"},
+		Defaults: []string{"title", "uploader", "file"},
+	}
+	missing := 0
+	for _, result := range results {
+		if result.Missing && !all {
+			missing++
+			continue
+		}
+		title := result.Title
+		if title == "" {
+			title = filepath.Base(result.FilePath)
+		}
+		list.Add(result, title, result.Uploader, strings.Join(result.Tags, ", "), result.FilePath,
+			result.Module, result.URL, terminal.FormatDateTime(result.DownloadedAt.Local()),
+			fmt.Sprint(result.Score), fmt.Sprint(result.Missing))
+	}
+
+	if len(list.Rows) == 0 && !outputFlagsSet(cmd) {
+		fmt.Printf("No downloads match %q\n", query)
+	} else if err := printList(cmd, list); err != nil {
+		return err
+	}
+	if missing > 0 && !outputFlagsSet(cmd) {
+		fmt.Printf("\n💡 %d more match(es) no longer on disk; --all shows them, 'converso library index <dir>' finds moved files\n", missing)
+	}
+	return nil
+}
+
+// runLibraryIndex indexes the sidecars found in dirs
+func runLibraryIndex(cfg *config.Config, dirs []string) error {
+	var entries []library.Entry
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(d.Name(), library.SidecarExt) {
+				return nil
+			}
+			entry, err := library.ReadSidecar(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Skipping %s: %v\n", path, err)
+				return nil
+			}
+			if entry.FilePath == "" {
+				return nil
+			}
+			entries = append(entries, *entry)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to scan %s: %w", dir, err)
+		}
+	}
+
+	index := library.NewIndex(library.DefaultPath(cfg))
+	if len(entries) == 0 {
+		fmt.Println("No .info.json sidecars found")
+	} else {
+		if err := index.Add(entries...); err != nil {
+			return err
+		}
+		fmt.Printf("📚 Indexed %d download(s)\n", len(entries))
+	}
+
+	// Downloads that were moved are now indexed at their new place
+	removed, err := index.Prune()
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		fmt.Printf("🗑️  Removed %d download(s) no longer on disk from the index\n", removed)
+	}
+	return nil
+}
+
+// runLibraryOrganize moves the indexed downloads to the paths the library
+// template gives them
+func runLibraryOrganize(cmd *cobra.Command, cfg *config.Config) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	symlink, _ := cmd.Flags().GetBool("symlink")
+	root, _ := cmd.Flags().GetString("dir")
+	pattern, _ := cmd.Flags().GetString("pattern")
+	if pattern == "" {
+		pattern = cfg.Library.Template
+	}
+
+	tmpl, err := interpolate.Parse(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid library template: %w", err)
+	}
+	if root != "" {
+		if root, err = config.ExpandPath(root); err != nil {
+			return err
+		}
+	}
+
+	index := library.NewIndex(library.DefaultPath(cfg))
+	entries, err := index.Entries()
+	if err != nil {
+		return err
+	}
+
+	moves := make(map[string]library.Entry)
+	paths := make(map[string]string)
+	targets := make(map[string]bool)
+	planned, failed := 0, 0
+	for _, entry := range entries {
+		if _, err := os.Stat(entry.FilePath); err != nil {
+			continue
+		}
+		dir := root
+		if dir == "" {
+			if dir, err = downloadDir(cfg, entry.Module); err != nil {
+				return err
+			}
+		}
+		target, err := library.Destination(tmpl, dir, entry)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Skipping %s: %v\n", entry.FilePath, err)
+			failed++
+			continue
+		}
+		if target == entry.FilePath {
+			continue
+		}
+		if targets[target] {
+			fmt.Fprintf(os.Stderr, "⚠️  Skipping %s: another download is moved to %s\n", entry.FilePath, target)
+			failed++
+			continue
+		}
+		targets[target] = true
+
+		if dryRun {
+			fmt.Printf("%s → %s\n", entry.FilePath, target)
+			planned++
+			continue
+		}
+		moved, err := library.MoveDownload(entry, target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Failed to move %s: %v\n", entry.FilePath, err)
+			failed++
+			continue
+		}
+		moves[entry.FilePath] = *moved
+		paths[entry.FilePath] = moved.FilePath
+		fmt.Printf("📦 %s → %s\n", entry.FilePath, moved.FilePath)
+		if symlink {
+			if err := os.Symlink(moved.FilePath, entry.FilePath); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Failed to link %s: %v\n", entry.FilePath, err)
+			}
+		}
+	}
+
+	switch {
+	case dryRun && planned > 0:
+		fmt.Printf("\n💡 %d download(s) would be moved; run without --dry-run to move them\n", planned)
+	case len(moves) > 0:
+		if err := index.Move(moves); err != nil {
+			return err
+		}
+		updated, err := history.NewStore(history.DefaultPath(cfg)).MoveFiles(paths)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n✅ Moved %d download(s), updating %d history record(s)\n", len(moves), updated)
+	case failed == 0:
+		fmt.Println("✅ All downloads are already organized")
+	}
+
+	if failed > 0 {
+		return &ExitError{Code: ExitCodePartialFailure, Err: fmt.Errorf("%d download(s) could not be organized", failed)}
+	}
+	return nil
+}
+
+// runLibraryFeed writes or serves the podcast feed of a directory
+func runLibraryFeed(cmd *cobra.Command, cfg *config.Config) error {
+	serve, _ := cmd.Flags().GetBool("serve")
+	addr, _ := cmd.Flags().GetString("addr")
+	baseURL, _ := cmd.Flags().GetString("base-url")
+	title, _ := cmd.Flags().GetString("title")
+	dir, err := expandedFlag(cmd, "dir")
+	if err != nil {
+		return err
+	}
+	if dir == "" {
+		if dir, err = downloadDir(cfg, "Converso"); err != nil {
+			return err
+		}
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if title == "" {
+		title = filepath.Base(dir)
+	}
+	if !serve && baseURL == "" {
+		return fmt.Errorf("--base-url is needed for the links of the feed; use --serve to serve it here instead")
+	}
+
+	episodes, err := library.Episodes(dir)
+	if err != nil {
+		return fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+	if len(episodes) == 0 {
+		fmt.Printf("⚠️  No audio files in %s yet\n", dir)
+	}
+
+	if !serve {
+		feedPath := filepath.Join(dir, strings.TrimPrefix(library.FeedPath, "/"))
+		var feed strings.Builder
+		if err := library.WriteFeed(&feed, title, baseURL, episodes); err != nil {
+			return err
+		}
+		if err := fileutil.WriteAtomic(feedPath, []byte(feed.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write feed: %w", err)
+		}
+		fmt.Printf("📻 Wrote %s with %d episode(s)\n", feedPath, len(episodes))
+		fmt.Printf("💡 Serve %s at %s and subscribe to %s%s\n", dir, baseURL, strings.TrimSuffix(baseURL, "/"), library.FeedPath)
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to start feed server: %w", err)
+	}
+	fmt.Printf("📻 Serving %d episode(s) of %s at http://%s%s\n", len(episodes), dir, listener.Addr(), library.FeedPath)
+	if !worker.IsLoopback(addr) {
+		fmt.Printf("⚠️  Anyone who can reach %s can read the feed and its audio files\n", addr)
+	}
+	fmt.Println("💡 Subscribe to that URL in your podcast app; press Ctrl+C to stop")
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Handler: library.FeedHandler(dir, title), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("feed server failed: %w", err)
+	}
+	return nil
+}
+
+// runLibraryCleanup reports, or with --apply deletes, the downloads the
+// retention rules no longer keep
+func runLibraryCleanup(cmd *cobra.Command, cfg *config.Config) error {
+	apply, _ := cmd.Flags().GetBool("apply")
+
+	removals, err := planCleanup(cfg)
+	if err != nil {
+		return err
+	}
+	if len(removals) == 0 {
+		if !outputFlagsSet(cmd) {
+			fmt.Println("✅ Nothing to clean up")
+		}
+		return nil
+	}
+
+	if !apply {
+		list := &listOutput{
+			Columns:  []string{"file", "reason", "size", "uploader", "title", "downloaded"},
+			Defaults: []string{"file", "reason", "size"},
+		}
+		var size int64
+		for _, removal := range removals {
+			size += removal.Size
+			list.Add(removal, removal.FilePath, removal.Reason, formatFileSize(removal.Size), removal.Uploader,
+				removal.Title, terminal.FormatDateTime(removal.DownloadedAt.Local()))
+		}
+		if err := printList(cmd, list); err != nil {
+			return err
+		}
+		if !outputFlagsSet(cmd) {
+			fmt.Printf("\n🧹 %d download(s), %s, would be deleted; run with --apply to delete them\n", len(removals), formatFileSize(size))
+		}
+		return nil
+	}
+
+	deleted, freed, failed := removeDownloads(cfg, removals, func(removal library.Removal, err error) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+			return
+		}
+		fmt.Printf("🗑️  %s (%s)\n", removal.FilePath, removal.Reason)
+	})
+	fmt.Printf("\n✅ Deleted %d download(s), freeing %s\n", deleted, formatFileSize(freed))
+	if failed > 0 {
+		return &ExitError{Code: ExitCodePartialFailure, Err: fmt.Errorf("%d download(s) could not be deleted", failed)}
+	}
+	return nil
+}
+
+// runLibraryWatched marks downloads as watched, or not watched with --unset
+func runLibraryWatched(cmd *cobra.Command, cfg *config.Config, files []string) error {
+	unset, _ := cmd.Flags().GetBool("unset")
+	at := time.Now()
+	if unset {
+		at = time.Time{}
+	}
+
+	var entries []library.Entry
+	for _, file := range files {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		entry, err := library.MarkWatched(path, at)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, *entry)
+	}
+	if err := library.NewIndex(library.DefaultPath(cfg)).Add(entries...); err != nil {
+		return err
+	}
+
+	if unset {
+		fmt.Printf("✅ Marked %d download(s) as not watched\n", len(entries))
+	} else {
+		fmt.Printf("👀 Marked %d download(s) as watched\n", len(entries))
+	}
+	return nil
+}
+
+// planCleanup returns the downloads the configured retention rules delete.
+// A download matched by several rules is listed once, with the reason of
+// the first.
+func planCleanup(cfg *config.Config) ([]library.Removal, error) {
+	if len(cfg.Cleanup.Rules) == 0 {
+		return nil, fmt.Errorf("no retention rules configured; add them under cleanup.rules in the config")
+	}
+	if err := cfg.Cleanup.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid cleanup rules: %w", err)
+	}
+
+	var removals []library.Removal
+	seen := make(map[string]bool)
+	now := time.Now()
+	for _, rule := range cfg.Cleanup.Rules {
+		planned, err := library.PlanCleanup(rule, now)
+		if err != nil {
+			return nil, err
+		}
+		for _, removal := range planned {
+			if !seen[removal.FilePath] {
+				seen[removal.FilePath] = true
+				removals = append(removals, removal)
+			}
+		}
+	}
+	return removals, nil
+}
+
+// removeDownloads deletes the planned downloads, reporting each to report,
+// and drops them from the library index. It returns how many were deleted,
+// the bytes freed and how many failed.
+func removeDownloads(cfg *config.Config, removals []library.Removal, report func(library.Removal, error)) (int, int64, int) {
+	deleted, failed := 0, 0
+	var freed int64
+	for _, removal := range removals {
+		if err := library.Remove(removal.Entry); err != nil {
+			report(removal, err)
+			failed++
+			continue
+		}
+		report(removal, nil)
+		deleted++
+		freed += removal.Size
+	}
+	if deleted > 0 {
+		if _, err := library.NewIndex(library.DefaultPath(cfg)).Prune(); err != nil {
+			report(library.Removal{}, fmt.Errorf("failed to update the library index: %w", err))
+		}
+	}
+	return deleted, freed, failed
+}
+
+// cleanupDownloads applies the retention rules as a scheduled worker task,
+// logging each download before deleting it
+func cleanupDownloads(cfg *config.Config, logger telemetry.Logger) error {
+	removals, err := planCleanup(cfg)
+	if err != nil {
+		return err
+	}
+	for _, removal := range removals {
+		logger.Info("Cleanup will delete download", "file", removal.FilePath, "reason", removal.Reason, "size", removal.Size)
+	}
+
+	deleted, freed, failed := removeDownloads(cfg, removals, func(removal library.Removal, err error) {
+		if err != nil {
+			logger.Warn("Cleanup failed", "file", removal.FilePath, "error", err)
+		}
+	})
+	logger.Info("Cleanup finished", "deleted", deleted, "freed_bytes", freed, "failed", failed)
+	if failed > 0 {
+		return fmt.Errorf("%d download(s) could not be deleted", failed)
+	}
+	return nil
+}
+
+// indexDownload returns the handler writing a .info.json sidecar for each
+// completed download and adding it to the library index. Like the history
+// it is best effort: failures are logged and never fail the download.
+func indexDownload(cfg *config.Config, logger telemetry.Logger) events.Handler {
+	return func(e events.Event) {
+		download := e.Payload.(events.Download)
+		if !cfg.Library.Sidecars || download.Command != "download" || download.Err != nil || download.Response == nil {
+			return
+		}
+
+		results := []bridge.ItemResult{{ID: download.URL, Success: download.Response.Success, Data: download.Response.Data}}
+		if download.Response.HasItems() {
+			results = download.Response.Items
+		}
+
+		var entries []library.Entry
+		for _, result := range results {
+			if !result.Success || bridge.Fields(result.Data).String("file_path") == "" {
+				continue
+			}
+			entry, err := library.WriteSidecar(download.Module, result.ID, result.Data)
+			if err != nil {
+				logger.Warn("Failed to write metadata sidecar", "url", result.ID, "error", err)
+				continue
+			}
+			entries = append(entries, *entry)
+		}
+		if len(entries) == 0 {
+			return
+		}
+		if err := library.NewIndex(library.DefaultPath(cfg)).Add(entries...); err != nil {
+			logger.Warn("Failed to index download", "url", download.URL, "error", err)
+		}
+	}
+}
@@ -0,0 +1,932 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+// mediaTagFields lists the text tags supported by media tag
+var mediaTagFields = []string{"title", "artist", "album"}
+
+// tagEdit describes the tag changes requested for one file. Tags holds only
+// the fields to change; an empty value clears the tag.
+type tagEdit struct {
+	File  string
+	Tags  map[string]string
+	Cover string
+}
+
+// fileTags is the current state of a file's tags as reported by the module
+type fileTags struct {
+	Tags     map[string]string
+	HasCover bool
+}
+
+// NewMediaCmd creates the media command
+func NewMediaCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	mediaCmd := &cobra.Command{
+		Use:   "media",
+		Short: "Media file commands",
+		Long:  "Inspect and edit media files",
+	}
+
+	// Tag command
+	tagCmd := &cobra.Command{
+		Use:   "tag [file]",
+		Short: "Read or write media tags",
+		Long: `Read or write the title, artist, album and cover art of media files.
+
+Without tag flags the current tags are printed. With --from-json, tags for many
+files are read from a JSON array of objects with a "file" key and any of
+"title", "artist", "album" and "cover". An empty value clears a tag.
+
+Examples:
+  converso media tag song.mp3
+  converso media tag song.mp3 --title "Intro" --artist "Converso" --cover cover.jpg
+  converso media tag --from-json tags.json --dry-run`,
+
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batch := newBatchRun(cmd)
+			return batch.Finish(runMediaTag(cmd, args, cfg, logger, batch))
+		},
+	}
+
+	// Add flags
+	for _, field := range mediaTagFields {
+		tagCmd.Flags().String(field, "", fmt.Sprintf("Set the %s tag (empty value clears it)", field))
+	}
+	tagCmd.Flags().String("cover", "", "Image file to embed as cover art")
+	tagCmd.Flags().String("from-json", "", "JSON file with tags for multiple files")
+	tagCmd.Flags().Bool("dry-run", false, "Show the changes without writing them")
+	addBatchFlags(tagCmd)
+
+	mediaCmd.AddCommand(tagCmd)
+
+	// Probe command
+	probeCmd := &cobra.Command{
+		Use:   "probe <file>",
+		Short: "Inspect codecs, resolution, bitrate and streams",
+		Long: `Inspect a media file's container, duration, bitrate and stream layout.
+
+With --preset the file is checked against a conversion preset, showing which
+options already match and which a conversion would change.
+
+Examples:
+  converso media probe video.mp4
+  converso media probe video.mp4 --output json
+  converso media probe video.mkv --preset web-720p`,
+
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMediaProbe(cmd, args, cfg, logger)
+		},
+	}
+	probeCmd.Flags().String("preset", "", "Check the file against a conversion preset")
+
+	mediaCmd.AddCommand(probeCmd)
+
+	// Frames command
+	framesCmd := &cobra.Command{
+		Use:   "frames <file>",
+		Short: "Extract still frames or GIF clips",
+		Long: `Extract still frames or GIF clips from a video.
+
+Timestamps are given as HH:MM:SS, MM:SS or seconds. With --every, frames are
+extracted at that interval starting at the first --at timestamp (or the
+beginning of the file). With --gif, a clip of --duration is created at each
+timestamp instead of a still.
+
+Output names are rendered from --name-template using {name}, {index}, {time},
+{seconds} and {ext}.
+
+Examples:
+  converso media frames video.mp4 --at 00:01:23
+  converso media frames video.mp4 --every 10s --format png
+  converso media frames video.mp4 --at 1:23 --gif --duration 4s`,
+
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batch := newBatchRun(cmd)
+			return batch.Finish(runMediaFrames(cmd, args, cfg, logger, batch))
+		},
+	}
+	framesCmd.Flags().StringSlice("at", nil, "Timestamp(s) to extract at (repeatable)")
+	framesCmd.Flags().Duration("every", 0, "Extract a frame at this interval (e.g. 10s)")
+	framesCmd.Flags().Bool("gif", false, "Create GIF clips instead of stills")
+	framesCmd.Flags().Duration("duration", 3*time.Second, "GIF clip length")
+	framesCmd.Flags().Int("fps", 10, "GIF frame rate")
+	framesCmd.Flags().Int("width", 480, "GIF width in pixels")
+	framesCmd.Flags().String("format", "jpg", "Still image format: jpg, png")
+	framesCmd.Flags().String("name-template", "{name}_{time}.{ext}", "Output file name template")
+	framesCmd.Flags().String("output-dir", "", "Output directory (default: next to the input file)")
+	addBatchFlags(framesCmd)
+
+	mediaCmd.AddCommand(framesCmd)
+
+	return mediaCmd
+}
+
+// runMediaTag executes the media tag command
+func runMediaTag(cmd *cobra.Command, args []string, cfg *config.Config, logger telemetry.Logger, batch *batchRun) error {
+	fromJSON, _ := cmd.Flags().GetString("from-json")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	// Collect requested edits
+	var edits []*tagEdit
+	var err error
+	switch {
+	case fromJSON != "" && len(args) > 0:
+		return fmt.Errorf("a file argument cannot be combined with --from-json")
+	case fromJSON != "":
+		if edits, err = loadTagEdits(fromJSON); err != nil {
+			return err
+		}
+	case len(args) == 1:
+		edits = []*tagEdit{tagEditFromFlags(cmd, args[0])}
+	default:
+		return fmt.Errorf("a file argument or --from-json is required")
+	}
+
+	if err := resolveTagEdits(edits); err != nil {
+		return err
+	}
+
+	// Load authentication
+	tokens, err := loadAuthTokens(cmd.Context(), cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	// Initialize plugin system
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	if _, err := registry.GetModuleInfo("media"); err != nil {
+		return fmt.Errorf("media module not found: %w", err)
+	}
+
+	// Read current tags
+	files := make([]interface{}, len(edits))
+	for i, edit := range edits {
+		files[i] = edit.File
+	}
+
+	resp, err := registry.ExecuteCommand(cmd.Context(), "media", "read_tags", map[string]interface{}{"files": files}, tokens)
+	if err != nil {
+		return fmt.Errorf("failed to read tags: %w", err)
+	}
+	if !resp.Success && !resp.HasItems() {
+		return fmt.Errorf("failed to read tags: %s", resp.Error)
+	}
+
+	current := make(map[string]*fileTags)
+	for _, item := range resp.Items {
+		if !item.Success {
+			return fmt.Errorf("failed to read tags of %s: %s", item.ID, item.Error)
+		}
+		current[item.ID] = fileTagsFromData(item.Data)
+	}
+
+	// Without any changes requested just show the tags
+	if fromJSON == "" && !edits[0].hasChanges() {
+		tags := current[edits[0].File]
+		if handled, err := printTemplate(cmd, tags); handled || err != nil {
+			return err
+		}
+		printFileTags(edits[0].File, tags)
+		return nil
+	}
+
+	// Show what would change
+	var entries []interface{}
+	for _, edit := range edits {
+		changes := edit.diff(current[edit.File])
+		printTagDiff(edit.File, changes)
+		if len(changes) > 0 {
+			entries = append(entries, edit.entry())
+		}
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("\n✅ Tags are already up to date")
+		return nil
+	}
+
+	if dryRun {
+		fmt.Printf("\n🔍 Dry run: %d file(s) would be updated, no changes written\n", len(entries))
+		return nil
+	}
+
+	logger.Info("Writing media tags", "files", len(entries))
+
+	// Write tags
+	resp, err = registry.ExecuteCommand(cmd.Context(), "media", "write_tags", map[string]interface{}{
+		"entries":   entries,
+		"fail_fast": batch.FailFast,
+	}, tokens)
+	batch.Record("write_tags", resp, err)
+	if err != nil {
+		return fmt.Errorf("failed to write tags: %w", err)
+	}
+
+	if resp.HasItems() && len(resp.Items) > 1 {
+		if err := printItemSummary(cmd, resp); err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("failed to write tags: %s", resp.Error)
+		}
+		return itemResultsError(resp)
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("failed to write tags: %s", resp.Error)
+	}
+	if failed := resp.FailedItems(); len(failed) > 0 {
+		return fmt.Errorf("failed to write tags: %s", failed[0].Error)
+	}
+
+	fmt.Printf("\n✅ Tags written to %d file(s)\n", len(entries))
+	return nil
+}
+
+// tagEditFromFlags builds an edit from the tag flags that were set
+func tagEditFromFlags(cmd *cobra.Command, file string) *tagEdit {
+	edit := &tagEdit{File: file, Tags: make(map[string]string)}
+	for _, field := range mediaTagFields {
+		if cmd.Flags().Changed(field) {
+			edit.Tags[field], _ = cmd.Flags().GetString(field)
+		}
+	}
+	edit.Cover, _ = cmd.Flags().GetString("cover")
+	return edit
+}
+
+// loadTagEdits reads batch tag edits from a JSON file
+func loadTagEdits(path string) ([]*tagEdit, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var entries []map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: expected an array of objects with string values: %w", path, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s contains no entries", path)
+	}
+
+	// Relative paths are resolved against the JSON file's directory
+	baseDir := filepath.Dir(path)
+	allowed := map[string]bool{"file": true, "cover": true}
+	for _, field := range mediaTagFields {
+		allowed[field] = true
+	}
+
+	edits := make([]*tagEdit, 0, len(entries))
+	for i, entry := range entries {
+		edit := &tagEdit{Tags: make(map[string]string)}
+		for key, value := range entry {
+			switch {
+			case !allowed[key]:
+				return nil, fmt.Errorf("entry %d: unknown field %q", i+1, key)
+			case key == "file":
+				edit.File = resolveRelative(baseDir, value)
+			case key == "cover":
+				if value != "" {
+					edit.Cover = resolveRelative(baseDir, value)
+				}
+			default:
+				edit.Tags[key] = value
+			}
+		}
+		if edit.File == "" {
+			return nil, fmt.Errorf("entry %d: file is required", i+1)
+		}
+		edits = append(edits, edit)
+	}
+
+	return edits, nil
+}
+
+// resolveTagEdits makes paths absolute and checks that the files exist
+func resolveTagEdits(edits []*tagEdit) error {
+	seen := make(map[string]bool)
+	for _, edit := range edits {
+		file, err := filepath.Abs(edit.File)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", edit.File, err)
+		}
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("media file not found: %w", err)
+		}
+		if seen[file] {
+			return fmt.Errorf("%s is listed more than once", edit.File)
+		}
+		seen[file] = true
+		edit.File = file
+
+		if edit.Cover != "" {
+			cover, err := filepath.Abs(edit.Cover)
+			if err != nil {
+				return fmt.Errorf("failed to resolve %s: %w", edit.Cover, err)
+			}
+			if _, err := os.Stat(cover); err != nil {
+				return fmt.Errorf("cover image not found: %w", err)
+			}
+			edit.Cover = cover
+		}
+	}
+	return nil
+}
+
+// resolveRelative joins relative paths onto baseDir
+func resolveRelative(baseDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// hasChanges reports whether the edit requests any change
+func (e *tagEdit) hasChanges() bool {
+	return len(e.Tags) > 0 || e.Cover != ""
+}
+
+// diff returns human-readable changes of the edit against the current tags,
+// sorted by field
+func (e *tagEdit) diff(current *fileTags) []string {
+	if current == nil {
+		current = &fileTags{Tags: map[string]string{}}
+	}
+
+	var changes []string
+	fields := make([]string, 0, len(e.Tags))
+	for field := range e.Tags {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		if old, value := current.Tags[field], e.Tags[field]; old != value {
+			changes = append(changes, fmt.Sprintf("%-7s %s → %s", field+":", quoteTag(old), quoteTag(value)))
+		}
+	}
+
+	if e.Cover != "" {
+		old := "(none)"
+		if current.HasCover {
+			old = "(embedded image)"
+		}
+		changes = append(changes, fmt.Sprintf("%-7s %s → %s", "cover:", old, filepath.Base(e.Cover)))
+	}
+
+	return changes
+}
+
+// entry returns the edit as a write_tags module argument
+func (e *tagEdit) entry() map[string]interface{} {
+	tags := make(map[string]interface{}, len(e.Tags))
+	for field, value := range e.Tags {
+		tags[field] = value
+	}
+
+	entry := map[string]interface{}{"file": e.File, "tags": tags}
+	if e.Cover != "" {
+		entry["cover"] = e.Cover
+	}
+	return entry
+}
+
+// fileTagsFromData parses the tags reported by the media module
+func fileTagsFromData(data map[string]interface{}) *fileTags {
+	tags := &fileTags{Tags: make(map[string]string)}
+	if values, ok := data["tags"].(map[string]interface{}); ok {
+		for field, value := range values {
+			if s, ok := value.(string); ok {
+				tags.Tags[field] = s
+			}
+		}
+	}
+	tags.HasCover, _ = data["has_cover"].(bool)
+	return tags
+}
+
+// printFileTags prints the current tags of a file
+func printFileTags(file string, tags *fileTags) {
+	fmt.Printf("\n🏷️  Tags: %s\n", file)
+	fmt.Println("==================")
+	for _, field := range mediaTagFields {
+		fmt.Printf("%-8s %s\n", field+":", quoteTag(tags.Tags[field]))
+	}
+	if tags.HasCover {
+		fmt.Printf("%-8s %s\n", "cover:", "(embedded image)")
+	} else {
+		fmt.Printf("%-8s %s\n", "cover:", "(none)")
+	}
+}
+
+// printTagDiff prints the changes for one file
+func printTagDiff(file string, changes []string) {
+	fmt.Printf("\n📝 %s\n", file)
+	if len(changes) == 0 {
+		fmt.Println("   no changes")
+		return
+	}
+	for _, change := range changes {
+		fmt.Printf("   %s\n", change)
+	}
+}
+
+// quoteTag formats a tag value for display
+func quoteTag(value string) string {
+	if value == "" {
+		return "(empty)"
+	}
+	return fmt.Sprintf("%q", value)
+}
+
+// mediaProbe is the inspection result reported by the media module
+type mediaProbe struct {
+	File       string        `json:"file"`
+	Format     string        `json:"format"`
+	FormatLong string        `json:"format_long"`
+	Duration   float64       `json:"duration"`
+	BitRate    int64         `json:"bit_rate"`
+	Size       int64         `json:"size"`
+	Streams    []probeStream `json:"streams"`
+	Preflight  []presetCheck `json:"preflight,omitempty"`
+}
+
+// probeStream describes one stream of a probed file
+type probeStream struct {
+	Index         int     `json:"index"`
+	Type          string  `json:"type"`
+	Codec         string  `json:"codec"`
+	BitRate       int64   `json:"bit_rate"`
+	Language      string  `json:"language,omitempty"`
+	Width         int     `json:"width,omitempty"`
+	Height        int     `json:"height,omitempty"`
+	FPS           float64 `json:"fps,omitempty"`
+	Channels      int     `json:"channels,omitempty"`
+	ChannelLayout string  `json:"channel_layout,omitempty"`
+	SampleRate    int     `json:"sample_rate,omitempty"`
+}
+
+// presetCheck compares one conversion preset option with a probed file
+type presetCheck struct {
+	Option  string `json:"option"`
+	Current string `json:"current"`
+	Target  string `json:"target"`
+	Matches bool   `json:"matches"`
+}
+
+// audioCodecs are preset codecs that apply to the audio stream
+var audioCodecs = map[string]bool{"aac": true, "mp3": true, "opus": true, "vorbis": true, "flac": true}
+
+// codecAliases maps preset codec names to the names reported by ffprobe
+var codecAliases = map[string]string{"h265": "hevc"}
+
+// containerAliases maps preset containers to ffprobe format names
+var containerAliases = map[string]string{"mkv": "matroska"}
+
+// runMediaProbe executes the media probe command
+func runMediaProbe(cmd *cobra.Command, args []string, cfg *config.Config, logger telemetry.Logger) error {
+	file, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", args[0], err)
+	}
+	if _, err := os.Stat(file); err != nil {
+		return fmt.Errorf("media file not found: %w", err)
+	}
+
+	// Resolve preset before probing so typos fail fast
+	presetName, _ := cmd.Flags().GetString("preset")
+	var preset config.ConversionPreset
+	if presetName != "" {
+		if preset, err = cfg.Preset(presetName); err != nil {
+			return err
+		}
+	}
+
+	// Load authentication
+	tokens, err := loadAuthTokens(cmd.Context(), cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	// Initialize plugin system
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	if _, err := registry.GetModuleInfo("media"); err != nil {
+		return fmt.Errorf("media module not found: %w", err)
+	}
+
+	logger.Info("Probing media file", "file", file)
+
+	resp, err := registry.ExecuteCommand(cmd.Context(), "media", "probe", map[string]interface{}{"file": file}, tokens)
+	if err != nil {
+		return fmt.Errorf("failed to probe file: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to probe file: %s", resp.Error)
+	}
+
+	var probe mediaProbe
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		return fmt.Errorf("failed to read probe result: %w", err)
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return fmt.Errorf("failed to read probe result: %w", err)
+	}
+
+	if presetName != "" {
+		probe.Preflight = checkPreset(&probe, preset)
+	}
+
+	if handled, err := printTemplate(cmd, &probe); handled || err != nil {
+		return err
+	}
+
+	printProbe(&probe)
+	if presetName != "" {
+		printPreflight(presetName, probe.Preflight)
+	}
+	return nil
+}
+
+// firstStream returns the first stream of the given type
+func (p *mediaProbe) firstStream(streamType string) *probeStream {
+	for i := range p.Streams {
+		if p.Streams[i].Type == streamType {
+			return &p.Streams[i]
+		}
+	}
+	return nil
+}
+
+// checkPreset compares a probed file with the options of a conversion preset
+func checkPreset(probe *mediaProbe, preset config.ConversionPreset) []presetCheck {
+	var checks []presetCheck
+
+	// Audio codecs apply to the audio stream, everything else to video
+	stream := probe.firstStream("video")
+	if audioCodecs[preset.Codec] || stream == nil {
+		stream = probe.firstStream("audio")
+	}
+
+	if preset.Codec != "" {
+		current := ""
+		if stream != nil {
+			current = stream.Codec
+		}
+		target := preset.Codec
+		if alias, ok := codecAliases[target]; ok {
+			target = alias
+		}
+		checks = append(checks, presetCheck{Option: "codec", Current: current, Target: preset.Codec, Matches: current == target})
+	}
+
+	if preset.Resolution != "" {
+		check := presetCheck{Option: "resolution", Target: preset.Resolution}
+		if video := probe.firstStream("video"); video != nil {
+			check.Current = fmt.Sprintf("%dx%d", video.Width, video.Height)
+			if strings.HasSuffix(preset.Resolution, "p") {
+				check.Matches = strings.TrimSuffix(preset.Resolution, "p") == strconv.Itoa(video.Height)
+			} else {
+				check.Matches = check.Current == preset.Resolution
+			}
+		}
+		checks = append(checks, check)
+	}
+
+	if preset.Bitrate != "" {
+		current := probe.BitRate
+		if stream != nil && stream.BitRate > 0 {
+			current = stream.BitRate
+		}
+		target := parseBitrate(preset.Bitrate)
+
+		// Treat bitrates within 10% of the target as matching
+		matches := target > 0 && current > 0 && float64(current) >= float64(target)*0.9 && float64(current) <= float64(target)*1.1
+		checks = append(checks, presetCheck{Option: "bitrate", Current: formatBitrate(current), Target: preset.Bitrate, Matches: matches})
+	}
+
+	if preset.Container != "" {
+		ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(probe.File)), ".")
+		target := preset.Container
+		if alias, ok := containerAliases[target]; ok {
+			target = alias
+		}
+		matches := ext == preset.Container
+		for _, name := range strings.Split(probe.Format, ",") {
+			matches = matches || name == target
+		}
+		checks = append(checks, presetCheck{Option: "container", Current: ext, Target: preset.Container, Matches: matches})
+	}
+
+	return checks
+}
+
+// printProbe renders a probe result as a summary and stream table
+func printProbe(probe *mediaProbe) {
+	fmt.Printf("\n🔎 Media: %s\n", probe.File)
+	fmt.Println("==================")
+	if probe.FormatLong != "" {
+		fmt.Printf("Format:    %s (%s)\n", probe.Format, probe.FormatLong)
+	} else {
+		fmt.Printf("Format:    %s\n", probe.Format)
+	}
+	fmt.Printf("Duration:  %s\n", formatSeconds(int(probe.Duration)))
+	fmt.Printf("Bitrate:   %s\n", formatBitrate(probe.BitRate))
+	fmt.Printf("Size:      %s\n\n", formatFileSize(probe.Size))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STREAM\tTYPE\tCODEC\tDETAILS\tBITRATE\tLANGUAGE")
+	for _, stream := range probe.Streams {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", stream.Index, stream.Type, stream.Codec,
+			valueOrDash(streamDetails(stream)),
+			formatBitrate(stream.BitRate),
+			valueOrDash(stream.Language),
+		)
+	}
+	w.Flush()
+}
+
+// printPreflight renders the preset comparison
+func printPreflight(name string, checks []presetCheck) {
+	fmt.Printf("\n🧪 Preflight: %s\n", name)
+	changes := 0
+	for _, check := range checks {
+		if check.Matches {
+			fmt.Printf("   ✅ %-11s %s\n", check.Option, valueOrDash(check.Current))
+			continue
+		}
+		changes++
+		fmt.Printf("   🔄 %-11s %s → %s\n", check.Option, valueOrDash(check.Current), check.Target)
+	}
+
+	if changes == 0 {
+		fmt.Println("\nFile already matches the preset, no conversion needed")
+	} else {
+		fmt.Printf("\nConversion would change %d option(s)\n", changes)
+	}
+}
+
+// streamDetails summarises the type-specific properties of a stream
+func streamDetails(stream probeStream) string {
+	switch stream.Type {
+	case "video", "cover":
+		if stream.FPS > 0 {
+			return fmt.Sprintf("%dx%d @ %s fps", stream.Width, stream.Height, strconv.FormatFloat(stream.FPS, 'f', -1, 64))
+		}
+		return fmt.Sprintf("%dx%d", stream.Width, stream.Height)
+	case "audio":
+		details := fmt.Sprintf("%dch", stream.Channels)
+		if stream.ChannelLayout != "" {
+			details += " " + stream.ChannelLayout
+		}
+		if stream.SampleRate > 0 {
+			details += fmt.Sprintf(" %d Hz", stream.SampleRate)
+		}
+		return details
+	}
+	return ""
+}
+
+// parseBitrate parses preset bitrates such as 2500k or 1.5M into bits per second
+func parseBitrate(value string) int64 {
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(value, "k"), strings.HasSuffix(value, "K"):
+		multiplier = 1000
+		value = value[:len(value)-1]
+	case strings.HasSuffix(value, "m"), strings.HasSuffix(value, "M"):
+		multiplier = 1000 * 1000
+		value = value[:len(value)-1]
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(n * multiplier)
+}
+
+// formatBitrate formats bits per second for display
+func formatBitrate(bps int64) string {
+	switch {
+	case bps <= 0:
+		return "-"
+	case bps >= 1000*1000:
+		return terminal.FormatFloat(float64(bps)/1000/1000, 1) + " Mb/s"
+	default:
+		return terminal.FormatInt(bps/1000) + " kb/s"
+	}
+}
+
+// frameTemplateFields are the placeholders supported in frame output templates
+var frameTemplateFields = map[string]bool{"name": true, "index": true, "time": true, "seconds": true, "ext": true}
+
+// templatePlaceholder matches {field} placeholders in output templates
+var templatePlaceholder = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// runMediaFrames executes the media frames command
+func runMediaFrames(cmd *cobra.Command, args []string, cfg *config.Config, logger telemetry.Logger, batch *batchRun) error {
+	at, _ := cmd.Flags().GetStringSlice("at")
+	every, _ := cmd.Flags().GetDuration("every")
+	gif, _ := cmd.Flags().GetBool("gif")
+	duration, _ := cmd.Flags().GetDuration("duration")
+	fps, _ := cmd.Flags().GetInt("fps")
+	width, _ := cmd.Flags().GetInt("width")
+	format, _ := cmd.Flags().GetString("format")
+	template, err := expandedFlag(cmd, "name-template")
+	if err != nil {
+		return err
+	}
+	outputDir, err := expandedFlag(cmd, "output-dir")
+	if err != nil {
+		return err
+	}
+
+	// Validate options
+	if len(at) == 0 && every == 0 {
+		return fmt.Errorf("--at or --every is required")
+	}
+	if every < 0 || (every > 0 && every < time.Second) {
+		return fmt.Errorf("--every must be at least 1s")
+	}
+	if len(at) > 1 && every > 0 {
+		return fmt.Errorf("--every takes a single --at start timestamp")
+	}
+	if format != "jpg" && format != "png" {
+		return fmt.Errorf("invalid format: %s. Valid formats: jpg, png", format)
+	}
+	if gif && (duration <= 0 || fps <= 0 || width <= 0) {
+		return fmt.Errorf("--duration, --fps and --width must be positive")
+	}
+	if err := validateFrameTemplate(template, len(at) > 1 || every > 0); err != nil {
+		return err
+	}
+
+	timestamps := make([]interface{}, 0, len(at))
+	for _, value := range at {
+		seconds, err := parseTimestamp(value)
+		if err != nil {
+			return err
+		}
+		timestamps = append(timestamps, seconds)
+	}
+
+	input, err := filepath.Abs(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", args[0], err)
+	}
+	if _, err := os.Stat(input); err != nil {
+		return fmt.Errorf("input file not found: %w", err)
+	}
+
+	// Default to writing next to the input file
+	if outputDir == "" {
+		outputDir = filepath.Dir(input)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Load authentication
+	tokens, err := loadAuthTokens(cmd.Context(), cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	// Initialize plugin system
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	if _, err := registry.GetModuleInfo("convert"); err != nil {
+		return fmt.Errorf("convert module not found: %w", err)
+	}
+
+	logger.Info("Extracting frames",
+		"input", input,
+		"timestamps", len(timestamps),
+		"every", every,
+		"gif", gif,
+		"output_dir", outputDir,
+	)
+
+	// Prepare arguments
+	argsMap := map[string]interface{}{
+		"input":      input,
+		"output_dir": outputDir,
+		"template":   template,
+		"timestamps": timestamps,
+		"every":      every.Seconds(),
+		"gif":        gif,
+		"format":     format,
+		"fail_fast":  batch.FailFast,
+	}
+	if gif {
+		argsMap["duration"] = duration.Seconds()
+		argsMap["fps"] = fps
+		argsMap["width"] = width
+	}
+
+	// Execute with progress tracking
+	progressChan := make(chan *bridge.ProgressEvent, 100)
+	progressDone := watchProgress(progressChan)
+
+	resp, err := registry.ExecuteCommandWithProgress(cmd.Context(), "convert", "frames", argsMap, tokens, progressChan)
+	close(progressChan)
+	<-progressDone
+	batch.Record(input, resp, err)
+
+	if err != nil {
+		return fmt.Errorf("frame extraction failed: %w", err)
+	}
+
+	if resp.HasItems() {
+		if err := printItemSummary(cmd, resp); err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("frame extraction failed: %s", resp.Error)
+		}
+		fmt.Printf("📍 Output directory: %s\n", outputDir)
+		return itemResultsError(resp)
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("frame extraction failed: %s", resp.Error)
+	}
+
+	fmt.Println("\nNo frames extracted")
+	return nil
+}
+
+// validateFrameTemplate checks the placeholders of an output template. Multiple
+// outputs need {index}, {time} or {seconds} so names do not collide.
+func validateFrameTemplate(template string, multiple bool) error {
+	if strings.ContainsAny(template, `/\`) {
+		return fmt.Errorf("template must be a file name, use --output-dir for directories")
+	}
+
+	unique := false
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(template, -1) {
+		field := match[1]
+		if !frameTemplateFields[field] {
+			return fmt.Errorf("unknown template placeholder {%s}. Valid placeholders: {name}, {index}, {time}, {seconds}, {ext}", field)
+		}
+		if field == "index" || field == "time" || field == "seconds" {
+			unique = true
+		}
+	}
+
+	if multiple && !unique {
+		return fmt.Errorf("template must contain {index}, {time} or {seconds} when extracting multiple frames")
+	}
+	return nil
+}
+
+// parseTimestamp parses HH:MM:SS, MM:SS or plain seconds into seconds
+func parseTimestamp(value string) (float64, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q, expected HH:MM:SS, MM:SS or seconds", value)
+	}
+
+	var seconds float64
+	for _, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid timestamp %q, expected HH:MM:SS, MM:SS or seconds", value)
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds, nil
+}
@@ -0,0 +1,235 @@
+package commands
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/plugin"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+// NewOpenCmd creates the open command
+func NewOpenCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	openCmd := &cobra.Command{
+		Use:   "open <url>",
+		Short: "Open a URL with the module that handles it",
+		Long: `Open a URL with the installed module whose url_patterns match it, running
+the module's default command. When several modules claim the URL you are
+asked to choose one.
+
+Examples:
+  converso open https://youtu.be/example
+  converso open https://youtu.be/example --module youtube --command info
+  converso open --routes`,
+
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOpen(cmd, args, cfg, logger)
+		},
+	}
+
+	// Add flags
+	openCmd.Flags().String("module", "", "Module to use instead of matching the URL")
+	openCmd.Flags().String("command", "", "Command to run instead of the module's default")
+	openCmd.Flags().Bool("routes", false, "List the URL routing table and exit")
+
+	return openCmd
+}
+
+// runOpen executes the open command
+func runOpen(cmd *cobra.Command, args []string, cfg *config.Config, logger telemetry.Logger) error {
+	moduleName, _ := cmd.Flags().GetString("module")
+	command, _ := cmd.Flags().GetString("command")
+	listRoutes, _ := cmd.Flags().GetBool("routes")
+
+	if !listRoutes && len(args) == 0 {
+		return fmt.Errorf("a URL is required")
+	}
+
+	// Initialize plugin system
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	if listRoutes {
+		return printURLRoutes(cmd, registry.URLRoutes())
+	}
+
+	target, err := plugin.ParseModuleURL(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Route the URL to a module and command
+	module, err := routeURL(registry, target, command, moduleName, canPrompt(cfg))
+	if err != nil {
+		return err
+	}
+	if command == "" {
+		command = module.Manifest.DefaultCommandName()
+	}
+	if !module.HasCommand(command) {
+		return fmt.Errorf("command %s not available in module %s", command, module.Manifest.Name)
+	}
+
+	// Load authentication
+	tokens, err := loadAuthTokens(cmd.Context(), cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Opening URL",
+		"url", target.String(),
+		"module", module.Manifest.Name,
+		"command", command,
+	)
+
+	// Execute with progress tracking
+	progressChan := make(chan *bridge.ProgressEvent, 100)
+	progressDone := watchProgress(progressChan)
+
+	resp, err := registry.ExecuteCommandWithProgress(cmd.Context(), module.Manifest.Name, command, map[string]interface{}{"url": target.String()}, tokens, progressChan)
+	close(progressChan)
+	<-progressDone
+
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", module.Manifest.Name, command, err)
+	}
+
+	if resp.HasItems() {
+		if err := printItemSummary(cmd, resp); err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("%s %s failed: %s", module.Manifest.Name, command, resp.Error)
+		}
+		return itemResultsError(resp)
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("%s %s failed: %s", module.Manifest.Name, command, resp.Error)
+	}
+
+	if handled, err := printTemplate(cmd, resp.Data); handled || err != nil {
+		return err
+	}
+
+	printResponseData(module.Manifest.Name, command, resp.Data)
+	return nil
+}
+
+// routeURL picks the module for target, honouring an explicit module choice
+// and prompting when several modules match and interactive is set. When
+// command is set only modules providing it are considered.
+func routeURL(registry *plugin.PluginRegistry, target *url.URL, command, moduleName string, interactive bool) (*plugin.ModuleInfo, error) {
+	if moduleName != "" {
+		module, err := registry.GetModuleInfo(moduleName)
+		if err != nil {
+			return nil, err
+		}
+		if command != "" && !module.HasCommand(command) {
+			return nil, fmt.Errorf("command %s not available in module %s", command, moduleName)
+		}
+		return module, nil
+	}
+
+	matches := registry.ModulesForURL(target, command)
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no installed module handles %s. Use --module to choose one", target)
+	case 1:
+		return matches[0], nil
+	}
+
+	names := make([]string, len(matches))
+	for i, module := range matches {
+		names[i] = module.Manifest.Name
+	}
+	if !interactive {
+		return nil, fmt.Errorf("multiple modules handle %s (%s). Use --module to choose one", target, strings.Join(names, ", "))
+	}
+
+	return promptModule(target, matches)
+}
+
+// canPrompt reports whether the user can be asked questions: never in
+// headless mode or when stdin is not a terminal
+func canPrompt(cfg *config.Config) bool {
+	return !cfg.Headless && terminal.IsTerminal(os.Stdin)
+}
+
+// promptModule asks the user to choose between modules claiming the same URL
+func promptModule(target *url.URL, modules []*plugin.ModuleInfo) (*plugin.ModuleInfo, error) {
+	fmt.Printf("Multiple modules handle %s:\n", target)
+	for i, module := range modules {
+		fmt.Printf("  [%d] %s - %s\n", i+1, module.Manifest.Name, module.Manifest.Description)
+	}
+	fmt.Printf("Select a module [1-%d]: ", len(modules))
+
+	var response string
+	fmt.Scanln(&response)
+
+	choice, err := strconv.Atoi(strings.TrimSpace(response))
+	if err != nil || choice < 1 || choice > len(modules) {
+		return nil, fmt.Errorf("invalid selection: %q", response)
+	}
+	return modules[choice-1], nil
+}
+
+// printURLRoutes renders the URL routing table
+func printURLRoutes(cmd *cobra.Command, routes []plugin.URLRoute) error {
+	if len(routes) == 0 {
+		fmt.Println("No installed module declares url_patterns")
+		return nil
+	}
+
+	list := &listOutput{Columns: []string{"pattern", "module", "default_command"}}
+	for _, route := range routes {
+		list.Add(route, route.Pattern, route.Module.Manifest.Name, route.Module.Manifest.DefaultCommandName())
+	}
+	return printList(cmd, list)
+}
+
+// printResponseData prints the top-level fields of a module response
+func printResponseData(module, command string, data map[string]interface{}) {
+	fmt.Printf("\n✅ %s %s completed\n", module, command)
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, key := range keys {
+		switch value := data[key].(type) {
+		case map[string]interface{}, []interface{}:
+			// Nested values are summarised rather than dumped
+			fmt.Fprintf(w, "%s:\t(%d entries)\n", key, nestedLen(value))
+		default:
+			fmt.Fprintf(w, "%s:\t%v\n", key, value)
+		}
+	}
+	w.Flush()
+}
+
+// nestedLen returns the number of entries of a nested JSON value
+func nestedLen(value interface{}) int {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return len(v)
+	case []interface{}:
+		return len(v)
+	}
+	return 0
+}
@@ -0,0 +1,247 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/converso-empire/cli/pkg/interpolate"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// addOutputFlags adds the output formatting flags shared by all commands
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("columns", "", "Comma-separated columns to show in list output (e.g. id,status,created)")
+	cmd.PersistentFlags().String("template", "", "Format output with a Go template, e.g. '{{.FilePath}}'")
+}
+
+// outputRow is a row of list output. Cells are the rendered column values
+// and Data is the value passed to --template.
+type outputRow struct {
+	Cells map[string]string
+	Data  interface{}
+}
+
+// listOutput is a table rendered by the shared output formatter
+type listOutput struct {
+	// Columns are all available columns in display order
+	Columns []string
+	// Defaults are the columns shown without --columns; nil shows all
+	Defaults []string
+	Rows     []outputRow
+}
+
+// Add appends a row. cells are given in the order of Columns.
+func (l *listOutput) Add(data interface{}, cells ...string) {
+	row := outputRow{Cells: make(map[string]string, len(l.Columns)), Data: data}
+	for i, column := range l.Columns {
+		if i < len(cells) {
+			row.Cells[column] = cells[i]
+		}
+	}
+	l.Rows = append(l.Rows, row)
+}
+
+// outputFlagsSet reports whether --columns or --template was given, or
+// --output json or yaml, so callers leave out their messages for people
+func outputFlagsSet(cmd *cobra.Command) bool {
+	columns, _ := cmd.Flags().GetString("columns")
+	tmpl, _ := cmd.Flags().GetString("template")
+	return columns != "" || tmpl != "" || terminal.Format() != ""
+}
+
+// printList renders a list as a table, honouring --columns and --template.
+// With --output json or yaml the rows' data is written as a list instead.
+func printList(cmd *cobra.Command, list *listOutput) error {
+	tmpl, err := outputTemplate(cmd)
+	if err != nil {
+		return err
+	}
+	if tmpl != nil {
+		for _, row := range list.Rows {
+			if err := executeTemplate(tmpl, row.Data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if format := terminal.Format(); format != "" {
+		data := make([]interface{}, len(list.Rows))
+		for i, row := range list.Rows {
+			data[i] = row.Data
+		}
+		return writeFormatted(os.Stdout, format, data)
+	}
+
+	columns, err := selectedColumns(cmd, list)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = strings.ToUpper(strings.ReplaceAll(column, "_", " "))
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+
+	for _, row := range list.Rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = valueOrDash(row.Cells[column])
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
+
+// printTemplate renders a single result with --template, or writes it with
+// --output json or yaml. It reports false when neither was given so the
+// caller prints its usual output.
+func printTemplate(cmd *cobra.Command, data interface{}) (bool, error) {
+	tmpl, err := outputTemplate(cmd)
+	if err != nil {
+		return false, err
+	}
+	if tmpl == nil {
+		if format := terminal.Format(); format != "" {
+			return true, writeFormatted(os.Stdout, format, data)
+		}
+		return false, nil
+	}
+
+	if m, ok := data.(map[string]interface{}); ok {
+		data = templateData(m)
+	}
+	return true, executeTemplate(tmpl, data)
+}
+
+// selectedColumns resolves the --columns flag against the available columns
+func selectedColumns(cmd *cobra.Command, list *listOutput) ([]string, error) {
+	value, _ := cmd.Flags().GetString("columns")
+	if value == "" {
+		if list.Defaults != nil {
+			return list.Defaults, nil
+		}
+		return list.Columns, nil
+	}
+
+	available := make(map[string]bool, len(list.Columns))
+	for _, column := range list.Columns {
+		available[column] = true
+	}
+
+	var columns []string
+	for _, column := range strings.Split(value, ",") {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !available[column] {
+			return nil, fmt.Errorf("unknown column %q (available: %s)", column, strings.Join(list.Columns, ", "))
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// outputTemplate parses the --template flag, returning nil when unset
+func outputTemplate(cmd *cobra.Command) (*template.Template, error) {
+	value, _ := cmd.Flags().GetString("template")
+	if value == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("output").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"join": strings.Join,
+	}).Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// executeTemplate renders one value followed by a newline
+func executeTemplate(tmpl *template.Template, data interface{}) error {
+	if err := tmpl.Execute(os.Stdout, data); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+	fmt.Println()
+	return nil
+}
+
+// writeFormatted writes v as JSON or YAML. YAML keeps the field names and
+// order of the JSON encoding, so both formats describe results the same way.
+func writeFormatted(w io.Writer, format string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	if format == terminal.ModeJSON {
+		_, err := fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+
+	// JSON is YAML, so decoding it keeps the order of its fields
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	blockStyle(&node)
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	return encoder.Close()
+}
+
+// blockStyle turns the JSON flow style of decoded YAML into block style,
+// quoting strings only where YAML needs it
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// templateData returns module response data with CamelCase aliases for its
+// top-level snake_case keys, so templates can use {{.FilePath}} as well as
+// {{.file_path}}
+func templateData(m map[string]interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(m)*2)
+	for key, value := range m {
+		data[key] = value
+		data[camelCase(key)] = value
+	}
+	return data
+}
+
+// camelCase converts a snake_case key to CamelCase
+func camelCase(key string) string {
+	parts := strings.Split(key, "_")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// expandedFlag returns a string flag, such as --output-dir or
+// --name-template, with its ${env:...} and ${date:...} references expanded
+func expandedFlag(cmd *cobra.Command, name string) (string, error) {
+	value, _ := cmd.Flags().GetString(name)
+	expanded, err := interpolate.Expand(value, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid --%s: %w", name, err)
+	}
+	return expanded, nil
+}
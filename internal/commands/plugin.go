@@ -0,0 +1,810 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/plugin"
+	"github.com/converso-empire/cli/pkg/stats"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+// moduleStatsRow is a module as shown by plugin stats
+type moduleStatsRow struct {
+	Module      string
+	Invocations int64
+	Failures    int64
+	FailureRate float64
+	P50         time.Duration
+	P95         time.Duration
+	P99         time.Duration
+	Bytes       int64
+	LastUsed    time.Time
+}
+
+// NewPluginCmd creates the plugin command
+func NewPluginCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	pluginCmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "Manage installed plugins",
+		Long: `Install, update and remove plugins, the Python modules that implement
+commands such as downloads, and inspect the installed ones. Plugins live in
+the plugins directory (plugins_dir, default ~/.converso/plugins).`,
+	}
+
+	// List command
+	listCmd := &cobra.Command{
+		Use:          "list",
+		Short:        "List installed plugins",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPluginList(cmd, cfg, logger)
+		},
+	}
+	pluginCmd.AddCommand(listCmd)
+
+	// Info command
+	infoCmd := &cobra.Command{
+		Use:          "info <module>",
+		Short:        "Show a plugin's manifest and where it is installed",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPluginInfo(cmd, cfg, logger, args[0])
+		},
+	}
+	pluginCmd.AddCommand(infoCmd)
+
+	// Search command
+	searchCmd := &cobra.Command{
+		Use:   "search [query]",
+		Short: "Search the plugin registry",
+		Long: `List the plugins in the registry (plugins.index_url) whose name or
+description contains the query, or all of them without one.`,
+		Example: `  converso plugin search youtube
+  converso plugin search --output json`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPluginSearch(cmd, cfg, logger, strings.Join(args, ""))
+		},
+	}
+	pluginCmd.AddCommand(searchCmd)
+
+	// Install command
+	installCmd := &cobra.Command{
+		Use:   "install <dir | name[@version]>",
+		Short: "Install a plugin from a directory or the registry",
+		Long: `Copy a plugin from a directory holding its manifest.json and __main__.py
+into the plugins directory, or download it from the plugin registry
+(plugins.index_url) by name, at its latest version unless one is given.
+Downloads are checked against the registry's SHA-256 checksums and kept in
+the data directory, so installing the same version again does not download
+it again.
+
+The plugin is installed under the name in its manifest unless --name is
+given, and is checked like any plugin on load, including its signature by
+a trusted key.`,
+		Example: `  converso plugin install ./my-module
+  converso plugin install ./checkout --name my-module
+  converso plugin install youtube
+  converso plugin install youtube@1.2.0`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, _ := cmd.Flags().GetString("name")
+			return runPluginInstall(cfg, logger, args[0], name)
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+	installCmd.Flags().String("name", "", "Install under this name instead of the manifest's")
+	pluginCmd.AddCommand(installCmd)
+
+	// Update command
+	updateCmd := &cobra.Command{
+		Use:   "update <module> <dir>",
+		Short: "Replace an installed plugin with a newer version",
+		Long: `Replace an installed plugin with the one in a directory. The installed
+version stays in place if the new one fails to load, and the plugin's
+virtual environment is kept unless the new version brings its own.`,
+		Example:      `  converso plugin update my-module ./my-module`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPluginUpdate(cfg, logger, args[0], args[1])
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+	pluginCmd.AddCommand(updateCmd)
+
+	// Uninstall command
+	uninstallCmd := &cobra.Command{
+		Use:          "uninstall <module>",
+		Aliases:      []string{"remove"},
+		Short:        "Remove an installed plugin",
+		Long:         `Remove a plugin and its directory, including its virtual environment.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			force, _ := cmd.Flags().GetBool("force")
+			return runPluginUninstall(cfg, logger, args[0], force)
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+	uninstallCmd.Flags().BoolP("force", "f", false, "Remove without asking for confirmation")
+	pluginCmd.AddCommand(uninstallCmd)
+
+	// Stats command
+	statsCmd := &cobra.Command{
+		Use:   "stats [module]",
+		Short: "Show per-module invocation statistics",
+		Long: `Show how often each module ran, how often it failed, its latency percentiles
+over the last 500 runs and the bytes of output files it produced. The
+statistics are kept locally in the data directory and are included in the
+worker's status reports.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reset, _ := cmd.Flags().GetBool("reset")
+			store := stats.NewStore(stats.DefaultPath(cfg))
+			if reset {
+				if err := store.Reset(args...); err != nil {
+					return err
+				}
+				fmt.Println("🗑️  Module statistics reset")
+				return nil
+			}
+			return runPluginStats(cmd, store, args)
+		},
+	}
+	statsCmd.Flags().Bool("reset", false, "Clear the statistics of the module, or of all modules")
+	pluginCmd.AddCommand(statsCmd)
+
+	// Deps command
+	depsCmd := &cobra.Command{
+		Use:   "deps [module]",
+		Short: "Check module dependencies for newer releases",
+		Long: `Compare the Python packages modules depend on, such as yt-dlp for the
+YouTube module, with the latest releases on the package index. Sites like
+YouTube change often and break older yt-dlp releases, so module commands
+also warn when a dependency is outdated, checking at most once a day.
+Turn that off with dependencies.check: false in the config.
+
+Dependencies that are not Python packages, such as ffmpeg, are not checked.`,
+		Example: `  converso plugin deps
+  converso plugin deps upgrade youtube`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPluginDeps(cmd, cfg, logger, args)
+		},
+	}
+	pluginCmd.AddCommand(depsCmd)
+
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade <module> [package...]",
+		Short: "Upgrade a module's dependencies in its virtual environment",
+		Long: `Install the latest releases of a module's Python dependencies, or only of
+the given packages, into the module's virtual environment. A module without
+one gets a virtual environment in its directory that still sees the system
+packages, and runs with it from then on.`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPluginDepsUpgrade(cfg, logger, args[0], args[1:])
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+	depsCmd.AddCommand(upgradeCmd)
+
+	pluginCmd.AddCommand(newPluginKeysCmd(cfg))
+
+	return pluginCmd
+}
+
+// runPluginList prints the installed modules
+func runPluginList(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) error {
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	list := &listOutput{
+		Columns:  []string{"name", "version", "commands", "description", "author", "path", "signed"},
+		Defaults: []string{"name", "version", "commands", "description"},
+	}
+	for _, module := range registry.ListModules() {
+		manifest := module.Manifest
+		signed := "no"
+		if module.Signature != "" {
+			signed = "yes"
+		}
+		list.Add(module,
+			manifest.Name,
+			manifest.Version,
+			strings.Join(manifest.Commands, ", "),
+			manifest.Description,
+			valueOrDash(manifest.Author),
+			module.Path,
+			signed)
+	}
+
+	if len(list.Rows) == 0 && !outputFlagsSet(cmd) {
+		fmt.Printf("No plugins installed in %s. Install one with 'converso plugin install <dir>'.\n", cfg.PluginsDir)
+		return nil
+	}
+	return printList(cmd, list)
+}
+
+// runPluginInfo prints a module's manifest and installation
+func runPluginInfo(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger, name string) error {
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+	module, err := registry.GetModuleInfo(name)
+	if err != nil {
+		return err
+	}
+	if printed, err := printTemplate(cmd, module); printed || err != nil {
+		return err
+	}
+
+	manifest := module.Manifest
+	fmt.Printf("🧩 %s %s\n", manifest.Name, manifest.Version)
+	fmt.Println("=========================")
+	if manifest.Description != "" {
+		fmt.Printf("Description:  %s\n", manifest.Description)
+	}
+	if manifest.Author != "" {
+		fmt.Printf("Author:       %s\n", manifest.Author)
+	}
+	if manifest.License != "" {
+		fmt.Printf("License:      %s\n", manifest.License)
+	}
+	fmt.Printf("Commands:     %s\n", strings.Join(manifest.Commands, ", "))
+	if manifest.DefaultCommand != "" {
+		fmt.Printf("Default:      %s\n", manifest.DefaultCommand)
+	}
+	if len(manifest.URLPatterns) > 0 {
+		fmt.Printf("URLs:         %s\n", strings.Join(manifest.URLPatterns, ", "))
+	}
+	if len(manifest.Options) > 0 {
+		fmt.Printf("Options:      %s\n", strings.Join(manifest.Options, ", "))
+	}
+	if len(manifest.Dependencies) > 0 {
+		fmt.Printf("Dependencies: %s\n", strings.Join(manifest.Dependencies, ", "))
+	}
+	fmt.Printf("Path:         %s\n", module.Path)
+	if module.SignedBy != "" {
+		fmt.Printf("Signed by:    %s\n", module.SignedBy)
+	}
+	return nil
+}
+
+// trustedKey is a module signing key as shown by plugin keys list
+type trustedKey struct {
+	Key string
+	// Source is "config" or "policy"
+	Source string
+}
+
+// newPluginKeysCmd creates the plugin keys command
+func newPluginKeysCmd(cfg *config.Config) *cobra.Command {
+	keysCmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage the keys trusted to sign plugins",
+		Long: fmt.Sprintf(`Manage the Ed25519 public keys trusted to sign plugins. Plugins are signed
+with 'converso dev sign-module', which writes %s over the manifest and all
+other plugin files. Plugins must be signed by a key trusted here or in the
+administrator policy, or by the key of the plugins bundled with the CLI, to
+load. --allow-unsigned loads the others anyway, unless the policy requires
+signed plugins.`, plugin.SignatureFile),
+	}
+
+	keysCmd.AddCommand(&cobra.Command{
+		Use:          "list",
+		Short:        "List the trusted signing keys",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPluginKeysList(cmd, cfg)
+		},
+	})
+
+	keysCmd.AddCommand(&cobra.Command{
+		Use:          "add <public-key>",
+		Short:        "Trust a key to sign plugins",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPluginKeysAdd(cfg, args[0])
+		},
+		Annotations: changesSetupAnnotations(""),
+	})
+
+	keysCmd.AddCommand(&cobra.Command{
+		Use:          "remove <public-key>",
+		Short:        "Stop trusting a key to sign plugins",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPluginKeysRemove(cfg, args[0])
+		},
+		Annotations: changesSetupAnnotations(""),
+	})
+
+	return keysCmd
+}
+
+// runPluginKeysList prints the keys trusted by the config and the policy
+func runPluginKeysList(cmd *cobra.Command, cfg *config.Config) error {
+	list := &listOutput{Columns: []string{"key", "source"}}
+	for _, key := range cfg.Plugins.TrustedKeys {
+		list.Add(trustedKey{Key: key, Source: "config"}, key, "config")
+	}
+	if cfg.Policy != nil {
+		for _, key := range cfg.Policy.PluginSigningKeys {
+			list.Add(trustedKey{Key: key, Source: "policy"}, key, "policy")
+		}
+	}
+
+	if len(list.Rows) == 0 && !outputFlagsSet(cmd) {
+		fmt.Println("Only the bundled plugins' key is trusted; other plugins need --allow-unsigned. Trust their signers with 'converso plugin keys add'.")
+		return nil
+	}
+	return printList(cmd, list)
+}
+
+// runPluginKeysAdd trusts a key to sign plugins
+func runPluginKeysAdd(cfg *config.Config, key string) error {
+	key = strings.TrimSpace(key)
+	if _, err := plugin.ParsePublicKey(key); err != nil {
+		return err
+	}
+	for _, trusted := range cfg.Plugins.TrustedKeys {
+		if trusted == key {
+			return fmt.Errorf("key %s is already trusted", key)
+		}
+	}
+
+	cfg.Plugins.TrustedKeys = append(cfg.Plugins.TrustedKeys, key)
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	fmt.Printf("✅ Trusted key %s\n", key)
+	return nil
+}
+
+// runPluginKeysRemove stops trusting a key set in the config
+func runPluginKeysRemove(cfg *config.Config, key string) error {
+	key = strings.TrimSpace(key)
+	for i, trusted := range cfg.Plugins.TrustedKeys {
+		if trusted != key {
+			continue
+		}
+		cfg.Plugins.TrustedKeys = append(cfg.Plugins.TrustedKeys[:i], cfg.Plugins.TrustedKeys[i+1:]...)
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+		fmt.Printf("✅ Key %s is no longer trusted\n", key)
+		return nil
+	}
+
+	if cfg.Policy != nil {
+		for _, trusted := range cfg.Policy.PluginSigningKeys {
+			if trusted == key {
+				return fmt.Errorf("key %s is trusted by the policy %s and cannot be removed here", key, cfg.Policy.Path)
+			}
+		}
+	}
+	return fmt.Errorf("key %s is not trusted", key)
+}
+
+// runPluginSearch prints the plugins of the registry matching a query
+func runPluginSearch(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger, query string) error {
+	matches, err := plugin.NewIndexClient(cfg, logger).Search(query)
+	if err != nil {
+		return err
+	}
+
+	installed := make(map[string]string)
+	if registry, err := newPluginRegistry(cfg, logger); err == nil {
+		for _, module := range registry.ListModules() {
+			installed[module.Manifest.Name] = module.Manifest.Version
+		}
+	}
+
+	list := &listOutput{
+		Columns:  []string{"name", "latest", "installed", "description", "author", "versions"},
+		Defaults: []string{"name", "latest", "installed", "description"},
+	}
+	for _, p := range matches {
+		latest := "-"
+		if release := p.Latest(); release != nil {
+			latest = release.Version
+		}
+		var versions []string
+		for _, release := range p.Releases {
+			versions = append(versions, release.Version)
+		}
+		list.Add(p,
+			p.Name,
+			latest,
+			valueOrDash(installed[p.Name]),
+			p.Description,
+			valueOrDash(p.Author),
+			strings.Join(versions, ", "))
+	}
+
+	if len(list.Rows) == 0 && !outputFlagsSet(cmd) {
+		if query == "" {
+			fmt.Println("The plugin registry lists no plugins.")
+		} else {
+			fmt.Printf("No plugins in the registry match %q.\n", query)
+		}
+		return nil
+	}
+	return printList(cmd, list)
+}
+
+// runPluginInstall installs a module from a directory, or from the
+// registry when source names a plugin rather than a directory
+func runPluginInstall(cfg *config.Config, logger telemetry.Logger, source, name string) error {
+	if isRegistryPlugin(source) {
+		return runPluginInstallFromRegistry(cfg, logger, source, name)
+	}
+	if err := checkPluginSource(source); err != nil {
+		return err
+	}
+	if name == "" {
+		var err error
+		if name, err = sourceModuleName(source); err != nil {
+			return err
+		}
+	}
+	return installPlugin(cfg, logger, name, source)
+}
+
+// runPluginInstallFromRegistry downloads a plugin given as name[@version]
+// from the registry and installs it
+func runPluginInstallFromRegistry(cfg *config.Config, logger telemetry.Logger, ref, name string) error {
+	module, version, _ := strings.Cut(ref, "@")
+	if name == "" {
+		name = module
+	}
+
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+	if _, err := registry.GetModuleInfo(name); err == nil {
+		return fmt.Errorf("module %s is already installed. Uninstall it first to install it from the registry", name)
+	}
+
+	client := plugin.NewIndexClient(cfg, logger)
+	entry, err := client.Lookup(module)
+	if err != nil {
+		return err
+	}
+	release, err := entry.Release(version)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("⬇️  Downloading %s %s\n", entry.Name, release.Version)
+	dir, source, err := client.Download(entry, release)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", entry.Name, err)
+	}
+	defer os.RemoveAll(dir)
+
+	return installPlugin(cfg, logger, name, source)
+}
+
+// installPlugin copies the module in source into the plugins directory
+// under name
+func installPlugin(cfg *config.Config, logger telemetry.Logger, name, source string) error {
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid module name %q", name)
+	}
+
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+	if _, err := registry.GetModuleInfo(name); err == nil {
+		return fmt.Errorf("module %s is already installed. Use 'converso plugin update %s %s' to replace it", name, name, source)
+	}
+	if err := registry.InstallModule(name, source); err != nil {
+		return fmt.Errorf("failed to install %s: %w", name, err)
+	}
+
+	module, err := registry.GetModuleInfo(name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Installed %s %s\n", name, module.Manifest.Version)
+	if len(module.Manifest.Dependencies) > 0 {
+		fmt.Printf("💡 It needs %s. Run 'converso plugin deps %s' to check them.\n", strings.Join(module.Manifest.Dependencies, ", "), name)
+	}
+	return nil
+}
+
+// runPluginUpdate replaces an installed module with the one in a directory
+func runPluginUpdate(cfg *config.Config, logger telemetry.Logger, name, source string) error {
+	if err := checkPluginSource(source); err != nil {
+		return err
+	}
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+	previous, err := registry.GetModuleInfo(name)
+	if err != nil {
+		return err
+	}
+	previousVersion := previous.Manifest.Version
+
+	if err := registry.UpdateModule(name, source); err != nil {
+		return fmt.Errorf("failed to update %s: %w", name, err)
+	}
+	module, err := registry.GetModuleInfo(name)
+	if err != nil {
+		return err
+	}
+	if module.Manifest.Version == previousVersion {
+		fmt.Printf("✅ Reinstalled %s %s\n", name, previousVersion)
+		return nil
+	}
+	fmt.Printf("✅ Updated %s from %s to %s\n", name, previousVersion, module.Manifest.Version)
+	return nil
+}
+
+// runPluginUninstall removes an installed module after confirmation
+func runPluginUninstall(cfg *config.Config, logger telemetry.Logger, name string, force bool) error {
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+	module, err := registry.GetModuleInfo(name)
+	if err != nil {
+		return err
+	}
+
+	if !force {
+		if !canPrompt(cfg) {
+			return fmt.Errorf("use --force to uninstall %s without confirmation", name)
+		}
+		fmt.Printf("Remove %s %s and %s? [y/N]: ", name, module.Manifest.Version, module.Path)
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" && response != "yes" {
+			fmt.Println("Uninstall cancelled.")
+			return nil
+		}
+	}
+
+	if err := registry.UninstallModule(name); err != nil {
+		return fmt.Errorf("failed to uninstall %s: %w", name, err)
+	}
+	fmt.Printf("🗑️  Uninstalled %s\n", name)
+	return nil
+}
+
+// isRegistryPlugin reports whether an install source names a plugin of the
+// registry, such as youtube or youtube@1.2.0, rather than a directory
+func isRegistryPlugin(source string) bool {
+	if strings.ContainsAny(source, `/\:`) || strings.HasPrefix(source, ".") {
+		return false
+	}
+	_, err := os.Stat(source)
+	return os.IsNotExist(err)
+}
+
+// checkPluginSource checks that a plugin is installed from a directory
+func checkPluginSource(source string) error {
+	if strings.Contains(source, "://") {
+		return fmt.Errorf("installing from URLs is not supported. Install registry plugins by name, or download and unpack the plugin and install it from its directory")
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("failed to read plugin source: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory. Unpack the plugin and install it from its directory", source)
+	}
+	return nil
+}
+
+// sourceModuleName returns the name in the manifest of the plugin in dir
+func sourceModuleName(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read plugin manifest: %w", err)
+	}
+	var manifest bridge.ModuleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", fmt.Errorf("failed to parse plugin manifest: %w", err)
+	}
+	if manifest.Name == "" {
+		return "", fmt.Errorf("plugin manifest has no name. Use --name to choose one")
+	}
+	return manifest.Name, nil
+}
+
+// runPluginDeps prints the dependencies of modules with their latest
+// releases
+func runPluginDeps(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger, args []string) error {
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+	modules := registry.ListModules()
+	if len(args) > 0 {
+		module, err := registry.GetModuleInfo(args[0])
+		if err != nil {
+			return err
+		}
+		modules = []*plugin.ModuleInfo{module}
+	}
+
+	checker := plugin.NewDependencyChecker(cfg, logger)
+	list := &listOutput{
+		Columns:  []string{"module", "package", "installed", "latest", "status"},
+		Defaults: []string{"module", "package", "installed", "latest", "status"},
+	}
+	var outdated []string
+	for _, module := range modules {
+		deps, err := checker.Check(module, true)
+		if err != nil {
+			logger.Warn("Failed to check module dependencies", "module", module.Manifest.Name, "error", err)
+			continue
+		}
+		for _, dep := range deps {
+			status := "up to date"
+			switch {
+			case dep.Error != "":
+				status = "unknown: " + dep.Error
+			case dep.Outdated():
+				status = "outdated"
+				if len(outdated) == 0 || outdated[len(outdated)-1] != dep.Module {
+					outdated = append(outdated, dep.Module)
+				}
+			}
+			list.Add(dep, dep.Module, dep.Package, dep.Installed, valueOrDash(dep.Latest), status)
+		}
+	}
+
+	if len(list.Rows) == 0 && !outputFlagsSet(cmd) {
+		fmt.Println("No installed Python dependencies to check.")
+		return nil
+	}
+	if err := printList(cmd, list); err != nil {
+		return err
+	}
+	if !outputFlagsSet(cmd) {
+		for _, module := range outdated {
+			fmt.Printf("\n💡 Run 'converso plugin deps upgrade %s' to update\n", module)
+		}
+	}
+	return nil
+}
+
+// runPluginDepsUpgrade upgrades a module's dependencies
+func runPluginDepsUpgrade(cfg *config.Config, logger telemetry.Logger, name string, packages []string) error {
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+	module, err := registry.GetModuleInfo(name)
+	if err != nil {
+		return err
+	}
+
+	if len(packages) == 0 {
+		packages = module.Manifest.Dependencies
+	}
+	if len(packages) == 0 {
+		return fmt.Errorf("module %s has no dependencies", name)
+	}
+
+	checker := plugin.NewDependencyChecker(cfg, logger)
+	if err := checker.Upgrade(module, packages, os.Stderr); err != nil {
+		return fmt.Errorf("failed to upgrade dependencies of %s: %w", name, err)
+	}
+
+	fmt.Printf("✅ Upgraded dependencies of %s\n", name)
+	return nil
+}
+
+// warnOutdatedDeps prints a warning for each outdated dependency of a
+// module, using a check at most a day old. It never fails the command.
+func warnOutdatedDeps(cfg *config.Config, logger telemetry.Logger, module *plugin.ModuleInfo) {
+	if !cfg.Dependencies.Check {
+		return
+	}
+	deps, err := plugin.NewDependencyChecker(cfg, logger).Check(module, false)
+	if err != nil {
+		logger.Debug("Failed to check module dependencies", "module", module.Manifest.Name, "error", err)
+		return
+	}
+	for _, dep := range deps {
+		if dep.Outdated() {
+			fmt.Fprintf(os.Stderr, "⚠️  %s %s is outdated (latest %s) and may fail on recent site changes. Run 'converso plugin deps upgrade %s' to update.\n",
+				dep.Package, dep.Installed, dep.Latest, dep.Module)
+		}
+	}
+}
+
+// runPluginStats prints the module statistics
+func runPluginStats(cmd *cobra.Command, store *stats.Store, args []string) error {
+	all, err := store.All()
+	if err != nil {
+		return err
+	}
+
+	modules := make([]string, 0, len(all))
+	for module := range all {
+		if len(args) == 0 || args[0] == module {
+			modules = append(modules, module)
+		}
+	}
+	sort.Strings(modules)
+
+	list := &listOutput{
+		Columns:  []string{"module", "calls", "failures", "failure_rate", "p50", "p95", "p99", "bytes", "last_used"},
+		Defaults: []string{"module", "calls", "failure_rate", "p50", "p95", "p99", "bytes", "last_used"},
+	}
+	for _, module := range modules {
+		m := all[module]
+		row := moduleStatsRow{
+			Module:      module,
+			Invocations: m.Invocations,
+			Failures:    m.Failures,
+			FailureRate: m.FailureRate(),
+			P50:         m.Percentile(50),
+			P95:         m.Percentile(95),
+			P99:         m.Percentile(99),
+			Bytes:       m.Bytes,
+			LastUsed:    m.LastUsed,
+		}
+		list.Add(row,
+			module,
+			fmt.Sprint(row.Invocations),
+			fmt.Sprint(row.Failures),
+			terminal.FormatFloat(row.FailureRate*100, 1)+"%",
+			formatLatency(row.P50),
+			formatLatency(row.P95),
+			formatLatency(row.P99),
+			formatFileSize(row.Bytes),
+			terminal.FormatDateTime(row.LastUsed.Local()))
+	}
+
+	if len(list.Rows) == 0 && !outputFlagsSet(cmd) {
+		fmt.Println("No module statistics recorded yet.")
+		return nil
+	}
+	return printList(cmd, list)
+}
+
+// formatLatency renders a latency with precision suited to its size
+func formatLatency(d time.Duration) string {
+	if d < time.Millisecond {
+		return terminal.FormatDuration(d.Round(time.Microsecond))
+	}
+	if d < time.Second {
+		return terminal.FormatDuration(d.Round(time.Millisecond))
+	}
+	return terminal.FormatDuration(d.Round(100 * time.Millisecond))
+}
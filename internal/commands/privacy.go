@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/httpclient"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/spf13/cobra"
+)
+
+// auditedEndpoint is an endpoint as shown by privacy audit
+type auditedEndpoint struct {
+	Name    string
+	Method  string
+	URL     string
+	Purpose string
+	Data    []string
+	Mutates bool
+}
+
+// NewPrivacyCmd creates the privacy command
+func NewPrivacyCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	privacyCmd := &cobra.Command{
+		Use:   "privacy",
+		Short: "Inspect what data leaves this machine",
+	}
+
+	// Audit command
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "List the network endpoints the CLI contacts and the data it sends",
+		Long: `List every network endpoint the CLI contacts, resolved against the current
+configuration, with the categories of data sent to it and the current
+telemetry settings. The list comes from the same endpoint registry the HTTP
+client checks every request against.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPrivacyAudit(cmd, cfg)
+		},
+	}
+	privacyCmd.AddCommand(auditCmd)
+
+	return privacyCmd
+}
+
+// runPrivacyAudit prints the endpoint registry and telemetry settings
+func runPrivacyAudit(cmd *cobra.Command, cfg *config.Config) error {
+	list := &listOutput{
+		Columns:  []string{"name", "method", "url", "data", "purpose"},
+		Defaults: []string{"method", "url", "data", "purpose"},
+	}
+	for _, e := range httpclient.Endpoints {
+		entry := auditedEndpoint{
+			Name:    e.Name,
+			Method:  e.Method,
+			URL:     e.Template(cfg),
+			Purpose: e.Purpose,
+			Mutates: e.Mutates,
+		}
+		for _, category := range e.Data {
+			entry.Data = append(entry.Data, string(category))
+		}
+		list.Add(entry, entry.Name, entry.Method, entry.URL, strings.Join(entry.Data, ", "), entry.Purpose)
+	}
+
+	if outputFlagsSet(cmd) {
+		return printList(cmd, list)
+	}
+
+	fmt.Println("🔒 Network Endpoints")
+	fmt.Println("====================")
+	if err := printList(cmd, list); err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Println("Plugins contact the sites of the URLs you pass them (e.g. youtube.com).")
+	fmt.Println("That traffic goes directly from the plugin and is not listed above.")
+
+	fmt.Println()
+	fmt.Println("📊 Telemetry")
+	fmt.Println("============")
+	fmt.Println("Usage analytics:   none collected")
+	fmt.Printf("Logging:           local only (stderr), debug %s\n", onOff(cfg.Debug))
+	if cfg.Policy.TelemetryDisabled() {
+		fmt.Println("Worker reports:    job status and progress only; worker status reports disabled by policy")
+	} else {
+		fmt.Println("Worker reports:    worker status every 5m and job status and progress, only while 'converso worker start' runs")
+	}
+	fmt.Printf("Dry run API:       %s (--dry-run-api logs backend changes instead of sending them)\n", onOff(cfg.DryRunAPI))
+	if cfg.Policy != nil {
+		fmt.Printf("Policy:            %s\n", cfg.Policy.Path)
+	}
+	return nil
+}
+
+// onOff renders a boolean setting
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
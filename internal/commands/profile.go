@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/profiling"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// FinishProfile ends a --profile session, writing its profiles and
+// printing the phase timings to stderr so command output stays clean
+func FinishProfile(logger telemetry.Logger) {
+	report, err := profiling.Stop()
+	if err != nil {
+		logger.Warn("Failed to write profile", "error", err)
+	}
+	if report == nil {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "\n⏱️  Profile of '%s': %s total\n", report.Command, formatLatency(report.Total))
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PHASE\tCALLS\tTIME\tSHARE")
+
+	var accounted time.Duration
+	for _, phase := range report.Phases {
+		accounted += phase.Total
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", phase.Name, phase.Count, formatLatency(phase.Total), share(phase.Total, report.Total))
+	}
+	if other := report.Total - accounted; other > 0 {
+		fmt.Fprintf(w, "other\t-\t%s\t%s\n", formatLatency(other), share(other, report.Total))
+	}
+	w.Flush()
+
+	fmt.Fprintf(os.Stderr, "Profiles written to %s (inspect with 'go tool pprof')\n", report.Dir)
+}
+
+// share renders part as a percentage of total
+func share(part, total time.Duration) string {
+	if total <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", float64(part)/float64(total)*100)
+}
@@ -0,0 +1,304 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/redact"
+	"github.com/converso-empire/cli/pkg/terminal"
+)
+
+const (
+	// progressBarWidth is the width of rendered progress bars in cells
+	progressBarWidth = 30
+
+	// progressLogInterval throttles progress lines when output is not a terminal
+	progressLogInterval = 5 * time.Second
+)
+
+// activeProgress is the renderer drawing the foreground command's progress,
+// which module log lines are printed above
+var (
+	activeProgress   *progressRenderer
+	activeProgressMu sync.Mutex
+)
+
+// progressRenderer draws progress events from a module. A single operation
+// is rendered as one bar updated in place; concurrent items get one bar each
+// plus an aggregate line. When stdout is not a terminal it falls back to
+// plain lines printed at most every progressLogInterval. Plain output for
+// scripts gets the lines without bars on stderr.
+type progressRenderer struct {
+	mu    sync.Mutex
+	out   *os.File
+	tty   bool
+	plain bool
+	// total is the number of items expected, counting those not started
+	// yet; 0 when unknown. With a total, finished items drop their bar and
+	// only count in the aggregate line.
+	total   int
+	items   map[string]*bridge.ProgressEvent
+	order   []string
+	drawn   int
+	stage   string
+	last    *bridge.ProgressEvent
+	logged  time.Time
+	started bool
+}
+
+// newProgressRenderer creates a renderer writing to out
+func newProgressRenderer(out *os.File) *progressRenderer {
+	plain := terminal.Plain()
+	return &progressRenderer{
+		out:   out,
+		tty:   !plain && terminal.IsTerminal(out),
+		plain: plain,
+		items: make(map[string]*bridge.ProgressEvent),
+	}
+}
+
+// watchProgress renders events from progressChan until it is closed. The
+// returned channel is closed once the last event has been drawn.
+func watchProgress(progressChan <-chan *bridge.ProgressEvent) <-chan struct{} {
+	return watchItemsProgress(progressChan, 0)
+}
+
+// watchItemsProgress renders the progress of total items like
+// watchProgress, aggregating it over all of them, not only those started
+func watchItemsProgress(progressChan <-chan *bridge.ProgressEvent, total int) <-chan struct{} {
+	done := make(chan struct{})
+
+	// Keep stdout for results when it is read by a script
+	out := os.Stdout
+	if terminal.Plain() {
+		out = os.Stderr
+	}
+	renderer := newProgressRenderer(out)
+	renderer.total = total
+	activeProgressMu.Lock()
+	activeProgress = renderer
+	activeProgressMu.Unlock()
+
+	go func() {
+		defer close(done)
+		for progress := range progressChan {
+			renderer.mu.Lock()
+			renderer.Render(progress)
+			renderer.mu.Unlock()
+		}
+
+		activeProgressMu.Lock()
+		if activeProgress == renderer {
+			activeProgress = nil
+		}
+		activeProgressMu.Unlock()
+		renderer.mu.Lock()
+		renderer.Finish()
+		renderer.mu.Unlock()
+	}()
+
+	return done
+}
+
+// Render draws a progress event
+func (r *progressRenderer) Render(progress *bridge.ProgressEvent) {
+	r.started = true
+	if progress.Item == "" {
+		r.renderSingle(progress)
+		return
+	}
+
+	if _, ok := r.items[progress.Item]; !ok {
+		r.order = append(r.order, progress.Item)
+	}
+	r.items[progress.Item] = progress
+
+	if r.tty {
+		r.redrawItems()
+		return
+	}
+
+	// Always log item completion, otherwise respect the log interval
+	if progress.Overall < 100 && time.Since(r.logged) < progressLogInterval {
+		return
+	}
+	r.logged = time.Now()
+	fmt.Fprintln(r.out, r.progressLine(progress.Item, progress))
+	fmt.Fprintln(r.out, r.aggregateLine())
+}
+
+// Finish terminates the in-place output so following output starts on a new line
+func (r *progressRenderer) Finish() {
+	if r.tty && r.started && len(r.items) == 0 {
+		fmt.Fprintln(r.out)
+	}
+}
+
+// Log prints a line above the progress drawn in place, which is redrawn
+// below it
+func (r *progressRenderer) Log(line string) {
+	if !r.tty {
+		fmt.Fprintln(r.out, line)
+		return
+	}
+
+	if len(r.items) > 0 {
+		if r.drawn > 0 {
+			fmt.Fprintf(r.out, "\033[%dA", r.drawn)
+		}
+		fmt.Fprintf(r.out, "\r%s\033[K\n", line)
+		r.drawn = 0
+		r.redrawItems()
+		return
+	}
+
+	fmt.Fprintf(r.out, "\r%s\033[K\n", line)
+	if r.last != nil {
+		fmt.Fprintf(r.out, "\r%s\033[K", r.progressLine(stageLabel(r.last), r.last))
+	}
+}
+
+// renderSingle draws the progress of a single operation
+func (r *progressRenderer) renderSingle(progress *bridge.ProgressEvent) {
+	r.last = progress
+	line := r.progressLine(stageLabel(progress), progress)
+	if r.tty {
+		fmt.Fprintf(r.out, "\r%s\033[K", line)
+		return
+	}
+
+	// Log stage changes immediately, otherwise respect the log interval
+	if progress.Stage == r.stage && progress.Overall < 100 && time.Since(r.logged) < progressLogInterval {
+		return
+	}
+	r.stage = progress.Stage
+	r.logged = time.Now()
+	fmt.Fprintln(r.out, line)
+}
+
+// redrawItems redraws the per-item bars and the aggregate line in place
+func (r *progressRenderer) redrawItems() {
+	if r.drawn > 0 {
+		fmt.Fprintf(r.out, "\033[%dA", r.drawn)
+	}
+
+	drawn := 0
+	for _, item := range r.order {
+		if r.total > 0 && r.items[item].Overall >= 100 {
+			continue
+		}
+		fmt.Fprintf(r.out, "\r%s\033[K\n", r.progressLine(item, r.items[item]))
+		drawn++
+	}
+	// Clearing below removes the bars of items finished since the last draw
+	fmt.Fprintf(r.out, "\r%s\033[K\n\033[J", r.aggregateLine())
+
+	r.drawn = drawn + 1
+}
+
+// aggregateLine summarises the progress of all items
+func (r *progressRenderer) aggregateLine() string {
+	var sum float64
+	completed := 0
+	for _, item := range r.order {
+		overall := r.items[item].Overall
+		sum += overall
+		if overall >= 100 {
+			completed++
+		}
+	}
+
+	count := len(r.order)
+	if r.total > count {
+		count = r.total
+	}
+	average := sum / float64(count)
+	if r.plain {
+		return fmt.Sprintf("Total %d%% %d/%d items complete", int(average), completed, count)
+	}
+	return fmt.Sprintf("Total [%s] %3d%% %d/%d items complete", progressBar(average), int(average), completed, count)
+}
+
+// progressLine formats one progress bar with its label
+func (r *progressRenderer) progressLine(label string, progress *bridge.ProgressEvent) string {
+	// Module is alive but has not reported progress for a while
+	if progress.StalledFor > 0 {
+		if r.plain {
+			return fmt.Sprintf("%s still working (no progress for %s) %s", label, progress.StalledFor.Round(time.Second), progress.Message)
+		}
+		return fmt.Sprintf("%s ⏳ still working (no progress for %s) %s", label, progress.StalledFor.Round(time.Second), progress.Message)
+	}
+
+	if r.plain {
+		return strings.TrimSpace(fmt.Sprintf("%s %d%% %s", label, int(progress.Overall), progress.Message))
+	}
+	return fmt.Sprintf("%s [%s] %3d%% %s", label, progressBar(progress.Overall), int(progress.Overall), progress.Message)
+}
+
+// stageLabel labels the current stage with its position in multi-stage operations
+func stageLabel(progress *bridge.ProgressEvent) string {
+	if progress.StageCount > 1 && progress.StageIndex > 0 {
+		return fmt.Sprintf("%s (%d/%d)", progress.Stage, progress.StageIndex, progress.StageCount)
+	}
+	return progress.Stage
+}
+
+// progressBar renders a bar filled to the given percentage
+func progressBar(percentage float64) string {
+	filled := int(float64(progressBarWidth) * percentage / 100)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+	if filled < 0 {
+		filled = 0
+	}
+	return strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+}
+
+// moduleLogPrinter shows the log lines of modules, with secrets masked,
+// above the progress of the foreground command
+func moduleLogPrinter(masker *redact.Masker) bridge.LogHandler {
+	return func(module string, entry *bridge.LogEntry) {
+		line := masker.String(moduleLogLine(module, entry))
+
+		activeProgressMu.Lock()
+		renderer := activeProgress
+		activeProgressMu.Unlock()
+		if renderer == nil {
+			fmt.Fprintln(os.Stderr, line)
+			return
+		}
+		renderer.mu.Lock()
+		defer renderer.mu.Unlock()
+		renderer.Log(line)
+	}
+}
+
+// moduleLogLine formats a module log line with its fields
+func moduleLogLine(module string, entry *bridge.LogEntry) string {
+	var b strings.Builder
+	switch entry.Level {
+	case bridge.LogLevelWarn:
+		b.WriteString("⚠️  ")
+	case bridge.LogLevelError:
+		b.WriteString("❌ ")
+	default:
+		b.WriteString("   ")
+	}
+	fmt.Fprintf(&b, "[%s] %s", module, entry.Message)
+
+	keys := make([]string, 0, len(entry.Fields))
+	for key := range entry.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s=%v", key, entry.Fields[key])
+	}
+	return b.String()
+}
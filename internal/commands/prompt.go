@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+)
+
+// stdinBytes delivers what is typed at the terminal. A single reader serves
+// every module prompt and the keys controlling a command, so a prompt that
+// timed out does not leave a read behind that swallows the answer to the
+// next one.
+var (
+	stdinBytes     chan byte
+	stdinBytesOnce sync.Once
+)
+
+// modulePrompter returns the prompter modules ask the user questions
+// through, or nil when nobody can answer them
+func modulePrompter(cfg *config.Config) bridge.Prompter {
+	if !canPrompt(cfg) {
+		return nil
+	}
+	return askModulePrompt
+}
+
+// askModulePrompt asks a module's question on the terminal until the
+// answer is valid or ctx is done
+func askModulePrompt(ctx context.Context, module string, prompt *bridge.Prompt) (string, error) {
+	// Start below an in-place progress bar
+	fmt.Fprintf(os.Stderr, "\n❓ %s asks: %s\n", module, prompt.Message)
+	for i, choice := range prompt.Choices {
+		fmt.Fprintf(os.Stderr, "  [%d] %s\n", i+1, choice)
+	}
+
+	// Typed answers need the terminal back in line mode
+	defer holdKeys()()
+
+	label := "> "
+	if prompt.Default != "" && !prompt.Secret {
+		label = fmt.Sprintf("[%s] > ", prompt.Default)
+	}
+	for {
+		fmt.Fprint(os.Stderr, label)
+		line, err := readPromptLine(ctx, prompt.Secret)
+		if err != nil {
+			fmt.Fprintln(os.Stderr)
+			return "", err
+		}
+		answer, err := prompt.Choose(line)
+		if err == nil {
+			return answer, nil
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+	}
+}
+
+// readPromptLine reads the next line typed at the terminal, without echo
+// for secrets
+func readPromptLine(ctx context.Context, secret bool) (string, error) {
+	input := terminalInput()
+
+	if secret {
+		restore := disableEcho()
+		defer fmt.Fprintln(os.Stderr)
+		defer restore()
+	}
+
+	// Interrupting gives up on the prompt, restoring echo, while the module
+	// is cancelled through the command's context
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	defer signal.Stop(interrupted)
+
+	var line []byte
+	for {
+		select {
+		case b, ok := <-input:
+			if !ok && len(line) == 0 {
+				return "", fmt.Errorf("input closed before an answer was given")
+			}
+			if !ok || b == '\n' {
+				return strings.TrimRight(string(line), "\r"), nil
+			}
+			line = append(line, b)
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-interrupted:
+			return "", fmt.Errorf("interrupted at the prompt")
+		}
+	}
+}
+
+// terminalInput returns the bytes typed at the terminal, starting the
+// reader on first use
+func terminalInput() <-chan byte {
+	stdinBytesOnce.Do(func() {
+		stdinBytes = make(chan byte)
+		go func() {
+			defer close(stdinBytes)
+			reader := bufio.NewReader(os.Stdin)
+			for {
+				b, err := reader.ReadByte()
+				if err != nil {
+					return
+				}
+				stdinBytes <- b
+			}
+		}()
+	})
+	return stdinBytes
+}
@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/converso-empire/cli/pkg/worker"
+	"github.com/spf13/cobra"
+)
+
+// NewQueueCmd creates the queue command
+func NewQueueCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	queueCmd := &cobra.Command{
+		Use:   "queue",
+		Short: "Reorder or drop the jobs waiting on a worker",
+		Long: `Show the jobs a running worker has queued but not started, in the order
+they run, and reprioritize or drop them without cancelling everything.
+Jobs already running are not affected; see 'converso jobs' for all jobs.
+
+Like 'converso jobs', this talks to the worker's control API on this
+machine, or with --remote on another one.
+
+Examples:
+  converso queue list
+  converso queue move job-1a2b top
+  converso --remote tcp://downloads.lan:8790 queue remove job-3c4d`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:          "list",
+		Short:        "List queued jobs in the order they run",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runQueueList(cmd, cfg)
+		},
+	}
+	queueCmd.AddCommand(listCmd)
+
+	moveCmd := &cobra.Command{
+		Use:          "move <job-id> top",
+		Short:        "Make a queued job run next",
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runQueueMove(cmd, cfg, args[0], args[1])
+		},
+	}
+	queueCmd.AddCommand(moveCmd)
+
+	removeCmd := &cobra.Command{
+		Use:          "remove <job-id>",
+		Short:        "Drop a queued job before it starts",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runQueueRemove(cfg, args[0])
+		},
+	}
+	queueCmd.AddCommand(removeCmd)
+
+	return queueCmd
+}
+
+// runQueueList prints the worker's queued jobs
+func runQueueList(cmd *cobra.Command, cfg *config.Config) error {
+	remote, err := workerControl(cfg)
+	if err != nil {
+		return err
+	}
+	jobs, err := remote.Queue()
+	if err != nil {
+		return err
+	}
+	return printQueue(cmd, jobs)
+}
+
+// runQueueMove moves a queued job to the given position
+func runQueueMove(cmd *cobra.Command, cfg *config.Config, id, position string) error {
+	if position != "top" {
+		return fmt.Errorf("invalid position %q: only top is supported", position)
+	}
+
+	remote, err := workerControl(cfg)
+	if err != nil {
+		return err
+	}
+	jobs, err := remote.MoveToTop(id)
+	if err != nil {
+		return err
+	}
+	if outputFlagsSet(cmd) {
+		return printQueue(cmd, jobs)
+	}
+
+	fmt.Printf("⬆️  Job %s runs next\n", id)
+	return nil
+}
+
+// runQueueRemove drops a queued job
+func runQueueRemove(cfg *config.Config, id string) error {
+	remote, err := workerControl(cfg)
+	if err != nil {
+		return err
+	}
+	job, err := remote.RemoveQueued(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🗑️  Removed job %s (%s %s) from the queue\n", job.ID, job.Module, job.Command)
+	return nil
+}
+
+// printQueue prints queued jobs with their position
+func printQueue(cmd *cobra.Command, jobs []*worker.Job) error {
+	if len(jobs) == 0 {
+		fmt.Println("No queued jobs")
+		return nil
+	}
+
+	list := &listOutput{
+		Columns:  []string{"position", "id", "module", "command", "url", "priority", "created", "device"},
+		Defaults: []string{"position", "id", "module", "command", "url", "created"},
+	}
+	for i, job := range jobs {
+		url, _ := job.Args["url"].(string)
+		list.Add(job, fmt.Sprint(i+1), job.ID, job.Module, job.Command, valueOrDash(url),
+			fmt.Sprint(job.Priority), terminal.FormatDateTime(job.CreatedAt.Local()), valueOrDash(job.Device))
+	}
+	return printList(cmd, list)
+}
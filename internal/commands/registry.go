@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/converso-empire/cli/pkg/auth"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/errcode"
+	"github.com/converso-empire/cli/pkg/plugin"
+	"github.com/converso-empire/cli/pkg/profiling"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// newPluginRegistry creates a plugin registry configured from cfg and
+// loads the installed plugins. Modules may prompt the user unless the CLI
+// runs headless or without a terminal, and their log lines are shown with
+// --verbose. Commands get tokens from the invocation's token provider, so
+// they stay valid however long the invocation runs.
+func newPluginRegistry(cfg *config.Config, logger telemetry.Logger) (*plugin.PluginRegistry, error) {
+	registry, err := plugin.Open(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	registry.SetPrompter(modulePrompter(cfg))
+	registry.SetTokenProvider(tokenProvider(cfg, logger))
+	if cfg.Verbose {
+		registry.SetLogHandler(moduleLogPrinter(secretMasker(cfg, logger)))
+	}
+	return registry, nil
+}
+
+// loadAuthTokens retrieves the stored tokens passed to module commands,
+// refreshing them when they are about to expire; the refresh ends when ctx
+// does
+func loadAuthTokens(ctx context.Context, cfg *config.Config, logger telemetry.Logger) (*auth.AuthTokens, error) {
+	defer profiling.Track(profiling.PhaseAuth)()
+
+	tokens, err := tokenProvider(cfg, logger).Tokens(ctx)
+	if err != nil {
+		if cfg.Headless {
+			return nil, errcode.New(errcode.AuthRequired, fmt.Errorf("authentication required: %w", err))
+		}
+		return nil, errcode.New(errcode.AuthRequired, fmt.Errorf("authentication required. Run 'converso login' first: %w", err))
+	}
+	return tokens, nil
+}
+
+// invocationTokens holds the token provider of this invocation, shared by
+// its commands and plugin registries
+var invocationTokens struct {
+	once     sync.Once
+	provider *auth.TokenProvider
+}
+
+// tokenProvider returns the invocation's token provider, which refreshes
+// the stored tokens shortly before they expire
+func tokenProvider(cfg *config.Config, logger telemetry.Logger) *auth.TokenProvider {
+	invocationTokens.once.Do(func() {
+		invocationTokens.provider = auth.NewTokenProvider(cfg, logger)
+	})
+	return invocationTokens.provider
+}
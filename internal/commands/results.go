@@ -0,0 +1,204 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/spf13/cobra"
+)
+
+// printItemSummary renders per-item results of a batch operation as a table
+func printItemSummary(cmd *cobra.Command, resp *bridge.ModuleResponse) error {
+	list := &listOutput{Columns: []string{"item", "status", "detail"}}
+	for _, item := range resp.Items {
+		status := "ok"
+		detail := ""
+		if item.Success {
+			if filePath, ok := item.Data["file_path"].(string); ok {
+				detail = filePath
+			}
+		} else {
+			status = "failed"
+			detail = item.Error
+		}
+		list.Add(item, item.ID, status, detail)
+	}
+
+	// Custom output is left free of headings so it can be piped
+	if outputFlagsSet(cmd) {
+		return printList(cmd, list)
+	}
+
+	fmt.Printf("\n📦 Batch Results\n")
+	fmt.Println("================")
+	if err := printList(cmd, list); err != nil {
+		return err
+	}
+
+	failed := len(resp.FailedItems())
+	fmt.Printf("\n✅ %d succeeded  ❌ %d failed  (total %d)\n", len(resp.Items)-failed, failed, len(resp.Items))
+	if skipped := skippedItems(resp); skipped > 0 {
+		fmt.Printf("⏭️  Stopped after the first failure, %d item(s) skipped\n", skipped)
+	}
+	return nil
+}
+
+// itemResultsError returns an error carrying the partial-failure exit code
+// when some items of a batch response failed
+func itemResultsError(resp *bridge.ModuleResponse) error {
+	if !resp.IsPartialFailure() {
+		return nil
+	}
+
+	return &ExitError{
+		Code: ExitCodePartialFailure,
+		Err:  fmt.Errorf("%d of %d items failed", len(resp.FailedItems()), len(resp.Items)),
+	}
+}
+
+// skippedItems returns how many items a module skipped after aborting a
+// batch with fail_fast
+func skippedItems(resp *bridge.ModuleResponse) int {
+	if skipped, ok := resp.Data["skipped"].(float64); ok {
+		return int(skipped)
+	}
+	return 0
+}
+
+// addBatchFlags adds the flags controlling batch error handling
+func addBatchFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("fail-fast", false, "Stop at the first failed item instead of continuing")
+	cmd.Flags().String("summary-file", "", "Write a JSON summary of the run (counts, failures) to this file")
+}
+
+// batchSummary is the machine-readable end-of-run summary written by
+// --summary-file
+type batchSummary struct {
+	RunID      string         `json:"run_id"`
+	Command    string         `json:"command"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Total      int            `json:"total"`
+	Succeeded  int            `json:"succeeded"`
+	Failed     int            `json:"failed"`
+	Skipped    int            `json:"skipped"`
+	Aborted    bool           `json:"aborted"`
+	ExitCode   int            `json:"exit_code"`
+	Error      string         `json:"error,omitempty"`
+	Failures   []batchFailure `json:"failures"`
+}
+
+// batchFailure is a failed item in a batch summary
+type batchFailure struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// batchRun tracks the items processed by a batch command so a summary can
+// be written when it finishes
+type batchRun struct {
+	FailFast bool
+
+	cmd         *cobra.Command
+	summaryFile string
+	started     time.Time
+	items       []bridge.ItemResult
+	skipped     int
+	aborted     bool
+}
+
+// newBatchRun starts tracking a batch command run
+func newBatchRun(cmd *cobra.Command) *batchRun {
+	failFast, _ := cmd.Flags().GetBool("fail-fast")
+	summaryFile, _ := cmd.Flags().GetString("summary-file")
+
+	return &batchRun{
+		FailFast:    failFast,
+		cmd:         cmd,
+		summaryFile: summaryFile,
+		started:     time.Now(),
+	}
+}
+
+// Record adds the outcome of a module command. Batch responses contribute
+// their items; other responses count as a single item identified by id.
+func (b *batchRun) Record(id string, resp *bridge.ModuleResponse, err error) {
+	switch {
+	case err != nil:
+		b.Add(bridge.ItemResult{ID: id, Error: err.Error()})
+	case resp.HasItems():
+		b.items = append(b.items, resp.Items...)
+		if skipped := skippedItems(resp); skipped > 0 {
+			b.Skip(skipped)
+		}
+	case !resp.Success:
+		b.Add(bridge.ItemResult{ID: id, Error: resp.Error})
+	default:
+		b.Add(bridge.ItemResult{ID: id, Success: true, Data: resp.Data})
+	}
+}
+
+// Add records a single item
+func (b *batchRun) Add(item bridge.ItemResult) {
+	b.items = append(b.items, item)
+}
+
+// Skip records items that were not attempted because the run stopped early
+func (b *batchRun) Skip(count int) {
+	b.skipped += count
+	b.aborted = true
+}
+
+// Abort marks the run as stopped early by --fail-fast
+func (b *batchRun) Abort() {
+	b.aborted = true
+}
+
+// Finish writes the summary file, if requested, and returns the command's
+// error unchanged
+func (b *batchRun) Finish(runErr error) error {
+	if b.summaryFile == "" {
+		return runErr
+	}
+
+	summary := batchSummary{
+		RunID:      telemetry.RunID(),
+		Command:    b.cmd.CommandPath(),
+		StartedAt:  b.started,
+		FinishedAt: time.Now(),
+		Total:      len(b.items) + b.skipped,
+		Skipped:    b.skipped,
+		Aborted:    b.aborted,
+		ExitCode:   ExitCode(runErr),
+		Failures:   []batchFailure{},
+	}
+	if runErr != nil {
+		summary.Error = runErr.Error()
+	}
+	for _, item := range b.items {
+		if item.Success {
+			summary.Succeeded++
+			continue
+		}
+		summary.Failed++
+		summary.Failures = append(summary.Failures, batchFailure{ID: item.ID, Error: item.Error})
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+	if err := os.WriteFile(b.summaryFile, append(data, '\n'), 0644); err != nil {
+		// Do not mask the command's own error
+		if runErr == nil {
+			return fmt.Errorf("failed to write summary file: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to write summary file: %v\n", err)
+	}
+
+	return runErr
+}
@@ -1,13 +1,18 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
 
-	"github.com/converso-empire/cli/pkg/auth"
 	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/errcode"
+	"github.com/converso-empire/cli/pkg/fips"
+	"github.com/converso-empire/cli/pkg/profiling"
 	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // RootCmd represents the base command when called without any subcommands
@@ -24,6 +29,8 @@ func NewRootCmd(version, commit, date string, cfg *config.Config, logger telemet
 		logger: logger,
 	}
 
+	var noColor bool
+
 	cmd := &cobra.Command{
 		Use:   "converso",
 		Short: "Converso CLI - Enterprise SaaS Command Line Interface",
@@ -39,11 +46,103 @@ Features:
   • Enterprise-grade security and compliance
   • Cross-platform support (Linux, macOS, Windows)`,
 		Version: fmt.Sprintf("%s (commit: %s, built: %s)", version, commit, date),
+		// main writes the error, as --error-format asks
+		SilenceErrors: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			// Check if command requires authentication
-			if requiresAuth(cmd) {
-				if !auth.IsAuthenticated(cfg) {
-					return fmt.Errorf("authentication required. Run 'converso login' first")
+			// Trivial commands keep running on the defaults
+			if requiresConfig(cmd) {
+				if err := loadConfig(cmd, cfg, logger); err != nil {
+					return err
+				}
+			}
+			telemetry.SetDebug(logger, cfg.Debug)
+
+			// --timeout bounds the whole invocation, including module
+			// commands and the API calls made along the way
+			if cfg.Timeouts.TotalOverride > 0 {
+				ctx, cancel := context.WithTimeout(cmd.Context(), cfg.Timeouts.TotalOverride)
+				cobra.OnFinalize(cancel)
+				cmd.SetContext(ctx)
+			}
+
+			// Restrict crypto before any connection is made
+			if cfg.FIPS {
+				fips.Enable()
+			}
+
+			if cfg.ErrorFormat != ErrorFormatText && cfg.ErrorFormat != ErrorFormatJSON {
+				return fmt.Errorf("invalid --error-format %q: use text or json", cfg.ErrorFormat)
+			}
+
+			// Apply the output theme, in ASCII if the terminal cannot render
+			// Unicode
+			if noColor {
+				cfg.Output.Color = false
+			}
+			output, err := terminal.FromConfig(cfg.Output)
+			if err != nil {
+				return err
+			}
+			if err := terminal.Configure(output); err != nil {
+				return err
+			}
+
+			// Resolve directory overrides before anything reads them
+			if cfg.DataDir, err = config.ExpandPath(cfg.DataDir); err != nil {
+				return fmt.Errorf("invalid --data-dir: %w", err)
+			}
+			if cfg.PluginsDir, err = config.ExpandPath(cfg.PluginsDir); err != nil {
+				return fmt.Errorf("invalid --plugins-dir: %w", err)
+			}
+
+			// Record the run, keep its logs for 'converso inspect' with
+			// secrets masked and subscribe what reacts to events
+			if requiresConfig(cmd) {
+				masker := secretMasker(cfg, logger)
+				telemetry.SetMasker(logger, masker)
+				startRun(cmd, cfg, logger, version)
+				subscribeEvents(cfg, logger)
+				if cfg.Explain {
+					startExplain(cmd, cfg, masker)
+				}
+			}
+
+			// Profile the rest of the invocation
+			if cfg.Profile {
+				if err := profiling.Start(profileDir(cfg, telemetry.RunID()), cmd.CommandPath()); err != nil {
+					return err
+				}
+			}
+
+			// Shared machines stay as the administrator set them up
+			if cfg.Policy.ReadOnlyRequired() {
+				cfg.ReadOnly = true
+			}
+			if cfg.ReadOnly && changesSetup(cmd) {
+				cmd.SilenceUsage = true
+				if cfg.Policy.ReadOnlyRequired() {
+					return fmt.Errorf("%s is not available in read-only mode, set by policy %s", cmd.CommandPath(), cfg.Policy.Path)
+				}
+				return fmt.Errorf("%s is not available in read-only mode", cmd.CommandPath())
+			}
+
+			// Only some commands manage a remote worker
+			if cfg.Remote.Addr != "" && !supportsRemote(cmd) {
+				if cmd.Flags().Changed("remote") {
+					return fmt.Errorf("%s cannot manage a remote worker", cmd.CommandPath())
+				}
+				cfg.Remote = config.RemoteConfig{}
+			}
+
+			// Check if command requires authentication, refreshing expired
+			// tokens; remote workers check their own control token
+			if requiresAuth(cmd) && cfg.Remote.Addr == "" {
+				if _, err := tokenProvider(cfg, logger).Tokens(cmd.Context()); err != nil {
+					logger.Debug("No valid tokens", "error", err)
+					if cfg.Headless {
+						return errcode.New(errcode.AuthRequired, fmt.Errorf("authentication required. Set CONVERSO_ACCESS_TOKEN or CONVERSO_ACCESS_TOKEN_FILE"))
+					}
+					return errcode.New(errcode.AuthRequired, fmt.Errorf("authentication required. Run 'converso login' first"))
 				}
 			}
 			return nil
@@ -52,14 +151,55 @@ Features:
 
 	// Add subcommands
 	cmd.AddCommand(NewSetupCmd(cfg, logger))
+	cmd.AddCommand(NewInitCmd(cfg, logger))
 	cmd.AddCommand(NewLoginCmd(cfg, logger))
 	cmd.AddCommand(NewLogoutCmd(cfg, logger))
+	cmd.AddCommand(NewDownloadCmd(cfg, logger))
+	cmd.AddCommand(NewOpenCmd(cfg, logger))
+	cmd.AddCommand(NewRunCmd(cfg, logger))
 	cmd.AddCommand(NewYouTubeCmd(cfg, logger))
+	cmd.AddCommand(NewConvertCmd(cfg, logger))
+	cmd.AddCommand(NewMediaCmd(cfg, logger))
+	cmd.AddCommand(NewHistoryCmd(cfg, logger))
+	cmd.AddCommand(NewStatsCmd(cfg, logger))
+	cmd.AddCommand(NewLibraryCmd(cfg, logger))
+	cmd.AddCommand(NewWorkerCmd(cfg, logger))
+	cmd.AddCommand(NewJobsCmd(cfg, logger))
+	cmd.AddCommand(NewQueueCmd(cfg, logger))
+	cmd.AddCommand(NewDevicesCmd(cfg, logger))
+	cmd.AddCommand(NewBackupCmd(cfg, logger))
+	cmd.AddCommand(NewDevCmd(cfg, logger))
+	cmd.AddCommand(NewPrivacyCmd(cfg, logger))
+	cmd.AddCommand(NewSecretsCmd(cfg, logger))
+	cmd.AddCommand(NewCookiesCmd(cfg, logger))
+	cmd.AddCommand(NewDoctorCmd(cfg, logger))
+	cmd.AddCommand(NewAboutCmd(version, cfg, logger))
+	cmd.AddCommand(NewPluginCmd(cfg, logger))
+	cmd.AddCommand(NewInspectCmd(cfg, logger))
 	cmd.AddCommand(NewVersionCmd(version, commit, date))
 
 	// Global flags
 	cmd.PersistentFlags().BoolVar(&cfg.Debug, "debug", false, "Enable debug logging")
+	cmd.PersistentFlags().BoolVar(&cfg.Verbose, "verbose", false, "Show the log lines modules emit while they run, and the resolved arguments of pipeline commands")
+	cmd.PersistentFlags().BoolVar(&cfg.Headless, "headless", cfg.Headless, "Container mode: credentials from the environment, no prompts (env: CONVERSO_HEADLESS)")
+	cmd.PersistentFlags().BoolVar(&cfg.DryRunAPI, "dry-run-api", cfg.DryRunAPI, "Log backend POST/PUT requests instead of sending them (env: CONVERSO_DRY_RUN_API)")
 	cmd.PersistentFlags().StringVar(&cfg.ConfigFile, "config", "", "Config file (default is $HOME/.converso/config.yaml)")
+	cmd.PersistentFlags().StringVar(&cfg.DataDir, "data-dir", cfg.DataDir, "Directory for tokens, history and other state (env: CONVERSO_DATA_DIR)")
+	cmd.PersistentFlags().StringVar(&cfg.PluginsDir, "plugins-dir", cfg.PluginsDir, "Directory to load plugins from (env: CONVERSO_PLUGINS_DIR)")
+	cmd.PersistentFlags().DurationVar(&cfg.Timeouts.TotalOverride, "timeout", 0, "Maximum total run time, including module commands and API calls (e.g. 30m)")
+	addOutputFlags(cmd)
+	cmd.PersistentFlags().DurationVar(&cfg.Timeouts.IdleOverride, "idle-timeout", 0, "Abort module commands that produce no output for this long (e.g. 2m)")
+	cmd.PersistentFlags().BoolVar(&cfg.StrictProtocol, "strict-protocol", cfg.StrictProtocol, "Fail on module output that breaks the bridge protocol (default on in CI; env: CONVERSO_STRICT_PROTOCOL)")
+	cmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (env: NO_COLOR)")
+	cmd.PersistentFlags().StringVar(&cfg.Output.Mode, "output", terminal.ModeAuto, "Output style: auto (plain when stdout is not a terminal), human, table, plain, or json/yaml for results")
+	cmd.PersistentFlags().StringVar(&cfg.Remote.Addr, "remote", "", "Manage the worker at tcp://host:port instead of this machine's (token: CONVERSO_REMOTE_TOKEN)")
+	cmd.PersistentFlags().StringVar(&cfg.Remote.CAFile, "remote-ca", "", "CA certificate verifying the remote worker, for self-signed certificates")
+	cmd.PersistentFlags().BoolVar(&cfg.Remote.Insecure, "remote-insecure", false, "Connect to the remote worker without TLS, e.g. through an SSH tunnel")
+	cmd.PersistentFlags().BoolVar(&cfg.Profile, "profile", false, "Write CPU/heap profiles and phase timings to the data dir and print a timing summary")
+	cmd.PersistentFlags().BoolVar(&cfg.AllowUnsigned, "allow-unsigned", false, "Load plugins not signed by a trusted key (not when the policy requires signed plugins)")
+	cmd.PersistentFlags().BoolVar(&cfg.Explain, "explain", false, "On failure, print the settings, modules, requests (credentials masked) and timings that led to the error")
+	cmd.PersistentFlags().BoolVar(&cfg.ReadOnly, "read-only", false, "Refuse commands that change the config, plugins, credentials, history, subscriptions or library, e.g. on shared machines")
+	cmd.PersistentFlags().StringVar(&cfg.ErrorFormat, "error-format", cfg.ErrorFormat, "Write errors to stderr as text, or as json with their category and exit code (env: CONVERSO_ERROR_FORMAT)")
 
 	return cmd
 }
@@ -69,13 +209,147 @@ func requiresAuth(cmd *cobra.Command) bool {
 	// Commands that don't require authentication
 	noAuthCommands := map[string]bool{
 		"setup":   true,
+		"init":    true,
 		"login":   true,
 		"logout":  true,
 		"version": true,
 		"help":    true,
+		"preset":  true,
+		"history": true,
+		"library": true,
+		// Health reflects the worker, not the caller's credentials
+		"healthcheck": true,
+		"dev":         true,
+		"privacy":     true,
+		"secrets":     true,
+		"cookies":     true,
+		"doctor":      true,
+		"about":       true,
+		"plugin":      true,
+		"completion":  true,
+		"inspect":     true,
+		"backup":      true,
+		// Jobs authenticate to the worker with its control token
+		"jobs":  true,
+		"queue": true,
+	}
+
+	// Stopping the local worker and reading its logs need no credentials
+	switch cmd.CommandPath() {
+	case "converso worker stop", "converso worker logs":
+		return false
+	}
+
+	// Subcommands inherit the exemption of their parent
+	for c := cmd; c != nil; c = c.Parent() {
+		if noAuthCommands[c.Name()] {
+			return false
+		}
+	}
+	return true
+}
+
+// changesSetupAnnotation marks the commands that change the configuration,
+// the installed plugins, the stored credentials, the history, the
+// subscriptions or the library, which read-only mode refuses. Its value is
+// empty, or the boolean flag without which the command only reads.
+const changesSetupAnnotation = "converso/changes-setup"
+
+// changesSetupAnnotations returns the annotations of a command that changes
+// the setup, only when flag is set if one is given
+func changesSetupAnnotations(flag string) map[string]string {
+	return map[string]string{changesSetupAnnotation: flag}
+}
+
+// changesSetup checks if a command changes the setup, as marked by
+// changesSetupAnnotation
+func changesSetup(cmd *cobra.Command) bool {
+	flag, ok := cmd.Annotations[changesSetupAnnotation]
+	if !ok || flag == "" {
+		return ok
 	}
+	set, _ := cmd.Flags().GetBool(flag)
+	return set
+}
 
-	return !noAuthCommands[cmd.Name()]
+// supportsRemote checks if a command can manage the worker given by
+// --remote
+func supportsRemote(cmd *cobra.Command) bool {
+	switch cmd.CommandPath() {
+	case "converso worker status":
+		return true
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Name() == "jobs" {
+			return true
+		}
+	}
+	return false
+}
+
+// requiresConfig checks if a command needs the full configuration. The
+// others start faster on the built-in defaults and environment.
+func requiresConfig(cmd *cobra.Command) bool {
+	// Commands that only print static information
+	lightCommands := map[string]bool{
+		"version":    true,
+		"help":       true,
+		"completion": true,
+	}
+
+	// Subcommands inherit the exemption of their parent
+	for c := cmd; c != nil; c = c.Parent() {
+		if lightCommands[c.Name()] {
+			return false
+		}
+	}
+	return true
+}
+
+// loadConfig replaces the defaults in cfg with the full configuration.
+// Flags given on the command line still win over the loaded values.
+func loadConfig(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) error {
+	// Remember the flags bound to cfg before the load overwrites them
+	changed := make(map[*pflag.Flag]string)
+	cmd.Root().PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed {
+			changed[f] = f.Value.String()
+		}
+	})
+
+	configLoaded := profiling.Track(profiling.PhaseConfigLoad)
+	load := config.Load
+	if cfg.ReadOnly {
+		load = config.LoadReadOnly
+	}
+	loaded, err := load()
+	configLoaded()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	*cfg = *loaded
+
+	for f, value := range changed {
+		if err := f.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid --%s: %w", f.Name, err)
+		}
+	}
+
+	// Report config upgrades
+	if m := cfg.Migration; m != nil {
+		logger.Info("Upgraded configuration file", "from_version", m.From, "to_version", m.To, "backup", m.BackupPath, "migrations", m.Applied)
+	}
+	return nil
+}
+
+// versionInfo is the build information the version command prints
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	Crypto    string `json:"crypto"`
 }
 
 // NewVersionCmd creates the version command
@@ -84,12 +358,25 @@ func NewVersionCmd(version, commit, date string) *cobra.Command {
 		Use:   "version",
 		Short: "Print the version number",
 		Long:  "Print the version number of Converso CLI",
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Printf("Converso CLI v%s\n", version)
-			fmt.Printf("Commit: %s\n", commit)
-			fmt.Printf("Build Date: %s\n", date)
-			fmt.Printf("Go Version: %s\n", os.Getenv("GOVERSION"))
-			fmt.Printf("Platform: %s/%s\n", os.Getenv("GOOS"), os.Getenv("GOARCH"))
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := versionInfo{
+				Version:   version,
+				Commit:    commit,
+				BuildDate: date,
+				GoVersion: os.Getenv("GOVERSION"),
+				Platform:  os.Getenv("GOOS") + "/" + os.Getenv("GOARCH"),
+				Crypto:    fips.Mode(),
+			}
+			if printed, err := printTemplate(cmd, info); printed || err != nil {
+				return err
+			}
+			fmt.Printf("Converso CLI v%s\n", info.Version)
+			fmt.Printf("Commit: %s\n", info.Commit)
+			fmt.Printf("Build Date: %s\n", info.BuildDate)
+			fmt.Printf("Go Version: %s\n", info.GoVersion)
+			fmt.Printf("Platform: %s\n", info.Platform)
+			fmt.Printf("Crypto: %s\n", info.Crypto)
+			return nil
 		},
 	}
 }
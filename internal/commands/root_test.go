@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// TestNoLocalFlagShadowsGlobal checks that no command defines a flag with
+// the name or shorthand of a global flag, which would hide the global one
+// for that command only
+func TestNoLocalFlagShadowsGlobal(t *testing.T) {
+	cfg, err := config.Defaults()
+	if err != nil {
+		t.Fatalf("Defaults: %v", err)
+	}
+	root := NewRootCmd("test", "none", "unknown", cfg, telemetry.NewLogger(false))
+
+	names := make(map[string]bool)
+	shorthands := make(map[string]string)
+	root.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		names[f.Name] = true
+		if f.Shorthand != "" {
+			shorthands[f.Shorthand] = f.Name
+		}
+	})
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		cmd.LocalNonPersistentFlags().VisitAll(func(f *pflag.Flag) {
+			if names[f.Name] {
+				t.Errorf("%s: --%s shadows the global flag", cmd.CommandPath(), f.Name)
+			}
+			if global, ok := shorthands[f.Shorthand]; ok {
+				t.Errorf("%s: -%s shadows the global --%s", cmd.CommandPath(), f.Shorthand, global)
+			}
+		})
+		for _, child := range cmd.Commands() {
+			walk(child)
+		}
+	}
+	for _, child := range root.Commands() {
+		walk(child)
+	}
+}
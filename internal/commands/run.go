@@ -0,0 +1,347 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/events"
+	"github.com/converso-empire/cli/pkg/fileutil"
+	"github.com/converso-empire/cli/pkg/pipeline"
+	"github.com/converso-empire/cli/pkg/redact"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/spf13/cobra"
+)
+
+// NewRunCmd creates the run command
+func NewRunCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run a pipeline of module commands",
+		Long: `Run the steps of a pipeline file in order, each a module command:
+
+  name: podcast
+  vars:
+    url: https://youtube.com/watch?v=example
+  steps:
+    - id: fetch
+      module: youtube
+      command: download
+      args:
+        url: ${vars.url}
+        mode: audio
+    - id: transcribe
+      module: whisper
+      command: transcribe
+      needs: [fetch]
+      args:
+        file: ${result.fetch.file_path}
+    - id: notify
+      module: slack
+      command: post
+      if: fetch.status = failed
+
+Arguments may reference ${vars.<name>}, ${env:NAME}, ${date:2006-01-02}
+and ${result.<step>.<field>} of the steps a step needs; see the README for
+fallbacks (??) and filters (| slug).
+
+A step runs after the steps it needs and only if they succeeded; a step
+with an 'if' condition runs when the condition holds instead. Conditions
+use the filter syntax over vars.<name>, <step>.status, <step>.error and
+the fields of a step's result. A failed step stops the pipeline unless it
+sets continue_on_error.
+
+Steps listed under 'parallel:' run at the same time, and a step with
+'foreach:' runs its command once per item of a list, or of a string of
+whitespace-separated items such as ${vars.urls}, with ${item} and ${index}
+in its arguments. At most 'concurrency' commands run at once. A foreach
+step fails when any of its items failed, once they all ran.
+
+Every command that succeeds is checkpointed. After an interrupted or
+failed run, --resume runs the pipeline again without repeating the
+commands whose arguments are unchanged and whose output files still match
+their checksums, such as the items of a foreach step that finished.
+
+--verbose shows the arguments of every command once references are
+expanded, and the log lines of their modules. Module secrets, credentials and environment variables matching
+sensitive_env are masked there, in errors and in the logs.
+
+A JSON summary of the run is written to the data directory, or to
+--summary-file.`,
+		Example: `  converso run -f pipeline.yaml
+  converso run -f pipeline.yaml --var url=https://youtu.be/example
+  converso run -f pipeline.yaml --dry-run
+  converso run -f pipeline.yaml --resume
+  converso run -f pipeline.yaml --verbose`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPipeline(cmd, cfg, logger)
+		},
+	}
+	cmd.Flags().StringP("file", "f", "", "Pipeline file")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringArray("var", nil, "Set a pipeline variable as name=value (repeatable)")
+	cmd.Flags().Bool("dry-run", false, "Show the steps in the order they run without running them")
+	cmd.Flags().Bool("resume", false, "Reuse the commands that succeeded in the last run of this pipeline")
+	cmd.Flags().String("summary-file", "", "Write the run summary to this file instead of the data directory")
+
+	return cmd
+}
+
+// runPipeline executes the run command
+func runPipeline(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) error {
+	file, _ := cmd.Flags().GetString("file")
+	varFlags, _ := cmd.Flags().GetStringArray("var")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	resume, _ := cmd.Flags().GetBool("resume")
+	summaryFile, _ := cmd.Flags().GetString("summary-file")
+	verbose := cfg.Verbose
+
+	vars := make(map[string]string, len(varFlags))
+	for _, flag := range varFlags {
+		name, value, ok := strings.Cut(flag, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid --var %q, expected name=value", flag)
+		}
+		vars[name] = value
+	}
+
+	p, err := pipeline.Load(file, vars)
+	if err != nil {
+		return err
+	}
+	stages, err := p.Order()
+	if err != nil {
+		return err
+	}
+	var steps []*pipeline.Step
+	for _, stage := range stages {
+		steps = append(steps, stage.Steps...)
+	}
+
+	if dryRun {
+		for i, stage := range stages {
+			if stage.Parallel {
+				fmt.Printf("%d. parallel:\n", i+1)
+			}
+			for _, step := range stage.Steps {
+				indent := "   "
+				if stage.Parallel {
+					fmt.Printf("   - %s: %s %s\n", step.ID, step.Module, step.Command)
+					indent = "     "
+				} else {
+					fmt.Printf("%d. %s: %s %s\n", i+1, step.ID, step.Module, step.Command)
+				}
+				if len(step.Needs) > 0 {
+					fmt.Printf("%sneeds: %s\n", indent, strings.Join(step.Needs, ", "))
+				}
+				if step.If != "" {
+					fmt.Printf("%sif: %s\n", indent, step.If)
+				}
+				if step.Foreach != nil {
+					fmt.Printf("%sforeach: %v\n", indent, step.Foreach)
+				}
+			}
+		}
+		return nil
+	}
+
+	tokens, err := loadAuthTokens(cmd.Context(), cfg, logger)
+	if err != nil {
+		return err
+	}
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	// Check every step's command before the first one runs
+	for _, step := range steps {
+		module, err := registry.GetModuleInfo(step.Module)
+		if err != nil {
+			return fmt.Errorf("step %s: %w", step.ID, err)
+		}
+		available := false
+		for _, command := range module.Manifest.Commands {
+			if command == step.Command {
+				available = true
+				break
+			}
+		}
+		if !available {
+			return fmt.Errorf("step %s: command %s not available in module %s", step.ID, step.Command, step.Module)
+		}
+	}
+
+	name := p.Name
+	if name == "" {
+		name = filepath.Base(file)
+	}
+	logger.Info("Starting pipeline", "pipeline", name, "file", file, "steps", len(steps))
+	fmt.Printf("🚀 Running pipeline %s (%d steps)\n", name, len(steps))
+
+	masker := secretMasker(cfg, logger)
+	masker.Add(tokens.AccessToken, tokens.RefreshToken, tokens.DeviceToken)
+	telemetry.SetMasker(logger, masker)
+
+	checkpointPath := pipeline.CheckpointPath(filepath.Join(cfg.DataDir, "pipelines", "checkpoints"), file)
+	checkpoint := pipeline.NewCheckpoint(checkpointPath, file)
+	if resume {
+		previous, err := pipeline.LoadCheckpoint(checkpointPath)
+		if err != nil {
+			return err
+		}
+		if previous != nil {
+			checkpoint = previous
+			fmt.Printf("♻️  Resuming from the checkpoint of run %s\n", previous.RunID)
+		} else {
+			fmt.Println("ℹ️  No checkpoint for this pipeline, running every step")
+		}
+	}
+
+	summary, err := p.Run(telemetry.RunID(), pipeline.Options{
+		Concurrency: cfg.Concurrency,
+		Checkpoint:  checkpoint,
+		Resume:      resume,
+		Warn: func(err error) {
+			logger.Warn("Failed to save pipeline checkpoint", "error", err)
+		},
+		Exec: func(step *pipeline.Step, args map[string]interface{}, progress chan<- *bridge.ProgressEvent) (*bridge.ModuleResponse, error) {
+			// Downloads are recorded in the history like any other
+			var download *events.Download
+			if url, ok := args["url"].(string); ok && step.Command == "download" {
+				download = &events.Download{Module: step.Module, Command: step.Command, URL: url}
+				events.Publish(events.DownloadStarted, *download)
+			}
+
+			resp, err := registry.ExecuteCommandWithProgress(cmd.Context(), step.Module, step.Command, args, tokens, progress)
+			if download != nil {
+				download.Response, download.Err = resp, err
+				events.Publish(events.DownloadCompleted, *download)
+			}
+			return resp, err
+		},
+		Progress: func() (chan<- *bridge.ProgressEvent, func()) {
+			progressChan := make(chan *bridge.ProgressEvent, 100)
+			progressDone := watchProgress(progressChan)
+			return progressChan, func() {
+				close(progressChan)
+				<-progressDone
+			}
+		},
+		Started: func(step *pipeline.Step, index, total int) {
+			fmt.Printf("\n▶️  [%d/%d] %s: %s %s\n", index, total, step.ID, step.Module, step.Command)
+		},
+		Calling: func(step *pipeline.Step, item string, args map[string]interface{}) {
+			logger.Debug("Running pipeline command", "pipeline", name, "step", step.ID, "item", item, "args", args)
+			if verbose {
+				fmt.Printf("   $ %s %s %s\n", step.Module, step.Command, formatArgs(args, masker))
+			}
+		},
+		Finished: func(result *pipeline.StepResult) {
+			logger.Info("Pipeline step finished", "pipeline", name, "step", result.ID, "status", result.Status, "error", result.Error)
+			switch result.Status {
+			case pipeline.StepSucceeded:
+				if result.Resumed {
+					fmt.Printf("\n♻️  %s already done in an earlier run\n", result.ID)
+					break
+				}
+				fmt.Printf("✅ %s succeeded in %s\n", result.ID, formatLatency(result.Duration()))
+			case pipeline.StepFailed:
+				fmt.Printf("❌ %s failed: %s\n", result.ID, masker.String(result.Error))
+				for _, item := range result.Items {
+					if !item.Success {
+						fmt.Printf("   ❌ %s: %s\n", masker.String(item.ID), masker.String(item.Error))
+					}
+				}
+			case pipeline.StepSkipped:
+				fmt.Printf("\n⏭️  %s skipped: %s\n", result.ID, result.Reason)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if summaryFile == "" {
+		summaryFile = filepath.Join(cfg.DataDir, "pipelines", summary.RunID+".json")
+	}
+	if err := writePipelineSummary(summaryFile, summary, masker); err != nil {
+		return err
+	}
+
+	succeeded, failed, skipped := summary.Counts()
+	fmt.Printf("\n📊 %d succeeded, %d failed, %d skipped in %s\n", succeeded, failed, skipped, formatLatency(summary.FinishedAt.Sub(summary.StartedAt)))
+	fmt.Printf("📄 Summary: %s\n", summaryFile)
+
+	if summary.Succeeded && failed == 0 {
+		if err := checkpoint.Remove(); err != nil {
+			logger.Warn("Failed to remove pipeline checkpoint", "error", err)
+		}
+	} else {
+		fmt.Printf("💾 Run again with --resume to continue from the checkpoint\n")
+	}
+
+	switch {
+	case !summary.Succeeded:
+		return fmt.Errorf("pipeline %s failed", name)
+	case failed > 0:
+		return &ExitError{Code: ExitCodePartialFailure, Err: fmt.Errorf("%d step(s) of pipeline %s failed", failed, name)}
+	}
+	return nil
+}
+
+// formatArgs formats command arguments as sorted name=value pairs, masking
+// secret values
+func formatArgs(args map[string]interface{}, masker *redact.Masker) string {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		value, ok := args[name].(string)
+		if !ok {
+			data, _ := json.Marshal(args[name])
+			value = string(data)
+		}
+		value = masker.String(value)
+		if value == "" || strings.ContainsAny(value, " \t\n") {
+			value = strconv.Quote(value)
+		}
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, " ")
+}
+
+// writePipelineSummary writes the summary of a pipeline run as JSON, with
+// secrets masked in the errors of steps
+func writePipelineSummary(path string, summary *pipeline.Summary, masker *redact.Masker) error {
+	for i := range summary.Steps {
+		step := &summary.Steps[i]
+		step.Error = masker.String(step.Error)
+		for j := range step.Items {
+			step.Items[j].Error = masker.String(step.Items[j].Error)
+		}
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := fileutil.WriteAtomic(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write summary file: %w", err)
+	}
+	return nil
+}
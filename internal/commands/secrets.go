@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/auth"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/redact"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+// moduleSecret is a secret as shown by secrets list; values are never shown
+type moduleSecret struct {
+	Module string
+	Key    string
+}
+
+// NewSecretsCmd creates the secrets command
+func NewSecretsCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	secretsCmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Manage credentials for modules",
+		Long: `Manage credentials that modules need for their own services, such as the
+API key of a translation service.
+
+Secrets are kept in the data directory, readable only by you, and are sent
+only to the module they were set for, in the secrets map of its bridge
+requests. In headless mode they come from CONVERSO_SECRET_<MODULE>_<KEY>
+environment variables (or _FILE variants) instead.`,
+	}
+
+	// Set command
+	setCmd := &cobra.Command{
+		Use:   "set <module> <key>",
+		Short: "Set a module secret",
+		Long: `Set a module secret. The value is prompted for without echo, or read from
+standard input when it is not a terminal, so it stays out of shell history.`,
+		Example: `  converso secrets set translate api_key
+  echo "$DEEPL_KEY" | converso secrets set translate api_key`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSecretsSet(cfg, logger, args[0], args[1])
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+
+	// List command
+	listCmd := &cobra.Command{
+		Use:   "list [module]",
+		Short: "List module secret keys",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSecretsList(cmd, cfg, logger, args)
+		},
+	}
+
+	// Delete command
+	deleteCmd := &cobra.Command{
+		Use:     "delete <module> <key>",
+		Aliases: []string{"rm"},
+		Short:   "Delete a module secret",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := auth.NewStorage(cfg, logger).DeleteSecret(args[0], args[1]); err != nil {
+				return fmt.Errorf("failed to delete secret: %w", err)
+			}
+			fmt.Printf("🗑️  Deleted secret %s for module %s\n", args[1], args[0])
+			return nil
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+
+	secretsCmd.AddCommand(setCmd, listCmd, deleteCmd)
+	return secretsCmd
+}
+
+// runSecretsSet stores a secret read from the terminal or stdin
+func runSecretsSet(cfg *config.Config, logger telemetry.Logger, module, key string) error {
+	for _, name := range []string{module, key} {
+		if err := auth.ValidateSecretName(name); err != nil {
+			return err
+		}
+	}
+
+	// Secrets may be set before the module is installed
+	if registry, err := newPluginRegistry(cfg, logger); err == nil {
+		if _, err := registry.GetModuleInfo(module); err != nil {
+			fmt.Printf("⚠️  Module %s is not installed; the secret is used once it is\n", module)
+		}
+	}
+
+	value, err := readSecretValue(fmt.Sprintf("Value for %s/%s: ", module, key))
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return errors.New("secret value is empty")
+	}
+
+	if err := auth.NewStorage(cfg, logger).StoreSecret(module, key, value); err != nil {
+		return fmt.Errorf("failed to store secret: %w", err)
+	}
+
+	fmt.Printf("✅ Stored secret %s for module %s\n", key, module)
+	return nil
+}
+
+// runSecretsList prints the secret keys per module
+func runSecretsList(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger, args []string) error {
+	keys, err := auth.NewStorage(cfg, logger).ListSecrets()
+	if err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	modules := make([]string, 0, len(keys))
+	for module := range keys {
+		if len(args) == 0 || args[0] == module {
+			modules = append(modules, module)
+		}
+	}
+	sort.Strings(modules)
+
+	list := &listOutput{Columns: []string{"module", "key"}}
+	for _, module := range modules {
+		for _, key := range keys[module] {
+			list.Add(moduleSecret{Module: module, Key: key}, module, key)
+		}
+	}
+
+	if len(list.Rows) == 0 && !outputFlagsSet(cmd) {
+		fmt.Println("No secrets set. Add one with 'converso secrets set <module> <key>'.")
+		return nil
+	}
+	return printList(cmd, list)
+}
+
+// readSecretValue prompts for a value without echo, or reads standard input
+// when it is not a terminal
+func readSecretValue(prompt string) (string, error) {
+	if !terminal.IsTerminal(os.Stdin) {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	restore := disableEcho()
+
+	// Restore echo if interrupted at the prompt
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-interrupted:
+			restore()
+			fmt.Fprintln(os.Stderr)
+			os.Exit(ExitCodeInterrupted)
+		case <-done:
+		}
+	}()
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	close(done)
+	signal.Stop(interrupted)
+	restore()
+	fmt.Fprintln(os.Stderr)
+
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// disableEcho turns off terminal echo with stty where available and returns
+// a function restoring it
+func disableEcho() func() {
+	if runtime.GOOS == "windows" {
+		return func() {}
+	}
+
+	stty := func(arg string) error {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = os.Stdin
+		return cmd.Run()
+	}
+	if err := stty("-echo"); err != nil {
+		return func() {}
+	}
+	return func() { stty("echo") }
+}
+
+// secretMasker returns a masker for every module secret, the credentials
+// given through the environment and the environment variables matching
+// sensitive_env
+func secretMasker(cfg *config.Config, logger telemetry.Logger) *redact.Masker {
+	masker := redact.NewMasker(cfg.SensitiveValues()...)
+
+	storage := auth.NewStorage(cfg, logger)
+	keys, err := storage.ListSecrets()
+	if err != nil {
+		logger.Debug("Module secrets not masked", "error", err)
+		return masker
+	}
+	for module := range keys {
+		secrets, err := storage.RetrieveSecrets(module)
+		if err != nil {
+			logger.Debug("Module secrets not masked", "module", module, "error", err)
+			continue
+		}
+		for _, value := range secrets {
+			masker.Add(value)
+		}
+	}
+	return masker
+}
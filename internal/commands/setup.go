@@ -21,18 +21,18 @@ func NewSetupCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
 
 This command will:
   • Create necessary directories and configuration files
-  • Check system requirements
+  • Check system requirements and detect hardware encoders
   • Display system information
   • Guide you through initial configuration`,
 		
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runSetup(cmd, cfg, logger)
 		},
+		Annotations: changesSetupAnnotations(""),
 	}
 
 	// Add flags
 	setupCmd.Flags().Bool("force", false, "Force setup even if already configured")
-	setupCmd.Flags().Bool("verbose", false, "Show detailed setup information")
 
 	return setupCmd
 }
@@ -40,7 +40,7 @@ This command will:
 // runSetup executes the setup process
 func runSetup(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) error {
 	force, _ := cmd.Flags().GetBool("force")
-	verbose, _ := cmd.Flags().GetBool("verbose")
+	verbose := cfg.Verbose
 
 	fmt.Println("🚀 Converso CLI Setup")
 	fmt.Println("===================")
@@ -85,6 +85,15 @@ func runSetup(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) e
 		fmt.Println("✅ FFmpeg found")
 	}
 
+	// Detect hardware encoders for conversions
+	if caps, err := detectHWAccel(cmd.Context(), cfg); err != nil {
+		fmt.Printf("⚠️  Hardware encoders not detected: %v\n", err)
+	} else if len(caps.Encoders) == 0 {
+		fmt.Println("ℹ️  No hardware encoders found, conversions use software encoders")
+	} else {
+		fmt.Printf("✅ Hardware encoders: %s\n", describeHWAccel(caps))
+	}
+
 	// Create directories
 	fmt.Println("\n📁 Creating Directories")
 	fmt.Println("----------------------")
@@ -162,7 +171,10 @@ func createSamplePlugin(pluginsDir string) error {
   "version": "1.0.0",
   "description": "YouTube downloader and format listing",
   "commands": ["download", "list_formats", "info"],
+  "url_patterns": ["youtube.com", "*.youtube.com", "youtu.be"],
+  "default_command": "download",
   "dependencies": ["yt-dlp", "ffmpeg"],
+  "options": ["proxy", "geo_bypass_country"],
   "author": "Converso Empire",
   "license": "MIT"
 }`
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/stats"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+// busiestHours is the number of hours of day 'converso stats' lists
+const busiestHours = 3
+
+// NewStatsCmd creates the stats command
+func NewStatsCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show how much was downloaded and converted this month",
+		Long: `Show the totals of a month's module runs: the bytes downloaded, the average
+speed of downloads, the busiest hours of the day and the runs, failures,
+output and speed of each module command. The totals are kept locally in
+the data directory; see 'converso plugin stats' for latencies.
+
+Examples:
+  converso stats
+  converso stats --month 2024-05
+  converso stats --output json`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			month, _ := cmd.Flags().GetString("month")
+			return runStats(cmd, cfg, month)
+		},
+	}
+	cmd.Flags().String("month", "", "Month to show, as YYYY-MM (default: this month)")
+	return cmd
+}
+
+// runStats prints the totals of a month
+func runStats(cmd *cobra.Command, cfg *config.Config, month string) error {
+	start := time.Now()
+	if month != "" {
+		parsed, err := time.ParseInLocation("2006-01", month, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid month %q, expected YYYY-MM", month)
+		}
+		start = parsed
+	}
+
+	all, err := stats.NewStore(stats.DefaultPath(cfg)).All()
+	if err != nil {
+		return fmt.Errorf("failed to read statistics: %w", err)
+	}
+	report := stats.NewReport(all, stats.MonthKey(start))
+
+	if handled, err := printTemplate(cmd, report); handled || err != nil {
+		return err
+	}
+
+	fmt.Printf("\n📊 %s\n\n", start.Format("January 2006"))
+	if report.Total.Runs == 0 {
+		fmt.Println("No module runs recorded this month.")
+		return nil
+	}
+
+	fmt.Printf("⬇️  Downloaded: %s in %d run(s)\n", terminal.FormatSize(report.Downloaded.Bytes), report.Downloaded.Runs)
+	if speed := report.Downloaded.Throughput(); speed > 0 {
+		fmt.Printf("⚡ Average speed: %s/s\n", terminal.FormatSize(int64(speed)))
+	} else {
+		fmt.Println("⚡ Average speed: unknown, no download produced output")
+	}
+	var hours []string
+	for _, hour := range report.Total.BusiestHours(busiestHours) {
+		hours = append(hours, fmt.Sprintf("%02d:00 (%d run(s))", hour, report.Total.Hours[hour]))
+	}
+	fmt.Printf("🕐 Busiest hours: %s\n\n", strings.Join(hours, ", "))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  MODULE\tCOMMAND\tRUNS\tFAILURES\tOUTPUT\tSPEED")
+	for _, c := range report.Commands {
+		speed := "-"
+		if throughput := c.Throughput(); throughput > 0 {
+			speed = terminal.FormatSize(int64(throughput)) + "/s"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%d\t%d\t%s\t%s\n", c.Module, c.Command, c.Runs, c.Failures, formatFileSize(c.Bytes), speed)
+	}
+	fmt.Fprintf(w, "  total\t\t%d\t%d\t%s\t\n", report.Total.Runs, report.Total.Failures, formatFileSize(report.Total.Bytes))
+	w.Flush()
+	return nil
+}
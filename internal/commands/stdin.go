@@ -0,0 +1,392 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/converso-empire/cli/pkg/archive"
+	"github.com/converso-empire/cli/pkg/auth"
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/events"
+	"github.com/converso-empire/cli/pkg/plugin"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/spf13/cobra"
+)
+
+// stdinArg is the URL argument that reads URLs from stdin
+const stdinArg = "-"
+
+// readURLs reads one item per line from source, stdin or a batch file,
+// skipping blank lines, # comments and duplicates, including other URLs of
+// the same video. A line holds the item's URL optionally followed by mirrors,
+// separated by whitespace; each item is returned as its URL and mirrors.
+func readURLs(r io.Reader, source string) ([][]string, error) {
+	var items [][]string
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sources := strings.Fields(line)
+		id := archive.ItemID(sources[0])
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		items = append(items, sources)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read URLs from %s: %w", source, err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no URLs in %s", source)
+	}
+	return items, nil
+}
+
+// downloadItems collects the items to download: the URL arguments, the
+// lines of stdin for "-" and those of the --batch-file
+func downloadItems(cmd *cobra.Command, args []string) ([][]string, error) {
+	var items [][]string
+	for _, arg := range args {
+		if arg != stdinArg {
+			items = append(items, []string{arg})
+			continue
+		}
+		read, err := readURLs(cmd.InOrStdin(), "stdin")
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, read...)
+	}
+
+	if path, _ := cmd.Flags().GetString("batch-file"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open batch file: %w", err)
+		}
+		read, err := readURLs(file, path)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, read...)
+	}
+
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no URLs to download. Pass URLs, - to read them from stdin, or --batch-file")
+	}
+	return items, nil
+}
+
+// uniqueItems drops the items whose URL is another URL of the video of an
+// earlier item
+func uniqueItems(items [][]string) [][]string {
+	var unique [][]string
+	seen := make(map[string]bool)
+	for _, sources := range items {
+		id := archive.ItemID(sources[0])
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, sources)
+	}
+	return unique
+}
+
+// urlDownloads runs a module's download command for a list of URLs,
+// falling back to an item's mirrors when its URL fails
+type urlDownloads struct {
+	Registry *plugin.PluginRegistry
+	Tokens   *auth.AuthTokens
+	Module   string
+	Logger   telemetry.Logger
+	// Args returns the command arguments for a URL
+	Args        func(url string) map[string]interface{}
+	Concurrency int
+	// Archive skips URLs whose content was downloaded before; nil
+	// downloads every URL
+	Archive *archive.Archive
+
+	ctx     context.Context
+	tmpl    *template.Template
+	batch   *batchRun
+	mu      sync.Mutex
+	results []bridge.ItemResult
+	stopped bool
+}
+
+// Run downloads items, at most Concurrency at a time, and writes a result
+// line per item: "ok<TAB>url<TAB>file", "failed<TAB>url<TAB>error" or
+// "skipped<TAB>url<TAB>content ID" for content downloaded before, the
+// --template rendered with the result, or with --output json or yaml the
+// result itself. Lines are written as downloads
+// finish when stdout is read by a script, and after the progress display
+// otherwise.
+func (d *urlDownloads) Run(cmd *cobra.Command, items [][]string, batch *batchRun) error {
+	tmpl, err := outputTemplate(cmd)
+	if err != nil {
+		return err
+	}
+	d.ctx, d.tmpl, d.batch = cmd.Context(), tmpl, batch
+
+	concurrency := d.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	stream := terminal.Plain() || tmpl != nil
+
+	progressChan := make(chan *bridge.ProgressEvent, 100)
+	progressDone := watchItemsProgress(progressChan, len(items))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for i, sources := range items {
+		slots <- struct{}{}
+		if d.failed() || d.ctx.Err() != nil {
+			<-slots
+			batch.Skip(len(items) - i)
+			break
+		}
+
+		wg.Add(1)
+		go func(sources []string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results := d.download(sources, progressChan)
+			if stream {
+				d.print(results)
+			}
+		}(sources)
+	}
+	wg.Wait()
+	close(progressChan)
+	<-progressDone
+
+	if !stream {
+		d.printAll()
+	}
+	if err := d.ctx.Err(); err != nil {
+		return &ExitError{Code: ExitCodeInterrupted, Err: fmt.Errorf("downloads interrupted: %w", err)}
+	}
+	return d.err()
+}
+
+// download runs the command for an item, trying its URL and then each
+// mirror until one succeeds. The outcome is recorded under the item's URL,
+// with the mirror it came from as the source.
+func (d *urlDownloads) download(sources []string, progress chan<- *bridge.ProgressEvent) []bridge.ItemResult {
+	url := sources[0]
+	if id := archive.ItemID(url); d.Archive != nil && d.Archive.Has(d.Module, id) {
+		result := duplicateResult(url, id)
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.batch.Add(result)
+		d.results = append(d.results, result)
+		return []bridge.ItemResult{result}
+	}
+
+	events.Publish(events.DownloadStarted, events.Download{Module: d.Module, Command: "download", URL: url})
+
+	var resp *bridge.ModuleResponse
+	var err error
+	var source string
+	for i, next := range sources {
+		source = next
+		if resp, err = d.fetch(url, source, progress); failure(resp, err) == "" {
+			break
+		}
+		if i < len(sources)-1 {
+			d.Logger.Warn("Download failed, trying the next mirror", "url", url, "source", source, "error", failure(resp, err))
+		}
+	}
+	if len(sources) > 1 && failure(resp, err) != "" {
+		err = fmt.Errorf("all %d sources failed, last: %s", len(sources), failure(resp, err))
+	}
+
+	download := events.Download{Module: d.Module, Command: "download", URL: url, Response: resp, Err: err}
+	if source != url {
+		download.Source = source
+	}
+	events.Publish(events.DownloadCompleted, download)
+
+	var results []bridge.ItemResult
+	switch {
+	case err != nil:
+		results = []bridge.ItemResult{{ID: url, Error: err.Error()}}
+	case resp.HasItems():
+		results = resp.Items
+		if !resp.Success && len(resp.FailedItems()) == 0 {
+			results = append(results, bridge.ItemResult{ID: url, Error: resp.Error})
+		}
+	case !resp.Success:
+		results = []bridge.ItemResult{{ID: url, Error: resp.Error}}
+	default:
+		results = []bridge.ItemResult{{ID: url, Success: true, Data: resp.Data}}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.batch.Record(url, resp, err)
+	d.results = append(d.results, results...)
+	for _, result := range results {
+		if !result.Success && d.batch.FailFast {
+			d.stopped = true
+		}
+	}
+	return results
+}
+
+// fetch runs the command for one source of an item, forwarding its progress
+// labelled with the item's URL so concurrent downloads get a bar each
+func (d *urlDownloads) fetch(url, source string, progress chan<- *bridge.ProgressEvent) (*bridge.ModuleResponse, error) {
+	callProgress := make(chan *bridge.ProgressEvent, 100)
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for event := range callProgress {
+			labelled := *event
+			if labelled.Item == "" {
+				labelled.Item = url
+			} else {
+				labelled.Item = url + "/" + labelled.Item
+			}
+			progress <- &labelled
+		}
+	}()
+
+	resp, err := d.Registry.ExecuteCommandWithProgress(d.ctx, d.Module, "download", d.Args(source), d.Tokens, callProgress)
+	close(callProgress)
+	<-forwarded
+	return resp, err
+}
+
+// failure returns the error of a download, or "" when it succeeded or
+// reports per-item results, whose failures a mirror would not fix
+func failure(resp *bridge.ModuleResponse, err error) string {
+	switch {
+	case err != nil:
+		return err.Error()
+	case !resp.Success && !resp.HasItems():
+		return resp.Error
+	}
+	return ""
+}
+
+// failed reports whether --fail-fast stops the remaining downloads
+func (d *urlDownloads) failed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stopped
+}
+
+// print writes the result lines of a download
+func (d *urlDownloads) print(results []bridge.ItemResult) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, result := range results {
+		if d.tmpl != nil {
+			if err := executeTemplate(d.tmpl, result); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+			}
+			continue
+		}
+		if format := terminal.Format(); format != "" {
+			if err := writeResult(format, result); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+			}
+			continue
+		}
+		fmt.Println(resultLine(result))
+	}
+}
+
+// writeResult writes a result of a stream of them: a line of JSON, or a
+// YAML document
+func writeResult(format string, result bridge.ItemResult) error {
+	if format == terminal.ModeJSON {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode output: %w", err)
+		}
+		fmt.Printf("%s\n", data)
+		return nil
+	}
+	fmt.Println("---")
+	return writeFormatted(os.Stdout, format, result)
+}
+
+// printAll writes the result lines of all downloads with a summary
+func (d *urlDownloads) printAll() {
+	fmt.Println()
+	for _, result := range d.results {
+		fmt.Println(resultLine(result))
+	}
+
+	failed, duplicates := d.failures(), 0
+	for _, result := range d.results {
+		if _, ok := result.Data["duplicate"]; ok {
+			duplicates++
+		}
+	}
+	fmt.Printf("\n✅ %d succeeded  ❌ %d failed  (total %d)\n", len(d.results)-failed, failed, len(d.results))
+	if duplicates > 0 {
+		fmt.Printf("⏭️  %d already downloaded; use --force to download them again\n", duplicates)
+	}
+	if d.stopped {
+		fmt.Printf("⏭️  Stopped after the first failure\n")
+	}
+}
+
+// err returns the error of the run: none when all downloads succeeded,
+// and the partial-failure exit code when only some failed
+func (d *urlDownloads) err() error {
+	failed := d.failures()
+	switch {
+	case failed == 0:
+		return nil
+	case failed == len(d.results):
+		return fmt.Errorf("all %d downloads failed", failed)
+	default:
+		return &ExitError{
+			Code: ExitCodePartialFailure,
+			Err:  fmt.Errorf("%d of %d downloads failed", failed, len(d.results)),
+		}
+	}
+}
+
+// failures returns how many results failed
+func (d *urlDownloads) failures() int {
+	failed := 0
+	for _, result := range d.results {
+		if !result.Success {
+			failed++
+		}
+	}
+	return failed
+}
+
+// resultLine formats a result as tab-separated status, ID and file or
+// error, for reading with cut or awk
+func resultLine(result bridge.ItemResult) string {
+	if !result.Success {
+		return "failed\t" + result.ID + "\t" + strings.Join(strings.Fields(result.Error), " ")
+	}
+	if id, ok := result.Data["duplicate"].(string); ok {
+		return "skipped\t" + result.ID + "\t" + id
+	}
+	filePath, _ := result.Data["file_path"].(string)
+	return "ok\t" + result.ID + "\t" + filePath
+}
@@ -0,0 +1,562 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/archive"
+	"github.com/converso-empire/cli/pkg/auth"
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/events"
+	"github.com/converso-empire/cli/pkg/plugin"
+	"github.com/converso-empire/cli/pkg/subscriptions"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/converso-empire/cli/pkg/worker"
+	"github.com/spf13/cobra"
+)
+
+// newSubscribeCmd creates the youtube subscribe command
+func newSubscribeCmd(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "subscribe [channel]",
+		Short: "Subscribe to a YouTube channel",
+		Long: `Subscribe to a YouTube channel so 'converso youtube sync' downloads its
+new uploads. Channels can be given as a URL, an @handle or a channel ID.
+
+Subscriptions can also be imported from a Google Takeout subscriptions.csv
+or a text file with one channel per line.
+
+Examples:
+  converso youtube subscribe @example
+  converso youtube subscribe https://www.youtube.com/@example --min-duration 2m
+  converso youtube subscribe @example --title-match "(?i)weekly update"
+  converso youtube subscribe --import subscriptions.csv`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSubscribe(cmd, args, cfg)
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+
+	cmd.Flags().Duration("min-duration", 0, "Skip uploads shorter than this (e.g. 60s)")
+	cmd.Flags().String("title-match", "", "Only download uploads whose title matches this regular expression")
+	cmd.Flags().String("output-dir", "", "Output directory (default: ~/Downloads/Converso_YT/<channel>)")
+	cmd.Flags().String("import", "", "Import channels from a Takeout CSV or a text file")
+
+	return cmd
+}
+
+// newUnsubscribeCmd creates the youtube unsubscribe command
+func newUnsubscribeCmd(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "unsubscribe <channel>",
+		Short: "Remove a channel subscription",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := subscriptions.Load(subscriptions.DefaultPath(cfg))
+			if err != nil {
+				return err
+			}
+			if err := store.Remove(args[0]); err != nil {
+				return err
+			}
+			if err := store.Save(); err != nil {
+				return err
+			}
+
+			fmt.Printf("✅ Unsubscribed from %s\n", subscriptions.NormalizeChannel(args[0]))
+			return nil
+		},
+		Annotations: changesSetupAnnotations(""),
+	}
+}
+
+// newSubscriptionsCmd creates the youtube subscriptions command
+func newSubscriptionsCmd(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "subscriptions",
+		Short: "List channel subscriptions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := subscriptions.Load(subscriptions.DefaultPath(cfg))
+			if err != nil {
+				return err
+			}
+
+			if len(store.Subscriptions) == 0 {
+				fmt.Println("No subscriptions. Add one with 'converso youtube subscribe <channel>'.")
+				return nil
+			}
+
+			list := &listOutput{Columns: []string{"channel", "title", "min_duration", "title_match", "output_dir", "added", "last_sync"}}
+			list.Defaults = []string{"channel", "min_duration", "title_match", "last_sync"}
+			for _, sub := range store.Subscriptions {
+				minDuration := ""
+				if sub.MinDuration > 0 {
+					minDuration = formatSeconds(sub.MinDuration)
+				}
+				lastSync := "never"
+				if !sub.LastSync.IsZero() {
+					lastSync = terminal.FormatDateTime(sub.LastSync.Local())
+				}
+				list.Add(sub, sub.Channel, sub.Title, minDuration, sub.TitleMatch, sub.OutputDir,
+					terminal.FormatDateTime(sub.AddedAt.Local()), lastSync)
+			}
+			return printList(cmd, list)
+		},
+	}
+}
+
+// newSyncCmd creates the youtube sync command
+func newSyncCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Download new uploads from subscribed channels",
+		Long: `Check every subscribed channel for new uploads and download the ones
+matching its filters. Downloaded videos are recorded in the download archive
+so they are not downloaded again.
+
+With --watch, sync keeps running and checks again every --interval.
+
+Examples:
+  converso youtube sync
+  converso youtube sync --watch
+  converso youtube sync --watch --interval 1h`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSync(cmd, cfg, logger)
+		},
+	}
+
+	cmd.Flags().Bool("watch", false, "Keep running and sync periodically")
+	cmd.Flags().Duration("interval", 0, "Time between syncs with --watch (default from config, 6h)")
+	cmd.Flags().Bool("dry-run", false, "Show which uploads would be downloaded without downloading")
+	addBatchFlags(cmd)
+
+	return cmd
+}
+
+// runSubscribe adds one channel or imports a list of channels
+func runSubscribe(cmd *cobra.Command, args []string, cfg *config.Config) error {
+	minDuration, _ := cmd.Flags().GetDuration("min-duration")
+	titleMatch, _ := cmd.Flags().GetString("title-match")
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+	importFile, _ := cmd.Flags().GetString("import")
+
+	if len(args) == 0 && importFile == "" {
+		return fmt.Errorf("a channel or --import file is required")
+	}
+
+	store, err := subscriptions.Load(subscriptions.DefaultPath(cfg))
+	if err != nil {
+		return err
+	}
+
+	// Collect the channels to add
+	var entries []*subscriptions.Subscription
+	if len(args) == 1 {
+		entries = append(entries, &subscriptions.Subscription{Channel: args[0]})
+	}
+	if importFile != "" {
+		imported, err := readSubscriptionImport(importFile)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, imported...)
+	}
+
+	added, skipped := 0, 0
+	for _, sub := range entries {
+		sub.MinDuration = int(minDuration.Seconds())
+		sub.TitleMatch = titleMatch
+		sub.OutputDir = outputDir
+
+		if store.Find(sub.Channel) != nil {
+			skipped++
+			continue
+		}
+		if err := store.Add(sub); err != nil {
+			return err
+		}
+		added++
+		fmt.Printf("✅ Subscribed to %s\n", sub.Channel)
+	}
+
+	if err := store.Save(); err != nil {
+		return err
+	}
+
+	if skipped > 0 {
+		fmt.Printf("⏭️  Skipped %d channel(s) already subscribed\n", skipped)
+	}
+	if importFile != "" {
+		fmt.Printf("📋 Imported %d subscription(s)\n", added)
+	}
+	return nil
+}
+
+// readSubscriptionImport reads channels from a Google Takeout
+// subscriptions.csv or a plain text file with one channel per line
+func readSubscriptionImport(path string) ([]*subscriptions.Subscription, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	header, err := reader.Peek(64)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+
+	// Takeout exports start with a "Channel Id,Channel Url,Channel Title" header
+	if strings.HasPrefix(strings.TrimPrefix(string(header), "\ufeff"), "Channel Id,") {
+		return readTakeoutCSV(reader)
+	}
+
+	var subs []*subscriptions.Subscription
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		subs = append(subs, &subscriptions.Subscription{Channel: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+	return subs, nil
+}
+
+// readTakeoutCSV parses a Google Takeout subscriptions export
+func readTakeoutCSV(r io.Reader) ([]*subscriptions.Subscription, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Takeout CSV: %w", err)
+	}
+
+	var subs []*subscriptions.Subscription
+	for _, record := range records[1:] {
+		if len(record) < 2 || record[1] == "" {
+			continue
+		}
+		sub := &subscriptions.Subscription{Channel: record[1]}
+		if len(record) > 2 {
+			sub.Title = record[2]
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// runSync syncs subscriptions once, or periodically with --watch
+func runSync(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) error {
+	watch, _ := cmd.Flags().GetBool("watch")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	if interval <= 0 {
+		interval = cfg.Subscriptions.SyncInterval
+	}
+	if interval <= 0 {
+		interval = config.DefaultSyncInterval
+	}
+
+	// Load authentication
+	tokens, err := loadAuthTokens(cmd.Context(), cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	// Initialize plugin system
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	// Check if YouTube module is available
+	moduleInfo, err := registry.GetModuleInfo("youtube")
+	if err != nil {
+		return fmt.Errorf("YouTube module not found: %w", err)
+	}
+	if !moduleInfo.HasCommand("channel_videos") {
+		return fmt.Errorf("YouTube module v%s does not support channel syncing; update the module", moduleInfo.Manifest.Version)
+	}
+
+	syncer := &channelSyncer{
+		cmd:      cmd,
+		cfg:      cfg,
+		logger:   logger,
+		registry: registry,
+		tokens:   tokens,
+		dryRun:   dryRun,
+	}
+
+	if !watch {
+		return syncer.Sync(cmd.Context())
+	}
+
+	// Run until interrupted
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("👀 Syncing subscriptions every %s (Ctrl+C to stop)\n", interval)
+	scheduler := worker.NewScheduler(logger)
+	scheduler.Add(&worker.ScheduledTask{
+		Name:     "youtube-sync",
+		Interval: interval,
+		Run:      syncer.Sync,
+	})
+
+	if err := scheduler.Run(ctx); err != nil && err != context.Canceled {
+		return err
+	}
+	fmt.Println("\n👋 Sync stopped")
+	return nil
+}
+
+// channelSyncer downloads new uploads from subscribed channels
+type channelSyncer struct {
+	cmd      *cobra.Command
+	cfg      *config.Config
+	logger   telemetry.Logger
+	registry *plugin.PluginRegistry
+	tokens   *auth.AuthTokens
+	dryRun   bool
+}
+
+// channelVideo is an upload listed by the youtube channel_videos command
+type channelVideo struct {
+	ID       string
+	Title    string
+	Duration int
+	URL      string
+}
+
+// Sync checks every subscription once. Subscriptions and the archive are
+// reloaded on each run so changes made while watching are picked up.
+func (s *channelSyncer) Sync(ctx context.Context) error {
+	batch := newBatchRun(s.cmd)
+	return batch.Finish(s.sync(ctx, batch))
+}
+
+// sync runs a single sync, recording each download in batch
+func (s *channelSyncer) sync(ctx context.Context, batch *batchRun) error {
+	store, err := subscriptions.Load(subscriptions.DefaultPath(s.cfg))
+	if err != nil {
+		return err
+	}
+	if len(store.Subscriptions) == 0 {
+		fmt.Println("No subscriptions. Add one with 'converso youtube subscribe <channel>'.")
+		return nil
+	}
+
+	downloads, err := archive.Open(archive.DefaultPath(s.cfg))
+	if err != nil {
+		return err
+	}
+
+	downloaded, failed := 0, 0
+	for _, sub := range store.Subscriptions {
+		if ctx != nil && ctx.Err() != nil {
+			break
+		}
+
+		n, err := s.syncChannel(ctx, sub, downloads, batch)
+		downloaded += n
+		if err != nil {
+			failed++
+			fmt.Printf("❌ %s: %v\n", sub.Channel, err)
+			s.logger.Error("Channel sync failed", "channel", sub.Channel, "error", err)
+			if batch.FailFast {
+				batch.Abort()
+				break
+			}
+			continue
+		}
+
+		if !s.dryRun {
+			sub.LastSync = time.Now()
+		}
+	}
+
+	if !s.dryRun {
+		if err := store.Save(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("\n📋 Synced %d channel(s): %d new download(s)", len(store.Subscriptions)-failed, downloaded)
+	if failed > 0 {
+		fmt.Printf(", %d channel(s) failed", failed)
+	}
+	fmt.Println()
+
+	if failed > 0 {
+		return &ExitError{Code: ExitCodePartialFailure, Err: fmt.Errorf("%d of %d channel(s) failed to sync", failed, len(store.Subscriptions))}
+	}
+	return nil
+}
+
+// syncChannel downloads the new, matching uploads of one channel
+func (s *channelSyncer) syncChannel(ctx context.Context, sub *subscriptions.Subscription, downloads *archive.Archive, batch *batchRun) (int, error) {
+	fmt.Printf("\n📺 %s\n", sub.Channel)
+
+	videos, err := s.channelVideos(ctx, sub)
+	if err != nil {
+		batch.Add(bridge.ItemResult{ID: sub.Channel, Error: err.Error()})
+		return 0, err
+	}
+
+	outputDir := sub.OutputDir
+	if outputDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		name := sub.Title
+		if name == "" {
+			name = filepath.Base(sub.Channel)
+		}
+		outputDir = filepath.Join(homeDir, "Downloads", "Converso_YT", strings.TrimPrefix(name, "@"))
+	}
+
+	// Select the new uploads that pass the filters
+	var pending []channelVideo
+	for _, video := range videos {
+		if downloads.Has("youtube", video.ID) {
+			continue
+		}
+
+		accepted, err := sub.Accepts(video.Title, video.Duration)
+		if err != nil {
+			batch.Add(bridge.ItemResult{ID: sub.Channel, Error: err.Error()})
+			return 0, err
+		}
+		if !accepted {
+			s.logger.Debug("Skipping filtered upload", "channel", sub.Channel, "id", video.ID, "title", video.Title)
+			continue
+		}
+
+		pending = append(pending, video)
+	}
+
+	downloaded, failed := 0, 0
+	for i, video := range pending {
+		if s.dryRun {
+			fmt.Printf("  ⏭️  Would download: %s (%s)\n", video.Title, formatSeconds(video.Duration))
+			continue
+		}
+
+		fmt.Printf("  ⬇️  %s (%s)\n", video.Title, formatSeconds(video.Duration))
+		if err := s.download(ctx, video, outputDir); err != nil {
+			failed++
+			batch.Add(bridge.ItemResult{ID: video.URL, Error: err.Error()})
+			fmt.Printf("  ❌ %s: %v\n", video.Title, err)
+
+			// Continue with the next upload unless --fail-fast was given
+			if batch.FailFast {
+				batch.Skip(len(pending) - i - 1)
+				return downloaded, fmt.Errorf("failed to download %s: %w", video.ID, err)
+			}
+			continue
+		}
+		if err := downloads.Add("youtube", video.ID); err != nil {
+			return downloaded, err
+		}
+		batch.Add(bridge.ItemResult{ID: video.URL, Success: true, Data: map[string]interface{}{"title": video.Title}})
+		downloaded++
+	}
+
+	if failed > 0 {
+		return downloaded, fmt.Errorf("%d of %d download(s) failed", failed, len(pending))
+	}
+	if downloaded == 0 && !s.dryRun {
+		fmt.Println("  ✅ Up to date")
+	}
+	return downloaded, nil
+}
+
+// channelVideos lists the recent uploads of a subscribed channel
+func (s *channelSyncer) channelVideos(ctx context.Context, sub *subscriptions.Subscription) ([]channelVideo, error) {
+	limit := s.cfg.Subscriptions.MaxVideos
+	if limit <= 0 {
+		limit = config.DefaultSyncMaxVideos
+	}
+
+	resp, err := s.registry.ExecuteCommand(ctx, "youtube", "channel_videos", map[string]interface{}{
+		"channel": sub.Channel,
+		"limit":   limit,
+	}, s.tokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list uploads: %w", err)
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("failed to list uploads: %s", resp.Error)
+	}
+
+	data := bridge.Fields(resp.Data)
+	if title := data.String("title"); title != "" && sub.Title == "" {
+		sub.Title = title
+	}
+
+	var videos []channelVideo
+	for _, entry := range data.List("videos") {
+		info := bridge.DecodeVideoInfo(entry)
+		if info.ID == "" || info.URL == "" {
+			continue
+		}
+		videos = append(videos, channelVideo{
+			ID:       info.ID,
+			Title:    info.Title,
+			URL:      info.URL,
+			Duration: int(info.Duration.Seconds()),
+		})
+	}
+	return videos, nil
+}
+
+// download downloads a single upload with progress
+func (s *channelSyncer) download(ctx context.Context, video channelVideo, outputDir string) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	download := events.Download{Module: "youtube", Command: "download", URL: video.URL}
+	events.Publish(events.DownloadStarted, download)
+
+	progressChan := make(chan *bridge.ProgressEvent, 100)
+	progressDone := watchProgress(progressChan)
+
+	resp, err := s.registry.ExecuteCommandWithProgress(ctx, "youtube", "download", map[string]interface{}{
+		"url":        video.URL,
+		"mode":       "best",
+		"container":  "mp4",
+		"output_dir": outputDir,
+	}, s.tokens, progressChan)
+	close(progressChan)
+	<-progressDone
+	download.Response, download.Err = resp, err
+	events.Publish(events.DownloadCompleted, download)
+
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("%s", resp.Error)
+	}
+	return nil
+}
@@ -0,0 +1,661 @@
+package commands
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fips"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
+	"github.com/converso-empire/cli/pkg/worker"
+	"github.com/spf13/cobra"
+)
+
+// NewWorkerCmd creates the worker command
+func NewWorkerCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
+	workerCmd := &cobra.Command{
+		Use:   "worker",
+		Short: "Run and inspect the background worker",
+		Long: `The background worker runs jobs queued from the web app. Heavy jobs
+(downloads and conversions) are paused on battery below worker.min_battery_percent
+or on metered connections, and resume automatically.`,
+	}
+
+	// Start command
+	startCmd := &cobra.Command{
+		Use:   "start",
+		Short: "Run the background worker",
+		Long: `Run the background worker until interrupted. SIGINT and SIGTERM stop it
+gracefully: the health endpoint goes down first and the running job is
+allowed to finish. Only one worker runs per data directory; it holds
+worker.pid there while it runs.
+
+With --daemon the worker runs in the background instead, writing its console
+output to logs/worker.out in the data directory. Stop it with 'converso
+worker stop' and follow it with 'converso worker logs --follow'.
+
+With --health-addr (default :8787 in headless mode) the worker serves
+probe endpoints for orchestrators such as Kubernetes:
+
+  /healthz  liveness: HTTP 200 while the worker is running
+  /readyz   readiness: HTTP 200 while the worker is running, credentials
+            are valid, plugins are loaded and the backend is reachable
+
+Both return 503 otherwise, with the failing checks in the JSON body.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWorkerStart(cmd, cfg, logger)
+		},
+	}
+	startCmd.Flags().String("health-addr", "", "Serve the health endpoint on this address (e.g. :8787)")
+	startCmd.Flags().Bool("daemon", false, "Run the worker in the background")
+	workerCmd.AddCommand(startCmd)
+
+	// Stop command
+	stopCmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the background worker",
+		Long: `Stop the worker running on this machine and wait for it to exit. The
+running job is allowed to finish first; with --kill the worker is killed if
+it has not stopped within --wait.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWorkerStop(cmd, cfg)
+		},
+	}
+	stopCmd.Flags().Duration("wait", 2*time.Minute, "Maximum time to wait for the running job to finish")
+	stopCmd.Flags().Bool("kill", false, "Kill the worker if it has not stopped within --wait")
+	workerCmd.AddCommand(stopCmd)
+
+	// Logs command
+	logsCmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Show the log of the background worker",
+		Long: `Show the log lines of the latest worker run. With --follow new lines are
+shown as they are written, across worker restarts, until interrupted.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWorkerLogs(cmd, cfg)
+		},
+	}
+	logsCmd.Flags().IntP("lines", "n", 50, "Number of recent lines to show (0 shows all)")
+	logsCmd.Flags().BoolP("follow", "f", false, "Keep showing new lines")
+	workerCmd.AddCommand(logsCmd)
+
+	// Healthcheck command
+	healthcheckCmd := &cobra.Command{
+		Use:   "healthcheck",
+		Short: "Exit non-zero unless the worker is healthy",
+		Long: `Check the health endpoint of a running worker and exit with status 0 when
+it is healthy and 1 otherwise, for use as a Docker HEALTHCHECK:
+
+  HEALTHCHECK CMD ["converso", "--headless", "worker", "healthcheck"]
+
+With --ready the readiness endpoint is checked instead of liveness.
+Without a health endpoint the worker status file is checked instead. The
+endpoint must answer within --timeout, 3s by default.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWorkerHealthcheck(cmd, cfg)
+		},
+	}
+	healthcheckCmd.Flags().String("addr", "", "Health endpoint address of the worker (default: worker.health_addr)")
+	healthcheckCmd.Flags().Bool("ready", false, "Check readiness instead of liveness")
+	workerCmd.AddCommand(healthcheckCmd)
+
+	// Status command
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show worker, power and network status",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWorkerStatus(cmd, cfg)
+		},
+	}
+	workerCmd.AddCommand(statusCmd)
+
+	return workerCmd
+}
+
+// runWorkerStart runs the worker until interrupted
+func runWorkerStart(cmd *cobra.Command, cfg *config.Config, logger telemetry.Logger) error {
+	if daemon, _ := cmd.Flags().GetBool("daemon"); daemon {
+		return startWorkerDaemon(cfg)
+	}
+
+	healthAddr, _ := cmd.Flags().GetString("health-addr")
+	if healthAddr == "" {
+		healthAddr = workerHealthAddr(cfg)
+	}
+
+	// Handle signals before starting so an early SIGTERM is not fatal
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// One worker per data directory
+	pidPath := worker.PIDPath(cfg)
+	if err := worker.WritePIDFile(pidPath); err != nil {
+		return err
+	}
+	defer func() {
+		if err := worker.RemovePIDFile(pidPath); err != nil {
+			logger.Warn("Failed to remove PID file", "error", err)
+		}
+	}()
+	ctx = watchStopRequest(ctx, cfg, logger)
+
+	w := worker.NewWorker(cfg, logger)
+
+	// Report plugin registry state on the readiness endpoint
+	registry, registryErr := newPluginRegistry(cfg, logger)
+	w.AddReadinessCheck("plugins", func(ctx context.Context) error {
+		if registryErr != nil {
+			return registryErr
+		}
+		if len(registry.ListModules()) == 0 {
+			return fmt.Errorf("no plugins loaded from %s", cfg.PluginsDir)
+		}
+		return nil
+	})
+
+	if err := cfg.Cleanup.Validate(); err != nil {
+		return fmt.Errorf("invalid cleanup rules: %w", err)
+	}
+	if err := w.Start(); err != nil {
+		return fmt.Errorf("failed to start worker: %w", err)
+	}
+
+	// Apply the retention rules on their interval
+	if cfg.Cleanup.Interval > 0 && len(cfg.Cleanup.Rules) > 0 {
+		scheduler := worker.NewScheduler(logger)
+		scheduler.Add(&worker.ScheduledTask{
+			Name:     "cleanup",
+			Interval: cfg.Cleanup.Interval,
+			Run: func(ctx context.Context) error {
+				return cleanupDownloads(cfg, logger)
+			},
+		})
+		go scheduler.Run(ctx)
+	}
+
+	// Start control API for 'converso --remote'
+	var control *http.Server
+	if cfg.Worker.ControlAddr != "" {
+		var err error
+		if control, err = serveControl(cfg, logger, w); err != nil {
+			w.Stop()
+			return err
+		}
+	}
+
+	// Start health endpoint
+	var server *http.Server
+	if healthAddr != "" {
+		listener, err := net.Listen("tcp", healthAddr)
+		if err != nil {
+			if control != nil {
+				control.Close()
+			}
+			w.Stop()
+			return fmt.Errorf("failed to start health endpoint: %w", err)
+		}
+		server = &http.Server{Handler: w.HealthHandler(), ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+				logger.Error("Health endpoint failed", "error", err)
+			}
+		}()
+		logger.Info("Health endpoint listening", "url", worker.ProbeURL(listener.Addr().String(), worker.HealthPath))
+	}
+
+	status := w.Status()
+	fmt.Println("🚀 Worker started (Ctrl+C to stop)")
+	if status.Paused {
+		fmt.Printf("⏸️  Heavy jobs paused: %s\n", status.PauseReason)
+	}
+
+	<-ctx.Done()
+	logger.Info("Shutdown requested, waiting for running jobs")
+
+	// Report unhealthy first so orchestrators stop routing to this instance
+	for _, s := range []*http.Server{server, control} {
+		if s != nil {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			s.Shutdown(shutdownCtx)
+			cancel()
+		}
+	}
+
+	if err := w.Stop(); err != nil {
+		return err
+	}
+	fmt.Println("\n👋 Worker stopped")
+	return nil
+}
+
+// daemonStartTimeout bounds how long 'worker start --daemon' waits for the
+// background worker to take the PID file
+const daemonStartTimeout = 15 * time.Second
+
+// startWorkerDaemon runs 'worker start' again in the background, without
+// --daemon, and waits until the worker is up
+func startWorkerDaemon(cfg *config.Config) error {
+	if pid, err := worker.RunningPID(cfg); err != nil {
+		return err
+	} else if pid != 0 {
+		return fmt.Errorf("worker is already running (pid %d)", pid)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the converso executable: %w", err)
+	}
+	var args []string
+	for _, arg := range os.Args[1:] {
+		if arg != "--daemon" && arg != "--daemon=true" {
+			args = append(args, arg)
+		}
+	}
+
+	logPath := worker.ConsoleLogPath(cfg)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	console, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open worker log: %w", err)
+	}
+	defer console.Close()
+
+	daemon := exec.Command(executable, args...)
+	daemon.Stdout = console
+	daemon.Stderr = console
+	daemon.SysProcAttr = worker.DetachedProcAttr()
+	if err := daemon.Start(); err != nil {
+		return fmt.Errorf("failed to start worker: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- daemon.Wait()
+	}()
+
+	// The worker is up once it holds the PID file
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(daemonStartTimeout)
+	for {
+		if pid, _ := worker.ReadPIDFile(worker.PIDPath(cfg)); pid == daemon.Process.Pid {
+			break
+		}
+		select {
+		case err := <-exited:
+			return fmt.Errorf("worker exited while starting (%v); see %s", err, logPath)
+		case <-timeout:
+			return fmt.Errorf("worker did not start within %s; see %s", daemonStartTimeout, logPath)
+		case <-ticker.C:
+		}
+	}
+
+	fmt.Printf("🚀 Worker started in the background (pid %d)\n", daemon.Process.Pid)
+	fmt.Println("💡 Follow it with 'converso worker logs --follow', stop it with 'converso worker stop'")
+	return nil
+}
+
+// watchStopRequest returns a context that also ends when 'worker stop'
+// leaves a stop request, as it does where there is no SIGTERM
+func watchStopRequest(ctx context.Context, cfg *config.Config, logger telemetry.Logger) context.Context {
+	path := worker.StopRequestPath(cfg)
+	os.Remove(path)
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		defer cancel()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if _, err := os.Stat(path); err == nil {
+				os.Remove(path)
+				logger.Info("Stop requested")
+				return
+			}
+		}
+	}()
+	return ctx
+}
+
+// runWorkerStop stops the local worker and waits for it to exit
+func runWorkerStop(cmd *cobra.Command, cfg *config.Config) error {
+	wait, _ := cmd.Flags().GetDuration("wait")
+	kill, _ := cmd.Flags().GetBool("kill")
+
+	pid, err := worker.RunningPID(cfg)
+	if err != nil {
+		return err
+	}
+	if pid == 0 {
+		fmt.Println("⚪ Worker not running")
+		return nil
+	}
+
+	if err := worker.RequestStop(cfg, pid); err != nil {
+		return fmt.Errorf("failed to stop worker (pid %d): %w", pid, err)
+	}
+	fmt.Printf("⏳ Stopping worker (pid %d), letting the running job finish...\n", pid)
+
+	deadline := time.Now().Add(wait)
+	for worker.ProcessAlive(pid) {
+		if time.Now().After(deadline) {
+			if !kill {
+				return fmt.Errorf("worker (pid %d) did not stop within %s; use --kill to kill it", pid, wait)
+			}
+			if process, err := os.FindProcess(pid); err == nil {
+				if err := process.Kill(); err != nil {
+					return fmt.Errorf("failed to kill worker (pid %d): %w", pid, err)
+				}
+			}
+			// A killed worker cannot remove its PID file
+			os.Remove(worker.PIDPath(cfg))
+			fmt.Printf("⚠️  Worker did not stop within %s and was killed\n", wait)
+			return nil
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	fmt.Println("👋 Worker stopped")
+	return nil
+}
+
+// runWorkerLogs prints the log of the latest worker run, following it with
+// --follow
+func runWorkerLogs(cmd *cobra.Command, cfg *config.Config) error {
+	lines, _ := cmd.Flags().GetInt("lines")
+	follow, _ := cmd.Flags().GetBool("follow")
+
+	status, err := worker.ReadStatus(worker.StatusPath(cfg))
+	if err != nil {
+		return err
+	}
+	if status == nil || status.RunID == "" {
+		return fmt.Errorf("no worker logs yet; start the worker with 'converso worker start'")
+	}
+
+	logDir := telemetry.LogDir(cfg.DataDir)
+	entries, err := telemetry.RunLogs(logDir, status.RunID)
+	if err != nil {
+		return err
+	}
+	start := 0
+	if lines > 0 && len(entries) > lines {
+		start = len(entries) - lines
+	}
+	for _, entry := range entries[start:] {
+		fmt.Println(formatLogEntry(entry))
+	}
+	if !follow {
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	runID, shown := status.RunID, len(entries)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		// Follow the worker across restarts
+		if status, err := worker.ReadStatus(worker.StatusPath(cfg)); err == nil && status != nil && status.RunID != runID {
+			runID, shown = status.RunID, 0
+			fmt.Printf("🔄 Worker restarted (run %s)\n", runID)
+		}
+		entries, err := telemetry.RunLogs(logDir, runID)
+		if err != nil {
+			return err
+		}
+		if len(entries) < shown {
+			// The log was rotated
+			shown = 0
+		}
+		for _, entry := range entries[shown:] {
+			fmt.Println(formatLogEntry(entry))
+		}
+		shown = len(entries)
+	}
+}
+
+// serveControl serves the worker control API on worker.control_addr. TLS
+// is required unless the address is loopback only.
+func serveControl(cfg *config.Config, logger telemetry.Logger, w *worker.Worker) (*http.Server, error) {
+	addr := cfg.Worker.ControlAddr
+	useTLS := cfg.Worker.ControlTLSCert != "" || cfg.Worker.ControlTLSKey != ""
+	if !useTLS && !worker.IsLoopback(addr) {
+		return nil, fmt.Errorf("control API on %s requires TLS: set worker.control_tls_cert and worker.control_tls_key, or listen on 127.0.0.1", addr)
+	}
+
+	token, err := worker.ControlToken(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start control API: %w", err)
+	}
+	if useTLS {
+		tlsConfig, err := controlTLSConfig(cfg)
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	server := &http.Server{Handler: w.ControlHandler(token), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Error("Control API failed", "error", err)
+		}
+	}()
+	logger.Info("Control API listening", "addr", listener.Addr().String(), "tls", useTLS)
+
+	tokenSource := worker.ControlTokenPath(cfg)
+	if secret, _ := config.Secret("worker_control_token"); secret != "" {
+		tokenSource = "CONVERSO_WORKER_CONTROL_TOKEN"
+	}
+	fmt.Printf("🔌 Remote control on %s\n", listener.Addr())
+	fmt.Printf("🔑 Clients set CONVERSO_REMOTE_TOKEN to the control token (%s)\n", tokenSource)
+	return server, nil
+}
+
+// controlTLSConfig loads the certificate of the control API
+func controlTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.Worker.ControlTLSCert == "" || cfg.Worker.ControlTLSKey == "" {
+		return nil, fmt.Errorf("control API TLS needs both worker.control_tls_cert and worker.control_tls_key")
+	}
+	certFile, err := config.ExpandPath(cfg.Worker.ControlTLSCert)
+	if err != nil {
+		return nil, err
+	}
+	keyFile, err := config.ExpandPath(cfg.Worker.ControlTLSKey)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load control API certificate: %w", err)
+	}
+
+	tlsConfig := fips.TLSConfig()
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	return tlsConfig, nil
+}
+
+// healthcheckTimeout is how long worker healthcheck waits for the health
+// endpoint without --timeout
+const healthcheckTimeout = 3 * time.Second
+
+// runWorkerHealthcheck exits non-zero unless the worker is healthy
+func runWorkerHealthcheck(cmd *cobra.Command, cfg *config.Config) error {
+	addr, _ := cmd.Flags().GetString("addr")
+	timeout := cfg.Timeouts.TotalOverride
+	if timeout <= 0 {
+		timeout = healthcheckTimeout
+	}
+	ready, _ := cmd.Flags().GetBool("ready")
+	if addr == "" {
+		addr = workerHealthAddr(cfg)
+	}
+
+	// Without a health endpoint fall back to the published status, which
+	// carries no readiness information
+	if addr == "" {
+		if ready {
+			return fmt.Errorf("--ready requires a health endpoint (set --addr or worker.health_addr)")
+		}
+		status, err := worker.ReadStatus(worker.StatusPath(cfg))
+		if err != nil {
+			return err
+		}
+		if status == nil || !status.Running || status.Stale(cfg.Worker.CheckInterval) {
+			return fmt.Errorf("worker is not running")
+		}
+		fmt.Println("healthy")
+		return nil
+	}
+
+	path, result := worker.HealthPath, "healthy"
+	if ready {
+		path, result = worker.ReadyPath, "ready"
+	}
+	if err := worker.Probe(addr, path, timeout); err != nil {
+		return err
+	}
+	fmt.Println(result)
+	return nil
+}
+
+// workerHealthAddr returns the configured health endpoint address, which
+// defaults to config.DefaultHealthAddr in headless mode
+func workerHealthAddr(cfg *config.Config) string {
+	if cfg.Worker.HealthAddr != "" {
+		return cfg.Worker.HealthAddr
+	}
+	if cfg.Headless {
+		return config.DefaultHealthAddr
+	}
+	return ""
+}
+
+// runWorkerStatus prints the published worker status, or that of the
+// worker given by --remote. When no worker is running the power and
+// network state is detected directly.
+func runWorkerStatus(cmd *cobra.Command, cfg *config.Config) error {
+	var status *worker.Status
+	if cfg.Remote.Addr != "" {
+		remote, err := workerControl(cfg)
+		if err != nil {
+			return err
+		}
+		if status, err = remote.Status(); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if status, err = worker.ReadStatus(worker.StatusPath(cfg)); err != nil {
+			return err
+		}
+	}
+
+	// A remote worker that answers is running, whatever its clock says
+	running := status != nil && status.Running && (cfg.Remote.Addr != "" || !status.Stale(cfg.Worker.CheckInterval))
+	if !running {
+		power := worker.DetectPowerState()
+		reason := worker.PauseReason(cfg.Worker, power)
+		status = &worker.Status{
+			Paused:      reason != "",
+			PauseReason: reason,
+			Power:       power,
+			UpdatedAt:   power.CheckedAt,
+		}
+	}
+	status.Running = running
+
+	if printed, err := printTemplate(cmd, status); printed || err != nil {
+		return err
+	}
+
+	if running {
+		fmt.Printf("🟢 Worker running (pid %d, since %s)\n", status.PID, terminal.FormatDateTime(status.StartedAt.Local()))
+		fmt.Printf("📋 Queued jobs: %d\n", status.QueueSize)
+	} else {
+		fmt.Println("⚪ Worker not running")
+	}
+
+	fmt.Printf("🔋 Power: %s\n", describePower(status.Power))
+	fmt.Printf("📶 Network: %s\n", describeNetwork(status.Power))
+
+	switch {
+	case status.Paused && running:
+		fmt.Printf("⏸️  Heavy jobs paused: %s\n", status.PauseReason)
+	case status.Paused:
+		fmt.Printf("⏸️  Heavy jobs would be paused: %s\n", status.PauseReason)
+	default:
+		fmt.Println("▶️  Heavy jobs allowed")
+	}
+	return nil
+}
+
+// describePower formats the battery state
+func describePower(state worker.PowerState) string {
+	if !state.BatteryKnown {
+		return "unknown (no battery detected)"
+	}
+
+	source := "AC power"
+	if state.OnBattery {
+		source = "battery"
+	}
+	if state.BatteryPercent >= 0 {
+		return fmt.Sprintf("%s, %d%% charged", source, state.BatteryPercent)
+	}
+	return source
+}
+
+// describeNetwork formats the metered connection state
+func describeNetwork(state worker.PowerState) string {
+	switch {
+	case !state.MeteredKnown:
+		return "unknown (metered detection unavailable)"
+	case state.Metered:
+		return "metered"
+	default:
+		return "not metered"
+	}
+}
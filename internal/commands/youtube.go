@@ -1,16 +1,19 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
-	"time"
 
+	"github.com/converso-empire/cli/pkg/archive"
 	"github.com/converso-empire/cli/pkg/auth"
 	"github.com/converso-empire/cli/pkg/bridge"
 	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/events"
 	"github.com/converso-empire/cli/pkg/plugin"
+	"github.com/converso-empire/cli/pkg/query"
 	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
 	"github.com/spf13/cobra"
 )
 
@@ -19,23 +22,54 @@ func NewYouTubeCmd(cfg *config.Config, logger telemetry.Logger) *cobra.Command {
 	youtubeCmd := &cobra.Command{
 		Use:   "youtube",
 		Short: "YouTube module commands",
-		Long:  "Access YouTube downloader and format listing functionality",
+		Long:  "Access YouTube downloader, format listing and channel subscription functionality",
 	}
 
 	// Download command
 	downloadCmd := &cobra.Command{
-		Use:   "download <url>",
-		Short: "Download YouTube video or audio",
+		Use:   "download <url|->...",
+		Short: "Download YouTube videos, playlists or audio",
 		Long: `Download YouTube videos or extract audio with various options.
 
+Several URLs may be given. With "-" as a URL, URLs are read from stdin, and
+with --batch-file from a file, one per line; blank lines, # comments and
+duplicates are skipped. Mirrors may follow a URL on its line, separated by
+spaces, and are tried in order when it fails; history records the mirror
+used as the entry's source. Playlist URLs (youtube.com/playlist?list=...)
+are expanded into their videos. With more than one video, they are
+downloaded up to 'concurrency' at a time with a progress bar each and a
+total, and each gets a result line, tab-separated:
+
+  ok      <url>  <file>
+  failed  <url>  <error>
+
+When stdout is piped, lines are written as downloads finish and progress
+goes to stderr.
+
+--estimate queries the videos' metadata and reports the expected download
+and output sizes, the duration at the throughput of past downloads and the
+disk space used, without downloading.
+
 Examples:
   converso youtube download https://youtube.com/watch?v=example
   converso youtube download https://youtube.com/watch?v=example --mode audio
-  converso youtube download https://youtube.com/watch?v=example --output-dir ./downloads`,
+  converso youtube download https://youtube.com/watch?v=example --output-dir ./downloads
+  converso youtube download https://youtube.com/watch?v=example --preset web-720p
+  converso youtube download https://youtube.com/playlist?list=PLexample --mode audio
+  converso youtube download https://youtu.be/one https://youtu.be/two
+  converso youtube download --batch-file urls.txt
+  converso youtube download https://youtube.com/playlist?list=PLexample --estimate
+  cat urls.txt | converso youtube download - --mode audio | grep ^failed`,
 		
-		Args: cobra.ExactArgs(1),
+		Args: cobra.ArbitraryArgs,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			// Every argument is a URL
+			return moduleArgsCompletion(cfg, logger, "youtube", "download", "url")(cmd, nil, toComplete)
+		},
+		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runYouTubeDownload(cmd, args, cfg, logger)
+			batch := newBatchRun(cmd)
+			return batch.Finish(runYouTubeDownload(cmd, args, cfg, logger, batch))
 		},
 	}
 
@@ -43,8 +77,17 @@ Examples:
 	downloadCmd.Flags().String("mode", "best", "Download mode: audio, video, merge, progressive")
 	downloadCmd.Flags().String("format-id", "", "Specific format ID to download")
 	downloadCmd.Flags().String("container", "mp4", "Output container format")
-	downloadCmd.Flags().String("output-dir", "", "Output directory (default: ~/Downloads/Converso_YT)")
+	downloadCmd.Flags().String("output-dir", "", "Output directory (default: output_dir or ~/Downloads/Converso_YT)")
 	downloadCmd.Flags().Bool("list-formats", false, "List available formats before downloading")
+	downloadCmd.Flags().String("preset", "", "Conversion preset applied after download (see 'converso convert preset list')")
+	downloadCmd.Flags().String("batch-file", "", "Also download the URLs in this file, one per line")
+	addEstimateFlag(downloadCmd)
+	addHWAccelFlag(downloadCmd)
+	addBatchFlags(downloadCmd)
+	addGeoFlags(downloadCmd)
+	addForceFlag(downloadCmd)
+	registerModuleFlagCompletions(downloadCmd, cfg, logger, "youtube", "download", "mode", "container")
+	downloadCmd.RegisterFlagCompletionFunc("preset", completePresets(cfg))
 
 	youtubeCmd.AddCommand(downloadCmd)
 
@@ -55,15 +98,19 @@ Examples:
 		Long: `List all available formats for a YouTube video with details about
 video quality, audio quality, and file sizes.
 
-Example:
-  converso youtube list-formats https://youtube.com/watch?v=example`,
+Examples:
+  converso youtube list-formats https://youtube.com/watch?v=example
+  converso youtube list-formats https://youtube.com/watch?v=example --filter "height>=1080 AND ext=mp4"`,
 		
-		Args: cobra.ExactArgs(1),
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: moduleArgsCompletion(cfg, logger, "youtube", "list_formats", "url"),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runYouTubeListFormats(cmd, args, cfg, logger)
 		},
 	}
 
+	addFilterFlag(listCmd)
+	addGeoFlags(listCmd)
 	youtubeCmd.AddCommand(listCmd)
 
 	// Info command
@@ -76,27 +123,47 @@ uploader, duration, view count, and other metadata.
 Example:
   converso youtube info https://youtube.com/watch?v=example`,
 		
-		Args: cobra.ExactArgs(1),
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: moduleArgsCompletion(cfg, logger, "youtube", "info", "url"),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runYouTubeInfo(cmd, args, cfg, logger)
 		},
 	}
 
+	addGeoFlags(infoCmd)
 	youtubeCmd.AddCommand(infoCmd)
 
+	// Channel subscriptions
+	youtubeCmd.AddCommand(newSubscribeCmd(cfg))
+	youtubeCmd.AddCommand(newUnsubscribeCmd(cfg))
+	youtubeCmd.AddCommand(newSubscriptionsCmd(cfg))
+	youtubeCmd.AddCommand(newSyncCmd(cfg, logger))
+
 	return youtubeCmd
 }
 
 // runYouTubeDownload executes the YouTube download command
-func runYouTubeDownload(cmd *cobra.Command, args []string, cfg *config.Config, logger telemetry.Logger) error {
-	url := args[0]
-	
+func runYouTubeDownload(cmd *cobra.Command, args []string, cfg *config.Config, logger telemetry.Logger, batch *batchRun) error {
 	// Get command flags
 	mode, _ := cmd.Flags().GetString("mode")
 	formatID, _ := cmd.Flags().GetString("format-id")
 	container, _ := cmd.Flags().GetString("container")
-	outputDir, _ := cmd.Flags().GetString("list-formats")
+	outputDir, err := expandedFlag(cmd, "output-dir")
+	if err != nil {
+		return err
+	}
 	listFormats, _ := cmd.Flags().GetBool("list-formats")
+	presetName := presetFlag(cmd, cfg)
+
+	items, err := downloadItems(cmd, args)
+	if err != nil {
+		return err
+	}
+	// A single URL argument downloads on its own unless it is a playlist
+	single := len(items) == 1 && len(args) == 1 && args[0] != stdinArg
+	if listFormats && !single {
+		return fmt.Errorf("--list-formats can only be used with a single URL")
+	}
 
 	// Validate mode
 	validModes := map[string]bool{
@@ -106,41 +173,49 @@ func runYouTubeDownload(cmd *cobra.Command, args []string, cfg *config.Config, l
 		return fmt.Errorf("invalid mode: %s. Valid modes: audio, video, merge, progressive, best", mode)
 	}
 
+	// Resolve post-processing preset
+	var postprocess map[string]interface{}
+	if presetName != "" {
+		preset, err := cfg.Preset(presetName)
+		if err != nil {
+			return err
+		}
+		postprocess = preset.Args()
+		addHWEncoders(cmd, cfg, logger, postprocess)
+	}
+
 	// Set default output directory
 	if outputDir == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return fmt.Errorf("failed to get home directory: %w", err)
+		if outputDir, err = downloadDir(cfg, "Converso_YT"); err != nil {
+			return err
 		}
-		outputDir = filepath.Join(homeDir, "Downloads", "Converso_YT")
 	}
 
-	// Create output directory
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	// Create output directory, unless only estimating
+	if !estimating(cmd) {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
 	}
 
 	// List formats if requested
 	if listFormats {
-		if err := runYouTubeListFormats(cmd, args, cfg, logger); err != nil {
+		if err := runYouTubeListFormats(cmd, items[0][:1], cfg, logger); err != nil {
 			return err
 		}
 		fmt.Println()
 	}
 
 	// Load authentication
-	authManager := auth.NewAuthManager(auth.NewFileStorage(cfg, logger), logger)
-	tokens, err := authManager.storage.RetrieveTokens()
+	tokens, err := loadAuthTokens(cmd.Context(), cfg, logger)
 	if err != nil {
-		return fmt.Errorf("authentication required. Run 'converso login' first: %w", err)
+		return err
 	}
 
 	// Initialize plugin system
-	bridge := bridge.NewJSONBridge(bridge.GetPythonPath(), cfg.PluginsDir, logger)
-	registry := plugin.NewPluginRegistry(cfg, logger, bridge)
-	
-	if err := registry.LoadPlugins(); err != nil {
-		return fmt.Errorf("failed to load plugins: %w", err)
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
 	}
 
 	// Check if YouTube module is available
@@ -148,55 +223,146 @@ func runYouTubeDownload(cmd *cobra.Command, args []string, cfg *config.Config, l
 	if err != nil {
 		return fmt.Errorf("YouTube module not found: %w", err)
 	}
+	warnOutdatedDeps(cfg, logger, moduleInfo)
+
+	options, err := geoOptions(cmd, cfg, moduleInfo.Manifest)
+	if err != nil {
+		return err
+	}
+	downloads := downloadArchive(cmd, cfg, logger)
+
+	expanded, err := expandPlaylists(cmd.Context(), registry, moduleInfo, tokens, items)
+	if err != nil {
+		return err
+	}
+	if len(expanded) != len(items) || expanded[0][0] != items[0][0] {
+		single = false
+	}
+	items = uniqueItems(expanded)
+
+	if estimating(cmd) {
+		urls := make([]string, len(items))
+		for i, sources := range items {
+			urls[i] = sources[0]
+		}
+		estimate := &downloadEstimate{
+			Registry:    registry,
+			Tokens:      tokens,
+			Module:      moduleInfo,
+			Options:     options,
+			Mode:        mode,
+			FormatID:    formatID,
+			Postprocess: postprocess,
+			OutputDir:   outputDir,
+		}
+		return estimate.Run(cmd, cfg, urls)
+	}
+
+	// Prepare arguments
+	downloadArgs := func(url string) map[string]interface{} {
+		argsMap := map[string]interface{}{
+			"url":        url,
+			"mode":       mode,
+			"format_id":  formatID,
+			"container":  container,
+			"output_dir": outputDir,
+			"fail_fast":  batch.FailFast,
+		}
+		for name, value := range options {
+			argsMap[name] = value
+		}
+		if postprocess != nil {
+			argsMap["postprocess"] = postprocess
+		}
+		return argsMap
+	}
+
+	if !single {
+		logger.Info("Starting YouTube downloads",
+			"urls", len(items),
+			"mode", mode,
+			"output_dir", outputDir,
+			"concurrency", cfg.Concurrency,
+		)
+		downloads := &urlDownloads{
+			Registry:    registry,
+			Tokens:      tokens,
+			Module:      "youtube",
+			Logger:      logger,
+			Args:        downloadArgs,
+			Concurrency: cfg.Concurrency,
+			Archive:     downloads,
+		}
+		return downloads.Run(cmd, items, batch)
+	}
+
+	url := items[0][0]
+	if skipDownloaded(downloads, batch, "youtube", url) {
+		return nil
+	}
 
 	logger.Info("Starting YouTube download",
 		"url", url,
 		"mode", mode,
 		"container", container,
 		"output_dir", outputDir,
+		"preset", presetName,
 		"module_version", moduleInfo.Manifest.Version,
 	)
 
-	// Prepare arguments
-	argsMap := map[string]interface{}{
-		"url":         url,
-		"mode":        mode,
-		"format_id":   formatID,
-		"container":   container,
-		"output_dir":  outputDir,
-	}
+	argsMap := downloadArgs(url)
+
+	download := events.Download{Module: "youtube", Command: "download", URL: url}
+	events.Publish(events.DownloadStarted, download)
 
 	// Execute with progress tracking
 	progressChan := make(chan *bridge.ProgressEvent, 100)
-	
-	go func() {
-		for progress := range progressChan {
-			printProgress(progress)
-		}
-	}()
+	controls, stopKeys := watchKeys(cfg)
+	progressDone := watchProgress(progressChan)
 
-	resp, err := registry.ExecuteCommandWithProgress("youtube", "download", argsMap, tokens, progressChan)
+	resp, err := registry.ExecuteCommandWithControl(cmd.Context(), "youtube", "download", argsMap, tokens, progressChan, controls)
+	stopKeys()
 	close(progressChan)
+	<-progressDone
+	download.Response, download.Err = resp, err
+	events.Publish(events.DownloadCompleted, download)
+	batch.Record(url, resp, err)
 
 	if err != nil {
 		return fmt.Errorf("download failed: %w", err)
 	}
 
+	// Batch downloads report per-item results
+	if resp.HasItems() {
+		if err := printItemSummary(cmd, resp); err != nil {
+			return err
+		}
+		if !resp.Success {
+			return fmt.Errorf("download failed: %s", resp.Error)
+		}
+		return itemResultsError(resp)
+	}
+
 	if !resp.Success {
-		return fmt.Errorf("download failed: %s", resp.Error)
+		return geoError(cfg, moduleInfo.Manifest, options, fmt.Errorf("download failed: %s", resp.Error))
+	}
+
+	if handled, err := printTemplate(cmd, resp.Data); handled || err != nil {
+		return err
 	}
 
 	// Print results
-	if result, ok := resp.Data["file_path"].(string); ok {
+	result := bridge.DecodeDownloadResult(resp.Data)
+	if result.FilePath != "" {
 		fmt.Printf("\n✅ Download completed successfully!\n")
-		fmt.Printf("📁 File: %s\n", result)
+		fmt.Printf("📁 File: %s\n", result.FilePath)
 		
-		if fileSize, ok := resp.Data["file_size"].(string); ok {
-			fmt.Printf("📊 Size: %s\n", fileSize)
+		if result.FileSize > 0 {
+			fmt.Printf("📊 Size: %s\n", formatFileSize(result.FileSize))
 		}
 		
-		if duration, ok := resp.Data["duration"].(string); ok {
-			fmt.Printf("⏱️  Duration: %s\n", duration)
+		if result.Duration > 0 {
+			fmt.Printf("⏱️  Duration: %s\n", formatSeconds(int(result.Duration.Seconds())))
 		}
 		
 		fmt.Printf("📍 Output directory: %s\n", outputDir)
@@ -205,55 +371,123 @@ func runYouTubeDownload(cmd *cobra.Command, args []string, cfg *config.Config, l
 	return nil
 }
 
+// expandPlaylists replaces the playlist URLs among items with the URLs of
+// their videos, listed with the module's playlist_videos command. Modules
+// without it get playlist URLs as they are.
+func expandPlaylists(ctx context.Context, registry *plugin.PluginRegistry, module *plugin.ModuleInfo, tokens *auth.AuthTokens, items [][]string) ([][]string, error) {
+	if !module.HasCommand("playlist_videos") {
+		return items, nil
+	}
+
+	var expanded [][]string
+	for _, sources := range items {
+		if archive.PlaylistID(sources[0]) == "" {
+			expanded = append(expanded, sources)
+			continue
+		}
+
+		resp, err := registry.ExecuteCommand(ctx, "youtube", "playlist_videos", map[string]interface{}{
+			"playlist": sources[0],
+		}, tokens)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list playlist %s: %w", sources[0], err)
+		}
+		if !resp.Success {
+			return nil, fmt.Errorf("failed to list playlist %s: %s", sources[0], resp.Error)
+		}
+
+		data := bridge.Fields(resp.Data)
+		videos := data.List("videos")
+		title := data.String("title")
+		if title == "" {
+			title = sources[0]
+		}
+		fmt.Fprintf(os.Stderr, "📋 Playlist %s: %d videos\n", title, len(videos))
+		for _, entry := range videos {
+			if info := bridge.DecodeVideoInfo(entry); info.URL != "" {
+				expanded = append(expanded, []string{info.URL})
+			}
+		}
+	}
+	if len(expanded) == 0 {
+		return nil, fmt.Errorf("no videos to download: the playlists are empty")
+	}
+	return expanded, nil
+}
+
 // runYouTubeListFormats executes the list formats command
 func runYouTubeListFormats(cmd *cobra.Command, args []string, cfg *config.Config, logger telemetry.Logger) error {
 	url := args[0]
 
+	// Format fields vary by site, so any field name is accepted
+	filter, err := filterFromFlags(cmd, nil)
+	if err != nil {
+		return err
+	}
+
 	// Load authentication
-	authManager := auth.NewAuthManager(auth.NewFileStorage(cfg, logger), logger)
-	tokens, err := authManager.storage.RetrieveTokens()
+	tokens, err := loadAuthTokens(cmd.Context(), cfg, logger)
 	if err != nil {
-		return fmt.Errorf("authentication required. Run 'converso login' first: %w", err)
+		return err
 	}
 
 	// Initialize plugin system
-	bridge := bridge.NewJSONBridge(bridge.GetPythonPath(), cfg.PluginsDir, logger)
-	registry := plugin.NewPluginRegistry(cfg, logger, bridge)
-	
-	if err := registry.LoadPlugins(); err != nil {
-		return fmt.Errorf("failed to load plugins: %w", err)
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
 	}
 
 	// Check if YouTube module is available
-	if _, err := registry.GetModuleInfo("youtube"); err != nil {
+	moduleInfo, err := registry.GetModuleInfo("youtube")
+	if err != nil {
 		return fmt.Errorf("YouTube module not found: %w", err)
 	}
+	warnOutdatedDeps(cfg, logger, moduleInfo)
 
 	logger.Info("Listing YouTube formats", "url", url)
 
+	options, err := geoOptions(cmd, cfg, moduleInfo.Manifest)
+	if err != nil {
+		return err
+	}
+	options["url"] = url
+
 	// Execute command
-	resp, err := registry.ExecuteCommand("youtube", "list_formats", map[string]interface{}{"url": url}, tokens)
+	resp, err := registry.ExecuteCommand(cmd.Context(), "youtube", "list_formats", options, tokens)
 	if err != nil {
 		return fmt.Errorf("failed to list formats: %w", err)
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("failed to list formats: %s", resp.Error)
+		return geoError(cfg, moduleInfo.Manifest, options, fmt.Errorf("failed to list formats: %s", resp.Error))
+	}
+
+	// Custom columns and templates use the shared formatter
+	formats := bridge.DecodeFormats(resp.Data)
+	if outputFlagsSet(cmd) {
+		return printFormatList(cmd, formats, filter)
 	}
 
 	// Print results
-	if formats, ok := resp.Data["formats"].([]interface{}); ok {
+	if _, ok := resp.Data["formats"]; ok {
 		fmt.Printf("\n📹 Available Formats for: %s\n", url)
 		fmt.Println("=" + fmt.Sprintf("%s", url)[:len(url)-1] + "=")
 		
+		shown := 0
 		for i, format := range formats {
-			if formatMap, ok := format.(map[string]interface{}); ok {
-				printFormat(i, formatMap)
+			if filter != nil && !filter.Match(query.MapGetter(format.Fields)) {
+				continue
 			}
+			printFormat(i, format)
+			shown++
 		}
 		
-		if totalCount, ok := resp.Data["total_count"].(float64); ok {
-			fmt.Printf("\n📋 Total formats available: %.0f\n", totalCount)
+		if totalCount := bridge.Fields(resp.Data).Int("total_count"); totalCount > 0 {
+			if filter != nil {
+				fmt.Printf("\n📋 %d of %d formats match: %s\n", shown, totalCount, filter)
+			} else {
+				fmt.Printf("\n📋 Total formats available: %d\n", totalCount)
+			}
 		}
 	}
 
@@ -265,134 +499,155 @@ func runYouTubeInfo(cmd *cobra.Command, args []string, cfg *config.Config, logge
 	url := args[0]
 
 	// Load authentication
-	authManager := auth.NewAuthManager(auth.NewFileStorage(cfg, logger), logger)
-	tokens, err := authManager.storage.RetrieveTokens()
+	tokens, err := loadAuthTokens(cmd.Context(), cfg, logger)
 	if err != nil {
-		return fmt.Errorf("authentication required. Run 'converso login' first: %w", err)
+		return err
 	}
 
 	// Initialize plugin system
-	bridge := bridge.NewJSONBridge(bridge.GetPythonPath(), cfg.PluginsDir, logger)
-	registry := plugin.NewPluginRegistry(cfg, logger, bridge)
-	
-	if err := registry.LoadPlugins(); err != nil {
-		return fmt.Errorf("failed to load plugins: %w", err)
+	registry, err := newPluginRegistry(cfg, logger)
+	if err != nil {
+		return err
 	}
 
 	// Check if YouTube module is available
-	if _, err := registry.GetModuleInfo("youtube"); err != nil {
+	moduleInfo, err := registry.GetModuleInfo("youtube")
+	if err != nil {
 		return fmt.Errorf("YouTube module not found: %w", err)
 	}
+	warnOutdatedDeps(cfg, logger, moduleInfo)
 
 	logger.Info("Getting YouTube video info", "url", url)
 
+	options, err := geoOptions(cmd, cfg, moduleInfo.Manifest)
+	if err != nil {
+		return err
+	}
+	options["url"] = url
+
 	// Execute command
-	resp, err := registry.ExecuteCommand("youtube", "info", map[string]interface{}{"url": url}, tokens)
+	resp, err := registry.ExecuteCommand(cmd.Context(), "youtube", "info", options, tokens)
 	if err != nil {
 		return fmt.Errorf("failed to get video info: %w", err)
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("failed to get video info: %s", resp.Error)
+		return geoError(cfg, moduleInfo.Manifest, options, fmt.Errorf("failed to get video info: %s", resp.Error))
+	}
+
+	if handled, err := printTemplate(cmd, resp.Data); handled || err != nil {
+		return err
 	}
 
 	// Print results
+	info := bridge.DecodeVideoInfo(resp.Data)
 	fmt.Printf("\n🎬 Video Information\n")
 	fmt.Println("==================")
 	
-	if title, ok := resp.Data["title"].(string); ok {
-		fmt.Printf("📺 Title: %s\n", title)
+	if info.Title != "" {
+		fmt.Printf("📺 Title: %s\n", info.Title)
 	}
 	
-	if uploader, ok := resp.Data["uploader"].(string); ok {
-		fmt.Printf("👤 Uploader: %s\n", uploader)
+	if info.Uploader != "" {
+		fmt.Printf("👤 Uploader: %s\n", info.Uploader)
 	}
 	
-	if duration, ok := resp.Data["duration"].(float64); ok {
-		fmt.Printf("⏱️  Duration: %s\n", formatDuration(int(duration)))
+	if info.Duration > 0 {
+		fmt.Printf("⏱️  Duration: %s\n", formatSeconds(int(info.Duration.Seconds())))
 	}
 	
-	if viewCount, ok := resp.Data["view_count"].(float64); ok {
-		fmt.Printf("👁️  Views: %s\n", formatNumber(int(viewCount)))
+	if info.ViewCount > 0 {
+		fmt.Printf("👁️  Views: %s\n", formatNumber(int(info.ViewCount)))
 	}
 	
-	if uploadDate, ok := resp.Data["upload_date"].(string); ok {
-		fmt.Printf("📅 Upload Date: %s\n", formatUploadDate(uploadDate))
+	if info.UploadDate != "" {
+		fmt.Printf("📅 Upload Date: %s\n", formatUploadDate(info.UploadDate))
 	}
 	
-	if description, ok := resp.Data["description"].(string); ok && description != "" {
-		fmt.Printf("📝 Description: %s\n", description)
+	if info.Description != "" {
+		fmt.Printf("📝 Description: %s\n", info.Description)
 	}
 
 	return nil
 }
 
-// Helper functions for output formatting
+// printFormatList renders formats through the shared output formatter
+func printFormatList(cmd *cobra.Command, formats []bridge.Format, filter *query.Query) error {
+	list := &listOutput{
+		Columns:  []string{"id", "ext", "resolution", "fps", "vcodec", "acodec", "abr", "size", "note"},
+		Defaults: []string{"id", "ext", "resolution", "vcodec", "acodec", "size", "note"},
+	}
+
+	for _, format := range formats {
+		if filter != nil && !filter.Match(query.MapGetter(format.Fields)) {
+			continue
+		}
 
-func printProgress(progress *bridge.ProgressEvent) {
-	percentage := int(progress.Percentage)
-	barLength := 30
-	filledLength := int(float64(barLength) * progress.Percentage / 100)
-
-	bar := ""
-	for i := 0; i < barLength; i++ {
-		if i < filledLength {
-			bar += "█"
-		} else {
-			bar += "░"
+		resolution := ""
+		if format.Height > 0 {
+			resolution = fmt.Sprintf("%dp", format.Height)
 		}
+		size := ""
+		if format.FileSize > 0 {
+			size = formatFileSize(format.FileSize)
+		}
+		list.Add(templateData(format.Fields), format.ID, format.Ext, resolution,
+			format.Fields.String("fps"), format.VideoCodec, format.AudioCodec,
+			format.Fields.String("abr"), size, format.Note)
 	}
 
-	fmt.Printf("\r%s [%s] %d%% %s", progress.Stage, bar, percentage, progress.Message)
+	return printList(cmd, list)
 }
 
-func printFormat(index int, format map[string]interface{}) {
+// Helper functions for output formatting
+
+func printFormat(index int, format bridge.Format) {
 	fmt.Printf("\n[%d] ", index)
 	
-	if formatID, ok := format["format_id"].(string); ok {
-		fmt.Printf("ID: %s", formatID)
+	if format.ID != "" {
+		fmt.Printf("ID: %s", format.ID)
 	}
 	
-	if ext, ok := format["ext"].(string); ok {
-		fmt.Printf(" | Ext: %s", ext)
+	if format.Ext != "" {
+		fmt.Printf(" | Ext: %s", format.Ext)
 	}
 	
-	if vcodec, ok := format["vcodec"].(string); ok && vcodec != "none" {
-		fmt.Printf(" | Video: %s", vcodec)
+	if format.HasVideo() {
+		fmt.Printf(" | Video: %s", format.VideoCodec)
 	}
 	
-	if acodec, ok := format["acodec"].(string); ok && acodec != "none" {
-		fmt.Printf(" | Audio: %s", acodec)
+	if format.HasAudio() {
+		fmt.Printf(" | Audio: %s", format.AudioCodec)
 	}
 	
-	if height, ok := format["height"].(float64); ok && height > 0 {
-		fmt.Printf(" | %dp", int(height))
+	if format.Height > 0 {
+		fmt.Printf(" | %dp", format.Height)
 	}
 	
-	if fps, ok := format["fps"].(float64); ok && fps > 0 {
-		fmt.Printf(" | %dfps", int(fps))
+	if format.FPS > 0 {
+		fmt.Printf(" | %dfps", int(format.FPS))
 	}
 	
-	if abr, ok := format["abr"].(float64); ok && abr > 0 {
-		fmt.Printf(" | %dkbps", int(abr))
+	if format.AudioBitrate > 0 {
+		fmt.Printf(" | %dkbps", int(format.AudioBitrate))
 	}
 	
-	if asr, ok := format["asr"].(float64); ok && asr > 0 {
-		fmt.Printf(" | %dHz", int(asr))
+	if format.SampleRate > 0 {
+		fmt.Printf(" | %dHz", format.SampleRate)
 	}
 	
-	if filesize, ok := format["filesize"].(float64); ok && filesize > 0 {
-		fmt.Printf(" | %s", formatFileSize(int64(filesize)))
+	if format.FileSize > 0 {
+		fmt.Printf(" | %s", formatFileSize(format.FileSize))
 	}
 	
-	if formatNote, ok := format["format_note"].(string); ok && formatNote != "" {
-		fmt.Printf(" | %s", formatNote)
+	if format.Note != "" {
+		fmt.Printf(" | %s", format.Note)
 	}
 	
 	fmt.Println()
 }
 
-func formatDuration(seconds int) string {
+func formatSeconds(seconds int) string {
 	if seconds <= 0 {
 		return "Unknown"
 	}
@@ -408,52 +663,17 @@ func formatDuration(seconds int) string {
 }
 
 func formatNumber(n int) string {
-	if n < 1000 {
-		return fmt.Sprintf("%d", n)
-	}
-	
-	// Simple number formatting
-	s := fmt.Sprintf("%d", n)
-	if len(s) <= 3 {
-		return s
-	}
-	
-	result := ""
-	for i, char := range s {
-		if i > 0 && (len(s)-i)%3 == 0 {
-			result += ","
-		}
-		result += string(char)
-	}
-	
-	return result
+	return terminal.FormatInt(int64(n))
 }
 
 func formatUploadDate(dateStr string) string {
-	if len(dateStr) != 8 {
+	date, ok := bridge.ParseUploadDate(dateStr)
+	if !ok {
 		return dateStr
 	}
-	
-	year := dateStr[:4]
-	month := dateStr[4:6]
-	day := dateStr[6:8]
-	
-	return fmt.Sprintf("%s-%s-%s", year, month, day)
+	return terminal.FormatDate(date)
 }
 
 func formatFileSize(bytes int64) string {
-	if bytes <= 0 {
-		return "0 B"
-	}
-	
-	units := []string{"B", "KB", "MB", "GB", "TB"}
-	i := 0
-	size := float64(bytes)
-	
-	for size >= 1024 && i < len(units)-1 {
-		size /= 1024
-		i++
-	}
-	
-	return fmt.Sprintf("%.1f %s", size, units[i])
+	return terminal.FormatSize(bytes)
 }
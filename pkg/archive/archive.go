@@ -0,0 +1,112 @@
+package archive
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/converso-empire/cli/pkg/config"
+)
+
+// Archive records the IDs of items that have already been downloaded so
+// repeated syncs and downloads skip them. The file uses yt-dlp's download archive format:
+// one "<module> <id>" pair per line.
+type Archive struct {
+	path string
+	seen map[string]bool
+	mu   sync.Mutex
+}
+
+// DefaultPath returns the location of the download archive
+func DefaultPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "archive.txt")
+}
+
+// Open loads the archive at path, starting empty if it does not exist
+func Open(path string) (*Archive, error) {
+	a := &Archive{path: path, seen: make(map[string]bool)}
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// load adds the entries in the archive file to the seen items
+func (a *Archive) load() error {
+	file, err := os.Open(a.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			a.seen[line] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	return nil
+}
+
+// Has reports whether the item has already been downloaded
+func (a *Archive) Has(module, id string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.seen[key(module, id)]
+}
+
+// Add records an item as downloaded
+func (a *Archive) Add(module, id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Another archive, such as the one downloads are recorded with, may
+	// have added the item since Open
+	k := key(module, id)
+	if !a.seen[k] {
+		if err := a.load(); err != nil {
+			return err
+		}
+	}
+	if a.seen[k] {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	file, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintln(file, k); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	a.seen[k] = true
+	return nil
+}
+
+// Len returns the number of archived items
+func (a *Archive) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.seen)
+}
+
+// key formats an archive entry
+func key(module, id string) string {
+	return module + " " + id
+}
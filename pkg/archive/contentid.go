@@ -0,0 +1,81 @@
+package archive
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// videoIDPattern matches YouTube video IDs
+var videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// youtubePaths are the path prefixes followed by a video ID on youtube.com
+var youtubePaths = map[string]bool{"shorts": true, "embed": true, "live": true, "v": true}
+
+// ContentID returns the ID of the video a URL points to. It is the same for
+// every form of the URL: short links, embeds, shorts and watch URLs with a
+// playlist or timestamp. It returns "" for URLs it does not recognize.
+func ContentID(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range []string{"www.", "m.", "music."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	var id string
+	switch host {
+	case "youtu.be":
+		id = parts[0]
+	case "youtube.com", "youtube-nocookie.com":
+		switch {
+		case parts[0] == "watch":
+			id = u.Query().Get("v")
+		case len(parts) >= 2 && youtubePaths[parts[0]]:
+			id = parts[1]
+		}
+	}
+	if !videoIDPattern.MatchString(id) {
+		return ""
+	}
+	return id
+}
+
+// ItemID returns the archive ID of a URL: its content ID, or the URL
+// itself when it has none
+func ItemID(rawURL string) string {
+	if id := ContentID(rawURL); id != "" {
+		return id
+	}
+	return strings.TrimSpace(rawURL)
+}
+
+// playlistIDPattern matches YouTube playlist IDs
+var playlistIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{12,}$`)
+
+// PlaylistID returns the ID of the playlist a youtube.com/playlist URL
+// points to. Watch URLs with a playlist are videos and return "", like
+// URLs it does not recognize.
+func PlaylistID(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range []string{"www.", "m.", "music."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	if host != "youtube.com" || strings.Trim(u.Path, "/") != "playlist" {
+		return ""
+	}
+	id := u.Query().Get("list")
+	if !playlistIDPattern.MatchString(id) {
+		return ""
+	}
+	return id
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+)
+
+// minClockSkew is the smallest clock skew that is applied. The Date header
+// has a resolution of one second, so smaller differences are noise.
+const minClockSkew = 2 * time.Second
+
+// measureClockSkew returns how far the server clock is ahead of the local
+// clock, from the Date header of a response to a request sent at sent. It
+// returns 0 when the header is missing or the skew is negligible.
+func measureClockSkew(resp *http.Response, sent time.Time) time.Duration {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0
+	}
+
+	// The server stamped the response somewhere during the round trip
+	local := sent.Add(time.Since(sent) / 2)
+	return significantSkew(date.Sub(local))
+}
+
+// significantSkew drops skews below minClockSkew
+func significantSkew(skew time.Duration) time.Duration {
+	if skew > -minClockSkew && skew < minClockSkew {
+		return 0
+	}
+	return skew
+}
+
+// newTokens builds tokens from a token response. Expiry is kept in server
+// time: the exp claim of a JWT access token when present, otherwise the
+// server's current time plus expires_in. The clock skew comes from the
+// Date header, or from the iat claim when the header is missing. Granted
+// scopes also come from the claims when present.
+func newTokens(resp *TokenResponse) *AuthTokens {
+	tokens := &AuthTokens{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		TokenType:    resp.TokenType,
+		Scope:        resp.Scope,
+		ClockSkew:    resp.ClockSkew,
+	}
+
+	claims, ok := ParseClaims(resp.AccessToken)
+	if ok && claims.ScopeString() != "" {
+		tokens.Scope = claims.ScopeString()
+	}
+	if ok && tokens.ClockSkew == 0 && claims.IssuedAt > 0 {
+		tokens.ClockSkew = significantSkew(time.Until(time.Unix(claims.IssuedAt, 0)))
+	}
+
+	if ok && claims.ExpiresAt > 0 {
+		tokens.ExpiresAt = claims.Expiry()
+	} else {
+		tokens.ExpiresAt = tokens.ServerNow().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return tokens
+}
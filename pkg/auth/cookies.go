@@ -0,0 +1,330 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+)
+
+// Files of the cookie store in the data directory: the jars of all modules
+// encrypted with AES-256-GCM, and the key they are encrypted with unless it
+// is kept in the OS credential store
+const (
+	cookiesFile   = "cookies.enc"
+	cookieKeyFile = "cookies.key"
+)
+
+// cookiesMagic starts the encrypted cookie file and versions its layout
+const cookiesMagic = "CVK1"
+
+// httpOnlyPrefix marks HttpOnly cookies in cookies.txt files
+const httpOnlyPrefix = "#HttpOnly_"
+
+// CookieStore keeps the cookies of each module encrypted in the data
+// directory, so site sessions modules log in to survive across invocations.
+// Cookies are only ever sent to the module that set them. With
+// credential_store set to keyring the key is kept in the OS credential
+// store; otherwise it sits next to the jars, which only obscures them.
+type CookieStore struct {
+	config *config.Config
+	// keyring holds the key when set, under account
+	keyring *keyringBackend
+	account string
+}
+
+// NewCookieStore creates a cookie store in the data directory
+func NewCookieStore(cfg *config.Config) *CookieStore {
+	store := &CookieStore{config: cfg}
+	if !cfg.Headless && cfg.CredentialStore == config.CredentialStoreKeyring {
+		keyring := osKeyring
+		sum := sha256.Sum256([]byte(cfg.DataDir))
+		store.keyring = &keyring
+		store.account = "cookies-" + hex.EncodeToString(sum[:6])
+	}
+	return store
+}
+
+// Cookies returns the unexpired cookies of a module
+func (s *CookieStore) Cookies(module string) ([]bridge.Cookie, error) {
+	jars, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return unexpired(jars[module], time.Now()), nil
+}
+
+// SetCookies replaces the cookies of a module. Expired cookies are dropped
+// and a module left without cookies is removed.
+func (s *CookieStore) SetCookies(module string, cookies []bridge.Cookie) error {
+	return s.update(func(jars map[string][]bridge.Cookie) {
+		if cookies = unexpired(cookies, time.Now()); len(cookies) == 0 {
+			delete(jars, module)
+		} else {
+			jars[module] = cookies
+		}
+	})
+}
+
+// ListCookies returns the unexpired cookies of every module
+func (s *CookieStore) ListCookies() (map[string][]bridge.Cookie, error) {
+	jars, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for module, cookies := range jars {
+		if jars[module] = unexpired(cookies, now); len(jars[module]) == 0 {
+			delete(jars, module)
+		}
+	}
+	return jars, nil
+}
+
+// update applies update to the stored jars under a lock
+func (s *CookieStore) update(update func(jars map[string][]bridge.Cookie)) error {
+	if err := os.MkdirAll(s.config.DataDir, 0700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	filename := filepath.Join(s.config.DataDir, cookiesFile)
+	lock, err := fileutil.Lock(filename+".lock", tokenLockTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to lock cookies: %w", err)
+	}
+	defer lock.Unlock()
+
+	jars, err := s.read()
+	if err != nil {
+		return err
+	}
+	update(jars)
+
+	plaintext, err := json.Marshal(jars)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cookies: %w", err)
+	}
+	gcm, err := s.cipher(true)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data := append([]byte(cookiesMagic), nonce...)
+	data = gcm.Seal(data, nonce, plaintext, []byte(cookiesMagic))
+	if err := fileutil.WriteAtomic(filename, data, 0600); err != nil {
+		return fmt.Errorf("failed to write cookies file: %w", err)
+	}
+	return nil
+}
+
+// read decrypts the stored jars; a missing file has none
+func (s *CookieStore) read() (map[string][]bridge.Cookie, error) {
+	jars := make(map[string][]bridge.Cookie)
+
+	data, err := os.ReadFile(filepath.Join(s.config.DataDir, cookiesFile))
+	if os.IsNotExist(err) {
+		return jars, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookies file: %w", err)
+	}
+
+	gcm, err := s.cipher(false)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(cookiesMagic)) || len(data) < len(cookiesMagic)+gcm.NonceSize() {
+		return nil, fmt.Errorf("cookies file %s is not a cookie store", cookiesFile)
+	}
+	data = data[len(cookiesMagic):]
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(cookiesMagic))
+	if err != nil {
+		return nil, errors.New("failed to decrypt cookies: the file or its key was changed")
+	}
+	if err := json.Unmarshal(plaintext, &jars); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cookies: %w", err)
+	}
+	return jars, nil
+}
+
+// cipher returns the AES-GCM cipher under the store's key, generating the
+// key when create is set and there is none yet
+func (s *CookieStore) cipher(create bool) (cipher.AEAD, error) {
+	var key []byte
+	var err error
+	if s.keyring != nil {
+		key, err = s.keyringKey(create)
+	} else {
+		key, err = s.fileKey(create)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, errors.New("cookie key is invalid")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// fileKey returns the key in cookies.key, generating it when create is set
+// and there is none yet
+func (s *CookieStore) fileKey(create bool) ([]byte, error) {
+	path := filepath.Join(s.config.DataDir, cookieKeyFile)
+	key, err := os.ReadFile(path)
+	if os.IsNotExist(err) && create {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := fileutil.WriteAtomic(path, key, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write cookie key: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read cookie key: %w", err)
+	}
+	return key, nil
+}
+
+// keyringKey returns the key in the keyring. A key left in cookies.key
+// moves there on first use, and one is generated when create is set and
+// there is none yet.
+func (s *CookieStore) keyringKey(create bool) ([]byte, error) {
+	encoded, err := s.keyring.read(keyringService, s.account)
+	if err == nil {
+		key, err := hex.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.New("cookie key in the keyring is invalid")
+		}
+		return key, nil
+	}
+	if !errors.Is(err, errKeyringNotFound) {
+		return nil, fmt.Errorf("failed to read cookie key from the keyring: %w", err)
+	}
+
+	path := filepath.Join(s.config.DataDir, cookieKeyFile)
+	key, err := os.ReadFile(path)
+	if os.IsNotExist(err) && create {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	} else if os.IsNotExist(err) {
+		return nil, fmt.Errorf("cookie key %w", errKeyringNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read cookie key: %w", err)
+	}
+
+	if err := s.keyring.write(keyringService, s.account, hex.EncodeToString(key)); err != nil {
+		return nil, fmt.Errorf("failed to store cookie key in the keyring: %w", err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to delete cookie key file: %w", err)
+	}
+	return key, nil
+}
+
+// unexpired returns the cookies that have not expired, sorted by domain,
+// path and name
+func unexpired(cookies []bridge.Cookie, now time.Time) []bridge.Cookie {
+	var kept []bridge.Cookie
+	for _, cookie := range cookies {
+		if !cookie.Expired(now) {
+			kept = append(kept, cookie)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		a, b := kept[i], kept[j]
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Name < b.Name
+	})
+	return kept
+}
+
+// ParseCookieFile reads cookies in the Netscape cookies.txt format written
+// by browser extensions and yt-dlp: one tab-separated cookie per line with
+// domain, subdomain flag, path, secure, expiry, name and value
+func ParseCookieFile(r io.Reader) ([]bridge.Cookie, error) {
+	var cookies []bridge.Cookie
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		line = strings.TrimPrefix(line, httpOnlyPrefix)
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d: expected 7 tab-separated fields, got %d", n, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid expiry %q", n, fields[4])
+		}
+		cookies = append(cookies, bridge.Cookie{
+			Domain:   fields[0],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Expires:  expires,
+			Name:     fields[5],
+			Value:    fields[6],
+			HTTPOnly: httpOnly,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cookies, nil
+}
+
+// MergeCookies returns cookies with added replacing those of the same
+// domain, path and name
+func MergeCookies(cookies, added []bridge.Cookie) []bridge.Cookie {
+	type key struct{ domain, path, name string }
+	index := make(map[key]int, len(cookies))
+	merged := append([]bridge.Cookie(nil), cookies...)
+	for i, cookie := range merged {
+		index[key{cookie.Domain, cookie.Path, cookie.Name}] = i
+	}
+	for _, cookie := range added {
+		k := key{cookie.Domain, cookie.Path, cookie.Name}
+		if i, ok := index[k]; ok {
+			merged[i] = cookie
+			continue
+		}
+		index[k] = len(merged)
+		merged = append(merged, cookie)
+	}
+	return merged
+}
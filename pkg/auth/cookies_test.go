@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+)
+
+func TestCookieStoreKeepsKeyInKeyring(t *testing.T) {
+	cfg := &config.Config{DataDir: t.TempDir(), CredentialStore: config.CredentialStoreKeyring}
+	cookies := []bridge.Cookie{{Domain: ".youtube.com", Path: "/", Name: "SID", Value: "secret"}}
+
+	// A jar written with the key in cookies.key
+	fileStore := &CookieStore{config: cfg}
+	if err := fileStore.SetCookies("youtube", cookies); err != nil {
+		t.Fatalf("SetCookies: %v", err)
+	}
+	keyPath := filepath.Join(cfg.DataDir, cookieKeyFile)
+	if _, err := os.Stat(keyPath); err != nil {
+		t.Fatalf("expected %s: %v", cookieKeyFile, err)
+	}
+
+	// moves its key to the keyring on first use
+	keyring := make(memoryKeyring)
+	backend := keyring.backend(t)
+	store := NewCookieStore(cfg)
+	store.keyring = &backend
+	got, err := store.Cookies("youtube")
+	if err != nil {
+		t.Fatalf("Cookies: %v", err)
+	}
+	if len(got) != 1 || got[0] != cookies[0] {
+		t.Fatalf("Cookies = %+v, want %+v", got, cookies)
+	}
+	if _, err := os.Stat(keyPath); !os.IsNotExist(err) {
+		t.Fatalf("expected %s removed, got %v", cookieKeyFile, err)
+	}
+	if len(keyring) != 1 {
+		t.Fatalf("expected the key in the keyring, got %d entries", len(keyring))
+	}
+
+	// and the jars cannot be read without it
+	if _, err := fileStore.Cookies("youtube"); err == nil {
+		t.Fatalf("expected the jars unreadable without the keyring")
+	}
+
+	// A new data directory gets its key straight in the keyring
+	cfg = &config.Config{DataDir: t.TempDir(), CredentialStore: config.CredentialStoreKeyring}
+	store = NewCookieStore(cfg)
+	store.keyring = &backend
+	if err := store.SetCookies("youtube", cookies); err != nil {
+		t.Fatalf("SetCookies: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.DataDir, cookieKeyFile)); !os.IsNotExist(err) {
+		t.Fatalf("expected no %s, got %v", cookieKeyFile, err)
+	}
+	if got, err := store.Cookies("youtube"); err != nil || len(got) != 1 {
+		t.Fatalf("Cookies = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// NewStorage returns the token storage for the current mode: secrets from
+// the environment in headless mode, otherwise files in the data directory
+// or, with credential_store set to keyring, the OS credential store
+func NewStorage(cfg *config.Config, logger telemetry.Logger) SecureStorage {
+	if cfg.Headless {
+		return NewEnvStorage(logger)
+	}
+	switch cfg.CredentialStore {
+	case config.CredentialStoreKeyring:
+		return NewKeyringStorage(cfg, logger)
+	case config.CredentialStoreFile, "":
+	default:
+		logger.Warn("Unknown credential store, keeping tokens in a file", "credential_store", cfg.CredentialStore)
+	}
+	return NewFileStorage(cfg, logger)
+}
+
+// EnvStorage implements SecureStorage over secrets provided through the
+// environment or secret files (CONVERSO_ACCESS_TOKEN or
+// CONVERSO_ACCESS_TOKEN_FILE, and likewise for the refresh token, device id,
+// device token and token expiry). It is read-only.
+type EnvStorage struct {
+	logger telemetry.Logger
+}
+
+// NewEnvStorage creates a new environment-backed storage
+func NewEnvStorage(logger telemetry.Logger) SecureStorage {
+	return &EnvStorage{logger: logger}
+}
+
+// errReadOnly is returned when headless mode tries to change stored credentials
+var errReadOnly = errors.New("credentials are read from the environment in headless mode")
+
+// StoreTokens is not supported in headless mode
+func (s *EnvStorage) StoreTokens(tokens *AuthTokens) error {
+	return errReadOnly
+}
+
+// RetrieveTokens reads tokens from the environment
+func (s *EnvStorage) RetrieveTokens() (*AuthTokens, error) {
+	accessToken, err := config.Secret("access_token")
+	if err != nil {
+		return nil, err
+	}
+	if accessToken == "" {
+		return nil, fmt.Errorf("CONVERSO_ACCESS_TOKEN or CONVERSO_ACCESS_TOKEN_FILE is not set")
+	}
+
+	// Injected tokens expire at CONVERSO_TOKEN_EXPIRES_AT or their exp
+	// claim; opaque tokens without either are assumed valid until the
+	// backend rejects them
+	tokens := &AuthTokens{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresAt:   time.Now().AddDate(1, 0, 0),
+	}
+	if claims, ok := ParseClaims(accessToken); ok && claims.ExpiresAt > 0 {
+		tokens.ExpiresAt = claims.Expiry()
+		tokens.Scope = claims.ScopeString()
+	}
+	expiresAt, err := config.Secret("token_expires_at")
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt != "" {
+		if tokens.ExpiresAt, err = time.Parse(time.RFC3339, expiresAt); err != nil {
+			return nil, fmt.Errorf("invalid CONVERSO_TOKEN_EXPIRES_AT: %w", err)
+		}
+	}
+	for name, field := range map[string]*string{
+		"refresh_token": &tokens.RefreshToken,
+		"device_id":     &tokens.DeviceID,
+		"device_token":  &tokens.DeviceToken,
+	} {
+		if *field, err = config.Secret(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return tokens, nil
+}
+
+// UpdateTokens passes the tokens from the environment to update. The
+// result is used by the caller but cannot be persisted.
+func (s *EnvStorage) UpdateTokens(update func(tokens *AuthTokens) (*AuthTokens, error)) (*AuthTokens, error) {
+	tokens, err := s.RetrieveTokens()
+	if err != nil {
+		return nil, err
+	}
+	return update(tokens)
+}
+
+// DeleteTokens is not supported in headless mode
+func (s *EnvStorage) DeleteTokens() error {
+	return errReadOnly
+}
+
+// StoreDevice is not supported in headless mode
+func (s *EnvStorage) StoreDevice(device *Device) error {
+	return errReadOnly
+}
+
+// RetrieveDevice builds the device from the environment
+func (s *EnvStorage) RetrieveDevice() (*Device, error) {
+	deviceID, err := config.Secret("device_id")
+	if err != nil {
+		return nil, err
+	}
+	if deviceID == "" {
+		return nil, fmt.Errorf("CONVERSO_DEVICE_ID is not set")
+	}
+	return &Device{ID: deviceID, Name: GetDeviceName()}, nil
+}
+
+// DeleteDevice is not supported in headless mode
+func (s *EnvStorage) DeleteDevice() error {
+	return errReadOnly
+}
@@ -0,0 +1,261 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+	"github.com/converso-empire/cli/pkg/httpclient"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// JWKS cache timing. Keys are refetched after jwksCacheTTL, or earlier for
+// an unknown key ID once the cache is older than jwksMinRefresh.
+const (
+	jwksCacheTTL   = 24 * time.Hour
+	jwksMinRefresh = 5 * time.Minute
+)
+
+// claimsLeeway is how far exp and nbf may be off after correcting for the
+// measured clock skew, which only has a resolution of seconds
+const claimsLeeway = 30 * time.Second
+
+// ErrKeysUnavailable is returned when no signing keys could be obtained,
+// typically because the machine is offline
+var ErrKeysUnavailable = errors.New("token signing keys unavailable")
+
+// jwk is a JSON Web Key
+type jwk struct {
+	KeyID string `json:"kid"`
+	Type  string `json:"kty"`
+	Use   string `json:"use,omitempty"`
+	N     string `json:"n,omitempty"`
+	E     string `json:"e,omitempty"`
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// jwksFile is the cached key set in the data directory
+type jwksFile struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Keys      []jwk     `json:"keys"`
+}
+
+// TokenVerifier verifies access tokens against the published JWKS, cached
+// in the data directory
+type TokenVerifier struct {
+	config     *config.Config
+	logger     telemetry.Logger
+	httpClient *http.Client
+}
+
+// NewTokenVerifier creates a new token verifier
+func NewTokenVerifier(cfg *config.Config, logger telemetry.Logger) *TokenVerifier {
+	return &TokenVerifier{
+		config:     cfg,
+		logger:     logger,
+		httpClient: httpclient.New(cfg, logger, 10*time.Second),
+	}
+}
+
+// Verify checks the signature of a JWT access token and its claims at now,
+// the server time: that it is valid at now, was issued by the auth_url
+// server and is meant for audience. It returns the claims. Opaque tokens
+// and a disabled jwks_url return nil claims without error.
+// ErrKeysUnavailable is returned when the keys cannot be fetched.
+func (v *TokenVerifier) Verify(token string, now time.Time, audience string) (*Claims, error) {
+	t, err := parseJWT(token)
+	if errors.Is(err, errNotJWT) || v.config.JWKSURL == "" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := v.key(t.header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.verify(key); err != nil {
+		return nil, err
+	}
+	if err := v.checkClaims(&t.claims, now, audience); err != nil {
+		return nil, err
+	}
+	return &t.claims, nil
+}
+
+// checkClaims checks the time, issuer and audience claims of a token.
+// The issuer is the auth_url server, or a path of it for servers hosting
+// several issuers; the client ID may also be named as authorized party.
+func (v *TokenVerifier) checkClaims(claims *Claims, now time.Time, audience string) error {
+	if claims.ExpiresAt > 0 && now.After(claims.Expiry().Add(claimsLeeway)) {
+		return fmt.Errorf("token expired at %s", claims.Expiry().Format(time.RFC3339))
+	}
+	if claims.NotBefore > 0 && now.Add(claimsLeeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return fmt.Errorf("token not valid before %s", time.Unix(claims.NotBefore, 0).Format(time.RFC3339))
+	}
+
+	issuer := strings.TrimSuffix(claims.Issuer, "/")
+	if issuer == "" || (v.config.AuthURL != issuer && !strings.HasPrefix(v.config.AuthURL, issuer+"/")) {
+		return fmt.Errorf("token issuer %q does not match %s", claims.Issuer, v.config.AuthURL)
+	}
+
+	if !claims.Audience.contains(audience) && (audience != v.config.ClientID || claims.AuthorizedParty != audience) {
+		return fmt.Errorf("token is not meant for %s", audience)
+	}
+	return nil
+}
+
+// key returns the public key for a key ID, refreshing the cache when it is
+// stale or does not know the key
+func (v *TokenVerifier) key(kid string) (crypto.PublicKey, error) {
+	cached, _ := v.readCache()
+	if cached != nil && time.Since(cached.FetchedAt) < jwksCacheTTL {
+		if key, ok := findKey(cached.Keys, kid); ok {
+			return key.publicKey()
+		}
+		if time.Since(cached.FetchedAt) < jwksMinRefresh {
+			return nil, fmt.Errorf("unknown token signing key %q", kid)
+		}
+	}
+
+	fetched, err := v.fetch()
+	if err != nil {
+		// Fall back to stale keys rather than failing offline
+		if cached != nil {
+			if key, ok := findKey(cached.Keys, kid); ok {
+				v.logger.Warn("Using cached token signing keys", "error", err)
+				return key.publicKey()
+			}
+		}
+		return nil, fmt.Errorf("%w: %v", ErrKeysUnavailable, err)
+	}
+
+	key, ok := findKey(fetched.Keys, kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown token signing key %q", kid)
+	}
+	return key.publicKey()
+}
+
+// fetch downloads the key set and caches it
+func (v *TokenVerifier) fetch() (*jwksFile, error) {
+	resp, err := v.httpClient.Get(httpclient.JWKS.URL(v.config))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS request failed with status %d", resp.StatusCode)
+	}
+
+	keys := &jwksFile{FetchedAt: time.Now()}
+	if err := json.NewDecoder(resp.Body).Decode(keys); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	if data, err := json.MarshalIndent(keys, "", "  "); err == nil {
+		if err := os.MkdirAll(v.config.DataDir, 0700); err == nil {
+			if err := fileutil.WriteAtomic(v.cachePath(), data, 0600); err != nil {
+				v.logger.Warn("Failed to cache token signing keys", "error", err)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// readCache reads the cached key set
+func (v *TokenVerifier) readCache() (*jwksFile, error) {
+	data, err := os.ReadFile(v.cachePath())
+	if err != nil {
+		return nil, err
+	}
+
+	var keys jwksFile
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+	return &keys, nil
+}
+
+// cachePath returns the location of the cached key set
+func (v *TokenVerifier) cachePath() string {
+	return filepath.Join(v.config.DataDir, "jwks.json")
+}
+
+// findKey looks up a key by ID. Tokens without a key ID match a sole key.
+func findKey(keys []jwk, kid string) (*jwk, bool) {
+	for i := range keys {
+		if keys[i].KeyID == kid && keys[i].Use != "enc" {
+			return &keys[i], true
+		}
+	}
+	if kid == "" && len(keys) == 1 {
+		return &keys[0], true
+	}
+	return nil, false
+}
+
+// publicKey converts the JWK into a public key
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Type {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA key %q: %w", k.KeyID, err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("invalid RSA key %q", k.KeyID)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q for key %q", k.Curve, k.KeyID)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC key %q: %w", k.KeyID, err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC key %q: %w", k.KeyID, err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q for key %q", k.Type, k.KeyID)
+}
+
+// decodeBigInt decodes a base64url big-endian integer
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Claims are the JWT claims the CLI uses from access tokens
+type Claims struct {
+	Subject   string `json:"sub"`
+	Issuer    string `json:"iss"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	NotBefore int64  `json:"nbf"`
+	Email     string `json:"email"`
+	Username  string `json:"preferred_username"`
+	Name      string `json:"name"`
+	Scope     string `json:"scope"`
+	// Scopes is the list form used by some identity providers
+	Scopes []string `json:"scp"`
+	// Audience is who the token is for; AuthorizedParty is the client it
+	// was issued to
+	Audience        audience `json:"aud"`
+	AuthorizedParty string   `json:"azp"`
+}
+
+// audience is the aud claim, a single string or a list
+type audience []string
+
+// UnmarshalJSON accepts both forms of the claim
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// contains reports whether the audience includes name
+func (a audience) contains(name string) bool {
+	for _, entry := range a {
+		if entry == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Expiry returns the exp claim, or the zero time if it is missing
+func (c *Claims) Expiry() time.Time {
+	if c.ExpiresAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// ScopeString returns the granted scopes space-separated
+func (c *Claims) ScopeString() string {
+	if c.Scope != "" {
+		return c.Scope
+	}
+	return strings.Join(c.Scopes, " ")
+}
+
+// DisplayName returns the best available user name
+func (c *Claims) DisplayName() string {
+	for _, name := range []string{c.Username, c.Name, c.Email, c.Subject} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// jwtHeader is the JOSE header of a JWT
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// jwt is a decoded, unverified JWT
+type jwt struct {
+	header    jwtHeader
+	claims    Claims
+	signed    string
+	signature []byte
+}
+
+// errNotJWT is returned for opaque access tokens
+var errNotJWT = errors.New("access token is not a JWT")
+
+// parseJWT decodes a JWT without verifying it
+func parseJWT(token string) (*jwt, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errNotJWT
+	}
+
+	t := &jwt{signed: parts[0] + "." + parts[1]}
+	if err := decodeSegment(parts[0], &t.header); err != nil {
+		return nil, errNotJWT
+	}
+	if err := decodeSegment(parts[1], &t.claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[2], "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature encoding: %w", err)
+	}
+	t.signature = signature
+	return t, nil
+}
+
+// ParseClaims reads the claims of a JWT access token without verifying its
+// signature, for offline expiry and display. It reports false for opaque
+// tokens.
+func ParseClaims(token string) (*Claims, bool) {
+	t, err := parseJWT(token)
+	if err != nil {
+		return nil, false
+	}
+	return &t.claims, true
+}
+
+// decodeSegment decodes a base64url JSON segment of a JWT
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// verify checks the token signature with key
+func (t *jwt) verify(key crypto.PublicKey) error {
+	hash, err := signatureHash(t.header.Algorithm)
+	if err != nil {
+		return err
+	}
+	h := hash.New()
+	h.Write([]byte(t.signed))
+	digest := h.Sum(nil)
+
+	switch strings.ToUpper(t.header.Algorithm[:2]) {
+	case "RS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key %q is not an RSA key", t.header.KeyID)
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, hash, digest, t.signature); err != nil {
+			return fmt.Errorf("invalid token signature")
+		}
+	case "PS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key %q is not an RSA key", t.header.KeyID)
+		}
+		if err := rsa.VerifyPSS(rsaKey, hash, digest, t.signature, nil); err != nil {
+			return fmt.Errorf("invalid token signature")
+		}
+	case "ES":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key %q is not an EC key", t.header.KeyID)
+		}
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(t.signature) != 2*size {
+			return fmt.Errorf("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(t.signature[:size])
+		s := new(big.Int).SetBytes(t.signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return fmt.Errorf("invalid token signature")
+		}
+	}
+	return nil
+}
+
+// signatureHash returns the hash of a supported JWS algorithm. Symmetric
+// and unsigned tokens cannot be verified against published keys.
+func signatureHash(algorithm string) (crypto.Hash, error) {
+	switch algorithm {
+	case "RS256", "PS256", "ES256":
+		return crypto.SHA256, nil
+	case "RS384", "PS384", "ES384":
+		return crypto.SHA384, nil
+	case "RS512", "PS512", "ES512":
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("unsupported token signing algorithm %q", algorithm)
+}
@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// keyringService names the CLI's entries in the OS credential store
+const keyringService = "converso-cli"
+
+// keyringPartsPrefix starts the entry of a secret split into parts, followed
+// by the number of parts
+const keyringPartsPrefix = "parts:"
+
+// errKeyringNotFound is returned when the credential store has no entry
+var errKeyringNotFound = errors.New("not found in the keyring")
+
+// keyringBackend is a credential store. Secrets larger than an entry holds
+// are split across entries named after the account with -1, -2 and so on,
+// and the account's own entry records how many there are.
+type keyringBackend struct {
+	get    func(service, account string) (string, error)
+	set    func(service, account, secret string) error
+	delete func(service, account string) error
+	// maxSecret is the largest secret an entry holds, 0 for no limit
+	maxSecret int
+}
+
+// osKeyring is the OS credential store
+var osKeyring = keyringBackend{get: keyringGet, set: keyringSet, delete: keyringDelete, maxSecret: keyringMaxSecret}
+
+// read returns the secret of an account, joining its parts
+func (k keyringBackend) read(service, account string) (string, error) {
+	secret, err := k.get(service, account)
+	if err != nil {
+		return "", err
+	}
+	count, split := k.parts(secret)
+	if !split {
+		return secret, nil
+	}
+
+	var joined strings.Builder
+	for i := 1; i <= count; i++ {
+		part, err := k.get(service, partAccount(account, i))
+		if err != nil {
+			return "", fmt.Errorf("failed to read part %d of %d: %w", i, count, err)
+		}
+		joined.WriteString(part)
+	}
+	return joined.String(), nil
+}
+
+// write stores the secret of an account, in parts when it is too large for
+// one entry. The parts are written before the entry that points to them,
+// and parts left over from a larger secret are removed after.
+func (k keyringBackend) write(service, account, secret string) error {
+	previous := 0
+	if current, err := k.get(service, account); err == nil {
+		previous, _ = k.parts(current)
+	}
+
+	count := 0
+	if k.maxSecret > 0 && len(secret) > k.maxSecret {
+		for start := 0; start < len(secret); start += k.maxSecret {
+			end := start + k.maxSecret
+			if end > len(secret) {
+				end = len(secret)
+			}
+			count++
+			if err := k.set(service, partAccount(account, count), secret[start:end]); err != nil {
+				return fmt.Errorf("failed to store part %d: %w", count, err)
+			}
+		}
+		secret = keyringPartsPrefix + strconv.Itoa(count)
+	}
+	if err := k.set(service, account, secret); err != nil {
+		return err
+	}
+
+	for i := count + 1; i <= previous; i++ {
+		if err := k.delete(service, partAccount(account, i)); err != nil && !errors.Is(err, errKeyringNotFound) {
+			return fmt.Errorf("failed to remove part %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// remove deletes the secret of an account and its parts
+func (k keyringBackend) remove(service, account string) error {
+	secret, err := k.get(service, account)
+	if err != nil {
+		return err
+	}
+	count, _ := k.parts(secret)
+	for i := 1; i <= count; i++ {
+		if err := k.delete(service, partAccount(account, i)); err != nil && !errors.Is(err, errKeyringNotFound) {
+			return fmt.Errorf("failed to remove part %d: %w", i, err)
+		}
+	}
+	return k.delete(service, account)
+}
+
+// parts reports whether an account's entry points to parts, and how many.
+// Secrets stored whole are JSON and never start with the prefix.
+func (k keyringBackend) parts(entry string) (int, bool) {
+	if !strings.HasPrefix(entry, keyringPartsPrefix) {
+		return 0, false
+	}
+	count, err := strconv.Atoi(strings.TrimPrefix(entry, keyringPartsPrefix))
+	if err != nil || count < 1 {
+		return 0, false
+	}
+	return count, true
+}
+
+// partAccount names the entry of a part of an account's secret
+func partAccount(account string, part int) string {
+	return account + "-" + strconv.Itoa(part)
+}
+
+// KeyringStorage implements SecureStorage with the tokens in the OS
+// credential store: the macOS Keychain, the Windows Credential Manager or
+// the Secret Service (libsecret) on Linux. The device registration and
+// module secrets are kept in files like FileStorage does. Tokens left in
+// tokens.json by FileStorage move to the keyring on first use.
+type KeyringStorage struct {
+	*FileStorage
+	// account identifies the data directory's tokens, so isolated
+	// instances keep tokens of their own
+	account string
+	keyring keyringBackend
+}
+
+// NewKeyringStorage creates a new storage keeping tokens in the OS
+// credential store
+func NewKeyringStorage(cfg *config.Config, logger telemetry.Logger) SecureStorage {
+	sum := sha256.Sum256([]byte(cfg.DataDir))
+	return &KeyringStorage{
+		FileStorage: &FileStorage{config: cfg, logger: logger},
+		account:     "tokens-" + hex.EncodeToString(sum[:6]),
+		keyring:     osKeyring,
+	}
+}
+
+// KeyringAvailable reports why the OS credential store cannot be used, if
+// it cannot
+func KeyringAvailable() error {
+	if _, err := keyringGet(keyringService, "probe"); err != nil && !errors.Is(err, errKeyringNotFound) {
+		return err
+	}
+	return nil
+}
+
+// StoreTokens stores authentication tokens in the keyring
+func (s *KeyringStorage) StoreTokens(tokens *AuthTokens) error {
+	lock, err := s.lockTokens()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return s.storeTokens(tokens)
+}
+
+// UpdateTokens reads the stored tokens, passes them to update and stores
+// the result if it differs, all while holding the token lock
+func (s *KeyringStorage) UpdateTokens(update func(tokens *AuthTokens) (*AuthTokens, error)) (*AuthTokens, error) {
+	lock, err := s.lockTokens()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	tokens, err := s.readTokens()
+	if errors.Is(err, errKeyringNotFound) {
+		tokens, err = s.migrateTokens()
+	}
+	if err != nil {
+		return nil, err
+	}
+	current := *tokens
+
+	updated, err := update(tokens)
+	if err != nil {
+		return nil, err
+	}
+	if *updated != current {
+		if err := s.storeTokens(updated); err != nil {
+			return nil, err
+		}
+	}
+	return updated, nil
+}
+
+// RetrieveTokens retrieves authentication tokens from the keyring
+func (s *KeyringStorage) RetrieveTokens() (*AuthTokens, error) {
+	tokens, err := s.readTokens()
+	if !errors.Is(err, errKeyringNotFound) {
+		return tokens, err
+	}
+
+	lock, err := s.lockTokens()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	return s.migrateTokens()
+}
+
+// DeleteTokens deletes the tokens from the keyring, along with any left
+// in tokens.json
+func (s *KeyringStorage) DeleteTokens() error {
+	if err := s.FileStorage.DeleteTokens(); err != nil {
+		return err
+	}
+
+	lock, err := s.lockTokens()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if err := s.keyring.remove(keyringService, s.account); err != nil && !errors.Is(err, errKeyringNotFound) {
+		return fmt.Errorf("failed to delete tokens from the keyring: %w", err)
+	}
+
+	s.logger.Info("Tokens deleted successfully")
+	return nil
+}
+
+// readTokens reads the tokens from the keyring
+func (s *KeyringStorage) readTokens() (*AuthTokens, error) {
+	data, err := s.keyring.read(keyringService, s.account)
+	if err != nil {
+		if errors.Is(err, errKeyringNotFound) {
+			return nil, fmt.Errorf("tokens %w", err)
+		}
+		return nil, fmt.Errorf("failed to read tokens from the keyring: %w", err)
+	}
+
+	var tokens AuthTokens
+	if err := json.Unmarshal([]byte(data), &tokens); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tokens: %w", err)
+	}
+	s.logger.Info("Tokens retrieved successfully")
+	return &tokens, nil
+}
+
+// storeTokens writes tokens to the keyring and removes a leftover
+// tokens.json; the caller holds the token lock
+func (s *KeyringStorage) storeTokens(tokens *AuthTokens) error {
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tokens: %w", err)
+	}
+	if err := s.keyring.write(keyringService, s.account, string(data)); err != nil {
+		return fmt.Errorf("failed to store tokens in the keyring: %w", err)
+	}
+
+	filename := filepath.Join(s.config.DataDir, "tokens.json")
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete tokens file: %w", err)
+	}
+
+	s.logger.Info("Tokens stored successfully")
+	return nil
+}
+
+// migrateTokens moves the tokens FileStorage left in tokens.json to the
+// keyring and returns them; the caller holds the token lock
+func (s *KeyringStorage) migrateTokens() (*AuthTokens, error) {
+	// Another process may have moved them while we waited for the lock
+	tokens, err := s.readTokens()
+	if !errors.Is(err, errKeyringNotFound) {
+		return tokens, err
+	}
+
+	tokens, err = s.FileStorage.RetrieveTokens()
+	if err != nil {
+		if _, statErr := os.Stat(filepath.Join(s.config.DataDir, "tokens.json")); os.IsNotExist(statErr) {
+			return nil, fmt.Errorf("tokens %w", errKeyringNotFound)
+		}
+		return nil, err
+	}
+	if err := s.storeTokens(tokens); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Tokens moved from tokens.json to the keyring")
+	return tokens, nil
+}
@@ -0,0 +1,67 @@
+//go:build darwin
+
+package auth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// errSecItemNotFound is the exit status of security for missing items
+const errSecItemNotFound = 44
+
+// keyringMaxSecret is 0 as the Keychain takes secrets of any size
+const keyringMaxSecret = 0
+
+// keyringGet reads a generic password from the login keychain
+func keyringGet(service, account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if err != nil {
+		return "", securityError(err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// keyringSet adds or replaces a generic password in the login keychain.
+// Commands are passed on stdin, so the secret never shows up in the
+// process list, and hex-encoded, so it needs no quoting.
+func keyringSet(service, account, secret string) error {
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", service, account, hex.EncodeToString([]byte(secret))))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	// security -i reports failed commands on stderr but exits cleanly
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// keyringDelete deletes a generic password from the login keychain
+func keyringDelete(service, account string) error {
+	if err := exec.Command("security", "delete-generic-password", "-s", service, "-a", account).Run(); err != nil {
+		return securityError(err)
+	}
+	return nil
+}
+
+// securityError translates a failed security command
+func securityError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.ExitCode() == errSecItemNotFound {
+			return errKeyringNotFound
+		}
+		if msg := strings.TrimSpace(string(exitErr.Stderr)); msg != "" {
+			return errors.New(msg)
+		}
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+//go:build !darwin && !windows
+
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keyringMaxSecret is 0 as the Secret Service takes secrets of any size
+const keyringMaxSecret = 0
+
+// keyringGet looks a secret up in the Secret Service with secret-tool
+func keyringGet(service, account string) (string, error) {
+	cmd := exec.Command("secret-tool", "lookup", "service", service, "account", account)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", secretToolError(err, stderr.String())
+	}
+	return string(out), nil
+}
+
+// keyringSet stores a secret in the Secret Service with secret-tool, which
+// reads it from stdin
+func keyringSet(service, account, secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", "Converso CLI "+account, "service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return secretToolError(err, stderr.String())
+	}
+	return nil
+}
+
+// keyringDelete deletes a secret from the Secret Service with secret-tool
+func keyringDelete(service, account string) error {
+	if _, err := keyringGet(service, account); err != nil {
+		return err
+	}
+	cmd := exec.Command("secret-tool", "clear", "service", service, "account", account)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return secretToolError(err, stderr.String())
+	}
+	return nil
+}
+
+// secretToolError translates a failed secret-tool command. It exits with
+// status 1 and says nothing when a secret is not found.
+func secretToolError(err error, stderr string) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("secret-tool not found; install libsecret-tools or set credential_store: file")
+	}
+	msg := strings.TrimSpace(stderr)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && msg == "" {
+		return errKeyringNotFound
+	}
+	if msg != "" {
+		return errors.New(msg)
+	}
+	return err
+}
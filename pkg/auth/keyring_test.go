@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// memoryKeyring is a credential store in memory with the Windows
+// Credential Manager's size limit
+type memoryKeyring map[string]string
+
+func (m memoryKeyring) backend(t *testing.T) keyringBackend {
+	return keyringBackend{
+		get: func(service, account string) (string, error) {
+			secret, ok := m[service+":"+account]
+			if !ok {
+				return "", errKeyringNotFound
+			}
+			return secret, nil
+		},
+		set: func(service, account, secret string) error {
+			if len(secret) > 5*512 {
+				t.Fatalf("entry %s of %d bytes exceeds the limit", account, len(secret))
+			}
+			m[service+":"+account] = secret
+			return nil
+		},
+		delete: func(service, account string) error {
+			if _, ok := m[service+":"+account]; !ok {
+				return errKeyringNotFound
+			}
+			delete(m, service+":"+account)
+			return nil
+		},
+		maxSecret: 5 * 512,
+	}
+}
+
+// fakeJWT returns a token shaped like a signed JWT with a payload of size
+// bytes and an RS256 signature
+func fakeJWT(size int) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	header := encode([]byte(`{"alg":"RS256","kid":"key-2024-05","typ":"JWT"}`))
+	payload := encode([]byte(`{"sub":"user_2abc","scope":"` + strings.Repeat("read:media ", size/11) + `"}`))
+	return header + "." + payload + "." + encode(make([]byte, 256))
+}
+
+func newTestKeyringStorage(t *testing.T, keyring memoryKeyring) *KeyringStorage {
+	cfg := &config.Config{DataDir: t.TempDir()}
+	storage := NewKeyringStorage(cfg, telemetry.NewLogger(false)).(*KeyringStorage)
+	storage.keyring = keyring.backend(t)
+	return storage
+}
+
+func TestKeyringStorageSplitsLargeTokens(t *testing.T) {
+	keyring := make(memoryKeyring)
+	storage := newTestKeyringStorage(t, keyring)
+
+	tokens := &AuthTokens{
+		AccessToken:  fakeJWT(1500),
+		RefreshToken: fakeJWT(600),
+		ExpiresAt:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		TokenType:    "Bearer",
+		Scope:        "openid profile email offline_access read:media write:media",
+		DeviceID:     "dev_6f1c2a9e4b7d",
+		DeviceToken:  fakeJWT(400),
+	}
+	if err := storage.StoreTokens(tokens); err != nil {
+		t.Fatalf("StoreTokens: %v", err)
+	}
+	if len(keyring) < 3 {
+		t.Fatalf("expected the tokens split across entries, got %d entries", len(keyring))
+	}
+
+	retrieved, err := storage.RetrieveTokens()
+	if err != nil {
+		t.Fatalf("RetrieveTokens: %v", err)
+	}
+	if *retrieved != *tokens {
+		t.Fatalf("retrieved tokens differ from the stored ones")
+	}
+
+	// Smaller tokens fit one entry and leave no parts behind
+	small := &AuthTokens{AccessToken: "opaque", TokenType: "Bearer", ExpiresAt: tokens.ExpiresAt}
+	if err := storage.StoreTokens(small); err != nil {
+		t.Fatalf("StoreTokens: %v", err)
+	}
+	if len(keyring) != 1 {
+		t.Fatalf("expected 1 entry after storing small tokens, got %d", len(keyring))
+	}
+	if retrieved, err := storage.RetrieveTokens(); err != nil || *retrieved != *small {
+		t.Fatalf("RetrieveTokens after shrinking: %v", err)
+	}
+
+	if err := storage.StoreTokens(tokens); err != nil {
+		t.Fatalf("StoreTokens: %v", err)
+	}
+	if err := storage.DeleteTokens(); err != nil {
+		t.Fatalf("DeleteTokens: %v", err)
+	}
+	if len(keyring) != 0 {
+		t.Fatalf("expected no entries after DeleteTokens, got %d", len(keyring))
+	}
+}
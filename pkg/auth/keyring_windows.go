@@ -0,0 +1,115 @@
+//go:build windows
+
+package auth
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32        = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	// credMaxBlobSize is the largest secret the Credential Manager keeps
+	credMaxBlobSize = 5 * 512
+	// keyringMaxSecret splits larger secrets across credentials
+	keyringMaxSecret = credMaxBlobSize
+	// errorNotFound is ERROR_NOT_FOUND
+	errorNotFound = 1168
+)
+
+// credential mirrors the CREDENTIALW structure
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// keyringGet reads a generic credential from the Credential Manager
+func keyringGet(service, account string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(service + ":" + account)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	if ret, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred))); ret == 0 {
+		return "", credError(err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+// keyringSet adds or replaces a generic credential in the Credential
+// Manager
+func keyringSet(service, account, secret string) error {
+	if len(secret) > credMaxBlobSize {
+		return fmt.Errorf("secret of %d bytes exceeds the Credential Manager's limit of %d", len(secret), credMaxBlobSize)
+	}
+	target, err := syscall.UTF16PtrFromString(service + ":" + account)
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+
+	blob := []byte(secret)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+	if ret, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); ret == 0 {
+		return credError(err)
+	}
+	return nil
+}
+
+// keyringDelete deletes a generic credential from the Credential Manager
+func keyringDelete(service, account string) error {
+	target, err := syscall.UTF16PtrFromString(service + ":" + account)
+	if err != nil {
+		return err
+	}
+	if ret, _, err := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0); ret == 0 {
+		return credError(err)
+	}
+	return nil
+}
+
+// credError translates the error of a failed credential call
+func credError(err error) error {
+	if errno, ok := err.(syscall.Errno); ok && errno == errorNotFound {
+		return errKeyringNotFound
+	}
+	return err
+}
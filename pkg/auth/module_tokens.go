@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// moduleTokenMinLifetime is how long a cached module token must remain
+// valid to be reused; shorter-lived tokens are exchanged again
+const moduleTokenMinLifetime = 2 * time.Minute
+
+// moduleTokensFile caches module tokens in the data directory. It is
+// removed together with the user's tokens on logout.
+const moduleTokensFile = "module_tokens.json"
+
+// cachedModuleToken is a module token with the access token it came from
+type cachedModuleToken struct {
+	// Subject is a hash of the access token that was exchanged, so a new
+	// login or refresh invalidates the entry
+	Subject string      `json:"subject"`
+	Tokens  *AuthTokens `json:"tokens"`
+}
+
+// ModuleTokens hands out the tokens passed to modules over the bridge.
+// Rather than the user's access token, each module gets a short-lived token
+// whose audience is the module name, so a leaking plugin exposes only that.
+type ModuleTokens struct {
+	config *config.Config
+	logger telemetry.Logger
+	client *OAuth2Client
+	mu     sync.Mutex
+}
+
+// NewModuleTokens creates a new module token source
+func NewModuleTokens(cfg *config.Config, logger telemetry.Logger) *ModuleTokens {
+	return &ModuleTokens{
+		config: cfg,
+		logger: logger,
+		client: NewOAuth2Client(cfg, logger),
+	}
+}
+
+// Token returns the token to pass to a module, exchanging the access token
+// for a module-scoped one when scoped_module_tokens is enabled. When the
+// exchange fails, modules that do not require auth get no token rather
+// than the access token.
+func (m *ModuleTokens) Token(ctx context.Context, tokens *AuthTokens, module string, requiresAuth bool) (string, error) {
+	if !m.config.ScopedModuleTokens || tokens.AccessToken == "" {
+		return tokens.AccessToken, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subject := tokenHash(tokens.AccessToken)
+	cache := m.readCache()
+	if cached, ok := cache[module]; ok && cached.Subject == subject && cached.Tokens.Remaining() > moduleTokenMinLifetime {
+		return cached.Tokens.AccessToken, nil
+	}
+
+	scoped, err := m.client.ExchangeToken(ctx, tokens, module)
+	if err != nil {
+		if !requiresAuth {
+			m.logger.Debug("Running module without a user token", "module", module, "error", err)
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to obtain a token for module %s, which requires auth (scoped_module_tokens is enabled; the server must support token exchange): %w", module, err)
+	}
+	m.logger.Debug("Exchanged module token", "module", module, "expires_at", scoped.ExpiresAt)
+
+	cache[module] = &cachedModuleToken{Subject: subject, Tokens: scoped}
+	if err := m.writeCache(cache); err != nil {
+		m.logger.Warn("Failed to cache module token", "module", module, "error", err)
+	}
+	return scoped.AccessToken, nil
+}
+
+// readCache reads the cached module tokens, dropping expired ones
+func (m *ModuleTokens) readCache() map[string]*cachedModuleToken {
+	cache := make(map[string]*cachedModuleToken)
+
+	data, err := os.ReadFile(filepath.Join(m.config.DataDir, moduleTokensFile))
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		m.logger.Warn("Ignoring unreadable module token cache", "error", err)
+		return make(map[string]*cachedModuleToken)
+	}
+
+	for module, cached := range cache {
+		if cached == nil || cached.Tokens == nil || cached.Tokens.IsExpired() {
+			delete(cache, module)
+		}
+	}
+	return cache
+}
+
+// writeCache stores the module tokens readable only by the user
+func (m *ModuleTokens) writeCache(cache map[string]*cachedModuleToken) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.config.DataDir, 0700); err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(filepath.Join(m.config.DataDir, moduleTokensFile), data, 0600)
+}
+
+// tokenHash returns a hex SHA-256 of a token
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -14,7 +14,10 @@ import (
 	"time"
 
 	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/httpclient"
+	"github.com/converso-empire/cli/pkg/qrcode"
 	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/terminal"
 	"github.com/google/uuid"
 	"github.com/shirou/gopsutil/v3/host"
 )
@@ -23,22 +26,43 @@ import (
 type OAuth2Client struct {
 	config     *config.Config
 	httpClient *http.Client
+	verifier   *TokenVerifier
+	flow       DeviceFlowOptions
 	logger     telemetry.Logger
 }
 
+// DeviceFlowOptions controls how the device flow prompts the user
+type DeviceFlowOptions struct {
+	// OpenBrowser opens the verification URL in the default browser
+	OpenBrowser bool
+	// ShowQRCode prints the verification URL as a QR code for scanning
+	// with a phone. It is also shown when the browser cannot be opened.
+	ShowQRCode bool
+	// Progress shows a spinner with the elapsed time and the time left
+	// before the user code expires while polling; it rewrites the line, so
+	// it needs a terminal
+	Progress bool
+}
+
 // NewOAuth2Client creates a new OAuth2 client
 func NewOAuth2Client(cfg *config.Config, logger telemetry.Logger) *OAuth2Client {
 	return &OAuth2Client{
-		config: cfg,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		logger: logger,
+		config:     cfg,
+		httpClient: httpclient.New(cfg, logger, 30*time.Second),
+		verifier:   NewTokenVerifier(cfg, logger),
+		logger:     logger,
 	}
 }
 
-// DeviceAuthFlow performs the OAuth2 device authorization flow
-func (c *OAuth2Client) DeviceAuthFlow() (*AuthTokens, error) {
+// SetDeviceFlowOptions sets how the device flow prompts the user
+func (c *OAuth2Client) SetDeviceFlowOptions(options DeviceFlowOptions) {
+	c.flow = options
+}
+
+// DeviceAuthFlow performs the OAuth2 device authorization flow. Cancelling
+// ctx stops polling and invalidates the pending code where the server
+// supports it; the returned error then wraps context.Canceled.
+func (c *OAuth2Client) DeviceAuthFlow(ctx context.Context) (*AuthTokens, error) {
 	c.logger.Info("Starting device authorization flow")
 
 	// Get device information
@@ -48,7 +72,7 @@ func (c *OAuth2Client) DeviceAuthFlow() (*AuthTokens, error) {
 	}
 
 	// Request device code
-	deviceAuthResp, err := c.requestDeviceCode(deviceInfo)
+	deviceAuthResp, err := c.requestDeviceCode(ctx, deviceInfo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to request device code: %w", err)
 	}
@@ -57,7 +81,11 @@ func (c *OAuth2Client) DeviceAuthFlow() (*AuthTokens, error) {
 	c.displayVerificationInstructions(deviceAuthResp)
 
 	// Poll for tokens
-	tokens, err := c.pollForTokens(deviceAuthResp)
+	tokens, err := c.pollForTokens(ctx, deviceAuthResp)
+	if err != nil && ctx.Err() != nil {
+		c.cancelDeviceCode(deviceAuthResp)
+		return nil, fmt.Errorf("login cancelled: %w", ctx.Err())
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to obtain tokens: %w", err)
 	}
@@ -76,8 +104,9 @@ func (c *OAuth2Client) DeviceAuthFlow() (*AuthTokens, error) {
 	return tokens, nil
 }
 
-// RefreshTokens refreshes the access token using the refresh token
-func (c *OAuth2Client) RefreshTokens(tokens *AuthTokens) (*AuthTokens, error) {
+// RefreshTokens refreshes the access token using the refresh token, giving
+// up when ctx ends
+func (c *OAuth2Client) RefreshTokens(ctx context.Context, tokens *AuthTokens) (*AuthTokens, error) {
 	c.logger.Info("Refreshing tokens")
 
 	data := map[string]string{
@@ -86,24 +115,69 @@ func (c *OAuth2Client) RefreshTokens(tokens *AuthTokens) (*AuthTokens, error) {
 		"client_id":     c.config.ClientID,
 	}
 
-	resp, err := c.makeTokenRequest(data)
+	resp, err := c.makeTokenRequest(ctx, data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to refresh tokens: %w", err)
 	}
 
 	// Update tokens
-	tokens.AccessToken = resp.AccessToken
-	tokens.RefreshToken = resp.RefreshToken
-	tokens.ExpiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
-	tokens.TokenType = resp.TokenType
-	tokens.Scope = resp.Scope
+	refreshed := newTokens(resp)
+	if err := c.verifyTokens(refreshed, c.config.ClientID); err != nil {
+		return nil, err
+	}
+	tokens.AccessToken = refreshed.AccessToken
+	tokens.RefreshToken = refreshed.RefreshToken
+	tokens.ExpiresAt = refreshed.ExpiresAt
+	tokens.ClockSkew = refreshed.ClockSkew
+	tokens.TokenType = refreshed.TokenType
+	tokens.Scope = refreshed.Scope
 
 	c.logger.Info("Tokens refreshed successfully")
 	return tokens, nil
 }
 
+// ExchangeToken exchanges an access token for a short-lived token limited
+// to an audience, using OAuth 2.0 Token Exchange (RFC 8693)
+func (c *OAuth2Client) ExchangeToken(ctx context.Context, tokens *AuthTokens, audience string) (*AuthTokens, error) {
+	data := map[string]string{
+		"grant_type":           "urn:ietf:params:oauth:grant-type:token-exchange",
+		"client_id":            c.config.ClientID,
+		"subject_token":        tokens.AccessToken,
+		"subject_token_type":   "urn:ietf:params:oauth:token-type:access_token",
+		"requested_token_type": "urn:ietf:params:oauth:token-type:access_token",
+		"audience":             audience,
+	}
+
+	resp, err := c.makeTokenRequest(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange token for %s: %w", audience, err)
+	}
+
+	exchanged := newTokens(resp)
+	exchanged.RefreshToken = ""
+	if err := c.verifyTokens(exchanged, audience); err != nil {
+		return nil, err
+	}
+	return exchanged, nil
+}
+
+// verifyTokens checks a JWT access token against the published keys and
+// its claims against audience, at the server time. With verification
+// configured, a token is rejected when no keys, fresh or cached, can be
+// had.
+func (c *OAuth2Client) verifyTokens(tokens *AuthTokens, audience string) error {
+	claims, err := c.verifier.Verify(tokens.AccessToken, tokens.ServerNow(), audience)
+	if err != nil {
+		return fmt.Errorf("access token failed verification: %w", err)
+	}
+	if claims != nil {
+		c.logger.Debug("Access token verified", "subject", claims.Subject, "issuer", claims.Issuer)
+	}
+	return nil
+}
+
 // requestDeviceCode requests a device code from the authorization server
-func (c *OAuth2Client) requestDeviceCode(deviceInfo *Device) (*DeviceAuthResponse, error) {
+func (c *OAuth2Client) requestDeviceCode(ctx context.Context, deviceInfo *Device) (*DeviceAuthResponse, error) {
 	data := map[string]string{
 		"client_id": c.config.ClientID,
 		"scope":     "openid profile email",
@@ -114,7 +188,7 @@ func (c *OAuth2Client) requestDeviceCode(deviceInfo *Device) (*DeviceAuthRespons
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", c.config.AuthURL+"/device/code", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, httpclient.DeviceCode.Method, httpclient.DeviceCode.URL(c.config), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
@@ -139,15 +213,45 @@ func (c *OAuth2Client) requestDeviceCode(deviceInfo *Device) (*DeviceAuthRespons
 	return &deviceAuthResp, nil
 }
 
+// Device flow polling intervals from RFC 8628
+const (
+	defaultPollInterval = 5 * time.Second
+	slowDownIncrement   = 5 * time.Second
+)
+
 // pollForTokens polls the token endpoint until tokens are available
-func (c *OAuth2Client) pollForTokens(deviceAuthResp *DeviceAuthResponse) (*AuthTokens, error) {
-	ticker := time.NewTicker(time.Duration(deviceAuthResp.Interval) * time.Second)
+func (c *OAuth2Client) pollForTokens(ctx context.Context, deviceAuthResp *DeviceAuthResponse) (*AuthTokens, error) {
+	interval := time.Duration(deviceAuthResp.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	timeout := time.After(time.Duration(deviceAuthResp.ExpiresIn) * time.Second)
+	started := time.Now()
+	expiresAt := started.Add(time.Duration(deviceAuthResp.ExpiresIn) * time.Second)
+	timeout := time.After(time.Until(expiresAt))
+
+	// Progress line, cleared once polling ends
+	var spinner <-chan time.Time
+	frame := 0
+	if c.flow.Progress {
+		spinnerTicker := time.NewTicker(100 * time.Millisecond)
+		defer spinnerTicker.Stop()
+		spinner = spinnerTicker.C
+		printPollProgress(frame, started, expiresAt)
+		defer fmt.Print("\r\033[K")
+	}
 
 	for {
 		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-spinner:
+			frame++
+			printPollProgress(frame, started, expiresAt)
+
 		case <-ticker.C:
 			data := map[string]string{
 				"grant_type":    "urn:ietf:params:oauth:grant-type:device_code",
@@ -156,23 +260,29 @@ func (c *OAuth2Client) pollForTokens(deviceAuthResp *DeviceAuthResponse) (*AuthT
 				"client_secret": c.config.ClientSecret,
 			}
 
-			resp, err := c.makeTokenRequest(data)
-			if err != nil {
-				if errors.Is(err, ErrAuthorizationPending) {
-					continue
-				}
+			resp, err := c.makeTokenRequest(ctx, data)
+			switch {
+			case errors.Is(err, ErrAuthorizationPending):
+				continue
+			case errors.Is(err, ErrSlowDown):
+				// RFC 8628 requires adding 5 seconds for this and all
+				// later requests
+				interval += slowDownIncrement
+				ticker.Reset(interval)
+				c.logger.Debug("Authorization server asked to slow down", "interval", interval.String())
+				continue
+			case errors.Is(err, ErrExpiredToken):
+				return nil, errors.New("the code expired before it was approved. Run 'converso login' again")
+			case errors.Is(err, ErrAccessDenied):
+				return nil, errors.New("the authorization request was denied")
+			case err != nil:
 				return nil, err
 			}
 
-			// Create AuthTokens
-			tokens := &AuthTokens{
-				AccessToken:  resp.AccessToken,
-				RefreshToken: resp.RefreshToken,
-				ExpiresAt:    time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
-				TokenType:    resp.TokenType,
-				Scope:        resp.Scope,
+			tokens := newTokens(resp)
+			if err := c.verifyTokens(tokens, c.config.ClientID); err != nil {
+				return nil, err
 			}
-
 			return tokens, nil
 
 		case <-timeout:
@@ -181,6 +291,43 @@ func (c *OAuth2Client) pollForTokens(deviceAuthResp *DeviceAuthResponse) (*AuthT
 	}
 }
 
+// cancelDeviceCode asks the authorization server to invalidate a pending
+// device code. RFC 8628 defines no cancellation, so this is best effort and
+// servers without the endpoint let the code expire instead.
+func (c *OAuth2Client) cancelDeviceCode(deviceAuthResp *DeviceAuthResponse) {
+	data := map[string]string{
+		"client_id":   c.config.ClientID,
+		"device_code": deviceAuthResp.DeviceCode,
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, httpclient.DeviceCodeCancel.Method, httpclient.DeviceCodeCancel.URL(c.config), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.logger.Debug("Failed to cancel device code", "error", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		c.logger.Debug("Device code was not cancelled", "status", resp.StatusCode)
+		return
+	}
+	c.logger.Info("Cancelled pending device code")
+}
+
 // registerDevice registers the device with the backend API
 func (c *OAuth2Client) registerDevice(deviceInfo *Device, tokens *AuthTokens) (*RegisterDeviceResponse, error) {
 	reqData := RegisterDeviceRequest{
@@ -196,7 +343,7 @@ func (c *OAuth2Client) registerDevice(deviceInfo *Device, tokens *AuthTokens) (*
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", c.config.APIEndpoint+"/api/v1/devices/register", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest(httpclient.RegisterDevice.Method, httpclient.RegisterDevice.URL(c.config), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
@@ -220,58 +367,121 @@ func (c *OAuth2Client) registerDevice(deviceInfo *Device, tokens *AuthTokens) (*
 		return nil, err
 	}
 
+	// Dry runs answer with an empty object
+	if deviceResp.DeviceID == "" {
+		deviceResp.DeviceID = reqData.DeviceID
+	}
+
 	return &deviceResp, nil
 }
 
 // makeTokenRequest makes a request to the token endpoint
-func (c *OAuth2Client) makeTokenRequest(data map[string]string) (*TokenResponse, error) {
+func (c *OAuth2Client) makeTokenRequest(ctx context.Context, data map[string]string) (*TokenResponse, error) {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", c.config.TokenURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, httpclient.Token.Method, httpclient.Token.URL(c.config), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
+	sent := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, ErrAuthorizationPending
-	}
-
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("token request failed with status %d", resp.StatusCode)
+		return nil, parseOAuthError(resp)
 	}
 
 	var tokenResp TokenResponse
 	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
 		return nil, err
 	}
+	tokenResp.ClockSkew = measureClockSkew(resp, sent)
+	if tokenResp.ClockSkew != 0 {
+		c.logger.Warn("Local clock differs from the server clock", "skew", tokenResp.ClockSkew.String())
+	}
 
 	return &tokenResp, nil
 }
 
 // displayVerificationInstructions displays the verification instructions to the user
 func (c *OAuth2Client) displayVerificationInstructions(deviceAuthResp *DeviceAuthResponse) {
+	// The complete URI carries the user code, so nothing needs typing
+	verificationURL := deviceAuthResp.VerificationURIComplete
+	if verificationURL == "" {
+		verificationURL = deviceAuthResp.VerificationURI
+	}
+
+	browserOpened := false
+	if c.flow.OpenBrowser {
+		if err := OpenBrowser(verificationURL); err != nil {
+			c.logger.Debug("Failed to open browser", "error", err)
+		} else {
+			browserOpened = true
+		}
+	}
+
 	fmt.Println()
 	fmt.Println("🔑 Authentication Required")
 	fmt.Println("=========================")
+	if browserOpened {
+		fmt.Printf("🌐 Opened %s in your browser\n", verificationURL)
+		fmt.Println("   If it did not open, follow the steps below.")
+		fmt.Println()
+	}
 	fmt.Printf("1. Open your browser and go to: %s\n", deviceAuthResp.VerificationURI)
 	fmt.Printf("2. Enter the following code: %s\n", deviceAuthResp.UserCode)
 	fmt.Println("3. Complete the authentication process")
 	fmt.Println()
+
+	if c.flow.ShowQRCode || (c.flow.OpenBrowser && !browserOpened) {
+		code, err := qrcode.Encode(verificationURL)
+		switch {
+		case err != nil:
+			c.logger.Debug("Failed to encode QR code", "error", err)
+		case !terminal.Unicode():
+			// Block characters have no ASCII replacement a phone could scan
+			c.logger.Debug("Skipping QR code on a terminal without Unicode")
+		default:
+			fmt.Println("📱 Or scan this code with your phone:")
+			fmt.Println()
+			fmt.Print(code.Terminal())
+			fmt.Println()
+		}
+	}
+
 	fmt.Println("Waiting for authentication...")
 	fmt.Println()
 }
 
+// spinnerFrames animate the polling progress line
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// printPollProgress rewrites the current line with a spinner, the time spent
+// waiting and the time left before the user code expires
+func printPollProgress(frame int, started, expiresAt time.Time) {
+	remaining := time.Until(expiresAt)
+	if remaining < 0 {
+		remaining = 0
+	}
+	fmt.Printf("\r\033[K%s Waiting for approval (%s elapsed), code expires in %s. Press Ctrl-C to cancel",
+		spinnerFrames[frame%len(spinnerFrames)], formatClock(time.Since(started)), formatClock(remaining))
+}
+
+// formatClock formats a duration as m:ss
+func formatClock(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d/time.Minute), int(d%time.Minute/time.Second))
+}
+
 // getDeviceInfo gets information about the current device
 func (c *OAuth2Client) getDeviceInfo() (*Device, error) {
 	hostname, err := os.Hostname()
@@ -297,9 +507,6 @@ func (c *OAuth2Client) getDeviceInfo() (*Device, error) {
 	return device, nil
 }
 
-// ErrAuthorizationPending is returned when authorization is still pending
-var ErrAuthorizationPending = errors.New("authorization pending")
-
 // OpenBrowser opens the default web browser to the specified URL
 func OpenBrowser(url string) error {
 	var cmd *exec.Cmd
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Device flow errors from RFC 8628 section 3.5. OAuthError unwraps to these,
+// so callers can test for them with errors.Is.
+var (
+	// ErrAuthorizationPending is returned when authorization is still pending
+	ErrAuthorizationPending = errors.New("authorization pending")
+	// ErrSlowDown is returned when polling too fast; the interval must grow
+	ErrSlowDown = errors.New("polling too fast")
+	// ErrExpiredToken is returned when the device code has expired
+	ErrExpiredToken = errors.New("device code expired")
+	// ErrAccessDenied is returned when the user denied the authorization
+	ErrAccessDenied = errors.New("authorization denied")
+)
+
+// OAuthError is a standard OAuth2 error response (RFC 6749 section 5.2)
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	URI         string `json:"error_uri,omitempty"`
+	StatusCode  int    `json:"-"`
+}
+
+// Error returns the error code with its description
+func (e *OAuthError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("%s: %s", e.Code, e.Description)
+	}
+	return e.Code
+}
+
+// Unwrap maps the device flow error codes to their sentinel errors
+func (e *OAuthError) Unwrap() error {
+	switch e.Code {
+	case "authorization_pending":
+		return ErrAuthorizationPending
+	case "slow_down":
+		return ErrSlowDown
+	case "expired_token":
+		return ErrExpiredToken
+	case "access_denied":
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+// parseOAuthError reads an OAuth2 error response. Bodies without an error
+// code produce a generic error with the status code.
+func parseOAuthError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	oauthErr := &OAuthError{StatusCode: resp.StatusCode}
+	if err := json.Unmarshal(body, oauthErr); err != nil || oauthErr.Code == "" {
+		return fmt.Errorf("token request failed with status %d", resp.StatusCode)
+	}
+	return oauthErr
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// refreshRetryInterval is how long a provider keeps using tokens it failed
+// to refresh before trying again, while they have not expired
+const refreshRetryInterval = time.Minute
+
+// TokenProvider hands out valid tokens for as long as a process runs,
+// refreshing them shortly before they expire. Refreshes hold the storage's
+// token lock and are stored, so processes running side by side refresh
+// once and pick up each other's tokens.
+type TokenProvider struct {
+	manager *AuthManager
+	refresh func(ctx context.Context, tokens *AuthTokens) (*AuthTokens, error)
+
+	mu     sync.Mutex
+	tokens *AuthTokens
+	// retryAt is when a failed refresh is tried again
+	retryAt time.Time
+}
+
+// NewTokenProvider creates a token provider over the configured storage
+func NewTokenProvider(cfg *config.Config, logger telemetry.Logger) *TokenProvider {
+	return &TokenProvider{
+		manager: NewAuthManager(NewStorage(cfg, logger), logger),
+		refresh: NewOAuth2Client(cfg, logger).RefreshTokens,
+	}
+}
+
+// Tokens returns tokens that have not expired, refreshing them first when
+// they are about to. The refresh ends when ctx does.
+func (p *TokenProvider) Tokens(ctx context.Context) (*AuthTokens, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.tokens != nil && !p.tokens.IsExpired() && (!p.tokens.NeedsRefresh() || time.Now().Before(p.retryAt)) {
+		tokens := *p.tokens
+		return &tokens, nil
+	}
+
+	tokens, err := p.manager.ValidTokens(ctx, p.refresh)
+	if err != nil {
+		return nil, err
+	}
+	if tokens.IsExpired() {
+		return nil, fmt.Errorf("access token expired at %s", tokens.ExpiresAt.Format(time.RFC3339))
+	}
+
+	p.tokens = tokens
+	p.retryAt = time.Time{}
+	if tokens.NeedsRefresh() {
+		// The refresh failed or is not possible; keep the tokens for a while
+		// instead of asking again on every call
+		p.retryAt = time.Now().Add(refreshRetryInterval)
+	}
+	current := *tokens
+	return &current, nil
+}
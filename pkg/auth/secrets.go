@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/fileutil"
+)
+
+// secretsFile holds module secrets in the data directory
+const secretsFile = "secrets.json"
+
+// envSecretPrefix prefixes module secrets in the environment in headless
+// mode, e.g. CONVERSO_SECRET_TRANSLATE_API_KEY for key api_key of module
+// translate
+const envSecretPrefix = "CONVERSO_SECRET_"
+
+// secretKeyPattern restricts module and key names, which also keeps them
+// representable in environment variable names
+var secretKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ValidateSecretName checks a module or secret key name
+func ValidateSecretName(name string) error {
+	if !secretKeyPattern.MatchString(name) {
+		return fmt.Errorf("invalid name %q: use letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// StoreSecret stores a secret for a module
+func (s *FileStorage) StoreSecret(module, key, value string) error {
+	return s.updateSecrets(func(secrets map[string]map[string]string) {
+		if secrets[module] == nil {
+			secrets[module] = make(map[string]string)
+		}
+		secrets[module][key] = value
+	})
+}
+
+// RetrieveSecrets returns the secrets of a module
+func (s *FileStorage) RetrieveSecrets(module string) (map[string]string, error) {
+	secrets, err := s.readSecrets()
+	if err != nil {
+		return nil, err
+	}
+	return secrets[module], nil
+}
+
+// DeleteSecret deletes a secret of a module
+func (s *FileStorage) DeleteSecret(module, key string) error {
+	found := false
+	err := s.updateSecrets(func(secrets map[string]map[string]string) {
+		if _, found = secrets[module][key]; found {
+			delete(secrets[module], key)
+			if len(secrets[module]) == 0 {
+				delete(secrets, module)
+			}
+		}
+	})
+	if err == nil && !found {
+		return fmt.Errorf("secret %s not set for module %s", key, module)
+	}
+	return err
+}
+
+// ListSecrets returns the sorted secret keys of every module
+func (s *FileStorage) ListSecrets() (map[string][]string, error) {
+	secrets, err := s.readSecrets()
+	if err != nil {
+		return nil, err
+	}
+	return secretKeys(secrets), nil
+}
+
+// updateSecrets applies update to the stored secrets under a lock
+func (s *FileStorage) updateSecrets(update func(secrets map[string]map[string]string)) error {
+	if err := os.MkdirAll(s.config.DataDir, 0700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	filename := filepath.Join(s.config.DataDir, secretsFile)
+	lock, err := fileutil.Lock(filename+".lock", tokenLockTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to lock secrets: %w", err)
+	}
+	defer lock.Unlock()
+
+	secrets, err := s.readSecrets()
+	if err != nil {
+		return err
+	}
+	update(secrets)
+
+	data, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+	if err := fileutil.WriteAtomic(filename, data, 0600); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	return nil
+}
+
+// readSecrets reads the stored secrets; a missing file has none
+func (s *FileStorage) readSecrets() (map[string]map[string]string, error) {
+	secrets := make(map[string]map[string]string)
+
+	data, err := os.ReadFile(filepath.Join(s.config.DataDir, secretsFile))
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal secrets: %w", err)
+	}
+	return secrets, nil
+}
+
+// StoreSecret is not supported in headless mode
+func (s *EnvStorage) StoreSecret(module, key, value string) error {
+	return errReadOnly
+}
+
+// RetrieveSecrets reads the secrets of a module from
+// CONVERSO_SECRET_<MODULE>_<KEY> variables, or from the file named by
+// CONVERSO_SECRET_<MODULE>_<KEY>_FILE
+func (s *EnvStorage) RetrieveSecrets(module string) (map[string]string, error) {
+	secrets, err := envSecrets()
+	if err != nil {
+		return nil, err
+	}
+	return secrets[module], nil
+}
+
+// DeleteSecret is not supported in headless mode
+func (s *EnvStorage) DeleteSecret(module, key string) error {
+	return errReadOnly
+}
+
+// ListSecrets returns the secret keys set in the environment
+func (s *EnvStorage) ListSecrets() (map[string][]string, error) {
+	secrets, err := envSecrets()
+	if err != nil {
+		return nil, err
+	}
+	return secretKeys(secrets), nil
+}
+
+// envSecrets collects module secrets from the environment. Module and key
+// are split at the first underscore and lowercased, so module names
+// containing underscores cannot be given secrets this way.
+func envSecrets() (map[string]map[string]string, error) {
+	secrets := make(map[string]map[string]string)
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, envSecretPrefix) {
+			continue
+		}
+
+		module, key, ok := strings.Cut(strings.ToLower(strings.TrimPrefix(name, envSecretPrefix)), "_")
+		if !ok || module == "" || key == "" {
+			continue
+		}
+		if strings.HasSuffix(key, "_file") {
+			data, err := os.ReadFile(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			key = strings.TrimSuffix(key, "_file")
+			value = strings.TrimRight(string(data), "\r\n")
+		}
+
+		if secrets[module] == nil {
+			secrets[module] = make(map[string]string)
+		}
+		secrets[module][key] = value
+	}
+	return secrets, nil
+}
+
+// secretKeys returns the sorted keys of each module's secrets
+func secretKeys(secrets map[string]map[string]string) map[string][]string {
+	keys := make(map[string][]string)
+	for module, values := range secrets {
+		for key := range values {
+			keys[module] = append(keys[module], key)
+		}
+		sort.Strings(keys[module])
+	}
+	return keys
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -8,21 +9,40 @@ import (
 	"time"
 
 	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/events"
+	"github.com/converso-empire/cli/pkg/fileutil"
 	"github.com/converso-empire/cli/pkg/telemetry"
 	"github.com/google/uuid"
 )
 
-// SecureStorage handles secure storage of authentication tokens
+// SecureStorage handles secure storage of authentication tokens and module
+// secrets
 type SecureStorage interface {
 	StoreTokens(tokens *AuthTokens) error
 	RetrieveTokens() (*AuthTokens, error)
+	UpdateTokens(update func(tokens *AuthTokens) (*AuthTokens, error)) (*AuthTokens, error)
 	DeleteTokens() error
 	StoreDevice(device *Device) error
 	RetrieveDevice() (*Device, error)
 	DeleteDevice() error
+	StoreSecret(module, key, value string) error
+	RetrieveSecrets(module string) (map[string]string, error)
+	DeleteSecret(module, key string) error
+	ListSecrets() (map[string][]string, error)
 }
 
-// FileStorage implements SecureStorage using encrypted files
+// tokenLockTimeout bounds the wait for another process refreshing tokens;
+// it exceeds the OAuth client timeout so a slow refresh is waited out
+const tokenLockTimeout = 45 * time.Second
+
+// CredentialFiles are the files in the data directory FileStorage keeps
+// tokens, the device registration and module secrets in, and CookieStore
+// module cookies
+var CredentialFiles = []string{"tokens.json", "device.json", secretsFile, cookiesFile, cookieKeyFile}
+
+// FileStorage implements SecureStorage using encrypted files. Files are
+// written atomically and token updates are serialized across processes
+// with a lock file next to tokens.json.
 type FileStorage struct {
 	config *config.Config
 	logger telemetry.Logger
@@ -38,6 +58,59 @@ func NewFileStorage(cfg *config.Config, logger telemetry.Logger) SecureStorage {
 
 // StoreTokens stores authentication tokens securely
 func (s *FileStorage) StoreTokens(tokens *AuthTokens) error {
+	lock, err := s.lockTokens()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return s.storeTokens(tokens)
+}
+
+// UpdateTokens reads the stored tokens, passes them to update and stores
+// the result if it differs, all while holding the token lock. Concurrent
+// invocations refreshing tokens therefore refresh once and the others see
+// the refreshed tokens.
+func (s *FileStorage) UpdateTokens(update func(tokens *AuthTokens) (*AuthTokens, error)) (*AuthTokens, error) {
+	lock, err := s.lockTokens()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	tokens, err := s.RetrieveTokens()
+	if err != nil {
+		return nil, err
+	}
+	current := *tokens
+
+	updated, err := update(tokens)
+	if err != nil {
+		return nil, err
+	}
+	if *updated != current {
+		if err := s.storeTokens(updated); err != nil {
+			return nil, err
+		}
+	}
+	return updated, nil
+}
+
+// lockTokens acquires the cross-process token lock
+func (s *FileStorage) lockTokens() (*fileutil.FileLock, error) {
+	if err := os.MkdirAll(s.config.DataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	lock, err := fileutil.Lock(filepath.Join(s.config.DataDir, "tokens.json.lock"), tokenLockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock tokens: %w", err)
+	}
+	return lock, nil
+}
+
+// storeTokens writes tokens; the caller holds the token lock
+func (s *FileStorage) storeTokens(tokens *AuthTokens) error {
 	// Create data directory if it doesn't exist
 	if err := os.MkdirAll(s.config.DataDir, 0700); err != nil {
 		return fmt.Errorf("failed to create data directory: %w", err)
@@ -53,7 +126,7 @@ func (s *FileStorage) StoreTokens(tokens *AuthTokens) error {
 	}
 
 	// Write to file with restricted permissions
-	if err := os.WriteFile(filename, data, 0600); err != nil {
+	if err := fileutil.WriteAtomic(filename, data, 0600); err != nil {
 		return fmt.Errorf("failed to write tokens file: %w", err)
 	}
 
@@ -90,6 +163,12 @@ func (s *FileStorage) RetrieveTokens() (*AuthTokens, error) {
 func (s *FileStorage) DeleteTokens() error {
 	filename := filepath.Join(s.config.DataDir, "tokens.json")
 
+	lock, err := s.lockTokens()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
 	// Check if file exists
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		return nil // File doesn't exist, nothing to delete
@@ -100,6 +179,11 @@ func (s *FileStorage) DeleteTokens() error {
 		return fmt.Errorf("failed to delete tokens file: %w", err)
 	}
 
+	// Module tokens derive from the deleted tokens
+	if err := os.Remove(filepath.Join(s.config.DataDir, moduleTokensFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete module tokens file: %w", err)
+	}
+
 	s.logger.Info("Tokens deleted successfully")
 	return nil
 }
@@ -121,7 +205,7 @@ func (s *FileStorage) StoreDevice(device *Device) error {
 	}
 
 	// Write to file with restricted permissions
-	if err := os.WriteFile(filename, data, 0600); err != nil {
+	if err := fileutil.WriteAtomic(filename, data, 0600); err != nil {
 		return fmt.Errorf("failed to write device file: %w", err)
 	}
 
@@ -196,6 +280,30 @@ func (m *AuthManager) IsAuthenticated(cfg *config.Config) bool {
 	return !tokens.IsExpired()
 }
 
+// ValidTokens returns the stored tokens, refreshing them first when they
+// are about to expire. If the refresh fails, tokens that have not expired
+// yet are still returned. The refresh ends when ctx does.
+func (m *AuthManager) ValidTokens(ctx context.Context, refresh func(ctx context.Context, tokens *AuthTokens) (*AuthTokens, error)) (*AuthTokens, error) {
+	return m.storage.UpdateTokens(func(tokens *AuthTokens) (*AuthTokens, error) {
+		// Another process may have refreshed while we waited for the lock
+		if !tokens.NeedsRefresh() || tokens.RefreshToken == "" {
+			return tokens, nil
+		}
+
+		current := *tokens
+		refreshed, err := refresh(ctx, tokens)
+		if err != nil {
+			if current.IsExpired() {
+				return nil, err
+			}
+			m.logger.Warn("Token refresh failed, using current token", "error", err)
+			return &current, nil
+		}
+		events.Publish(events.AuthRefreshed, events.Auth{DeviceID: refreshed.DeviceID, ExpiresAt: refreshed.ExpiresAt})
+		return refreshed, nil
+	})
+}
+
 // GetAuthStatus returns the current authentication status
 func (m *AuthManager) GetAuthStatus(cfg *config.Config) (*AuthStatus, error) {
 	tokens, err := m.storage.RetrieveTokens()
@@ -205,22 +313,29 @@ func (m *AuthManager) GetAuthStatus(cfg *config.Config) (*AuthStatus, error) {
 		}, nil
 	}
 
+	status := &AuthStatus{
+		Authenticated: !tokens.IsExpired(),
+		DeviceID:      tokens.DeviceID,
+		Scope:         tokens.Scope,
+		ExpiresAt:     tokens.ExpiresAt,
+		ClockSkew:     tokens.ClockSkew,
+	}
+
 	device, err := m.storage.RetrieveDevice()
-	if err != nil {
-		return &AuthStatus{
-			Authenticated: !tokens.IsExpired(),
-			DeviceID:      tokens.DeviceID,
-			ExpiresAt:     tokens.ExpiresAt,
-		}, nil
+	if err == nil {
+		status.DeviceID = device.ID
+		status.Username = device.Name
 	}
 
-	return &AuthStatus{
-		Authenticated: !tokens.IsExpired(),
-		DeviceID:      device.ID,
-		Username:      device.Name,
-		Email:         "", // Would be populated from token claims
-		ExpiresAt:     tokens.ExpiresAt,
-	}, nil
+	// User details come from the access token claims when it is a JWT
+	if claims, ok := ParseClaims(tokens.AccessToken); ok {
+		if name := claims.DisplayName(); name != "" {
+			status.Username = name
+		}
+		status.Email = claims.Email
+	}
+
+	return status, nil
 }
 
 // ClearAuth clears all authentication data
@@ -25,6 +25,9 @@ type AuthTokens struct {
 	Scope           string    `json:"scope"`
 	DeviceID        string    `json:"device_id"`
 	DeviceToken     string    `json:"device_token"`
+	// ClockSkew is how far the server clock was ahead of the local clock
+	// when the tokens were issued; ExpiresAt is in server time
+	ClockSkew time.Duration `json:"clock_skew,omitempty"`
 }
 
 // OAuth2Config represents OAuth2 configuration
@@ -53,6 +56,9 @@ type TokenResponse struct {
 	ExpiresIn    int    `json:"expires_in"`
 	RefreshToken string `json:"refresh_token"`
 	Scope        string `json:"scope"`
+
+	// ClockSkew is measured from the response's Date header
+	ClockSkew time.Duration `json:"-"`
 }
 
 // RegisterDeviceRequest represents the request to register a device
@@ -72,22 +78,35 @@ type RegisterDeviceResponse struct {
 
 // AuthStatus represents the current authentication status
 type AuthStatus struct {
-	Authenticated bool      `json:"authenticated"`
-	DeviceID      string    `json:"device_id"`
-	Username      string    `json:"username"`
-	Email         string    `json:"email"`
-	ExpiresAt     time.Time `json:"expires_at"`
+	Authenticated bool          `json:"authenticated"`
+	DeviceID      string        `json:"device_id"`
+	Username      string        `json:"username"`
+	Email         string        `json:"email"`
+	Scope         string        `json:"scope"`
+	ExpiresAt     time.Time     `json:"expires_at"`
+	ClockSkew     time.Duration `json:"clock_skew"`
+}
+
+// ServerNow estimates the current server time from the local clock and
+// the recorded clock skew
+func (t *AuthTokens) ServerNow() time.Time {
+	return time.Now().Add(t.ClockSkew)
+}
+
+// Remaining returns how long the tokens stay valid
+func (t *AuthTokens) Remaining() time.Duration {
+	return t.ExpiresAt.Sub(t.ServerNow())
 }
 
 // IsExpired checks if the tokens are expired
 func (t *AuthTokens) IsExpired() bool {
-	return time.Now().After(t.ExpiresAt)
+	return t.ServerNow().After(t.ExpiresAt)
 }
 
 // NeedsRefresh checks if the tokens need to be refreshed
 func (t *AuthTokens) NeedsRefresh() bool {
 	// Refresh if expires within 5 minutes
-	return time.Now().Add(5 * time.Minute).After(t.ExpiresAt)
+	return t.ServerNow().Add(5 * time.Minute).After(t.ExpiresAt)
 }
 
 // MarshalJSON implements custom JSON marshaling for AuthTokens
@@ -0,0 +1,358 @@
+// Package backup archives the CLI's state so it can be moved to another
+// machine or recovered after a reinstall. A backup is a gzipped tar of the
+// user config, the files in the data directory that record history and
+// other state, a lockfile of the installed plugins and, optionally, the
+// credentials encrypted with a passphrase.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/archive"
+	"github.com/converso-empire/cli/pkg/auth"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+	"github.com/converso-empire/cli/pkg/history"
+	"github.com/converso-empire/cli/pkg/runs"
+	"github.com/converso-empire/cli/pkg/stats"
+	"github.com/converso-empire/cli/pkg/subscriptions"
+	"github.com/converso-empire/cli/pkg/worker"
+)
+
+// FormatVersion is the version of the backup layout written by Create
+const FormatVersion = 1
+
+// Names of the entries besides state files
+const (
+	manifestName    = "manifest.json"
+	pluginsLockName = "plugins.lock.json"
+	credentialsName = "credentials.enc"
+)
+
+// Manifest describes the contents of a backup
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Host      string    `json:"host"`
+	// Files are the archive names of the state files
+	Files []string `json:"files"`
+	// Credentials reports whether encrypted credentials are included
+	Credentials bool `json:"credentials"`
+	// Plugins is the lockfile of installed plugins
+	Plugins []Plugin `json:"plugins"`
+}
+
+// Plugin pins an installed plugin
+type Plugin struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// Options configures Create
+type Options struct {
+	// Plugins are recorded in the plugin lockfile
+	Plugins []Plugin
+	// Passphrase encrypts the credentials; they are left out when empty
+	Passphrase string
+}
+
+// file maps an archive name to a local path
+type file struct {
+	name string
+	path string
+}
+
+// stateFiles returns the config and data files a backup holds
+func stateFiles(cfg *config.Config) ([]file, error) {
+	configFile, err := config.UserConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	files := []file{{name: "config/config.yaml", path: configFile}}
+	for _, dataPath := range []string{
+		history.DefaultPath(cfg),
+		archive.DefaultPath(cfg),
+		subscriptions.DefaultPath(cfg),
+		stats.DefaultPath(cfg),
+		runs.DefaultPath(cfg),
+	} {
+		files = append(files, dataFile(dataPath))
+	}
+	return files, nil
+}
+
+// credentialFiles returns the files holding credentials
+func credentialFiles(cfg *config.Config) []file {
+	var files []file
+	for _, name := range auth.CredentialFiles {
+		files = append(files, dataFile(filepath.Join(cfg.DataDir, name)))
+	}
+	return append(files, dataFile(worker.ControlTokenPath(cfg)))
+}
+
+// dataFile names a file of the data directory in the archive
+func dataFile(path string) file {
+	return file{name: "data/" + filepath.Base(path), path: path}
+}
+
+// existing returns the files that exist
+func existing(files []file) []file {
+	var found []file
+	for _, f := range files {
+		if info, err := os.Stat(f.path); err == nil && info.Mode().IsRegular() {
+			found = append(found, f)
+		}
+	}
+	return found
+}
+
+// Create writes a backup of the state to dest
+func Create(cfg *config.Config, dest string, opts Options) (*Manifest, error) {
+	files, err := stateFiles(cfg)
+	if err != nil {
+		return nil, err
+	}
+	files = existing(files)
+
+	host, _ := os.Hostname()
+	manifest := &Manifest{
+		Version:   FormatVersion,
+		CreatedAt: time.Now().UTC(),
+		Host:      host,
+		Plugins:   opts.Plugins,
+	}
+	for _, f := range files {
+		manifest.Files = append(manifest.Files, f.name)
+	}
+	sort.Slice(manifest.Plugins, func(i, j int) bool { return manifest.Plugins[i].Name < manifest.Plugins[j].Name })
+
+	// Credentials are packed into their own tar and encrypted
+	var credentials []byte
+	if opts.Passphrase != "" {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, f := range existing(credentialFiles(cfg)) {
+			if err := addFile(tw, f); err != nil {
+				return nil, err
+			}
+		}
+		if err := tw.Close(); err != nil {
+			return nil, err
+		}
+		if credentials, err = encrypt(buf.Bytes(), opts.Passphrase); err != nil {
+			return nil, fmt.Errorf("failed to encrypt credentials: %w", err)
+		}
+		manifest.Credentials = true
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := addJSON(tw, manifestName, manifest); err != nil {
+		return nil, err
+	}
+	if err := addJSON(tw, pluginsLockName, manifest.Plugins); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if err := addFile(tw, f); err != nil {
+			return nil, err
+		}
+	}
+	if credentials != nil {
+		if err := addBytes(tw, credentialsName, credentials); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	// History and the like are private, so the backup is too
+	if err := fileutil.WriteAtomic(dest, buf.Bytes(), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	return manifest, nil
+}
+
+// addFile adds a local file to a tar
+func addFile(tw *tar.Writer, f file) error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.path, err)
+	}
+	return addBytes(tw, f.name, data)
+}
+
+// addJSON adds a value as a JSON file to a tar
+func addJSON(tw *tar.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return addBytes(tw, name, data)
+}
+
+// addBytes adds a file with the given contents to a tar
+func addBytes(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// contents is a read backup
+type contents struct {
+	manifest    *Manifest
+	files       map[string][]byte
+	credentials []byte
+}
+
+// read loads a backup into memory
+func read(src string) (*contents, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a Converso backup: %w", src, err)
+	}
+	tr := tar.NewReader(gz)
+
+	c := &contents{files: make(map[string][]byte)}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+
+		switch name := path.Clean(header.Name); name {
+		case manifestName:
+			var manifest Manifest
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				return nil, fmt.Errorf("invalid backup manifest: %w", err)
+			}
+			c.manifest = &manifest
+		case credentialsName:
+			c.credentials = data
+		case pluginsLockName:
+			// The manifest holds the same list
+		default:
+			c.files[name] = data
+		}
+	}
+
+	if c.manifest == nil {
+		return nil, fmt.Errorf("%s is not a Converso backup: no manifest", src)
+	}
+	if c.manifest.Version > FormatVersion {
+		return nil, fmt.Errorf("backup format %d is newer than this release supports (%d); upgrade converso", c.manifest.Version, FormatVersion)
+	}
+	return c, nil
+}
+
+// Inspect returns the manifest of a backup
+func Inspect(src string) (*Manifest, error) {
+	c, err := read(src)
+	if err != nil {
+		return nil, err
+	}
+	return c.manifest, nil
+}
+
+// Restore replaces the state with the contents of the backup at src. The
+// credentials are restored when the backup has them and passphrase is
+// given. It returns the paths written.
+func Restore(cfg *config.Config, src, passphrase string) ([]string, error) {
+	c, err := read(src)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only known files are restored, which keeps archive names from
+	// escaping the data directory
+	targets, err := stateFiles(cfg)
+	if err != nil {
+		return nil, err
+	}
+	writes := make(map[string][]byte)
+	var order []string
+	for _, f := range targets {
+		if data, ok := c.files[f.name]; ok {
+			writes[f.path] = data
+			order = append(order, f.path)
+		}
+	}
+
+	if c.credentials != nil && passphrase != "" {
+		plaintext, err := decrypt(c.credentials, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		packed := make(map[string][]byte)
+		tr := tar.NewReader(bytes.NewReader(plaintext))
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read credentials: %w", err)
+			}
+			if packed[path.Clean(header.Name)], err = io.ReadAll(tr); err != nil {
+				return nil, fmt.Errorf("failed to read credentials: %w", err)
+			}
+		}
+		for _, f := range credentialFiles(cfg) {
+			if data, ok := packed[f.name]; ok {
+				writes[f.path] = data
+				order = append(order, f.path)
+			}
+		}
+	}
+
+	// Everything is read and decrypted before the first file is replaced
+	var restored []string
+	for _, target := range order {
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return restored, fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+		}
+		if err := fileutil.WriteAtomic(target, writes[target], 0600); err != nil {
+			return restored, fmt.Errorf("failed to restore %s: %w", target, err)
+		}
+		restored = append(restored, target)
+	}
+	return restored, nil
+}
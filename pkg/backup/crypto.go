@@ -0,0 +1,101 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Credentials are encrypted with AES-256-GCM under a key derived from the
+// passphrase with PBKDF2-HMAC-SHA256, both FIPS 140 approved
+const (
+	encryptionMagic  = "CVB1"
+	saltSize         = 16
+	kdfIterations    = 600000
+	encryptionKeyLen = 32
+)
+
+// errPassphrase is returned when credentials cannot be decrypted
+var errPassphrase = errors.New("wrong passphrase or corrupted backup")
+
+// encrypt seals plaintext with a key derived from passphrase. The output
+// is the magic, the salt, the nonce and the ciphertext.
+func encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte(encryptionMagic), salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, []byte(encryptionMagic)), nil
+}
+
+// decrypt opens data sealed by encrypt
+func decrypt(data []byte, passphrase string) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(encryptionMagic)) || len(data) < len(encryptionMagic)+saltSize {
+		return nil, fmt.Errorf("unsupported credentials format")
+	}
+	data = data[len(encryptionMagic):]
+	gcm, err := newGCM(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[saltSize:]
+	if len(data) < gcm.NonceSize() {
+		return nil, errPassphrase
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(encryptionMagic))
+	if err != nil {
+		return nil, errPassphrase
+	}
+	return plaintext, nil
+}
+
+// newGCM returns the AES-GCM cipher for a passphrase and salt
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2SHA256([]byte(passphrase), salt, kdfIterations, encryptionKeyLen))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 derives a key as specified in RFC 8018 section 5.2
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
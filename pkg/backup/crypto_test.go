@@ -0,0 +1,66 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// TestPBKDF2SHA256 checks the key derivation against the PBKDF2-HMAC-SHA256
+// vectors of RFC 7914 section 11 and draft-josefsson-pbkdf2-test-vectors
+func TestPBKDF2SHA256(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		iterations     int
+		want           string
+	}{
+		{"password", "salt", 1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{"password", "salt", 2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{"password", "salt", 4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, "348c89dbcbd32b2f32d814b8116e84cf2b17347ebc1800181c4e2a1fb8dd53e1c635518c7dac47e9"},
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+	for _, tt := range tests {
+		want, _ := hex.DecodeString(tt.want)
+		got := pbkdf2SHA256([]byte(tt.password), []byte(tt.salt), tt.iterations, len(want))
+		if !bytes.Equal(got, want) {
+			t.Errorf("pbkdf2SHA256(%q, %q, %d) = %x, want %s", tt.password, tt.salt, tt.iterations, got, tt.want)
+		}
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	plaintext := []byte(`{"access_token":"eyJhbGciOiJSUzI1NiJ9.e30.c2ln"}`)
+
+	sealed, err := encrypt(plaintext, "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Fatalf("sealed data contains the plaintext")
+	}
+
+	opened, err := decrypt(sealed, "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Fatalf("decrypt = %q, want %q", opened, plaintext)
+	}
+
+	if _, err := decrypt(sealed, "wrong passphrase"); !errors.Is(err, errPassphrase) {
+		t.Fatalf("decrypt with a wrong passphrase: got %v, want %v", err, errPassphrase)
+	}
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := decrypt(tampered, "correct horse battery staple"); !errors.Is(err, errPassphrase) {
+		t.Fatalf("decrypt of tampered data: got %v, want %v", err, errPassphrase)
+	}
+
+	if _, err := decrypt([]byte("not a backup"), "correct horse battery staple"); err == nil {
+		t.Fatalf("decrypt of foreign data succeeded")
+	}
+}
@@ -2,6 +2,8 @@ package bridge
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -11,7 +13,19 @@ type ModuleRequest struct {
 	Args        map[string]interface{} `json:"args"`
 	AuthToken   string                 `json:"auth_token"`
 	DeviceToken string                 `json:"device_token"`
+	Secrets     map[string]string      `json:"secrets,omitempty"`
 	Timeout     int                    `json:"timeout"`
+	IdleTimeout int                    `json:"idle_timeout,omitempty"`
+	Compression *Compression           `json:"compression,omitempty"`
+	// RunID is the CLI invocation the request belongs to, for modules to
+	// stamp on what they produce
+	RunID string `json:"run_id,omitempty"`
+	// Cookies are the module's stored cookies, so site sessions carry over
+	// between invocations
+	Cookies []Cookie `json:"cookies,omitempty"`
+	// LogLevel is the lowest level of log lines the module should send as
+	// log frames; modules only send them to CLIs that set it
+	LogLevel string `json:"log_level,omitempty"`
 }
 
 // ModuleResponse represents a response from a Python module
@@ -20,16 +34,72 @@ type ModuleResponse struct {
 	Data        map[string]interface{} `json:"data"`
 	Error       string                 `json:"error"`
 	Progress    *ProgressEvent         `json:"progress,omitempty"`
+	Items       []ItemResult           `json:"items,omitempty"`
+	// Cookies replace the module's stored cookies when set; modules that
+	// did not touch their cookies leave it out
+	Cookies []Cookie `json:"cookies,omitempty"`
+}
+
+// Cookie is an HTTP cookie a module keeps between invocations
+type Cookie struct {
+	Domain string `json:"domain"`
+	Path   string `json:"path"`
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	// Expires is the expiry in Unix seconds, 0 for session cookies
+	Expires  int64 `json:"expires,omitempty"`
+	Secure   bool  `json:"secure,omitempty"`
+	HTTPOnly bool  `json:"http_only,omitempty"`
+}
+
+// Expired reports whether the cookie expired before now
+func (c Cookie) Expired(now time.Time) bool {
+	return c.Expires > 0 && c.Expires <= now.Unix()
+}
+
+// Matches reports whether the cookie belongs to domain or one of its
+// subdomains
+func (c Cookie) Matches(domain string) bool {
+	host := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// ItemResult represents the outcome of a single item in a batch operation
+type ItemResult struct {
+	ID      string                 `json:"id"`
+	Success bool                   `json:"success"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	Error   string                 `json:"error,omitempty"`
 }
 
 // ProgressEvent represents a progress update from a module
 type ProgressEvent struct {
+	Item        string  `json:"item,omitempty"`
 	Stage       string  `json:"stage"`
 	Current     int64   `json:"current"`
 	Total       int64   `json:"total"`
 	Percentage  float64 `json:"percentage"`
 	Message     string  `json:"message"`
 	Timestamp   time.Time `json:"timestamp"`
+
+	// Overall is the weighted percentage across all stages, and StageIndex
+	// and StageCount give the 1-based position of the current stage when the
+	// module declared a stage plan
+	Overall    float64 `json:"overall"`
+	StageIndex int     `json:"stage_index,omitempty"`
+	StageCount int     `json:"stage_count,omitempty"`
+
+	// StalledFor is set by the bridge when a module is alive but has not
+	// reported progress for a while
+	StalledFor time.Duration `json:"-"`
+}
+
+// KeepaliveEvent is emitted by modules during long silent phases to show
+// they are still working
+type KeepaliveEvent struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
 }
 
 // ModuleManifest represents a Python module's manifest
@@ -41,6 +111,95 @@ type ModuleManifest struct {
 	Dependencies []string `json:"dependencies"`
 	Author      string   `json:"author"`
 	License     string   `json:"license"`
+
+	// URLPatterns declares the URLs the module handles, as host names
+	// ("youtu.be"), wildcard domains ("*.youtube.com") or schemes ("magnet:")
+	URLPatterns []string `json:"url_patterns,omitempty"`
+
+	// DefaultCommand is run by 'converso open' for matching URLs
+	DefaultCommand string `json:"default_command,omitempty"`
+
+	// Completions lists, per module command, the arguments and flags whose
+	// shell completion values come from the module's complete command
+	Completions map[string][]string `json:"completions,omitempty"`
+
+	// Options lists the optional arguments the module's commands accept
+	// besides their own, such as "proxy" or "geo_bypass_country"
+	Options []string `json:"options,omitempty"`
+
+	// Protocol is the bridge protocol version the module speaks. Modules
+	// declaring one send a hello frame when they start, which the bridge
+	// waits for; others are sent their request right away.
+	Protocol int `json:"protocol,omitempty"`
+
+	// RequiresAuth declares that the module calls Converso APIs with the
+	// user's token. Other modules run without one when no module-scoped
+	// token can be had.
+	RequiresAuth bool `json:"requires_auth,omitempty"`
+}
+
+// CompleteCommand is the module command that returns shell completion values
+const CompleteCommand = "complete"
+
+// Completion is a shell completion value offered by a module
+type Completion struct {
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+// Completes reports whether the module provides completion values for an
+// argument or flag of one of its commands
+func (m *ModuleManifest) Completes(command, arg string) bool {
+	for _, name := range m.Completions[command] {
+		if name == arg {
+			return true
+		}
+	}
+	return false
+}
+
+// Accepts reports whether the module's commands take an optional argument
+func (m *ModuleManifest) Accepts(option string) bool {
+	for _, name := range m.Options {
+		if name == option {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCompletions reads the values returned by a complete command. Values
+// may be plain strings or objects with a value and description.
+func ParseCompletions(resp *ModuleResponse) []Completion {
+	raw, _ := resp.Data["values"].([]interface{})
+
+	completions := make([]Completion, 0, len(raw))
+	for _, value := range raw {
+		switch v := value.(type) {
+		case string:
+			completions = append(completions, Completion{Value: v})
+		case map[string]interface{}:
+			c := Completion{}
+			c.Value, _ = v["value"].(string)
+			c.Description, _ = v["description"].(string)
+			if c.Value != "" {
+				completions = append(completions, c)
+			}
+		}
+	}
+	return completions
+}
+
+// DefaultCommandName returns the command run for URLs opened with this
+// module, falling back to the first declared command
+func (m *ModuleManifest) DefaultCommandName() string {
+	if m.DefaultCommand != "" {
+		return m.DefaultCommand
+	}
+	if len(m.Commands) > 0 {
+		return m.Commands[0]
+	}
+	return ""
 }
 
 // ModuleInfo represents information about a loaded module
@@ -93,9 +252,36 @@ func (r *ModuleResponse) Validate() error {
 	if !r.Success && r.Error == "" {
 		return ErrInvalidResponse("error message required for failed response")
 	}
+	for i, item := range r.Items {
+		if item.ID == "" {
+			return ErrInvalidResponse(fmt.Sprintf("item %d is missing an id", i))
+		}
+	}
 	return nil
 }
 
+// HasItems reports whether the response carries per-item results
+func (r *ModuleResponse) HasItems() bool {
+	return len(r.Items) > 0
+}
+
+// FailedItems returns the items that did not succeed
+func (r *ModuleResponse) FailedItems() []ItemResult {
+	var failed []ItemResult
+	for _, item := range r.Items {
+		if !item.Success {
+			failed = append(failed, item)
+		}
+	}
+	return failed
+}
+
+// IsPartialFailure reports whether some, but not all, items failed
+func (r *ModuleResponse) IsPartialFailure() bool {
+	failed := len(r.FailedItems())
+	return failed > 0 && failed < len(r.Items)
+}
+
 // Validate checks if the progress event is valid
 func (p *ProgressEvent) Validate() error {
 	if p.Stage == "" {
@@ -132,7 +318,10 @@ var (
 	ErrInvalidProgress = func(msg string) *BridgeError { return &BridgeError{Code: "INVALID_PROGRESS", Message: msg} }
 	ErrModuleNotFound  = func(msg string) *BridgeError { return &BridgeError{Code: "MODULE_NOT_FOUND", Message: msg} }
 	ErrModuleTimeout   = func(msg string) *BridgeError { return &BridgeError{Code: "MODULE_TIMEOUT", Message: msg} }
+	ErrModuleIdle      = func(msg string) *BridgeError { return &BridgeError{Code: "MODULE_IDLE_TIMEOUT", Message: msg} }
+	ErrModuleCancelled = func(msg string) *BridgeError { return &BridgeError{Code: "MODULE_CANCELLED", Message: msg} }
 	ErrModuleError     = func(msg string) *BridgeError { return &BridgeError{Code: "MODULE_ERROR", Message: msg} }
+	ErrProtocol        = func(msg string) *BridgeError { return &BridgeError{Code: "PROTOCOL_ERROR", Message: msg} }
 )
 
 // JSON serialization helpers
@@ -192,6 +381,15 @@ func ModuleManifestFromJSON(data []byte) (*ModuleManifest, error) {
 	return &manifest, nil
 }
 
+// KeepaliveEventFromJSON deserializes JSON into a KeepaliveEvent
+func KeepaliveEventFromJSON(data []byte) (*KeepaliveEvent, error) {
+	var keepalive KeepaliveEvent
+	if err := json.Unmarshal(data, &keepalive); err != nil {
+		return nil, err
+	}
+	return &keepalive, nil
+}
+
 // FromJSON deserializes JSON into a Job
 func JobFromJSON(data []byte) (*Job, error) {
 	var job Job
@@ -0,0 +1,261 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ControlProtocol is the first protocol version whose modules take control
+// messages on stdin while a command runs
+const ControlProtocol = 3
+
+// Control actions
+const (
+	ControlPause        = "pause"
+	ControlResume       = "resume"
+	ControlCancel       = "cancel"
+	ControlSetRateLimit = "set_rate_limit"
+)
+
+// cancelGrace is how long a cancelled module may take to wind down and
+// respond before its process is killed
+const cancelGrace = 10 * time.Second
+
+// Control is a message sent to a running module command, such as pausing
+// it or limiting its download rate
+type Control struct {
+	Type   string `json:"type"`
+	Action string `json:"action"`
+	// RateLimit is the download rate set_rate_limit allows in bytes per
+	// second; zero removes the limit
+	RateLimit int64 `json:"rate_limit,omitempty"`
+}
+
+// Validate validates a control message
+func (c *Control) Validate() error {
+	switch c.Action {
+	case ControlPause, ControlResume, ControlCancel:
+		if c.RateLimit != 0 {
+			return fmt.Errorf("%s takes no rate limit", c.Action)
+		}
+	case ControlSetRateLimit:
+		if c.RateLimit < 0 {
+			return fmt.Errorf("rate limit must not be negative")
+		}
+	default:
+		return fmt.Errorf("unknown control action %q", c.Action)
+	}
+	return nil
+}
+
+// IsCancelled reports whether err ended a command cancelled through a
+// control message or by cancelling its context
+func IsCancelled(err error) bool {
+	var bridgeErr *BridgeError
+	return errors.As(err, &bridgeErr) && bridgeErr.Code == "MODULE_CANCELLED"
+}
+
+// syncWriter serializes the frames written to a module's stdin by the
+// response reader and the control watcher
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write writes a whole frame
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// runControl forwards control messages to a running command and reports
+// their effect in its progress
+type runControl struct {
+	b      *JSONBridge
+	module string
+	// w is the module's stdin, nil when the module takes no controls
+	w            io.Writer
+	kill         context.CancelFunc
+	progressChan chan<- *ProgressEvent
+
+	mu        sync.Mutex
+	last      *ProgressEvent
+	paused    bool
+	cancelled bool
+}
+
+// watch applies controls until the returned stop function is called,
+// cancelling the command once interrupted is closed. Stop waits for the
+// watcher, so no notice is sent on progressChan after it returns.
+func (c *runControl) watch(controls <-chan *Control, interrupted <-chan struct{}) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		var grace <-chan time.Time
+		for {
+			select {
+			case control, ok := <-controls:
+				if !ok {
+					controls = nil
+					continue
+				}
+				if c.apply(control) && grace == nil {
+					grace = time.After(cancelGrace)
+				}
+			case <-interrupted:
+				interrupted = nil
+				c.b.logger.Info("Cancelling interrupted module", "module", c.module)
+				if c.apply(&Control{Action: ControlCancel}) && grace == nil {
+					grace = time.After(cancelGrace)
+				}
+			case <-grace:
+				c.b.logger.Warn("Killing cancelled module", "module", c.module, "grace", cancelGrace)
+				c.kill()
+				grace = nil
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// apply sends a control to the module. It returns true when the module was
+// asked to cancel and has cancelGrace to stop.
+func (c *runControl) apply(control *Control) bool {
+	if err := control.Validate(); err != nil {
+		c.b.logger.Warn("Invalid module control", "module", c.module, "error", err.Error())
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancelled {
+		return false
+	}
+
+	if c.w == nil {
+		// Modules predating controls can still be cancelled by killing them
+		if control.Action == ControlCancel {
+			c.cancelled = true
+			c.notice("Cancelling...")
+			c.kill()
+			return false
+		}
+		c.b.logger.Warn("Module does not take controls", "module", c.module, "action", control.Action)
+		c.notice(fmt.Sprintf("%s does not support %s", c.module, control.Action))
+		return false
+	}
+
+	switch control.Action {
+	case ControlPause:
+		if c.paused {
+			return false
+		}
+	case ControlResume:
+		if !c.paused {
+			return false
+		}
+	}
+
+	sent := *control
+	sent.Type = FrameTypeControl
+	data, err := json.Marshal(&sent)
+	if err == nil {
+		_, err = c.w.Write(append(data, '\n'))
+	}
+	if err != nil {
+		c.b.logger.Warn("Failed to send module control", "module", c.module, "action", control.Action, "error", err.Error())
+		return false
+	}
+	c.b.logger.Debug("Module control sent", "module", c.module, "action", control.Action, "rate_limit", control.RateLimit)
+
+	switch control.Action {
+	case ControlPause:
+		c.paused = true
+		c.notice("Paused")
+	case ControlResume:
+		c.paused = false
+		c.notice("Resumed")
+	case ControlCancel:
+		c.cancelled = true
+		c.notice("Cancelling...")
+		return true
+	case ControlSetRateLimit:
+		if control.RateLimit == 0 {
+			c.notice("Rate limit removed")
+		} else {
+			c.notice("Rate limited to " + formatRate(control.RateLimit))
+		}
+	}
+	return false
+}
+
+// notice shows message in place of the last progress message. The caller
+// holds mu.
+func (c *runControl) notice(message string) {
+	if c.progressChan == nil {
+		return
+	}
+	event := &ProgressEvent{Stage: "working"}
+	if c.last != nil {
+		copied := *c.last
+		event = &copied
+	}
+	event.Message = message
+	event.StalledFor = 0
+	event.Timestamp = time.Now()
+	c.progressChan <- event
+}
+
+// observe remembers the module's latest progress
+func (c *runControl) observe(progress *ProgressEvent) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.last = progress
+}
+
+// isPaused reports whether the command was paused
+func (c *runControl) isPaused() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// wasCancelled reports whether the command was cancelled
+func (c *runControl) wasCancelled() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cancelled
+}
+
+// formatRate formats a rate limit in bytes per second
+func formatRate(bytesPerSecond int64) string {
+	units := []string{"B/s", "KB/s", "MB/s", "GB/s"}
+	rate := float64(bytesPerSecond)
+	i := 0
+	for rate >= 1024 && i < len(units)-1 {
+		rate /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", rate, units[i])
+}
@@ -0,0 +1,244 @@
+package bridge
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields reads module response data tolerantly. Modules do not agree on
+// types: numbers may arrive as JSON numbers or strings, sizes as bytes or
+// "150.5 MB" and durations as seconds or "10:30". Missing or unreadable
+// fields read as zero values.
+type Fields map[string]interface{}
+
+// String returns a field as a string, formatting numbers and booleans
+func (f Fields) String(key string) string {
+	switch v := f[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// Float returns a field given as a number or a numeric string
+func (f Fields) Float(key string) float64 {
+	switch v := f[key].(type) {
+	case float64:
+		return v
+	case string:
+		if n, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// Int returns a field given as a number or a numeric string, truncated
+func (f Fields) Int(key string) int64 {
+	return int64(f.Float(key))
+}
+
+// Size returns a size in bytes given as a number or as a human-readable
+// string such as "150.5 MB"
+func (f Fields) Size(key string) int64 {
+	if s, ok := f[key].(string); ok {
+		return ParseSize(s)
+	}
+	return f.Int(key)
+}
+
+// Duration returns a duration given as seconds or as "[h:]mm:ss"
+func (f Fields) Duration(key string) time.Duration {
+	if s, ok := f[key].(string); ok {
+		return parseClock(s)
+	}
+	return time.Duration(f.Float(key) * float64(time.Second))
+}
+
+// Strings returns the strings in a list field, skipping other values
+func (f Fields) Strings(key string) []string {
+	raw, _ := f[key].([]interface{})
+	list := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if s, ok := item.(string); ok && s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// List returns the objects in a list field, skipping other values
+func (f Fields) List(key string) []Fields {
+	raw, _ := f[key].([]interface{})
+	list := make([]Fields, 0, len(raw))
+	for _, item := range raw {
+		if m, ok := item.(map[string]interface{}); ok {
+			list = append(list, Fields(m))
+		}
+	}
+	return list
+}
+
+// UploadDate returns the upload_date field and whether it holds a date
+func (f Fields) UploadDate() (time.Time, bool) {
+	return ParseUploadDate(f.String("upload_date"))
+}
+
+// ParseUploadDate reads an upload date as reported by yt-dlp, YYYYMMDD
+func ParseUploadDate(s string) (time.Time, bool) {
+	date, err := time.Parse("20060102", s)
+	return date, err == nil
+}
+
+// sizeUnits are the multipliers of human-readable sizes, binary as written
+// by the module helpers' format_size
+var sizeUnits = map[string]float64{
+	"":    1,
+	"B":   1,
+	"KB":  1 << 10,
+	"KIB": 1 << 10,
+	"MB":  1 << 20,
+	"MIB": 1 << 20,
+	"GB":  1 << 30,
+	"GIB": 1 << 30,
+	"TB":  1 << 40,
+	"TIB": 1 << 40,
+}
+
+// ParseSize parses a size such as "150.5 MB" or "1024" into bytes. It
+// returns 0 for sizes it cannot read.
+func ParseSize(s string) int64 {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0
+	}
+	unit, ok := sizeUnits[strings.ToUpper(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0
+	}
+	return int64(n * unit)
+}
+
+// parseClock parses "[h:]mm:ss" or plain seconds into a duration
+func parseClock(s string) time.Duration {
+	var seconds float64
+	for _, part := range strings.Split(strings.TrimSpace(s), ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// VideoInfo is the metadata of a video, as returned by info and listing
+// commands
+type VideoInfo struct {
+	ID          string
+	URL         string
+	Title       string
+	Uploader    string
+	Description string
+	Duration    time.Duration
+	ViewCount   int64
+	// UploadDate is YYYYMMDD as reported by the site
+	UploadDate string
+}
+
+// DecodeVideoInfo reads video metadata from module data
+func DecodeVideoInfo(data map[string]interface{}) VideoInfo {
+	f := Fields(data)
+	return VideoInfo{
+		ID:          f.String("id"),
+		URL:         f.String("url"),
+		Title:       f.String("title"),
+		Uploader:    f.String("uploader"),
+		Description: f.String("description"),
+		Duration:    f.Duration("duration"),
+		ViewCount:   f.Int("view_count"),
+		UploadDate:  f.String("upload_date"),
+	}
+}
+
+// Format is a downloadable format of a video
+type Format struct {
+	ID         string
+	Ext        string
+	VideoCodec string
+	AudioCodec string
+	Height     int
+	FPS        float64
+	// AudioBitrate is in kbps and SampleRate in Hz
+	AudioBitrate float64
+	SampleRate   int
+	FileSize     int64
+	Note         string
+
+	// Fields are all fields the module sent, for filters and templates
+	Fields Fields
+}
+
+// HasVideo reports whether the format carries a video stream
+func (f *Format) HasVideo() bool {
+	return f.VideoCodec != "" && f.VideoCodec != "none"
+}
+
+// HasAudio reports whether the format carries an audio stream
+func (f *Format) HasAudio() bool {
+	return f.AudioCodec != "" && f.AudioCodec != "none"
+}
+
+// DecodeFormats reads the formats list from module data
+func DecodeFormats(data map[string]interface{}) []Format {
+	entries := Fields(data).List("formats")
+	formats := make([]Format, 0, len(entries))
+	for _, f := range entries {
+		formats = append(formats, Format{
+			ID:           f.String("format_id"),
+			Ext:          f.String("ext"),
+			VideoCodec:   f.String("vcodec"),
+			AudioCodec:   f.String("acodec"),
+			Height:       int(f.Int("height")),
+			FPS:          f.Float("fps"),
+			AudioBitrate: f.Float("abr"),
+			SampleRate:   int(f.Int("asr")),
+			FileSize:     f.Size("filesize"),
+			Note:         f.String("format_note"),
+			Fields:       f,
+		})
+	}
+	return formats
+}
+
+// DownloadResult is the outcome of a download command
+type DownloadResult struct {
+	FilePath string
+	Title    string
+	FileSize int64
+	Duration time.Duration
+}
+
+// DecodeDownloadResult reads a download result from module data
+func DecodeDownloadResult(data map[string]interface{}) DownloadResult {
+	f := Fields(data)
+	return DownloadResult{
+		FilePath: f.String("file_path"),
+		Title:    f.String("title"),
+		FileSize: f.Size("file_size"),
+		Duration: f.Duration("duration"),
+	}
+}
@@ -0,0 +1,355 @@
+package bridge
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// EncodingGzip identifies gzip-compressed, base64-encoded frames
+const EncodingGzip = "gzip"
+
+// Frame types emitted by modules. Untyped frames are treated as progress
+// events or the final response for compatibility with older modules.
+const (
+	FrameTypeHello     = "hello"
+	FrameTypeProgress  = "progress"
+	FrameTypeKeepalive = "keepalive"
+	FrameTypeStages    = "stages"
+	FrameTypePrompt    = "prompt"
+	FrameTypePong      = "pong"
+	FrameTypeLog       = "log"
+)
+
+// Frame types the bridge writes to a module's stdin besides requests:
+// answers to prompts, controls of a running command, and health checks of
+// persistent processes
+const (
+	FrameTypeAnswer  = "answer"
+	FrameTypeControl = "control"
+	FrameTypePing    = "ping"
+)
+
+// handshakeTimeout bounds how long the bridge waits for a module's hello frame
+const handshakeTimeout = 5 * time.Second
+
+// HelloFrame is the handshake a module emits when it starts
+type HelloFrame struct {
+	Type      string   `json:"type"`
+	Protocol  int      `json:"protocol"`
+	Encodings []string `json:"encodings"`
+	// Persistent confirms the module serves requests until its stdin
+	// closes, as asked by PersistentEnv
+	Persistent bool `json:"persistent,omitempty"`
+}
+
+// Supports reports whether the module accepts the given frame encoding
+func (h *HelloFrame) Supports(encoding string) bool {
+	for _, e := range h.Encodings {
+		if e == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+// Compression describes the frame compression negotiated for a request
+type Compression struct {
+	Encoding  string `json:"encoding"`
+	Threshold int    `json:"threshold"`
+}
+
+// EncodedFrame wraps a compressed request or response line
+type EncodedFrame struct {
+	Encoding string `json:"encoding"`
+	Payload  string `json:"payload"`
+}
+
+// frameBufferLimit bounds how much of a single frame the decoder may
+// buffer, so a runaway module cannot exhaust memory
+const frameBufferLimit = 64 << 20
+
+// frame is a single message from a module, decoded straight from the pipe
+// into the typed shapes the bridge knows. Which fields are set depends on
+// Type; untyped frames are progress events or the final response.
+type frame struct {
+	Type string `json:"type"`
+
+	// Encoding and Payload wrap a compressed frame
+	Encoding string `json:"encoding"`
+	Payload  string `json:"payload"`
+
+	// Protocol, Encodings and Persistent are sent in the hello frame
+	Protocol   int      `json:"protocol"`
+	Encodings  []string `json:"encodings"`
+	Persistent bool     `json:"persistent"`
+
+	// Stages are sent in the stage plan frame
+	Stages []StageWeight `json:"stages"`
+
+	// ID, Choices, Default, Secret and Timeout are sent in prompt frames,
+	// along with the progress message
+	ID      string   `json:"id"`
+	Choices []string `json:"choices"`
+	Default string   `json:"default"`
+	Secret  bool     `json:"secret"`
+	Timeout int      `json:"timeout"`
+
+	// Level and Fields are sent in log frames, along with the message
+	Level  string                 `json:"level"`
+	Fields map[string]interface{} `json:"fields"`
+
+	// Progress events and responses share no keys, so both decode in the
+	// same pass; keepalives reuse the progress message
+	ProgressEvent
+	ModuleResponse
+
+	// raw is the frame as sent, kept in strict mode for validation
+	raw json.RawMessage
+}
+
+// hello returns the handshake carried by the frame, if any
+func (f *frame) hello() *HelloFrame {
+	if f.Type != FrameTypeHello {
+		return nil
+	}
+	return &HelloFrame{Type: f.Type, Protocol: f.Protocol, Encodings: f.Encodings, Persistent: f.Persistent}
+}
+
+// keepalive returns the frame as a keepalive event
+func (f *frame) keepalive() *KeepaliveEvent {
+	return &KeepaliveEvent{Type: f.Type, Message: f.Message}
+}
+
+// stagePlan returns the frame as a stage plan
+func (f *frame) stagePlan() *StagePlan {
+	return &StagePlan{Type: f.Type, Stages: f.Stages}
+}
+
+// prompt returns the frame as a prompt
+func (f *frame) prompt() *Prompt {
+	return &Prompt{
+		Type:    f.Type,
+		ID:      f.ID,
+		Message: f.Message,
+		Choices: f.Choices,
+		Default: f.Default,
+		Secret:  f.Secret,
+		Timeout: f.Timeout,
+	}
+}
+
+// logEntry returns the frame as a module log line
+func (f *frame) logEntry() *LogEntry {
+	return &LogEntry{Type: f.Type, Level: f.Level, Message: f.Message, Fields: f.Fields}
+}
+
+// progress returns the frame as a progress event, or nil when it is not a
+// valid one
+func (f *frame) progress() *ProgressEvent {
+	if err := f.ProgressEvent.Validate(); err != nil {
+		return nil
+	}
+	return &f.ProgressEvent
+}
+
+// response returns the frame as the final module response
+func (f *frame) response() *ModuleResponse {
+	return &f.ModuleResponse
+}
+
+// encodeFrame gzip-compresses a JSON frame and wraps it in an EncodedFrame
+func encodeFrame(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&EncodedFrame{
+		Encoding: EncodingGzip,
+		Payload:  base64.StdEncoding.EncodeToString(buf.Bytes()),
+	})
+}
+
+// decodeFrame reads the next frame from decoder, unwrapping compressed
+// frames without materializing their decompressed JSON unless keepRaw asks
+// for the frame as sent
+func decodeFrame(decoder *json.Decoder, keepRaw bool) (*frame, error) {
+	f, err := decodeFields(decoder, keepRaw)
+	if err != nil {
+		return nil, err
+	}
+	if f.Encoding == "" || f.Payload == "" {
+		return f, nil
+	}
+
+	if f.Encoding != EncodingGzip {
+		return nil, ErrInvalidResponse(fmt.Sprintf("unsupported frame encoding: %s", f.Encoding))
+	}
+
+	compressed := base64.NewDecoder(base64.StdEncoding, strings.NewReader(f.Payload))
+	zr, err := gzip.NewReader(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress frame: %w", err)
+	}
+	defer zr.Close()
+
+	limited := &boundedReader{r: zr, limit: frameBufferLimit}
+	inner, err := decodeFields(json.NewDecoder(limited), keepRaw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode compressed frame: %w", err)
+	}
+	return inner, nil
+}
+
+// decodeFields decodes a single frame, keeping its raw JSON if asked to
+func decodeFields(decoder *json.Decoder, keepRaw bool) (*frame, error) {
+	f := &frame{}
+	if !keepRaw {
+		if err := decoder.Decode(f); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+
+	if err := decoder.Decode(&f.raw); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(f.raw, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// boundedReader fails once more than limit bytes were read past mark, the
+// offset where the frame being decoded starts
+type boundedReader struct {
+	r     io.Reader
+	read  int64
+	mark  int64
+	limit int64
+}
+
+// Read reads from the underlying reader while the current frame is within
+// the limit
+func (b *boundedReader) Read(p []byte) (int, error) {
+	if b.read-b.mark > b.limit {
+		return 0, ErrInvalidResponse(fmt.Sprintf("frame larger than %d bytes", b.limit))
+	}
+	n, err := b.r.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+// frameResult is a single frame read from a module process
+type frameResult struct {
+	frame *frame
+	err   error
+}
+
+// frameReader decodes the JSON frames a module writes to its stdout. Frames
+// are newline-delimited on the wire, but the decoder streams them without
+// reading whole lines first.
+type frameReader struct {
+	results chan frameResult
+	done    chan struct{}
+	pending *frame
+}
+
+// newFrameReader starts reading frames from r in the background. With
+// keepRaw, frames keep their JSON as sent for strict validation.
+func newFrameReader(r io.Reader, keepRaw bool) *frameReader {
+	fr := &frameReader{
+		results: make(chan frameResult, 16),
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(fr.results)
+		bounded := &boundedReader{r: r, limit: frameBufferLimit}
+		decoder := json.NewDecoder(bounded)
+		for {
+			f, err := decodeFrame(decoder, keepRaw)
+			if err != nil {
+				// The decoder cannot resync after a bad frame
+				if !errors.Is(err, io.EOF) {
+					err = fmt.Errorf("failed to parse frame: %w", err)
+				}
+				select {
+				case fr.results <- frameResult{err: err}:
+				case <-fr.done:
+				}
+				return
+			}
+			bounded.mark = decoder.InputOffset()
+
+			select {
+			case fr.results <- frameResult{frame: f}:
+			case <-fr.done:
+				return
+			}
+		}
+	}()
+
+	return fr
+}
+
+// next returns the next decoded frame, waiting until ctx is done
+func (fr *frameReader) next(ctx context.Context) (*frame, error) {
+	if fr.pending != nil {
+		f := fr.pending
+		fr.pending = nil
+		return f, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res, ok := <-fr.results:
+		if !ok {
+			return nil, io.EOF
+		}
+		return res.frame, res.err
+	}
+}
+
+// unread pushes a frame back so the next call to next returns it again
+func (fr *frameReader) unread(f *frame) {
+	fr.pending = f
+}
+
+// close stops the background reader
+func (fr *frameReader) close() {
+	close(fr.done)
+}
+
+// awaitHello waits briefly for the module's hello frame. Modules that predate
+// the handshake never send one, in which case nil is returned and any frame
+// read in the meantime is kept for the response reader.
+func (fr *frameReader) awaitHello(ctx context.Context) *frame {
+	ctx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+
+	f, err := fr.next(ctx)
+	if err != nil {
+		return nil
+	}
+
+	if f.hello() != nil {
+		return f
+	}
+
+	fr.unread(f)
+	return nil
+}
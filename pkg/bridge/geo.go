@@ -0,0 +1,39 @@
+package bridge
+
+import "strings"
+
+// Optional module arguments that route requests around regional blocks
+const (
+	OptionProxy            = "proxy"
+	OptionGeoBypassCountry = "geo_bypass_country"
+)
+
+// geoMarkers are phrases of errors that sites and yt-dlp report for
+// content blocked in the requesting region
+var geoMarkers = []string{
+	"not available in your country",
+	"not available in your region",
+	"not available in your location",
+	"not made this video available in your country",
+	"blocked in your country",
+	"from your location",
+	"geo restricted",
+	"geo-restricted",
+	"georestricted",
+	"geo restriction",
+	"geo-restriction",
+	"geoblocked",
+	"geo-blocked",
+}
+
+// IsGeoRestricted reports whether a module error says the content is
+// blocked in the region the request came from
+func IsGeoRestricted(message string) bool {
+	message = strings.ToLower(message)
+	for _, marker := range geoMarkers {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
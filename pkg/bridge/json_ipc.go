@@ -1,28 +1,46 @@
 package bridge
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/converso-empire/cli/pkg/profiling"
 	"github.com/converso-empire/cli/pkg/telemetry"
 )
 
 // JSONBridge implements JSON-based IPC communication with Python modules
 type JSONBridge struct {
-	pythonPath string
-	modulesDir string
-	logger     telemetry.Logger
-	mu         sync.RWMutex
-	processes  map[string]*exec.Cmd
+	pythonPath  string
+	modulesDir  string
+	logger      telemetry.Logger
+	compression *compressionSettings
+	strict      bool
+	prompter    Prompter
+	logLevel    string
+	logHandler  LogHandler
+	pool        *processPool
+	mu          sync.RWMutex
+	processes   map[string]*exec.Cmd
+	// protocols are the protocol versions module manifests declare
+	protocols map[string]int
+}
+
+// stallNoticeAfter is how long a module may go without progress before
+// keepalives are reported to the user as a stall notice
+const stallNoticeAfter = 30 * time.Second
+
+// compressionSettings holds the size thresholds above which frames are gzipped
+type compressionSettings struct {
+	requestThreshold  int
+	responseThreshold int
 }
 
 // NewJSONBridge creates a new JSON IPC bridge
@@ -31,86 +49,74 @@ func NewJSONBridge(pythonPath, modulesDir string, logger telemetry.Logger) *JSON
 		pythonPath: pythonPath,
 		modulesDir: modulesDir,
 		logger:     logger,
+		logLevel:   LogLevelInfo,
 		processes:  make(map[string]*exec.Cmd),
+		protocols:  make(map[string]int),
 	}
 }
 
-// Execute executes a command on a Python module
-func (b *JSONBridge) Execute(ctx context.Context, module string, req *ModuleRequest) (*ModuleResponse, error) {
-	if err := req.Validate(); err != nil {
-		return nil, err
-	}
-
-	b.logger.Info("Executing module command",
-		"module", module,
-		"command", req.Command,
-		"timeout", req.Timeout,
-	)
-
-	// Find the module
-	modulePath, err := b.findModule(module)
-	if err != nil {
-		return nil, ErrModuleNotFound(fmt.Sprintf("module %s not found: %v", module, err))
+// SetCompression enables gzip compression of request frames larger than
+// requestThreshold bytes and asks modules to compress response frames larger
+// than responseThreshold bytes. Compression is only used with modules that
+// announce gzip support in their handshake.
+func (b *JSONBridge) SetCompression(requestThreshold, responseThreshold int) {
+	b.compression = &compressionSettings{
+		requestThreshold:  requestThreshold,
+		responseThreshold: responseThreshold,
 	}
+}
 
-	// Launch Python subprocess
-	cmd, stdout, stderr, err := b.launchPythonProcess(modulePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to launch Python process: %w", err)
-	}
+// SetStrict makes frames that break the module contract fail the command
+// with a protocol error instead of being interpreted leniently
+func (b *JSONBridge) SetStrict(strict bool) {
+	b.strict = strict
+}
 
-	// Store process reference
-	processID := fmt.Sprintf("%s-%d", module, time.Now().UnixNano())
+// SetModuleProtocol records the protocol version a module's manifest
+// declares. Only modules declaring one are waited for to send their hello
+// frame, so modules predating the handshake are not held up by it.
+func (b *JSONBridge) SetModuleProtocol(module string, protocol int) {
 	b.mu.Lock()
-	b.processes[processID] = cmd
-	b.mu.Unlock()
-
-	defer func() {
-		b.mu.Lock()
-		delete(b.processes, processID)
-		b.mu.Unlock()
-		cmd.Process.Kill()
-		cmd.Wait()
-	}()
-
-	// Set up context with timeout
-	ctx, cancel := context.WithTimeout(ctx, time.Duration(req.Timeout)*time.Second)
-	defer cancel()
-
-	// Send request to Python module
-	if err := b.sendRequest(stdout, req); err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-
-	// Read response from Python module
-	resp, err := b.readResponse(ctx, stderr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if err := resp.Validate(); err != nil {
-		return nil, err
-	}
+	defer b.mu.Unlock()
+	b.protocols[module] = protocol
+}
 
-	b.logger.Info("Module command completed successfully",
-		"module", module,
-		"command", req.Command,
-		"success", resp.Success,
-	)
+// moduleProtocol returns the protocol version a module declares, 0 if none
+func (b *JSONBridge) moduleProtocol(module string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.protocols[module]
+}
 
-	return resp, nil
+// Execute executes a command on a Python module
+func (b *JSONBridge) Execute(ctx context.Context, module string, req *ModuleRequest) (*ModuleResponse, error) {
+	return b.ExecuteWithProgress(ctx, module, req, nil)
 }
 
 // ExecuteWithProgress executes a command with progress tracking
 func (b *JSONBridge) ExecuteWithProgress(ctx context.Context, module string, req *ModuleRequest, progressChan chan<- *ProgressEvent) (*ModuleResponse, error) {
+	return b.ExecuteWithControl(ctx, module, req, progressChan, nil)
+}
+
+// ExecuteWithControl executes a command with progress tracking, sending the
+// controls received on controls to the module while it runs. Modules
+// predating controls can only be cancelled, by stopping their process.
+// Cancelling ctx cancels the command the same way. A cancelled command
+// fails with ErrModuleCancelled unless it finished anyway.
+func (b *JSONBridge) ExecuteWithControl(ctx context.Context, module string, req *ModuleRequest, progressChan chan<- *ProgressEvent, controls <-chan *Control) (*ModuleResponse, error) {
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
+	if req.RunID == "" {
+		req.RunID = telemetry.RunID()
+	}
+	req.LogLevel = b.logLevel
 
-	b.logger.Info("Executing module command with progress",
+	b.logger.Info("Executing module command",
 		"module", module,
 		"command", req.Command,
 		"timeout", req.Timeout,
+		"idle_timeout", req.IdleTimeout,
 	)
 
 	// Find the module
@@ -119,40 +125,95 @@ func (b *JSONBridge) ExecuteWithProgress(ctx context.Context, module string, req
 		return nil, ErrModuleNotFound(fmt.Sprintf("module %s not found: %v", module, err))
 	}
 
-	// Launch Python subprocess
-	cmd, stdout, stderr, err := b.launchPythonProcess(modulePath)
+	var checker *protocolChecker
+	if b.strict {
+		checker = newProtocolChecker()
+	}
+
+	// Set up context with timeout; time spent paused counts towards it
+	interrupted := ctx.Done()
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(req.Timeout)*time.Second)
+	defer cancel()
+
+	// Launch Python subprocess, or reuse a pooled one; with a handshake,
+	// spawning lasts until the interpreter is up and greets us
+	spawned := profiling.Track(profiling.PhaseBridgeSpawn)
+	proc, err := b.acquire(ctx, module, modulePath, checker, controls != nil)
+	spawned()
 	if err != nil {
 		return nil, fmt.Errorf("failed to launch Python process: %w", err)
 	}
+	succeeded := false
+	defer func() {
+		b.finish(proc, succeeded)
+	}()
 
 	// Store process reference
 	processID := fmt.Sprintf("%s-%d", module, time.Now().UnixNano())
 	b.mu.Lock()
-	b.processes[processID] = cmd
+	b.processes[processID] = proc.cmd
 	b.mu.Unlock()
-
 	defer func() {
 		b.mu.Lock()
 		delete(b.processes, processID)
 		b.mu.Unlock()
-		cmd.Process.Kill()
-		cmd.Wait()
 	}()
 
-	// Set up context with timeout
-	ctx, cancel := context.WithTimeout(ctx, time.Duration(req.Timeout)*time.Second)
-	defer cancel()
+	// Negotiate compression and prompts with modules that support them
+	hello := proc.hello
+	compress := false
+	if hello != nil && b.compression != nil && hello.Supports(EncodingGzip) {
+		req.Compression = &Compression{
+			Encoding:  EncodingGzip,
+			Threshold: b.compression.responseThreshold,
+		}
+		compress = true
+	}
+	// Modules that can prompt or take controls keep reading stdin for the
+	// answers and controls, as do persistent processes for their next
+	// request
+	prompts := hello != nil && b.prompter != nil && hello.Protocol >= PromptProtocol
+	controllable := hello != nil && hello.Protocol >= ControlProtocol
 
 	// Send request to Python module
-	if err := b.sendRequest(stdout, req); err != nil {
+	defer profiling.Track(profiling.PhaseModuleExecution)()
+	if err := b.sendRequest(proc.stdin, req, compress); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	var answers io.Writer
+	if prompts || controllable || proc.persistent {
+		answers = &syncWriter{w: proc.stdin}
+	} else if err := proc.stdin.Close(); err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
+	// Cancelling stops reading the response, after which the process is
+	// stopped. An interrupted command is asked to cancel first, so reading
+	// goes on until the module responds or its grace runs out.
+	deadline, _ := ctx.Deadline()
+	runCtx, kill := context.WithDeadline(context.WithoutCancel(ctx), deadline)
+	defer kill()
+	ctl := &runControl{b: b, module: module, kill: kill, progressChan: progressChan}
+	if controllable {
+		ctl.w = answers
+	}
+	stop := ctl.watch(controls, interrupted)
+	defer stop()
+
 	// Read response with progress tracking
-	resp, err := b.readResponseWithProgress(ctx, stderr, progressChan)
+	idleTimeout := time.Duration(req.IdleTimeout) * time.Second
+	resp, err := b.readResponseWithProgress(runCtx, module, proc.frames, checker, idleTimeout, answers, ctl, progressChan)
 	if err != nil {
+		if ctl.wasCancelled() {
+			return nil, ErrModuleCancelled("module command cancelled")
+		}
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
+	succeeded = true
+	if ctl.wasCancelled() && !resp.Success {
+		b.logger.Info("Module command cancelled", "module", module, "command", req.Command)
+		return nil, ErrModuleCancelled("module command cancelled")
+	}
 
 	if err := resp.Validate(); err != nil {
 		return nil, err
@@ -180,10 +241,15 @@ func (b *JSONBridge) findModule(module string) (string, error) {
 	return modulePath, nil
 }
 
-// launchPythonProcess launches a Python subprocess for a module
-func (b *JSONBridge) launchPythonProcess(modulePath string) (*exec.Cmd, io.WriteCloser, io.ReadCloser, error) {
+// launchPythonProcess launches a Python subprocess for a module,
+// persistent ones serving requests until their stdin closes
+func (b *JSONBridge) launchPythonProcess(modulePath string, persistent bool) (*exec.Cmd, io.WriteCloser, io.ReadCloser, error) {
 	// Construct Python command
-	cmd := exec.Command(b.pythonPath, modulePath)
+	cmd := exec.Command(ModulePython(filepath.Dir(modulePath), b.pythonPath), modulePath)
+	cmd.SysProcAttr = moduleProcAttr()
+	if persistent {
+		cmd.Env = persistentEnv()
+	}
 
 	// Set up pipes for communication
 	stdin, err := cmd.StdinPipe()
@@ -196,26 +262,28 @@ func (b *JSONBridge) launchPythonProcess(modulePath string) (*exec.Cmd, io.Write
 		return nil, nil, nil, err
 	}
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, nil, nil, err
-	}
-
 	// Start the process
 	if err := cmd.Start(); err != nil {
 		return nil, nil, nil, err
 	}
 
-	return cmd, stdin, stderr, nil
+	return cmd, stdin, stdout, nil
 }
 
-// sendRequest sends a request to the Python module
-func (b *JSONBridge) sendRequest(stdin io.WriteCloser, req *ModuleRequest) error {
+// sendRequest sends a request to the Python module, compressing it when
+// negotiated and larger than the request threshold
+func (b *JSONBridge) sendRequest(stdin io.Writer, req *ModuleRequest, compress bool) error {
 	data, err := req.ToJSON()
 	if err != nil {
 		return err
 	}
 
+	if compress && len(data) > b.compression.requestThreshold {
+		if data, err = encodeFrame(data); err != nil {
+			return fmt.Errorf("failed to compress request: %w", err)
+		}
+	}
+
 	// Write request to stdin
 	_, err = stdin.Write(data)
 	if err != nil {
@@ -224,79 +292,149 @@ func (b *JSONBridge) sendRequest(stdin io.WriteCloser, req *ModuleRequest) error
 
 	// Write newline to signal end of request
 	_, err = stdin.Write([]byte("\n"))
-	if err != nil {
-		return err
-	}
-
-	// Close stdin to signal end of input
-	return stdin.Close()
+	return err
 }
 
-// readResponse reads a response from the Python module
-func (b *JSONBridge) readResponse(ctx context.Context, stderr io.ReadCloser) (*ModuleResponse, error) {
-	reader := bufio.NewReader(stderr)
-	
-	select {
-	case <-ctx.Done():
-		return nil, ErrModuleTimeout("module execution timed out")
-	default:
-		// Read response line
-		line, err := reader.ReadString('\n')
+// readResponseWithProgress reads a response with progress tracking. Any frame,
+// including keepalives, resets the idle timer; an idle timeout of zero
+// disables it. Prompts are answered on answers, which is nil when the
+// module cannot prompt. ctl, when set, tracks controls sent meanwhile.
+func (b *JSONBridge) readResponseWithProgress(ctx context.Context, module string, frames *frameReader, checker *protocolChecker, idleTimeout time.Duration, answers io.Writer, ctl *runControl, progressChan chan<- *ProgressEvent) (*ModuleResponse, error) {
+	var lastProgress *ProgressEvent
+	lastProgressAt := time.Now()
+	// Items of a batch run concurrently, so each tracks its own stages
+	var plan *StagePlan
+	stages := make(map[string]*stageTracker)
+
+	for {
+		f, err := b.nextFrame(ctx, frames, idleTimeout)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ErrModuleTimeout("module execution timed out")
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, ErrModuleIdle(fmt.Sprintf("module produced no output for %s", idleTimeout))
+			}
 			if errors.Is(err, io.EOF) {
 				return nil, ErrModuleError("module process ended unexpectedly")
 			}
 			return nil, fmt.Errorf("failed to read response: %w", err)
 		}
+		if err := b.checkFrame(module, checker, f); err != nil {
+			return nil, err
+		}
 
-		// Parse response
-		resp, err := ModuleResponseFromJSON([]byte(strings.TrimSpace(line)))
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse response: %w", err)
+		switch f.Type {
+		case FrameTypeHello, FrameTypePong:
+			// Late handshakes from slow-starting modules carry no payload,
+			// and pongs answer health checks that already gave up
+			continue
+		case FrameTypeKeepalive:
+			keepalive := f.keepalive()
+			stalledFor := time.Since(lastProgressAt)
+			b.logger.Debug("Module keepalive received", "message", keepalive.Message, "stalled_for", stalledFor)
+			if ctl.isPaused() {
+				// Time spent paused is not a stall either
+				lastProgressAt = time.Now()
+				continue
+			}
+			if progressChan != nil && stalledFor >= stallNoticeAfter {
+				progressChan <- stalledProgress(lastProgress, keepalive, stalledFor)
+			}
+			continue
+		case FrameTypeLog:
+			entry := f.logEntry()
+			if _, ok := logLevels[entry.Level]; !ok {
+				entry.Level = LogLevelInfo
+			}
+			b.logModuleEntry(module, entry)
+			continue
+		case FrameTypeStages:
+			declared := f.stagePlan()
+			b.logger.Debug("Module declared stages", "count", len(declared.Stages))
+			plan = declared
+			stages = make(map[string]*stageTracker)
+			continue
+		case FrameTypePrompt:
+			prompt := f.prompt()
+			if answers == nil {
+				// The module reads EOF and falls back on its own
+				b.logger.Warn("Module prompt cannot be answered", "module", module, "prompt", prompt.ID)
+				continue
+			}
+			if err := b.answerPrompt(ctx, module, answers, prompt); err != nil {
+				return nil, fmt.Errorf("failed to answer prompt: %w", err)
+			}
+			// Time spent waiting for the user is not a stall
+			lastProgressAt = time.Now()
+			continue
+		}
+
+		// Valid progress events come first; anything else is the response
+		if progress := f.progress(); progress != nil {
+			progress.Timestamp = time.Now()
+			tracker, ok := stages[progress.Item]
+			if !ok {
+				tracker = newStageTracker(plan)
+				stages[progress.Item] = tracker
+			}
+			tracker.apply(progress)
+			ctl.observe(progress)
+			lastProgress = progress
+			lastProgressAt = progress.Timestamp
+			if progressChan != nil {
+				progressChan <- progress
+			}
+			continue
 		}
 
-		return resp, nil
+		return f.response(), nil
 	}
 }
 
-// readResponseWithProgress reads a response with progress tracking
-func (b *JSONBridge) readResponseWithProgress(ctx context.Context, stderr io.ReadCloser, progressChan chan<- *ProgressEvent) (*ModuleResponse, error) {
-	reader := bufio.NewReader(stderr)
-	
-	for {
-		select {
-		case <-ctx.Done():
-			return nil, ErrModuleTimeout("module execution timed out")
-		default:
-			// Read line
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if errors.Is(err, io.EOF) {
-					return nil, ErrModuleError("module process ended unexpectedly")
-				}
-				return nil, fmt.Errorf("failed to read response: %w", err)
-			}
+// checkFrame validates a frame in strict mode, logging the offending
+// payload so plugin authors can see what the module sent
+func (b *JSONBridge) checkFrame(module string, checker *protocolChecker, f *frame) error {
+	if checker == nil {
+		return nil
+	}
+	if err := checker.check(f); err != nil {
+		b.logger.Error("Module broke the bridge protocol",
+			"module", module,
+			"error", err.Error(),
+			"payload", payloadExcerpt(f.raw),
+		)
+		return ErrProtocol(fmt.Sprintf("module %s broke the bridge protocol: %v", module, err))
+	}
+	return nil
+}
 
-			// Try to parse as progress event first
-			progress, err := ProgressEventFromJSON([]byte(strings.TrimSpace(line)))
-			if err == nil {
-				// Validate progress event
-				if err := progress.Validate(); err == nil {
-					progress.Timestamp = time.Now()
-					progressChan <- progress
-					continue
-				}
-			}
+// nextFrame reads the next frame, giving up after idleTimeout without output
+func (b *JSONBridge) nextFrame(ctx context.Context, frames *frameReader, idleTimeout time.Duration) (*frame, error) {
+	if idleTimeout <= 0 {
+		return frames.next(ctx)
+	}
 
-			// Try to parse as response
-			resp, err := ModuleResponseFromJSON([]byte(strings.TrimSpace(line)))
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse response: %w", err)
-			}
+	idleCtx, cancel := context.WithTimeout(ctx, idleTimeout)
+	defer cancel()
+	return frames.next(idleCtx)
+}
 
-			return resp, nil
-		}
+// stalledProgress builds the progress event reported while a module sends
+// keepalives without making progress
+func stalledProgress(last *ProgressEvent, keepalive *KeepaliveEvent, stalledFor time.Duration) *ProgressEvent {
+	event := &ProgressEvent{Stage: "working"}
+	if last != nil {
+		copied := *last
+		event = &copied
+	}
+
+	if keepalive.Message != "" {
+		event.Message = keepalive.Message
 	}
+	event.StalledFor = stalledFor
+	event.Timestamp = time.Now()
+	return event
 }
 
 // GetPythonPath returns the path to the Python interpreter
@@ -321,6 +459,24 @@ func GetPythonPath() string {
 	return "python3"
 }
 
+// VenvDir returns where a module keeps its virtual environment
+func VenvDir(moduleDir string) string {
+	return filepath.Join(moduleDir, ".venv")
+}
+
+// ModulePython returns the interpreter a module runs with: the one of its
+// virtual environment when it has one, otherwise pythonPath
+func ModulePython(moduleDir, pythonPath string) string {
+	python := filepath.Join(VenvDir(moduleDir), "bin", "python")
+	if runtime.GOOS == "windows" {
+		python = filepath.Join(VenvDir(moduleDir), "Scripts", "python.exe")
+	}
+	if _, err := os.Stat(python); err == nil {
+		return python
+	}
+	return pythonPath
+}
+
 // CheckPythonAvailability checks if Python is available
 func CheckPythonAvailability() error {
 	pythonPath := GetPythonPath()
@@ -0,0 +1,89 @@
+package bridge
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Log levels of module log frames, from the most to the least verbose
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// logLevels ranks the log levels modules may use
+var logLevels = map[string]int{
+	LogLevelDebug: 0,
+	LogLevelInfo:  1,
+	LogLevelWarn:  2,
+	LogLevelError: 3,
+}
+
+// LogEntry is a log line a module emits while it runs, kept apart from its
+// progress and final response
+type LogEntry struct {
+	Type    string                 `json:"type"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Validate validates a log entry
+func (e *LogEntry) Validate() error {
+	if _, ok := logLevels[e.Level]; !ok {
+		return fmt.Errorf("unknown log level %q", e.Level)
+	}
+	if e.Message == "" {
+		return fmt.Errorf("log entry has no message")
+	}
+	return nil
+}
+
+// LogHandler receives the log lines of modules besides the logger, such as
+// to show them to the user
+type LogHandler func(module string, entry *LogEntry)
+
+// SetLogLevel sets the lowest level of log lines modules send, debug or
+// info by default
+func (b *JSONBridge) SetLogLevel(level string) {
+	if _, ok := logLevels[level]; ok {
+		b.logLevel = level
+	}
+}
+
+// SetLogHandler passes module log lines to handler as well as the logger;
+// nil only logs them
+func (b *JSONBridge) SetLogHandler(handler LogHandler) {
+	b.logHandler = handler
+}
+
+// logModuleEntry routes a module's log line to the logger at its level and
+// to the log handler, if any
+func (b *JSONBridge) logModuleEntry(module string, entry *LogEntry) {
+	fields := []interface{}{"module", module}
+	keys := make([]string, 0, len(entry.Fields))
+	for key := range entry.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fields = append(fields, key, entry.Fields[key])
+	}
+
+	switch entry.Level {
+	case LogLevelDebug:
+		b.logger.Debug(entry.Message, fields...)
+	case LogLevelWarn:
+		b.logger.Warn(entry.Message, fields...)
+	case LogLevelError:
+		b.logger.Error(entry.Message, fields...)
+	default:
+		b.logger.Info(entry.Message, fields...)
+	}
+
+	if b.logHandler != nil {
+		b.logHandler(module, entry)
+	}
+}
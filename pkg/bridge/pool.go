@@ -0,0 +1,325 @@
+package bridge
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// PersistentEnv asks a module process to keep serving requests until its
+// stdin closes instead of exiting after the first response
+const PersistentEnv = "CONVERSO_PERSISTENT"
+
+// healthCheckTimeout bounds how long an idle pooled process may take to
+// answer a ping before it is replaced
+const healthCheckTimeout = 5 * time.Second
+
+// stopGrace is how long a pooled process may take to exit once its stdin
+// closes before it is killed
+const stopGrace = 2 * time.Second
+
+// moduleProcess is a running module interpreter
+type moduleProcess struct {
+	module string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	frames *frameReader
+	hello  *HelloFrame
+	// persistent processes serve further requests after a response and
+	// belong to the pool
+	persistent bool
+	lastUsed   time.Time
+}
+
+// ping checks that an idle process still serves requests. Frames left over
+// from its previous command are dropped on the way.
+func (p *moduleProcess) ping(ctx context.Context) error {
+	if _, err := io.WriteString(p.stdin, `{"type":"`+FrameTypePing+`"}`+"\n"); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	for {
+		f, err := p.frames.next(ctx)
+		if err != nil {
+			return err
+		}
+		if f.Type == FrameTypePong {
+			return nil
+		}
+	}
+}
+
+// stop ends the process. Graceful stops close stdin and give the module
+// stopGrace to exit on its own.
+func (p *moduleProcess) stop(graceful bool) {
+	p.frames.close()
+	p.stdin.Close()
+	if !graceful {
+		killModule(p.cmd)
+		p.cmd.Wait()
+		return
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		p.cmd.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+	case <-time.After(stopGrace):
+		killModule(p.cmd)
+		<-exited
+	}
+}
+
+// processPool keeps persistent module processes between commands, up to
+// max per module
+type processPool struct {
+	max         int
+	idleTimeout time.Duration
+	mu          sync.Mutex
+	idle        map[string][]*moduleProcess
+	// running counts each module's pooled processes, busy or idle
+	running map[string]int
+	closed  bool
+	done    chan struct{}
+}
+
+// SetPool keeps module processes running between commands, so commands
+// after the first skip the interpreter start. Each module runs up to
+// maxProcesses persistent processes; commands beyond that, and modules
+// that cannot run persistently, get a process of their own as usual. Idle
+// processes exit after idleTimeout.
+func (b *JSONBridge) SetPool(maxProcesses int, idleTimeout time.Duration) {
+	if maxProcesses < 1 {
+		maxProcesses = 1
+	}
+	pool := &processPool{
+		max:         maxProcesses,
+		idleTimeout: idleTimeout,
+		idle:        make(map[string][]*moduleProcess),
+		running:     make(map[string]int),
+		done:        make(chan struct{}),
+	}
+	b.pool = pool
+	if idleTimeout > 0 {
+		go pool.reap(b)
+	}
+}
+
+// Close stops the pooled module processes. Processes still running a
+// command stop when it finishes.
+func (b *JSONBridge) Close() {
+	pool := b.pool
+	if pool == nil {
+		return
+	}
+
+	pool.mu.Lock()
+	if pool.closed {
+		pool.mu.Unlock()
+		return
+	}
+	pool.closed = true
+	close(pool.done)
+	var idle []*moduleProcess
+	for module, processes := range pool.idle {
+		idle = append(idle, processes...)
+		pool.running[module] -= len(processes)
+	}
+	pool.idle = make(map[string][]*moduleProcess)
+	pool.mu.Unlock()
+
+	for _, p := range idle {
+		p.stop(true)
+	}
+}
+
+// acquire returns a healthy idle process of the module, or launches one.
+// Unhealthy processes are replaced; without a pool, or with all of the
+// module's pooled processes busy, the process serves this request only.
+// With handshake set, new processes are awaited for their hello.
+func (b *JSONBridge) acquire(ctx context.Context, module, modulePath string, checker *protocolChecker, handshake bool) (*moduleProcess, error) {
+	pool := b.pool
+	if pool == nil {
+		return b.launch(ctx, module, modulePath, checker, handshake, false)
+	}
+
+	for p := pool.take(module); p != nil; p = pool.take(module) {
+		err := p.ping(ctx)
+		if err == nil {
+			b.logger.Debug("Reusing module process", "module", module, "pid", p.cmd.Process.Pid)
+			return p, nil
+		}
+		b.logger.Warn("Restarting unresponsive module process", "module", module, "pid", p.cmd.Process.Pid, "error", err)
+		pool.release(module)
+		p.stop(false)
+	}
+
+	if !pool.reserve(module) {
+		return b.launch(ctx, module, modulePath, checker, handshake, false)
+	}
+	p, err := b.launch(ctx, module, modulePath, checker, handshake, true)
+	if err != nil {
+		pool.release(module)
+		return nil, err
+	}
+	if !p.persistent {
+		// The module predates persistent processes
+		pool.release(module)
+	}
+	return p, nil
+}
+
+// finish returns a process to the pool after a successful command, and
+// stops it otherwise
+func (b *JSONBridge) finish(p *moduleProcess, ok bool) {
+	if !p.persistent {
+		p.stop(false)
+		return
+	}
+
+	p.lastUsed = time.Now()
+	if ok && b.pool.put(p) {
+		return
+	}
+	// A failed command may still be running in the module
+	b.pool.release(p.module)
+	p.stop(false)
+}
+
+// launch starts a module process and waits for its handshake when
+// anything depends on it, or handshake is set, and the module declares a
+// protocol. Persistent processes are asked to keep serving requests.
+func (b *JSONBridge) launch(ctx context.Context, module, modulePath string, checker *protocolChecker, handshake, persistent bool) (*moduleProcess, error) {
+	cmd, stdin, stdout, err := b.launchPythonProcess(modulePath, persistent)
+	if err != nil {
+		return nil, err
+	}
+	p := &moduleProcess{
+		module: module,
+		cmd:    cmd,
+		stdin:  stdin,
+		frames: newFrameReader(stdout, b.strict),
+	}
+
+	if b.moduleProtocol(module) > 0 && (handshake || persistent || b.compression != nil || b.prompter != nil) {
+		if f := p.frames.awaitHello(ctx); f != nil {
+			if err := b.checkFrame(module, checker, f); err != nil {
+				p.stop(false)
+				return nil, err
+			}
+			p.hello = f.hello()
+		}
+	}
+	if p.hello != nil {
+		b.logger.Debug("Module handshake received",
+			"module", module,
+			"protocol", p.hello.Protocol,
+			"encodings", p.hello.Encodings,
+			"persistent", p.hello.Persistent,
+		)
+	}
+	p.persistent = persistent && p.hello != nil && p.hello.Persistent
+	return p, nil
+}
+
+// take removes an idle process of the module from the pool
+func (pool *processPool) take(module string) *moduleProcess {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	idle := pool.idle[module]
+	if len(idle) == 0 {
+		return nil
+	}
+	// The most recently used process is the least likely to have gone stale
+	p := idle[len(idle)-1]
+	pool.idle[module] = idle[:len(idle)-1]
+	return p
+}
+
+// put makes a process available again, unless the pool was closed
+func (pool *processPool) put(p *moduleProcess) bool {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.closed {
+		return false
+	}
+	pool.idle[p.module] = append(pool.idle[p.module], p)
+	return true
+}
+
+// reserve counts a new pooled process of the module, if it may have one
+func (pool *processPool) reserve(module string) bool {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.closed || pool.running[module] >= pool.max {
+		return false
+	}
+	pool.running[module]++
+	return true
+}
+
+// release stops counting a pooled process of the module
+func (pool *processPool) release(module string) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.running[module] > 0 {
+		pool.running[module]--
+	}
+}
+
+// reap stops processes idle for longer than the idle timeout until the pool
+// is closed
+func (pool *processPool) reap(b *JSONBridge) {
+	interval := pool.idleTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pool.done:
+			return
+		case <-ticker.C:
+		}
+
+		var expired []*moduleProcess
+		pool.mu.Lock()
+		for module, idle := range pool.idle {
+			kept := idle[:0]
+			for _, p := range idle {
+				if time.Since(p.lastUsed) >= pool.idleTimeout {
+					expired = append(expired, p)
+					pool.running[module]--
+					continue
+				}
+				kept = append(kept, p)
+			}
+			pool.idle[module] = kept
+		}
+		pool.mu.Unlock()
+
+		for _, p := range expired {
+			b.logger.Debug("Stopping idle module process", "module", p.module, "pid", p.cmd.Process.Pid)
+			p.stop(true)
+		}
+	}
+}
+
+// persistentEnv returns the environment of a persistent module process
+func persistentEnv() []string {
+	return append(os.Environ(), PersistentEnv+"=1")
+}
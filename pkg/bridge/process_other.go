@@ -0,0 +1,23 @@
+//go:build !windows
+
+package bridge
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// moduleProcAttr starts a module in a process group of its own, so Ctrl-C
+// at the terminal reaches it only through the CLI, which asks it to cancel
+// and clean up before stopping it
+func moduleProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killModule kills a module process along with the processes it started,
+// such as FFmpeg, which are in its process group
+func killModule(cmd *exec.Cmd) {
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		cmd.Process.Kill()
+	}
+}
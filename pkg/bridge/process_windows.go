@@ -0,0 +1,23 @@
+//go:build windows
+
+package bridge
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// createNewProcessGroup keeps console Ctrl-C events from reaching a process
+const createNewProcessGroup = 0x00000200
+
+// moduleProcAttr starts a module in a process group of its own, so Ctrl-C
+// at the console reaches it only through the CLI, which asks it to cancel
+// and clean up before stopping it
+func moduleProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: createNewProcessGroup}
+}
+
+// killModule kills a module process
+func killModule(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
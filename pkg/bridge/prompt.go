@@ -0,0 +1,168 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PromptProtocol is the first protocol version whose modules may prompt:
+// they read the request as a single line and keep reading stdin for the
+// answers
+const PromptProtocol = 2
+
+// defaultPromptTimeout bounds how long a prompt waits for the user when the
+// module sets no timeout
+const defaultPromptTimeout = 5 * time.Minute
+
+// ErrNotInteractive is returned by prompters that cannot ask the user
+var ErrNotInteractive = errors.New("not running interactively")
+
+// Prompt is a question a module asks the user mid-run, such as which
+// account to use or whether to go ahead
+type Prompt struct {
+	Type    string   `json:"type"`
+	ID      string   `json:"id"`
+	Message string   `json:"message"`
+	Choices []string `json:"choices,omitempty"`
+	Default string   `json:"default,omitempty"`
+	// Secret answers are not echoed
+	Secret bool `json:"secret,omitempty"`
+	// Timeout is how long to wait for an answer in seconds; zero waits
+	// defaultPromptTimeout
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// Validate validates a prompt
+func (p *Prompt) Validate() error {
+	if p.ID == "" {
+		return fmt.Errorf("prompt has no id")
+	}
+	if p.Message == "" {
+		return fmt.Errorf("prompt %q has no message", p.ID)
+	}
+	if p.Timeout < 0 {
+		return fmt.Errorf("prompt %q has a negative timeout", p.ID)
+	}
+	if p.Default != "" && len(p.Choices) > 0 && !p.hasChoice(p.Default) {
+		return fmt.Errorf("default of prompt %q is not one of its choices", p.ID)
+	}
+	return nil
+}
+
+// Choose resolves what the user typed into an answer: empty input picks
+// the default, and choices may be given by number or name
+func (p *Prompt) Choose(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		if p.Default == "" && len(p.Choices) > 0 {
+			return "", fmt.Errorf("choose one of %s", strings.Join(p.Choices, ", "))
+		}
+		return p.Default, nil
+	}
+	if len(p.Choices) == 0 {
+		return input, nil
+	}
+
+	if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= len(p.Choices) {
+		return p.Choices[n-1], nil
+	}
+	for _, choice := range p.Choices {
+		if strings.EqualFold(choice, input) {
+			return choice, nil
+		}
+	}
+	return "", fmt.Errorf("%q is not one of %s", input, strings.Join(p.Choices, ", "))
+}
+
+// hasChoice reports whether value is one of the prompt's choices
+func (p *Prompt) hasChoice(value string) bool {
+	for _, choice := range p.Choices {
+		if choice == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Answer is the reply to a prompt, written to the module's stdin as a
+// single line. Error is set instead of Value when nobody answered.
+type Answer struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Value string `json:"value,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Prompter asks the user a module's prompt and returns the answer. It
+// returns ErrNotInteractive when it cannot ask, and gives up when ctx is
+// done.
+type Prompter func(ctx context.Context, module string, prompt *Prompt) (string, error)
+
+// SetPrompter lets modules ask the user questions mid-run. Without a
+// prompter, or when it fails or times out, prompts get their default
+// answer, or an error answer when they have none.
+func (b *JSONBridge) SetPrompter(prompter Prompter) {
+	b.prompter = prompter
+}
+
+// answerPrompt asks the user a module's prompt and writes the answer to
+// the module
+func (b *JSONBridge) answerPrompt(ctx context.Context, module string, w io.Writer, prompt *Prompt) error {
+	answer := &Answer{Type: FrameTypeAnswer, ID: prompt.ID}
+	value, err := b.ask(ctx, module, prompt)
+	switch {
+	case err == nil:
+		answer.Value = value
+	case ctx.Err() != nil:
+		// The run itself timed out; the response reader reports it
+		return nil
+	case prompt.Default != "":
+		b.logger.Info("Module prompt answered with its default", "module", module, "prompt", prompt.ID, "reason", err.Error())
+		answer.Value = prompt.Default
+	default:
+		b.logger.Warn("Module prompt not answered", "module", module, "prompt", prompt.ID, "error", err.Error())
+		answer.Error = err.Error()
+	}
+
+	data, err := json.Marshal(answer)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ask asks the prompter within the prompt's timeout
+func (b *JSONBridge) ask(ctx context.Context, module string, prompt *Prompt) (string, error) {
+	if err := prompt.Validate(); err != nil {
+		return "", err
+	}
+	if b.prompter == nil {
+		return "", ErrNotInteractive
+	}
+
+	timeout := defaultPromptTimeout
+	if prompt.Timeout > 0 {
+		timeout = time.Duration(prompt.Timeout) * time.Second
+	}
+	promptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	value, err := b.prompter(promptCtx, module, prompt)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			return "", fmt.Errorf("no answer within %s", timeout)
+		}
+		return "", err
+	}
+	if len(prompt.Choices) > 0 && !prompt.hasChoice(value) {
+		return "", fmt.Errorf("%q is not one of %s", value, strings.Join(prompt.Choices, ", "))
+	}
+	return value, nil
+}
@@ -0,0 +1,122 @@
+package bridge
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// defaultStageWeight is used for declared stages that omit a weight
+const defaultStageWeight = 1.0
+
+// defaultStages are the usual stages of yt-dlp and FFmpeg work in the order
+// they run, weighted for modules that declare no stages of their own.
+// Downloading takes most of the time.
+var defaultStages = []StageWeight{
+	{Name: "fetching", Weight: 1},
+	{Name: "analyzing", Weight: 1},
+	{Name: "preparing", Weight: 1},
+	{Name: "downloading", Weight: 7},
+	{Name: "merging", Weight: 1},
+	{Name: "processing", Weight: 1},
+	{Name: "converting", Weight: 2},
+}
+
+// StageWeight declares a stage of a multi-stage operation and its share of
+// the overall work
+type StageWeight struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight,omitempty"`
+}
+
+// StagePlan is emitted by a module before a multi-stage operation starts
+type StagePlan struct {
+	Type   string        `json:"type"`
+	Stages []StageWeight `json:"stages"`
+}
+
+// StagePlanFromJSON deserializes JSON into a StagePlan
+func StagePlanFromJSON(data []byte) (*StagePlan, error) {
+	var plan StagePlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// stageTracker turns per-stage progress into one overall percentage
+type stageTracker struct {
+	stages  []StageWeight
+	index   map[string]int
+	total   float64
+	overall float64
+	// defaults is set without a declared plan, to plan defaultStages from
+	// the first one the module reports
+	defaults bool
+}
+
+// newStageTracker creates a tracker for the given plan, or for
+// defaultStages without one. Stages without a weight count as
+// defaultStageWeight.
+func newStageTracker(plan *StagePlan) *stageTracker {
+	t := &stageTracker{index: make(map[string]int)}
+	if plan == nil {
+		t.defaults = true
+		return t
+	}
+	t.add(plan.Stages)
+	return t
+}
+
+// add appends stages to the plan
+func (t *stageTracker) add(stages []StageWeight) {
+	for _, stage := range stages {
+		if stage.Name == "" {
+			continue
+		}
+		if stage.Weight <= 0 {
+			stage.Weight = defaultStageWeight
+		}
+		t.index[stage.Name] = len(t.stages)
+		t.stages = append(t.stages, stage)
+		t.total += stage.Weight
+	}
+}
+
+// apply fills in the overall percentage and stage position of a progress
+// event. Without a declared plan, defaultStages are planned from the first
+// stage reported, and the stage percentage is the overall percentage for
+// modules whose stages are not among them. Stages missing from the plan
+// keep the last overall value so the bar never moves backwards.
+func (t *stageTracker) apply(progress *ProgressEvent) {
+	if len(t.stages) == 0 && t.defaults {
+		for i, stage := range defaultStages {
+			if stage.Name == progress.Stage {
+				t.add(defaultStages[i:])
+				break
+			}
+		}
+	}
+	if len(t.stages) == 0 {
+		progress.Overall = progress.Percentage
+		return
+	}
+
+	i, ok := t.index[progress.Stage]
+	if !ok {
+		progress.Overall = t.overall
+		return
+	}
+
+	done := 0.0
+	for _, stage := range t.stages[:i] {
+		done += stage.Weight
+	}
+	done += t.stages[i].Weight * progress.Percentage / 100
+
+	t.overall = math.Max(t.overall, math.Min(100, done/t.total*100))
+	progress.Overall = t.overall
+	// Only declared plans know which stages will run
+	if !t.defaults {
+		progress.StageIndex, progress.StageCount = i+1, len(t.stages)
+	}
+}
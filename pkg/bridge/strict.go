@@ -0,0 +1,189 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// maxLoggedPayload bounds the part of an offending frame that is logged
+const maxLoggedPayload = 2048
+
+// progressFrame is a typed progress event as modules send it
+type progressFrame struct {
+	Type string `json:"type"`
+	ProgressEvent
+}
+
+// protocolChecker enforces the module contract in strict mode: frames may
+// only carry known fields, progress must be valid and move forward, and
+// responses must carry their required fields
+type protocolChecker struct {
+	plan *StagePlan
+	// last is the latest progress per item and stage, stageIndex the
+	// position of each item's current stage in the plan
+	last       map[string]ProgressEvent
+	stageIndex map[string]int
+}
+
+// newProtocolChecker creates a checker for a single module execution
+func newProtocolChecker() *protocolChecker {
+	return &protocolChecker{
+		last:       make(map[string]ProgressEvent),
+		stageIndex: make(map[string]int),
+	}
+}
+
+// check validates a frame read from the module
+func (c *protocolChecker) check(f *frame) error {
+	switch f.Type {
+	case FrameTypeHello:
+		return strictDecode(f.raw, &HelloFrame{})
+	case FrameTypeKeepalive:
+		return strictDecode(f.raw, &KeepaliveEvent{})
+	case FrameTypePong:
+		return strictDecode(f.raw, &struct {
+			Type string `json:"type"`
+		}{})
+	case FrameTypeStages:
+		return c.checkStages(f)
+	case FrameTypePrompt:
+		if err := strictDecode(f.raw, &Prompt{}); err != nil {
+			return err
+		}
+		return f.prompt().Validate()
+	case FrameTypeLog:
+		if err := strictDecode(f.raw, &LogEntry{}); err != nil {
+			return err
+		}
+		return f.logEntry().Validate()
+	case FrameTypeProgress:
+		return c.checkProgress(f)
+	case "":
+		// Untyped frames with a stage are progress from older modules
+		if f.Stage != "" {
+			return c.checkProgress(f)
+		}
+		return checkResponse(f)
+	}
+	return fmt.Errorf("unknown frame type %q", f.Type)
+}
+
+// checkStages validates a stage plan and restarts progress tracking
+func (c *protocolChecker) checkStages(f *frame) error {
+	if err := strictDecode(f.raw, &StagePlan{}); err != nil {
+		return err
+	}
+	if len(f.Stages) == 0 {
+		return fmt.Errorf("stage plan declares no stages")
+	}
+
+	seen := make(map[string]bool, len(f.Stages))
+	for _, stage := range f.Stages {
+		if stage.Name == "" {
+			return fmt.Errorf("stage plan has a stage without a name")
+		}
+		if seen[stage.Name] {
+			return fmt.Errorf("stage plan declares %q twice", stage.Name)
+		}
+		if stage.Weight < 0 {
+			return fmt.Errorf("stage %q has a negative weight", stage.Name)
+		}
+		seen[stage.Name] = true
+	}
+
+	c.plan = f.stagePlan()
+	c.last = make(map[string]ProgressEvent)
+	c.stageIndex = make(map[string]int)
+	return nil
+}
+
+// checkProgress validates a progress event and that it follows the
+// previous events of its item
+func (c *protocolChecker) checkProgress(f *frame) error {
+	if err := strictDecode(f.raw, &progressFrame{}); err != nil {
+		return err
+	}
+	progress := f.ProgressEvent
+	if err := progress.Validate(); err != nil {
+		return fmt.Errorf("invalid progress: %w", err)
+	}
+
+	// Stages of a declared plan run in order
+	if c.plan != nil {
+		index := -1
+		for i, stage := range c.plan.Stages {
+			if stage.Name == progress.Stage {
+				index = i
+			}
+		}
+		if index < 0 {
+			return fmt.Errorf("progress for stage %q, which the stage plan does not declare", progress.Stage)
+		}
+		if previous, ok := c.stageIndex[progress.Item]; ok && index < previous {
+			return fmt.Errorf("progress returned to stage %q after %q", progress.Stage, c.plan.Stages[previous].Name)
+		}
+		c.stageIndex[progress.Item] = index
+	}
+
+	// Within a stage, progress only moves forward
+	key := progress.Item + "\x00" + progress.Stage
+	if last, ok := c.last[key]; ok {
+		if progress.Current < last.Current || progress.Percentage < last.Percentage {
+			return fmt.Errorf("progress of stage %q went backwards from %d/%d to %d/%d",
+				progress.Stage, last.Current, last.Total, progress.Current, progress.Total)
+		}
+	}
+	c.last[key] = progress
+	return nil
+}
+
+// checkResponse validates the final response, including the fields a
+// lenient decode would silently default
+func checkResponse(f *frame) error {
+	if err := strictDecode(f.raw, &ModuleResponse{}); err != nil {
+		return err
+	}
+
+	var fields struct {
+		Success *bool                    `json:"success"`
+		Data    json.RawMessage          `json:"data"`
+		Items   []map[string]interface{} `json:"items"`
+	}
+	if err := json.Unmarshal(f.raw, &fields); err != nil {
+		return err
+	}
+	if fields.Success == nil {
+		return fmt.Errorf("response is missing %q", "success")
+	}
+	if len(fields.Data) == 0 || string(fields.Data) == "null" {
+		return fmt.Errorf("response is missing %q", "data")
+	}
+	for i, item := range fields.Items {
+		for _, key := range []string{"id", "success"} {
+			if _, ok := item[key]; !ok {
+				return fmt.Errorf("item %d is missing %q", i, key)
+			}
+		}
+	}
+
+	return f.ModuleResponse.Validate()
+}
+
+// strictDecode decodes raw into v, rejecting fields v does not declare
+func strictDecode(raw []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid frame: %w", err)
+	}
+	return nil
+}
+
+// payloadExcerpt returns the start of a frame for logging
+func payloadExcerpt(raw []byte) string {
+	if len(raw) > maxLoggedPayload {
+		return string(raw[:maxLoggedPayload]) + "…"
+	}
+	return string(raw)
+}
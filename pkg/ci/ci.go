@@ -0,0 +1,261 @@
+// Package ci generates CI workflow snippets that run converso headless:
+// credentials of a service account come from CI secrets, 'converso doctor'
+// checks the setup, and the snippet runs a pipeline, the worker or both.
+package ci
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Platforms are the CI systems snippets are generated for
+var Platforms = []string{"github", "gitlab", "shell"}
+
+// InstallURL is the install script the snippets fetch converso with
+const InstallURL = "https://cli.conversoempire.world/install.sh"
+
+// OutputDir is where downloads go in CI, relative to the checkout, and
+// what is kept as an artifact
+const OutputDir = "converso-output"
+
+// SummaryFile is the pipeline summary kept as an artifact
+const SummaryFile = "converso-summary.json"
+
+// Var is an environment variable set in the snippet
+type Var struct {
+	Name  string
+	Value string
+}
+
+// Options describes the snippet
+type Options struct {
+	Platform string
+	// Pipeline is the pipeline file to run, relative to the repository
+	Pipeline string
+	// Worker adds a scheduled job running the worker for WorkerMinutes
+	Worker        bool
+	WorkerMinutes int
+	// Env are plain settings, such as endpoints that differ from the
+	// defaults
+	Env []Var
+	// Secrets are the variables taken from CI secrets: the service
+	// account's tokens and module secrets
+	Secrets []string
+}
+
+// DefaultPath returns where a platform expects the snippet
+func DefaultPath(platform string) string {
+	switch platform {
+	case "github":
+		return ".github/workflows/converso.yml"
+	case "gitlab":
+		return ".gitlab-ci.yml"
+	default:
+		return "converso-ci.sh"
+	}
+}
+
+// Generate renders the snippet for opts.Platform
+func Generate(opts Options) ([]byte, error) {
+	text, ok := templates[opts.Platform]
+	if !ok {
+		return nil, fmt.Errorf("unknown CI platform %q (available: %s)", opts.Platform, strings.Join(Platforms, ", "))
+	}
+	if opts.Pipeline == "" && !opts.Worker {
+		return nil, fmt.Errorf("nothing to run: give a pipeline file or add the worker")
+	}
+	if opts.WorkerMinutes <= 0 {
+		opts.WorkerMinutes = 50
+	}
+	env := append([]Var(nil), opts.Env...)
+	sort.Slice(env, func(i, j int) bool { return env[i].Name < env[j].Name })
+	opts.Env = env
+
+	tmpl, err := template.New(opts.Platform).Delims("[[", "]]").Funcs(template.FuncMap{
+		"yaml": strconv.Quote,
+		"sh":   shellQuote,
+	}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct {
+		Options
+		InstallURL, OutputDir, SummaryFile string
+	}{opts, InstallURL, OutputDir, SummaryFile}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// templates are the snippets by platform, with [[ ]] delimiters so the
+// platforms' own ${{ }} and $VAR syntax passes through
+var templates = map[string]string{
+	"github": `# Generated by 'converso init ci github'
+#
+# Add these repository secrets with the service account's credentials:
+[[- range .Secrets]]
+#   [[.]]
+[[- end]]
+name: Converso
+
+on:
+  workflow_dispatch:
+[[- if .Pipeline]]
+  push:
+    paths:
+      - [[yaml .Pipeline]]
+[[- end]]
+[[- if .Worker]]
+  schedule:
+    - cron: "0 * * * *"
+[[- end]]
+
+env:
+  CONVERSO_HEADLESS: "true"
+  CONVERSO_OUTPUT_DIR: ${{ github.workspace }}/[[.OutputDir]]
+[[- range .Env]]
+  [[.Name]]: [[yaml .Value]]
+[[- end]]
+[[- range .Secrets]]
+  [[.]]: ${{ secrets.[[.]] }}
+[[- end]]
+
+jobs:
+[[- if .Pipeline]]
+  pipeline:
+    if: github.event_name != 'schedule'
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-python@v5
+        with:
+          python-version: "3.11"
+      - name: Install converso
+        run: |
+          sudo apt-get update && sudo apt-get install -y ffmpeg
+          curl -sSfL [[.InstallURL]] | bash
+      - name: Check credentials and modules
+        run: converso doctor
+      - name: Run pipeline
+        run: converso run -f [[.Pipeline]] --summary-file [[.SummaryFile]]
+      - name: Keep results
+        if: always()
+        uses: actions/upload-artifact@v4
+        with:
+          name: converso
+          path: |
+            [[.SummaryFile]]
+            [[.OutputDir]]/
+[[- end]]
+[[- if .Worker]]
+  worker:
+    if: github.event_name != 'push'
+    runs-on: ubuntu-latest
+    timeout-minutes: [[.WorkerMinutes]]
+    steps:
+      - uses: actions/setup-python@v5
+        with:
+          python-version: "3.11"
+      - name: Install converso
+        run: |
+          sudo apt-get update && sudo apt-get install -y ffmpeg
+          curl -sSfL [[.InstallURL]] | bash
+      - name: Check credentials and modules
+        run: converso doctor
+      # SIGTERM lets the running job finish before the worker stops
+      - name: Run worker
+        run: timeout --signal=TERM --kill-after=5m [[.WorkerMinutes]]m converso worker start || [ $? -eq 124 ]
+[[- end]]
+`,
+
+	"gitlab": `# Generated by 'converso init ci gitlab'
+#
+# Add these masked CI/CD variables with the service account's credentials:
+[[- range .Secrets]]
+#   [[.]]
+[[- end]]
+variables:
+  CONVERSO_HEADLESS: "true"
+  CONVERSO_OUTPUT_DIR: $CI_PROJECT_DIR/[[.OutputDir]]
+[[- range .Env]]
+  [[.Name]]: [[yaml .Value]]
+[[- end]]
+
+.converso:
+  image: python:3.11
+  before_script:
+    - apt-get update && apt-get install -y ffmpeg
+    - curl -sSfL [[.InstallURL]] | bash
+    - converso doctor
+[[- if .Pipeline]]
+
+converso-pipeline:
+  extends: .converso
+  rules:
+    - if: $CI_PIPELINE_SOURCE != "schedule"
+  script:
+    - converso run -f [[.Pipeline]] --summary-file [[.SummaryFile]]
+  artifacts:
+    when: always
+    paths:
+      - [[.SummaryFile]]
+      - [[.OutputDir]]/
+[[- end]]
+[[- if .Worker]]
+
+# Run from a pipeline schedule; SIGTERM lets the running job finish
+converso-worker:
+  extends: .converso
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "schedule"
+  timeout: [[.WorkerMinutes]]m
+  script:
+    - timeout --signal=TERM --kill-after=5m [[.WorkerMinutes]]m converso worker start || [ $? -eq 124 ]
+[[- end]]
+`,
+
+	"shell": `#!/usr/bin/env bash
+# Generated by 'converso init ci shell'
+#
+# Runs converso headless with the service account's credentials from the
+# environment (or files named by the same variables with a _FILE suffix):
+[[- range .Secrets]]
+#   [[.]]
+[[- end]]
+set -euo pipefail
+
+if [ -z "${CONVERSO_ACCESS_TOKEN:-}" ] && [ -z "${CONVERSO_ACCESS_TOKEN_FILE:-}" ]; then
+  echo "Set CONVERSO_ACCESS_TOKEN or CONVERSO_ACCESS_TOKEN_FILE" >&2
+  exit 1
+fi
+
+export CONVERSO_HEADLESS=true
+export CONVERSO_OUTPUT_DIR="${CONVERSO_OUTPUT_DIR:-$PWD/[[.OutputDir]]}"
+[[- range .Env]]
+export [[.Name]]=[[sh .Value]]
+[[- end]]
+
+command -v converso >/dev/null || curl -sSfL [[.InstallURL]] | bash
+converso doctor
+[[- if .Pipeline]]
+
+converso run -f [[sh .Pipeline]] --summary-file [[.SummaryFile]]
+[[- end]]
+[[- if .Worker]]
+
+# SIGTERM lets the running job finish before the worker stops
+timeout --signal=TERM --kill-after=5m [[.WorkerMinutes]]m converso worker start || [ $? -eq 124 ]
+[[- end]]
+`,
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// CleanupConfig holds the retention rules for downloaded files
+type CleanupConfig struct {
+	// Interval runs the rules in the worker; zero leaves them to
+	// 'converso library cleanup --apply'
+	Interval time.Duration `mapstructure:"interval"`
+	Rules    []CleanupRule `mapstructure:"rules"`
+}
+
+// CleanupRule deletes downloads in a directory that are beyond the newest
+// KeepLast of their channel, older than OlderThan, or watched. Zero values
+// disable a condition.
+type CleanupRule struct {
+	Dir       string        `mapstructure:"dir"`
+	KeepLast  int           `mapstructure:"keep_last"`
+	OlderThan time.Duration `mapstructure:"older_than"`
+	Watched   bool          `mapstructure:"watched"`
+}
+
+// Validate checks that the rule names a directory and deletes something
+func (r CleanupRule) Validate() error {
+	switch {
+	case r.Dir == "":
+		return fmt.Errorf("dir is required")
+	case r.KeepLast < 0:
+		return fmt.Errorf("keep_last must not be negative")
+	case r.OlderThan < 0:
+		return fmt.Errorf("older_than must not be negative")
+	case r.KeepLast == 0 && r.OlderThan == 0 && !r.Watched:
+		return fmt.Errorf("set keep_last, older_than or watched")
+	}
+	return nil
+}
+
+// Validate checks every rule
+func (c CleanupConfig) Validate() error {
+	if c.Interval < 0 {
+		return fmt.Errorf("interval must not be negative")
+	}
+	for i, rule := range c.Rules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
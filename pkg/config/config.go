@@ -4,23 +4,348 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/converso-empire/cli/pkg/interpolate"
 	"github.com/spf13/viper"
 )
 
 // Config represents the application configuration
 type Config struct {
+	ConfigVersion int `mapstructure:"config_version"`
 	Debug       bool   `mapstructure:"debug"`
+	Headless    bool   `mapstructure:"headless"`
+	DryRunAPI   bool   `mapstructure:"dry_run_api"`
 	ConfigFile  string `mapstructure:"config_file"`
 	APIEndpoint string `mapstructure:"api_endpoint"`
 	AuthURL     string `mapstructure:"auth_url"`
 	TokenURL    string `mapstructure:"token_url"`
+	JWKSURL     string `mapstructure:"jwks_url"`
+	// ScopedModuleTokens exchanges the access token for a short-lived token
+	// per module before passing it over the bridge
+	ScopedModuleTokens bool `mapstructure:"scoped_module_tokens"`
+	// CredentialStore is where tokens are kept: CredentialStoreFile or
+	// CredentialStoreKeyring
+	CredentialStore string `mapstructure:"credential_store"`
+	// FIPS restricts crypto to FIPS-approved algorithms at runtime
+	FIPS        bool   `mapstructure:"fips"`
+	// StrictProtocol turns module responses that break the bridge contract
+	// into errors; it defaults to on in CI
+	StrictProtocol bool `mapstructure:"strict_protocol"`
+	// ErrorFormat is how a failed command's error is written to stderr:
+	// "text", or "json" for an envelope with its category and exit code
+	ErrorFormat string `mapstructure:"error_format"`
 	ClientID    string `mapstructure:"client_id"`
 	ClientSecret string `mapstructure:"client_secret"`
 	DeviceName  string `mapstructure:"device_name"`
 	Concurrency int    `mapstructure:"concurrency"`
 	PluginsDir  string `mapstructure:"plugins_dir"`
 	DataDir     string `mapstructure:"data_dir"`
+	// OutputDir is where downloads go when --output-dir is not given
+	OutputDir string `mapstructure:"output_dir"`
+	// ConversionProfile names the preset applied when --preset is not given
+	ConversionProfile string `mapstructure:"profile"`
+	// HWAccel lets conversions use the hardware encoders setup and doctor
+	// detected; --no-hwaccel turns it off for one invocation
+	HWAccel bool `mapstructure:"hwaccel"`
+	// Proxy is passed to modules that accept one, such as
+	// socks5://127.0.0.1:1080, to reach content blocked in this region
+	Proxy string `mapstructure:"proxy"`
+	Bridge      BridgeConfig `mapstructure:"bridge"`
+	Timeouts    TimeoutsConfig `mapstructure:"timeouts"`
+	Presets     map[string]ConversionPreset `mapstructure:"presets"`
+	Subscriptions SubscriptionsConfig `mapstructure:"subscriptions"`
+	Worker      WorkerConfig `mapstructure:"worker"`
+	Output      OutputConfig `mapstructure:"output"`
+	// SensitiveEnv are glob patterns of environment variables whose values
+	// are masked in echoed commands and logs
+	SensitiveEnv []string `mapstructure:"sensitive_env"`
+	Remote      RemoteConfig `mapstructure:"remote"`
+	Dependencies DependenciesConfig `mapstructure:"dependencies"`
+	Library     LibraryConfig `mapstructure:"library"`
+	Transcode   TranscodeConfig `mapstructure:"transcode"`
+	Integrations IntegrationsConfig `mapstructure:"integrations"`
+	Cleanup     CleanupConfig `mapstructure:"cleanup"`
+	Plugins     PluginsConfig `mapstructure:"plugins"`
+
+	// Migration is set when Load upgraded an older config file
+	Migration *MigrationResult `mapstructure:"-"`
+
+	// Policy is the administrator policy in force, nil when there is none
+	Policy *Policy `mapstructure:"-"`
+
+	// Project is the project config in force, nil when there is none
+	Project *ProjectConfig `mapstructure:"-"`
+
+	// Profile is set by --profile to profile this invocation
+	Profile bool `mapstructure:"-"`
+
+	// Verbose is set by --verbose to show the log lines of modules
+	Verbose bool `mapstructure:"-"`
+
+	// Explain is set by --explain to print a decision trace on failure
+	Explain bool `mapstructure:"-"`
+
+	// AllowUnsigned is set by --allow-unsigned to load modules without a
+	// signature by a trusted key
+	AllowUnsigned bool `mapstructure:"-"`
+
+	// ReadOnly is set by --read-only or the policy's read_only to refuse
+	// changes to the configuration, plugins, credentials, history,
+	// subscriptions and library
+	ReadOnly bool `mapstructure:"-"`
+
+	// layers are the system config and included files beneath the user
+	// config
+	layers *configLayers
+}
+
+// PluginsConfig controls which modules are trusted
+type PluginsConfig struct {
+	// TrustedKeys are base64 Ed25519 public keys trusted to sign modules,
+	// next to the policy's plugin_signing_keys and the bundled modules'
+	// key. Modules must be signed by one of them.
+	TrustedKeys []string `mapstructure:"trusted_keys"`
+	// IndexURL is the index.json of the plugin registry 'converso plugin
+	// search' and 'converso plugin install <name>' use
+	IndexURL string `mapstructure:"index_url"`
+}
+
+// BridgeConfig holds settings for the Python module bridge
+type BridgeConfig struct {
+	Compression CompressionConfig `mapstructure:"compression"`
+	Pool        PoolConfig        `mapstructure:"pool"`
+	// MaxConcurrent bounds the commands each module runs at once, with
+	// limits of their own for the modules in ModuleConcurrency; 0 uses
+	// the global concurrency
+	MaxConcurrent     int            `mapstructure:"max_concurrent"`
+	ModuleConcurrency map[string]int `mapstructure:"module_concurrency"`
+}
+
+// ModuleConcurrency returns how many commands module may run at once;
+// zero or less means no limit
+func (c *Config) ModuleConcurrency(module string) int {
+	if limit, ok := c.Bridge.ModuleConcurrency[module]; ok {
+		return limit
+	}
+	if c.Bridge.MaxConcurrent != 0 {
+		return c.Bridge.MaxConcurrent
+	}
+	return c.Concurrency
+}
+
+// PoolConfig keeps module processes running between commands instead of
+// starting an interpreter for each
+type PoolConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxProcesses bounds the persistent processes per module
+	MaxProcesses int           `mapstructure:"max_processes"`
+	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+}
+
+// CompressionConfig controls gzip compression of bridge frames
+type CompressionConfig struct {
+	Enabled           bool `mapstructure:"enabled"`
+	RequestThreshold  int  `mapstructure:"request_threshold"`
+	ResponseThreshold int  `mapstructure:"response_threshold"`
+}
+
+// SubscriptionsConfig controls channel subscription syncing
+type SubscriptionsConfig struct {
+	SyncInterval time.Duration `mapstructure:"sync_interval"`
+	MaxVideos    int           `mapstructure:"max_videos"`
+}
+
+// WorkerConfig controls when the background worker pauses heavy jobs
+type WorkerConfig struct {
+	PauseOnBattery    bool          `mapstructure:"pause_on_battery"`
+	MinBatteryPercent int           `mapstructure:"min_battery_percent"`
+	PauseOnMetered    bool          `mapstructure:"pause_on_metered"`
+	CheckInterval     time.Duration `mapstructure:"check_interval"`
+	// HeavyCommands are the module commands paused by power and network
+	// conditions; other jobs always run
+	HeavyCommands []string `mapstructure:"heavy_commands"`
+	// HealthAddr is the listen address of the health endpoint; empty
+	// disables it outside headless mode
+	HealthAddr string `mapstructure:"health_addr"`
+	// ControlAddr is the listen address of the control API used by
+	// 'converso --remote'; empty disables it
+	ControlAddr string `mapstructure:"control_addr"`
+	// ControlTLSCert and ControlTLSKey serve the control API over TLS,
+	// which is required unless it listens on loopback only
+	ControlTLSCert string `mapstructure:"control_tls_cert"`
+	ControlTLSKey  string `mapstructure:"control_tls_key"`
+	// Sync makes completed downloads available to other devices with
+	// 'converso jobs fetch': SyncDirect serves them from the control API,
+	// SyncBackend uploads them to the backend. Empty keeps them here.
+	Sync string `mapstructure:"sync"`
+}
+
+// Credential stores
+const (
+	CredentialStoreFile    = "file"
+	CredentialStoreKeyring = "keyring"
+)
+
+// Worker sync modes
+const (
+	SyncDirect  = "direct"
+	SyncBackend = "backend"
+)
+
+// RemoteConfig selects a worker on another machine to manage instead of
+// the local one
+type RemoteConfig struct {
+	// Addr is the worker's control address as tcp://host:port
+	Addr string `mapstructure:"addr"`
+	// CAFile verifies the worker's certificate, for self-signed ones
+	CAFile string `mapstructure:"ca_file"`
+	// Insecure connects without TLS, for SSH tunnels
+	Insecure bool `mapstructure:"insecure"`
+}
+
+// DependenciesConfig controls the check for outdated Python packages that
+// modules depend on, such as yt-dlp
+type DependenciesConfig struct {
+	// Check compares installed versions with the package index once a day
+	// and warns when a module runs with an outdated package
+	Check bool `mapstructure:"check"`
+	// IndexURL is the package index with PyPI's JSON API
+	IndexURL string `mapstructure:"index_url"`
+}
+
+// LibraryConfig controls the metadata kept about downloads
+type LibraryConfig struct {
+	// Sidecars writes a .info.json file with the metadata of each download
+	// next to it and adds it to the index 'converso library search' uses
+	Sidecars bool `mapstructure:"sidecars"`
+	// Template is where 'converso library organize' moves downloads,
+	// relative to the output directory, filled in from their sidecars
+	Template string `mapstructure:"template"`
+}
+
+// IntegrationsConfig connects downloads to other applications
+type IntegrationsConfig struct {
+	MediaServer MediaServerConfig `mapstructure:"mediaserver"`
+}
+
+// Media server types
+const (
+	MediaServerPlex     = "plex"
+	MediaServerJellyfin = "jellyfin"
+)
+
+// MediaServerConfig tells a Plex or Jellyfin server about finished
+// downloads so they show up without waiting for its next scan
+type MediaServerConfig struct {
+	// Type is plex or jellyfin; empty disables the integration
+	Type string `mapstructure:"type"`
+	URL  string `mapstructure:"url"`
+	// Token is the Plex token or Jellyfin API key;
+	// CONVERSO_MEDIASERVER_TOKEN overrides it
+	Token string `mapstructure:"token"`
+	// Section is the ID of the Plex library to scan; empty scans all
+	// libraries
+	Section string `mapstructure:"section"`
+	// NFO writes a .nfo file with the title, description and uploader next
+	// to each download, which both servers read instead of guessing
+	NFO bool `mapstructure:"nfo"`
+}
+
+// Enabled reports whether a media server is configured
+func (m MediaServerConfig) Enabled() bool {
+	return m.Type != ""
+}
+
+// Validate checks the media server settings
+func (m MediaServerConfig) Validate() error {
+	switch m.Type {
+	case "":
+		return nil
+	case MediaServerPlex, MediaServerJellyfin:
+	default:
+		return fmt.Errorf("unknown media server type %q, expected %s or %s", m.Type, MediaServerPlex, MediaServerJellyfin)
+	}
+	if m.URL == "" {
+		return fmt.Errorf("%s needs the url of the server", m.Type)
+	}
+	if m.Token == "" {
+		return fmt.Errorf("%s needs a token; set it in the config or CONVERSO_MEDIASERVER_TOKEN", m.Type)
+	}
+	return nil
+}
+
+// OutputConfig controls how the CLI writes to the terminal
+type OutputConfig struct {
+	// Unicode is "auto" to detect whether the terminal renders Unicode, or
+	// a boolean to force Unicode or the ASCII theme
+	Unicode string `mapstructure:"unicode"`
+	// Theme names the symbol and color theme: default, minimal or
+	// high-contrast
+	Theme string `mapstructure:"theme"`
+	// Color enables colored output; NO_COLOR and --no-color turn it off
+	Color bool `mapstructure:"color"`
+	// Locale formats numbers, dates and durations: "auto" to detect it
+	// from the environment, or a locale such as "de_DE"
+	Locale string `mapstructure:"locale"`
+	// SizeUnits is "binary" for file sizes in powers of 1024 (MiB) or
+	// "si" for powers of 1000 (MB)
+	SizeUnits string `mapstructure:"size_units"`
+
+	// Mode is set by --output: auto, human, table, plain, json or yaml
+	Mode string `mapstructure:"-"`
+}
+
+// TimeoutsConfig controls how long module commands may run in total and
+// without producing any output
+type TimeoutsConfig struct {
+	Total   time.Duration                        `mapstructure:"total"`
+	Idle    time.Duration                        `mapstructure:"idle"`
+	Modules map[string]map[string]CommandTimeout `mapstructure:"modules"`
+
+	// Per-invocation overrides set from the --timeout and --idle-timeout flags
+	TotalOverride time.Duration `mapstructure:"-"`
+	IdleOverride  time.Duration `mapstructure:"-"`
+}
+
+// CommandTimeout overrides the timeouts of a single module command
+type CommandTimeout struct {
+	Total time.Duration `mapstructure:"total"`
+	Idle  time.Duration `mapstructure:"idle"`
+}
+
+// For returns the total and idle timeouts that apply to a module command.
+// Flag overrides take precedence over per-command settings, which take
+// precedence over the global defaults.
+func (t TimeoutsConfig) For(module, command string) (total, idle time.Duration) {
+	total, idle = t.Total, t.Idle
+
+	if override, ok := t.Modules[module][command]; ok {
+		if override.Total > 0 {
+			total = override.Total
+		}
+		if override.Idle > 0 {
+			idle = override.Idle
+		}
+	}
+
+	if t.TotalOverride > 0 {
+		total = t.TotalOverride
+	}
+	if t.IdleOverride > 0 {
+		idle = t.IdleOverride
+	}
+
+	return total, idle
+}
+
+// IdleSet reports whether the idle timeout of a module command is set by
+// --idle-timeout or for the command itself rather than taken from the
+// global default
+func (t TimeoutsConfig) IdleSet(module, command string) bool {
+	return t.IdleOverride > 0 || t.Modules[module][command].Idle > 0
 }
 
 // Default configuration values
@@ -28,21 +353,66 @@ const (
 	DefaultAPIEndpoint = "https://capi.conversoempire.world"
 	DefaultAuthURL     = "https://clerk.conversoempire.world/oauth/authorize"
 	DefaultTokenURL    = "https://clerk.conversoempire.world/oauth/token"
+	DefaultJWKSURL     = "https://clerk.conversoempire.world/.well-known/jwks.json"
+	DefaultIndexURL    = "https://pypi.org"
 	DefaultClientID    = "converso-cli"
 	DefaultConcurrency = 10
+
+	// DefaultPluginIndexURL is the index of the public plugin registry
+	DefaultPluginIndexURL = "https://plugins.conversoempire.world/index.json"
+
+	// DefaultCompressionThreshold is the frame size in bytes above which
+	// bridge frames are compressed
+	DefaultCompressionThreshold = 64 * 1024
+
+	// DefaultPoolMaxProcesses and DefaultPoolIdleTimeout bound the
+	// persistent module processes of the bridge pool
+	DefaultPoolMaxProcesses = 2
+	DefaultPoolIdleTimeout  = 5 * time.Minute
+
+	DefaultTimeout     = 5 * time.Minute
+	DefaultIdleTimeout = 2 * time.Minute
+
+	DefaultSyncInterval = 6 * time.Hour
+	DefaultSyncMaxVideos = 30
+
+	DefaultMinBatteryPercent  = 30
+	DefaultPowerCheckInterval = time.Minute
+
+	// DefaultHealthAddr is where the worker serves its health endpoint in
+	// headless mode
+	DefaultHealthAddr = ":8787"
+
+	// DefaultLibraryTemplate sorts downloads into uploader/year folders
+	DefaultLibraryTemplate = "${uploader | slug ?? 'unknown'}/${year}/${title | slug ?? id}.${ext}"
 )
 
+// DefaultHeavyCommands are the module commands the worker pauses on battery
+// or metered connections
+var DefaultHeavyCommands = []string{"download", "convert", "frames"}
+
+// DefaultSensitiveEnv are the environment variables masked in echoed
+// commands and logs unless sensitive_env is set
+var DefaultSensitiveEnv = []string{"*TOKEN", "*SECRET", "*SECRET_*", "*PASSWORD", "*PASSPHRASE", "*API_KEY", "*CREDENTIALS"}
+
 // Load loads the configuration from various sources
 func Load() (*Config, error) {
+	return load(false)
+}
+
+// LoadReadOnly loads the configuration like Load for read-only mode, which
+// upgrades a config file written by an older release in memory only
+func LoadReadOnly() (*Config, error) {
+	return load(true)
+}
+
+// load loads the configuration, leaving an old config file as it is when
+// readOnly is set or the policy requires read-only mode
+func load(readOnly bool) (*Config, error) {
 	cfg := &Config{}
 
-	// Set default values
-	viper.SetDefault("debug", false)
-	viper.SetDefault("api_endpoint", DefaultAPIEndpoint)
-	viper.SetDefault("auth_url", DefaultAuthURL)
-	viper.SetDefault("token_url", DefaultTokenURL)
-	viper.SetDefault("client_id", DefaultClientID)
-	viper.SetDefault("concurrency", DefaultConcurrency)
+	// Set default values and environment variables
+	setDefaults()
 
 	// Set configuration file name and type
 	viper.SetConfigName("config")
@@ -58,15 +428,23 @@ func Load() (*Config, error) {
 	viper.AddConfigPath(configDir)
 	viper.AddConfigPath(".")
 
-	// Set environment variables
-	viper.SetEnvPrefix("CONVERSO")
-	viper.AutomaticEnv()
+	// Read the administrator policy before any configuration
+	policy, err := LoadPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the system-wide config the user config is layered over
+	layers, err := loadSystemLayer()
+	if err != nil {
+		return nil, err
+	}
 
 	// Read configuration file
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
 			// Config file not found, create default config
-			if err := createDefaultConfig(configDir); err != nil {
+			if err := createDefaultConfig(configDir, len(layers.Files) > 0); err != nil {
 				return nil, fmt.Errorf("failed to create default config: %w", err)
 			}
 			if err := viper.ReadInConfig(); err != nil {
@@ -77,45 +455,460 @@ func Load() (*Config, error) {
 		}
 	}
 
+	// Upgrade config files written by older releases
+	readOnly = readOnly || policy.ReadOnlyRequired()
+	var migration *MigrationResult
+	if !readOnly {
+		if migration, err = Migrate(viper.ConfigFileUsed()); err != nil {
+			return nil, err
+		}
+		if migration != nil {
+			if err := viper.ReadInConfig(); err != nil {
+				return nil, fmt.Errorf("failed to read config after migration: %w", err)
+			}
+		}
+	}
+
+	// Layer the user config over the system config and included files
+	own, err := layers.addUserIncludes(viper.ConfigFileUsed())
+	if err != nil {
+		return nil, err
+	}
+	if readOnly {
+		// Read-only mode never writes the config file
+		if _, err := migrateSettings(own); err != nil {
+			return nil, err
+		}
+	}
+	settings := make(map[string]interface{})
+	mergeSettings(settings, layers.Base)
+	mergeSettings(settings, own)
+	if err := viper.MergeConfigMap(settings); err != nil {
+		return nil, fmt.Errorf("failed to merge config layers: %w", err)
+	}
+
+	// Locked system settings win over the user config and environment
+	for _, key := range layers.Locked {
+		if value, ok := lookupSetting(layers.System, key); ok {
+			viper.Set(key, value)
+		}
+	}
+
+	// Policy settings win over everything else
+	for key, value := range policy.PinnedSettings() {
+		viper.Set(key, value)
+	}
+
 	// Unmarshal configuration
 	if err := viper.Unmarshal(cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	cfg.Migration = migration
+	cfg.Policy = policy
+	cfg.layers = layers
 
-	// Set computed paths
-	cfg.DataDir = filepath.Join(configDir, "data")
-	cfg.PluginsDir = filepath.Join(configDir, "plugins")
+	// Secrets may also come from files mounted by container runtimes
+	clientSecret, err := Secret("client_secret")
+	if err != nil {
+		return nil, err
+	}
+	if clientSecret != "" {
+		cfg.ClientSecret = clientSecret
+	}
+	mediaServerToken, err := Secret("mediaserver_token")
+	if err != nil {
+		return nil, err
+	}
+	if mediaServerToken != "" {
+		cfg.Integrations.MediaServer.Token = mediaServerToken
+	}
+
+	// Set computed paths unless overridden in the config file or environment
+	if err := cfg.setDirs(configDir); err != nil {
+		return nil, err
+	}
+
+	// A project config in the working directory or above wins over the
+	// user config for that project
+	if cwd, err := os.Getwd(); err == nil {
+		if path := FindProjectConfig(cwd); path != "" {
+			project, err := loadProjectConfig(path)
+			if err != nil {
+				return nil, err
+			}
+			cfg.applyProject(project, layers.Locked)
+		}
+	}
 
 	return cfg, nil
 }
 
-// createDefaultConfig creates a default configuration file
-func createDefaultConfig(configDir string) error {
+// Defaults returns the built-in configuration with environment overrides
+// applied, without reading the policy or any config file. It is cheap
+// enough for commands such as version and help; Load replaces it for
+// commands that need the real configuration.
+func Defaults() (*Config, error) {
+	cfg := &Config{}
+	setDefaults()
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	if err := viper.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	if err := cfg.setDirs(filepath.Join(homeDir, ".converso")); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// setDefaults registers the built-in defaults and environment variables
+func setDefaults() {
+	viper.SetDefault("debug", false)
+	viper.SetDefault("headless", false)
+	viper.SetDefault("dry_run_api", false)
+	viper.SetDefault("api_endpoint", DefaultAPIEndpoint)
+	viper.SetDefault("auth_url", DefaultAuthURL)
+	viper.SetDefault("token_url", DefaultTokenURL)
+	viper.SetDefault("jwks_url", DefaultJWKSURL)
+	viper.SetDefault("scoped_module_tokens", true)
+	viper.SetDefault("credential_store", CredentialStoreFile)
+	viper.SetDefault("fips", false)
+	viper.SetDefault("strict_protocol", runningInCI())
+	viper.SetDefault("error_format", "text")
+	viper.SetDefault("hwaccel", true)
+	viper.SetDefault("client_id", DefaultClientID)
+	viper.SetDefault("concurrency", DefaultConcurrency)
+	viper.SetDefault("proxy", "")
+	viper.SetDefault("bridge.compression.enabled", true)
+	viper.SetDefault("bridge.compression.request_threshold", DefaultCompressionThreshold)
+	viper.SetDefault("bridge.compression.response_threshold", DefaultCompressionThreshold)
+	viper.SetDefault("bridge.pool.enabled", false)
+	viper.SetDefault("bridge.pool.max_processes", DefaultPoolMaxProcesses)
+	viper.SetDefault("bridge.pool.idle_timeout", DefaultPoolIdleTimeout)
+	viper.SetDefault("bridge.max_concurrent", 0)
+	viper.SetDefault("timeouts.total", DefaultTimeout)
+	viper.SetDefault("timeouts.idle", DefaultIdleTimeout)
+	viper.SetDefault("subscriptions.sync_interval", DefaultSyncInterval)
+	viper.SetDefault("subscriptions.max_videos", DefaultSyncMaxVideos)
+	viper.SetDefault("worker.pause_on_battery", true)
+	viper.SetDefault("worker.min_battery_percent", DefaultMinBatteryPercent)
+	viper.SetDefault("worker.pause_on_metered", true)
+	viper.SetDefault("worker.check_interval", DefaultPowerCheckInterval)
+	viper.SetDefault("worker.heavy_commands", DefaultHeavyCommands)
+	viper.SetDefault("worker.health_addr", "")
+	viper.SetDefault("worker.control_addr", "")
+	viper.SetDefault("worker.control_tls_cert", "")
+	viper.SetDefault("worker.control_tls_key", "")
+	viper.SetDefault("worker.sync", "")
+	viper.SetDefault("output.unicode", "auto")
+	viper.SetDefault("output.theme", "default")
+	viper.SetDefault("output.color", true)
+	viper.SetDefault("output.locale", "auto")
+	viper.SetDefault("output.size_units", "binary")
+	viper.SetDefault("sensitive_env", DefaultSensitiveEnv)
+	viper.SetDefault("remote.addr", "")
+	viper.SetDefault("remote.ca_file", "")
+	viper.SetDefault("remote.insecure", false)
+	viper.SetDefault("dependencies.check", true)
+	viper.SetDefault("dependencies.index_url", DefaultIndexURL)
+	viper.SetDefault("plugins.index_url", DefaultPluginIndexURL)
+	viper.SetDefault("library.sidecars", true)
+	viper.SetDefault("library.template", DefaultLibraryTemplate)
+	viper.SetDefault("integrations.mediaserver.type", "")
+	viper.SetDefault("integrations.mediaserver.url", "")
+	viper.SetDefault("integrations.mediaserver.section", "")
+	viper.SetDefault("integrations.mediaserver.nfo", false)
+	viper.SetDefault("cleanup.interval", time.Duration(0))
+
+	// Set environment variables
+	viper.SetEnvPrefix("CONVERSO")
+	viper.AutomaticEnv()
+
+	// Directories and the profile have no default in viper, so bind their
+	// variables (CONVERSO_DATA_DIR, CONVERSO_PLUGINS_DIR, CONVERSO_OUTPUT_DIR,
+	// CONVERSO_PROFILE) explicitly
+	viper.BindEnv("data_dir")
+	viper.BindEnv("plugins_dir")
+	viper.BindEnv("output_dir")
+	viper.BindEnv("profile")
+}
+
+// runningInCI reports whether a CI system runs the CLI. CI systems set
+// the CI variable.
+func runningInCI() bool {
+	ci, err := strconv.ParseBool(os.Getenv("CI"))
+	return err == nil && ci
+}
+
+// setDirs defaults the data and plugins directories to configDir and
+// expands them along with the output directory
+func (c *Config) setDirs(configDir string) error {
+	var err error
+	if c.DataDir == "" {
+		c.DataDir = filepath.Join(configDir, "data")
+	}
+	if c.PluginsDir == "" {
+		c.PluginsDir = filepath.Join(configDir, "plugins")
+	}
+	if c.DataDir, err = ExpandPath(c.DataDir); err != nil {
+		return fmt.Errorf("invalid data_dir: %w", err)
+	}
+	if c.PluginsDir, err = ExpandPath(c.PluginsDir); err != nil {
+		return fmt.Errorf("invalid plugins_dir: %w", err)
+	}
+	if c.OutputDir != "" {
+		if c.OutputDir, err = expandOutputDir(c.OutputDir); err != nil {
+			return fmt.Errorf("invalid output_dir: %w", err)
+		}
+	}
+	return nil
+}
+
+// expandOutputDir resolves the ${env:...} and ${date:...} references of an
+// output directory, then expands it like ExpandPath
+func expandOutputDir(dir string) (string, error) {
+	dir, err := interpolate.Expand(dir, nil)
+	if err != nil {
+		return "", err
+	}
+	return ExpandPath(dir)
+}
+
+// ExpandPath resolves a leading ~ to the home directory and makes the path
+// absolute
+func ExpandPath(path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get user home directory: %w", err)
+		}
+		path = filepath.Join(homeDir, path[1:])
+	}
+	return filepath.Abs(path)
+}
+
+// createDefaultConfig creates a default configuration file. When a system
+// config exists, the file only records the version so settings are
+// inherited from it rather than pinned to the defaults.
+func createDefaultConfig(configDir string, hasSystemConfig bool) error {
 	// Create config directory
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
+	configFile := filepath.Join(configDir, "config.yaml")
+	if hasSystemConfig {
+		userConfig := []byte(fmt.Sprintf(`# Converso CLI Configuration
+#
+# Settings not given here come from the system config (%s).
+# Add personal settings below to override it.
+
+# Schema version, upgraded automatically by newer releases
+config_version: %d
+`, SystemConfigPath(), CurrentVersion))
+		if err := os.WriteFile(configFile, userConfig, 0644); err != nil {
+			return fmt.Errorf("failed to write default config file: %w", err)
+		}
+		return nil
+	}
+
 	// Create default config content
-	defaultConfig := []byte(`# Converso CLI Configuration
+	defaultConfig := []byte(fmt.Sprintf(`# Converso CLI Configuration
+
+# Schema version, upgraded automatically by newer releases
+config_version: %d
+
 debug: false
 
 # API Configuration
 api_endpoint: "https://capi.conversoempire.world"
 auth_url: "https://clerk.conversoempire.world/oauth/authorize"
 token_url: "https://clerk.conversoempire.world/oauth/token"
+# Keys for verifying access tokens; empty disables verification
+jwks_url: "https://clerk.conversoempire.world/.well-known/jwks.json"
+# Give each module a short-lived token scoped to it instead of the access
+# token, through token exchange (RFC 8693) at token_url. Without exchange
+# support, modules that do not require auth get no token.
+scoped_module_tokens: true
+# Keep tokens in a file in the data directory (file) or in the OS credential
+# store (keyring: macOS Keychain, Windows Credential Manager, libsecret)
+credential_store: file
+# Restrict TLS and other crypto to FIPS-approved algorithms
+fips: false
 client_id: "ssUkfqPfE4NC9TWz"
 
 # Application Settings
 concurrency: 10
 device_name: "default"
+# Proxy for modules that accept one, e.g. to reach region-blocked videos
+# proxy: "socks5://127.0.0.1:1080"
+
+plugins:
+  # Registry index for 'converso plugin search' and 'plugin install <name>'
+  index_url: "https://plugins.conversoempire.world/index.json"
+  # Public keys of module signers ('converso plugin keys add'), next to the
+  # bundled modules' key; modules must be signed by one unless
+  # --allow-unsigned is given
+  # trusted_keys: ["<public key from 'converso dev signing-key'>"]
+
+# Module Bridge
+bridge:
+  compression:
+    enabled: true
+    # Frames larger than these sizes (bytes) are gzip-compressed
+    request_threshold: 65536
+    response_threshold: 65536
+  # Keep module processes running between commands of long-running
+  # commands, pipelines and the worker instead of starting Python each time
+  pool:
+    enabled: false
+    max_processes: 2
+    idle_timeout: 5m
+  # Commands each module runs at once (0 uses concurrency), with limits per
+  # module below
+  max_concurrent: 0
+  # module_concurrency:
+  #   youtube: 3
+
+# Module command timeouts: total run time and time without any output.
+# The idle timeout applies to modules declaring a bridge protocol, which send
+# keepalives; set it per command for others.
+timeouts:
+  total: 5m
+  idle: 2m
+  modules:
+    youtube:
+      download:
+        total: 2h
+
+# Conversion presets for 'converso convert --preset' and download post-processing
+presets:
+  web-720p:
+    codec: h264
+    bitrate: 2500k
+    resolution: 1280x720
+    container: mp4
+  audio-mp3:
+    codec: mp3
+    bitrate: 192k
+    container: mp3
 
-# Paths (auto-generated)
+# Preset applied by download and convert when --preset is not given
+# profile: web-720p
+
+# Convert with the hardware encoders (NVENC, Quick Sync, VideoToolbox) that
+# 'converso setup' and 'converso doctor' detect; --no-hwaccel turns it off
+# for one command
+# hwaccel: true
+
+# Download directory (default ~/Downloads/Converso), may use ${env:NAME} and
+# ${date:2006-01}; a .converso.yaml in a project directory can set
+# output_dir, presets and profile for that project
+# output_dir: "~/Videos/Converso/${date:2006-01}"
+
+# Channel subscriptions for 'converso youtube sync'
+subscriptions:
+  sync_interval: 6h
+  # Number of recent uploads checked per channel
+  max_videos: 30
+
+# Background worker: pause heavy jobs on battery or metered networks
+worker:
+  pause_on_battery: true
+  # Heavy jobs run on battery while the charge is at or above this level
+  min_battery_percent: 30
+  pause_on_metered: true
+  check_interval: 1m
+  heavy_commands: [download, convert, frames]
+  # Health endpoint for orchestration (default :8787 in headless mode)
+  # health_addr: ":8787"
+  # Control API for 'converso --remote'; needs TLS unless on loopback
+  # control_addr: ":8790"
+  # control_tls_cert: "/etc/converso/tls/cert.pem"
+  # control_tls_key: "/etc/converso/tls/key.pem"
+  # Let other devices fetch completed downloads: direct (through the control
+  # API) or backend (uploaded after each job)
+  # sync: direct
+
+# Terminal output: auto detects Unicode support from the locale; false uses
+# ASCII instead of emoji and block characters
+output:
+  unicode: auto
+  # Symbol and color theme: default, minimal or high-contrast
+  theme: default
+  # Colored output; also off with --no-color or the NO_COLOR variable
+  color: true
+  # Number, date and duration formats: auto (from LC_ALL, LC_NUMERIC, LC_TIME
+  # or LANG) or a locale such as de_DE
+  locale: auto
+  # File sizes in binary (1 MiB = 1024 KiB) or si (1 MB = 1000 kB) units
+  size_units: binary
+
+# Environment variables (glob patterns) whose values are masked, like module
+# secrets and credentials, wherever commands and their arguments are echoed
+# or logged
+sensitive_env: ["*TOKEN", "*SECRET", "*SECRET_*", "*PASSWORD", "*PASSPHRASE", "*API_KEY", "*CREDENTIALS"]
+
+# Manage the worker on another machine instead of this one (also --remote)
+# remote:
+#   addr: "tcp://downloads.lan:8790"
+#   # Verify a self-signed worker certificate
+#   ca_file: "~/.converso/worker-ca.pem"
+
+# Warn when a module's Python packages, such as yt-dlp for youtube, are
+# outdated; the package index is asked once a day
+dependencies:
+  check: true
+  index_url: "https://pypi.org"
+
+# Write a .info.json file with each download's metadata next to it and index
+# its title, uploader and tags for 'converso library search'
+library:
+  sidecars: true
+  # Where 'converso library organize' moves downloads, relative to the output
+  # directory; ${...} takes any field of the .info.json sidecar
+  template: "${uploader | slug ?? 'unknown'}/${year}/${title | slug ?? id}.${ext}"
+
+# Convert downloads the worker finishes when they match a rule, with a preset
+# or explicit options; conditions are codec, container, device and module
+# transcode:
+#   rules:
+#     - when: {codec: av1, device: tv}
+#       to: {codec: h264, container: mp4}
+#     - when: {container: webm}
+#       preset: web-720p
+
+# Ask a Plex or Jellyfin server to scan new downloads when they finish, and
+# optionally write a .nfo file with their metadata next to them
+# integrations:
+#   mediaserver:
+#     type: plex                  # or jellyfin
+#     url: "http://localhost:32400"
+#     token: ""                   # or CONVERSO_MEDIASERVER_TOKEN
+#     section: "1"                # Plex library ID; empty scans all
+#     nfo: true
+
+# Delete downloads (only those with a .info.json sidecar) beyond the newest
+# keep_last per channel, older than older_than, or marked watched with
+# 'converso library watched'; preview with 'converso library cleanup'
+# cleanup:
+#   interval: 24h                 # run in the worker; 0 runs only with --apply
+#   rules:
+#     - dir: "~/Downloads/Converso_YT"
+#       keep_last: 10
+#       older_than: 720h
+#       watched: true
+
+# Paths (default under ~/.converso; also set per invocation with --data-dir and
+# --plugins-dir or CONVERSO_DATA_DIR and CONVERSO_PLUGINS_DIR)
 # data_dir: "~/.converso/data"
 # plugins_dir: "~/.converso/plugins"
-`)
+`, CurrentVersion))
 
-	configFile := filepath.Join(configDir, "config.yaml")
 	if err := os.WriteFile(configFile, defaultConfig, 0644); err != nil {
 		return fmt.Errorf("failed to write default config file: %w", err)
 	}
@@ -123,27 +916,134 @@ device_name: "default"
 	return nil
 }
 
-// Save saves the configuration to file
-func (c *Config) Save() error {
+// UserConfigFile returns the path of the user's config file,
+// ~/.converso/config.yaml
+func UserConfigFile() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed to get user home directory: %w", err)
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
 	}
+	return filepath.Join(homeDir, ".converso", "config.yaml"), nil
+}
 
-	configDir := filepath.Join(homeDir, ".converso")
-	configFile := filepath.Join(configDir, "config.yaml")
+// Save saves the configuration to file
+func (c *Config) Save() error {
+	if c.ReadOnly {
+		return fmt.Errorf("the configuration cannot be changed in read-only mode")
+	}
+	configFile, err := UserConfigFile()
+	if err != nil {
+		return err
+	}
 
 	// Set viper values
+	viper.Set("config_version", CurrentVersion)
 	viper.Set("debug", c.Debug)
 	viper.Set("api_endpoint", c.APIEndpoint)
 	viper.Set("auth_url", c.AuthURL)
 	viper.Set("token_url", c.TokenURL)
+	viper.Set("jwks_url", c.JWKSURL)
+	viper.Set("scoped_module_tokens", c.ScopedModuleTokens)
+	viper.Set("credential_store", c.CredentialStore)
+	viper.Set("fips", c.FIPS)
 	viper.Set("client_id", c.ClientID)
 	viper.Set("concurrency", c.Concurrency)
 	viper.Set("device_name", c.DeviceName)
+	viper.Set("proxy", c.Proxy)
+	viper.Set("bridge.compression.enabled", c.Bridge.Compression.Enabled)
+	viper.Set("bridge.compression.request_threshold", c.Bridge.Compression.RequestThreshold)
+	viper.Set("bridge.compression.response_threshold", c.Bridge.Compression.ResponseThreshold)
+	viper.Set("bridge.pool.enabled", c.Bridge.Pool.Enabled)
+	viper.Set("bridge.pool.max_processes", c.Bridge.Pool.MaxProcesses)
+	viper.Set("bridge.pool.idle_timeout", c.Bridge.Pool.IdleTimeout.String())
+	viper.Set("bridge.max_concurrent", c.Bridge.MaxConcurrent)
+	viper.Set("timeouts.total", c.Timeouts.Total.String())
+	viper.Set("timeouts.idle", c.Timeouts.Idle.String())
+	viper.Set("subscriptions.sync_interval", c.Subscriptions.SyncInterval.String())
+	viper.Set("subscriptions.max_videos", c.Subscriptions.MaxVideos)
+	viper.Set("worker.pause_on_battery", c.Worker.PauseOnBattery)
+	viper.Set("worker.min_battery_percent", c.Worker.MinBatteryPercent)
+	viper.Set("worker.pause_on_metered", c.Worker.PauseOnMetered)
+	viper.Set("worker.check_interval", c.Worker.CheckInterval.String())
+	viper.Set("worker.heavy_commands", c.Worker.HeavyCommands)
+	viper.Set("worker.health_addr", c.Worker.HealthAddr)
+	viper.Set("worker.control_addr", c.Worker.ControlAddr)
+	viper.Set("worker.control_tls_cert", c.Worker.ControlTLSCert)
+	viper.Set("worker.control_tls_key", c.Worker.ControlTLSKey)
+	viper.Set("worker.sync", c.Worker.Sync)
+	viper.Set("output.unicode", c.Output.Unicode)
+	viper.Set("output.theme", c.Output.Theme)
+	viper.Set("output.color", c.Output.Color)
+	viper.Set("output.locale", c.Output.Locale)
+	viper.Set("output.size_units", c.Output.SizeUnits)
+	viper.Set("sensitive_env", c.SensitiveEnv)
+	viper.Set("remote.addr", c.Remote.Addr)
+	viper.Set("remote.ca_file", c.Remote.CAFile)
+	viper.Set("remote.insecure", c.Remote.Insecure)
+	viper.Set("dependencies.check", c.Dependencies.Check)
+	viper.Set("dependencies.index_url", c.Dependencies.IndexURL)
+	viper.Set("plugins.trusted_keys", c.Plugins.TrustedKeys)
+	viper.Set("plugins.index_url", c.Plugins.IndexURL)
+	viper.Set("library.sidecars", c.Library.Sidecars)
+	viper.Set("library.template", c.Library.Template)
+	viper.Set("integrations.mediaserver.type", c.Integrations.MediaServer.Type)
+	viper.Set("integrations.mediaserver.url", c.Integrations.MediaServer.URL)
+	viper.Set("integrations.mediaserver.section", c.Integrations.MediaServer.Section)
+	viper.Set("integrations.mediaserver.nfo", c.Integrations.MediaServer.NFO)
+	viper.Set("cleanup.interval", c.Cleanup.Interval.String())
+
+	// Presets are replaced as a whole so removed presets do not survive
+	// from the previously loaded file
+	settings := viper.AllSettings()
+	settings["presets"] = c.presetSettings()
+
+	// Directory and profile overrides from the environment apply to one
+	// invocation only
+	for _, key := range []string{"data_dir", "plugins_dir", "output_dir", "profile"} {
+		if !viper.InConfig(key) {
+			delete(settings, key)
+		}
+	}
+
+	// Strict protocol follows CI unless the user chose explicitly
+	if !viper.InConfig("strict_protocol") {
+		delete(settings, "strict_protocol")
+	}
+
+	// Read what the user config file itself sets
+	own := make(map[string]interface{})
+	if _, err := os.Stat(configFile); err == nil {
+		if own, err = readFile(configFile); err != nil {
+			return err
+		}
+	}
+
+	// Values pinned by policy are not the user's; keep what the user wrote
+	for key := range c.Policy.PinnedSettings() {
+		if value, ok := own[key]; ok {
+			settings[key] = value
+		} else {
+			delete(settings, key)
+		}
+	}
+
+	// Keep values inherited from the system config and included files out
+	// of the user config unless the user set them there
+	if c.layers != nil {
+		pruneInherited(settings, c.layers.Base, own)
+		delete(settings, includeKey)
+		if include, ok := own[includeKey]; ok {
+			settings[includeKey] = include
+		}
+	}
+
+	out := viper.New()
+	if err := out.MergeConfigMap(settings); err != nil {
+		return fmt.Errorf("failed to prepare config: %w", err)
+	}
 
 	// Write to file
-	if err := viper.WriteConfigAs(configFile); err != nil {
+	if err := out.WriteConfigAs(configFile); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// maxIncludeDepth bounds nested include directives
+const maxIncludeDepth = 8
+
+// Layer directives, which are not settings themselves
+const (
+	includeKey = "include"
+	lockedKey  = "locked"
+)
+
+// SystemConfigPath returns the system-wide config file layered beneath the
+// user config: /etc/converso/config.yaml, or %ProgramData%\Converso on
+// Windows. CONVERSO_SYSTEM_CONFIG overrides it.
+func SystemConfigPath() string {
+	if path := os.Getenv("CONVERSO_SYSTEM_CONFIG"); path != "" {
+		return path
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "Converso", "config.yaml")
+	}
+	return "/etc/converso/config.yaml"
+}
+
+// configLayers are the settings the user config is layered over
+type configLayers struct {
+	// Base holds the system config and the files included by either
+	// config, everything the user config file itself overrides
+	Base map[string]interface{}
+	// System holds the system config with its includes
+	System map[string]interface{}
+	// Locked lists keys of the system config that user settings and the
+	// environment cannot override
+	Locked []string
+	// Files lists the config files read, lowest layer first
+	Files []string
+	// User holds the settings of the user config file itself
+	User map[string]interface{}
+}
+
+// loadSystemLayer reads the system config and its includes, if present
+func loadSystemLayer() (*configLayers, error) {
+	layers := &configLayers{
+		Base:   make(map[string]interface{}),
+		System: make(map[string]interface{}),
+	}
+
+	path := SystemConfigPath()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return layers, nil
+	}
+
+	settings, err := readLayer(path, map[string]bool{}, 0, &layers.Files)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system config: %w", err)
+	}
+
+	if locked, ok := settings[lockedKey]; ok {
+		for _, key := range toStrings(locked) {
+			layers.Locked = append(layers.Locked, strings.ToLower(key))
+		}
+		delete(settings, lockedKey)
+	}
+	delete(settings, "config_version")
+
+	layers.System = settings
+	mergeSettings(layers.Base, settings)
+	return layers, nil
+}
+
+// addUserIncludes layers the files included by the user config into Base
+// and returns the user config's own settings
+func (l *configLayers) addUserIncludes(path string) (map[string]interface{}, error) {
+	own, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	included, err := readIncludes(path, own, map[string]bool{absPath(path): true}, 0, &l.Files)
+	if err != nil {
+		return nil, err
+	}
+	delete(own, includeKey)
+
+	mergeSettings(l.Base, included)
+	l.Files = append(l.Files, path)
+	l.User = own
+	return own, nil
+}
+
+// readLayer reads a config file with the files it includes layered beneath
+// it
+func readLayer(path string, seen map[string]bool, depth int, files *[]string) (map[string]interface{}, error) {
+	abs := absPath(path)
+	if seen[abs] {
+		return nil, fmt.Errorf("config include cycle at %s", path)
+	}
+	if depth > maxIncludeDepth {
+		return nil, fmt.Errorf("config includes nested deeper than %d at %s", maxIncludeDepth, path)
+	}
+	seen[abs] = true
+	defer delete(seen, abs)
+
+	own, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := readIncludes(path, own, seen, depth, files)
+	if err != nil {
+		return nil, err
+	}
+	delete(own, includeKey)
+
+	mergeSettings(settings, own)
+	*files = append(*files, path)
+	return settings, nil
+}
+
+// readIncludes reads the files named by the include directive of a config
+// file, in order, each overriding the previous. Paths are relative to the
+// including file and may be glob patterns; a pattern matching nothing is
+// skipped, a missing plain path is an error.
+func readIncludes(path string, own map[string]interface{}, seen map[string]bool, depth int, files *[]string) (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+
+	for _, include := range toStrings(own[includeKey]) {
+		pattern, err := ExpandPath(include)
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(include) && !strings.HasPrefix(include, "~") {
+			pattern = filepath.Join(filepath.Dir(path), include)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include %q in %s: %w", include, path, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(include, "*?[") {
+			return nil, fmt.Errorf("included config %s not found (from %s)", pattern, path)
+		}
+
+		for _, match := range matches {
+			included, err := readLayer(match, seen, depth+1, files)
+			if err != nil {
+				return nil, err
+			}
+			mergeSettings(settings, included)
+		}
+	}
+	return settings, nil
+}
+
+// readFile reads the settings of a single YAML config file
+func readFile(path string) (map[string]interface{}, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	return v.AllSettings(), nil
+}
+
+// mergeSettings deep-merges src into dst, src taking precedence. Nested
+// maps are copied so dst never aliases src.
+func mergeSettings(dst, src map[string]interface{}) {
+	for key, value := range src {
+		if nested, ok := value.(map[string]interface{}); ok {
+			existing, ok := dst[key].(map[string]interface{})
+			if !ok {
+				existing = make(map[string]interface{})
+				dst[key] = existing
+			}
+			mergeSettings(existing, nested)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// lookupSetting finds a dotted key in nested settings
+func lookupSetting(settings map[string]interface{}, key string) (interface{}, bool) {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		value, ok := settings[part]
+		if !ok {
+			return nil, false
+		}
+		if i == len(parts)-1 {
+			return value, true
+		}
+		if settings, ok = value.(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// pruneInherited removes settings that merely repeat an inherited value
+// and are not set in the user's own file, so saving the config does not
+// pin values managed by the system config or included files
+func pruneInherited(settings, inherited, own map[string]interface{}) {
+	for key, value := range settings {
+		base, inBase := inherited[key]
+		if !inBase {
+			continue
+		}
+		ownValue, inOwn := own[key]
+
+		nested, isMap := value.(map[string]interface{})
+		baseNested, baseIsMap := base.(map[string]interface{})
+		if isMap && baseIsMap {
+			ownNested, _ := ownValue.(map[string]interface{})
+			pruneInherited(nested, baseNested, ownNested)
+			if len(nested) == 0 && !inOwn {
+				delete(settings, key)
+			}
+			continue
+		}
+
+		if !inOwn && sameSetting(value, base) {
+			delete(settings, key)
+		}
+	}
+}
+
+// sameSetting compares setting values as written, treating equal
+// durations such as "6h" and "6h0m0s" as the same
+func sameSetting(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) || fmt.Sprint(a) == fmt.Sprint(b) {
+		return true
+	}
+	da, errA := time.ParseDuration(fmt.Sprint(a))
+	db, errB := time.ParseDuration(fmt.Sprint(b))
+	return errA == nil && errB == nil && da == db
+}
+
+// toStrings converts a string or list directive value to strings
+func toStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			result = append(result, fmt.Sprint(item))
+		}
+		return result
+	}
+	return nil
+}
+
+// absPath returns an absolute path, or the path itself if that fails
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// CurrentVersion is the config_version written by this release. Files
+// without a config_version predate versioning and are version 0.
+const CurrentVersion = 1
+
+// migration upgrades raw config settings from one version to the next
+type migration struct {
+	Description string
+	Apply       func(settings map[string]interface{}) error
+}
+
+// migrations[i] upgrades a config file from version i to version i+1. To
+// rename or restructure config keys, bump CurrentVersion and append a
+// migration that rewrites the old keys; never edit a released migration.
+var migrations = []migration{
+	{
+		Description: "Introduce config_version",
+		Apply:       func(settings map[string]interface{}) error { return nil },
+	},
+}
+
+// MigrationResult describes an upgraded config file
+type MigrationResult struct {
+	From       int
+	To         int
+	BackupPath string
+	Applied    []string
+}
+
+// Migrate upgrades the config file at path to CurrentVersion in place,
+// backing up the original first. It returns nil when the file is current.
+func Migrate(path string) (*MigrationResult, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	settings := v.AllSettings()
+	result, err := migrateSettings(settings)
+	if err != nil || result == nil {
+		return nil, err
+	}
+
+	// Back up the original before touching it
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	backupPath := fmt.Sprintf("%s.v%d.bak", path, result.From)
+	if err := os.WriteFile(backupPath, original, 0600); err != nil {
+		return nil, fmt.Errorf("failed to back up config file: %w", err)
+	}
+	result.BackupPath = backupPath
+
+	out := viper.New()
+	if err := out.MergeConfigMap(settings); err != nil {
+		return nil, fmt.Errorf("failed to prepare config: %w", err)
+	}
+	if err := out.WriteConfigAs(path); err != nil {
+		return nil, fmt.Errorf("failed to write migrated config (original kept in %s): %w", backupPath, err)
+	}
+
+	return result, nil
+}
+
+// migrateSettings upgrades raw config settings to CurrentVersion in memory.
+// It returns nil when they are current.
+func migrateSettings(settings map[string]interface{}) (*MigrationResult, error) {
+	version, _ := settings["config_version"].(int)
+	if version > CurrentVersion {
+		return nil, fmt.Errorf("config file version %d is newer than this release supports (%d); upgrade converso", version, CurrentVersion)
+	}
+	if version == CurrentVersion {
+		return nil, nil
+	}
+	if len(migrations) != CurrentVersion {
+		return nil, fmt.Errorf("missing config migrations: have %d, need %d", len(migrations), CurrentVersion)
+	}
+
+	result := &MigrationResult{From: version, To: CurrentVersion}
+	for i := version; i < CurrentVersion; i++ {
+		if err := migrations[i].Apply(settings); err != nil {
+			return nil, fmt.Errorf("failed to migrate config from version %d to %d: %w", i, i+1, err)
+		}
+		result.Applied = append(result.Applied, migrations[i].Description)
+	}
+	settings["config_version"] = CurrentVersion
+	return result, nil
+}
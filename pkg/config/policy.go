@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Policy holds settings enforced by administrators, typically deployed by
+// MDM or configuration management. It is read-only to the CLI and wins over
+// the system config, user config, environment and flags.
+type Policy struct {
+	// Path is the policy file the policy was read from
+	Path string `mapstructure:"-"`
+
+	// DisableTelemetry stops the worker's periodic status reports
+	DisableTelemetry bool `mapstructure:"disable_telemetry"`
+
+	// Pinned endpoints; empty values are not pinned
+	APIEndpoint string `mapstructure:"api_endpoint"`
+	AuthURL     string `mapstructure:"auth_url"`
+	TokenURL    string `mapstructure:"token_url"`
+	JWKSURL     string `mapstructure:"jwks_url"`
+
+	// AllowedPlugins lists the module names that may be installed and
+	// loaded, as names or path.Match patterns; empty allows all
+	AllowedPlugins []string `mapstructure:"allowed_plugins"`
+
+	// RequireSignedPlugins refuses modules without a valid signature by
+	// one of PluginSigningKeys
+	RequireSignedPlugins bool `mapstructure:"require_signed_plugins"`
+	// PluginSigningKeys are base64 Ed25519 public keys trusted to sign
+	// modules
+	PluginSigningKeys []string `mapstructure:"plugin_signing_keys"`
+
+	// Content restricts what may be downloaded
+	Content ContentPolicy `mapstructure:"content"`
+
+	// ReadOnly keeps the CLI from changing its configuration, plugins,
+	// credentials, history, subscriptions and library, for shared machines
+	// such as lab computers and kiosks
+	ReadOnly bool `mapstructure:"read_only"`
+	// AllowedCommands lists the module commands that may run, as
+	// module.command names or path.Match patterns such as youtube.*; empty
+	// allows all
+	AllowedCommands []string `mapstructure:"allowed_commands"`
+}
+
+// ContentPolicy restricts downloads by the metadata the module's info
+// command reports for them, such as for parental control
+type ContentPolicy struct {
+	// BlockAgeRestricted refuses content with an age limit
+	BlockAgeRestricted bool `mapstructure:"block_age_restricted"`
+	// BlockLive refuses live and upcoming streams
+	BlockLive bool `mapstructure:"block_live"`
+	// MaxDuration refuses content running longer; zero allows any length
+	MaxDuration time.Duration `mapstructure:"max_duration"`
+}
+
+// Enabled reports whether the content policy restricts anything
+func (c ContentPolicy) Enabled() bool {
+	return c.BlockAgeRestricted || c.BlockLive || c.MaxDuration > 0
+}
+
+// PolicyPath returns the policy file: /etc/converso/policy.yaml, or
+// %ProgramData%\Converso\policy.yaml on Windows. Unlike the system config it
+// cannot be moved with an environment variable.
+func PolicyPath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "Converso", "policy.yaml")
+	}
+	return "/etc/converso/policy.yaml"
+}
+
+// LoadPolicy reads the policy file. It returns nil without error when there
+// is none; an unreadable or unknown setting is an error, so a broken policy
+// is never silently ignored.
+func LoadPolicy() (*Policy, error) {
+	policyPath := PolicyPath()
+	if _, err := os.Stat(policyPath); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(policyPath)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read policy file %s: %w", policyPath, err)
+	}
+
+	policy := &Policy{Path: policyPath}
+	if err := v.UnmarshalExact(policy); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", policyPath, err)
+	}
+	for _, pattern := range policy.AllowedPlugins {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid allowed_plugins pattern %q in %s: %w", pattern, policyPath, err)
+		}
+	}
+	for _, pattern := range policy.AllowedCommands {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid allowed_commands pattern %q in %s: %w", pattern, policyPath, err)
+		}
+	}
+	if policy.Content.MaxDuration < 0 {
+		return nil, fmt.Errorf("invalid content max_duration %s in %s", policy.Content.MaxDuration, policyPath)
+	}
+	return policy, nil
+}
+
+// PinnedSettings returns the config settings the policy pins
+func (p *Policy) PinnedSettings() map[string]string {
+	pinned := make(map[string]string)
+	if p == nil {
+		return pinned
+	}
+	for key, value := range map[string]string{
+		"api_endpoint": p.APIEndpoint,
+		"auth_url":     p.AuthURL,
+		"token_url":    p.TokenURL,
+		"jwks_url":     p.JWKSURL,
+	} {
+		if value != "" {
+			pinned[key] = value
+		}
+	}
+	return pinned
+}
+
+// TelemetryDisabled reports whether the policy turns telemetry off
+func (p *Policy) TelemetryDisabled() bool {
+	return p != nil && p.DisableTelemetry
+}
+
+// PluginAllowed reports whether a module may be installed and loaded
+func (p *Policy) PluginAllowed(name string) bool {
+	if p == nil || len(p.AllowedPlugins) == 0 {
+		return true
+	}
+	for _, pattern := range p.AllowedPlugins {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandAllowed reports whether a module command may run
+func (p *Policy) CommandAllowed(module, command string) bool {
+	if p == nil || len(p.AllowedCommands) == 0 {
+		return true
+	}
+	for _, pattern := range p.AllowedCommands {
+		if ok, _ := path.Match(pattern, module+"."+command); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadOnlyRequired reports whether the policy puts the CLI in read-only
+// mode
+func (p *Policy) ReadOnlyRequired() bool {
+	return p != nil && p.ReadOnly
+}
+
+// ContentFilter returns the content policy in force, or nil when downloads
+// are not restricted
+func (p *Policy) ContentFilter() *ContentPolicy {
+	if p == nil || !p.Content.Enabled() {
+		return nil
+	}
+	return &p.Content
+}
+
+// SignedPluginsRequired reports whether modules must be signed
+func (p *Policy) SignedPluginsRequired() bool {
+	return p != nil && p.RequireSignedPlugins
+}
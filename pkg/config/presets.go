@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+var (
+	presetNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	bitratePattern    = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[kKmM]?$`)
+	resolutionPattern = regexp.MustCompile(`^([0-9]+x[0-9]+|[0-9]+p)$`)
+)
+
+// ConversionPreset is a named set of media conversion options
+type ConversionPreset struct {
+	Codec      string `mapstructure:"codec"`
+	Bitrate    string `mapstructure:"bitrate"`
+	Resolution string `mapstructure:"resolution"`
+	Container  string `mapstructure:"container"`
+}
+
+// Validate checks that the preset sets at least one option and that the
+// options are well formed
+func (p ConversionPreset) Validate() error {
+	if p.Codec == "" && p.Bitrate == "" && p.Resolution == "" && p.Container == "" {
+		return fmt.Errorf("preset must set at least one of codec, bitrate, resolution or container")
+	}
+	if p.Bitrate != "" && !bitratePattern.MatchString(p.Bitrate) {
+		return fmt.Errorf("invalid bitrate %q, expected a number with optional k or M suffix (e.g. 2500k)", p.Bitrate)
+	}
+	if p.Resolution != "" && !resolutionPattern.MatchString(p.Resolution) {
+		return fmt.Errorf("invalid resolution %q, expected WIDTHxHEIGHT or a height like 720p", p.Resolution)
+	}
+	return nil
+}
+
+// Merge returns a copy of the preset with the non-empty options of other
+// taking precedence
+func (p ConversionPreset) Merge(other ConversionPreset) ConversionPreset {
+	if other.Codec != "" {
+		p.Codec = other.Codec
+	}
+	if other.Bitrate != "" {
+		p.Bitrate = other.Bitrate
+	}
+	if other.Resolution != "" {
+		p.Resolution = other.Resolution
+	}
+	if other.Container != "" {
+		p.Container = other.Container
+	}
+	return p
+}
+
+// Args returns the preset as module arguments, omitting unset options
+func (p ConversionPreset) Args() map[string]interface{} {
+	args := make(map[string]interface{})
+	if p.Codec != "" {
+		args["codec"] = p.Codec
+	}
+	if p.Bitrate != "" {
+		args["bitrate"] = p.Bitrate
+	}
+	if p.Resolution != "" {
+		args["resolution"] = p.Resolution
+	}
+	if p.Container != "" {
+		args["container"] = p.Container
+	}
+	return args
+}
+
+// ValidatePresetName checks that a preset name can be stored as a config key
+func ValidatePresetName(name string) error {
+	if !presetNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid preset name %q, use lowercase letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// Preset returns the named conversion preset
+func (c *Config) Preset(name string) (ConversionPreset, error) {
+	preset, ok := c.Presets[name]
+	if !ok {
+		return ConversionPreset{}, fmt.Errorf("preset %s not found. Run 'converso convert preset list' to see available presets", name)
+	}
+	return preset, nil
+}
+
+// PresetNames returns the names of all configured presets in sorted order
+func (c *Config) PresetNames() []string {
+	names := make([]string, 0, len(c.Presets))
+	for name := range c.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// presetSettings converts the presets into the map written to the config
+// file. Presets of the project config are left out unless they were
+// changed, so saving keeps the user presets they shadow.
+func (c *Config) presetSettings() map[string]interface{} {
+	settings := make(map[string]interface{}, len(c.Presets))
+	for name, preset := range c.Presets {
+		settings[name] = preset.Args()
+	}
+	if c.Project == nil || c.Project.shadowed == nil {
+		return settings
+	}
+	for name, preset := range c.Project.Presets {
+		current, ok := c.Presets[name]
+		if !ok || current != preset {
+			continue
+		}
+		delete(settings, name)
+		if user, ok := c.Project.shadowed[name]; ok {
+			settings[name] = user.Args()
+		}
+	}
+	return settings
+}
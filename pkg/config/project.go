@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/interpolate"
+	"github.com/spf13/viper"
+)
+
+// ProjectConfigName is the file name of project-local configuration
+const ProjectConfigName = ".converso.yaml"
+
+// projectKeys are the settings a project config may set. Endpoints and
+// credentials stay out of reach of files that come with a checkout.
+var projectKeys = []string{"output_dir", "presets", "profile"}
+
+// ProjectConfig is a .converso.yaml found in the current directory or one
+// of its parents, overriding the output directory, presets and profile
+// for that project
+type ProjectConfig struct {
+	// Path is the project config file
+	Path      string                      `mapstructure:"-"`
+	OutputDir string                      `mapstructure:"output_dir"`
+	Presets   map[string]ConversionPreset `mapstructure:"presets"`
+	Profile   string                      `mapstructure:"profile"`
+
+	// shadowed are the user presets replaced by project presets
+	shadowed map[string]ConversionPreset
+}
+
+// FindProjectConfig returns the nearest .converso.yaml in dir or one of
+// its parents, or "" when there is none. CONVERSO_PROJECT_CONFIG names the
+// file instead; set to "off" it disables project config.
+func FindProjectConfig(dir string) string {
+	if path := os.Getenv("CONVERSO_PROJECT_CONFIG"); path != "" {
+		if path == "off" {
+			return ""
+		}
+		return path
+	}
+
+	for {
+		path := filepath.Join(dir, ProjectConfigName)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadProjectConfig reads the project config at path
+func loadProjectConfig(path string) (*ProjectConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read project config %s: %w", path, err)
+	}
+
+	for key := range v.AllSettings() {
+		if !containsString(projectKeys, key) {
+			return nil, fmt.Errorf("%s sets %q; project config can only set %s", path, key, strings.Join(projectKeys, ", "))
+		}
+	}
+
+	project := &ProjectConfig{Path: path}
+	if err := v.Unmarshal(project); err != nil {
+		return nil, fmt.Errorf("invalid project config %s: %w", path, err)
+	}
+
+	names := make([]string, 0, len(project.Presets))
+	for name := range project.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := ValidatePresetName(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := project.Presets[name].Validate(); err != nil {
+			return nil, fmt.Errorf("%s: preset %s: %w", path, name, err)
+		}
+	}
+
+	// A relative output directory belongs to the project
+	if project.OutputDir != "" {
+		dir, err := interpolate.Expand(project.OutputDir, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid output_dir: %w", path, err)
+		}
+		if filepath.IsAbs(dir) || strings.HasPrefix(dir, "~") {
+			dir, err = ExpandPath(dir)
+		} else {
+			dir = filepath.Join(filepath.Dir(absPath(path)), dir)
+		}
+		if err != nil {
+			return nil, err
+		}
+		project.OutputDir = dir
+	}
+	return project, nil
+}
+
+// applyProject layers the project config over the loaded configuration.
+// Settings locked by the system config or set in the environment are left
+// alone.
+func (c *Config) applyProject(project *ProjectConfig, locked []string) {
+	overridden := func(key string) bool {
+		return containsString(locked, key) || os.Getenv("CONVERSO_"+strings.ToUpper(key)) != ""
+	}
+
+	if project.OutputDir != "" && !overridden("output_dir") {
+		c.OutputDir = project.OutputDir
+	}
+
+	if len(project.Presets) > 0 && !overridden("presets") {
+		if c.Presets == nil {
+			c.Presets = make(map[string]ConversionPreset)
+		}
+		project.shadowed = make(map[string]ConversionPreset)
+		for name, preset := range project.Presets {
+			if user, ok := c.Presets[name]; ok {
+				project.shadowed[name] = user
+			}
+			c.Presets[name] = preset
+		}
+	}
+
+	if project.Profile != "" && !overridden("profile") {
+		c.ConversionProfile = project.Profile
+	}
+	c.Project = project
+}
+
+// sets reports whether the project config sets a setting
+func (p *ProjectConfig) sets(key string) bool {
+	switch {
+	case key == "output_dir":
+		return p.OutputDir != ""
+	case key == "profile":
+		return p.Profile != ""
+	case key == "presets":
+		return len(p.Presets) > 0
+	case strings.HasPrefix(key, "presets."):
+		_, ok := p.Presets[strings.SplitN(strings.TrimPrefix(key, "presets."), ".", 2)[0]]
+		return ok
+	}
+	return false
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
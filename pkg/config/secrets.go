@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Secret reads a secret from the file named by CONVERSO_<NAME>_FILE, as
+// mounted by Docker and Kubernetes secrets, or else from CONVERSO_<NAME>.
+// It returns an empty string when neither is set.
+func Secret(name string) (string, error) {
+	env := "CONVERSO_" + strings.ToUpper(name)
+
+	if path := os.Getenv(env + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s_FILE: %w", env, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	return os.Getenv(env), nil
+}
+
+// credentialSecrets are the credentials read with Secret
+var credentialSecrets = []string{
+	"access_token", "refresh_token", "device_token", "remote_token",
+	"worker_control_token", "backup_passphrase", "client_secret",
+	"mediaserver_token",
+}
+
+// SensitiveValues returns the values to mask in echoed commands and logs:
+// the credentials given through the environment, the client secret and
+// the environment variables matching sensitive_env
+func (c *Config) SensitiveValues() []string {
+	var values []string
+	for _, name := range credentialSecrets {
+		if value, err := Secret(name); err == nil && value != "" {
+			values = append(values, value)
+		}
+	}
+	if c.ClientSecret != "" {
+		values = append(values, c.ClientSecret)
+	}
+	if c.Integrations.MediaServer.Token != "" {
+		values = append(values, c.Integrations.MediaServer.Token)
+	}
+
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if value != "" && c.SensitiveEnvVar(name) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// SensitiveEnvVar reports whether an environment variable matches one of
+// the sensitive_env patterns, ignoring case
+func (c *Config) SensitiveEnvVar(name string) bool {
+	name = strings.ToUpper(name)
+	for _, pattern := range c.SensitiveEnv {
+		if ok, _ := path.Match(strings.ToUpper(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
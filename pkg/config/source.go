@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Source describes where the value of a setting, given by its dotted key
+// such as "timeouts.total", came from. Settings come from the policy, a
+// locked system setting, the environment, the project config, the user
+// config file, the system config or a file it includes, or the built-in
+// defaults, in that order of precedence. Command-line flags are not seen
+// here.
+func (c *Config) Source(key string) string {
+	if _, ok := c.Policy.PinnedSettings()[key]; ok {
+		return "policy"
+	}
+
+	var layers configLayers
+	if c.layers != nil {
+		layers = *c.layers
+	}
+	if _, ok := lookupSetting(layers.System, key); ok && containsString(layers.Locked, key) {
+		return "system config (locked)"
+	}
+	if name := "CONVERSO_" + strings.ToUpper(key); os.Getenv(name) != "" {
+		return fmt.Sprintf("environment (%s)", name)
+	}
+	if c.Project != nil && c.Project.sets(key) {
+		return fmt.Sprintf("project config (%s)", c.Project.Path)
+	}
+	if _, ok := lookupSetting(layers.User, key); ok {
+		return fmt.Sprintf("config file (%s)", layers.Files[len(layers.Files)-1])
+	}
+	if _, ok := lookupSetting(layers.Base, key); ok {
+		return "system config or include"
+	}
+	return "default"
+}
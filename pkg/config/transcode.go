@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TranscodeConfig holds the rules the worker applies to finished downloads
+type TranscodeConfig struct {
+	Rules []TranscodeRule `mapstructure:"rules"`
+}
+
+// TranscodeRule converts downloads matching its conditions once they
+// finish, with a named preset or explicit conversion options
+type TranscodeRule struct {
+	When TranscodeMatch `mapstructure:"when"`
+	// Preset names the conversion preset to apply; To overrides its options
+	Preset string           `mapstructure:"preset"`
+	To     ConversionPreset `mapstructure:"to"`
+}
+
+// TranscodeMatch are the conditions of a rule; empty ones match anything
+type TranscodeMatch struct {
+	// Codec is the video codec family of the download, such as av1, vp9,
+	// h264 or h265
+	Codec string `mapstructure:"codec"`
+	// Container is the extension of the downloaded file, such as webm
+	Container string `mapstructure:"container"`
+	// Device is the registered device the download ran on
+	Device string `mapstructure:"device"`
+	// Module is the module that downloaded the file
+	Module string `mapstructure:"module"`
+}
+
+// Match reports whether a download matches the conditions
+func (m TranscodeMatch) Match(codec, container, device, module string) bool {
+	return matchCondition(m.Codec, CodecFamily(codec)) &&
+		matchCondition(m.Container, strings.TrimPrefix(container, ".")) &&
+		matchCondition(m.Device, device) &&
+		matchCondition(m.Module, module)
+}
+
+// matchCondition compares a condition with a value; empty conditions match
+// anything
+func matchCondition(condition, value string) bool {
+	return condition == "" || strings.EqualFold(condition, value)
+}
+
+// Options returns the conversion options of the rule
+func (r TranscodeRule) Options(presets map[string]ConversionPreset) (ConversionPreset, error) {
+	var options ConversionPreset
+	if r.Preset != "" {
+		preset, ok := presets[r.Preset]
+		if !ok {
+			return ConversionPreset{}, fmt.Errorf("preset %s not found", r.Preset)
+		}
+		options = preset
+	}
+	options = options.Merge(r.To)
+	if err := options.Validate(); err != nil {
+		return ConversionPreset{}, err
+	}
+	return options, nil
+}
+
+// Validate checks that every rule converts to valid options
+func (c TranscodeConfig) Validate(presets map[string]ConversionPreset) error {
+	for i, rule := range c.Rules {
+		if _, err := rule.Options(presets); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Match returns the first rule matching a download, or nil
+func (c TranscodeConfig) Match(codec, container, device, module string) *TranscodeRule {
+	for i := range c.Rules {
+		if c.Rules[i].When.Match(codec, container, device, module) {
+			return &c.Rules[i]
+		}
+	}
+	return nil
+}
+
+// CodecFamily returns the family of a codec as modules report it, so rules
+// can say av1 for av01.0.08M.08 or h264 for avc1.64001F
+func CodecFamily(codec string) string {
+	codec = strings.ToLower(codec)
+	if i := strings.IndexByte(codec, '.'); i >= 0 {
+		codec = codec[:i]
+	}
+	switch codec {
+	case "av01":
+		return "av1"
+	case "avc1", "avc3", "avc":
+		return "h264"
+	case "hev1", "hvc1", "hevc":
+		return "h265"
+	case "vp09":
+		return "vp9"
+	case "vp08":
+		return "vp8"
+	}
+	return codec
+}
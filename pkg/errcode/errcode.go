@@ -0,0 +1,177 @@
+// Package errcode sorts the errors commands fail with into categories,
+// each with its own exit code, so wrapper scripts can branch on the kind
+// of failure, and writes them as JSON envelopes.
+package errcode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+)
+
+// Category is a kind of failure
+type Category string
+
+// Failure categories
+const (
+	General        Category = "error"
+	PartialFailure Category = "partial_failure"
+	GeoBlocked     Category = "geo_blocked"
+	AuthRequired   Category = "auth_required"
+	ModuleNotFound Category = "module_not_found"
+	Timeout        Category = "timeout"
+	Network        Category = "network"
+	Interrupted    Category = "interrupted"
+)
+
+// Exit codes of the categories
+const (
+	ExitSuccess        = 0
+	ExitFailure        = 1
+	ExitPartialFailure = 3
+	ExitGeoBlocked     = 4
+	ExitAuthRequired   = 5
+	ExitModuleNotFound = 6
+	ExitTimeout        = 7
+	ExitNetwork        = 8
+	ExitInterrupted    = 130
+)
+
+var exitCodes = map[Category]int{
+	General:        ExitFailure,
+	PartialFailure: ExitPartialFailure,
+	GeoBlocked:     ExitGeoBlocked,
+	AuthRequired:   ExitAuthRequired,
+	ModuleNotFound: ExitModuleNotFound,
+	Timeout:        ExitTimeout,
+	Network:        ExitNetwork,
+	Interrupted:    ExitInterrupted,
+}
+
+// ExitCode returns the exit code of the category
+func (c Category) ExitCode() int {
+	if code, ok := exitCodes[c]; ok {
+		return code
+	}
+	return ExitFailure
+}
+
+// ForExitCode returns the category an exit code stands for, General for
+// codes of no category
+func ForExitCode(code int) Category {
+	for category, categoryCode := range exitCodes {
+		if categoryCode == code {
+			return category
+		}
+	}
+	return General
+}
+
+// Error marks an error with the category it fails in
+type Error struct {
+	Category Category
+	Err      error
+}
+
+// New marks err with a category
+func New(category Category, err error) error {
+	return &Error{Category: category, Err: err}
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Classify returns the category of an error: the one it was marked with,
+// else the one its bridge error code or network error implies, else
+// General
+func Classify(err error) Category {
+	var marked *Error
+	if errors.As(err, &marked) {
+		return marked.Category
+	}
+
+	var bridgeErr *bridge.BridgeError
+	if errors.As(err, &bridgeErr) {
+		switch bridgeErr.Code {
+		case "MODULE_NOT_FOUND":
+			return ModuleNotFound
+		case "MODULE_TIMEOUT", "MODULE_IDLE_TIMEOUT":
+			return Timeout
+		case "MODULE_CANCELLED":
+			return Interrupted
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Timeout
+	}
+
+	// Failed connections, DNS lookups and HTTP requests; not net.Error,
+	// which file errors implement too
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	var urlErr *url.Error
+	switch {
+	case errors.As(err, &opErr):
+		return networkCategory(opErr)
+	case errors.As(err, &dnsErr):
+		return networkCategory(dnsErr)
+	case errors.As(err, &urlErr):
+		return networkCategory(urlErr)
+	}
+	return General
+}
+
+// networkCategory tells network timeouts from other network failures
+func networkCategory(err net.Error) Category {
+	if err.Timeout() {
+		return Timeout
+	}
+	return Network
+}
+
+// Envelope is an error as written for scripts
+type Envelope struct {
+	Error Details `json:"error"`
+}
+
+// Details describe a failure
+type Details struct {
+	Category Category `json:"category"`
+	ExitCode int      `json:"exit_code"`
+	Message  string   `json:"message"`
+	// Code is the bridge error code, such as MODULE_TIMEOUT, when a module
+	// call failed
+	Code string `json:"code,omitempty"`
+}
+
+// NewEnvelope describes err, which exits with exitCode
+func NewEnvelope(err error, exitCode int) Envelope {
+	details := Details{
+		Category: ForExitCode(exitCode),
+		ExitCode: exitCode,
+		Message:  err.Error(),
+	}
+	var bridgeErr *bridge.BridgeError
+	if errors.As(err, &bridgeErr) {
+		details.Code = bridgeErr.Code
+	}
+	return Envelope{Error: details}
+}
+
+// WriteJSON writes the envelope of err, which exits with exitCode, as a
+// single line of JSON
+func WriteJSON(w io.Writer, err error, exitCode int) error {
+	return json.NewEncoder(w).Encode(NewEnvelope(err, exitCode))
+}
@@ -0,0 +1,233 @@
+// Package estimate predicts what a download or conversion costs before it
+// runs: the bytes downloaded and written, how long that takes at the
+// throughput seen before, and how much of the free disk space it uses.
+package estimate
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// Item is the estimate for a single URL or file
+type Item struct {
+	Source string `json:"source"`
+	Title  string `json:"title,omitempty"`
+	// Format is the format a download picks, "+"-joined when merged
+	Format        string  `json:"format,omitempty"`
+	MediaSeconds  float64 `json:"media_seconds,omitempty"`
+	DownloadBytes int64   `json:"download_bytes"`
+	OutputBytes   int64   `json:"output_bytes"`
+	// Error is why the item could not be estimated
+	Error string `json:"error,omitempty"`
+}
+
+// Estimate is the estimated cost of a command. Sizes and durations that
+// could not be estimated are zero.
+type Estimate struct {
+	Module        string `json:"module"`
+	Command       string `json:"command"`
+	Items         []Item `json:"items"`
+	DownloadBytes int64  `json:"download_bytes"`
+	OutputBytes   int64  `json:"output_bytes"`
+	// Throughput is the module's historical output rate in bytes per
+	// second, and Seconds how long the output takes to produce at it
+	Throughput float64 `json:"throughput"`
+	Seconds    float64 `json:"seconds"`
+	Disk       *Disk   `json:"disk,omitempty"`
+}
+
+// Disk is the free space on the filesystem of the output directory
+type Disk struct {
+	Dir  string `json:"dir"`
+	Free int64  `json:"free"`
+	// Share is the part of the free space the output takes, from 0 to 1
+	// and above when it does not fit
+	Share float64 `json:"share"`
+}
+
+// Fits reports whether the output fits in the free space
+func (d *Disk) Fits() bool {
+	return d.Share <= 1
+}
+
+// Add adds an item to the totals
+func (e *Estimate) Add(item Item) {
+	e.Items = append(e.Items, item)
+	e.DownloadBytes += item.DownloadBytes
+	e.OutputBytes += item.OutputBytes
+}
+
+// Failed returns the number of items that could not be estimated
+func (e *Estimate) Failed() int {
+	failed := 0
+	for _, item := range e.Items {
+		if item.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Finish works out the duration at throughput, in bytes per second, and
+// the share of the free space in dir the output takes
+func (e *Estimate) Finish(throughput float64, dir string) error {
+	e.Throughput = throughput
+	if throughput > 0 {
+		e.Seconds = float64(e.OutputBytes) / throughput
+	}
+
+	free, err := FreeSpace(dir)
+	if err != nil {
+		return err
+	}
+	e.Disk = &Disk{Dir: dir, Free: free}
+	if free > 0 {
+		e.Disk.Share = float64(e.OutputBytes) / float64(free)
+	}
+	return nil
+}
+
+// Duration returns how long the command is estimated to take
+func (e *Estimate) Duration() time.Duration {
+	return time.Duration(e.Seconds * float64(time.Second))
+}
+
+// FreeSpace returns the bytes available on the filesystem holding dir. A
+// directory that does not exist yet is looked up by its nearest parent.
+func FreeSpace(dir string) (int64, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	usage, err := disk.Usage(dir)
+	if err != nil {
+		return 0, err
+	}
+	return int64(usage.Free), nil
+}
+
+// SelectFormats picks the formats a download mode fetches, as yt-dlp does:
+// the best audio-only format for audio, the best video-only format for
+// video, the best format with both for progressive, and for merge and best
+// the best video and audio merged. A format ID picks that format. It
+// returns the IDs joined with "+" and their total size, zero when unknown.
+func SelectFormats(formats []bridge.Format, mode, formatID string) (string, int64) {
+	if formatID != "" {
+		for _, f := range formats {
+			if f.ID == formatID {
+				return f.ID, f.FileSize
+			}
+		}
+		return formatID, 0
+	}
+
+	var video, audio, both *bridge.Format
+	for i := range formats {
+		f := &formats[i]
+		switch {
+		case f.HasVideo() && f.HasAudio():
+			if both == nil || betterVideo(f, both) {
+				both = f
+			}
+		case f.HasVideo():
+			if video == nil || betterVideo(f, video) {
+				video = f
+			}
+		case f.HasAudio():
+			if audio == nil || betterAudio(f, audio) {
+				audio = f
+			}
+		}
+	}
+
+	var picked []*bridge.Format
+	switch mode {
+	case "audio":
+		picked = []*bridge.Format{audio}
+	case "video":
+		picked = []*bridge.Format{video}
+	case "progressive":
+		picked = []*bridge.Format{both}
+	default:
+		picked = []*bridge.Format{video, audio}
+		if video == nil || audio == nil {
+			picked = []*bridge.Format{both}
+		}
+	}
+
+	var ids []string
+	var size int64
+	for _, f := range picked {
+		if f == nil {
+			return "", 0
+		}
+		ids = append(ids, f.ID)
+		size += f.FileSize
+	}
+	return strings.Join(ids, "+"), size
+}
+
+// betterVideo reports whether a has better video than b
+func betterVideo(a, b *bridge.Format) bool {
+	if a.Height != b.Height {
+		return a.Height > b.Height
+	}
+	if a.FPS != b.FPS {
+		return a.FPS > b.FPS
+	}
+	return a.FileSize > b.FileSize
+}
+
+// betterAudio reports whether a has better audio than b
+func betterAudio(a, b *bridge.Format) bool {
+	if a.AudioBitrate != b.AudioBitrate {
+		return a.AudioBitrate > b.AudioBitrate
+	}
+	return a.FileSize > b.FileSize
+}
+
+// ConvertedSize estimates the size of media lasting duration converted at
+// bitrate, such as "2500k". Without a bitrate or duration the output is
+// taken to be as large as the input.
+func ConvertedSize(duration time.Duration, bitrate string, inputBytes int64) int64 {
+	bitsPerSecond := ParseBitrate(bitrate)
+	if bitsPerSecond <= 0 || duration <= 0 {
+		return inputBytes
+	}
+	return int64(duration.Seconds() * bitsPerSecond / 8)
+}
+
+// ParseBitrate parses a bitrate such as "2500k", "2.5M" or "128000" into
+// bits per second. It returns 0 for bitrates it cannot read.
+func ParseBitrate(s string) float64 {
+	s = strings.TrimSpace(s)
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"), strings.HasSuffix(s, "K"):
+		multiplier, s = 1e3, s[:len(s)-1]
+	case strings.HasSuffix(s, "m"), strings.HasSuffix(s, "M"):
+		multiplier, s = 1e6, s[:len(s)-1]
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n * multiplier
+}
@@ -0,0 +1,161 @@
+// Package events is the bus that subsystems publish what happened on, such
+// as downloads and job state changes. Features that react to them, like
+// the download history or the log, subscribe instead of being called from
+// command code.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// Type identifies what an event reports
+type Type string
+
+const (
+	// DownloadStarted is published before a module starts a download, with
+	// a Download payload
+	DownloadStarted Type = "download.started"
+	// DownloadCompleted is published when a download finished, successfully
+	// or not, with a Download payload
+	DownloadCompleted Type = "download.completed"
+	// JobStateChanged is published when a worker job changes status, with a
+	// Job payload
+	JobStateChanged Type = "job.state_changed"
+	// PluginInstalled is published when a module is installed, with a
+	// Plugin payload
+	PluginInstalled Type = "plugin.installed"
+	// AuthRefreshed is published when the access token was refreshed, with
+	// an Auth payload
+	AuthRefreshed Type = "auth.refreshed"
+)
+
+// Event is something that happened during a run
+type Event struct {
+	Type    Type        `json:"type"`
+	Time    time.Time   `json:"time"`
+	RunID   string      `json:"run_id"`
+	Payload interface{} `json:"payload"`
+}
+
+// Download is the payload of download events
+type Download struct {
+	Module  string `json:"module"`
+	Command string `json:"command"`
+	URL     string `json:"url"`
+	// Source is the mirror downloaded from when URL failed, set on
+	// DownloadCompleted
+	Source string `json:"source,omitempty"`
+	// Response and Err are the outcome, set on DownloadCompleted
+	Response *bridge.ModuleResponse `json:"response,omitempty"`
+	Err      error                  `json:"-"`
+}
+
+// Job is the payload of JobStateChanged
+type Job struct {
+	ID      string `json:"id"`
+	Module  string `json:"module"`
+	Command string `json:"command"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Plugin is the payload of PluginInstalled
+type Plugin struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Path    string `json:"path"`
+}
+
+// Auth is the payload of AuthRefreshed
+type Auth struct {
+	DeviceID  string    `json:"device_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Handler receives the events it subscribed to. Handlers run in the
+// publishing goroutine, so they must not block for long.
+type Handler func(Event)
+
+// subscription is a handler and the event types it wants, all when empty
+type subscription struct {
+	id      int
+	handler Handler
+	types   map[Type]bool
+}
+
+// Bus delivers published events to their subscribers
+type Bus struct {
+	mu     sync.RWMutex
+	subs   []subscription
+	nextID int
+}
+
+// NewBus creates a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers handler for events of the given types, or for all
+// events when none are given. The returned function unsubscribes it.
+func (b *Bus) Subscribe(handler Handler, types ...Type) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := subscription{id: b.nextID, handler: handler}
+	b.nextID++
+	if len(types) > 0 {
+		sub.types = make(map[Type]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+	b.subs = append(b.subs, sub)
+
+	return func() { b.unsubscribe(sub.id) }
+}
+
+// unsubscribe removes the subscription with the given id
+func (b *Bus) unsubscribe(id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, sub := range b.subs {
+		if sub.id == id {
+			b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Publish delivers an event to its subscribers in the order they
+// subscribed, and returns once all have handled it
+func (b *Bus) Publish(t Type, payload interface{}) {
+	event := Event{Type: t, Time: time.Now(), RunID: telemetry.RunID(), Payload: payload}
+
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		if sub.types == nil || sub.types[t] {
+			sub.handler(event)
+		}
+	}
+}
+
+// defaultBus is the bus of the process
+var defaultBus = NewBus()
+
+// Subscribe registers handler on the process bus
+func Subscribe(handler Handler, types ...Type) func() {
+	return defaultBus.Subscribe(handler, types...)
+}
+
+// Publish delivers an event on the process bus
+func Publish(t Type, payload interface{}) {
+	defaultBus.Publish(t, payload)
+}
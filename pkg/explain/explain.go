@@ -0,0 +1,109 @@
+// Package explain records the module calls a CLI invocation makes, with
+// the requests they were sent, so that --explain can show how a failed
+// invocation got to its error. Nothing is recorded until Enable is called.
+package explain
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/redact"
+)
+
+// Call is a module command run during the invocation
+type Call struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	// Path is the directory the module was loaded from
+	Path    string `json:"path"`
+	Command string `json:"command"`
+	// Request is the request the module was sent, with credentials masked
+	Request  json.RawMessage `json:"request"`
+	Duration time.Duration   `json:"duration_ns"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// trace holds what is recorded
+var trace struct {
+	mu      sync.Mutex
+	enabled bool
+	masker  *redact.Masker
+	calls   []Call
+}
+
+// Enable starts recording. Secret values known to masker are masked in
+// what is recorded.
+func Enable(masker *redact.Masker) {
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	trace.enabled = true
+	trace.masker = masker
+}
+
+// Enabled reports whether calls are recorded
+func Enabled() bool {
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	return trace.enabled
+}
+
+// Record adds a module call, when recording
+func Record(call Call) {
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	if !trace.enabled {
+		return
+	}
+	call.Error = trace.masker.String(call.Error)
+	trace.calls = append(trace.calls, call)
+}
+
+// Calls returns the module calls recorded so far
+func Calls() []Call {
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	return append([]Call(nil), trace.calls...)
+}
+
+// Mask masks the secret values known to the recording in s
+func Mask(s string) string {
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	return trace.masker.String(s)
+}
+
+// Request encodes a module request for a Call. Tokens, secrets and cookie
+// values are masked, and so are secret values passed as arguments.
+func Request(req *bridge.ModuleRequest) json.RawMessage {
+	masked := *req
+	masked.AuthToken = maskValue(req.AuthToken)
+	masked.DeviceToken = maskValue(req.DeviceToken)
+	if req.Secrets != nil {
+		masked.Secrets = make(map[string]string, len(req.Secrets))
+		for name, value := range req.Secrets {
+			masked.Secrets[name] = maskValue(value)
+		}
+	}
+	masked.Cookies = make([]bridge.Cookie, len(req.Cookies))
+	for i, cookie := range req.Cookies {
+		cookie.Value = maskValue(cookie.Value)
+		masked.Cookies[i] = cookie
+	}
+
+	data, err := json.MarshalIndent(masked, "", "  ")
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	return json.RawMessage(Mask(string(data)))
+}
+
+// maskValue masks a credential, keeping empty values empty so their
+// absence shows
+func maskValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redact.Mask
+}
@@ -0,0 +1,102 @@
+package fileutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// lockRetryInterval is how often a held lock is tried again
+const lockRetryInterval = 50 * time.Millisecond
+
+// WriteAtomic writes data to path so that readers see either the old or the
+// new contents, never a partial file. The data is written to a temporary
+// file in the same directory and renamed over path.
+func WriteAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	// Remove the temporary file unless the rename succeeded
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	committed = true
+	return nil
+}
+
+// FileLock is an advisory lock shared between processes, held with an OS
+// lock on an open lock file. The OS releases it when the process holding it
+// exits, so a crashed process never leaves it behind.
+type FileLock struct {
+	file *os.File
+}
+
+// Lock acquires the lock file at path, waiting up to timeout for another
+// process to release it
+func Lock(path string, timeout time.Duration) (*FileLock, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		locked, err := tryLock(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			// Record the holder for whoever looks at the file
+			file.Truncate(0)
+			file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+			return &FileLock{file: file}, nil
+		}
+
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("timed out waiting for lock %s", path)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// Unlock releases the lock. The lock file is kept: removing it would let a
+// process that opened it before the removal lock a file others no longer
+// see.
+func (l *FileLock) Unlock() error {
+	err := unlock(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+//go:build windows
+
+package fileutil
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x00000001
+	lockfileExclusiveLock   = 0x00000002
+	// errorLockViolation is ERROR_LOCK_VIOLATION
+	errorLockViolation = 33
+)
+
+// tryLock locks the first byte of the file with LockFileEx without waiting,
+// reporting whether another process holds it
+func tryLock(file *os.File) (bool, error) {
+	var overlapped syscall.Overlapped
+	ret, _, err := procLockFileEx.Call(file.Fd(),
+		lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0,
+		uintptr(unsafe.Pointer(&overlapped)))
+	if ret != 0 {
+		return true, nil
+	}
+	if errors.Is(err, syscall.Errno(errorLockViolation)) {
+		return false, nil
+	}
+	return false, err
+}
+
+// unlock releases the lock on the first byte of the file
+func unlock(file *os.File) error {
+	var overlapped syscall.Overlapped
+	ret, _, err := procUnlockFileEx.Call(file.Fd(), 0, 1, 0,
+		uintptr(unsafe.Pointer(&overlapped)))
+	if ret == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build boringcrypto
+
+package fips
+
+import (
+	"crypto/boring"
+
+	// Restrict crypto/tls to FIPS-approved settings process-wide
+	_ "crypto/tls/fipsonly"
+)
+
+// validatedModule reports whether BoringCrypto is in use
+func validatedModule() bool {
+	return boring.Enabled()
+}
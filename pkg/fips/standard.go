@@ -0,0 +1,8 @@
+//go:build !boringcrypto
+
+package fips
+
+// validatedModule reports false: standard builds use Go's own crypto
+func validatedModule() bool {
+	return false
+}
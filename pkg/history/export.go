@@ -0,0 +1,205 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Supported export formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Fields lists the exportable entry fields in their default order
+var Fields = []string{"id", "created_at", "module", "command", "url", "title", "file_path", "file_size", "status", "error", "run_id", "source"}
+
+// ParseFields validates a comma-separated field list, returning all fields
+// when the list is empty
+func ParseFields(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return Fields, nil
+	}
+
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if !isField(field) {
+			return nil, fmt.Errorf("unknown history field %q (available: %s)", field, strings.Join(Fields, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Export writes entries in the given format, limited to fields
+func Export(w io.Writer, entries []Entry, format string, fields []string) error {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(fields); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+		for _, e := range entries {
+			record := make([]string, len(fields))
+			for i, field := range fields {
+				record[i] = e.Field(field)
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("failed to write CSV: %w", err)
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+
+	case FormatJSON:
+		rows := make([]map[string]string, 0, len(entries))
+		for _, e := range entries {
+			row := make(map[string]string, len(fields))
+			for _, field := range fields {
+				row[field] = e.Field(field)
+			}
+			rows = append(rows, row)
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+
+	default:
+		return fmt.Errorf("unsupported format: %s (use csv or json)", format)
+	}
+}
+
+// Import reads entries exported by Export. Columns that are missing are
+// left empty; unknown columns are ignored.
+func Import(r io.Reader, format string) ([]Entry, error) {
+	var rows []map[string]string
+
+	switch format {
+	case FormatCSV:
+		records, err := csv.NewReader(r).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV: %w", err)
+		}
+		if len(records) == 0 {
+			return nil, nil
+		}
+		header := records[0]
+		for _, record := range records[1:] {
+			row := make(map[string]string, len(header))
+			for i, field := range header {
+				if i < len(record) {
+					row[strings.TrimSpace(field)] = record[i]
+				}
+			}
+			rows = append(rows, row)
+		}
+
+	case FormatJSON:
+		if err := json.NewDecoder(r).Decode(&rows); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported format: %s (use csv or json)", format)
+	}
+
+	entries := make([]Entry, 0, len(rows))
+	for i, row := range rows {
+		var e Entry
+		for field, value := range row {
+			if err := e.SetField(field, value); err != nil {
+				return nil, fmt.Errorf("row %d: %w", i+1, err)
+			}
+		}
+		if e.URL == "" && e.FilePath == "" {
+			return nil, fmt.Errorf("row %d: url or file_path is required", i+1)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Field returns the string value of a named field
+func (e Entry) Field(name string) string {
+	switch name {
+	case "id":
+		return e.ID
+	case "created_at":
+		return e.CreatedAt.Format(time.RFC3339)
+	case "module":
+		return e.Module
+	case "command":
+		return e.Command
+	case "url":
+		return e.URL
+	case "title":
+		return e.Title
+	case "file_path":
+		return e.FilePath
+	case "file_size":
+		return e.FileSize
+	case "status":
+		return e.Status
+	case "error":
+		return e.Error
+	case "run_id":
+		return e.RunID
+	case "source":
+		return e.Source
+	}
+	return ""
+}
+
+// SetField sets a named field from its string value. Unknown fields are
+// ignored.
+func (e *Entry) SetField(name, value string) error {
+	switch name {
+	case "id":
+		e.ID = value
+	case "created_at":
+		if value == "" {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("invalid created_at %q: %w", value, err)
+		}
+		e.CreatedAt = t
+	case "module":
+		e.Module = value
+	case "command":
+		e.Command = value
+	case "url":
+		e.URL = value
+	case "title":
+		e.Title = value
+	case "file_path":
+		e.FilePath = value
+	case "file_size":
+		e.FileSize = value
+	case "status":
+		e.Status = value
+	case "error":
+		e.Error = value
+	case "run_id":
+		e.RunID = value
+	case "source":
+		e.Source = value
+	}
+	return nil
+}
+
+// isField reports whether name is an exportable field
+func isField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+)
+
+// Entry statuses
+const (
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Entry is a single download recorded in the history
+type Entry struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Module    string    `json:"module"`
+	Command   string    `json:"command"`
+	URL       string    `json:"url"`
+	Title     string    `json:"title,omitempty"`
+	FilePath  string    `json:"file_path,omitempty"`
+	FileSize  string    `json:"file_size,omitempty"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	// RunID is the CLI invocation that recorded the entry
+	RunID string `json:"run_id,omitempty"`
+	// Source is the mirror the file came from when URL failed
+	Source string `json:"source,omitempty"`
+}
+
+// Filter selects history entries by creation time. Zero bounds are open.
+type Filter struct {
+	Since time.Time
+	Until time.Time
+}
+
+// Match reports whether an entry falls inside the filter
+func (f Filter) Match(e Entry) bool {
+	if !f.Since.IsZero() && e.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.CreatedAt.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Store is an append-only history file with one JSON entry per line
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultPath returns the location of the download history
+func DefaultPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "history.jsonl")
+}
+
+// NewStore creates a history store backed by path
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Append records new entries, filling in missing IDs and timestamps
+func (s *Store) Append(entries ...Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for i := range entries {
+		if entries[i].CreatedAt.IsZero() {
+			entries[i].CreatedAt = time.Now()
+		}
+		if entries[i].ID == "" {
+			entries[i].ID = newID(entries[i].CreatedAt, i)
+		}
+		if err := encoder.Encode(entries[i]); err != nil {
+			return fmt.Errorf("failed to write history: %w", err)
+		}
+	}
+	return nil
+}
+
+// Entries returns the entries matching filter, oldest first
+func (s *Store) Entries(filter Filter) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse history line %d: %w", line, err)
+		}
+		if filter.Match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// Import appends entries whose IDs are not already in the history and
+// returns how many were added
+func (s *Store) Import(entries []Entry) (int, error) {
+	existing, err := s.Entries(Filter{})
+	if err != nil {
+		return 0, err
+	}
+
+	seen := make(map[string]bool, len(existing))
+	for _, e := range existing {
+		seen[e.ID] = true
+	}
+
+	var added []Entry
+	for _, e := range entries {
+		if e.ID != "" && seen[e.ID] {
+			continue
+		}
+		if e.Status == "" {
+			e.Status = StatusCompleted
+		}
+		added = append(added, e)
+		seen[e.ID] = true
+	}
+
+	if len(added) == 0 {
+		return 0, nil
+	}
+	if err := s.Append(added...); err != nil {
+		return 0, err
+	}
+	return len(added), nil
+}
+
+// MoveFiles points the entries recorded for files that were moved at
+// their new paths, given as a map from old to new path, and returns how
+// many entries changed. The history is rewritten in place, keeping the
+// order and content of all other lines.
+func (s *Store) MoveFiles(moves map[string]string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	changed := 0
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return 0, fmt.Errorf("failed to parse history line %d: %w", i+1, err)
+		}
+		moved, ok := moves[entry.FilePath]
+		if !ok || entry.FilePath == "" {
+			continue
+		}
+		entry.FilePath = moved
+		if lines[i], err = json.Marshal(entry); err != nil {
+			return 0, fmt.Errorf("failed to write history: %w", err)
+		}
+		changed++
+	}
+
+	if changed == 0 {
+		return 0, nil
+	}
+	if err := fileutil.WriteAtomic(s.path, bytes.Join(lines, []byte("\n")), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write history: %w", err)
+	}
+	return changed, nil
+}
+
+// newID generates a sortable entry ID from its creation time
+func newID(t time.Time, seq int) string {
+	return strconv.FormatInt(t.UnixNano()+int64(seq), 36)
+}
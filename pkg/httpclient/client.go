@@ -0,0 +1,103 @@
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fips"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// DryRunHeader marks responses synthesized for requests that were not sent
+const DryRunHeader = "X-Converso-Dry-Run"
+
+// New creates an HTTP client for talking to the Converso backend. Requests
+// are checked against the endpoint registry; with cfg.DryRunAPI set,
+// requests that would change backend state are logged instead of sent.
+// In FIPS mode TLS is restricted to approved suites.
+func New(cfg *config.Config, logger telemetry.Logger, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &registryTransport{
+			next:   fips.Transport(),
+			config: cfg,
+			logger: logger,
+		},
+	}
+}
+
+// registryTransport matches requests against the endpoint registry. It
+// warns about unregistered endpoints and, in dry-run mode, logs mutating
+// requests and answers them with an empty JSON object. Reads and the OAuth
+// requests needed to log in pass through.
+type registryTransport struct {
+	next   http.RoundTripper
+	config *config.Config
+	logger telemetry.Logger
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *registryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := Match(t.config, req.Method, req.URL)
+	if endpoint == nil {
+		t.logger.Warn("Request to unregistered endpoint", "method", req.Method, "url", req.URL.String())
+	}
+	if endpoint != nil && endpoint.Telemetry && t.config.Policy.TelemetryDisabled() {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%s request blocked: telemetry is disabled by policy", endpoint.Name)
+	}
+
+	// Unregistered mutations are skipped too, so a dry run never leaks
+	// what the audit does not list
+	skip := endpoint == nil && isMutation(req.Method) || endpoint != nil && endpoint.Mutates
+	if !t.config.DryRunAPI || !skip {
+		return t.next.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Headers are omitted because they carry credentials
+	t.logger.Info("Dry run: backend request not sent",
+		"method", req.Method,
+		"url", req.URL.String(),
+		"body", string(bytes.TrimSpace(body)))
+
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	header.Set(DryRunHeader, "true")
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader("{}")),
+		ContentLength: 2,
+		Request:       req,
+	}, nil
+}
+
+// isMutation reports whether an HTTP method may change server state
+func isMutation(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,335 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/config"
+)
+
+// DataCategory names a kind of data sent to an endpoint
+type DataCategory string
+
+// Data categories sent to the backend
+const (
+	DataClientCredentials DataCategory = "client credentials"
+	DataTokens            DataCategory = "tokens"
+	DataDeviceInfo        DataCategory = "device info"
+	DataWorkerStatus      DataCategory = "worker status"
+	DataJobMetadata       DataCategory = "job metadata"
+	DataFiles             DataCategory = "downloaded files"
+	DataFilePaths         DataCategory = "file paths"
+)
+
+// Base names the configured URL an endpoint is relative to
+type Base string
+
+// Endpoint bases
+const (
+	BaseAPI   Base = "api_endpoint"
+	BaseAuth  Base = "auth_url"
+	BaseToken Base = "token_url"
+	BaseJWKS  Base = "jwks_url"
+	BaseIndex Base = "dependencies.index_url"
+	// BasePluginIndex is the plugin registry's index.json and
+	// BasePluginRegistry the directory holding it and the plugin archives
+	BasePluginIndex    Base = "plugins.index_url"
+	BasePluginRegistry Base = "plugins.index_url directory"
+	// BaseMediaServer is the Plex or Jellyfin server of the media server
+	// integration
+	BaseMediaServer Base = "integrations.mediaserver.url"
+)
+
+// Endpoint describes a network endpoint contacted by the CLI. Every
+// request made through New must match a registered endpoint, which keeps
+// the privacy audit complete.
+type Endpoint struct {
+	Name   string
+	Method string
+	Base   Base
+	// Path is appended to the base URL; {name} segments are placeholders
+	Path    string
+	Purpose string
+	Data    []DataCategory
+	// Mutates reports whether the request changes backend state; dry runs
+	// skip these
+	Mutates bool
+	// Telemetry marks reports a policy with disable_telemetry blocks
+	Telemetry bool
+}
+
+// Registered endpoints
+var (
+	DeviceCode = Endpoint{
+		Name:    "device_code",
+		Method:  http.MethodPost,
+		Base:    BaseAuth,
+		Path:    "/device/code",
+		Purpose: "Start device login",
+		Data:    []DataCategory{DataClientCredentials},
+	}
+	DeviceCodeCancel = Endpoint{
+		Name:    "device_code_cancel",
+		Method:  http.MethodPost,
+		Base:    BaseAuth,
+		Path:    "/device/cancel",
+		Purpose: "Invalidate the pending login code when login is cancelled",
+		Data:    []DataCategory{DataClientCredentials},
+		Mutates: true,
+	}
+	Token = Endpoint{
+		Name:    "token",
+		Method:  http.MethodPost,
+		Base:    BaseToken,
+		Purpose: "Obtain, refresh and exchange access tokens for module tokens",
+		Data:    []DataCategory{DataClientCredentials, DataTokens},
+	}
+	RegisterDevice = Endpoint{
+		Name:    "register_device",
+		Method:  http.MethodPost,
+		Base:    BaseAPI,
+		Path:    "/api/v1/devices/register",
+		Purpose: "Register this device after login",
+		Data:    []DataCategory{DataTokens, DataDeviceInfo},
+		Mutates: true,
+	}
+	PendingJobs = Endpoint{
+		Name:    "pending_jobs",
+		Method:  http.MethodGet,
+		Base:    BaseAPI,
+		Path:    "/api/v1/jobs/pending",
+		Purpose: "Fetch queued jobs (worker, every 30s)",
+		Data:    []DataCategory{DataTokens},
+	}
+	WorkerStatus = Endpoint{
+		Name:      "worker_status",
+		Method:    http.MethodPost,
+		Base:      BaseAPI,
+		Path:      "/api/v1/worker/status",
+		Purpose:   "Report worker status (worker, every 5m)",
+		Data:      []DataCategory{DataTokens, DataWorkerStatus},
+		Mutates:   true,
+		Telemetry: true,
+	}
+	Jobs = Endpoint{
+		Name:    "jobs",
+		Method:  http.MethodGet,
+		Base:    BaseAPI,
+		Path:    "/api/v1/jobs",
+		Purpose: "List queued and finished jobs (Go SDK)",
+		Data:    []DataCategory{DataTokens},
+	}
+	CreateJob = Endpoint{
+		Name:    "create_job",
+		Method:  http.MethodPost,
+		Base:    BaseAPI,
+		Path:    "/api/v1/jobs",
+		Purpose: "Queue a job for a worker (Go SDK)",
+		Data:    []DataCategory{DataTokens, DataJobMetadata},
+		Mutates: true,
+	}
+	JobStatus = Endpoint{
+		Name:    "job_status",
+		Method:  http.MethodPut,
+		Base:    BaseAPI,
+		Path:    "/api/v1/jobs/{id}/status",
+		Purpose: "Report job state and result (worker)",
+		Data:    []DataCategory{DataTokens, DataJobMetadata},
+		Mutates: true,
+	}
+	JobProgress = Endpoint{
+		Name:    "job_progress",
+		Method:  http.MethodPost,
+		Base:    BaseAPI,
+		Path:    "/api/v1/jobs/{id}/progress",
+		Purpose: "Report job progress (worker)",
+		Data:    []DataCategory{DataTokens, DataJobMetadata},
+		Mutates: true,
+	}
+	UploadJobFile = Endpoint{
+		Name:    "upload_job_file",
+		Method:  http.MethodPut,
+		Base:    BaseAPI,
+		Path:    "/api/v1/jobs/{id}/file",
+		Purpose: "Upload a completed download for other devices (worker, with worker.sync: backend)",
+		Data:    []DataCategory{DataTokens, DataFiles},
+		Mutates: true,
+	}
+	JobFile = Endpoint{
+		Name:    "job_file",
+		Method:  http.MethodGet,
+		Base:    BaseAPI,
+		Path:    "/api/v1/jobs/{id}/file",
+		Purpose: "Fetch a download completed on another device (jobs fetch --from backend)",
+		Data:    []DataCategory{DataTokens},
+	}
+	JWKS = Endpoint{
+		Name:    "jwks",
+		Method:  http.MethodGet,
+		Base:    BaseJWKS,
+		Purpose: "Fetch keys for verifying access tokens (cached for 24h)",
+	}
+	BackendHealth = Endpoint{
+		Name:    "backend_health",
+		Method:  http.MethodGet,
+		Base:    BaseAPI,
+		Purpose: "Check backend reachability (worker /readyz)",
+	}
+	PackageRelease = Endpoint{
+		Name:    "package_release",
+		Method:  http.MethodGet,
+		Base:    BaseIndex,
+		Path:    "/pypi/{package}/json",
+		Purpose: "Look up the latest version of module dependencies such as yt-dlp (once a day)",
+	}
+	PluginIndex = Endpoint{
+		Name:    "plugin_index",
+		Method:  http.MethodGet,
+		Base:    BasePluginIndex,
+		Purpose: "List the plugins of the registry (plugin search, plugin install <name>)",
+	}
+	PluginArchive = Endpoint{
+		Name:    "plugin_archive",
+		Method:  http.MethodGet,
+		Base:    BasePluginRegistry,
+		Path:    "/{module}/{archive}",
+		Purpose: "Download a plugin from the registry (plugin install <name>, cached)",
+	}
+	PlexRefresh = Endpoint{
+		Name:    "plex_refresh",
+		Method:  http.MethodGet,
+		Base:    BaseMediaServer,
+		Path:    "/library/sections/{section}/refresh",
+		Purpose: "Ask Plex to scan finished downloads (integrations.mediaserver)",
+		Data:    []DataCategory{DataTokens, DataFilePaths},
+	}
+	PlexIdentity = Endpoint{
+		Name:    "plex_identity",
+		Method:  http.MethodGet,
+		Base:    BaseMediaServer,
+		Path:    "/identity",
+		Purpose: "Check that Plex is reachable (doctor)",
+	}
+	JellyfinMediaUpdated = Endpoint{
+		Name:    "jellyfin_media_updated",
+		Method:  http.MethodPost,
+		Base:    BaseMediaServer,
+		Path:    "/Library/Media/Updated",
+		Purpose: "Ask Jellyfin to scan finished downloads (integrations.mediaserver)",
+		Data:    []DataCategory{DataTokens, DataFilePaths},
+	}
+	JellyfinInfo = Endpoint{
+		Name:    "jellyfin_info",
+		Method:  http.MethodGet,
+		Base:    BaseMediaServer,
+		Path:    "/System/Info/Public",
+		Purpose: "Check that Jellyfin is reachable (doctor)",
+	}
+)
+
+// Endpoints lists every registered endpoint
+var Endpoints = []*Endpoint{
+	&DeviceCode,
+	&DeviceCodeCancel,
+	&Token,
+	&RegisterDevice,
+	&PendingJobs,
+	&WorkerStatus,
+	&Jobs,
+	&CreateJob,
+	&JobStatus,
+	&JobProgress,
+	&UploadJobFile,
+	&JobFile,
+	&JWKS,
+	&BackendHealth,
+	&PackageRelease,
+	&PluginIndex,
+	&PluginArchive,
+	&PlexRefresh,
+	&PlexIdentity,
+	&JellyfinMediaUpdated,
+	&JellyfinInfo,
+}
+
+// URL returns the endpoint URL for the configuration, filling {name}
+// placeholders with args in order
+func (e *Endpoint) URL(cfg *config.Config, args ...string) string {
+	path := e.Path
+	for _, arg := range args {
+		start := strings.Index(path, "{")
+		end := strings.Index(path, "}")
+		if start < 0 || end < start {
+			break
+		}
+		path = path[:start] + url.PathEscape(arg) + path[end+1:]
+	}
+	return strings.TrimSuffix(baseURL(cfg, e.Base), "/") + path
+}
+
+// Template returns the endpoint URL for the configuration with
+// placeholders left in place
+func (e *Endpoint) Template(cfg *config.Config) string {
+	return strings.TrimSuffix(baseURL(cfg, e.Base), "/") + e.Path
+}
+
+// Match returns the registered endpoint a request goes to, or nil
+func Match(cfg *config.Config, method string, u *url.URL) *Endpoint {
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	target := *u
+	target.RawQuery = ""
+	target.Fragment = ""
+	requested := strings.Split(strings.TrimSuffix(target.String(), "/"), "/")
+
+	for _, e := range Endpoints {
+		if e.Method != method {
+			continue
+		}
+		if segmentsMatch(strings.Split(e.Template(cfg), "/"), requested) {
+			return e
+		}
+	}
+	return nil
+}
+
+// segmentsMatch compares URL segments, treating {name} as a wildcard
+func segmentsMatch(template, requested []string) bool {
+	if len(template) != len(requested) {
+		return false
+	}
+	for i := range template {
+		if strings.HasPrefix(template[i], "{") && strings.HasSuffix(template[i], "}") && requested[i] != "" {
+			continue
+		}
+		if template[i] != requested[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// baseURL returns the configured URL for a base
+func baseURL(cfg *config.Config, base Base) string {
+	switch base {
+	case BaseAuth:
+		return cfg.AuthURL
+	case BaseToken:
+		return cfg.TokenURL
+	case BaseJWKS:
+		return cfg.JWKSURL
+	case BaseIndex:
+		return cfg.Dependencies.IndexURL
+	case BasePluginIndex:
+		return cfg.Plugins.IndexURL
+	case BasePluginRegistry:
+		return cfg.Plugins.IndexURL[:strings.LastIndex(cfg.Plugins.IndexURL, "/")+1]
+	case BaseMediaServer:
+		return cfg.Integrations.MediaServer.URL
+	default:
+		return cfg.APIEndpoint
+	}
+}
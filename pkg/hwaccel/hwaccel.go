@@ -0,0 +1,196 @@
+// Package hwaccel detects the hardware video encoders FFmpeg can use on
+// this machine (NVIDIA NVENC, Intel Quick Sync and Apple VideoToolbox),
+// stores them, and turns them into the arguments conversion modules pick
+// their encoders from.
+package hwaccel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+)
+
+// Backends of hardware encoders
+const (
+	BackendNVENC        = "nvenc"
+	BackendQSV          = "qsv"
+	BackendVideoToolbox = "videotoolbox"
+)
+
+// ArgEncoders is the module argument carrying the hardware encoder of each
+// codec
+const ArgEncoders = "hw_encoders"
+
+// probeTimeout bounds the test encode of a single encoder
+const probeTimeout = 15 * time.Second
+
+// Encoder is an FFmpeg hardware encoder for a codec
+type Encoder struct {
+	Name    string `json:"name"`
+	Codec   string `json:"codec"`
+	Backend string `json:"backend"`
+}
+
+// candidates are the encoders looked for, by backend in order of
+// preference on each platform
+var candidates = []Encoder{
+	{Name: "h264_videotoolbox", Codec: "h264", Backend: BackendVideoToolbox},
+	{Name: "hevc_videotoolbox", Codec: "h265", Backend: BackendVideoToolbox},
+	{Name: "h264_nvenc", Codec: "h264", Backend: BackendNVENC},
+	{Name: "hevc_nvenc", Codec: "h265", Backend: BackendNVENC},
+	{Name: "av1_nvenc", Codec: "av1", Backend: BackendNVENC},
+	{Name: "h264_qsv", Codec: "h264", Backend: BackendQSV},
+	{Name: "hevc_qsv", Codec: "h265", Backend: BackendQSV},
+	{Name: "vp9_qsv", Codec: "vp9", Backend: BackendQSV},
+	{Name: "av1_qsv", Codec: "av1", Backend: BackendQSV},
+}
+
+// Capabilities are the hardware encoders found on this machine
+type Capabilities struct {
+	DetectedAt time.Time `json:"detected_at"`
+	// FFmpeg is the FFmpeg binary the encoders were tested with
+	FFmpeg string `json:"ffmpeg"`
+	// Encoders are the encoders that completed a test encode
+	Encoders []Encoder `json:"encoders"`
+	// Failed are encoders FFmpeg was built with but could not use, with
+	// FFmpeg's error, such as a missing driver
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// Backends returns the backends of the usable encoders, in order of
+// preference
+func (c *Capabilities) Backends() []string {
+	var backends []string
+	seen := make(map[string]bool)
+	for _, encoder := range c.Encoders {
+		if !seen[encoder.Backend] {
+			seen[encoder.Backend] = true
+			backends = append(backends, encoder.Backend)
+		}
+	}
+	return backends
+}
+
+// Args returns the encoder conversion modules use for each codec, the
+// preferred backend's first, or nil without hardware encoders
+func (c *Capabilities) Args() map[string]interface{} {
+	if c == nil || len(c.Encoders) == 0 {
+		return nil
+	}
+	encoders := make(map[string]interface{})
+	for _, encoder := range c.Encoders {
+		if _, ok := encoders[encoder.Codec]; !ok {
+			encoders[encoder.Codec] = encoder.Name
+		}
+	}
+	return encoders
+}
+
+// Detect finds the hardware encoders the FFmpeg on the PATH was built with
+// and checks each with a one-frame test encode, since encoders are built
+// in whether or not the machine has the hardware and drivers for them
+func Detect(ctx context.Context) (*Capabilities, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, fmt.Errorf("FFmpeg not found: %w", err)
+	}
+	output, err := exec.CommandContext(ctx, ffmpeg, "-hide_banner", "-encoders").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list FFmpeg encoders: %w", err)
+	}
+	built := make(map[string]bool)
+	for _, line := range strings.Split(string(output), "\n") {
+		// " V....D h264_nvenc  NVIDIA NVENC H.264 encoder"
+		if fields := strings.Fields(line); len(fields) >= 2 {
+			built[fields[1]] = true
+		}
+	}
+
+	caps := &Capabilities{DetectedAt: time.Now(), FFmpeg: ffmpeg, Failed: make(map[string]string)}
+	for _, encoder := range preferred(runtime.GOOS) {
+		if !built[encoder.Name] {
+			continue
+		}
+		if err := probe(ctx, ffmpeg, encoder.Name); err != nil {
+			caps.Failed[encoder.Name] = err.Error()
+			continue
+		}
+		caps.Encoders = append(caps.Encoders, encoder)
+	}
+	return caps, nil
+}
+
+// preferred returns the candidates in order of preference on an OS:
+// VideoToolbox only exists on macOS, NVENC is preferred over Quick Sync
+// where a machine has both
+func preferred(goos string) []Encoder {
+	var encoders []Encoder
+	for _, encoder := range candidates {
+		if encoder.Backend == BackendVideoToolbox && goos != "darwin" {
+			continue
+		}
+		encoders = append(encoders, encoder)
+	}
+	return encoders
+}
+
+// probe encodes a single generated frame with an encoder
+func probe(ctx context.Context, ffmpeg, encoder string) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, ffmpeg, "-hide_banner", "-loglevel", "error",
+		"-f", "lavfi", "-i", "color=black:size=256x256:duration=0.1",
+		"-frames:v", "1", "-c:v", encoder, "-f", "null", "-")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+			return fmt.Errorf("%s", last)
+		}
+		return err
+	}
+	return nil
+}
+
+// DefaultPath returns the location of the stored capabilities
+func DefaultPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "hwaccel.json")
+}
+
+// Load reads stored capabilities, nil when they were never detected
+func Load(path string) (*Capabilities, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hardware encoders: %w", err)
+	}
+	var caps Capabilities
+	if err := json.Unmarshal(data, &caps); err != nil {
+		return nil, fmt.Errorf("failed to parse hardware encoders: %w", err)
+	}
+	return &caps, nil
+}
+
+// Save stores the capabilities at path
+func (c *Capabilities) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, data, 0644)
+}
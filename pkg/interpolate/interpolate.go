@@ -0,0 +1,459 @@
+// Package interpolate expands ${...} references in pipeline files, config
+// values and output templates:
+//
+//	${env:HOME}                     an environment variable
+//	${date:2006-01-02}              the current time in a Go layout
+//	${result.fetch.file_path}       a value by dotted path
+//	${env:OUT ?? "~/Downloads"}     the first defined, non-empty operand
+//	${result.fetch.title | slug}    a value passed through filters
+//
+// Expressions can only look values up, choose between them and apply the
+// built-in filters, so expanding untrusted text never runs code. "$${"
+// stands for a literal "${".
+package interpolate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Context is what references are resolved against
+type Context struct {
+	// Now is the time date: references format, time.Now when zero
+	Now time.Time
+	// Env looks up environment variables, os.LookupEnv when nil
+	Env func(name string) (string, bool)
+	// Values holds the values of dotted paths: nested maps whose leaves
+	// are strings, numbers or booleans
+	Values map[string]interface{}
+}
+
+// Template is a parsed string with ${...} references
+type Template struct {
+	text  string
+	parts []part
+}
+
+// part is literal text or, when expr is set, an expression
+type part struct {
+	literal string
+	expr    *expression
+}
+
+// Parse parses the references of a string
+func Parse(text string) (*Template, error) {
+	t := &Template{text: text}
+	var literal strings.Builder
+	rest := text
+	for {
+		i := strings.Index(rest, "${")
+		if i < 0 {
+			literal.WriteString(rest)
+			break
+		}
+		if i > 0 && rest[i-1] == '$' {
+			literal.WriteString(rest[:i-1])
+			literal.WriteString("${")
+			rest = rest[i+2:]
+			continue
+		}
+		literal.WriteString(rest[:i])
+
+		end := closingBrace(rest[i+2:])
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated ${ in %q", text)
+		}
+		expr, err := parseExpression(rest[i+2 : i+2+end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid reference ${%s}: %w", rest[i+2:i+2+end], err)
+		}
+		if literal.Len() > 0 {
+			t.parts = append(t.parts, part{literal: literal.String()})
+			literal.Reset()
+		}
+		t.parts = append(t.parts, part{expr: expr})
+		rest = rest[i+2+end+1:]
+	}
+	if literal.Len() > 0 {
+		t.parts = append(t.parts, part{literal: literal.String()})
+	}
+	return t, nil
+}
+
+// closingBrace returns the index of the } ending an expression, skipping
+// quoted strings, or -1
+func closingBrace(s string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+// Paths returns the dotted paths the template references, without env:
+// and date: references
+func (t *Template) Paths() []string {
+	var paths []string
+	for _, p := range t.parts {
+		if p.expr == nil {
+			continue
+		}
+		for _, alt := range p.expr.alternatives {
+			if alt.kind == operandPath {
+				paths = append(paths, alt.value)
+			}
+		}
+	}
+	return paths
+}
+
+// Execute expands the template
+func (t *Template) Execute(ctx *Context) (string, error) {
+	if ctx == nil {
+		ctx = &Context{}
+	}
+	var b strings.Builder
+	for _, p := range t.parts {
+		if p.expr == nil {
+			b.WriteString(p.literal)
+			continue
+		}
+		value, err := p.expr.eval(ctx)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value)
+	}
+	return b.String(), nil
+}
+
+// Expand parses and expands a string
+func Expand(text string, ctx *Context) (string, error) {
+	if !strings.Contains(text, "${") {
+		return text, nil
+	}
+	t, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+	return t.Execute(ctx)
+}
+
+// ExpandValue expands the strings of a decoded YAML or JSON value,
+// returning a copy
+func ExpandValue(value interface{}, ctx *Context) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return Expand(v, ctx)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			expanded, err := ExpandValue(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = expanded
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			expanded, err := ExpandValue(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = expanded
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+// Strings returns the strings of a decoded YAML or JSON value, for
+// parsing them ahead of expansion
+func Strings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case map[string]interface{}:
+		var all []string
+		for _, item := range v {
+			all = append(all, Strings(item)...)
+		}
+		return all
+	case []interface{}:
+		var all []string
+		for _, item := range v {
+			all = append(all, Strings(item)...)
+		}
+		return all
+	default:
+		return nil
+	}
+}
+
+// Operand kinds
+const (
+	operandString = iota
+	operandEnv
+	operandDate
+	operandPath
+)
+
+// expression is a list of alternatives separated by ??, the first
+// defined, non-empty one winning
+type expression struct {
+	text         string
+	alternatives []operand
+}
+
+// operand is a literal or reference with the filters applied to it
+type operand struct {
+	kind    int
+	value   string
+	filters []string
+}
+
+// parseExpression parses: operand ("|" filter)* ("??" operand ("|" filter)*)*
+func parseExpression(text string) (*expression, error) {
+	expr := &expression{text: strings.TrimSpace(text)}
+	for _, alt := range splitOutsideQuotes(text, "??") {
+		segments := splitOutsideQuotes(alt, "|")
+		op, err := parseOperand(strings.TrimSpace(segments[0]))
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range segments[1:] {
+			name = strings.TrimSpace(name)
+			if _, ok := filters[name]; !ok {
+				return nil, fmt.Errorf("unknown filter %q (available: %s)", name, filterNames())
+			}
+			op.filters = append(op.filters, name)
+		}
+		expr.alternatives = append(expr.alternatives, op)
+	}
+	return expr, nil
+}
+
+// parseOperand parses a quoted string, env:NAME, date:LAYOUT or a dotted
+// path
+func parseOperand(text string) (operand, error) {
+	switch {
+	case text == "":
+		return operand{}, fmt.Errorf("empty expression")
+	case text[0] == '"' || text[0] == '\'':
+		if len(text) < 2 || text[len(text)-1] != text[0] {
+			return operand{}, fmt.Errorf("unterminated string %s", text)
+		}
+		return operand{kind: operandString, value: text[1 : len(text)-1]}, nil
+	case strings.HasPrefix(text, "env:"):
+		name := strings.TrimPrefix(text, "env:")
+		if name == "" || strings.ContainsAny(name, " \t=") {
+			return operand{}, fmt.Errorf("invalid environment variable name %q", name)
+		}
+		return operand{kind: operandEnv, value: name}, nil
+	case strings.HasPrefix(text, "date:"):
+		layout := strings.TrimPrefix(text, "date:")
+		if layout == "" {
+			return operand{}, fmt.Errorf("date needs a layout such as date:2006-01-02")
+		}
+		return operand{kind: operandDate, value: layout}, nil
+	}
+
+	for _, segment := range strings.Split(text, ".") {
+		if !validIdentifier(segment) {
+			return operand{}, fmt.Errorf("invalid reference %q", text)
+		}
+	}
+	return operand{kind: operandPath, value: text}, nil
+}
+
+// validIdentifier reports whether s is a path segment: letters, digits,
+// '_' and '-'
+func validIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// splitOutsideQuotes splits s at sep where it is not inside a quoted
+// string
+func splitOutsideQuotes(s, sep string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case strings.HasPrefix(s[i:], sep):
+			parts = append(parts, s[start:i])
+			i += len(sep) - 1
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// eval returns the first defined, non-empty alternative; the last one may
+// be empty
+func (e *expression) eval(ctx *Context) (string, error) {
+	for i, alt := range e.alternatives {
+		value, ok, err := alt.eval(ctx)
+		if err != nil {
+			return "", err
+		}
+		if ok && (value != "" || i == len(e.alternatives)-1) {
+			return value, nil
+		}
+	}
+
+	last := e.alternatives[len(e.alternatives)-1]
+	switch last.kind {
+	case operandEnv:
+		return "", fmt.Errorf("environment variable %s is not set", last.value)
+	default:
+		return "", fmt.Errorf("${%s} is not defined", e.text)
+	}
+}
+
+// eval resolves an operand and applies its filters
+func (o operand) eval(ctx *Context) (string, bool, error) {
+	var value string
+	switch o.kind {
+	case operandString:
+		value = o.value
+	case operandEnv:
+		lookup := ctx.Env
+		if lookup == nil {
+			lookup = os.LookupEnv
+		}
+		var ok bool
+		if value, ok = lookup(o.value); !ok {
+			return "", false, nil
+		}
+	case operandDate:
+		now := ctx.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		value = now.Format(o.value)
+	case operandPath:
+		var ok bool
+		var err error
+		if value, ok, err = lookupPath(ctx.Values, o.value); err != nil || !ok {
+			return "", false, err
+		}
+	}
+
+	for _, name := range o.filters {
+		value = filters[name](value)
+	}
+	return value, true, nil
+}
+
+// lookupPath finds a dotted path in nested maps
+func lookupPath(values map[string]interface{}, path string) (string, bool, error) {
+	var current interface{} = values
+	for _, segment := range strings.Split(path, ".") {
+		switch m := current.(type) {
+		case map[string]interface{}:
+			value, ok := m[segment]
+			if !ok {
+				return "", false, nil
+			}
+			current = value
+		case map[string]string:
+			value, ok := m[segment]
+			if !ok {
+				return "", false, nil
+			}
+			current = value
+		default:
+			return "", false, nil
+		}
+	}
+
+	switch v := current.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return v, true, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true, nil
+	case int:
+		return strconv.Itoa(v), true, nil
+	case int64:
+		return strconv.FormatInt(v, 10), true, nil
+	case bool:
+		return strconv.FormatBool(v), true, nil
+	default:
+		return "", false, fmt.Errorf("${%s} is not a single value", path)
+	}
+}
+
+// filters are the functions expressions can apply
+var filters = map[string]func(string) string{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	"base":  filepath.Base,
+	"dir":   filepath.Dir,
+	"ext":   filepath.Ext,
+	"stem": func(s string) string {
+		base := filepath.Base(s)
+		return strings.TrimSuffix(base, filepath.Ext(base))
+	},
+	"slug": slug,
+}
+
+// filterNames lists the filters for error messages
+func filterNames() string {
+	return "lower, upper, trim, base, dir, ext, stem, slug"
+}
+
+// slug makes s safe as a file name: runs of anything but letters, digits,
+// '.', '-' and '_' become a single '-', and leading or trailing dots and
+// dashes are dropped
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' || r == '_' {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.Trim(b.String(), "-.")
+}
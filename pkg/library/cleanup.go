@@ -0,0 +1,120 @@
+package library
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/terminal"
+)
+
+// Removal is a download a cleanup rule deletes
+type Removal struct {
+	Entry
+	Reason string
+	Size   int64
+}
+
+// PlanCleanup returns the downloads under the rule's directory that it
+// deletes, oldest first. Only downloads with a sidecar are considered, so
+// files Converso did not download are never deleted. Downloads are grouped
+// into channels by uploader, or by folder when there is none.
+func PlanCleanup(rule config.CleanupRule, now time.Time) ([]Removal, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	dir, err := config.ExpandPath(rule.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := make(map[string][]Removal)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), SidecarExt) {
+			return nil
+		}
+		entry, err := ReadSidecar(path)
+		if err != nil || entry.FilePath == "" {
+			return nil
+		}
+		if rel, err := filepath.Rel(dir, entry.FilePath); err != nil || !filepath.IsLocal(rel) {
+			return nil
+		}
+		info, err := os.Stat(entry.FilePath)
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if entry.DownloadedAt.IsZero() {
+			entry.DownloadedAt = info.ModTime()
+		}
+
+		channel := strings.ToLower(entry.Uploader)
+		if channel == "" {
+			channel = filepath.Dir(entry.FilePath)
+		}
+		channels[channel] = append(channels[channel], Removal{Entry: *entry, Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+
+	var removals []Removal
+	for _, downloads := range channels {
+		sort.SliceStable(downloads, func(i, j int) bool {
+			return downloads[i].DownloadedAt.After(downloads[j].DownloadedAt)
+		})
+		for i, download := range downloads {
+			var reasons []string
+			if rule.KeepLast > 0 && i >= rule.KeepLast {
+				reasons = append(reasons, fmt.Sprintf("not among the newest %d of its channel", rule.KeepLast))
+			}
+			if rule.OlderThan > 0 && now.Sub(download.DownloadedAt) > rule.OlderThan {
+				reasons = append(reasons, fmt.Sprintf("downloaded %s ago", formatAge(now.Sub(download.DownloadedAt))))
+			}
+			if rule.Watched && !download.WatchedAt.IsZero() {
+				reasons = append(reasons, "watched "+terminal.FormatDate(download.WatchedAt.Local()))
+			}
+			if len(reasons) > 0 {
+				download.Reason = strings.Join(reasons, ", ")
+				removals = append(removals, download)
+			}
+		}
+	}
+
+	sort.SliceStable(removals, func(i, j int) bool {
+		return removals[i].DownloadedAt.Before(removals[j].DownloadedAt)
+	})
+	return removals, nil
+}
+
+// formatAge returns an age in days, or hours below a day
+func formatAge(age time.Duration) string {
+	if age < 24*time.Hour {
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(age.Hours()/24))
+}
+
+// Remove deletes a download together with its sidecar and .nfo file
+func Remove(entry Entry) error {
+	paths := []string{
+		entry.FilePath,
+		entry.InfoPath,
+		strings.TrimSuffix(entry.FilePath, filepath.Ext(entry.FilePath)) + ".nfo",
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package library
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+)
+
+// FeedPath is where a served feed is found
+const FeedPath = "/feed.xml"
+
+// mediaPath prefixes the audio files of a served feed
+const mediaPath = "/media/"
+
+// audioTypes are the MIME types of the files listed in podcast feeds
+var audioTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".opus": "audio/ogg",
+	".ogg":  "audio/ogg",
+	".flac": "audio/flac",
+	".wav":  "audio/wav",
+}
+
+// Episode is an audio file listed in a podcast feed
+type Episode struct {
+	// Path is the file's path relative to the feed directory, with
+	// forward slashes
+	Path        string
+	FilePath    string
+	Title       string
+	Description string
+	Author      string
+	URL         string
+	Published   time.Time
+	Duration    time.Duration
+	Size        int64
+	Type        string
+}
+
+// Episodes returns the audio files under dir, newest first. Title,
+// description, uploader and dates come from the .info.json sidecar of the
+// download the file was made from when there is one.
+func Episodes(dir string) ([]Episode, error) {
+	var episodes []Episode
+	err := filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		mimeType, ok := audioTypes[strings.ToLower(filepath.Ext(d.Name()))]
+		if d.IsDir() || !ok {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+
+		episode := Episode{
+			Path:      filepath.ToSlash(rel),
+			FilePath:  filePath,
+			Title:     strings.TrimSuffix(d.Name(), filepath.Ext(d.Name())),
+			Published: info.ModTime(),
+			Size:      info.Size(),
+			Type:      mimeType,
+		}
+		episode.addSidecar(SidecarPath(filePath))
+		episodes = append(episodes, episode)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(episodes, func(i, j int) bool {
+		return episodes[i].Published.After(episodes[j].Published)
+	})
+	return episodes, nil
+}
+
+// addSidecar fills in the metadata of the sidecar at infoPath, if any
+func (e *Episode) addSidecar(infoPath string) {
+	data, err := os.ReadFile(infoPath)
+	if err != nil {
+		return
+	}
+	var info map[string]interface{}
+	if json.Unmarshal(data, &info) != nil {
+		return
+	}
+
+	f := bridge.Fields(info)
+	if title := f.String("title"); title != "" {
+		e.Title = title
+	}
+	e.Description = f.String("description")
+	e.Author = f.String("uploader")
+	if e.Author == "" {
+		e.Author = f.String("channel")
+	}
+	e.URL = f.String("url")
+	e.Duration = f.Duration("duration")
+	if published, ok := f.UploadDate(); ok {
+		e.Published = published
+	} else if downloaded, err := time.Parse(time.RFC3339, f.String("downloaded_at")); err == nil {
+		e.Published = downloaded
+	}
+}
+
+// rssFeed is an RSS 2.0 feed with the iTunes tags podcast apps read
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	ITunes  string     `xml:"xmlns:itunes,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// rssChannel describes the podcast
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+// rssItem is one episode
+type rssItem struct {
+	Title       string       `xml:"title"`
+	Description string       `xml:"description,omitempty"`
+	Author      string       `xml:"itunes:author,omitempty"`
+	GUID        rssGUID      `xml:"guid"`
+	PubDate     string       `xml:"pubDate"`
+	Enclosure   rssEnclosure `xml:"enclosure"`
+	Duration    string       `xml:"itunes:duration,omitempty"`
+}
+
+// rssGUID identifies an episode across feed updates
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// rssEnclosure is the audio file of an episode
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// WriteFeed writes a podcast feed of episodes whose files are found under
+// baseURL by their relative paths
+func WriteFeed(w io.Writer, title, baseURL string, episodes []Episode) error {
+	base := strings.TrimSuffix(baseURL, "/")
+	feed := rssFeed{
+		Version: "2.0",
+		ITunes:  "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		Channel: rssChannel{
+			Title:         title,
+			Link:          base + "/",
+			Description:   fmt.Sprintf("%s, %d episode(s) downloaded with Converso", title, len(episodes)),
+			LastBuildDate: time.Now().Format(time.RFC1123Z),
+		},
+	}
+	for _, episode := range episodes {
+		segments := strings.Split(episode.Path, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		guid := episode.URL
+		if guid == "" {
+			guid = episode.Path
+		}
+
+		item := rssItem{
+			Title:       episode.Title,
+			Description: episode.Description,
+			Author:      episode.Author,
+			GUID:        rssGUID{Value: guid},
+			PubDate:     episode.Published.Format(time.RFC1123Z),
+			Enclosure:   rssEnclosure{URL: base + "/" + strings.Join(segments, "/"), Length: episode.Size, Type: episode.Type},
+		}
+		if episode.Duration > 0 {
+			item.Duration = fmt.Sprint(int(episode.Duration.Seconds()))
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(feed); err != nil {
+		return fmt.Errorf("failed to encode feed: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// FeedHandler serves the podcast feed of the audio files under dir at
+// FeedPath, and the files themselves. The feed is built on each request, so
+// new downloads show up on the next refresh, and links use the host the
+// client asked for, so the feed works on any address the server is reached
+// at. Nothing but the listed audio files is served.
+func FeedHandler(dir, title string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(FeedPath, func(rw http.ResponseWriter, r *http.Request) {
+		episodes, err := Episodes(dir)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		WriteFeed(rw, title, "http://"+r.Host+strings.TrimSuffix(mediaPath, "/"), episodes)
+	})
+	mux.HandleFunc(mediaPath, func(rw http.ResponseWriter, r *http.Request) {
+		rel := path.Clean(strings.TrimPrefix(r.URL.Path, mediaPath))
+		mimeType, ok := audioTypes[strings.ToLower(path.Ext(rel))]
+		if !ok || !filepath.IsLocal(filepath.FromSlash(rel)) {
+			http.NotFound(rw, r)
+			return
+		}
+		file, err := os.Open(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			http.NotFound(rw, r)
+			return
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(rw, r)
+			return
+		}
+		rw.Header().Set("Content-Type", mimeType)
+		http.ServeContent(rw, r, info.Name(), info.ModTime(), file)
+	})
+	return mux
+}
@@ -0,0 +1,294 @@
+// Package library indexes downloads by the metadata in their .info.json
+// sidecars, so media downloaded earlier can be found again on disk.
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+)
+
+// lockTimeout bounds the wait for another process updating the index
+const lockTimeout = 5 * time.Second
+
+// Field weights when ranking search results
+const (
+	titleWeight    = 3
+	uploaderWeight = 2
+	tagWeight      = 2
+)
+
+// Entry is an indexed download
+type Entry struct {
+	FilePath     string    `json:"file_path"`
+	InfoPath     string    `json:"info_path"`
+	Module       string    `json:"module,omitempty"`
+	URL          string    `json:"url,omitempty"`
+	Title        string    `json:"title,omitempty"`
+	Uploader     string    `json:"uploader,omitempty"`
+	Tags         []string  `json:"tags,omitempty"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+	WatchedAt    time.Time `json:"watched_at,omitempty"`
+}
+
+// Result is an entry matching a search
+type Result struct {
+	Entry
+	Score int `json:"score"`
+	// Missing is set when the file is no longer on disk
+	Missing bool `json:"missing,omitempty"`
+}
+
+// indexFile is the stored index: the entries and, for each word of their
+// title, uploader and tags, the positions of the entries containing it
+type indexFile struct {
+	Entries []Entry          `json:"entries"`
+	Terms   map[string][]int `json:"terms"`
+}
+
+// Index is the full-text index of downloads shared by all CLI processes
+type Index struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultPath returns the location of the library index
+func DefaultPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "library.json")
+}
+
+// NewIndex creates an index backed by path
+func NewIndex(path string) *Index {
+	return &Index{path: path}
+}
+
+// Add indexes entries, replacing earlier entries for the same file
+func (x *Index) Add(entries ...Entry) error {
+	return x.update(func(index *indexFile) {
+		positions := make(map[string]int, len(index.Entries))
+		for i, entry := range index.Entries {
+			positions[entry.FilePath] = i
+		}
+		for _, entry := range entries {
+			if i, ok := positions[entry.FilePath]; ok {
+				index.Entries[i] = entry
+				continue
+			}
+			positions[entry.FilePath] = len(index.Entries)
+			index.Entries = append(index.Entries, entry)
+		}
+	})
+}
+
+// Move re-indexes moved downloads: moves maps the old file path of each to
+// its new entry
+func (x *Index) Move(moves map[string]Entry) error {
+	return x.update(func(index *indexFile) {
+		for i, entry := range index.Entries {
+			if moved, ok := moves[entry.FilePath]; ok {
+				index.Entries[i] = moved
+			}
+		}
+	})
+}
+
+// Prune removes the entries whose files are no longer on disk and returns
+// how many were removed
+func (x *Index) Prune() (int, error) {
+	removed := 0
+	err := x.update(func(index *indexFile) {
+		kept := index.Entries[:0]
+		for _, entry := range index.Entries {
+			if _, err := os.Stat(entry.FilePath); err != nil {
+				removed++
+				continue
+			}
+			kept = append(kept, entry)
+		}
+		index.Entries = kept
+	})
+	return removed, err
+}
+
+// update applies fn to the stored index under a lock and rebuilds its
+// terms
+func (x *Index) update(fn func(index *indexFile)) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(x.path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	lock, err := fileutil.Lock(x.path+".lock", lockTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to lock library index: %w", err)
+	}
+	defer lock.Unlock()
+
+	index, err := x.load()
+	if err != nil {
+		// Start over rather than failing every download on a corrupt file;
+		// 'converso library index' restores it from the sidecars
+		index = &indexFile{}
+	}
+	fn(index)
+
+	index.Terms = make(map[string][]int)
+	for i, entry := range index.Entries {
+		seen := make(map[string]bool)
+		for _, term := range entryTerms(entry) {
+			if !seen[term] {
+				seen[term] = true
+				index.Terms[term] = append(index.Terms[term], i)
+			}
+		}
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("failed to marshal library index: %w", err)
+	}
+	if err := fileutil.WriteAtomic(x.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write library index: %w", err)
+	}
+	return nil
+}
+
+// Search returns the entries containing every word of query in their
+// title, uploader or tags, best matches first. Words match as prefixes, so
+// "tut" finds "Tutorial".
+func (x *Index) Search(query string) ([]Result, error) {
+	words := Terms(query)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("empty search query")
+	}
+
+	x.mu.Lock()
+	index, err := x.load()
+	x.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// Intersect the entries matching each word
+	var matches map[int]bool
+	for _, word := range words {
+		found := make(map[int]bool)
+		for term, positions := range index.Terms {
+			if strings.HasPrefix(term, word) {
+				for _, i := range positions {
+					if matches == nil || matches[i] {
+						found[i] = true
+					}
+				}
+			}
+		}
+		matches = found
+	}
+
+	results := make([]Result, 0, len(matches))
+	for i := range matches {
+		if i >= len(index.Entries) {
+			continue
+		}
+		entry := index.Entries[i]
+		result := Result{Entry: entry, Score: score(entry, words)}
+		if _, err := os.Stat(entry.FilePath); err != nil {
+			result.Missing = true
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].DownloadedAt.After(results[j].DownloadedAt)
+	})
+	return results, nil
+}
+
+// Len returns the number of indexed entries
+func (x *Index) Len() (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	index, err := x.load()
+	if err != nil {
+		return 0, err
+	}
+	return len(index.Entries), nil
+}
+
+// Entries returns the indexed entries in the order they were added
+func (x *Index) Entries() ([]Entry, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	index, err := x.load()
+	if err != nil {
+		return nil, err
+	}
+	return index.Entries, nil
+}
+
+// load reads the stored index; a missing file is empty. x.mu must be held.
+func (x *Index) load() (*indexFile, error) {
+	index := &indexFile{}
+	data, err := os.ReadFile(x.path)
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read library index: %w", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse library index: %w", err)
+	}
+	return index, nil
+}
+
+// Terms splits text into lowercase words
+func Terms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// entryTerms returns the indexed words of an entry
+func entryTerms(entry Entry) []string {
+	terms := append(Terms(entry.Title), Terms(entry.Uploader)...)
+	for _, tag := range entry.Tags {
+		terms = append(terms, Terms(tag)...)
+	}
+	return terms
+}
+
+// score ranks an entry for the query words by the fields they match in
+func score(entry Entry, words []string) int {
+	total := 0
+	for _, word := range words {
+		total += fieldScore(Terms(entry.Title), word, titleWeight)
+		total += fieldScore(Terms(entry.Uploader), word, uploaderWeight)
+		for _, tag := range entry.Tags {
+			total += fieldScore(Terms(tag), word, tagWeight)
+		}
+	}
+	return total
+}
+
+// fieldScore returns weight when one of terms starts with word
+func fieldScore(terms []string, word string, weight int) int {
+	for _, term := range terms {
+		if strings.HasPrefix(term, word) {
+			return weight
+		}
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/archive"
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/fileutil"
+	"github.com/converso-empire/cli/pkg/interpolate"
+)
+
+// Destination returns where tmpl puts a download under root. The template
+// sees every field of the download's sidecar, plus year, month and day of
+// its upload (or download) date, ext, the extension of its file, and id,
+// the video ID of its URL when the module reported none. As in the index,
+// uploader falls back to the channel.
+func Destination(tmpl *interpolate.Template, root string, entry Entry) (string, error) {
+	data, err := os.ReadFile(entry.InfoPath)
+	if err != nil {
+		return "", err
+	}
+	values := make(map[string]interface{})
+	if err := json.Unmarshal(data, &values); err != nil {
+		return "", fmt.Errorf("invalid metadata in %s: %w", entry.InfoPath, err)
+	}
+
+	date := entry.DownloadedAt
+	if upload, ok := bridge.Fields(values).UploadDate(); ok {
+		date = upload
+	}
+	values["year"], values["month"], values["day"] = date.Format("2006"), date.Format("01"), date.Format("02")
+	values["ext"] = strings.TrimPrefix(filepath.Ext(entry.FilePath), ".")
+	values["uploader"] = entry.Uploader
+	if bridge.Fields(values).String("id") == "" {
+		values["id"] = archive.ItemID(entry.URL)
+	}
+
+	rel, err := tmpl.Execute(&interpolate.Context{Now: entry.DownloadedAt, Values: values})
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.Clean(filepath.FromSlash(rel))
+	if !filepath.IsLocal(rel) || strings.HasSuffix(rel, SidecarExt) {
+		return "", fmt.Errorf("template gives %q, which is not a file name under %s", rel, root)
+	}
+	return filepath.Join(root, rel), nil
+}
+
+// MoveDownload moves a download and its sidecar to target, updating the
+// file path the sidecar records, and returns its new entry
+func MoveDownload(entry Entry, target string) (*Entry, error) {
+	if _, err := os.Lstat(target); err == nil {
+		return nil, fmt.Errorf("%s already exists", target)
+	}
+	info, err := readInfo(entry.InfoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.Rename(entry.FilePath, target); err != nil {
+		return nil, fmt.Errorf("failed to move file: %w", err)
+	}
+
+	info["file_path"] = target
+	encoded, err := json.MarshalIndent(info, "", "  ")
+	if err == nil {
+		err = fileutil.WriteAtomic(SidecarPath(target), append(encoded, '\n'), 0644)
+	}
+	if err != nil {
+		os.Rename(target, entry.FilePath)
+		return nil, fmt.Errorf("failed to write sidecar: %w", err)
+	}
+	if SidecarPath(target) != entry.InfoPath {
+		os.Remove(entry.InfoPath)
+	}
+	return entryFromInfo(SidecarPath(target), info), nil
+}
@@ -0,0 +1,129 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/fileutil"
+)
+
+// SidecarExt is the extension of the metadata file written next to each
+// download, as yt-dlp's --write-info-json names it
+const SidecarExt = ".info.json"
+
+// SidecarPath returns the sidecar of a media file: video.mp4 has
+// video.info.json
+func SidecarPath(filePath string) string {
+	return strings.TrimSuffix(filePath, filepath.Ext(filePath)) + SidecarExt
+}
+
+// WriteSidecar writes the result data of a download next to its file,
+// adding the module, URL and download time, and returns the entry to index.
+// The data is kept as the module sent it, so sidecars hold whatever
+// metadata the module reports.
+func WriteSidecar(module, url string, data map[string]interface{}) (*Entry, error) {
+	filePath := bridge.Fields(data).String("file_path")
+	if filePath == "" {
+		return nil, fmt.Errorf("download result has no file_path")
+	}
+
+	info := make(map[string]interface{}, len(data)+3)
+	for key, value := range data {
+		info[key] = value
+	}
+	info["module"] = module
+	if _, ok := info["url"]; !ok {
+		info["url"] = url
+	}
+	info["downloaded_at"] = time.Now().UTC().Format(time.RFC3339)
+
+	encoded, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	path := SidecarPath(filePath)
+	if err := fileutil.WriteAtomic(path, append(encoded, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return entryFromInfo(path, info), nil
+}
+
+// ReadSidecar reads the entry of a sidecar. A media file moved together
+// with its sidecar is found next to it rather than at the recorded path.
+func ReadSidecar(path string) (*Entry, error) {
+	info, err := readInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	return entryFromInfo(path, info), nil
+}
+
+// MarkWatched records in the sidecar of a download when it was watched,
+// for cleanup rules deleting watched downloads. A zero time unmarks it.
+func MarkWatched(filePath string, at time.Time) (*Entry, error) {
+	path := SidecarPath(filePath)
+	info, err := readInfo(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s has no %s sidecar", filePath, SidecarExt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if at.IsZero() {
+		delete(info, "watched_at")
+	} else {
+		info["watched_at"] = at.UTC().Format(time.RFC3339)
+	}
+	encoded, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	if err := fileutil.WriteAtomic(path, append(encoded, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return entryFromInfo(path, info), nil
+}
+
+// readInfo reads the metadata of a sidecar
+func readInfo(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var info map[string]interface{}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("invalid metadata in %s: %w", path, err)
+	}
+	return info, nil
+}
+
+// entryFromInfo builds the index entry of a sidecar's metadata
+func entryFromInfo(path string, info map[string]interface{}) *Entry {
+	f := bridge.Fields(info)
+	entry := &Entry{
+		FilePath: f.String("file_path"),
+		InfoPath: path,
+		Module:   f.String("module"),
+		URL:      f.String("url"),
+		Title:    f.String("title"),
+		Uploader: f.String("uploader"),
+		Tags:     f.Strings("tags"),
+	}
+	if entry.Uploader == "" {
+		entry.Uploader = f.String("channel")
+	}
+	if nextTo := filepath.Join(filepath.Dir(path), filepath.Base(entry.FilePath)); entry.FilePath != "" && nextTo != entry.FilePath {
+		if _, err := os.Stat(nextTo); err == nil {
+			entry.FilePath = nextTo
+		}
+	}
+	entry.DownloadedAt, _ = time.Parse(time.RFC3339, f.String("downloaded_at"))
+	entry.WatchedAt, _ = time.Parse(time.RFC3339, f.String("watched_at"))
+	return entry
+}
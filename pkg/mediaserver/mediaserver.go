@@ -0,0 +1,149 @@
+// Package mediaserver tells Plex and Jellyfin servers about finished
+// downloads, so they appear in their libraries without waiting for the next
+// scheduled scan.
+package mediaserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/httpclient"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// Notifier asks the configured media server to scan new files
+type Notifier struct {
+	config *config.Config
+	server config.MediaServerConfig
+	client *http.Client
+}
+
+// NewNotifier creates a notifier for the configured media server
+func NewNotifier(cfg *config.Config, logger telemetry.Logger) *Notifier {
+	return &Notifier{
+		config: cfg,
+		server: cfg.Integrations.MediaServer,
+		client: httpclient.New(cfg, logger, 15*time.Second),
+	}
+}
+
+// Scan asks the server to scan the files at paths. Plex scans the folders
+// holding them, Jellyfin the files themselves.
+func (n *Notifier) Scan(paths []string) error {
+	if err := n.server.Validate(); err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	switch n.server.Type {
+	case config.MediaServerPlex:
+		return n.scanPlex(paths)
+	case config.MediaServerJellyfin:
+		return n.scanJellyfin(paths)
+	}
+	return fmt.Errorf("no media server configured")
+}
+
+// Ping checks that the server is reachable
+func (n *Notifier) Ping() error {
+	if err := n.server.Validate(); err != nil {
+		return err
+	}
+	endpoint := &httpclient.PlexIdentity
+	if n.server.Type == config.MediaServerJellyfin {
+		endpoint = &httpclient.JellyfinInfo
+	}
+	return n.do(endpoint, endpoint.URL(n.config), nil)
+}
+
+// scanPlex refreshes the library section for each folder holding a file.
+// Without a section, every library is refreshed once.
+func (n *Notifier) scanPlex(paths []string) error {
+	if n.server.Section == "" {
+		return n.do(&httpclient.PlexRefresh, httpclient.PlexRefresh.URL(n.config, "all"), nil)
+	}
+
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		dirs[filepath.Dir(path)] = true
+	}
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Strings(sorted)
+
+	for _, dir := range sorted {
+		target := httpclient.PlexRefresh.URL(n.config, n.server.Section) + "?path=" + url.QueryEscape(dir)
+		if err := n.do(&httpclient.PlexRefresh, target, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanJellyfin reports the files as created
+func (n *Notifier) scanJellyfin(paths []string) error {
+	type update struct {
+		Path       string `json:"Path"`
+		UpdateType string `json:"UpdateType"`
+	}
+	body := struct {
+		Updates []update `json:"Updates"`
+	}{}
+	for _, path := range paths {
+		body.Updates = append(body.Updates, update{Path: path, UpdateType: "Created"})
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return n.do(&httpclient.JellyfinMediaUpdated, httpclient.JellyfinMediaUpdated.URL(n.config), data)
+}
+
+// do sends a request to the server with its token
+func (n *Notifier) do(endpoint *httpclient.Endpoint, target string, body []byte) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(endpoint.Method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	switch n.server.Type {
+	case config.MediaServerPlex:
+		req.Header.Set("X-Plex-Token", n.server.Token)
+	case config.MediaServerJellyfin:
+		req.Header.Set("Authorization", fmt.Sprintf("MediaBrowser Token=%q", n.server.Token))
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", n.server.Type, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return fmt.Errorf("%s rejected the token (HTTP %d)", n.server.Type, resp.StatusCode)
+		}
+		return fmt.Errorf("%s returned HTTP %d: %s", n.server.Type, resp.StatusCode, bytes.TrimSpace(message))
+	}
+	return nil
+}
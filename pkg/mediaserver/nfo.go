@@ -0,0 +1,79 @@
+package mediaserver
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/fileutil"
+)
+
+// nfoMovie is the Kodi .nfo format Plex agents and Jellyfin read local
+// metadata from
+type nfoMovie struct {
+	XMLName   xml.Name     `xml:"movie"`
+	Title     string       `xml:"title"`
+	Plot      string       `xml:"plot,omitempty"`
+	Studio    string       `xml:"studio,omitempty"`
+	Premiered string       `xml:"premiered,omitempty"`
+	Year      string       `xml:"year,omitempty"`
+	Runtime   int          `xml:"runtime,omitempty"`
+	Tags      []string     `xml:"tag,omitempty"`
+	UniqueID  *nfoUniqueID `xml:"uniqueid,omitempty"`
+}
+
+// nfoUniqueID is the ID of the video on the site it came from
+type nfoUniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr"`
+	ID      string `xml:",chardata"`
+}
+
+// NFOPath returns the .nfo file of a media file: video.mp4 has video.nfo
+func NFOPath(filePath string) string {
+	return strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ".nfo"
+}
+
+// WriteNFO writes a .nfo file next to a download with the metadata its
+// module reported
+func WriteNFO(module string, data map[string]interface{}) (string, error) {
+	f := bridge.Fields(data)
+	filePath := f.String("file_path")
+	if filePath == "" {
+		return "", fmt.Errorf("download result has no file_path")
+	}
+
+	movie := nfoMovie{
+		Title:   f.String("title"),
+		Plot:    f.String("description"),
+		Studio:  f.String("uploader"),
+		Runtime: int(f.Duration("duration").Minutes()),
+		Tags:    f.Strings("tags"),
+	}
+	if movie.Title == "" {
+		movie.Title = strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	}
+	if movie.Studio == "" {
+		movie.Studio = f.String("channel")
+	}
+	if date, ok := f.UploadDate(); ok {
+		movie.Premiered = date.Format("2006-01-02")
+		movie.Year = date.Format("2006")
+	}
+	if id := f.String("id"); id != "" {
+		movie.UniqueID = &nfoUniqueID{Type: module, Default: true, ID: id}
+	}
+
+	encoded, err := xml.MarshalIndent(movie, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	path := NFOPath(filePath)
+	content := append([]byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n"), encoded...)
+	if err := fileutil.WriteAtomic(path, append(content, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,545 @@
+package mockapi
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/auth"
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/telemetry"
+	"github.com/converso-empire/cli/pkg/transfer"
+	"github.com/converso-empire/cli/pkg/worker"
+)
+
+// Paths served by the mock backend. The OAuth paths mirror the layout of
+// the default auth_url and token_url.
+const (
+	AuthPath           = "/oauth/authorize"
+	DeviceCodePath     = AuthPath + "/device/code"
+	DeviceCancelPath   = AuthPath + "/device/cancel"
+	TokenPath          = "/oauth/token"
+	RegisterDevicePath = "/api/v1/devices/register"
+	JobsPath           = "/api/v1/jobs"
+	PendingJobsPath    = JobsPath + "/pending"
+	WorkerStatusPath   = "/api/v1/worker/status"
+)
+
+// Lifetimes of issued access tokens and exchanged module tokens
+const (
+	tokenLifetime       = time.Hour
+	moduleTokenLifetime = 15 * time.Minute
+)
+
+// Options configures the mock backend
+type Options struct {
+	// Strict rejects bearer tokens the server did not issue; otherwise any
+	// non-empty token is accepted, such as CONVERSO_ACCESS_TOKEN in headless
+	// mode
+	Strict bool
+
+	// ApproveAfter delays approval of device codes by a number of token
+	// polls, to exercise the authorization_pending path
+	ApproveAfter int
+}
+
+// Server is an in-memory fake of the Converso backend covering device
+// authorization, token issue and refresh, device registration and the
+// worker job endpoints
+type Server struct {
+	options Options
+	logger  telemetry.Logger
+
+	mu           sync.Mutex
+	deviceCodes  map[string]int
+	tokens       map[string]bool
+	refresh      map[string]bool
+	devices      map[string]auth.RegisterDeviceRequest
+	jobs         map[string]*worker.Job
+	dispatched   map[string]bool
+	progress     map[string][]*bridge.ProgressEvent
+	files        map[string]*jobFile
+	workerStatus map[string]interface{}
+}
+
+// jobFile is a file uploaded by a worker for other devices
+type jobFile struct {
+	name     string
+	checksum string
+	data     []byte
+	uploaded time.Time
+}
+
+// NewServer creates a new mock backend
+func NewServer(options Options, logger telemetry.Logger) *Server {
+	return &Server{
+		options:     options,
+		logger:      logger,
+		deviceCodes: make(map[string]int),
+		tokens:      make(map[string]bool),
+		refresh:     make(map[string]bool),
+		devices:     make(map[string]auth.RegisterDeviceRequest),
+		jobs:        make(map[string]*worker.Job),
+		dispatched:  make(map[string]bool),
+		progress:    make(map[string][]*bridge.ProgressEvent),
+		files:       make(map[string]*jobFile),
+	}
+}
+
+// Handler returns the HTTP handler of the mock backend. Tests can serve it
+// with httptest.NewServer.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleRoot)
+	mux.HandleFunc(DeviceCodePath, s.handleDeviceCode)
+	mux.HandleFunc(DeviceCancelPath, s.handleDeviceCancel)
+	mux.HandleFunc(TokenPath, s.handleToken)
+	mux.HandleFunc(RegisterDevicePath, s.requireAuth(s.handleRegisterDevice))
+	mux.HandleFunc(JobsPath, s.requireAuth(s.handleJobs))
+	mux.HandleFunc(JobsPath+"/", s.requireAuth(s.handleJob))
+	mux.HandleFunc(WorkerStatusPath, s.requireAuth(s.handleWorkerStatus))
+	return s.logRequests(mux)
+}
+
+// AddJob queues a job for the worker and returns a copy of it. Missing IDs,
+// timestamps and statuses are filled in.
+func (s *Server) AddJob(job *worker.Job) *worker.Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job.ID == "" {
+		job.ID = "job-" + randomToken(4)
+	}
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+	if job.ExpiresAt.IsZero() {
+		job.ExpiresAt = job.CreatedAt.Add(24 * time.Hour)
+	}
+	if job.Status == "" {
+		job.Status = string(worker.JobStatusPending)
+	}
+
+	s.jobs[job.ID] = job
+	delete(s.dispatched, job.ID)
+
+	added := *job
+	return &added
+}
+
+// Jobs returns copies of all jobs ordered by creation time
+func (s *Server) Jobs() []*worker.Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]*worker.Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		job := *job
+		jobs = append(jobs, &job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// handleRoot answers reachability checks
+func (s *Server) handleRoot(rw http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		writeError(rw, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(rw, http.StatusOK, map[string]string{"service": "converso-mock-api", "status": "ok"})
+}
+
+// handleDeviceCode starts a device authorization
+func (s *Server) handleDeviceCode(rw http.ResponseWriter, r *http.Request) {
+	if !allowMethod(rw, r, http.MethodPost) {
+		return
+	}
+
+	deviceCode := randomToken(16)
+	userCode := strings.ToUpper(randomToken(2) + "-" + randomToken(2))
+
+	s.mu.Lock()
+	s.deviceCodes[deviceCode] = s.options.ApproveAfter
+	s.mu.Unlock()
+
+	verificationURI := "http://" + r.Host + AuthPath
+	writeJSON(rw, http.StatusOK, auth.DeviceAuthResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + userCode,
+		ExpiresIn:               600,
+		Interval:                1,
+	})
+}
+
+// handleDeviceCancel invalidates a pending device code
+func (s *Server) handleDeviceCancel(rw http.ResponseWriter, r *http.Request) {
+	if !allowMethod(rw, r, http.MethodPost) {
+		return
+	}
+
+	var req map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOAuthError(rw, "invalid_request", "invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deviceCodes[req["device_code"]]; !ok {
+		writeOAuthError(rw, "invalid_grant", "unknown device code")
+		return
+	}
+	delete(s.deviceCodes, req["device_code"])
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// handleToken issues tokens for approved device codes and refresh tokens
+func (s *Server) handleToken(rw http.ResponseWriter, r *http.Request) {
+	if !allowMethod(rw, r, http.MethodPost) {
+		return
+	}
+
+	var req map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOAuthError(rw, "invalid_request", "invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req["grant_type"] {
+	case "urn:ietf:params:oauth:grant-type:device_code":
+		remaining, ok := s.deviceCodes[req["device_code"]]
+		if !ok {
+			writeOAuthError(rw, "invalid_grant", "unknown device code")
+			return
+		}
+		if remaining > 0 {
+			s.deviceCodes[req["device_code"]] = remaining - 1
+			writeOAuthError(rw, "authorization_pending", "")
+			return
+		}
+		delete(s.deviceCodes, req["device_code"])
+
+	case "refresh_token":
+		if !s.refresh[req["refresh_token"]] {
+			writeOAuthError(rw, "invalid_grant", "unknown refresh token")
+			return
+		}
+		delete(s.refresh, req["refresh_token"])
+
+	case "urn:ietf:params:oauth:grant-type:token-exchange":
+		if req["subject_token"] == "" || (s.options.Strict && !s.tokens[req["subject_token"]]) {
+			writeOAuthError(rw, "invalid_grant", "unknown subject token")
+			return
+		}
+		if req["audience"] == "" {
+			writeOAuthError(rw, "invalid_target", "missing audience")
+			return
+		}
+		resp := auth.TokenResponse{
+			AccessToken: "mock-module-" + req["audience"] + "-" + randomToken(16),
+			TokenType:   "Bearer",
+			ExpiresIn:   int(moduleTokenLifetime.Seconds()),
+		}
+		s.tokens[resp.AccessToken] = true
+		writeJSON(rw, http.StatusOK, resp)
+		return
+
+	default:
+		writeOAuthError(rw, "unsupported_grant_type", "")
+		return
+	}
+
+	resp := auth.TokenResponse{
+		AccessToken:  "mock-access-" + randomToken(16),
+		TokenType:    "Bearer",
+		ExpiresIn:    int(tokenLifetime.Seconds()),
+		RefreshToken: "mock-refresh-" + randomToken(16),
+		Scope:        "openid profile email",
+	}
+	s.tokens[resp.AccessToken] = true
+	s.refresh[resp.RefreshToken] = true
+	writeJSON(rw, http.StatusOK, resp)
+}
+
+// handleRegisterDevice registers a device
+func (s *Server) handleRegisterDevice(rw http.ResponseWriter, r *http.Request) {
+	if !allowMethod(rw, r, http.MethodPost) {
+		return
+	}
+
+	var req auth.RegisterDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.DeviceID == "" {
+		writeError(rw, http.StatusBadRequest, "invalid device registration")
+		return
+	}
+
+	s.mu.Lock()
+	s.devices[req.DeviceID] = req
+	s.mu.Unlock()
+
+	writeJSON(rw, http.StatusOK, auth.RegisterDeviceResponse{
+		DeviceID:    req.DeviceID,
+		DeviceToken: "mock-device-" + randomToken(16),
+	})
+}
+
+// handleJobs lists all jobs on GET and queues a job on POST
+func (s *Server) handleJobs(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(rw, http.StatusOK, s.Jobs())
+
+	case http.MethodPost:
+		var job worker.Job
+		if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+			writeError(rw, http.StatusBadRequest, "invalid job")
+			return
+		}
+		if job.Module == "" || job.Command == "" {
+			writeError(rw, http.StatusBadRequest, "job requires module and command")
+			return
+		}
+		writeJSON(rw, http.StatusOK, s.AddJob(&job))
+
+	default:
+		rw.Header().Set("Allow", "GET, POST")
+		writeError(rw, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleJob serves the pending queue and per-job status and progress
+func (s *Server) handleJob(rw http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == PendingJobsPath {
+		if allowMethod(rw, r, http.MethodGet) {
+			writeJSON(rw, http.StatusOK, s.dispatchPending(r.URL.Query().Get("device")))
+		}
+		return
+	}
+
+	// /api/v1/jobs/{id}/{status,progress,file}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, JobsPath+"/"), "/")
+	if len(parts) != 2 {
+		writeError(rw, http.StatusNotFound, "not found")
+		return
+	}
+	id, action := parts[0], parts[1]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		writeError(rw, http.StatusNotFound, fmt.Sprintf("job %s not found", id))
+		return
+	}
+
+	switch action {
+	case "status":
+		if !allowMethod(rw, r, http.MethodPut) {
+			return
+		}
+		var update worker.Job
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			writeError(rw, http.StatusBadRequest, "invalid job status")
+			return
+		}
+		job.Status = update.Status
+		job.Progress = update.Progress
+		job.Result = update.Result
+		writeJSON(rw, http.StatusOK, job)
+
+	case "progress":
+		if !allowMethod(rw, r, http.MethodPost) {
+			return
+		}
+		var event bridge.ProgressEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			writeError(rw, http.StatusBadRequest, "invalid progress event")
+			return
+		}
+		job.Progress = &event
+		s.progress[id] = append(s.progress[id], &event)
+		writeJSON(rw, http.StatusOK, map[string]string{"status": "ok"})
+
+	case "file":
+		s.handleJobFile(rw, r, id)
+
+	default:
+		writeError(rw, http.StatusNotFound, "not found")
+	}
+}
+
+// handleJobFile stores a job's file on PUT and serves it on GET. The
+// caller holds s.mu.
+func (s *Server) handleJobFile(rw http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(rw, http.StatusBadRequest, "failed to read file")
+			return
+		}
+		sum := sha256.Sum256(data)
+		checksum := hex.EncodeToString(sum[:])
+		if expected := r.Header.Get(transfer.ChecksumHeader); expected != checksum {
+			writeError(rw, http.StatusBadRequest, "checksum mismatch")
+			return
+		}
+		name := "file"
+		if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+			name = params["filename"]
+		}
+		s.files[id] = &jobFile{name: name, checksum: checksum, data: data, uploaded: time.Now()}
+		writeJSON(rw, http.StatusOK, map[string]string{"status": "ok"})
+
+	case http.MethodGet:
+		file, ok := s.files[id]
+		if !ok {
+			writeError(rw, http.StatusNotFound, fmt.Sprintf("no file uploaded for job %s", id))
+			return
+		}
+		rw.Header().Set(transfer.ChecksumHeader, file.checksum)
+		http.ServeContent(rw, r, file.name, file.uploaded, bytes.NewReader(file.data))
+
+	default:
+		rw.Header().Set("Allow", "GET, PUT")
+		writeError(rw, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// dispatchPending returns pending jobs that have not been handed out yet
+// and are routed to device or to any device. Unrouted jobs are assigned to
+// device.
+func (s *Server) dispatchPending(device string) []*worker.Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := []*worker.Job{}
+	for id, job := range s.jobs {
+		if job.Status != string(worker.JobStatusPending) || s.dispatched[id] {
+			continue
+		}
+		if job.Device != "" && job.Device != device {
+			continue
+		}
+		s.dispatched[id] = true
+		job.Device = device
+		job := *job
+		jobs = append(jobs, &job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].Priority != jobs[j].Priority {
+			return jobs[i].Priority > jobs[j].Priority
+		}
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// handleWorkerStatus records worker status reports and returns the latest
+func (s *Server) handleWorkerStatus(rw http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(rw, http.StatusOK, s.workerStatus)
+
+	case http.MethodPost:
+		var status map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+			writeError(rw, http.StatusBadRequest, "invalid worker status")
+			return
+		}
+		s.workerStatus = status
+		writeJSON(rw, http.StatusOK, map[string]string{"status": "ok"})
+
+	default:
+		rw.Header().Set("Allow", "GET, POST")
+		writeError(rw, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// requireAuth rejects requests without an acceptable bearer token
+func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || token == r.Header.Get("Authorization") {
+			writeError(rw, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		if s.options.Strict {
+			s.mu.Lock()
+			issued := s.tokens[token]
+			s.mu.Unlock()
+			if !issued {
+				writeError(rw, http.StatusUnauthorized, "unknown access token")
+				return
+			}
+		}
+
+		next(rw, r)
+	}
+}
+
+// logRequests logs each request at debug level
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		s.logger.Debug("Mock API request", "method", r.Method, "path", r.URL.Path)
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// allowMethod writes a 405 response unless the request uses method
+func allowMethod(rw http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	rw.Header().Set("Allow", method)
+	writeError(rw, http.StatusMethodNotAllowed, "method not allowed")
+	return false
+}
+
+// writeJSON writes a JSON response
+func writeJSON(rw http.ResponseWriter, code int, body interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	json.NewEncoder(rw).Encode(body)
+}
+
+// writeError writes a JSON error response
+func writeError(rw http.ResponseWriter, code int, message string) {
+	writeJSON(rw, code, map[string]string{"error": message})
+}
+
+// writeOAuthError writes an OAuth2 error response (RFC 6749 section 5.2)
+func writeOAuthError(rw http.ResponseWriter, code, description string) {
+	writeJSON(rw, http.StatusBadRequest, auth.OAuthError{Code: code, Description: description})
+}
+
+// randomToken returns n random bytes hex encoded
+func randomToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,193 @@
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/fileutil"
+	"github.com/converso-empire/cli/pkg/transfer"
+)
+
+// Checkpoint records the module commands of a pipeline run that
+// succeeded, so an interrupted run can resume without repeating them. It
+// is saved after every command.
+type Checkpoint struct {
+	File      string    `json:"file"`
+	RunID     string    `json:"run_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Steps holds the succeeded commands of every step: one, or one per
+	// foreach item
+	Steps map[string][]CallRecord `json:"steps"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// CallRecord is a module command that succeeded
+type CallRecord struct {
+	Item string `json:"item,omitempty"`
+	// Fingerprint identifies the module, command and expanded arguments
+	Fingerprint string                 `json:"fingerprint"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	// Outputs maps the files named in the result to their SHA-256
+	Outputs    map[string]string `json:"outputs,omitempty"`
+	FinishedAt time.Time         `json:"finished_at"`
+}
+
+// CheckpointPath returns where the checkpoint of a pipeline file is kept
+// under dir
+func CheckpointPath(dir, file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	sum := sha256.Sum256([]byte(file))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// NewCheckpoint creates an empty checkpoint saved to path
+func NewCheckpoint(path, file string) *Checkpoint {
+	return &Checkpoint{File: file, Steps: make(map[string][]CallRecord), path: path}
+}
+
+// LoadCheckpoint reads the checkpoint at path, returning nil when there
+// is none
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	c := &Checkpoint{path: path}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	if c.Steps == nil {
+		c.Steps = make(map[string][]CallRecord)
+	}
+	return c, nil
+}
+
+// Path returns the file the checkpoint is saved to
+func (c *Checkpoint) Path() string {
+	return c.path
+}
+
+// Remove deletes the saved checkpoint
+func (c *Checkpoint) Remove() error {
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	return nil
+}
+
+// lookup returns the record of a command with the same fingerprint whose
+// outputs still exist with the recorded checksums, or nil
+func (c *Checkpoint) lookup(step, fingerprint string) *CallRecord {
+	c.mu.Lock()
+	var found *CallRecord
+	for _, record := range c.Steps[step] {
+		if record.Fingerprint == fingerprint {
+			record := record
+			found = &record
+			break
+		}
+	}
+	c.mu.Unlock()
+
+	if found == nil {
+		return nil
+	}
+	for path, checksum := range found.Outputs {
+		if sum, err := transfer.FileSHA256(path); err != nil || sum != checksum {
+			return nil
+		}
+	}
+	return found
+}
+
+// record adds a succeeded command, checksumming the files in its result,
+// and saves the checkpoint
+func (c *Checkpoint) record(runID, step string, record CallRecord) error {
+	outputs := make(map[string]string)
+	for _, path := range outputFiles(record.Data) {
+		sum, err := transfer.FileSHA256(path)
+		if err != nil {
+			return fmt.Errorf("failed to checksum %s: %w", path, err)
+		}
+		outputs[path] = sum
+	}
+	if len(outputs) > 0 {
+		record.Outputs = outputs
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	records := c.Steps[step][:0:0]
+	for _, existing := range c.Steps[step] {
+		if existing.Fingerprint != record.Fingerprint {
+			records = append(records, existing)
+		}
+	}
+	c.Steps[step] = append(records, record)
+	c.RunID = runID
+	c.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(c.path), err)
+	}
+	if err := fileutil.WriteAtomic(c.path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// fingerprint identifies a command by its module, command and arguments
+func fingerprint(step *Step, args map[string]interface{}) string {
+	data, _ := json.Marshal(struct {
+		Module  string                 `json:"module"`
+		Command string                 `json:"command"`
+		Args    map[string]interface{} `json:"args"`
+	}{step.Module, step.Command, args})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// outputFiles returns the existing files a result names in fields such as
+// file_path, output or thumbnail_file
+func outputFiles(data map[string]interface{}) []string {
+	var files []string
+	for key, value := range data {
+		path, ok := value.(string)
+		if !ok || path == "" || !outputKey(key) {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// outputKey reports whether a result field names a file
+func outputKey(key string) bool {
+	switch key {
+	case "file", "path", "output":
+		return true
+	}
+	return strings.HasSuffix(key, "_path") || strings.HasSuffix(key, "_file")
+}
@@ -0,0 +1,331 @@
+// Package pipeline runs sequences of module commands defined in YAML files,
+// such as download, transcribe, convert and upload. Steps may depend on
+// other steps, run only when a condition holds and use the pipeline's
+// variables, the environment, the date and the results of the steps they
+// need in their arguments. Parallel groups run several steps at once, and a
+// foreach step runs its command once per item of a list.
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/interpolate"
+	"github.com/converso-empire/cli/pkg/query"
+	"github.com/spf13/viper"
+)
+
+var stepIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Pipeline is a parsed pipeline file
+type Pipeline struct {
+	Name string `mapstructure:"name"`
+	// Vars are referenced as ${vars.name} in step arguments and as
+	// vars.name in conditions. Their values may reference the environment
+	// and the date.
+	Vars  map[string]string `mapstructure:"vars"`
+	Steps []*Step           `mapstructure:"steps"`
+
+	// Path is the file the pipeline was loaded from
+	Path string `mapstructure:"-"`
+
+	// now is the time ${date:...} references format, the same for every
+	// step
+	now time.Time
+}
+
+// Step runs one module command
+type Step struct {
+	ID      string                 `mapstructure:"id"`
+	Module  string                 `mapstructure:"module"`
+	Command string                 `mapstructure:"command"`
+	Args    map[string]interface{} `mapstructure:"args"`
+	// Needs lists the steps that must run first. The step is skipped
+	// unless they all succeeded, or, when it has a condition, unless the
+	// condition holds. Arguments may reference ${result.<step>.<field>}
+	// of the steps it needs, directly or indirectly.
+	Needs []string `mapstructure:"needs"`
+	// If is a filter expression over vars.<name>, <step>.status,
+	// <step>.error and the fields of earlier steps' results
+	If string `mapstructure:"if"`
+	// ContinueOnError lets the pipeline go on when the step fails
+	ContinueOnError bool `mapstructure:"continue_on_error"`
+	// Foreach runs the command once per item, a list or a string of
+	// whitespace-separated items, with ${item} and ${index} (from 1) in the
+	// arguments. The step fails when any item fails.
+	Foreach interface{} `mapstructure:"foreach"`
+
+	// Parallel makes the entry a group of steps that run at the same time
+	// instead of a step of its own
+	Parallel []*Step `mapstructure:"parallel"`
+
+	condition *query.Query
+}
+
+// Load reads and validates a pipeline file. vars override the variables
+// the file defines.
+func Load(path string, vars map[string]string) (*Pipeline, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read pipeline: %w", err)
+	}
+
+	p := &Pipeline{Path: path, now: time.Now()}
+	if err := v.UnmarshalExact(p); err != nil {
+		return nil, fmt.Errorf("invalid pipeline %s: %w", path, err)
+	}
+	if p.Vars == nil {
+		p.Vars = make(map[string]string)
+	}
+	for name, value := range vars {
+		p.Vars[strings.ToLower(name)] = value
+	}
+	for name, value := range p.Vars {
+		expanded, err := interpolate.Expand(value, &interpolate.Context{Now: p.now})
+		if err != nil {
+			return nil, fmt.Errorf("invalid pipeline %s: variable %s: %w", path, name, err)
+		}
+		p.Vars[name] = expanded
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid pipeline %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate checks that the steps are well formed, that their dependencies
+// exist and form no cycle, and that their arguments only reference
+// defined variables and the results of steps they need
+func (p *Pipeline) Validate() error {
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("no steps")
+	}
+
+	for i, entry := range p.Steps {
+		if entry == nil || entry.Parallel == nil {
+			continue
+		}
+		if entry.ID != "" || entry.Module != "" || entry.Command != "" || entry.Args != nil ||
+			entry.Needs != nil || entry.If != "" || entry.Foreach != nil || entry.ContinueOnError {
+			return fmt.Errorf("step %d: a parallel group only lists its steps", i+1)
+		}
+		if len(entry.Parallel) == 0 {
+			return fmt.Errorf("step %d: empty parallel group", i+1)
+		}
+		for _, member := range entry.Parallel {
+			if member != nil && member.Parallel != nil {
+				return fmt.Errorf("step %d: parallel groups cannot be nested", i+1)
+			}
+		}
+	}
+
+	steps := p.steps()
+	ids := make(map[string]bool, len(steps))
+	for i, step := range steps {
+		if step == nil || step.ID == "" {
+			return fmt.Errorf("step %d has no id", i+1)
+		}
+		if !stepIDPattern.MatchString(step.ID) {
+			return fmt.Errorf("invalid step id %q, use lowercase letters, digits, '-' and '_'", step.ID)
+		}
+		if ids[step.ID] {
+			return fmt.Errorf("duplicate step id %q", step.ID)
+		}
+		ids[step.ID] = true
+
+		if step.Module == "" || step.Command == "" {
+			return fmt.Errorf("step %s: module and command are required", step.ID)
+		}
+		if step.If != "" {
+			condition, err := query.Parse(step.If)
+			if err != nil {
+				return fmt.Errorf("step %s: %w", step.ID, err)
+			}
+			step.condition = condition
+		}
+		switch items := step.Foreach.(type) {
+		case nil, string:
+		case []interface{}:
+			for _, item := range items {
+				switch item.(type) {
+				case map[string]interface{}, []interface{}, nil:
+					return fmt.Errorf("step %s: foreach items must be single values", step.ID)
+				}
+			}
+		default:
+			return fmt.Errorf("step %s: foreach must be a list or a string", step.ID)
+		}
+	}
+
+	for _, step := range steps {
+		for _, need := range step.Needs {
+			if !ids[need] {
+				return fmt.Errorf("step %s needs unknown step %q", step.ID, need)
+			}
+		}
+	}
+	for _, entry := range p.Steps {
+		for _, member := range entry.Parallel {
+			for _, other := range entry.Parallel {
+				if containsString(member.Needs, other.ID) {
+					return fmt.Errorf("step %s needs %s, which runs in the same parallel group", member.ID, other.ID)
+				}
+			}
+		}
+	}
+	if _, err := p.Order(); err != nil {
+		return err
+	}
+
+	for _, step := range steps {
+		if err := p.checkReferences(step); err != nil {
+			return fmt.Errorf("step %s: %w", step.ID, err)
+		}
+	}
+	return nil
+}
+
+// steps returns every step, with the members of parallel groups in place
+// of their group
+func (p *Pipeline) steps() []*Step {
+	var steps []*Step
+	for _, entry := range p.Steps {
+		if entry != nil && entry.Parallel != nil {
+			steps = append(steps, entry.Parallel...)
+			continue
+		}
+		steps = append(steps, entry)
+	}
+	return steps
+}
+
+// checkReferences checks the ${...} references in a step's arguments and
+// foreach list. Only the arguments of a foreach step may use ${item} and
+// ${index}.
+func (p *Pipeline) checkReferences(step *Step) error {
+	needed := p.needed(step, make(map[string]bool))
+	check := func(texts []string, perItem bool) error {
+		for _, text := range texts {
+			tmpl, err := interpolate.Parse(text)
+			if err != nil {
+				return err
+			}
+			for _, path := range tmpl.Paths() {
+				segments := strings.Split(path, ".")
+				switch {
+				case segments[0] == "vars" && len(segments) == 2:
+					if _, ok := p.Vars[segments[1]]; !ok {
+						return fmt.Errorf("undefined variable %q in ${%s} (variable names are lowercase)", segments[1], path)
+					}
+				case segments[0] == "result" && len(segments) >= 3:
+					if !needed[segments[1]] {
+						return fmt.Errorf("${%s} refers to step %q, which the step does not need", path, segments[1])
+					}
+				case path == "item" || path == "index":
+					if !perItem {
+						return fmt.Errorf("${%s} is only defined in the arguments of a foreach step", path)
+					}
+				default:
+					return fmt.Errorf("unknown reference ${%s}, use vars.<name> or result.<step>.<field>", path)
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := check(interpolate.Strings(step.Foreach), false); err != nil {
+		return fmt.Errorf("foreach: %w", err)
+	}
+	return check(interpolate.Strings(step.Args), step.Foreach != nil)
+}
+
+// needed returns the steps a step needs, directly or indirectly
+func (p *Pipeline) needed(step *Step, acc map[string]bool) map[string]bool {
+	for _, id := range step.Needs {
+		if acc[id] {
+			continue
+		}
+		acc[id] = true
+		for _, other := range p.steps() {
+			if other.ID == id {
+				p.needed(other, acc)
+			}
+		}
+	}
+	return acc
+}
+
+// Stage is one or more steps that run at the same time
+type Stage struct {
+	Steps []*Step
+	// Parallel is set for the stage of a parallel group
+	Parallel bool
+}
+
+// Order returns the stages in the order they run: every step after the
+// steps it needs, otherwise in file order. A parallel group is one stage
+// that runs once all of its members' needs are done.
+func (p *Pipeline) Order() ([]Stage, error) {
+	done := make(map[string]bool)
+	order := make([]Stage, 0, len(p.Steps))
+
+	for len(order) < len(p.Steps) {
+		progressed := false
+		for _, entry := range p.Steps {
+			stage := entry.stage()
+			if done[stage.Steps[0].ID] || !stage.ready(done) {
+				continue
+			}
+			for _, step := range stage.Steps {
+				done[step.ID] = true
+			}
+			order = append(order, stage)
+			progressed = true
+			break
+		}
+		if !progressed {
+			var stuck []string
+			for _, step := range p.steps() {
+				if !done[step.ID] {
+					stuck = append(stuck, step.ID)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle between steps %s", strings.Join(stuck, ", "))
+		}
+	}
+	return order, nil
+}
+
+// stage returns the stage of a top-level entry
+func (s *Step) stage() Stage {
+	if s.Parallel != nil {
+		return Stage{Steps: s.Parallel, Parallel: true}
+	}
+	return Stage{Steps: []*Step{s}}
+}
+
+// ready reports whether everything the stage's steps need is done
+func (s Stage) ready(done map[string]bool) bool {
+	for _, step := range s.Steps {
+		for _, id := range step.Needs {
+			if !done[id] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,568 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/interpolate"
+	"github.com/converso-empire/cli/pkg/query"
+)
+
+// StepStatus is the outcome of a step
+type StepStatus string
+
+const (
+	StepSucceeded StepStatus = "succeeded"
+	StepFailed    StepStatus = "failed"
+	StepSkipped   StepStatus = "skipped"
+)
+
+// Summary is the record of a pipeline run, written as its artifact
+type Summary struct {
+	RunID      string       `json:"run_id"`
+	Pipeline   string       `json:"pipeline"`
+	File       string       `json:"file"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	Succeeded  bool         `json:"succeeded"`
+	Steps      []StepResult `json:"steps"`
+}
+
+// Counts returns how many steps succeeded, failed and were skipped
+func (s *Summary) Counts() (succeeded, failed, skipped int) {
+	for _, step := range s.Steps {
+		switch step.Status {
+		case StepSucceeded:
+			succeeded++
+		case StepFailed:
+			failed++
+		case StepSkipped:
+			skipped++
+		}
+	}
+	return
+}
+
+// StepResult is the outcome of a step
+type StepResult struct {
+	ID      string     `json:"id"`
+	Module  string     `json:"module"`
+	Command string     `json:"command"`
+	Status  StepStatus `json:"status"`
+	// StartedAt is unset for skipped steps
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Seconds   float64    `json:"seconds,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	// Reason tells why a step was skipped
+	Reason string                 `json:"reason,omitempty"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+	// Items are the outcomes of a foreach step's items, whose Data counts
+	// them as items, succeeded and failed
+	Items []bridge.ItemResult `json:"items,omitempty"`
+	// Resumed is set when the step's results came from the checkpoint of
+	// an earlier run
+	Resumed bool `json:"resumed,omitempty"`
+}
+
+// Duration returns how long the step ran
+func (r *StepResult) Duration() time.Duration {
+	return time.Duration(r.Seconds * float64(time.Second))
+}
+
+// ExecFunc runs a module command for a step with its expanded arguments,
+// sending the command's progress to progress
+type ExecFunc func(step *Step, args map[string]interface{}, progress chan<- *bridge.ProgressEvent) (*bridge.ModuleResponse, error)
+
+// Options configures Run
+type Options struct {
+	Exec ExecFunc
+	// Concurrency bounds how many module commands run at once across
+	// parallel groups and foreach items, 1 when unset
+	Concurrency int
+	// Progress starts showing the progress of a stage, returning the
+	// channel its commands report to and a function that ends the display.
+	// Commands running at the same time label their events' Item with the
+	// step and item, such as fetch[2].
+	Progress func() (chan<- *bridge.ProgressEvent, func())
+	// Checkpoint, when set, records every command that succeeded
+	Checkpoint *Checkpoint
+	// Resume reuses the commands in the checkpoint whose arguments are
+	// unchanged and whose output files still match their checksums
+	Resume bool
+	// Warn is called when the checkpoint cannot be saved
+	Warn func(err error)
+	// Started is called before a step runs, with its position in the run
+	Started func(step *Step, index, total int)
+	// Calling is called with the expanded arguments of every command
+	// before it runs, and the item of a foreach step
+	Calling func(step *Step, item string, args map[string]interface{})
+	// Finished is called with the outcome of every step, including
+	// skipped ones, once its stage is over
+	Finished func(result *StepResult)
+}
+
+// Run executes the stages in order. A failed step stops the pipeline
+// unless it continues on error: the steps after it are skipped, except
+// those whose condition holds, so a step with
+// "if: fetch.status = failed" can report the failure. The steps of a
+// parallel group and the items of a foreach step all run to the end, even
+// when one of them fails.
+//
+// With a checkpoint, every command that succeeds is recorded; resuming
+// takes the recorded outcome of commands whose arguments and output files
+// are unchanged instead of running them again.
+func (p *Pipeline) Run(runID string, opts Options) (*Summary, error) {
+	stages, err := p.Order()
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		RunID:     runID,
+		Pipeline:  p.Name,
+		File:      p.Path,
+		StartedAt: time.Now(),
+		Succeeded: true,
+	}
+	if p.now.IsZero() {
+		p.now = summary.StartedAt
+	}
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	r := &runner{
+		p:       p,
+		opts:    opts,
+		summary: summary,
+		results: make(map[string]*StepResult),
+		total:   len(p.steps()),
+		slots:   make(chan struct{}, concurrency),
+	}
+
+	for _, stage := range stages {
+		r.runStage(stage)
+	}
+
+	summary.FinishedAt = time.Now()
+	return summary, nil
+}
+
+// runner is the state of a pipeline run
+type runner struct {
+	p       *Pipeline
+	opts    Options
+	summary *Summary
+	results map[string]*StepResult
+	// stopped is the step whose failure stopped the pipeline
+	stopped string
+	// position counts the steps reached so far, total all steps
+	position, total int
+	// slots holds a token for every module command running
+	slots chan struct{}
+}
+
+// planned is a step of a stage and the module commands it runs
+type planned struct {
+	step   *Step
+	result *StepResult
+	calls  []*call
+}
+
+// runStage decides which steps of a stage run, runs their commands at the
+// same time and records the outcomes
+func (r *runner) runStage(stage Stage) {
+	var plans []*planned
+	var calls []*call
+	for _, step := range stage.Steps {
+		r.position++
+		plan := &planned{step: step, result: &StepResult{ID: step.ID, Module: step.Module, Command: step.Command}}
+		r.results[step.ID] = plan.result
+		plans = append(plans, plan)
+
+		if reason := r.skipReason(step); reason != "" {
+			plan.result.Status, plan.result.Reason = StepSkipped, reason
+			continue
+		}
+		stepCalls, err := r.p.calls(step, r.p.context(r.results))
+		if err == nil && len(stepCalls) == 0 {
+			plan.result.Status, plan.result.Reason = StepSkipped, "foreach has no items"
+			continue
+		}
+		if err == nil && r.resume(stepCalls) {
+			plan.calls = stepCalls
+			continue
+		}
+		if r.opts.Started != nil {
+			r.opts.Started(step, r.position, r.total)
+		}
+		if err != nil {
+			started := time.Now()
+			plan.result.StartedAt = &started
+			plan.result.Status, plan.result.Error = StepFailed, err.Error()
+			continue
+		}
+		plan.calls = stepCalls
+		for _, c := range stepCalls {
+			if c.resumed {
+				continue
+			}
+			if r.opts.Calling != nil {
+				r.opts.Calling(step, c.item, c.args)
+			}
+			calls = append(calls, c)
+		}
+	}
+
+	// Commands running side by side tell their progress apart by label
+	if len(calls) > 1 {
+		for _, c := range calls {
+			c.label = c.name
+		}
+	}
+	r.execute(calls)
+
+	for _, plan := range plans {
+		if plan.calls != nil {
+			plan.result.record(plan.step, plan.calls)
+		}
+		if plan.result.Status == StepFailed && !plan.step.ContinueOnError {
+			if r.stopped == "" {
+				r.stopped = plan.step.ID
+			}
+			r.summary.Succeeded = false
+		}
+		r.summary.Steps = append(r.summary.Steps, *plan.result)
+		if r.opts.Finished != nil {
+			r.opts.Finished(plan.result)
+		}
+	}
+}
+
+// skipReason tells why a step is skipped, or returns "" when it runs
+func (r *runner) skipReason(step *Step) string {
+	switch {
+	case step.condition != nil && !step.condition.Match(r.p.getter(r.results)):
+		return fmt.Sprintf("condition not met: %s", step.If)
+	case step.condition == nil && r.stopped != "":
+		return fmt.Sprintf("pipeline stopped after %s failed", r.stopped)
+	case step.condition == nil && !succeeded(step.Needs, r.results):
+		return fmt.Sprintf("needs %s", strings.Join(step.Needs, ", "))
+	}
+	return ""
+}
+
+// execute runs the calls at the same time, as far as the concurrency
+// allows, under one progress display
+func (r *runner) execute(calls []*call) {
+	if len(calls) == 0 {
+		return
+	}
+
+	var progress chan<- *bridge.ProgressEvent
+	var stop func()
+	if r.opts.Progress != nil {
+		progress, stop = r.opts.Progress()
+	} else {
+		discard := make(chan *bridge.ProgressEvent, 100)
+		drained := make(chan struct{})
+		go func() {
+			for range discard {
+			}
+			close(drained)
+		}()
+		progress, stop = discard, func() {
+			close(discard)
+			<-drained
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range calls {
+		wg.Add(1)
+		go func(c *call) {
+			defer wg.Done()
+			r.slots <- struct{}{}
+			defer func() { <-r.slots }()
+			c.run(r.opts.Exec, progress)
+			r.checkpoint(c)
+		}(c)
+	}
+	wg.Wait()
+	stop()
+}
+
+// resume takes the outcome of the calls the checkpoint holds from an
+// earlier run, reporting whether that covers all of them
+func (r *runner) resume(calls []*call) bool {
+	if r.opts.Checkpoint == nil {
+		return false
+	}
+	all := true
+	for _, c := range calls {
+		c.fingerprint = fingerprint(c.step, c.args)
+		if !r.opts.Resume {
+			all = false
+			continue
+		}
+		record := r.opts.Checkpoint.lookup(c.step.ID, c.fingerprint)
+		if record == nil {
+			all = false
+			continue
+		}
+		c.resumed = true
+		c.started, c.finished = record.FinishedAt, record.FinishedAt
+		c.resp = &bridge.ModuleResponse{Success: true, Data: record.Data}
+	}
+	return all
+}
+
+// checkpoint records a call that succeeded
+func (r *runner) checkpoint(c *call) {
+	if r.opts.Checkpoint == nil || c.err != nil || !c.resp.Success {
+		return
+	}
+	err := r.opts.Checkpoint.record(r.summary.RunID, c.step.ID, CallRecord{
+		Item:        c.item,
+		Fingerprint: c.fingerprint,
+		Data:        c.resp.Data,
+		FinishedAt:  c.finished,
+	})
+	if err != nil && r.opts.Warn != nil {
+		r.opts.Warn(err)
+	}
+}
+
+// call is one module command of a step: the step itself or one of its
+// foreach items
+type call struct {
+	step *Step
+	args map[string]interface{}
+	// item is the foreach item, name identifies the call as step or
+	// step[index], and label is set to name when other calls run alongside
+	item, name, label string
+
+	// fingerprint identifies the call in the checkpoint, and resumed is set
+	// when its outcome came from there
+	fingerprint string
+	resumed     bool
+
+	started, finished time.Time
+	resp              *bridge.ModuleResponse
+	err               error
+}
+
+// run executes the call, labelling its progress events when it has a
+// label
+func (c *call) run(exec ExecFunc, progress chan<- *bridge.ProgressEvent) {
+	c.started = time.Now()
+	defer func() { c.finished = time.Now() }()
+
+	if c.label == "" {
+		c.resp, c.err = exec(c.step, c.args, progress)
+		return
+	}
+
+	events := make(chan *bridge.ProgressEvent, 100)
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for event := range events {
+			labelled := *event
+			labelled.Item = c.label
+			if event.Item != "" {
+				labelled.Item += "/" + event.Item
+			}
+			progress <- &labelled
+		}
+	}()
+	c.resp, c.err = exec(c.step, c.args, events)
+	close(events)
+	<-forwarded
+}
+
+// outcome returns the status, error and data of a finished call
+func (c *call) outcome() (StepStatus, string, map[string]interface{}) {
+	switch {
+	case c.err != nil:
+		return StepFailed, c.err.Error(), nil
+	case !c.resp.Success:
+		return StepFailed, c.resp.Error, c.resp.Data
+	default:
+		return StepSucceeded, "", c.resp.Data
+	}
+}
+
+// record sets the outcome of a step from its finished calls. A foreach
+// step fails when any of its items failed.
+func (r *StepResult) record(step *Step, calls []*call) {
+	// The time of a partly resumed step is that of the calls that ran
+	r.Resumed = true
+	for _, c := range calls {
+		r.Resumed = r.Resumed && c.resumed
+	}
+	var started, finished time.Time
+	for _, c := range calls {
+		if c.resumed && !r.Resumed {
+			continue
+		}
+		if started.IsZero() || c.started.Before(started) {
+			started = c.started
+		}
+		if c.finished.After(finished) {
+			finished = c.finished
+		}
+	}
+	r.StartedAt = &started
+	r.Seconds = finished.Sub(started).Seconds()
+
+	if step.Foreach == nil {
+		r.Status, r.Error, r.Data = calls[0].outcome()
+		return
+	}
+
+	failed := 0
+	for _, c := range calls {
+		status, errText, data := c.outcome()
+		if status != StepSucceeded {
+			failed++
+		}
+		r.Items = append(r.Items, bridge.ItemResult{ID: c.item, Success: status == StepSucceeded, Data: data, Error: errText})
+	}
+	r.Data = map[string]interface{}{"items": len(calls), "succeeded": len(calls) - failed, "failed": failed}
+	r.Status = StepSucceeded
+	if failed > 0 {
+		r.Status, r.Error = StepFailed, fmt.Sprintf("%d of %d items failed", failed, len(calls))
+	}
+}
+
+// calls expands a step's arguments into the module commands it runs: one,
+// or one per foreach item
+func (p *Pipeline) calls(step *Step, ctx *interpolate.Context) ([]*call, error) {
+	if step.Foreach == nil {
+		args, err := expandArgs(step.Args, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []*call{{step: step, args: args, name: step.ID}}, nil
+	}
+
+	items, err := foreachItems(step.Foreach, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("foreach: %w", err)
+	}
+	calls := make([]*call, 0, len(items))
+	for i, item := range items {
+		values := make(map[string]interface{}, len(ctx.Values)+2)
+		for key, value := range ctx.Values {
+			values[key] = value
+		}
+		values["item"], values["index"] = item, i+1
+
+		args, err := expandArgs(step.Args, &interpolate.Context{Now: ctx.Now, Values: values})
+		if err != nil {
+			return nil, fmt.Errorf("item %s: %w", item, err)
+		}
+		calls = append(calls, &call{step: step, args: args, item: item, name: fmt.Sprintf("%s[%d]", step.ID, i+1)})
+	}
+	return calls, nil
+}
+
+// expandArgs expands the references in step arguments
+func expandArgs(args map[string]interface{}, ctx *interpolate.Context) (map[string]interface{}, error) {
+	expanded, err := interpolate.ExpandValue(args, ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, _ := expanded.(map[string]interface{})
+	return result, nil
+}
+
+// foreachItems expands a foreach list, or splits a foreach string at
+// whitespace after expanding it. Empty items are dropped.
+func foreachItems(foreach interface{}, ctx *interpolate.Context) ([]string, error) {
+	switch v := foreach.(type) {
+	case string:
+		expanded, err := interpolate.Expand(v, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Fields(expanded), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, value := range v {
+			text, ok := value.(string)
+			if !ok {
+				text = fmt.Sprint(value)
+			}
+			expanded, err := interpolate.Expand(text, ctx)
+			if err != nil {
+				return nil, err
+			}
+			if expanded = strings.TrimSpace(expanded); expanded != "" {
+				items = append(items, expanded)
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("must be a list or a string")
+}
+
+// context returns what the references in step arguments resolve against
+func (p *Pipeline) context(results map[string]*StepResult) *interpolate.Context {
+	vars := make(map[string]interface{}, len(p.Vars))
+	for name, value := range p.Vars {
+		vars[name] = value
+	}
+	data := make(map[string]interface{}, len(results))
+	for id, result := range results {
+		if result.Data != nil {
+			data[id] = result.Data
+		}
+	}
+	return &interpolate.Context{
+		Now:    p.now,
+		Values: map[string]interface{}{"vars": vars, "result": data},
+	}
+}
+
+// succeeded reports whether every step in ids succeeded
+func succeeded(ids []string, results map[string]*StepResult) bool {
+	for _, id := range ids {
+		if result, ok := results[id]; !ok || result.Status != StepSucceeded {
+			return false
+		}
+	}
+	return true
+}
+
+// getter returns the fields conditions are evaluated against:
+// vars.<name>, <step>.status, <step>.error and <step>.<field> for the
+// fields of a step's result
+func (p *Pipeline) getter(results map[string]*StepResult) query.Getter {
+	return func(field string) (string, bool) {
+		prefix, name, ok := strings.Cut(field, ".")
+		if !ok {
+			return "", false
+		}
+		if prefix == "vars" {
+			value, ok := p.Vars[strings.ToLower(name)]
+			return value, ok
+		}
+
+		result, ok := results[prefix]
+		if !ok {
+			return "", false
+		}
+		switch name {
+		case "status":
+			return string(result.Status), true
+		case "error":
+			return result.Error, result.Error != ""
+		}
+		return query.MapGetter(result.Data)(name)
+	}
+}
@@ -0,0 +1,59 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+)
+
+// lookupCommand returns a loaded module providing command. The registry
+// lock is held only for the lookup, so commands run without it.
+func (r *PluginRegistry) lookupCommand(module, command string) (*ModuleInfo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	moduleInfo, exists := r.modules[module]
+	if !exists {
+		return nil, bridge.ErrModuleNotFound(fmt.Sprintf("module %s not found", module))
+	}
+	if !r.config.Policy.CommandAllowed(module, command) {
+		return nil, fmt.Errorf("command %s %s is not allowed by policy %s", module, command, r.config.Policy.Path)
+	}
+	for _, cmd := range moduleInfo.Manifest.Commands {
+		if cmd == command {
+			return moduleInfo, nil
+		}
+	}
+	return nil, fmt.Errorf("command %s not available in module %s", command, module)
+}
+
+// acquireSlot waits until module may run another command, or ctx ends, and
+// returns the function releasing the slot. Modules run up to their
+// configured concurrency at once; other modules are not held up.
+func (r *PluginRegistry) acquireSlot(ctx context.Context, module string) (func(), error) {
+	limit := r.config.ModuleConcurrency(module)
+	if limit <= 0 {
+		return func() {}, nil
+	}
+
+	r.slotsMu.Lock()
+	slots, ok := r.slots[module]
+	if !ok {
+		slots = make(chan struct{}, limit)
+		r.slots[module] = slots
+	}
+	r.slotsMu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+	default:
+		r.logger.Debug("Waiting for a module slot", "module", module, "limit", limit)
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("gave up waiting to run a command of module %s: %w", module, ctx.Err())
+		}
+	}
+	return func() { <-slots }, nil
+}
@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/auth"
+	"github.com/converso-empire/cli/pkg/config"
+)
+
+// ContentBlockedError is returned for downloads the content filter of the
+// administrator policy refuses
+type ContentBlockedError struct {
+	URL    string
+	Reason string
+	Policy string
+}
+
+func (e *ContentBlockedError) Error() string {
+	return fmt.Sprintf("%s is blocked by policy %s: %s", e.URL, e.Policy, e.Reason)
+}
+
+// checkContent looks up a download with the module's info command and
+// refuses it when the content policy does not allow it. Modules without an
+// info command cannot download anything while a content policy is in force.
+func (r *PluginRegistry) checkContent(ctx context.Context, module *ModuleInfo, args map[string]interface{}, authTokens *auth.AuthTokens) error {
+	filter := r.config.Policy.ContentFilter()
+	if filter == nil {
+		return nil
+	}
+
+	name := module.Manifest.Name
+	url, _ := args["url"].(string)
+	blocked := func(reason string) error {
+		return &ContentBlockedError{URL: url, Reason: reason, Policy: r.config.Policy.Path}
+	}
+	if !module.HasCommand("info") {
+		return blocked(fmt.Sprintf("module %s cannot report the metadata the content filter needs", name))
+	}
+
+	req, err := r.newRequest(ctx, name, "info", map[string]interface{}{"url": url}, authTokens)
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+	resp, err := r.bridge.Execute(ctx, name, req)
+	r.recordStats(name, "info", started, resp, err)
+	r.storeCookies(name, resp)
+	if err != nil {
+		return fmt.Errorf("failed to check content policy: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("failed to check content policy: %s", resp.Error)
+	}
+
+	if reason := contentViolation(filter, resp.Data); reason != "" {
+		r.logger.Warn("Download blocked by content policy", "module", name, "url", url, "reason", reason)
+		return blocked(reason)
+	}
+	return nil
+}
+
+// contentViolation returns why the metadata an info command reported breaks
+// the content policy, or "". Metadata follows yt-dlp: age_limit, is_live or
+// live_status, and duration in seconds.
+func contentViolation(filter *config.ContentPolicy, info map[string]interface{}) string {
+	live := info["is_live"] == true || info["live_status"] == "is_live" || info["live_status"] == "is_upcoming"
+
+	if filter.BlockAgeRestricted {
+		if limit, _ := info["age_limit"].(float64); limit > 0 || info["age_restricted"] == true {
+			return "it is age-restricted"
+		}
+	}
+	if filter.BlockLive && live {
+		return "it is a live stream"
+	}
+	if filter.MaxDuration > 0 {
+		if live {
+			return fmt.Sprintf("live streams may run longer than %s", filter.MaxDuration)
+		}
+		duration, ok := contentDuration(info["duration"])
+		if !ok {
+			return fmt.Sprintf("its length is unknown and may exceed %s", filter.MaxDuration)
+		}
+		if duration > filter.MaxDuration {
+			return fmt.Sprintf("it runs %s, longer than %s", duration, filter.MaxDuration)
+		}
+	}
+	return ""
+}
+
+// contentDuration reads a duration given in seconds or as [h:]mm:ss
+func contentDuration(value interface{}) (time.Duration, bool) {
+	switch v := value.(type) {
+	case float64:
+		return time.Duration(v * float64(time.Second)), v > 0
+	case string:
+		var seconds int
+		for _, part := range strings.Split(v, ":") {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, false
+			}
+			seconds = seconds*60 + n
+		}
+		return time.Duration(seconds) * time.Second, seconds > 0
+	}
+	return 0, false
+}
@@ -0,0 +1,301 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+	"github.com/converso-empire/cli/pkg/httpclient"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// depsCacheTTL is how long checked versions are reused before the package
+// index is asked again
+const depsCacheTTL = 24 * time.Hour
+
+// installedVersionsScript prints the installed versions of the packages
+// given as arguments as a JSON object, leaving out those not installed
+const installedVersionsScript = `import json, sys
+from importlib import metadata
+versions = {}
+for name in sys.argv[1:]:
+    try:
+        versions[name] = metadata.version(name)
+    except metadata.PackageNotFoundError:
+        pass
+print(json.dumps(versions))`
+
+// Dependency is an installed Python package a module depends on
+type Dependency struct {
+	Module    string `json:"module"`
+	Package   string `json:"package"`
+	Installed string `json:"installed"`
+	// Latest is the newest release on the package index, empty when it
+	// could not be looked up
+	Latest string `json:"latest,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Outdated reports whether a newer release is available
+func (d Dependency) Outdated() bool {
+	return d.Latest != "" && CompareVersions(d.Installed, d.Latest) < 0
+}
+
+// depsCacheEntry is the last check of a module's dependencies
+type depsCacheEntry struct {
+	CheckedAt    time.Time    `json:"checked_at"`
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// DependencyChecker compares the Python packages modules depend on with
+// the package index and upgrades them in the modules' virtual environments
+type DependencyChecker struct {
+	config    *config.Config
+	logger    telemetry.Logger
+	client    *http.Client
+	cachePath string
+	mu        sync.Mutex
+}
+
+// NewDependencyChecker creates a dependency checker
+func NewDependencyChecker(cfg *config.Config, logger telemetry.Logger) *DependencyChecker {
+	return &DependencyChecker{
+		config:    cfg,
+		logger:    logger,
+		client:    httpclient.New(cfg, logger, 5*time.Second),
+		cachePath: filepath.Join(cfg.DataDir, "dependencies.json"),
+	}
+}
+
+// Check returns the module's dependencies that are installed Python
+// packages, with their latest versions. Results less than a day old are
+// reused unless refresh is set. Dependencies that are not Python packages,
+// such as ffmpeg, are left out.
+func (c *DependencyChecker) Check(module *ModuleInfo, refresh bool) ([]Dependency, error) {
+	name := module.Manifest.Name
+	if !refresh {
+		if entry, ok := c.cached(name); ok && time.Since(entry.CheckedAt) < depsCacheTTL {
+			return entry.Dependencies, nil
+		}
+	}
+
+	installed, err := installedVersions(c.python(module.Path), module.Manifest.Dependencies)
+	if err != nil {
+		return nil, err
+	}
+
+	var deps []Dependency
+	for _, pkg := range module.Manifest.Dependencies {
+		version, ok := installed[pkg]
+		if !ok {
+			continue
+		}
+		dep := Dependency{Module: name, Package: pkg, Installed: version}
+		if dep.Latest, err = c.latestVersion(pkg); err != nil {
+			dep.Error = err.Error()
+		}
+		deps = append(deps, dep)
+	}
+
+	if err := c.store(name, &depsCacheEntry{CheckedAt: time.Now(), Dependencies: deps}); err != nil {
+		c.logger.Warn("Failed to cache dependency check", "module", name, "error", err)
+	}
+	return deps, nil
+}
+
+// Upgrade installs the latest versions of packages into the module's
+// virtual environment, writing pip's output to out. A module without one
+// gets a virtual environment that still sees the system packages, so
+// dependencies installed there keep working.
+func (c *DependencyChecker) Upgrade(module *ModuleInfo, packages []string, out io.Writer) error {
+	venv := bridge.VenvDir(module.Path)
+	if bridge.ModulePython(module.Path, "") == "" {
+		fmt.Fprintf(out, "Creating virtual environment %s\n", venv)
+		create := exec.Command(bridge.GetPythonPath(), "-m", "venv", "--system-site-packages", venv)
+		create.Stdout, create.Stderr = out, out
+		if err := create.Run(); err != nil {
+			return fmt.Errorf("failed to create virtual environment: %w", err)
+		}
+	}
+
+	args := []string{"-m", "pip", "install", "--upgrade", "--disable-pip-version-check"}
+	if index := strings.TrimSuffix(c.config.Dependencies.IndexURL, "/"); index != "" && index != config.DefaultIndexURL {
+		args = append(args, "--index-url", index+"/simple")
+	}
+	pip := exec.Command(bridge.ModulePython(module.Path, ""), append(args, packages...)...)
+	pip.Stdout, pip.Stderr = out, out
+	if err := pip.Run(); err != nil {
+		return fmt.Errorf("pip failed: %w", err)
+	}
+
+	if err := c.store(module.Manifest.Name, nil); err != nil {
+		c.logger.Warn("Failed to clear dependency check", "module", module.Manifest.Name, "error", err)
+	}
+	return nil
+}
+
+// python returns the interpreter of the module at path
+func (c *DependencyChecker) python(path string) string {
+	return bridge.ModulePython(path, bridge.GetPythonPath())
+}
+
+// latestVersion looks up the newest release of a package
+func (c *DependencyChecker) latestVersion(pkg string) (string, error) {
+	resp, err := c.client.Get(httpclient.PackageRelease.URL(c.config, pkg))
+	if err != nil {
+		return "", fmt.Errorf("failed to reach the package index: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("package index returned HTTP %d for %s", resp.StatusCode, pkg)
+	}
+	var release struct {
+		Info struct {
+			Version string `json:"version"`
+		} `json:"info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", fmt.Errorf("invalid package index response: %w", err)
+	}
+	return release.Info.Version, nil
+}
+
+// cached returns the last check of a module
+func (c *DependencyChecker) cached(module string) (*depsCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, err := c.load()
+	if err != nil {
+		return nil, false
+	}
+	entry, ok := entries[module]
+	return entry, ok
+}
+
+// store saves the check of a module, or forgets it when entry is nil
+func (c *DependencyChecker) store(module string, entry *depsCacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries, err := c.load()
+	if err != nil {
+		entries = make(map[string]*depsCacheEntry)
+	}
+	if entry == nil {
+		delete(entries, module)
+	} else {
+		entries[module] = entry
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.cachePath), 0755); err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(c.cachePath, append(data, '\n'), 0644)
+}
+
+// load reads the cached checks. c.mu must be held.
+func (c *DependencyChecker) load() (map[string]*depsCacheEntry, error) {
+	entries := make(map[string]*depsCacheEntry)
+	data, err := os.ReadFile(c.cachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// installedVersions returns the installed versions of packages for python
+func installedVersions(python string, packages []string) (map[string]string, error) {
+	versions := make(map[string]string)
+	if len(packages) == 0 {
+		return versions, nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(python, append([]string{"-c", installedVersionsScript}, packages...)...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read installed packages with %s: %w: %s", python, err, strings.TrimSpace(stderr.String()))
+	}
+	if err := json.Unmarshal(output, &versions); err != nil {
+		return nil, fmt.Errorf("failed to read installed packages: %w", err)
+	}
+	return versions, nil
+}
+
+// CompareVersions compares two release versions such as 2024.08.06 or
+// 1.2.0rc1 part by part, returning -1, 0 or 1. A pre-release sorts before
+// its release.
+func CompareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		if c := comparePart(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// comparePart compares one dot-separated part of a version: its number
+// first, then a suffix such as rc1, where no suffix is the newer
+func comparePart(a, b string) int {
+	an, asuffix := splitNumber(a)
+	bn, bsuffix := splitNumber(b)
+	switch {
+	case an != bn:
+		if an < bn {
+			return -1
+		}
+		return 1
+	case asuffix == bsuffix:
+		return 0
+	case asuffix == "":
+		return 1
+	case bsuffix == "":
+		return -1
+	case asuffix < bsuffix:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// splitNumber splits a version part into its leading number and the rest
+func splitNumber(part string) (int, string) {
+	end := 0
+	for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(part[:end])
+	return n, part[end:]
+}
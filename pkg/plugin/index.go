@@ -0,0 +1,411 @@
+package plugin
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/httpclient"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// maxArchiveSize bounds the plugin archives downloaded from the registry
+const maxArchiveSize = 512 << 20
+
+// Index is the plugin registry's index.json
+type Index struct {
+	Plugins []IndexPlugin `json:"plugins"`
+}
+
+// IndexPlugin is a plugin in the registry with its published releases
+type IndexPlugin struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Author      string         `json:"author,omitempty"`
+	Releases    []IndexRelease `json:"releases"`
+}
+
+// IndexRelease is a published version of a plugin. URL points to a
+// .tar.gz or .zip archive and may be relative to the index.
+type IndexRelease struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	SHA256  string `json:"sha256"`
+}
+
+// Latest returns the newest release of the plugin, nil when it has none
+func (p *IndexPlugin) Latest() *IndexRelease {
+	var latest *IndexRelease
+	for i := range p.Releases {
+		if latest == nil || CompareVersions(p.Releases[i].Version, latest.Version) > 0 {
+			latest = &p.Releases[i]
+		}
+	}
+	return latest
+}
+
+// Release returns the release with the version, or the latest one when
+// version is empty
+func (p *IndexPlugin) Release(version string) (*IndexRelease, error) {
+	if version == "" {
+		if latest := p.Latest(); latest != nil {
+			return latest, nil
+		}
+		return nil, fmt.Errorf("plugin %s has no releases in the registry", p.Name)
+	}
+	for i := range p.Releases {
+		if p.Releases[i].Version == version {
+			return &p.Releases[i], nil
+		}
+	}
+
+	var versions []string
+	for _, release := range p.Releases {
+		versions = append(versions, release.Version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return CompareVersions(versions[i], versions[j]) > 0 })
+	return nil, fmt.Errorf("plugin %s has no version %s in the registry (available: %s)", p.Name, version, strings.Join(versions, ", "))
+}
+
+// IndexClient looks plugins up in the registry at plugins.index_url and
+// downloads them, keeping verified archives in a cache so reinstalling a
+// version does not download it again
+type IndexClient struct {
+	config   *config.Config
+	logger   telemetry.Logger
+	client   *http.Client
+	cacheDir string
+}
+
+// NewIndexClient creates a plugin registry client
+func NewIndexClient(cfg *config.Config, logger telemetry.Logger) *IndexClient {
+	return &IndexClient{
+		config:   cfg,
+		logger:   logger,
+		client:   httpclient.New(cfg, logger, 5*time.Minute),
+		cacheDir: ArchiveCacheDir(cfg),
+	}
+}
+
+// ArchiveCacheDir returns where downloaded plugin archives are kept
+func ArchiveCacheDir(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "cache", "plugins")
+}
+
+// Fetch downloads the registry index
+func (c *IndexClient) Fetch() (*Index, error) {
+	if c.config.Plugins.IndexURL == "" {
+		return nil, fmt.Errorf("no plugin registry is configured. Set plugins.index_url")
+	}
+	resp, err := c.client.Get(httpclient.PluginIndex.URL(c.config))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the plugin registry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("plugin registry returned HTTP %d for %s", resp.StatusCode, c.config.Plugins.IndexURL)
+	}
+	var index Index
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, fmt.Errorf("invalid plugin registry index: %w", err)
+	}
+	return &index, nil
+}
+
+// Search returns the plugins whose name or description contains query,
+// ignoring case, sorted by name. An empty query matches every plugin.
+func (c *IndexClient) Search(query string) ([]IndexPlugin, error) {
+	index, err := c.Fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(query)
+	var matches []IndexPlugin
+	for _, p := range index.Plugins {
+		if strings.Contains(strings.ToLower(p.Name), query) || strings.Contains(strings.ToLower(p.Description), query) {
+			matches = append(matches, p)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Name < matches[j].Name })
+	return matches, nil
+}
+
+// Lookup returns a plugin of the registry by name
+func (c *IndexClient) Lookup(name string) (*IndexPlugin, error) {
+	index, err := c.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	for i := range index.Plugins {
+		if index.Plugins[i].Name == name {
+			return &index.Plugins[i], nil
+		}
+	}
+	return nil, fmt.Errorf("plugin %s is not in the registry. Find plugins with 'converso plugin search'", name)
+}
+
+// Download fetches a release of a plugin, or takes it from the cache,
+// checks its SHA-256 checksum and unpacks it into a temporary directory.
+// It returns the directory holding the plugin's manifest.json; the caller
+// removes dir when done.
+func (c *IndexClient) Download(p *IndexPlugin, release *IndexRelease) (dir, moduleDir string, err error) {
+	if release.SHA256 == "" {
+		return "", "", fmt.Errorf("plugin %s %s has no checksum in the registry", p.Name, release.Version)
+	}
+	source, err := c.archiveURL(release.URL)
+	if err != nil {
+		return "", "", err
+	}
+
+	archive, err := c.cachedArchive(p.Name, release, source)
+	if err != nil {
+		return "", "", err
+	}
+
+	dir, err = os.MkdirTemp("", "converso-plugin-")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	if strings.HasSuffix(strings.ToLower(source.Path), ".zip") {
+		err = extractZip(archive, dir)
+	} else {
+		err = extractTarGz(archive, dir)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", "", fmt.Errorf("failed to unpack %s: %w", filepath.Base(archive), err)
+	}
+
+	moduleDir, err = manifestDir(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", "", err
+	}
+	return dir, moduleDir, nil
+}
+
+// archiveURL resolves the URL of a release against the index URL
+func (c *IndexClient) archiveURL(ref string) (*url.URL, error) {
+	base, err := url.Parse(c.config.Plugins.IndexURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugins.index_url: %w", err)
+	}
+	target, err := url.Parse(ref)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive URL %q in the plugin registry: %w", ref, err)
+	}
+	return base.ResolveReference(target), nil
+}
+
+// cachedArchive returns the path of the release's archive in the cache,
+// downloading it first unless a copy with the right checksum is there
+func (c *IndexClient) cachedArchive(name string, release *IndexRelease, source *url.URL) (string, error) {
+	ext := ".tar.gz"
+	if strings.HasSuffix(strings.ToLower(source.Path), ".zip") {
+		ext = ".zip"
+	}
+	// Base keeps names from the index from pointing outside the cache
+	path := filepath.Join(c.cacheDir, filepath.Base(fmt.Sprintf("%s-%s%s", name, release.Version, ext)))
+
+	if sum, err := fileSHA256(path); err == nil {
+		if strings.EqualFold(sum, release.SHA256) {
+			c.logger.Debug("Using cached plugin archive", "path", path)
+			return path, nil
+		}
+		c.logger.Warn("Cached plugin archive does not match its checksum, downloading it again", "path", path)
+	}
+
+	if err := os.MkdirAll(c.cacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create plugin cache: %w", err)
+	}
+	resp, err := c.client.Get(source.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("plugin registry returned HTTP %d for %s", resp.StatusCode, source)
+	}
+
+	tmp, err := os.CreateTemp(c.cacheDir, ".download-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create plugin cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(resp.Body, maxArchiveSize+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", source, err)
+	}
+	if n > maxArchiveSize {
+		return "", fmt.Errorf("plugin archive %s is larger than %d MiB", source, maxArchiveSize>>20)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(sum, release.SHA256) {
+		return "", fmt.Errorf("checksum mismatch for %s %s: expected %s, got %s", name, release.Version, release.SHA256, sum)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to cache plugin archive: %w", err)
+	}
+	return path, nil
+}
+
+// fileSHA256 returns the hex SHA-256 checksum of a file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// manifestDir returns the directory of an unpacked archive holding
+// manifest.json: its root, or the single directory archives are often
+// wrapped in
+func manifestDir(dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "manifest.json")); err == nil {
+		return dir, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		nested := filepath.Join(dir, entries[0].Name())
+		if _, err := os.Stat(filepath.Join(nested, "manifest.json")); err == nil {
+			return nested, nil
+		}
+	}
+	return "", fmt.Errorf("plugin archive has no manifest.json")
+}
+
+// extractPath returns where an archive entry is unpacked in dir, refusing
+// names that would escape it
+func extractPath(dir, name string) (string, error) {
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if target != dir && !strings.HasPrefix(target, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry %q is outside the plugin directory", name)
+	}
+	return target, nil
+}
+
+// extractTarGz unpacks the directories and regular files of a .tar.gz
+// archive into dir
+func extractTarGz(archive, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target, err := extractPath(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := extractFile(target, tr, header.FileInfo().Mode()); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("archive entry %q is not a regular file", header.Name)
+		}
+	}
+}
+
+// extractZip unpacks the directories and regular files of a .zip archive
+// into dir
+func extractZip(archive, dir string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, entry := range zr.File {
+		target, err := extractPath(dir, entry.Name)
+		if err != nil {
+			return err
+		}
+		mode := entry.Mode()
+		if mode.IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !mode.IsRegular() {
+			return fmt.Errorf("archive entry %q is not a regular file", entry.Name)
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		err = extractFile(target, rc, mode)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractFile writes an archive entry to target, keeping its executable
+// bits
+func extractFile(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644|mode.Perm()&0111)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -1,8 +1,10 @@
 package plugin
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -14,6 +16,10 @@ import (
 	"github.com/converso-empire/cli/pkg/auth"
 	"github.com/converso-empire/cli/pkg/bridge"
 	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/events"
+	"github.com/converso-empire/cli/pkg/explain"
+	"github.com/converso-empire/cli/pkg/profiling"
+	"github.com/converso-empire/cli/pkg/stats"
 	"github.com/converso-empire/cli/pkg/telemetry"
 )
 
@@ -24,7 +30,17 @@ type PluginRegistry struct {
 	bridge     *bridge.JSONBridge
 	modules    map[string]*ModuleInfo
 	manifests  map[string]*bridge.ModuleManifest
-	mu         sync.RWMutex
+	routes     []URLRoute
+	tokens     *auth.ModuleTokens
+	provider   *auth.TokenProvider
+	storage    auth.SecureStorage
+	cookies    *auth.CookieStore
+	stats      *stats.Store
+	// mu guards the loaded modules and routes; commands run without it
+	mu sync.RWMutex
+	// slots bound the commands each module runs at once
+	slotsMu sync.Mutex
+	slots   map[string]chan struct{}
 }
 
 // ModuleInfo contains information about a loaded module
@@ -33,21 +49,79 @@ type ModuleInfo struct {
 	Path      string                 `json:"path"`
 	LoadedAt  time.Time              `json:"loaded_at"`
 	Signature string                 `json:"signature,omitempty"`
+	// SignedBy is the trusted key that made the signature
+	SignedBy string `json:"signed_by,omitempty"`
 }
 
 // NewPluginRegistry creates a new plugin registry
-func NewPluginRegistry(cfg *config.Config, logger telemetry.Logger, bridge *bridge.JSONBridge) *PluginRegistry {
+func NewPluginRegistry(cfg *config.Config, logger telemetry.Logger, jsonBridge *bridge.JSONBridge) *PluginRegistry {
 	return &PluginRegistry{
 		config:    cfg,
 		logger:    logger,
-		bridge:    bridge,
+		bridge:    jsonBridge,
 		modules:   make(map[string]*ModuleInfo),
 		manifests: make(map[string]*bridge.ModuleManifest),
+		tokens:    auth.NewModuleTokens(cfg, logger),
+		storage:   auth.NewStorage(cfg, logger),
+		cookies:   auth.NewCookieStore(cfg),
+		stats:     stats.NewStore(stats.DefaultPath(cfg)),
+		slots:     make(map[string]chan struct{}),
 	}
 }
 
+// Open creates a registry backed by a JSON bridge configured from cfg and
+// loads the installed plugins
+func Open(cfg *config.Config, logger telemetry.Logger) (*PluginRegistry, error) {
+	jsonBridge := bridge.NewJSONBridge(bridge.GetPythonPath(), cfg.PluginsDir, logger)
+
+	compression := cfg.Bridge.Compression
+	if compression.Enabled {
+		jsonBridge.SetCompression(compression.RequestThreshold, compression.ResponseThreshold)
+	}
+	jsonBridge.SetStrict(cfg.StrictProtocol)
+	if cfg.Debug || cfg.Verbose {
+		jsonBridge.SetLogLevel(bridge.LogLevelDebug)
+	}
+	if pool := cfg.Bridge.Pool; pool.Enabled {
+		jsonBridge.SetPool(pool.MaxProcesses, pool.IdleTimeout)
+	}
+
+	registry := NewPluginRegistry(cfg, logger, jsonBridge)
+	if err := registry.LoadPlugins(); err != nil {
+		return nil, fmt.Errorf("failed to load plugins: %w", err)
+	}
+
+	return registry, nil
+}
+
+// Close stops the module processes kept running between commands
+func (r *PluginRegistry) Close() {
+	r.bridge.Close()
+}
+
+// SetPrompter lets modules ask the user questions while they run; nil
+// answers their prompts without asking
+func (r *PluginRegistry) SetPrompter(prompter bridge.Prompter) {
+	r.bridge.SetPrompter(prompter)
+}
+
+// SetLogHandler shows module log lines through handler besides logging
+// them; nil only logs them
+func (r *PluginRegistry) SetLogHandler(handler bridge.LogHandler) {
+	r.bridge.SetLogHandler(handler)
+}
+
+// SetTokenProvider makes commands use the provider's tokens instead of the
+// ones passed in, so long-running callers keep working after their tokens
+// expire
+func (r *PluginRegistry) SetTokenProvider(provider *auth.TokenProvider) {
+	r.provider = provider
+}
+
 // LoadPlugins scans for and loads available plugins
 func (r *PluginRegistry) LoadPlugins() error {
+	defer profiling.Track(profiling.PhasePluginScan)()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -66,7 +140,8 @@ func (r *PluginRegistry) LoadPlugins() error {
 
 	loadedCount := 0
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		// Hidden directories hold modules being replaced by an update
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
 			continue
 		}
 
@@ -87,6 +162,15 @@ func (r *PluginRegistry) LoadPlugins() error {
 
 // loadModule loads a single module
 func (r *PluginRegistry) loadModule(name, path string) error {
+	// Enforce the administrator policy
+	if !r.config.Policy.PluginAllowed(name) {
+		return fmt.Errorf("module %s is not allowed by policy %s", name, r.config.Policy.Path)
+	}
+	signature, signedBy, err := r.verifySignature(name, path)
+	if err != nil {
+		return err
+	}
+
 	// Check if module has a manifest
 	manifestPath := filepath.Join(path, "manifest.json")
 	if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
@@ -112,18 +196,53 @@ func (r *PluginRegistry) loadModule(name, path string) error {
 
 	// Store module info
 	moduleInfo := &ModuleInfo{
-		Manifest: manifest,
-		Path:     path,
-		LoadedAt: time.Now(),
+		Manifest:  manifest,
+		Path:      path,
+		LoadedAt:  time.Now(),
+		Signature: signature,
+		SignedBy:  signedBy,
 	}
 
 	r.modules[name] = moduleInfo
 	r.manifests[name] = manifest
+	r.rebuildRoutes()
+	r.bridge.SetModuleProtocol(name, manifest.Protocol)
 
 	r.logger.Info("Module loaded", "name", name, "version", manifest.Version)
 	return nil
 }
 
+// verifySignature checks a module's signature before it is loaded. Modules
+// the policy requires to be signed must be signed by a policy key. Other
+// modules must be signed by the bundled modules' key or a key trusted by
+// the policy or the config, unless --allow-unsigned is given.
+func (r *PluginRegistry) verifySignature(name, path string) (signature, signedBy string, err error) {
+	policy := r.config.Policy
+	if policy.SignedPluginsRequired() {
+		signature, signedBy, err = VerifyModule(path, policy.PluginSigningKeys)
+		if err != nil {
+			return "", "", fmt.Errorf("policy requires signed modules: %w", err)
+		}
+		return signature, signedBy, nil
+	}
+
+	keys := []string{BundledModulesKey}
+	if policy != nil {
+		keys = append(keys, policy.PluginSigningKeys...)
+	}
+	keys = append(keys, r.config.Plugins.TrustedKeys...)
+
+	signature, signedBy, err = VerifyModule(path, keys)
+	if err != nil {
+		if r.config.AllowUnsigned {
+			r.logger.Warn("Loading module without a trusted signature", "module", name, "reason", err)
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("%w; trust its signer with 'converso plugin keys add' or load it anyway with --allow-unsigned", err)
+	}
+	return signature, signedBy, nil
+}
+
 // readManifest reads and parses a module manifest
 func (r *PluginRegistry) readManifest(path string) (*bridge.ModuleManifest, error) {
 	data, err := os.ReadFile(path)
@@ -163,6 +282,40 @@ func (r *PluginRegistry) validateManifest(manifest *bridge.ModuleManifest) error
 		return fmt.Errorf("invalid version format, expected semantic versioning")
 	}
 
+	for _, pattern := range manifest.URLPatterns {
+		if err := validateURLPattern(pattern); err != nil {
+			return err
+		}
+	}
+
+	if manifest.DefaultCommand != "" {
+		found := false
+		for _, cmd := range manifest.Commands {
+			if cmd == manifest.DefaultCommand {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("default command %s is not one of the module's commands", manifest.DefaultCommand)
+		}
+	}
+
+	if len(manifest.Completions) > 0 {
+		commands := make(map[string]bool, len(manifest.Commands))
+		for _, cmd := range manifest.Commands {
+			commands[cmd] = true
+		}
+		if !commands[bridge.CompleteCommand] {
+			return fmt.Errorf("completions require a %s command", bridge.CompleteCommand)
+		}
+		for cmd := range manifest.Completions {
+			if !commands[cmd] {
+				return fmt.Errorf("completions declared for unknown command %s", cmd)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -224,42 +377,37 @@ func (r *PluginRegistry) checkDependency(dep string) error {
 	return nil
 }
 
-// ExecuteCommand executes a command on a loaded module
-func (r *PluginRegistry) ExecuteCommand(module, command string, args map[string]interface{}, authTokens *auth.AuthTokens) (*bridge.ModuleResponse, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	// Check if module is loaded
-	moduleInfo, exists := r.modules[module]
-	if !exists {
-		return nil, fmt.Errorf("module %s not found", module)
+// ExecuteCommand executes a command on a loaded module. The command, and
+// the API calls it takes, end when ctx does.
+func (r *PluginRegistry) ExecuteCommand(ctx context.Context, module, command string, args map[string]interface{}, authTokens *auth.AuthTokens) (*bridge.ModuleResponse, error) {
+	moduleInfo, err := r.lookupCommand(module, command)
+	if err != nil {
+		return nil, err
 	}
-
-	// Check if command is available
-	manifest := moduleInfo.Manifest
-	commandAvailable := false
-	for _, cmd := range manifest.Commands {
-		if cmd == command {
-			commandAvailable = true
-			break
-		}
+	release, err := r.acquireSlot(ctx, module)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
-	if !commandAvailable {
-		return nil, fmt.Errorf("command %s not available in module %s", command, module)
+	// Enforce the administrator policy's content filter
+	if command == "download" {
+		if err := r.checkContent(ctx, moduleInfo, args, authTokens); err != nil {
+			return nil, err
+		}
 	}
 
-	// Create request
-	req := &bridge.ModuleRequest{
-		Command:     command,
-		Args:        args,
-		AuthToken:   authTokens.AccessToken,
-		DeviceToken: authTokens.DeviceToken,
-		Timeout:     300, // 5 minutes default
+	req, err := r.newRequest(ctx, module, command, args, authTokens)
+	if err != nil {
+		return nil, err
 	}
 
 	// Execute via bridge
-	resp, err := r.bridge.Execute(context.Background(), module, req)
+	started := time.Now()
+	resp, err := r.bridge.Execute(ctx, module, req)
+	r.recordStats(module, command, started, resp, err)
+	r.explainCall(moduleInfo, req, started, resp, err)
+	r.storeCookies(module, resp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute command: %w", err)
 	}
@@ -268,46 +416,255 @@ func (r *PluginRegistry) ExecuteCommand(module, command string, args map[string]
 }
 
 // ExecuteCommandWithProgress executes a command with progress tracking
-func (r *PluginRegistry) ExecuteCommandWithProgress(module, command string, args map[string]interface{}, authTokens *auth.AuthTokens, progressChan chan<- *bridge.ProgressEvent) (*bridge.ModuleResponse, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+func (r *PluginRegistry) ExecuteCommandWithProgress(ctx context.Context, module, command string, args map[string]interface{}, authTokens *auth.AuthTokens, progressChan chan<- *bridge.ProgressEvent) (*bridge.ModuleResponse, error) {
+	return r.ExecuteCommandWithControl(ctx, module, command, args, authTokens, progressChan, nil)
+}
 
-	// Check if module is loaded
-	moduleInfo, exists := r.modules[module]
-	if !exists {
-		return nil, fmt.Errorf("module %s not found", module)
+// ExecuteCommandWithControl executes a command with progress tracking,
+// pausing, resuming, cancelling or rate limiting it as told on controls
+func (r *PluginRegistry) ExecuteCommandWithControl(ctx context.Context, module, command string, args map[string]interface{}, authTokens *auth.AuthTokens, progressChan chan<- *bridge.ProgressEvent, controls <-chan *bridge.Control) (*bridge.ModuleResponse, error) {
+	moduleInfo, err := r.lookupCommand(module, command)
+	if err != nil {
+		return nil, err
 	}
+	release, err := r.acquireSlot(ctx, module)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
-	// Check if command is available
-	manifest := moduleInfo.Manifest
-	commandAvailable := false
-	for _, cmd := range manifest.Commands {
-		if cmd == command {
-			commandAvailable = true
-			break
+	// Enforce the administrator policy's content filter
+	if command == "download" {
+		if err := r.checkContent(ctx, moduleInfo, args, authTokens); err != nil {
+			return nil, err
 		}
 	}
 
-	if !commandAvailable {
-		return nil, fmt.Errorf("command %s not available in module %s", command, module)
+	req, err := r.newRequest(ctx, module, command, args, authTokens)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create request
-	req := &bridge.ModuleRequest{
+	// Execute via bridge with progress
+	started := time.Now()
+	resp, err := r.bridge.ExecuteWithControl(ctx, module, req, progressChan, controls)
+	r.recordStats(module, command, started, resp, err)
+	r.explainCall(moduleInfo, req, started, resp, err)
+	r.storeCookies(module, resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute command: %w", err)
+	}
+
+	return resp, nil
+}
+
+// newRequest creates the request running command on module, with the
+// token scoped to the module and its secrets and cookies. The module's
+// timeout ends no later than ctx.
+func (r *PluginRegistry) newRequest(ctx context.Context, module, command string, args map[string]interface{}, authTokens *auth.AuthTokens) (*bridge.ModuleRequest, error) {
+	authTokens = r.currentTokens(ctx, authTokens)
+	authToken, secrets, err := r.credentials(ctx, module, authTokens)
+	if err != nil {
+		return nil, err
+	}
+	cookies, err := r.cookies.Cookies(module)
+	if err != nil {
+		r.logger.Warn("Failed to load module cookies", "module", module, "error", err)
+	}
+
+	totalTimeout, idleTimeout := r.config.Timeouts.For(module, command)
+	if manifest, ok := r.manifests[module]; ok && manifest.Protocol == 0 && !r.config.Timeouts.IdleSet(module, command) {
+		// Modules without a protocol send no keepalives during long
+		// silent phases, so the global idle timeout is not theirs
+		idleTimeout = 0
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline).Round(time.Second); remaining < totalTimeout {
+			// Modules take a timeout in whole seconds
+			totalTimeout = remaining
+			if totalTimeout < time.Second {
+				totalTimeout = time.Second
+			}
+		}
+	}
+	return &bridge.ModuleRequest{
 		Command:     command,
 		Args:        args,
-		AuthToken:   authTokens.AccessToken,
+		AuthToken:   authToken,
 		DeviceToken: authTokens.DeviceToken,
-		Timeout:     300, // 5 minutes default
+		Secrets:     secrets,
+		Timeout:     wholeSeconds(totalTimeout),
+		IdleTimeout: wholeSeconds(idleTimeout),
+		Cookies:     cookies,
+	}, nil
+}
+
+// wholeSeconds returns d in seconds, rounded up so that a timeout under a
+// second or with a fraction is not shortened
+func wholeSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
 	}
+	return int((d + time.Second - 1) / time.Second)
+}
 
-	// Execute via bridge with progress
-	resp, err := r.bridge.ExecuteWithProgress(context.Background(), module, req, progressChan)
+// recordStats adds a module invocation to the runtime statistics. Bytes
+// processed are the sizes of the output files the module reports.
+func (r *PluginRegistry) recordStats(module, command string, started time.Time, resp *bridge.ModuleResponse, err error) {
+	run := stats.Run{
+		Command: command,
+		Started: started,
+		Millis:  time.Since(started).Milliseconds(),
+		Failed:  err != nil || resp == nil || !resp.Success,
+	}
+	if resp != nil {
+		run.Bytes = outputBytes(resp.Data)
+		for _, item := range resp.Items {
+			if item.Success {
+				run.Bytes += outputBytes(item.Data)
+			}
+		}
+	}
+
+	if err := r.stats.Record(module, run); err != nil {
+		r.logger.Warn("Failed to record module stats", "module", module, "error", err)
+	}
+}
+
+// explainCall records a module invocation for --explain
+func (r *PluginRegistry) explainCall(moduleInfo *ModuleInfo, req *bridge.ModuleRequest, started time.Time, resp *bridge.ModuleResponse, err error) {
+	if !explain.Enabled() {
+		return
+	}
+	call := explain.Call{
+		Module:   moduleInfo.Manifest.Name,
+		Version:  moduleInfo.Manifest.Version,
+		Path:     moduleInfo.Path,
+		Command:  req.Command,
+		Request:  explain.Request(req),
+		Duration: time.Since(started),
+	}
+	if err != nil {
+		call.Error = err.Error()
+	} else if resp != nil && !resp.Success {
+		call.Error = resp.Error
+	}
+	explain.Record(call)
+}
+
+// storeCookies keeps the cookies a module sent back for its next run
+func (r *PluginRegistry) storeCookies(module string, resp *bridge.ModuleResponse) {
+	if resp == nil || resp.Cookies == nil {
+		return
+	}
+	if err := r.cookies.SetCookies(module, resp.Cookies); err != nil {
+		r.logger.Warn("Failed to store module cookies", "module", module, "error", err)
+	}
+}
+
+// outputBytes returns the size of the file named by file_path in result
+// data, or zero
+func outputBytes(data map[string]interface{}) int64 {
+	path, _ := data["file_path"].(string)
+	if path == "" {
+		return 0
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return 0
+	}
+	return info.Size()
+}
+
+// currentTokens returns the token provider's tokens, refreshed if they were
+// about to expire, or authTokens without a provider or when it fails
+func (r *PluginRegistry) currentTokens(ctx context.Context, authTokens *auth.AuthTokens) *auth.AuthTokens {
+	if r.provider == nil {
+		return authTokens
+	}
+	tokens, err := r.provider.Tokens(ctx)
+	if err != nil {
+		r.logger.Warn("Failed to refresh tokens, using the ones passed", "error", err)
+		return authTokens
+	}
+	return tokens
+}
+
+// credentials returns the module-scoped token and the secrets of a module.
+// Secrets are only ever sent to the module they were set for.
+func (r *PluginRegistry) credentials(ctx context.Context, module string, authTokens *auth.AuthTokens) (string, map[string]string, error) {
+	var requiresAuth bool
+	if manifest, ok := r.manifests[module]; ok {
+		requiresAuth = manifest.RequiresAuth
+	}
+	authToken, err := r.tokens.Token(ctx, authTokens, module, requiresAuth)
+	if err != nil {
+		return "", nil, err
+	}
+
+	secrets, err := r.storage.RetrieveSecrets(module)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load secrets for module %s: %w", module, err)
+	}
+	return authToken, secrets, nil
+}
+
+// completeTimeout bounds complete commands so shell completion stays
+// responsive
+const completeTimeout = 5 * time.Second
+
+// Complete asks a module for completion values of an argument or flag of
+// one of its commands. args are the positional arguments already given and
+// prefix is the word being completed. It returns nil when the module does
+// not complete the argument.
+func (r *PluginRegistry) Complete(ctx context.Context, module, command, arg string, args []string, prefix string, authTokens *auth.AuthTokens) ([]bridge.Completion, error) {
+	r.mu.RLock()
+	moduleInfo, exists := r.modules[module]
+	r.mu.RUnlock()
+
+	if !exists || !moduleInfo.Manifest.Completes(command, arg) {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, completeTimeout)
+	defer cancel()
+
+	authTokens = r.currentTokens(ctx, authTokens)
+	authToken, secrets, err := r.credentials(ctx, module, authTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &bridge.ModuleRequest{
+		Command: bridge.CompleteCommand,
+		Args: map[string]interface{}{
+			"command": command,
+			"arg":     arg,
+			"args":    args,
+			"prefix":  prefix,
+		},
+		AuthToken:   authToken,
+		DeviceToken: authTokens.DeviceToken,
+		Secrets:     secrets,
+		Timeout:     int(completeTimeout.Seconds()),
+	}
+
+	resp, err := r.bridge.Execute(ctx, module, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute command: %w", err)
 	}
+	if !resp.Success {
+		return nil, fmt.Errorf("completion failed: %s", resp.Error)
+	}
 
-	return resp, nil
+	// Modules may ignore the prefix, so filter here as well
+	var completions []bridge.Completion
+	for _, c := range bridge.ParseCompletions(resp) {
+		if strings.HasPrefix(c.Value, prefix) {
+			completions = append(completions, c)
+		}
+	}
+	return completions, nil
 }
 
 // ListModules returns a list of loaded modules
@@ -335,7 +692,7 @@ func (r *PluginRegistry) GetModuleInfo(name string) (*ModuleInfo, error) {
 
 	module, exists := r.modules[name]
 	if !exists {
-		return nil, fmt.Errorf("module %s not found", name)
+		return nil, bridge.ErrModuleNotFound(fmt.Sprintf("module %s not found", name))
 	}
 
 	return module, nil
@@ -343,6 +700,9 @@ func (r *PluginRegistry) GetModuleInfo(name string) (*ModuleInfo, error) {
 
 // InstallModule installs a new module from a local path or URL
 func (r *PluginRegistry) InstallModule(name, source string) error {
+	if r.config.ReadOnly {
+		return fmt.Errorf("plugins cannot be changed in read-only mode")
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -350,6 +710,9 @@ func (r *PluginRegistry) InstallModule(name, source string) error {
 	if _, exists := r.modules[name]; exists {
 		return fmt.Errorf("module %s already exists", name)
 	}
+	if !r.config.Policy.PluginAllowed(name) {
+		return fmt.Errorf("module %s is not allowed by policy %s", name, r.config.Policy.Path)
+	}
 
 	// Create module directory
 	modulePath := filepath.Join(r.config.PluginsDir, name)
@@ -370,6 +733,11 @@ func (r *PluginRegistry) InstallModule(name, source string) error {
 	}
 
 	r.logger.Info("Module installed successfully", "name", name)
+	events.Publish(events.PluginInstalled, events.Plugin{
+		Name:    name,
+		Version: r.modules[name].Manifest.Version,
+		Path:    modulePath,
+	})
 	return nil
 }
 
@@ -416,6 +784,9 @@ func (r *PluginRegistry) copyFile(src, dst string) error {
 	}
 	defer srcFile.Close()
 
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
 	dstFile, err := os.Create(dst)
 	if err != nil {
 		return err
@@ -428,17 +799,21 @@ func (r *PluginRegistry) copyFile(src, dst string) error {
 
 // UninstallModule removes a module
 func (r *PluginRegistry) UninstallModule(name string) error {
+	if r.config.ReadOnly {
+		return fmt.Errorf("plugins cannot be changed in read-only mode")
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	// Check if module exists
 	if _, exists := r.modules[name]; !exists {
-		return fmt.Errorf("module %s not found", name)
+		return bridge.ErrModuleNotFound(fmt.Sprintf("module %s not found", name))
 	}
 
 	// Remove from registry
 	delete(r.modules, name)
 	delete(r.manifests, name)
+	r.rebuildRoutes()
 
 	// Remove directory
 	modulePath := filepath.Join(r.config.PluginsDir, name)
@@ -450,13 +825,71 @@ func (r *PluginRegistry) UninstallModule(name string) error {
 	return nil
 }
 
-// UpdateModule updates an existing module
+// UpdateModule replaces an installed module with the one at source. The
+// installed module is kept until the new one loads, and is restored if it
+// does not; its virtual environment carries over unless source has one.
 func (r *PluginRegistry) UpdateModule(name, source string) error {
-	// For now, uninstall and reinstall
-	// In production, you'd implement proper update logic
-	if err := r.UninstallModule(name); err != nil {
+	if r.config.ReadOnly {
+		return fmt.Errorf("plugins cannot be changed in read-only mode")
+	}
+	if _, err := r.GetModuleInfo(name); err != nil {
 		return err
 	}
+	modulePath := filepath.Join(r.config.PluginsDir, name)
+	if within(source, modulePath) {
+		return fmt.Errorf("cannot update module %s from its own directory", name)
+	}
 
-	return r.InstallModule(name, source)
+	previousPath := filepath.Join(r.config.PluginsDir, "."+name+".previous")
+	if err := os.RemoveAll(previousPath); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", previousPath, err)
+	}
+	if err := os.Rename(modulePath, previousPath); err != nil {
+		return fmt.Errorf("failed to set aside module %s: %w", name, err)
+	}
+	r.mu.Lock()
+	delete(r.modules, name)
+	delete(r.manifests, name)
+	r.mu.Unlock()
+
+	if err := r.InstallModule(name, source); err != nil {
+		os.RemoveAll(modulePath)
+		if restoreErr := os.Rename(previousPath, modulePath); restoreErr != nil {
+			return fmt.Errorf("%w (and failed to restore the previous version from %s: %v)", err, previousPath, restoreErr)
+		}
+		r.mu.Lock()
+		loadErr := r.loadModule(name, modulePath)
+		r.mu.Unlock()
+		if loadErr != nil {
+			r.logger.Warn("Failed to reload restored module", "module", name, "error", loadErr)
+		}
+		return err
+	}
+
+	venv := bridge.VenvDir(modulePath)
+	if _, err := os.Stat(venv); os.IsNotExist(err) {
+		if err := os.Rename(bridge.VenvDir(previousPath), venv); err != nil && !os.IsNotExist(err) {
+			r.logger.Warn("Failed to keep the module's virtual environment", "module", name, "error", err)
+		}
+	}
+	if err := os.RemoveAll(previousPath); err != nil {
+		r.logger.Warn("Failed to remove the previous module version", "path", previousPath, "error", err)
+	}
+
+	r.logger.Info("Module updated successfully", "name", name)
+	return nil
+}
+
+// within reports whether path is dir or inside it
+func within(path, dir string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && filepath.IsLocal(rel)
 }
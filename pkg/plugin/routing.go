@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ParseModuleURL parses a URL given on the command line. Web URLs without a
+// scheme are assumed to be https.
+func ParseModuleURL(rawURL string) (*url.URL, error) {
+	if !strings.Contains(rawURL, ":") {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if u.Scheme == "" || (u.Host == "" && u.Opaque == "" && u.RawQuery == "") {
+		return nil, fmt.Errorf("invalid URL %q", rawURL)
+	}
+	return u, nil
+}
+
+// MatchURLPattern reports whether u matches a manifest URL pattern. Patterns
+// ending in ":" match a scheme, "*.example.com" matches example.com and its
+// subdomains, and any other pattern matches the host exactly.
+func MatchURLPattern(pattern string, u *url.URL) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if strings.HasSuffix(pattern, ":") {
+		return strings.ToLower(u.Scheme)+":" == pattern
+	}
+
+	// Host patterns only apply to web URLs
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if domain := strings.TrimPrefix(pattern, "*."); domain != pattern {
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	}
+	return host == pattern
+}
+
+// validateURLPattern checks that a manifest URL pattern is well formed
+func validateURLPattern(pattern string) error {
+	switch {
+	case pattern == "":
+		return fmt.Errorf("empty URL pattern")
+	case strings.HasSuffix(pattern, ":"):
+		if strings.ContainsAny(pattern[:len(pattern)-1], ":/.*") {
+			return fmt.Errorf("invalid scheme pattern %q", pattern)
+		}
+	case strings.ContainsAny(pattern, ":/") || strings.Contains(strings.TrimPrefix(pattern, "*."), "*"):
+		return fmt.Errorf("invalid host pattern %q, expected a host name or *.domain", pattern)
+	}
+	return nil
+}
+
+// URLRoute maps a manifest URL pattern to the module declaring it
+type URLRoute struct {
+	Pattern string
+	Module  *ModuleInfo
+}
+
+// rebuildRoutes rebuilds the URL routing table from the loaded manifests.
+// Callers must hold the write lock.
+func (r *PluginRegistry) rebuildRoutes() {
+	names := make([]string, 0, len(r.modules))
+	for name := range r.modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	r.routes = r.routes[:0]
+	for _, name := range names {
+		module := r.modules[name]
+		for _, pattern := range module.Manifest.URLPatterns {
+			r.routes = append(r.routes, URLRoute{Pattern: pattern, Module: module})
+		}
+	}
+}
+
+// URLRoutes returns the URL routing table ordered by module name
+func (r *PluginRegistry) URLRoutes() []URLRoute {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	routes := make([]URLRoute, len(r.routes))
+	copy(routes, r.routes)
+	return routes
+}
+
+// ModulesForURL returns the loaded modules whose URL patterns match u, sorted
+// by name. When command is set, only modules providing it are returned.
+func (r *PluginRegistry) ModulesForURL(u *url.URL, command string) []*ModuleInfo {
+	var matches []*ModuleInfo
+	seen := make(map[*ModuleInfo]bool)
+	for _, route := range r.URLRoutes() {
+		if seen[route.Module] || !MatchURLPattern(route.Pattern, u) {
+			continue
+		}
+		if command != "" && !route.Module.HasCommand(command) {
+			continue
+		}
+		seen[route.Module] = true
+		matches = append(matches, route.Module)
+	}
+	return matches
+}
+
+// HasCommand reports whether the module provides command
+func (m *ModuleInfo) HasCommand(command string) bool {
+	for _, cmd := range m.Manifest.Commands {
+		if cmd == command {
+			return true
+		}
+	}
+	return false
+}
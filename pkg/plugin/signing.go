@@ -0,0 +1,142 @@
+package plugin
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SignatureFile holds a module's base64 Ed25519 signature of its digest
+const SignatureFile = "module.sig"
+
+// BundledModulesKey is the public key of the release key that signs the
+// modules shipped with the CLI. It is always trusted.
+const BundledModulesKey = "4alOg1bWG6qnUCKGFh8Y4dV0lwfcfyQRSiBgX0Lrozs="
+
+// ErrUnsigned is returned when a module has no signature file
+var ErrUnsigned = errors.New("module is not signed")
+
+// ModuleDigest returns a SHA-256 digest over the module's files, their
+// paths and contents, excluding the signature file and Python caches
+func ModuleDigest(dir string) ([]byte, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "__pycache__" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == SignatureFile || strings.HasSuffix(rel, ".pyc") {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return fmt.Errorf("%s is not a regular file", rel)
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module files: %w", err)
+	}
+	sort.Strings(files)
+
+	digest := sha256.New()
+	for _, rel := range files {
+		sum, err := fileHash(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(digest, "%s\x00%s\n", rel, sum)
+	}
+	return digest.Sum(nil), nil
+}
+
+// SignModule writes the signature file of a module
+func SignModule(dir string, key ed25519.PrivateKey) error {
+	digest, err := ModuleDigest(dir)
+	if err != nil {
+		return err
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, digest))
+	if err := os.WriteFile(filepath.Join(dir, SignatureFile), []byte(signature+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+	return nil
+}
+
+// VerifyModule checks a module's signature, over its manifest and all its
+// other files, against the trusted base64 public keys. It returns the
+// signature and the key that made it.
+func VerifyModule(dir string, trustedKeys []string) (signature, signedBy string, err error) {
+	data, err := os.ReadFile(filepath.Join(dir, SignatureFile))
+	if os.IsNotExist(err) {
+		return "", "", ErrUnsigned
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read signature: %w", err)
+	}
+	signature = strings.TrimSpace(string(data))
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return "", "", fmt.Errorf("malformed signature: %w", err)
+	}
+
+	digest, err := ModuleDigest(dir)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, encoded := range trustedKeys {
+		key, err := ParsePublicKey(encoded)
+		if err != nil {
+			return "", "", err
+		}
+		if ed25519.Verify(key, digest, raw) {
+			return signature, strings.TrimSpace(encoded), nil
+		}
+	}
+	return "", "", errors.New("signature does not match any trusted key")
+}
+
+// ParsePublicKey decodes a base64 Ed25519 public key
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid plugin signing key %q", encoded)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// fileHash returns the hex SHA-256 of a file
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
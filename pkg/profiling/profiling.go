@@ -0,0 +1,168 @@
+// Package profiling records how long the phases of a CLI invocation take
+// and, when profiling is started, CPU and heap profiles of the process.
+// Phase timing is always on; it costs a lock per phase.
+package profiling
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+)
+
+// Phase names used across the CLI
+const (
+	PhaseConfigLoad      = "config load"
+	PhaseAuth            = "auth"
+	PhasePluginScan      = "plugin scan"
+	PhaseBridgeSpawn     = "bridge spawn"
+	PhaseModuleExecution = "module execution"
+)
+
+// processStart approximates when the process started
+var processStart = time.Now()
+
+// PhaseTiming is the accumulated time spent in a phase
+type PhaseTiming struct {
+	Name  string        `json:"name"`
+	Count int           `json:"count"`
+	Total time.Duration `json:"total_ns"`
+}
+
+// recorder accumulates phase timings in first-seen order
+type recorder struct {
+	mu     sync.Mutex
+	phases []*PhaseTiming
+}
+
+var timings recorder
+
+// Track starts timing a phase and returns the function that ends it
+func Track(name string) func() {
+	started := time.Now()
+	return func() {
+		Record(name, time.Since(started))
+	}
+}
+
+// Record adds time spent in a phase
+func Record(name string, d time.Duration) {
+	timings.mu.Lock()
+	defer timings.mu.Unlock()
+
+	for _, phase := range timings.phases {
+		if phase.Name == name {
+			phase.Count++
+			phase.Total += d
+			return
+		}
+	}
+	timings.phases = append(timings.phases, &PhaseTiming{Name: name, Count: 1, Total: d})
+}
+
+// Timings returns the phases recorded so far
+func Timings() []PhaseTiming {
+	timings.mu.Lock()
+	defer timings.mu.Unlock()
+
+	result := make([]PhaseTiming, len(timings.phases))
+	for i, phase := range timings.phases {
+		result[i] = *phase
+	}
+	return result
+}
+
+// Report describes a finished profiling session
+type Report struct {
+	Dir     string        `json:"dir"`
+	Command string        `json:"command"`
+	Total   time.Duration `json:"total_ns"`
+	Phases  []PhaseTiming `json:"phases"`
+}
+
+// session is the running profiling session
+var session struct {
+	mu      sync.Mutex
+	dir     string
+	command string
+	cpu     *os.File
+}
+
+// Start begins CPU profiling into dir, which is created
+func Start(dir, command string) error {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.cpu != nil {
+		return fmt.Errorf("profiling already started")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+
+	cpu, err := os.Create(filepath.Join(dir, "cpu.pprof"))
+	if err != nil {
+		return fmt.Errorf("failed to create CPU profile: %w", err)
+	}
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		cpu.Close()
+		return fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+
+	session.dir = dir
+	session.command = command
+	session.cpu = cpu
+	return nil
+}
+
+// Stop ends the session, writing the heap profile and phase timings next
+// to the CPU profile. It returns nil when profiling was not started.
+func Stop() (*Report, error) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.cpu == nil {
+		return nil, nil
+	}
+	pprof.StopCPUProfile()
+	session.cpu.Close()
+	session.cpu = nil
+
+	report := &Report{
+		Dir:     session.dir,
+		Command: session.command,
+		Total:   time.Since(processStart),
+		Phases:  Timings(),
+	}
+
+	heap, err := os.Create(filepath.Join(session.dir, "heap.pprof"))
+	if err != nil {
+		return report, fmt.Errorf("failed to create heap profile: %w", err)
+	}
+	defer heap.Close()
+
+	// Collect garbage first so the profile shows live memory
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(heap); err != nil {
+		return report, fmt.Errorf("failed to write heap profile: %w", err)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return report, fmt.Errorf("failed to marshal timings: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(session.dir, "timings.json"), data, 0644); err != nil {
+		return report, fmt.Errorf("failed to write timings: %w", err)
+	}
+	return report, nil
+}
+
+// Elapsed returns the time since the process started
+func Elapsed() time.Duration {
+	return time.Since(processStart)
+}
@@ -0,0 +1,483 @@
+package qrcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Code is an encoded QR code. Modules are indexed [y][x]; true is dark.
+type Code struct {
+	Size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+// version describes the layout of a QR version at error correction level M
+type version struct {
+	ecPerBlock int
+	// blocks lists the data codewords of each block, short blocks first
+	blocks    []int
+	alignment []int
+}
+
+// versions 1-10 at error correction level M, enough for URLs of up to 213
+// bytes
+var versions = []version{
+	{10, []int{16}, nil},
+	{16, []int{28}, []int{6, 18}},
+	{26, []int{44}, []int{6, 22}},
+	{18, []int{32, 32}, []int{6, 26}},
+	{24, []int{43, 43}, []int{6, 30}},
+	{16, []int{27, 27, 27, 27}, []int{6, 34}},
+	{18, []int{31, 31, 31, 31}, []int{6, 22, 38}},
+	{22, []int{38, 38, 39, 39}, []int{6, 24, 42}},
+	{22, []int{36, 36, 36, 37, 37}, []int{6, 26, 46}},
+	{26, []int{43, 43, 43, 43, 44}, []int{6, 28, 50}},
+}
+
+// formatBitsM are the error correction level bits of level M
+const formatBitsM = 0
+
+// Encode encodes text as a QR code in byte mode with medium (M) error
+// correction, using the smallest version that fits
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+
+	for i, v := range versions {
+		number := i + 1
+		capacity := 0
+		for _, n := range v.blocks {
+			capacity += n
+		}
+		countBits := 8
+		if number >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) > 8*capacity {
+			continue
+		}
+
+		codewords := encodeData(data, countBits, capacity)
+		code := newCode(number, v)
+		code.drawCodewords(interleave(codewords, v))
+		code.applyBestMask()
+		return code, nil
+	}
+
+	return nil, fmt.Errorf("text too long for a QR code (%d bytes)", len(data))
+}
+
+// Dark reports whether the module at x, y is dark
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// encodeData builds the data codewords: byte mode indicator, character
+// count, data, terminator and padding
+func encodeData(data []byte, countBits, capacity int) []byte {
+	var bits bitBuffer
+	bits.append(0x4, 4)
+	bits.append(len(data), countBits)
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	// Terminator and padding to a byte boundary
+	terminator := 8*capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+
+	codewords := bits.bytes()
+	for pad := byte(0xEC); len(codewords) < capacity; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+	return codewords
+}
+
+// interleave splits data into blocks, adds error correction to each and
+// interleaves the result
+func interleave(data []byte, v version) []byte {
+	divisor := reedSolomonDivisor(v.ecPerBlock)
+
+	var dataBlocks, ecBlocks [][]byte
+	offset := 0
+	for _, n := range v.blocks {
+		block := data[offset : offset+n]
+		offset += n
+		dataBlocks = append(dataBlocks, block)
+		ecBlocks = append(ecBlocks, reedSolomonRemainder(block, divisor))
+	}
+
+	var result []byte
+	longest := v.blocks[len(v.blocks)-1]
+	for i := 0; i < longest; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < v.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// newCode creates a code with the function patterns of a version drawn
+func newCode(number int, v version) *Code {
+	size := 17 + 4*number
+	c := &Code{Size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for y := range c.modules {
+		c.modules[y] = make([]bool, size)
+		c.function[y] = make([]bool, size)
+	}
+
+	// Timing patterns
+	for i := 0; i < size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns with separators
+	for _, corner := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := corner[0]+dx, corner[1]+dy
+				if x < 0 || x >= size || y < 0 || y >= size {
+					continue
+				}
+				dist := max(abs(dx), abs(dy))
+				c.setFunction(x, y, dist != 2 && dist != 4)
+			}
+		}
+	}
+
+	// Alignment patterns, except where they would overlap finders
+	last := len(v.alignment) - 1
+	for i, ay := range v.alignment {
+		for j, ax := range v.alignment {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.setFunction(ax+dx, ay+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve format areas; drawn once the mask is known
+	c.drawFormat(0)
+
+	// Version information
+	if number >= 7 {
+		rem := number
+		for i := 0; i < 12; i++ {
+			rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+		}
+		bits := number<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := bits>>i&1 != 0
+			a, b := size-11+i%3, i/3
+			c.setFunction(a, b, dark)
+			c.setFunction(b, a, dark)
+		}
+	}
+
+	return c
+}
+
+// drawFormat draws both copies of the format information for a mask
+func (c *Code) drawFormat(mask int) {
+	bits := formatBits(mask)
+	bit := func(i int) bool { return bits>>i&1 != 0 }
+
+	// First copy, around the top-left finder
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	// Second copy, split between the other two finders
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+	c.setFunction(8, c.Size-8, true)
+}
+
+// formatBits returns the 15 format bits for level M and a mask
+func formatBits(mask int) int {
+	data := formatBitsM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// drawCodewords places codewords in the zigzag order of the standard
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if c.function[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				c.modules[y][x] = codewords[i>>3]>>(7-i&7)&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+// applyBestMask applies the mask with the lowest penalty
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if penalty := c.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		c.applyMask(mask) // masks are their own inverse
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+}
+
+// applyMask XORs a mask pattern onto the data modules
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !c.function[y][x] {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores a masked code with the four rules of the standard
+func (c *Code) penalty() int {
+	penalty := 0
+	dark := 0
+
+	for y := 0; y < c.Size; y++ {
+		penalty += linePenalty(c.Size, func(i int) bool { return c.modules[y][i] })
+		penalty += linePenalty(c.Size, func(i int) bool { return c.modules[i][y] })
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			// 2x2 blocks of one color
+			if x < c.Size-1 && y < c.Size-1 {
+				color := c.modules[y][x]
+				if c.modules[y][x+1] == color && c.modules[y+1][x] == color && c.modules[y+1][x+1] == color {
+					penalty += 3
+				}
+			}
+		}
+	}
+
+	// Deviation from 50% dark modules
+	total := c.Size * c.Size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	if k > 0 {
+		penalty += k * 10
+	}
+	return penalty
+}
+
+// linePenalty scores runs of five or more same-colored modules and
+// finder-like 1:1:3:1:1 patterns in one row or column
+func linePenalty(size int, module func(i int) bool) int {
+	penalty := 0
+
+	run := 1
+	for i := 1; i <= size; i++ {
+		if i < size && module(i) == module(i-1) {
+			run++
+			continue
+		}
+		if run >= 5 {
+			penalty += run - 2
+		}
+		run = 1
+	}
+
+	// Dark-light-dark-dark-dark-light-dark with four light modules on
+	// either side, treating the outside as light
+	pattern := []bool{true, false, true, true, true, false, true}
+	at := func(i int) bool { return i >= 0 && i < size && module(i) }
+	for i := 0; i+7 <= size; i++ {
+		matched := true
+		for j, want := range pattern {
+			if at(i+j) != want {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		before, after := true, true
+		for j := 1; j <= 4; j++ {
+			before = before && !at(i-j)
+			after = after && !at(i+6+j)
+		}
+		if before || after {
+			penalty += 40
+		}
+	}
+	return penalty
+}
+
+// setFunction sets a function module
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+// Terminal renders the code with half-block characters, two modules per
+// line, as light modules on a dark terminal background with a quiet zone
+func (c *Code) Terminal() string {
+	const quiet = 2
+	light := func(x, y int) bool {
+		if x < 0 || x >= c.Size || y < 0 || y >= c.Size {
+			return true
+		}
+		return !c.modules[y][x]
+	}
+
+	var b strings.Builder
+	for y := -quiet; y < c.Size+quiet; y += 2 {
+		for x := -quiet; x < c.Size+quiet; x++ {
+			top, bottom := light(x, y), light(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// bitBuffer accumulates bits most significant first
+type bitBuffer []bool
+
+// append adds the low n bits of value
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, value>>i&1 != 0)
+	}
+}
+
+// bytes packs the bits into bytes
+func (b bitBuffer) bytes() []byte {
+	result := make([]byte, (len(b)+7)/8)
+	for i, bit := range b {
+		if bit {
+			result[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return result
+}
+
+// reedSolomonDivisor returns the generator polynomial of a degree,
+// highest coefficient omitted
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// reedSolomonRemainder returns the error correction codewords of data
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// abs returns the absolute value of an int
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,60 @@
+package qrcode
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestEncodeGolden compares codes with the module matrices Kazuhiko Arase's
+// QRCode library draws for the same payload, version, level M and mask.
+// Versions 7 and 10 cover the version information and, at 10, the 16-bit
+// character count.
+func TestEncodeGolden(t *testing.T) {
+	tests := []struct {
+		golden string
+		text   string
+	}{
+		{"version1", "converso"},
+		{"version4", "https://conversoempire.world/device?code=WDJB-MJHT"},
+		{"version7", "https://conversoempire.world/activate?user_code=WDJB-MJHT&client_id=converso-cli&device=linux-amd64&session=8f14e4"},
+		{"version10", "https://conversoempire.world/activate?user_code=WDJB-MJHT&client_id=converso-cli&device=linux-amd64&session=8f14e45fceea167a5a36dedd4bea2543&redirect_uri=https%3A%2F%2Fconversoempire.world%2Fdone&state=af0ifjsldkj"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.golden+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.Fields(string(data))
+
+			code, err := Encode(tt.text)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if code.Size != len(want) {
+				t.Fatalf("size %d, want %d", code.Size, len(want))
+			}
+			for y, row := range want {
+				var got strings.Builder
+				for x := 0; x < code.Size; x++ {
+					if code.Dark(x, y) {
+						got.WriteByte('#')
+					} else {
+						got.WriteByte('.')
+					}
+				}
+				if got.String() != row {
+					t.Errorf("row %d:\n got %s\nwant %s", y, got.String(), row)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	if _, err := Encode(strings.Repeat("x", 214)); err == nil {
+		t.Fatal("expected an error for text longer than version 10 holds")
+	}
+}
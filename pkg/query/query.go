@@ -0,0 +1,338 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Getter returns the value of a named field of the record being filtered,
+// and false when the record has no such field
+type Getter func(field string) (string, bool)
+
+// Query is a parsed filter expression such as
+//
+//	status=failed AND module=youtube
+//	height>=1080 AND (ext=mp4 OR ext=webm)
+//	NOT title~"(?i)trailer"
+//
+// Comparisons are numeric when both sides are numbers and case-insensitive
+// string comparisons otherwise. The ~ operator matches a regular expression.
+type Query struct {
+	expr node
+	text string
+}
+
+// Parse parses a filter expression
+func Parse(text string) (*Query, error) {
+	tokens, err := lex(text)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("invalid filter: unexpected %q", p.peek().text)
+	}
+
+	return &Query{expr: expr, text: text}, nil
+}
+
+// String returns the original expression
+func (q *Query) String() string {
+	return q.text
+}
+
+// Match reports whether a record satisfies the query. A field missing from
+// the record only satisfies != comparisons.
+func (q *Query) Match(get Getter) bool {
+	return q.expr.eval(get)
+}
+
+// Validate checks that every field used by the query is one of known
+func (q *Query) Validate(known []string) error {
+	allowed := make(map[string]bool, len(known))
+	for _, field := range known {
+		allowed[field] = true
+	}
+
+	for _, field := range q.expr.fields(nil) {
+		if !allowed[field] {
+			return fmt.Errorf("unknown filter field %q (available: %s)", field, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// MapGetter returns a Getter over a decoded JSON object
+func MapGetter(m map[string]interface{}) Getter {
+	return func(field string) (string, bool) {
+		value, ok := m[field]
+		if !ok || value == nil {
+			return "", false
+		}
+		switch v := value.(type) {
+		case string:
+			return v, true
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(v), true
+		default:
+			return fmt.Sprint(v), true
+		}
+	}
+}
+
+// node is a node of the expression tree
+type node interface {
+	eval(get Getter) bool
+	fields(acc []string) []string
+}
+
+type andNode struct{ left, right node }
+
+func (n *andNode) eval(get Getter) bool { return n.left.eval(get) && n.right.eval(get) }
+func (n *andNode) fields(acc []string) []string {
+	return n.right.fields(n.left.fields(acc))
+}
+
+type orNode struct{ left, right node }
+
+func (n *orNode) eval(get Getter) bool { return n.left.eval(get) || n.right.eval(get) }
+func (n *orNode) fields(acc []string) []string {
+	return n.right.fields(n.left.fields(acc))
+}
+
+type notNode struct{ inner node }
+
+func (n *notNode) eval(get Getter) bool         { return !n.inner.eval(get) }
+func (n *notNode) fields(acc []string) []string { return n.inner.fields(acc) }
+
+// comparison compares a field with a literal value
+type comparison struct {
+	field string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+func (c *comparison) fields(acc []string) []string { return append(acc, c.field) }
+
+func (c *comparison) eval(get Getter) bool {
+	actual, ok := get(c.field)
+	if !ok {
+		return c.op == "!="
+	}
+
+	if c.op == "~" {
+		return c.re.MatchString(actual)
+	}
+
+	// Compare numerically when both sides are numbers
+	var cmp int
+	a, errA := strconv.ParseFloat(actual, 64)
+	b, errB := strconv.ParseFloat(c.value, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case a < b:
+			cmp = -1
+		case a > b:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(strings.ToLower(actual), strings.ToLower(c.value))
+	}
+
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// Token kinds
+const (
+	tokenWord = iota
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+// lex splits an expression into tokens
+func lex(text string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(text); {
+		ch := text[i]
+		switch {
+		case ch == ' ' || ch == '\t':
+			i++
+
+		case ch == '(':
+			tokens = append(tokens, token{tokenLParen, "("})
+			i++
+
+		case ch == ')':
+			tokens = append(tokens, token{tokenRParen, ")"})
+			i++
+
+		case ch == '"' || ch == '\'':
+			end := strings.IndexByte(text[i+1:], ch)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid filter: unterminated string at position %d", i+1)
+			}
+			tokens = append(tokens, token{tokenString, text[i+1 : i+1+end]})
+			i += end + 2
+
+		case strings.ContainsRune("=!<>~", rune(ch)):
+			op := string(ch)
+			if i+1 < len(text) && text[i+1] == '=' && ch != '=' && ch != '~' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("invalid filter: expected != at position %d", i+1)
+			}
+			tokens = append(tokens, token{tokenOp, op})
+			i += len(op)
+
+		default:
+			start := i
+			for i < len(text) && !strings.ContainsRune(" \t()=!<>~\"'", rune(text[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokenWord, text[start:i]})
+		}
+	}
+
+	return tokens, nil
+}
+
+// parser is a recursive descent parser over the token stream
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool  { return p.pos >= len(p.tokens) }
+func (p *parser) peek() token { return p.tokens[p.pos] }
+func (p *parser) next() token { t := p.tokens[p.pos]; p.pos++; return t }
+
+// keyword reports whether the next token is the given keyword
+func (p *parser) keyword(word string) bool {
+	return !p.done() && p.peek().kind == tokenWord && strings.EqualFold(p.peek().text, word)
+}
+
+// parseOr parses: and ("OR" and)*
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses: unary ("AND" unary)*
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+// parseUnary parses: "NOT" unary | "(" or ")" | comparison
+func (p *parser) parseUnary() (node, error) {
+	if p.done() {
+		return nil, fmt.Errorf("invalid filter: unexpected end of expression")
+	}
+
+	if p.keyword("NOT") {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{inner}, nil
+	}
+
+	if p.peek().kind == tokenLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.done() || p.peek().kind != tokenRParen {
+			return nil, fmt.Errorf("invalid filter: missing )")
+		}
+		p.next()
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+// parseComparison parses: field op value
+func (p *parser) parseComparison() (node, error) {
+	field := p.next()
+	if field.kind != tokenWord {
+		return nil, fmt.Errorf("invalid filter: expected field name, got %q", field.text)
+	}
+
+	if p.done() || p.peek().kind != tokenOp {
+		return nil, fmt.Errorf("invalid filter: expected operator after %q", field.text)
+	}
+	op := p.next().text
+
+	if p.done() || (p.peek().kind != tokenWord && p.peek().kind != tokenString) {
+		return nil, fmt.Errorf("invalid filter: expected value after %s%s", field.text, op)
+	}
+	value := p.next().text
+
+	c := &comparison{field: strings.ToLower(field.text), op: op, value: value}
+	if op == "~" {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: bad pattern %q: %w", value, err)
+		}
+		c.re = re
+	}
+	return c, nil
+}
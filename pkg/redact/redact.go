@@ -0,0 +1,124 @@
+// Package redact masks secret values in text shown to users or written to
+// logs, such as CI output.
+package redact
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces secret values
+const Mask = "********"
+
+// minLength is the length below which values are not masked, so that
+// short values such as "1" or "yes" do not blank out unrelated text
+const minLength = 4
+
+// Masker replaces known secret values with Mask
+type Masker struct {
+	mu     sync.RWMutex
+	values []string
+}
+
+// NewMasker creates a masker for values
+func NewMasker(values ...string) *Masker {
+	m := &Masker{}
+	m.Add(values...)
+	return m
+}
+
+// Add registers more secret values
+func (m *Masker) Add(values ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) < minLength || contains(m.values, value) {
+			continue
+		}
+		m.values = append(m.values, value)
+	}
+	// Longer values first, so a secret containing another is masked whole
+	sort.Slice(m.values, func(i, j int) bool { return len(m.values[i]) > len(m.values[j]) })
+}
+
+// Empty reports whether the masker has no values
+func (m *Masker) Empty() bool {
+	if m == nil {
+		return true
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.values) == 0
+}
+
+// String masks the secret values in s
+func (m *Masker) String(s string) string {
+	if m == nil {
+		return s
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, value := range m.values {
+		s = strings.ReplaceAll(s, value, Mask)
+	}
+	return s
+}
+
+// Error masks the message of err, keeping nil as nil
+func (m *Masker) Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	if masked := m.String(err.Error()); masked != err.Error() {
+		return errors.New(masked)
+	}
+	return err
+}
+
+// Value masks the strings of a decoded JSON or YAML value, returning a
+// copy
+func (m *Masker) Value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return m.String(v)
+	case []string:
+		out := make([]string, len(v))
+		for i, item := range v {
+			out[i] = m.String(item)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]string, len(v))
+		for key, item := range v {
+			out[key] = m.String(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = m.Value(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = m.Value(item)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// contains reports whether list holds s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
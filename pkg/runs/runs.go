@@ -0,0 +1,130 @@
+// Package runs records each CLI invocation under its run ID so that its
+// logs, history entries, jobs and files can be found again later.
+package runs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+)
+
+// Run is a finished invocation of the CLI
+type Run struct {
+	ID         string    `json:"id"`
+	Command    string    `json:"command"`
+	Args       []string  `json:"args"`
+	Version    string    `json:"version"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	ExitCode   int       `json:"exit_code"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Duration returns how long the run took
+func (r *Run) Duration() time.Duration {
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// Store is an append-only file of runs with one JSON record per line
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultPath returns the location of the run records
+func DefaultPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "runs.jsonl")
+}
+
+// NewStore creates a run store backed by path
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Append records a finished run
+func (s *Store) Append(run *Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create runs directory: %w", err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open runs: %w", err)
+	}
+	defer file.Close()
+
+	if err := json.NewEncoder(file).Encode(run); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	return nil
+}
+
+// Runs returns all recorded runs, oldest first
+func (s *Store) Runs() ([]Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open runs: %w", err)
+	}
+	defer file.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("failed to parse runs line %d: %w", line, err)
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read runs: %w", err)
+	}
+	return runs, nil
+}
+
+// Find returns the run whose ID is id or starts with it. A prefix must
+// match exactly one run.
+func (s *Store) Find(id string) (*Run, error) {
+	all, err := s.Runs()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Run
+	for _, run := range all {
+		if run.ID == id {
+			return &run, nil
+		}
+		if strings.HasPrefix(run.ID, id) {
+			matches = append(matches, run)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("run %s not found", id)
+	case 1:
+		return &matches[0], nil
+	}
+	return nil, fmt.Errorf("run ID %s is ambiguous: it matches %d runs", id, len(matches))
+}
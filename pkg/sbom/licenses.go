@@ -0,0 +1,49 @@
+package sbom
+
+// NoAssertion is the SPDX value for unknown licenses and locations
+const NoAssertion = "NOASSERTION"
+
+// moduleLicenses maps the Go modules the CLI may be built with to the
+// SPDX identifiers of their licenses, taken from each module's LICENSE
+// file. Add new dependencies here when go.mod changes; unknown modules are
+// reported as NOASSERTION.
+var moduleLicenses = map[string]string{
+	"github.com/converso-empire/cli":        "MIT",
+	"github.com/fatih/color":                "MIT",
+	"github.com/fsnotify/fsnotify":          "BSD-3-Clause",
+	"github.com/golang/oauth2":              "BSD-3-Clause",
+	"github.com/google/uuid":                "BSD-3-Clause",
+	"github.com/hashicorp/go-cleanhttp":     "MPL-2.0",
+	"github.com/hashicorp/go-retryablehttp": "MPL-2.0",
+	"github.com/hashicorp/hcl":              "MPL-2.0",
+	"github.com/inconshreveable/mousetrap":  "Apache-2.0",
+	"github.com/keybase/go-keychain":        "MIT",
+	"github.com/magiconair/properties":      "BSD-2-Clause",
+	"github.com/mattn/go-colorable":         "MIT",
+	"github.com/mattn/go-isatty":            "MIT",
+	"github.com/mitchellh/mapstructure":     "MIT",
+	"github.com/pelletier/go-toml/v2":       "MIT",
+	"github.com/rs/zerolog":                 "MIT",
+	"github.com/sagikazarmark/slog-shim":    "BSD-3-Clause",
+	"github.com/shirou/gopsutil/v3":         "BSD-3-Clause",
+	"github.com/spf13/afero":                "Apache-2.0",
+	"github.com/spf13/cast":                 "MIT",
+	"github.com/spf13/cobra":                "Apache-2.0",
+	"github.com/spf13/pflag":                "BSD-3-Clause",
+	"github.com/spf13/viper":                "MIT",
+	"github.com/subosito/gotenv":            "MIT",
+	"golang.org/x/oauth2":                   "BSD-3-Clause",
+	"golang.org/x/sys":                      "BSD-3-Clause",
+	"golang.org/x/text":                     "BSD-3-Clause",
+	"gopkg.in/ini.v1":                       "Apache-2.0",
+	"gopkg.in/yaml.v3":                      "MIT AND Apache-2.0",
+	"stdlib":                                "BSD-3-Clause",
+}
+
+// License returns the SPDX license identifier of a Go module
+func License(module string) string {
+	if license, ok := moduleLicenses[module]; ok {
+		return license
+	}
+	return NoAssertion
+}
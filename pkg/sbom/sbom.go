@@ -0,0 +1,216 @@
+// Package sbom describes the software shipped in the CLI binary and the
+// installed plugins, as a license report and an SPDX 2.3 document.
+package sbom
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/google/uuid"
+)
+
+// mainModule is the module path of the CLI itself
+const mainModule = "github.com/converso-empire/cli"
+
+// Component kinds
+const (
+	KindApplication = "application"
+	KindGoModule    = "go-module"
+	KindPlugin      = "plugin"
+)
+
+// Component is a piece of software in the report
+type Component struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	License string `json:"license"`
+	Kind    string `json:"kind"`
+	// PURL is the package URL of Go components
+	PURL string `json:"purl,omitempty"`
+	// Supplier is the plugin author from its manifest
+	Supplier string `json:"supplier,omitempty"`
+}
+
+// Components lists the CLI, the Go standard library and modules compiled
+// into the binary, and the installed plugins. version is the CLI release,
+// used because local builds report "(devel)".
+func Components(version string, plugins []*bridge.ModuleManifest) []Component {
+	components := []Component{{
+		Name:    mainModule,
+		Version: version,
+		License: License(mainModule),
+		Kind:    KindApplication,
+		PURL:    goPURL(mainModule, version),
+	}, {
+		Name:    "stdlib",
+		Version: runtime.Version(),
+		License: License("stdlib"),
+		Kind:    KindGoModule,
+		PURL:    goPURL("stdlib", runtime.Version()),
+	}}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Replace != nil {
+				dep = dep.Replace
+			}
+			components = append(components, Component{
+				Name:    dep.Path,
+				Version: dep.Version,
+				License: License(dep.Path),
+				Kind:    KindGoModule,
+				PURL:    goPURL(dep.Path, dep.Version),
+			})
+		}
+	}
+
+	for _, manifest := range plugins {
+		license := manifest.License
+		if license == "" {
+			license = NoAssertion
+		}
+		components = append(components, Component{
+			Name:     manifest.Name,
+			Version:  manifest.Version,
+			License:  license,
+			Kind:     KindPlugin,
+			Supplier: manifest.Author,
+		})
+	}
+	return components
+}
+
+// goPURL returns the package URL of a Go module
+func goPURL(module, version string) string {
+	return fmt.Sprintf("pkg:golang/%s@%s", module, version)
+}
+
+// Document is an SPDX 2.3 document in its JSON serialization
+type Document struct {
+	SPDXVersion       string         `json:"spdxVersion"`
+	DataLicense       string         `json:"dataLicense"`
+	SPDXID            string         `json:"SPDXID"`
+	Name              string         `json:"name"`
+	DocumentNamespace string         `json:"documentNamespace"`
+	CreationInfo      CreationInfo   `json:"creationInfo"`
+	Packages          []Package      `json:"packages"`
+	Relationships     []Relationship `json:"relationships"`
+}
+
+// CreationInfo records when and by what a document was created
+type CreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+// Package is an SPDX package
+type Package struct {
+	Name             string        `json:"name"`
+	SPDXID           string        `json:"SPDXID"`
+	VersionInfo      string        `json:"versionInfo,omitempty"`
+	Supplier         string        `json:"supplier,omitempty"`
+	DownloadLocation string        `json:"downloadLocation"`
+	FilesAnalyzed    bool          `json:"filesAnalyzed"`
+	LicenseConcluded string        `json:"licenseConcluded"`
+	LicenseDeclared  string        `json:"licenseDeclared"`
+	CopyrightText    string        `json:"copyrightText"`
+	PrimaryPurpose   string        `json:"primaryPackagePurpose,omitempty"`
+	ExternalRefs     []ExternalRef `json:"externalRefs,omitempty"`
+}
+
+// ExternalRef is an SPDX external reference such as a package URL
+type ExternalRef struct {
+	Category string `json:"referenceCategory"`
+	Type     string `json:"referenceType"`
+	Locator  string `json:"referenceLocator"`
+}
+
+// Relationship is an SPDX relationship between two elements
+type Relationship struct {
+	Element        string `json:"spdxElementId"`
+	Type           string `json:"relationshipType"`
+	RelatedElement string `json:"relatedSpdxElement"`
+}
+
+// spdxIDChars are the characters allowed in SPDX identifiers
+var spdxIDChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// SPDX builds an SPDX document for the components. The first component is
+// the one the document describes; Go modules are its dependencies and
+// plugins optional components of it.
+func SPDX(components []Component, tool string) *Document {
+	root := components[0]
+	doc := &Document{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              fmt.Sprintf("%s-%s", root.Name, root.Version),
+		DocumentNamespace: fmt.Sprintf("https://cli.conversoempire.world/spdx/%s-%s", root.Version, uuid.New().String()),
+		CreationInfo: CreationInfo{
+			Created:  time.Now().UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + tool},
+		},
+	}
+
+	for i, c := range components {
+		pkg := Package{
+			Name:             c.Name,
+			SPDXID:           spdxID(i, c),
+			VersionInfo:      c.Version,
+			DownloadLocation: NoAssertion,
+			LicenseConcluded: c.License,
+			LicenseDeclared:  c.License,
+			CopyrightText:    NoAssertion,
+		}
+		if c.Supplier != "" {
+			pkg.Supplier = "Organization: " + c.Supplier
+		}
+		if c.PURL != "" {
+			pkg.ExternalRefs = []ExternalRef{{Category: "PACKAGE-MANAGER", Type: "purl", Locator: c.PURL}}
+		}
+
+		switch c.Kind {
+		case KindApplication:
+			pkg.PrimaryPurpose = "APPLICATION"
+			doc.Relationships = append(doc.Relationships, Relationship{doc.SPDXID, "DESCRIBES", pkg.SPDXID})
+		case KindGoModule:
+			pkg.PrimaryPurpose = "LIBRARY"
+			if c.Name != "stdlib" {
+				pkg.DownloadLocation = "https://proxy.golang.org/" + proxyEscape(c.Name) + "/@v/" + proxyEscape(c.Version) + ".zip"
+			}
+			doc.Relationships = append(doc.Relationships, Relationship{spdxID(0, root), "DEPENDS_ON", pkg.SPDXID})
+		case KindPlugin:
+			// Plugin licenses are declared by their manifests, not checked
+			pkg.PrimaryPurpose = "LIBRARY"
+			pkg.LicenseConcluded = NoAssertion
+			doc.Relationships = append(doc.Relationships, Relationship{pkg.SPDXID, "OPTIONAL_COMPONENT_OF", spdxID(0, root)})
+		}
+		doc.Packages = append(doc.Packages, pkg)
+	}
+	return doc
+}
+
+// proxyEscape escapes a module path or version for the module proxy,
+// which encodes upper case letters as '!' and the lower case letter
+func proxyEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// spdxID returns a document-unique SPDX identifier for a component
+func spdxID(index int, c Component) string {
+	return fmt.Sprintf("SPDXRef-%s-%d-%s", c.Kind, index, strings.Trim(spdxIDChars.ReplaceAllString(c.Name, "-"), "-"))
+}
@@ -0,0 +1,135 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/converso-empire/cli/pkg/httpclient"
+	"github.com/converso-empire/cli/pkg/transfer"
+	"github.com/converso-empire/cli/pkg/worker"
+)
+
+// EnqueueJob queues a job on the backend for a worker to run. Module and
+// Command are required; the backend fills in the ID and timestamps.
+func (c *Client) EnqueueJob(job *worker.Job) (*worker.Job, error) {
+	if job.Module == "" || job.Command == "" {
+		return nil, fmt.Errorf("job requires a module and a command")
+	}
+
+	var queued worker.Job
+	if err := c.callAPI(&httpclient.CreateJob, job, &queued); err != nil {
+		return nil, fmt.Errorf("failed to queue job: %w", err)
+	}
+	return &queued, nil
+}
+
+// EnqueueDownload queues a download of rawURL for a worker. Without a
+// module, the URL is routed with the locally installed plugins.
+func (c *Client) EnqueueDownload(rawURL, module string, priority int) (*worker.Job, error) {
+	module, target, err := c.route(rawURL, module)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.EnqueueJob(&worker.Job{
+		Type:     "download",
+		Module:   module,
+		Command:  "download",
+		Args:     map[string]interface{}{"url": target},
+		Priority: priority,
+	})
+}
+
+// Jobs returns the jobs known to the backend, queued and finished
+func (c *Client) Jobs() ([]*worker.Job, error) {
+	var jobs []*worker.Job
+	if err := c.callAPI(&httpclient.Jobs, nil, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// DeviceJobs returns the jobs routed to or taken by a registered device
+func (c *Client) DeviceJobs(device string) ([]*worker.Job, error) {
+	jobs, err := c.Jobs()
+	if err != nil {
+		return nil, err
+	}
+	routed := make([]*worker.Job, 0, len(jobs))
+	for _, job := range jobs {
+		if job.Device == device {
+			routed = append(routed, job)
+		}
+	}
+	return routed, nil
+}
+
+// Job returns the job with the given ID
+func (c *Client) Job(id string) (*worker.Job, error) {
+	jobs, err := c.Jobs()
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return nil, fmt.Errorf("job %s not found", id)
+}
+
+// FetchJobFile downloads the file of a job completed on another device to
+// dest through the backend, resuming an earlier interrupted fetch. The
+// device's worker must have worker.sync set to backend.
+func (c *Client) FetchJobFile(id, dest string, progress transfer.Progress) error {
+	tokens, err := c.tokens(context.Background())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(httpclient.JobFile.Method, httpclient.JobFile.URL(c.config, id), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+
+	// Files can take longer than the API timeout
+	return transfer.Fetch(httpclient.New(c.config, c.logger, 0), req, dest, progress)
+}
+
+// callAPI sends body as JSON to a backend endpoint with the stored access
+// token and decodes the response into out
+func (c *Client) callAPI(endpoint *httpclient.Endpoint, body, out interface{}) error {
+	tokens, err := c.tokens(context.Background())
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if body != nil {
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(endpoint.Method, endpoint.URL(c.config), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.api.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
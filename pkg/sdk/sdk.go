@@ -0,0 +1,289 @@
+// Package sdk lets Go programs use what the CLI can do without running the
+// binary: run module commands and downloads through the plugin registry,
+// queue jobs for workers and query them, and manage plugins.
+//
+//	client, err := sdk.New(sdk.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	resp, err := client.Download("https://youtu.be/example", sdk.DownloadOptions{})
+//
+// Clients use the same configuration, credentials and data directory as the
+// CLI, so programs run as a user who logged in with 'converso login' are
+// authenticated too. Downloads publish the same events as the CLI's; see
+// package events.
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/auth"
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/events"
+	"github.com/converso-empire/cli/pkg/httpclient"
+	"github.com/converso-empire/cli/pkg/hwaccel"
+	"github.com/converso-empire/cli/pkg/plugin"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// Options configures a Client
+type Options struct {
+	// Config is used instead of loading ~/.converso/config.yaml and the
+	// system layers
+	Config *config.Config
+	// Logger receives the client's logs; the global logger when nil
+	Logger telemetry.Logger
+}
+
+// Client runs the CLI's capabilities in-process. It is safe for
+// concurrent use.
+type Client struct {
+	config *config.Config
+	logger telemetry.Logger
+	api    *http.Client
+	// provider refreshes the credentials for as long as the client is used
+	provider *auth.TokenProvider
+
+	// registry is loaded on first use, since job queries do not need it
+	registryOnce sync.Once
+	registry     *plugin.PluginRegistry
+	registryErr  error
+}
+
+// New creates a client
+func New(opts Options) (*Client, error) {
+	cfg := opts.Config
+	if cfg == nil {
+		loaded, err := config.Load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load configuration: %w", err)
+		}
+		cfg = loaded
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = telemetry.GetGlobalLogger()
+	}
+
+	return &Client{
+		config:   cfg,
+		logger:   logger,
+		api:      httpclient.New(cfg, logger, 30*time.Second),
+		provider: auth.NewTokenProvider(cfg, logger),
+	}, nil
+}
+
+// Config returns the configuration the client uses
+func (c *Client) Config() *config.Config {
+	return c.config
+}
+
+// Registry returns the plugin registry, loading the installed plugins the
+// first time
+func (c *Client) Registry() (*plugin.PluginRegistry, error) {
+	c.registryOnce.Do(func() {
+		c.registry, c.registryErr = plugin.Open(c.config, c.logger)
+		if c.registryErr == nil {
+			c.registry.SetTokenProvider(c.provider)
+		}
+	})
+	return c.registry, c.registryErr
+}
+
+// Close stops the module processes the client keeps running when the
+// bridge pool is enabled. The client is not used afterwards.
+func (c *Client) Close() {
+	if c.registry != nil {
+		c.registry.Close()
+	}
+}
+
+// tokens returns the stored credentials, refreshing them when they are
+// about to expire, giving up on the refresh when ctx ends
+func (c *Client) tokens(ctx context.Context) (*auth.AuthTokens, error) {
+	tokens, err := c.provider.Tokens(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("authentication required: %w", err)
+	}
+	return tokens, nil
+}
+
+// Run runs a command of a module. Progress, when set, is called with each
+// progress event the module reports.
+func (c *Client) Run(module, command string, args map[string]interface{}, progress func(*bridge.ProgressEvent)) (*bridge.ModuleResponse, error) {
+	return c.RunContext(context.Background(), module, command, args, progress)
+}
+
+// RunContext is Run with the command, and the API calls it takes, ending
+// when ctx does
+func (c *Client) RunContext(ctx context.Context, module, command string, args map[string]interface{}, progress func(*bridge.ProgressEvent)) (*bridge.ModuleResponse, error) {
+	registry, err := c.Registry()
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := c.tokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	progressChan := make(chan *bridge.ProgressEvent, 100)
+	progressDone := make(chan struct{})
+	go func() {
+		defer close(progressDone)
+		for event := range progressChan {
+			if progress != nil {
+				progress(event)
+			}
+		}
+	}()
+
+	resp, err := registry.ExecuteCommandWithProgress(ctx, module, command, args, tokens, progressChan)
+	close(progressChan)
+	<-progressDone
+	return resp, err
+}
+
+// DownloadOptions configures a download
+type DownloadOptions struct {
+	// Module handles the URL instead of the module matching it
+	Module string
+	// OutputDir defaults to ~/Downloads/Converso like the CLI's
+	OutputDir string
+	// Preset is a conversion preset applied after download
+	Preset string
+	// Progress is called with each progress event
+	Progress func(*bridge.ProgressEvent)
+}
+
+// Download downloads a URL with the module that handles it. Failed
+// downloads return the module's response along with the error when there
+// is one, so per-item results of batches can be inspected.
+func (c *Client) Download(rawURL string, opts DownloadOptions) (*bridge.ModuleResponse, error) {
+	return c.DownloadContext(context.Background(), rawURL, opts)
+}
+
+// DownloadContext is Download with the download ending when ctx does
+func (c *Client) DownloadContext(ctx context.Context, rawURL string, opts DownloadOptions) (*bridge.ModuleResponse, error) {
+	module, target, err := c.route(rawURL, opts.Module)
+	if err != nil {
+		return nil, err
+	}
+
+	outputDir := opts.OutputDir
+	if outputDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		outputDir = filepath.Join(homeDir, "Downloads", "Converso")
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	args := map[string]interface{}{
+		"url":        target,
+		"output_dir": outputDir,
+	}
+	if opts.Preset != "" {
+		preset, err := c.config.Preset(opts.Preset)
+		if err != nil {
+			return nil, err
+		}
+		postprocess := preset.Args()
+		if c.config.HWAccel {
+			caps, err := hwaccel.Load(hwaccel.DefaultPath(c.config))
+			if err != nil {
+				c.logger.Warn("Using software encoders", "error", err)
+			} else if encoders := caps.Args(); encoders != nil {
+				postprocess[hwaccel.ArgEncoders] = encoders
+			}
+		}
+		args["postprocess"] = postprocess
+	}
+
+	download := events.Download{Module: module, Command: "download", URL: target}
+	events.Publish(events.DownloadStarted, download)
+
+	resp, err := c.RunContext(ctx, module, "download", args, opts.Progress)
+	download.Response, download.Err = resp, err
+	events.Publish(events.DownloadCompleted, download)
+
+	if err != nil {
+		return resp, fmt.Errorf("download failed: %w", err)
+	}
+	if !resp.Success {
+		return resp, fmt.Errorf("download failed: %s", resp.Error)
+	}
+	return resp, nil
+}
+
+// route returns the module that downloads rawURL, which is moduleName when
+// set, and the normalized URL
+func (c *Client) route(rawURL, moduleName string) (string, string, error) {
+	target, err := plugin.ParseModuleURL(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	if moduleName != "" {
+		return moduleName, target.String(), nil
+	}
+
+	registry, err := c.Registry()
+	if err != nil {
+		return "", "", err
+	}
+	matches := registry.ModulesForURL(target, "download")
+	switch len(matches) {
+	case 0:
+		return "", "", fmt.Errorf("no installed module handles %s", target)
+	case 1:
+		return matches[0].Manifest.Name, target.String(), nil
+	default:
+		return "", "", fmt.Errorf("multiple modules handle %s; set the module to use", target)
+	}
+}
+
+// Plugins returns the installed plugins
+func (c *Client) Plugins() ([]*plugin.ModuleInfo, error) {
+	registry, err := c.Registry()
+	if err != nil {
+		return nil, err
+	}
+	return registry.ListModules(), nil
+}
+
+// InstallPlugin installs a plugin from a local directory
+func (c *Client) InstallPlugin(name, source string) error {
+	registry, err := c.Registry()
+	if err != nil {
+		return err
+	}
+	return registry.InstallModule(name, source)
+}
+
+// UpdatePlugin replaces an installed plugin with the one in source
+func (c *Client) UpdatePlugin(name, source string) error {
+	registry, err := c.Registry()
+	if err != nil {
+		return err
+	}
+	return registry.UpdateModule(name, source)
+}
+
+// UninstallPlugin removes an installed plugin
+func (c *Client) UninstallPlugin(name string) error {
+	registry, err := c.Registry()
+	if err != nil {
+		return err
+	}
+	return registry.UninstallModule(name)
+}
@@ -0,0 +1,353 @@
+// Package stats keeps per-module runtime statistics: invocations,
+// failures, latency, bytes processed and throughput, totalled by month.
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+)
+
+// maxSamples bounds the latency samples kept per module; percentiles are
+// computed over the most recent invocations
+const maxSamples = 500
+
+// lockTimeout bounds the wait for another process recording stats
+const lockTimeout = 5 * time.Second
+
+// ModuleStats are the statistics of a single module
+type ModuleStats struct {
+	Invocations int64     `json:"invocations"`
+	Failures    int64     `json:"failures"`
+	Bytes       int64     `json:"bytes"`
+	LastUsed    time.Time `json:"last_used"`
+	// Samples are the durations in milliseconds of the latest invocations
+	Samples []int64 `json:"samples"`
+	// Runs are the latest invocations, which throughput is averaged over
+	Runs []Run `json:"runs,omitempty"`
+	// Months totals the invocations by month ("2006-01") and command
+	Months map[string]map[string]*Totals `json:"months,omitempty"`
+}
+
+// Run is a single invocation of a module command
+type Run struct {
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+	Millis  int64     `json:"ms"`
+	// Bytes are the sizes of the output files the command produced
+	Bytes  int64 `json:"bytes,omitempty"`
+	Failed bool  `json:"failed,omitempty"`
+}
+
+// Totals add up the invocations of a period
+type Totals struct {
+	Runs     int64 `json:"runs"`
+	Failures int64 `json:"failures"`
+	Bytes    int64 `json:"bytes"`
+	// Millis is the time taken by the runs that produced output
+	Millis int64 `json:"ms"`
+	// Hours counts the runs by the local hour of day they started in
+	Hours [24]int64 `json:"hours"`
+}
+
+// Add adds a run to the totals
+func (t *Totals) Add(run Run) {
+	t.Runs++
+	if run.Failed {
+		t.Failures++
+	}
+	if run.Bytes > 0 {
+		t.Bytes += run.Bytes
+		t.Millis += run.Millis
+	}
+	t.Hours[run.Started.Local().Hour()]++
+}
+
+// Merge adds other to the totals
+func (t *Totals) Merge(other *Totals) {
+	t.Runs += other.Runs
+	t.Failures += other.Failures
+	t.Bytes += other.Bytes
+	t.Millis += other.Millis
+	for hour, runs := range other.Hours {
+		t.Hours[hour] += runs
+	}
+}
+
+// Throughput returns the bytes produced per second by the runs that
+// produced output; zero without any
+func (t *Totals) Throughput() float64 {
+	if t.Millis <= 0 {
+		return 0
+	}
+	return float64(t.Bytes) / (float64(t.Millis) / 1000)
+}
+
+// BusiestHours returns the hours of day with the most runs, busiest
+// first, up to n
+func (t *Totals) BusiestHours(n int) []int {
+	var hours []int
+	for hour, runs := range t.Hours {
+		if runs > 0 {
+			hours = append(hours, hour)
+		}
+	}
+	sort.SliceStable(hours, func(i, j int) bool { return t.Hours[hours[i]] > t.Hours[hours[j]] })
+	if len(hours) > n {
+		hours = hours[:n]
+	}
+	return hours
+}
+
+// MonthKey returns the month t is totalled under
+func MonthKey(t time.Time) string {
+	return t.Local().Format("2006-01")
+}
+
+// FailureRate returns the share of failed invocations
+func (m *ModuleStats) FailureRate() float64 {
+	if m.Invocations == 0 {
+		return 0
+	}
+	return float64(m.Failures) / float64(m.Invocations)
+}
+
+// Throughput returns the bytes a command of the module produces per
+// second, averaged over its latest runs that produced output; zero without
+// any
+func (m *ModuleStats) Throughput(command string) float64 {
+	var totals Totals
+	for _, run := range m.Runs {
+		if run.Command == command {
+			totals.Add(run)
+		}
+	}
+	return totals.Throughput()
+}
+
+// Month returns the totals of the module's runs in a month, over all
+// commands
+func (m *ModuleStats) Month(month string) Totals {
+	var totals Totals
+	for _, command := range m.Months[month] {
+		totals.Merge(command)
+	}
+	return totals
+}
+
+// Percentile returns the p-th percentile (0-100) of the sampled latencies
+func (m *ModuleStats) Percentile(p float64) time.Duration {
+	if len(m.Samples) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), m.Samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return time.Duration(sorted[rank]) * time.Millisecond
+}
+
+// Summary is a compact view of a module's statistics for reports
+type Summary struct {
+	Invocations int64   `json:"invocations"`
+	FailureRate float64 `json:"failure_rate"`
+	P50Ms       int64   `json:"p50_ms"`
+	P95Ms       int64   `json:"p95_ms"`
+	Bytes       int64   `json:"bytes"`
+}
+
+// Summary returns the compact view of the statistics
+func (m *ModuleStats) Summary() Summary {
+	return Summary{
+		Invocations: m.Invocations,
+		FailureRate: m.FailureRate(),
+		P50Ms:       m.Percentile(50).Milliseconds(),
+		P95Ms:       m.Percentile(95).Milliseconds(),
+		Bytes:       m.Bytes,
+	}
+}
+
+// Report totals the runs of all modules in a month
+type Report struct {
+	Month string `json:"month"`
+	// Downloaded totals the download commands and Total every command
+	Downloaded Totals `json:"downloaded"`
+	Total      Totals `json:"total"`
+	// Commands are the totals by module and command, busiest first
+	Commands []CommandTotals `json:"commands"`
+}
+
+// CommandTotals are the totals of a module command
+type CommandTotals struct {
+	Module  string `json:"module"`
+	Command string `json:"command"`
+	Totals
+}
+
+// NewReport totals the runs of all modules in month ("2006-01")
+func NewReport(all map[string]*ModuleStats, month string) *Report {
+	r := &Report{Month: month, Commands: []CommandTotals{}}
+	for module, m := range all {
+		for command, totals := range m.Months[month] {
+			r.Commands = append(r.Commands, CommandTotals{Module: module, Command: command, Totals: *totals})
+			r.Total.Merge(totals)
+			if command == "download" {
+				r.Downloaded.Merge(totals)
+			}
+		}
+	}
+	sort.Slice(r.Commands, func(i, j int) bool {
+		a, b := r.Commands[i], r.Commands[j]
+		if a.Runs != b.Runs {
+			return a.Runs > b.Runs
+		}
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		return a.Command < b.Command
+	})
+	return r
+}
+
+// Store is the stats file shared by all CLI processes
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultPath returns the location of the module stats
+func DefaultPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "module_stats.json")
+}
+
+// NewStore creates a stats store backed by path
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Record adds an invocation of a module
+func (s *Store) Record(module string, run Run) error {
+	return s.update(func(all map[string]*ModuleStats) {
+		m := all[module]
+		if m == nil {
+			m = &ModuleStats{}
+			all[module] = m
+		}
+
+		m.Invocations++
+		if run.Failed {
+			m.Failures++
+		}
+		m.Bytes += run.Bytes
+		m.LastUsed = time.Now()
+		m.Samples = append(m.Samples, run.Millis)
+		if len(m.Samples) > maxSamples {
+			m.Samples = m.Samples[len(m.Samples)-maxSamples:]
+		}
+		m.Runs = append(m.Runs, run)
+		if len(m.Runs) > maxSamples {
+			m.Runs = m.Runs[len(m.Runs)-maxSamples:]
+		}
+
+		month := MonthKey(run.Started)
+		if m.Months == nil {
+			m.Months = make(map[string]map[string]*Totals)
+		}
+		if m.Months[month] == nil {
+			m.Months[month] = make(map[string]*Totals)
+		}
+		totals := m.Months[month][run.Command]
+		if totals == nil {
+			totals = &Totals{}
+			m.Months[month][run.Command] = totals
+		}
+		totals.Add(run)
+	})
+}
+
+// Reset clears the statistics of the given modules, or of all modules
+func (s *Store) Reset(modules ...string) error {
+	return s.update(func(all map[string]*ModuleStats) {
+		if len(modules) == 0 {
+			for module := range all {
+				delete(all, module)
+			}
+		}
+		for _, module := range modules {
+			delete(all, module)
+		}
+	})
+}
+
+// All returns the statistics of every module
+func (s *Store) All() (map[string]*ModuleStats, error) {
+	all := make(map[string]*ModuleStats)
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module stats: %w", err)
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to parse module stats: %w", err)
+	}
+	return all, nil
+}
+
+// Summaries returns the compact statistics of every module
+func (s *Store) Summaries() (map[string]Summary, error) {
+	all, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	summaries := make(map[string]Summary, len(all))
+	for module, m := range all {
+		summaries[module] = m.Summary()
+	}
+	return summaries, nil
+}
+
+// update applies fn to the stored statistics under a lock
+func (s *Store) update(fn func(all map[string]*ModuleStats)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	lock, err := fileutil.Lock(s.path+".lock", lockTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to lock module stats: %w", err)
+	}
+	defer lock.Unlock()
+
+	all, err := s.All()
+	if err != nil {
+		// Start over rather than failing every command on a corrupt file
+		all = make(map[string]*ModuleStats)
+	}
+	fn(all)
+
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal module stats: %w", err)
+	}
+	if err := fileutil.WriteAtomic(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write module stats: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package subscriptions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+)
+
+// channelIDPattern matches raw YouTube channel IDs
+var channelIDPattern = regexp.MustCompile(`^UC[0-9A-Za-z_-]{22}$`)
+
+// Subscription is a channel whose new uploads are downloaded by sync
+type Subscription struct {
+	Channel     string    `json:"channel"`
+	Title       string    `json:"title,omitempty"`
+	MinDuration int       `json:"min_duration,omitempty"`
+	TitleMatch  string    `json:"title_match,omitempty"`
+	OutputDir   string    `json:"output_dir,omitempty"`
+	AddedAt     time.Time `json:"added_at"`
+	LastSync    time.Time `json:"last_sync,omitempty"`
+}
+
+// Accepts reports whether a video passes the subscription's filters
+func (s *Subscription) Accepts(title string, duration int) (bool, error) {
+	if s.MinDuration > 0 && duration < s.MinDuration {
+		return false, nil
+	}
+	if s.TitleMatch != "" {
+		re, err := regexp.Compile(s.TitleMatch)
+		if err != nil {
+			return false, fmt.Errorf("invalid title filter for %s: %w", s.Channel, err)
+		}
+		if !re.MatchString(title) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Store persists subscriptions as JSON in the data directory
+type Store struct {
+	path          string
+	Subscriptions []*Subscription `json:"subscriptions"`
+}
+
+// DefaultPath returns the location of the subscriptions file
+func DefaultPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "subscriptions.json")
+}
+
+// Load reads the store at path, starting empty if it does not exist
+func Load(path string) (*Store, error) {
+	s := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscriptions: %w", err)
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse subscriptions: %w", err)
+	}
+	return s, nil
+}
+
+// Save writes the store to disk
+func (s *Store) Save() error {
+	sort.Slice(s.Subscriptions, func(i, j int) bool {
+		return s.Subscriptions[i].Channel < s.Subscriptions[j].Channel
+	})
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal subscriptions: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := fileutil.WriteAtomic(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write subscriptions: %w", err)
+	}
+	return nil
+}
+
+// Find returns the subscription for a channel, or nil
+func (s *Store) Find(channel string) *Subscription {
+	channel = NormalizeChannel(channel)
+	for _, sub := range s.Subscriptions {
+		if sub.Channel == channel {
+			return sub
+		}
+	}
+	return nil
+}
+
+// Add adds a subscription, failing if the channel is already subscribed
+func (s *Store) Add(sub *Subscription) error {
+	sub.Channel = NormalizeChannel(sub.Channel)
+	if s.Find(sub.Channel) != nil {
+		return fmt.Errorf("already subscribed to %s", sub.Channel)
+	}
+	if sub.TitleMatch != "" {
+		if _, err := regexp.Compile(sub.TitleMatch); err != nil {
+			return fmt.Errorf("invalid title filter: %w", err)
+		}
+	}
+	if sub.AddedAt.IsZero() {
+		sub.AddedAt = time.Now()
+	}
+
+	s.Subscriptions = append(s.Subscriptions, sub)
+	return nil
+}
+
+// Remove deletes the subscription for a channel
+func (s *Store) Remove(channel string) error {
+	channel = NormalizeChannel(channel)
+	for i, sub := range s.Subscriptions {
+		if sub.Channel == channel {
+			s.Subscriptions = append(s.Subscriptions[:i], s.Subscriptions[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("not subscribed to %s", channel)
+}
+
+// NormalizeChannel turns @handles, channel IDs and channel URLs into a
+// canonical channel URL
+func NormalizeChannel(channel string) string {
+	channel = strings.TrimSpace(channel)
+	switch {
+	case strings.HasPrefix(channel, "@"):
+		return "https://www.youtube.com/" + channel
+	case channelIDPattern.MatchString(channel):
+		return "https://www.youtube.com/channel/" + channel
+	case strings.HasPrefix(channel, "http://"):
+		channel = "https://" + strings.TrimPrefix(channel, "http://")
+	case !strings.HasPrefix(channel, "https://"):
+		channel = "https://" + channel
+	}
+
+	channel = strings.Replace(channel, "https://youtube.com/", "https://www.youtube.com/", 1)
+	channel = strings.Replace(channel, "https://m.youtube.com/", "https://www.youtube.com/", 1)
+	return strings.TrimSuffix(channel, "/")
+}
@@ -0,0 +1,119 @@
+package telemetry
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// logFileName is the log shared by all invocations
+	logFileName = "converso.log"
+
+	// maxLogFileSize is the size at which the log is rotated, keeping one
+	// previous file
+	maxLogFileSize = 10 << 20
+)
+
+// LogDir returns the directory of the CLI's log files
+func LogDir(dataDir string) string {
+	return filepath.Join(dataDir, "logs")
+}
+
+// OpenLogFile opens the log in dir for appending, rotating it first once
+// it has grown past maxLogFileSize
+func OpenLogFile(dir string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	path := filepath.Join(dir, logFileName)
+	if info, err := os.Stat(path); err == nil && info.Size() > maxLogFileSize {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return nil, fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	return file, nil
+}
+
+// SetLogFile makes a logger created by NewLogger also write JSON lines to
+// w, whatever its console output
+func SetLogFile(logger Logger, w io.Writer) {
+	if l, ok := logger.(*ZerologAdapter); ok {
+		l.file = w
+		l.SetDebug(l.debug)
+	}
+}
+
+// LogEntry is a line of the log file
+type LogEntry struct {
+	Time    time.Time
+	Level   string
+	Message string
+	// Fields are the structured fields logged with the message
+	Fields map[string]interface{}
+}
+
+// RunLogs returns the log entries of a run from the logs in dir, oldest
+// first
+func RunLogs(dir, runID string) ([]LogEntry, error) {
+	var entries []LogEntry
+	for _, name := range []string{logFileName + ".1", logFileName} {
+		found, err := readRunLogs(filepath.Join(dir, name), runID)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, found...)
+	}
+	return entries, nil
+}
+
+// readRunLogs reads the entries of a run from one log file. Lines that are
+// not JSON are skipped.
+func readRunLogs(path, runID string) ([]LogEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []LogEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil {
+			continue
+		}
+		if fields["run_id"] != runID {
+			continue
+		}
+
+		entry := LogEntry{Fields: fields}
+		entry.Level, _ = fields["level"].(string)
+		entry.Message, _ = fields["message"].(string)
+		if value, ok := fields["time"].(string); ok {
+			entry.Time, _ = time.Parse(time.RFC3339, value)
+		}
+		for _, key := range []string{"run_id", "level", "message", "time"} {
+			delete(fields, key)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read log file: %w", err)
+	}
+	return entries, nil
+}
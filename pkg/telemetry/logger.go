@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 
+	"github.com/converso-empire/cli/pkg/redact"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -21,25 +22,45 @@ type Logger interface {
 // ZerologAdapter adapts zerolog to our Logger interface
 type ZerologAdapter struct {
 	logger zerolog.Logger
+	// out is the process's standard error as it was at start, so logs are
+	// never rewritten by the terminal output theme
+	out io.Writer
+	// file receives JSON lines as well, nil until SetLogFile
+	file  io.Writer
+	debug bool
+	// masker hides secret values in messages and fields, nil until
+	// SetMasker
+	masker *redact.Masker
 }
 
 // NewLogger creates a new structured logger
 func NewLogger(debug bool) Logger {
+	l := &ZerologAdapter{out: os.Stderr}
+	l.SetDebug(debug)
+	return l
+}
+
+// SetDebug switches the logger between debug and production output
+func (l *ZerologAdapter) SetDebug(debug bool) {
 	// Configure output
 	var output io.Writer
 	if debug {
 		// Human-readable output for debugging
 		output = zerolog.ConsoleWriter{
-			Out:        os.Stderr,
+			Out:        l.out,
 			TimeFormat: "15:04:05",
 		}
 	} else {
 		// JSON output for production
-		output = os.Stderr
+		output = l.out
+	}
+
+	if l.file != nil {
+		output = zerolog.MultiLevelWriter(output, l.file)
 	}
 
-	// Create logger
-	logger := zerolog.New(output).With().Timestamp().Logger()
+	// Create logger; every line carries the run ID
+	logger := zerolog.New(output).With().Timestamp().Str("run_id", RunID()).Logger()
 
 	// Set log level
 	if debug {
@@ -50,7 +71,24 @@ func NewLogger(debug bool) Logger {
 		logger = logger.Level(zerolog.InfoLevel)
 	}
 
-	return &ZerologAdapter{logger: logger}
+	l.logger = logger
+	l.debug = debug
+}
+
+// SetDebug switches a logger created by NewLogger between debug and
+// production output, for settings only known once the config is loaded
+func SetDebug(logger Logger, debug bool) {
+	if l, ok := logger.(*ZerologAdapter); ok {
+		l.SetDebug(debug)
+	}
+}
+
+// SetMasker makes a logger created by NewLogger mask the values of m in
+// every message and field
+func SetMasker(logger Logger, m *redact.Masker) {
+	if l, ok := logger.(*ZerologAdapter); ok {
+		l.masker = m
+	}
 }
 
 // Debug logs a debug message
@@ -88,13 +126,20 @@ func (l *ZerologAdapter) Panic(msg string, fields ...interface{}) {
 // logEvent logs an event with structured fields
 func (l *ZerologAdapter) logEvent(level, msg string, fields ...interface{}) {
 	event := l.logger.With().Logger()
+	msg = l.masker.String(msg)
 
 	// Process fields in pairs (key, value)
 	for i := 0; i < len(fields); i += 2 {
 		if i+1 < len(fields) {
 			key, ok := fields[i].(string)
-			if ok {
-				event = event.With().Interface(key, fields[i+1]).Logger()
+			if !ok {
+				continue
+			}
+			// Errors have no exported fields, so log their message
+			if err, isErr := fields[i+1].(error); isErr {
+				event = event.With().AnErr(key, l.masker.Error(err)).Logger()
+			} else {
+				event = event.With().Interface(key, l.masker.Value(fields[i+1])).Logger()
 			}
 		}
 	}
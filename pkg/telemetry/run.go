@@ -0,0 +1,24 @@
+package telemetry
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// runID identifies this invocation of the CLI. It is attached to every log
+// line and stored with the history entries, jobs and files the run produces.
+var runID = newRunID(time.Now())
+
+// RunID returns the ID of this invocation
+func RunID() string {
+	return runID
+}
+
+// newRunID creates a run ID that sorts by start time, such as
+// 20261016T130334-3f9a1c
+func newRunID(t time.Time) string {
+	var suffix [3]byte
+	rand.Read(suffix[:])
+	return t.UTC().Format("20060102T150405") + "-" + hex.EncodeToString(suffix[:])
+}
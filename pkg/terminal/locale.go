@@ -0,0 +1,248 @@
+package terminal
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Size units selected with output.size_units
+const (
+	// SizeUnitsBinary counts file sizes in powers of 1024: KiB, MiB, GiB
+	SizeUnitsBinary = "binary"
+	// SizeUnitsSI counts file sizes in powers of 1000: kB, MB, GB
+	SizeUnitsSI = "si"
+)
+
+// Locale holds how numbers, dates and times are written
+type Locale struct {
+	Name string
+	// Group separates thousands and Decimal the fraction
+	Group   string
+	Decimal string
+	// Date is the layout of dates and Time of times of day, without
+	// seconds
+	Date string
+	Time string
+}
+
+// DefaultLocale writes ISO dates and 24-hour times, for the C locale and
+// for output read by scripts
+var DefaultLocale = Locale{Name: "C", Group: ",", Decimal: ".", Date: "2006-01-02", Time: "15:04"}
+
+// locales by language, or language and region where they differ
+var locales = map[string]Locale{
+	"en":    {Group: ",", Decimal: ".", Date: "01/02/2006", Time: "3:04 PM"},
+	"en_AU": {Group: ",", Decimal: ".", Date: "02/01/2006", Time: "3:04 PM"},
+	"en_CA": {Group: ",", Decimal: ".", Date: "2006-01-02", Time: "3:04 PM"},
+	"en_GB": {Group: ",", Decimal: ".", Date: "02/01/2006", Time: "15:04"},
+	"en_IE": {Group: ",", Decimal: ".", Date: "02/01/2006", Time: "15:04"},
+	"en_NZ": {Group: ",", Decimal: ".", Date: "02/01/2006", Time: "3:04 PM"},
+	"de":    {Group: ".", Decimal: ",", Date: "02.01.2006", Time: "15:04"},
+	"de_CH": {Group: "\u2019", Decimal: ".", Date: "02.01.2006", Time: "15:04"},
+	"fr":    {Group: "\u202f", Decimal: ",", Date: "02/01/2006", Time: "15:04"},
+	"fr_CA": {Group: "\u00a0", Decimal: ",", Date: "2006-01-02", Time: "15:04"},
+	"fr_CH": {Group: "\u202f", Decimal: ",", Date: "02.01.2006", Time: "15:04"},
+	"es":    {Group: ".", Decimal: ",", Date: "02/01/2006", Time: "15:04"},
+	"es_MX": {Group: ",", Decimal: ".", Date: "02/01/2006", Time: "15:04"},
+	"it":    {Group: ".", Decimal: ",", Date: "02/01/2006", Time: "15:04"},
+	"pt":    {Group: "\u00a0", Decimal: ",", Date: "02/01/2006", Time: "15:04"},
+	"pt_BR": {Group: ".", Decimal: ",", Date: "02/01/2006", Time: "15:04"},
+	"nl":    {Group: ".", Decimal: ",", Date: "02-01-2006", Time: "15:04"},
+	"da":    {Group: ".", Decimal: ",", Date: "02.01.2006", Time: "15.04"},
+	"nb":    {Group: "\u00a0", Decimal: ",", Date: "02.01.2006", Time: "15:04"},
+	"sv":    {Group: "\u00a0", Decimal: ",", Date: "2006-01-02", Time: "15:04"},
+	"fi":    {Group: "\u00a0", Decimal: ",", Date: "2.1.2006", Time: "15.04"},
+	"pl":    {Group: "\u00a0", Decimal: ",", Date: "02.01.2006", Time: "15:04"},
+	"cs":    {Group: "\u00a0", Decimal: ",", Date: "02.01.2006", Time: "15:04"},
+	"ru":    {Group: "\u00a0", Decimal: ",", Date: "02.01.2006", Time: "15:04"},
+	"uk":    {Group: "\u00a0", Decimal: ",", Date: "02.01.2006", Time: "15:04"},
+	"tr":    {Group: ".", Decimal: ",", Date: "02.01.2006", Time: "15:04"},
+	"ja":    {Group: ",", Decimal: ".", Date: "2006/01/02", Time: "15:04"},
+	"zh":    {Group: ",", Decimal: ".", Date: "2006/01/02", Time: "15:04"},
+	"ko":    {Group: ",", Decimal: ".", Date: "2006. 01. 02.", Time: "15:04"},
+}
+
+// LookupLocale returns the formats of a locale such as "de_DE.UTF-8",
+// "pt-BR" or "fr", falling back from the region to the language.
+// "C" and "POSIX" are DefaultLocale.
+func LookupLocale(name string) (Locale, error) {
+	tag := normalizeLocale(name)
+	if tag == "c" || tag == "posix" {
+		return DefaultLocale, nil
+	}
+
+	language, _, _ := strings.Cut(tag, "_")
+	for _, key := range []string{tag, language} {
+		if locale, ok := locales[key]; ok {
+			locale.Name = tag
+			return locale, nil
+		}
+	}
+	return Locale{}, fmt.Errorf("unknown locale %q", name)
+}
+
+// ResolveLocale turns the output.locale setting into a locale: "auto" (or
+// empty) detects it from the environment, falling back to DefaultLocale
+// for locales without formats of their own
+func ResolveLocale(setting string) (Locale, error) {
+	if setting != "" && !strings.EqualFold(setting, "auto") {
+		return LookupLocale(setting)
+	}
+	if name := SystemLocale(); name != "" {
+		if locale, err := LookupLocale(name); err == nil {
+			return locale, nil
+		}
+	}
+	return DefaultLocale, nil
+}
+
+// normalizeLocale turns "de-DE.UTF-8@euro" into "de_DE": the charset and
+// modifier are dropped, the language lowercased and the region uppercased
+func normalizeLocale(name string) string {
+	name, _, _ = strings.Cut(name, ".")
+	name, _, _ = strings.Cut(name, "@")
+	language, region, found := strings.Cut(strings.ReplaceAll(name, "-", "_"), "_")
+	language = strings.ToLower(strings.TrimSpace(language))
+	if !found {
+		return language
+	}
+	return language + "_" + strings.ToUpper(region)
+}
+
+// ascii replaces the separators of a locale that terminals without Unicode
+// cannot show
+func (l Locale) ascii() Locale {
+	switch l.Group {
+	case "\u00a0", "\u202f":
+		l.Group = " "
+	case "\u2019":
+		l.Group = "'"
+	}
+	return l
+}
+
+// formats returns the locale and size units output is written with
+func formats() (Locale, string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if current.Locale.Name == "" {
+		return DefaultLocale, current.SizeUnits
+	}
+	return current.Locale, current.SizeUnits
+}
+
+// FormatInt writes an integer with the thousands grouped, like 1,234,567
+func FormatInt(n int64) string {
+	locale, _ := formats()
+	return locale.FormatInt(n)
+}
+
+// FormatFloat writes a number with the given number of decimals
+func FormatFloat(f float64, decimals int) string {
+	locale, _ := formats()
+	return locale.FormatFloat(f, decimals)
+}
+
+// FormatSize writes a file size in the configured units, like 1.5 MiB
+func FormatSize(bytes int64) string {
+	locale, units := formats()
+	return locale.FormatSize(bytes, units)
+}
+
+// FormatDate writes the date of t
+func FormatDate(t time.Time) string {
+	locale, _ := formats()
+	return t.Format(locale.Date)
+}
+
+// FormatDateTime writes the date and time of t, to the minute
+func FormatDateTime(t time.Time) string {
+	locale, _ := formats()
+	return t.Format(locale.Date + " " + locale.Time)
+}
+
+// FormatTimestamp writes the date and time of t, to the second
+func FormatTimestamp(t time.Time) string {
+	locale, _ := formats()
+	return t.Format(locale.Date + " " + locale.timeWithSeconds())
+}
+
+// FormatDuration writes a duration like time.Duration.String does, such as
+// 1h2m3.5s, with the locale's decimal separator
+func FormatDuration(d time.Duration) string {
+	locale, _ := formats()
+	return strings.Replace(d.String(), ".", locale.Decimal, 1)
+}
+
+// FormatInt writes an integer with the thousands grouped
+func (l Locale) FormatInt(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	return sign + l.group(digits)
+}
+
+// FormatFloat writes a number with the given number of decimals
+func (l Locale) FormatFloat(f float64, decimals int) string {
+	s := strconv.FormatFloat(math.Abs(f), 'f', decimals, 64)
+	whole, fraction, _ := strings.Cut(s, ".")
+	sign := ""
+	if f < 0 && strings.Trim(s, "0.") != "" {
+		sign = "-"
+	}
+	if fraction == "" {
+		return sign + l.group(whole)
+	}
+	return sign + l.group(whole) + l.Decimal + fraction
+}
+
+// FormatSize writes a file size in binary or SI units
+func (l Locale) FormatSize(bytes int64, units string) string {
+	if bytes <= 0 {
+		return "0 B"
+	}
+
+	base, names := 1024.0, []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	if units == SizeUnitsSI {
+		base, names = 1000.0, []string{"B", "kB", "MB", "GB", "TB"}
+	}
+	size := float64(bytes)
+	i := 0
+	for size >= base && i < len(names)-1 {
+		size /= base
+		i++
+	}
+	if i == 0 {
+		return l.FormatInt(bytes) + " B"
+	}
+	return l.FormatFloat(size, 1) + " " + names[i]
+}
+
+// group inserts the thousands separator into a string of digits
+func (l Locale) group(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(l.Group)
+		}
+		b.WriteRune(digit)
+	}
+	return b.String()
+}
+
+// timeWithSeconds returns the time layout with seconds after the minutes
+func (l Locale) timeWithSeconds() string {
+	i := strings.Index(l.Time, "04")
+	if i < 1 {
+		return l.Time
+	}
+	return l.Time[:i+2] + l.Time[i-1:i] + "05" + l.Time[i+2:]
+}
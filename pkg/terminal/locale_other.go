@@ -0,0 +1,16 @@
+//go:build !windows
+
+package terminal
+
+import "os"
+
+// SystemLocale returns the locale numbers and dates are formatted in: the
+// first of LC_ALL, LC_NUMERIC, LC_TIME and LANG that is set
+func SystemLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_NUMERIC", "LC_TIME", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package terminal
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// localeNameMaxLength is the size of a buffer holding any locale name
+const localeNameMaxLength = 85
+
+var getUserDefaultLocaleName = syscall.NewLazyDLL("kernel32.dll").NewProc("GetUserDefaultLocaleName")
+
+// SystemLocale returns the user's locale from the regional settings, such
+// as "de-DE"
+func SystemLocale() string {
+	var name [localeNameMaxLength]uint16
+	n, _, _ := getUserDefaultLocaleName.Call(uintptr(unsafe.Pointer(&name[0])), localeNameMaxLength)
+	if n == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(name[:])
+}
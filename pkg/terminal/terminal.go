@@ -0,0 +1,248 @@
+// Package terminal adapts output to what the user's terminal can display
+// and to the chosen theme. Themes color status symbols, and terminals that
+// cannot render Unicode get ASCII: status emoji become short tags,
+// decorative emoji are dropped and progress glyphs are replaced. Output for
+// scripts is plain: only the symbols that start its lines are rewritten.
+package terminal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/converso-empire/cli/pkg/config"
+)
+
+// Output modes selected with --output
+const (
+	// ModeAuto writes plain output when stdout is not a terminal
+	ModeAuto = "auto"
+	// ModeHuman always writes output meant for people
+	ModeHuman = "human"
+	// ModePlain always writes plain output meant for scripts
+	ModePlain = "plain"
+	// ModeTable is ModeHuman, named for the tables of list commands
+	ModeTable = "table"
+	// ModeJSON writes command results as JSON
+	ModeJSON = "json"
+	// ModeYAML writes command results as YAML
+	ModeYAML = "yaml"
+)
+
+// Options controls how output is written to the terminal
+type Options struct {
+	// Unicode is whether the terminal renders Unicode
+	Unicode bool
+	// Color enables ANSI colors
+	Color bool
+	Theme *Theme
+	// Plain writes stdout for scripts: stable lines without emoji, colors
+	// or progress bars
+	Plain bool
+	// Format is ModeJSON or ModeYAML when results are written in that
+	// format, which implies Plain
+	Format string
+	// Locale formats numbers, dates and durations, and SizeUnits file
+	// sizes
+	Locale    Locale
+	SizeUnits string
+}
+
+// symbols reports whether Unicode symbols are written as they are
+func (o Options) symbols() bool {
+	return o.Unicode && o.Theme.Emoji
+}
+
+// rewrites reports whether output needs rewriting for these options
+func (o Options) rewrites() bool {
+	return !o.symbols() || (o.Color && len(o.Theme.Colors) > 0)
+}
+
+// FromConfig resolves the output settings of the config. Colors are also
+// off when NO_COLOR is set or the terminal is dumb.
+func FromConfig(cfg config.OutputConfig) (Options, error) {
+	var plain bool
+	var format string
+	switch cfg.Mode {
+	case "", ModeAuto:
+		plain = !IsTerminal(os.Stdout)
+	case ModeHuman, ModeTable:
+	case ModePlain:
+		plain = true
+	case ModeJSON, ModeYAML:
+		plain, format = true, cfg.Mode
+	default:
+		return Options{}, fmt.Errorf("invalid --output %q: expected auto, human, table, plain, json or yaml", cfg.Mode)
+	}
+
+	unicode, err := Resolve(cfg.Unicode)
+	if err != nil {
+		return Options{}, fmt.Errorf("invalid output.unicode: %w", err)
+	}
+	theme, err := LookupTheme(cfg.Theme)
+	if err != nil {
+		return Options{}, fmt.Errorf("invalid output.theme: %w", err)
+	}
+
+	// Output for scripts keeps stable formats unless a locale is set
+	locale := DefaultLocale
+	if !plain || (cfg.Locale != "" && !strings.EqualFold(cfg.Locale, "auto")) {
+		if locale, err = ResolveLocale(cfg.Locale); err != nil {
+			return Options{}, fmt.Errorf("invalid output.locale: %w", err)
+		}
+	}
+	if !unicode {
+		locale = locale.ascii()
+	}
+	sizeUnits := strings.ToLower(cfg.SizeUnits)
+	switch sizeUnits {
+	case "":
+		sizeUnits = SizeUnitsBinary
+	case SizeUnitsBinary, SizeUnitsSI:
+	default:
+		return Options{}, fmt.Errorf("invalid output.size_units %q: expected binary or si", cfg.SizeUnits)
+	}
+
+	color := cfg.Color && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	return Options{Unicode: unicode, Color: color, Theme: theme, Plain: plain, Format: format, Locale: locale, SizeUnits: sizeUnits}, nil
+}
+
+// Resolve turns the output.unicode setting into whether to write Unicode:
+// "auto" (or empty) detects terminal support, anything else is a boolean
+func Resolve(setting string) (bool, error) {
+	if setting == "" || strings.EqualFold(setting, "auto") {
+		return SupportsUnicode(), nil
+	}
+	unicode, err := strconv.ParseBool(setting)
+	if err != nil {
+		return false, fmt.Errorf("expected auto, true or false, got %q", setting)
+	}
+	return unicode, nil
+}
+
+// themedStream is a standard stream redirected through a theme
+type themedStream struct {
+	std      **os.File
+	original *os.File
+	pipe     *os.File
+	done     chan struct{}
+}
+
+var (
+	mu      sync.Mutex
+	current = Options{Unicode: true, Theme: Themes[DefaultTheme]}
+	streams []*themedStream
+)
+
+// Configure applies opts to the standard output and error streams. Apart
+// from plain stdout, only streams attached to a terminal are affected;
+// Close must be called before the process exits to flush them.
+func Configure(opts Options) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	closeStreams()
+	current = opts
+
+	for _, std := range []**os.File{&os.Stdout, &os.Stderr} {
+		var newWriter func(io.Writer) io.Writer
+		switch {
+		case opts.Plain && std == &os.Stdout:
+			newWriter = func(w io.Writer) io.Writer { return newPlainWriter(w) }
+		case opts.rewrites() && isCharDevice(*std):
+			newWriter = func(w io.Writer) io.Writer { return newThemeWriter(w, opts) }
+		default:
+			continue
+		}
+
+		if err := redirect(std, newWriter); err != nil {
+			closeStreams()
+			return err
+		}
+	}
+	return nil
+}
+
+// redirect sends everything written to a standard stream through the
+// writer newWriter creates around the original stream
+func redirect(std **os.File, newWriter func(io.Writer) io.Writer) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to redirect output: %w", err)
+	}
+
+	s := &themedStream{std: std, original: *std, pipe: w, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		defer r.Close()
+		io.Copy(newWriter(s.original), r)
+	}()
+
+	*std = w
+	streams = append(streams, s)
+	return nil
+}
+
+// Unicode reports whether Unicode symbols reach the terminal as they are
+func Unicode() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return !current.Plain && (len(streams) == 0 || current.symbols())
+}
+
+// Plain reports whether stdout is written for scripts rather than people
+func Plain() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return current.Plain
+}
+
+// Format returns ModeJSON or ModeYAML when results are written in that
+// format, and "" otherwise
+func Format() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return current.Format
+}
+
+// Close restores the standard streams and writes any pending output
+func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	closeStreams()
+}
+
+// closeStreams undoes the redirection of all streams
+func closeStreams() {
+	for _, s := range streams {
+		*s.std = s.original
+		s.pipe.Close()
+		<-s.done
+	}
+	streams = nil
+}
+
+// IsTerminal reports whether f is attached to a terminal. Standard streams
+// redirected through a theme report on the terminal behind them.
+func IsTerminal(f *os.File) bool {
+	mu.Lock()
+	for _, s := range streams {
+		if f == s.pipe {
+			f = s.original
+		}
+	}
+	mu.Unlock()
+	return isCharDevice(f)
+}
+
+// isCharDevice reports whether f is a character device such as a terminal
+func isCharDevice(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
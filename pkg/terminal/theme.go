@@ -0,0 +1,269 @@
+package terminal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Role is the meaning of a symbol the CLI prints, which themes color
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleSuccess
+	RoleError
+	RoleWarning
+	RoleInfo
+	RoleProgress
+)
+
+// symbolRoles gives the meaning of the CLI's status symbols
+var symbolRoles = map[rune]Role{
+	'✅': RoleSuccess,
+	'🟢': RoleSuccess,
+	'❌': RoleError,
+	'🚫': RoleError,
+	'⚠': RoleWarning,
+	'ℹ': RoleInfo,
+	'💡': RoleInfo,
+	'❓': RoleInfo,
+	'█': RoleProgress,
+}
+
+// asciiSymbols replaces the symbols the CLI prints with ASCII. Status
+// symbols become tags; other emoji are decoration and are dropped.
+var asciiSymbols = map[rune]string{
+	'✅': "[ok]",
+	'❌': "[x]",
+	'🚫': "[x]",
+	'⚠': "[!]",
+	'ℹ': "[i]",
+	'💡': "Tip:",
+	'❓': "[?]",
+	'🟢': "[+]",
+	'⚪': "[-]",
+	'⏸': "||",
+	'▶': ">",
+	'⏭': ">>",
+	'•': "*",
+	'→': "->",
+	'…': "...",
+	'█': "#",
+	'░': "-",
+	'▀': "\"",
+	'▄': "_",
+
+	// The braille spinner turns into the classic one
+	'⠋': "|", '⠙': "/", '⠹': "-", '⠸': "\\", '⠼': "|",
+	'⠴': "/", '⠦': "-", '⠧': "\\", '⠇': "|", '⠏': "/",
+}
+
+// Theme decides how the CLI's symbols look
+type Theme struct {
+	Name string
+	// Emoji keeps emoji on terminals that render Unicode; themes without
+	// them always use the ASCII symbols
+	Emoji bool
+	// Colors are the SGR parameters per role, such as "32" for green
+	Colors map[Role]string
+}
+
+// DefaultTheme is used unless the config selects another theme
+const DefaultTheme = "default"
+
+// Themes are the selectable themes by name
+var Themes = map[string]*Theme{
+	DefaultTheme: {
+		Name:  DefaultTheme,
+		Emoji: true,
+		Colors: map[Role]string{
+			RoleSuccess:  "32",
+			RoleError:    "31",
+			RoleWarning:  "33",
+			RoleInfo:     "36",
+			RoleProgress: "32",
+		},
+	},
+	// minimal drops emoji and colors only what needs attention
+	"minimal": {
+		Name: "minimal",
+		Colors: map[Role]string{
+			RoleError:   "31",
+			RoleWarning: "33",
+		},
+	},
+	// high-contrast uses text tags in bold, bright colors
+	"high-contrast": {
+		Name: "high-contrast",
+		Colors: map[Role]string{
+			RoleSuccess:  "1;92",
+			RoleError:    "1;91",
+			RoleWarning:  "1;93",
+			RoleInfo:     "1;96",
+			RoleProgress: "1;97",
+		},
+	},
+}
+
+// LookupTheme returns the theme with the given name; empty selects the
+// default theme
+func LookupTheme(name string) (*Theme, error) {
+	if name == "" {
+		name = DefaultTheme
+	}
+	theme, ok := Themes[name]
+	if !ok {
+		names := make([]string, 0, len(Themes))
+		for n := range Themes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return theme, nil
+}
+
+// themeWriter rewrites UTF-8 output with a theme. Text that is not a
+// symbol, such as accented letters in titles and paths, is kept.
+type themeWriter struct {
+	w      io.Writer
+	ascii  bool
+	colors map[Role]string
+	// markersOnly limits rewriting to the symbols that start a line, so
+	// data printed by plain output stays as it is
+	markersOnly bool
+	// partial holds the start of a character split across writes
+	partial []byte
+	// dropSpace is set after a dropped emoji so the spaces that separated
+	// it from the text go too
+	dropSpace bool
+	// color is the SGR parameters in effect, empty when none are
+	color string
+	// inText is set once the current line has left its leading symbols
+	inText bool
+}
+
+// newThemeWriter creates a writer applying opts to terminal output in w
+func newThemeWriter(w io.Writer, opts Options) *themeWriter {
+	t := &themeWriter{w: w, ascii: !opts.symbols()}
+	if opts.Color {
+		t.colors = opts.Theme.Colors
+	}
+	return t
+}
+
+// newPlainWriter creates a writer for plain output in w: the symbols that
+// start lines are written as ASCII, without colors
+func newPlainWriter(w io.Writer) *themeWriter {
+	return &themeWriter{w: w, ascii: true, markersOnly: true}
+}
+
+// Write rewrites p and writes it to the underlying writer
+func (t *themeWriter) Write(p []byte) (int, error) {
+	data := append(t.partial, p...)
+	t.partial = nil
+
+	out := make([]byte, 0, len(data))
+	for len(data) > 0 {
+		if !utf8.FullRune(data) {
+			t.partial = append([]byte(nil), data...)
+			break
+		}
+		r, size := utf8.DecodeRune(data)
+		out = t.appendRune(out, r, data[:size])
+		data = data[size:]
+	}
+	// Colors never outlast a write, so they cannot leak past the CLI
+	out = t.setColor(out, "")
+
+	if _, err := t.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// appendRune appends the themed form of r, whose encoding is raw
+func (t *themeWriter) appendRune(out []byte, r rune, raw []byte) []byte {
+	if t.markersOnly {
+		switch {
+		case r == '\n' || r == '\r':
+			t.inText = false
+		case t.inText:
+			return append(out, raw...)
+		case r != ' ' && r != '\t' && !isSymbol(r):
+			t.inText = true
+			t.dropSpace = false
+			return append(out, raw...)
+		}
+	}
+
+	if t.ascii {
+		// Modifiers vanish with the symbol they modify
+		if isModifier(r) {
+			return out
+		}
+		if t.dropSpace && r == ' ' {
+			return out
+		}
+		t.dropSpace = false
+	}
+
+	// ASCII and bytes that are not UTF-8 pass through unchanged
+	if r < utf8.RuneSelf || r == utf8.RuneError {
+		return append(t.setColor(out, ""), raw...)
+	}
+
+	// Modifiers of an emoji keep its color
+	if isModifier(r) {
+		return append(out, raw...)
+	}
+
+	symbol := raw
+	if t.ascii {
+		if s, ok := asciiSymbols[r]; ok {
+			symbol = []byte(s)
+		} else if isEmoji(r) {
+			t.dropSpace = true
+			return out
+		}
+	}
+
+	out = t.setColor(out, t.colors[symbolRoles[r]])
+	return append(out, symbol...)
+}
+
+// setColor switches the color in effect, with empty resetting it
+func (t *themeWriter) setColor(out []byte, color string) []byte {
+	if color == t.color {
+		return out
+	}
+	if t.color != "" {
+		out = append(out, "\033[0m"...)
+	}
+	if color != "" {
+		out = append(out, "\033["+color+"m"...)
+	}
+	t.color = color
+	return out
+}
+
+// isSymbol reports whether r is a symbol the theme rewrites
+func isSymbol(r rune) bool {
+	_, ok := asciiSymbols[r]
+	return ok || isModifier(r) || isEmoji(r)
+}
+
+// isModifier reports whether r only modifies the emoji before it, such as
+// a variation selector or zero-width joiner
+func isModifier(r rune) bool {
+	return r == '\u200d' || (r >= '\ufe00' && r <= '\ufe0f') || (r >= 0x1f3fb && r <= 0x1f3ff)
+}
+
+// isEmoji reports whether r is an emoji or other pictographic symbol
+func isEmoji(r rune) bool {
+	return (r >= 0x1f000 && r <= 0x1faff) || unicode.Is(unicode.So, r)
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+
+package terminal
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// SupportsUnicode reports whether the terminal is expected to render UTF-8.
+// The locale decides: the first of LC_ALL, LC_CTYPE and LANG that is set
+// must name a UTF-8 charset. The Linux console cannot draw emoji at all.
+func SupportsUnicode() bool {
+	if os.Getenv("TERM") == "linux" {
+		return false
+	}
+
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			return isUTF8Locale(locale)
+		}
+	}
+
+	// Without a locale the C library falls back to ASCII, except on macOS
+	// where the system is always UTF-8
+	return runtime.GOOS == "darwin"
+}
+
+// isUTF8Locale reports whether a locale such as "en_US.UTF-8" or
+// "C.utf8" uses the UTF-8 charset
+func isUTF8Locale(locale string) bool {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "-", ""))
+	return strings.Contains(locale, "utf8")
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package terminal
+
+import (
+	"os"
+	"syscall"
+)
+
+// codePageUTF8 is the Windows code page identifier of UTF-8
+const codePageUTF8 = 65001
+
+var getConsoleOutputCP = syscall.NewLazyDLL("kernel32.dll").NewProc("GetConsoleOutputCP")
+
+// SupportsUnicode reports whether the console is expected to render UTF-8.
+// Windows Terminal and VS Code always do; the legacy console only when it
+// was switched to the UTF-8 code page, for example with 'chcp 65001'.
+func SupportsUnicode() bool {
+	if os.Getenv("WT_SESSION") != "" || os.Getenv("TERM_PROGRAM") == "vscode" {
+		return true
+	}
+
+	codePage, _, _ := getConsoleOutputCP.Call()
+	return codePage == codePageUTF8
+}
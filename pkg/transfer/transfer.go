@@ -0,0 +1,171 @@
+// Package transfer moves downloaded files between devices over HTTP.
+// Servers send each file with its SHA-256 checksum and support range
+// requests, so interrupted fetches resume where they stopped and finished
+// ones are verified before they are moved into place.
+package transfer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ChecksumHeader carries the hex SHA-256 of the whole file
+const ChecksumHeader = "X-Converso-Sha256"
+
+// partSuffix is appended to the destination while a fetch is incomplete
+const partSuffix = ".part"
+
+// FileSHA256 returns the hex SHA-256 of a file
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Serve writes a file with its checksum, answering range requests
+func Serve(rw http.ResponseWriter, r *http.Request, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(rw, "file not available", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.Error(rw, "file not available", http.StatusNotFound)
+		return
+	}
+	checksum, err := FileSHA256(path)
+	if err != nil {
+		http.Error(rw, "failed to read file", http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set(ChecksumHeader, checksum)
+	rw.Header().Set("ETag", `"`+checksum+`"`)
+	rw.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(path)}))
+	http.ServeContent(rw, r, filepath.Base(path), info.ModTime(), f)
+}
+
+// Progress is called as a fetch writes data, with the bytes written so far
+// and the file size, or -1 when the server did not send it
+type Progress func(written, total int64)
+
+// Fetch downloads the file req points at to dest. Data left in dest.part
+// by an interrupted fetch is resumed with a range request. The finished
+// file must match the checksum the server sent; on a mismatch the partial
+// data is removed so the next fetch starts over.
+func Fetch(client *http.Client, req *http.Request, dest string, progress Progress) error {
+	part := dest + partSuffix
+
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("transfer failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// The server ignored the range; start over
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Everything was fetched before; only verification is left
+		flags |= os.O_APPEND
+	default:
+		var body struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &body) != nil || body.Error == "" {
+			body.Error = strings.TrimSpace(string(data))
+		}
+		return fmt.Errorf("transfer failed: HTTP %d: %s", resp.StatusCode, body.Error)
+	}
+
+	checksum := resp.Header.Get(ChecksumHeader)
+	if checksum == "" {
+		return fmt.Errorf("transfer failed: the server sent no checksum")
+	}
+
+	out, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", part, err)
+	}
+
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		total := int64(-1)
+		if size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+			total = offset + size
+		}
+		var w io.Writer = out
+		if progress != nil {
+			w = &progressWriter{w: out, written: offset, total: total, progress: progress}
+		}
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			out.Close()
+			return fmt.Errorf("transfer interrupted, run again to resume: %w", err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", part, err)
+	}
+
+	actual, err := FileSHA256(part)
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", part, err)
+	}
+	if actual != checksum {
+		os.Remove(part)
+		return fmt.Errorf("checksum mismatch (expected %s, got %s); run again to start over", checksum, actual)
+	}
+
+	if err := os.Rename(part, dest); err != nil {
+		return fmt.Errorf("failed to move %s into place: %w", dest, err)
+	}
+	return nil
+}
+
+// progressWriter reports the bytes written through it
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	progress Progress
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.progress(p.written, p.total)
+	return n, err
+}
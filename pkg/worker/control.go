@@ -0,0 +1,288 @@
+package worker
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+	"github.com/converso-empire/cli/pkg/transfer"
+)
+
+// Control API paths. The control API lets 'converso --remote' manage the
+// worker from another machine. Only the queue paths and pausing and
+// resuming jobs change anything.
+const (
+	ControlStatusPath = "/v1/status"
+	ControlJobsPath   = "/v1/jobs"
+	ControlQueuePath  = "/v1/queue"
+)
+
+// controlFileSuffix follows a job path to fetch the job's file
+const controlFileSuffix = "/file"
+
+// controlTopSuffix follows a queued job's path to move it to the top
+const controlTopSuffix = "/top"
+
+// controlPauseSuffix and controlResumeSuffix follow a running job's path
+// to pause and resume it
+const (
+	controlPauseSuffix  = "/pause"
+	controlResumeSuffix = "/resume"
+)
+
+// maxTrackedJobs bounds the finished jobs remembered for the control API
+const maxTrackedJobs = 200
+
+// trackJob remembers a copy of the job's current state and persists its
+// progress
+func (w *Worker) trackJob(job *Job) {
+	w.jobsMu.Lock()
+	defer w.jobsMu.Unlock()
+
+	previous, seen := w.jobs[job.ID]
+	if !seen {
+		w.jobsOrder = append(w.jobsOrder, job.ID)
+	}
+	// Progress reported after a job finished is its last, so it is written
+	// at once too
+	tracked := *job
+	tracked.Status = w.pausedStatus(job)
+	status := JobStatus(tracked.Status)
+	save := !seen || previous.Status != tracked.Status || previous.Next != job.Next ||
+		(status != JobStatusPending && status != JobStatusRunning && status != JobStatusPaused)
+	w.jobs[job.ID] = &tracked
+
+	// Forget the oldest finished jobs
+	for i := 0; len(w.jobsOrder) > maxTrackedJobs && i < len(w.jobsOrder); {
+		id := w.jobsOrder[i]
+		if status := JobStatus(w.jobs[id].Status); status == JobStatusPending || status == JobStatusRunning || status == JobStatusPaused {
+			i++
+			continue
+		}
+		delete(w.jobs, id)
+		w.jobsOrder = append(w.jobsOrder[:i], w.jobsOrder[i+1:]...)
+	}
+	w.saveProgress(save)
+}
+
+// Jobs returns the jobs this worker has queued, run or finished, oldest
+// first
+func (w *Worker) Jobs() []*Job {
+	w.jobsMu.RLock()
+	defer w.jobsMu.RUnlock()
+
+	jobs := make([]*Job, 0, len(w.jobsOrder))
+	for _, id := range w.jobsOrder {
+		job := *w.jobs[id]
+		jobs = append(jobs, &job)
+	}
+	return jobs
+}
+
+// Job returns the job with the given ID, or nil when the worker has not
+// seen it
+func (w *Worker) Job(id string) *Job {
+	w.jobsMu.RLock()
+	defer w.jobsMu.RUnlock()
+
+	tracked, ok := w.jobs[id]
+	if !ok {
+		return nil
+	}
+	job := *tracked
+	return &job
+}
+
+// ControlHandler serves the control API. Every request must carry token as
+// a bearer token.
+func (w *Worker) ControlHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(ControlStatusPath, func(rw http.ResponseWriter, r *http.Request) {
+		if allowMethod(rw, r, http.MethodGet) {
+			writeControl(rw, http.StatusOK, w.Status())
+		}
+	})
+
+	mux.HandleFunc(ControlJobsPath, func(rw http.ResponseWriter, r *http.Request) {
+		if allowMethod(rw, r, http.MethodGet) {
+			writeControl(rw, http.StatusOK, w.Jobs())
+		}
+	})
+
+	// /v1/jobs/{id} and /v1/jobs/{id}/file, and POST /v1/jobs/{id}/pause
+	// and /v1/jobs/{id}/resume
+	mux.HandleFunc(ControlJobsPath+"/", func(rw http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, ControlJobsPath+"/")
+		var control func(id string) (*Job, error)
+		switch {
+		case strings.HasSuffix(id, controlPauseSuffix):
+			id, control = strings.TrimSuffix(id, controlPauseSuffix), w.PauseJob
+		case strings.HasSuffix(id, controlResumeSuffix):
+			id, control = strings.TrimSuffix(id, controlResumeSuffix), w.ResumeJob
+		}
+		if control != nil {
+			if !allowMethod(rw, r, http.MethodPost) {
+				return
+			}
+			if w.Job(id) == nil {
+				writeControl(rw, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("job %s not found", id)})
+				return
+			}
+			job, err := control(id)
+			if err != nil {
+				writeControl(rw, http.StatusConflict, map[string]string{"error": err.Error()})
+				return
+			}
+			writeControl(rw, http.StatusOK, job)
+			return
+		}
+
+		if !allowMethod(rw, r, http.MethodGet) {
+			return
+		}
+		file := false
+		if strings.HasSuffix(id, controlFileSuffix) {
+			id, file = strings.TrimSuffix(id, controlFileSuffix), true
+		}
+		job := w.Job(id)
+		if job == nil {
+			writeControl(rw, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("job %s not found", id)})
+			return
+		}
+		if !file {
+			writeControl(rw, http.StatusOK, job)
+			return
+		}
+
+		if w.config.Worker.Sync != config.SyncDirect {
+			writeControl(rw, http.StatusForbidden, map[string]string{"error": "file sync is off on this worker (worker.sync)"})
+			return
+		}
+		path := JobFile(job)
+		if path == "" {
+			writeControl(rw, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("job %s has no downloaded file", id)})
+			return
+		}
+		transfer.Serve(rw, r, path)
+	})
+
+	mux.HandleFunc(ControlQueuePath, func(rw http.ResponseWriter, r *http.Request) {
+		if allowMethod(rw, r, http.MethodGet) {
+			writeControl(rw, http.StatusOK, w.QueuedJobs())
+		}
+	})
+
+	// POST /v1/queue/{id}/top and DELETE /v1/queue/{id}
+	mux.HandleFunc(ControlQueuePath+"/", func(rw http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, ControlQueuePath+"/")
+		if strings.HasSuffix(id, controlTopSuffix) {
+			if !allowMethod(rw, r, http.MethodPost) {
+				return
+			}
+			if err := w.MoveJobToTop(strings.TrimSuffix(id, controlTopSuffix)); err != nil {
+				writeControl(rw, http.StatusNotFound, map[string]string{"error": err.Error()})
+				return
+			}
+			writeControl(rw, http.StatusOK, w.QueuedJobs())
+			return
+		}
+
+		if !allowMethod(rw, r, http.MethodDelete) {
+			return
+		}
+		job, err := w.RemoveQueuedJob(id)
+		if err != nil {
+			writeControl(rw, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeControl(rw, http.StatusOK, job)
+	})
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeControl(rw, http.StatusUnauthorized, map[string]string{"error": "invalid control token"})
+			return
+		}
+		mux.ServeHTTP(rw, r)
+	})
+}
+
+// allowMethod reports whether a request uses method, answering it with
+// 405 otherwise
+func allowMethod(rw http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	rw.Header().Set("Allow", method)
+	writeControl(rw, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	return false
+}
+
+// writeControl writes a JSON control API response
+func writeControl(rw http.ResponseWriter, code int, body interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	json.NewEncoder(rw).Encode(body)
+}
+
+// ControlTokenPath returns where the generated control token is kept
+func ControlTokenPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "worker-control-token")
+}
+
+// ControlToken returns the token clients of the control API must present:
+// CONVERSO_WORKER_CONTROL_TOKEN (or its _FILE variant) when set, otherwise
+// a random token generated on first use and kept in the data directory
+func ControlToken(cfg *config.Config) (string, error) {
+	token, err := config.Secret("worker_control_token")
+	if err != nil || token != "" {
+		return token, err
+	}
+
+	path := ControlTokenPath(cfg)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read control token: %w", err)
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate control token: %w", err)
+	}
+	token = hex.EncodeToString(raw)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create data directory: %w", err)
+	}
+	if err := fileutil.WriteAtomic(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write control token: %w", err)
+	}
+	return token, nil
+}
+
+// IsLoopback reports whether a listen address only accepts connections
+// from this machine
+func IsLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,180 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/httpclient"
+)
+
+// Health endpoint paths. /healthz is the liveness probe and only fails when
+// the worker has stopped; /readyz is the readiness probe and also fails
+// while a dependency such as authentication or the backend is unavailable.
+const (
+	HealthPath = "/healthz"
+	ReadyPath  = "/readyz"
+)
+
+// readinessTimeout bounds a single readiness check
+const readinessTimeout = 3 * time.Second
+
+// ReadinessCheck reports whether a dependency of the worker is usable
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of a readiness check
+type CheckResult struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Readiness is the response of the readiness endpoint
+type Readiness struct {
+	Ready  bool          `json:"ready"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// AddReadinessCheck registers a check reported by the readiness endpoint.
+// Checks must be added before the worker is started.
+func (w *Worker) AddReadinessCheck(name string, check func(ctx context.Context) error) {
+	w.readinessChecks = append(w.readinessChecks, ReadinessCheck{Name: name, Check: check})
+}
+
+// Readiness runs the readiness checks. The worker is ready when it is
+// running and every check passes.
+func (w *Worker) Readiness(ctx context.Context) *Readiness {
+	readiness := &Readiness{Ready: true}
+
+	running := CheckResult{Name: "worker", OK: w.IsRunning()}
+	if !running.OK {
+		running.Error = "worker is not running"
+	}
+	readiness.Checks = append(readiness.Checks, running)
+
+	for _, check := range w.readinessChecks {
+		checkCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+		err := check.Check(checkCtx)
+		cancel()
+
+		result := CheckResult{Name: check.Name, OK: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		readiness.Checks = append(readiness.Checks, result)
+	}
+
+	for _, result := range readiness.Checks {
+		if !result.OK {
+			readiness.Ready = false
+		}
+	}
+	return readiness
+}
+
+// checkAuth verifies that credentials are available and unexpired. Tokens
+// are reloaded so rotated or removed credentials are noticed.
+func (w *Worker) checkAuth(ctx context.Context) error {
+	tokens, err := w.loadAuthTokens(ctx)
+	if err != nil {
+		return err
+	}
+	if tokens.IsExpired() {
+		return fmt.Errorf("access token expired at %s", tokens.ExpiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// checkBackend verifies that the API endpoint answers. Any response below
+// 500 counts as reachable.
+func (w *Worker) checkBackend(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, httpclient.BackendHealth.Method, httpclient.BackendHealth.URL(w.config), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("backend unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("backend unavailable: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// HealthHandler serves the liveness and readiness endpoints
+func (w *Worker) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(HealthPath, func(rw http.ResponseWriter, r *http.Request) {
+		status := w.Status()
+		writeProbe(rw, status.Running, status)
+	})
+
+	mux.HandleFunc(ReadyPath, func(rw http.ResponseWriter, r *http.Request) {
+		readiness := w.Readiness(r.Context())
+		writeProbe(rw, readiness.Ready, readiness)
+	})
+
+	return mux
+}
+
+// writeProbe writes a probe response: HTTP 200 when ok, 503 otherwise
+func writeProbe(rw http.ResponseWriter, ok bool, body interface{}) {
+	code := http.StatusOK
+	if !ok {
+		code = http.StatusServiceUnavailable
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	json.NewEncoder(rw).Encode(body)
+}
+
+// Probe queries a health endpoint path of a worker listening on addr and
+// returns an error unless it answers HTTP 200
+func Probe(addr, path string, timeout time.Duration) error {
+	client := &http.Client{Timeout: timeout}
+
+	resp, err := client.Get(ProbeURL(addr, path))
+	if err != nil {
+		return fmt.Errorf("worker health endpoint unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("worker %s returned HTTP %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// ProbeURL returns the URL of a health endpoint path for a listen address
+func ProbeURL(addr, path string) string {
+	return "http://" + LocalAddr(addr) + path
+}
+
+// LocalAddr returns the address to reach a listen address from this
+// machine, using the loopback interface when the address binds all
+// interfaces
+func LocalAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
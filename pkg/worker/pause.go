@@ -0,0 +1,123 @@
+package worker
+
+import (
+	"fmt"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/events"
+)
+
+// jobControl holds the controls sent to a running job, which the module
+// running it receives as control messages
+type jobControl struct {
+	controls chan *bridge.Control
+	paused   bool
+}
+
+// startControl lets a job be paused and resumed while it runs and returns
+// the controls sent to it
+func (w *Worker) startControl(id string) <-chan *bridge.Control {
+	w.jobsMu.Lock()
+	defer w.jobsMu.Unlock()
+
+	control := &jobControl{controls: make(chan *bridge.Control, 16)}
+	w.controls[id] = control
+	return control.controls
+}
+
+// endControl stops taking controls for a job once it finished
+func (w *Worker) endControl(id string) {
+	w.jobsMu.Lock()
+	defer w.jobsMu.Unlock()
+	delete(w.controls, id)
+}
+
+// PauseJob pauses a running job until it is resumed and returns it. Time
+// spent paused counts towards the job's timeout.
+func (w *Worker) PauseJob(id string) (*Job, error) {
+	return w.controlJob(id, true)
+}
+
+// ResumeJob resumes a paused job and returns it
+func (w *Worker) ResumeJob(id string) (*Job, error) {
+	return w.controlJob(id, false)
+}
+
+// controlJob pauses or resumes a running job
+func (w *Worker) controlJob(id string, pause bool) (*Job, error) {
+	w.jobsMu.Lock()
+	tracked, ok := w.jobs[id]
+	if !ok {
+		w.jobsMu.Unlock()
+		return nil, fmt.Errorf("job %s not found", id)
+	}
+	control, ok := w.controls[id]
+	if !ok {
+		w.jobsMu.Unlock()
+		return nil, fmt.Errorf("job %s is not running (status: %s)", id, tracked.Status)
+	}
+	if control.paused == pause {
+		w.jobsMu.Unlock()
+		if pause {
+			return nil, fmt.Errorf("job %s is already paused", id)
+		}
+		return nil, fmt.Errorf("job %s is not paused", id)
+	}
+
+	action, status := bridge.ControlResume, JobStatusRunning
+	if pause {
+		action, status = bridge.ControlPause, JobStatusPaused
+	}
+	select {
+	case control.controls <- &bridge.Control{Action: action}:
+	default:
+		w.jobsMu.Unlock()
+		return nil, fmt.Errorf("job %s is not taking controls, try again", id)
+	}
+	control.paused = pause
+	tracked.Status = string(status)
+	w.saveProgress(true)
+	job := *tracked
+	w.jobsMu.Unlock()
+
+	if pause {
+		w.logger.Info("Job paused", "job_id", id)
+	} else {
+		w.logger.Info("Job resumed", "job_id", id)
+	}
+	events.Publish(events.JobStateChanged, events.Job{ID: job.ID, Module: job.Module, Command: job.Command, Status: job.Status})
+	return &job, nil
+}
+
+// pausedStatus returns the status of a job to track, which is paused while
+// a running job is. The caller holds jobsMu.
+func (w *Worker) pausedStatus(job *Job) string {
+	if control, ok := w.controls[job.ID]; ok && control.paused && JobStatus(job.Status) == JobStatusRunning {
+		return string(JobStatusPaused)
+	}
+	return job.Status
+}
+
+// honorControls applies the controls sent to a job, waiting while it is
+// paused. It returns false if the worker stops meanwhile.
+func (w *Worker) honorControls(controls <-chan *bridge.Control) bool {
+	paused := false
+	for {
+		if !paused {
+			select {
+			case control := <-controls:
+				paused = control.Action == bridge.ControlPause
+				continue
+			default:
+				return true
+			}
+		}
+
+		select {
+		case control := <-controls:
+			paused = control.Action == bridge.ControlPause
+		case <-w.stopCh:
+			return false
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+)
+
+// PowerState describes the power source and network the machine is on.
+// Conditions that cannot be detected on the current platform are reported
+// as unknown and never pause the worker.
+type PowerState struct {
+	BatteryKnown   bool      `json:"battery_known"`
+	OnBattery      bool      `json:"on_battery"`
+	BatteryPercent int       `json:"battery_percent"`
+	MeteredKnown   bool      `json:"metered_known"`
+	Metered        bool      `json:"metered"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// detectTimeout bounds the helper commands used for detection
+const detectTimeout = 5 * time.Second
+
+// batteryPercentPattern matches the charge percentage in pmset output
+var batteryPercentPattern = regexp.MustCompile(`(\d+)%`)
+
+// DetectPowerState reads the current power and network state
+func DetectPowerState() PowerState {
+	state := PowerState{BatteryPercent: -1, CheckedAt: time.Now()}
+
+	switch runtime.GOOS {
+	case "linux":
+		detectLinuxBattery(&state)
+		detectLinuxMetered(&state)
+	case "darwin":
+		detectDarwinBattery(&state)
+	case "windows":
+		detectWindowsBattery(&state)
+	}
+
+	return state
+}
+
+// PauseReason returns why heavy jobs should wait under the given state, or
+// an empty string when they may run
+func PauseReason(cfg config.WorkerConfig, state PowerState) string {
+	if cfg.PauseOnBattery && state.BatteryKnown && state.OnBattery {
+		if state.BatteryPercent < 0 {
+			return "on battery"
+		}
+		if state.BatteryPercent < cfg.MinBatteryPercent {
+			return fmt.Sprintf("on battery at %d%% (below %d%%)", state.BatteryPercent, cfg.MinBatteryPercent)
+		}
+	}
+
+	if cfg.PauseOnMetered && state.MeteredKnown && state.Metered {
+		return "on a metered connection"
+	}
+
+	return ""
+}
+
+// detectLinuxBattery reads battery and AC adapter state from sysfs
+func detectLinuxBattery(state *PowerState) {
+	supplies, _ := filepath.Glob("/sys/class/power_supply/*")
+
+	batteryFound, discharging, acOnline, acFound := false, false, false, false
+	for _, supply := range supplies {
+		switch readSysfs(supply, "type") {
+		case "Battery":
+			// Peripheral batteries (mice, keyboards) are not system batteries
+			if scope := readSysfs(supply, "scope"); scope == "Device" {
+				continue
+			}
+			batteryFound = true
+			if capacity, err := strconv.Atoi(readSysfs(supply, "capacity")); err == nil {
+				state.BatteryPercent = capacity
+			}
+			if readSysfs(supply, "status") == "Discharging" {
+				discharging = true
+			}
+		case "Mains", "USB", "USB_C":
+			acFound = true
+			if readSysfs(supply, "online") == "1" {
+				acOnline = true
+			}
+		}
+	}
+
+	if !batteryFound {
+		return
+	}
+	state.BatteryKnown = true
+	state.OnBattery = discharging || (acFound && !acOnline)
+}
+
+// detectLinuxMetered asks NetworkManager whether the primary connection is
+// metered
+func detectLinuxMetered(state *PowerState) {
+	out, err := runDetect("busctl", "get-property", "org.freedesktop.NetworkManager",
+		"/org/freedesktop/NetworkManager", "org.freedesktop.NetworkManager", "Metered")
+	if err != nil {
+		return
+	}
+
+	// Output is "u <NMMetered>": 1 yes, 2 no, 3 guessed yes, 4 guessed no
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return
+	}
+	switch fields[1] {
+	case "1", "3":
+		state.MeteredKnown, state.Metered = true, true
+	case "2", "4":
+		state.MeteredKnown = true
+	}
+}
+
+// detectDarwinBattery parses the output of pmset
+func detectDarwinBattery(state *PowerState) {
+	out, err := runDetect("pmset", "-g", "batt")
+	if err != nil || !strings.Contains(out, "InternalBattery") {
+		return
+	}
+
+	state.BatteryKnown = true
+	state.OnBattery = strings.Contains(out, "'Battery Power'")
+	if match := batteryPercentPattern.FindStringSubmatch(out); match != nil {
+		state.BatteryPercent, _ = strconv.Atoi(match[1])
+	}
+}
+
+// detectWindowsBattery queries WMI through wmic
+func detectWindowsBattery(state *PowerState) {
+	out, err := runDetect("wmic", "path", "Win32_Battery", "get", "BatteryStatus,EstimatedChargeRemaining", "/format:list")
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "BatteryStatus":
+			state.BatteryKnown = true
+			// 1 means the battery is discharging
+			state.OnBattery = value == "1"
+		case "EstimatedChargeRemaining":
+			if percent, err := strconv.Atoi(value); err == nil {
+				state.BatteryPercent = percent
+			}
+		}
+	}
+}
+
+// readSysfs reads a trimmed sysfs attribute
+func readSysfs(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// runDetect runs a detection helper with a timeout
+func runDetect(name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), detectTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	return string(out), err
+}
@@ -0,0 +1,106 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/converso-empire/cli/pkg/config"
+)
+
+// PIDPath returns the location of the PID file a running worker holds
+func PIDPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "worker.pid")
+}
+
+// StopRequestPath returns the file whose creation asks a running worker to
+// stop, for systems without SIGTERM
+func StopRequestPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "worker.stop")
+}
+
+// ConsoleLogPath returns where a worker started with --daemon writes its
+// console output
+func ConsoleLogPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "logs", "worker.out")
+}
+
+// WritePIDFile claims the PID file for this process. It fails while another
+// worker holds it; files left by workers that died are taken over.
+func WritePIDFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = file.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+			file.Close()
+			if err != nil {
+				os.Remove(path)
+				return fmt.Errorf("failed to write PID file: %w", err)
+			}
+			return nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("failed to create PID file: %w", err)
+		}
+
+		pid, err := ReadPIDFile(path)
+		if err != nil {
+			return err
+		}
+		if pid != 0 && ProcessAlive(pid) {
+			return fmt.Errorf("worker is already running (pid %d)", pid)
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale PID file: %w", err)
+		}
+	}
+}
+
+// ReadPIDFile returns the PID in a PID file, or 0 when there is none
+func ReadPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read PID file: %w", err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		// A worker that died while writing it; treat as stale
+		return 0, nil
+	}
+	return pid, nil
+}
+
+// RemovePIDFile removes the PID file if this process holds it
+func RemovePIDFile(path string) error {
+	pid, err := ReadPIDFile(path)
+	if err != nil || pid != os.Getpid() {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove PID file: %w", err)
+	}
+	return nil
+}
+
+// RunningPID returns the PID of the running worker, or 0 when none runs
+func RunningPID(cfg *config.Config) (int, error) {
+	pid, err := ReadPIDFile(PIDPath(cfg))
+	if err != nil || pid == 0 {
+		return 0, err
+	}
+	if !ProcessAlive(pid) {
+		return 0, nil
+	}
+	return pid, nil
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package worker
+
+import (
+	"errors"
+	"syscall"
+
+	"github.com/converso-empire/cli/pkg/config"
+)
+
+// ProcessAlive reports whether a process with the PID exists
+func ProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// RequestStop asks the worker process to stop gracefully with SIGTERM
+func RequestStop(cfg *config.Config, pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
+
+// DetachedProcAttr starts a process in a session of its own, so it keeps
+// running after the terminal that started it closes
+func DetachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
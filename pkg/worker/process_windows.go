@@ -0,0 +1,48 @@
+//go:build windows
+
+package worker
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/converso-empire/cli/pkg/config"
+)
+
+// Windows process constants
+const (
+	processQueryLimitedInformation = 0x1000
+	stillActive                    = 259
+	detachedProcess                = 0x00000008
+	createNewProcessGroup          = 0x00000200
+)
+
+// ProcessAlive reports whether a process with the PID is running
+func ProcessAlive(pid int) bool {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(handle, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// RequestStop asks the worker process to stop gracefully. Windows has no
+// SIGTERM for detached processes, so a stop request file is left for it.
+func RequestStop(cfg *config.Config, pid int) error {
+	return os.WriteFile(StopRequestPath(cfg), nil, 0644)
+}
+
+// DetachedProcAttr starts a process without a console, so it keeps running
+// after the one that started it closes
+func DetachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		CreationFlags: detachedProcess | createNewProcessGroup,
+		HideWindow:    true,
+	}
+}
@@ -0,0 +1,90 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+)
+
+// progressSaveInterval throttles writes of the progress file while jobs
+// report progress; status changes are written at once
+const progressSaveInterval = time.Second
+
+// JobProgress is the latest state of a job as the worker persists it, so
+// the job can be followed without the control API and from where it is
+// after the CLI restarts
+type JobProgress struct {
+	Status    string                `json:"status"`
+	Progress  *bridge.ProgressEvent `json:"progress,omitempty"`
+	Next      string                `json:"next,omitempty"`
+	Error     string                `json:"error,omitempty"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+// ProgressPath returns the location of the file a worker persists the
+// latest progress of its jobs to
+func ProgressPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "worker-progress.json")
+}
+
+// ReadProgress reads the progress persisted by a worker, by job ID. It
+// returns an empty map when no worker has run yet.
+func ReadProgress(path string) (map[string]*JobProgress, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]*JobProgress{}, nil
+		}
+		return nil, fmt.Errorf("failed to read job progress: %w", err)
+	}
+
+	var jobs map[string]*JobProgress
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse job progress: %w", err)
+	}
+	return jobs, nil
+}
+
+// ProgressOf returns the state of a job to persist
+func ProgressOf(job *Job) *JobProgress {
+	state := &JobProgress{Status: job.Status, Progress: job.Progress, Next: job.Next, UpdatedAt: time.Now()}
+	if job.Result != nil && !job.Result.Success {
+		state.Error = job.Result.Error
+	}
+	return state
+}
+
+// saveProgress persists the latest progress of the tracked jobs, unless it
+// was written less than progressSaveInterval ago and force is false. The
+// caller holds jobsMu.
+func (w *Worker) saveProgress(force bool) {
+	if !force && time.Since(w.progressSaved) < progressSaveInterval {
+		return
+	}
+	w.progressSaved = time.Now()
+
+	jobs := make(map[string]*JobProgress, len(w.jobs))
+	for id, job := range w.jobs {
+		jobs[id] = ProgressOf(job)
+	}
+	data, err := json.Marshal(jobs)
+	if err != nil {
+		w.logger.Warn("Failed to encode job progress", "error", err)
+		return
+	}
+
+	path := ProgressPath(w.config)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		w.logger.Warn("Failed to write job progress", "error", err)
+		return
+	}
+	if err := fileutil.WriteAtomic(path, data, 0644); err != nil {
+		w.logger.Warn("Failed to write job progress", "error", err)
+	}
+}
@@ -0,0 +1,293 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// maxQueuedJobs bounds the jobs waiting to run
+const maxQueuedJobs = 100
+
+// jobQueue holds the jobs waiting to run, in the order they run. Unlike a
+// channel, queued jobs can be listed, moved and removed. Every change is
+// written to a journal file, together with the jobs taken out to run until
+// they finish, so the queue survives restarts of the worker.
+type jobQueue struct {
+	mu    sync.Mutex
+	jobs  []*Job
+	ready chan struct{}
+	// running are copies of the jobs taken out of the queue that have not
+	// finished
+	running []*Job
+	// path is the journal file; empty keeps the queue in memory only
+	path   string
+	logger telemetry.Logger
+}
+
+// queueJournal is the content of the journal file
+type queueJournal struct {
+	Queued  []*Job `json:"queued"`
+	Running []*Job `json:"running"`
+}
+
+// QueuePath returns the location of the file a worker journals its job
+// queue to
+func QueuePath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "worker-queue.json")
+}
+
+// newJobQueue creates an empty queue journaled to path
+func newJobQueue(path string, logger telemetry.Logger) *jobQueue {
+	return &jobQueue{ready: make(chan struct{}, 1), path: path, logger: logger}
+}
+
+// Restore loads the journal left by the previous run and queues its jobs
+// again, ahead of any queued since: first the jobs that were running when
+// it ended, which start over, then the ones that were waiting. Jobs that
+// expired in the meantime are dropped. It returns the restored jobs.
+func (q *jobQueue) Restore() ([]*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(q.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read job queue: %w", err)
+	}
+
+	var journal queueJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, fmt.Errorf("failed to parse job queue: %w", err)
+	}
+
+	var restored []*Job
+	dropped := 0
+	for _, job := range append(journal.Running, journal.Queued...) {
+		if job == nil || q.index(job.ID) >= 0 {
+			continue
+		}
+		if !job.ExpiresAt.IsZero() && time.Now().After(job.ExpiresAt) {
+			q.logger.Info("Dropping expired job", "job_id", job.ID, "expired_at", job.ExpiresAt)
+			continue
+		}
+		// The journal may come from an older release or another tool
+		if len(restored)+len(q.jobs) >= maxQueuedJobs {
+			dropped++
+			continue
+		}
+		job.Status = string(JobStatusPending)
+		job.Progress = nil
+		job.Result = nil
+		restored = append(restored, job)
+	}
+	if dropped > 0 {
+		q.logger.Warn("Dropping jobs beyond the queue limit", "dropped", dropped, "limit", maxQueuedJobs)
+	}
+	q.jobs = append(restored, q.jobs...)
+	q.running = nil
+	q.save()
+	if len(q.jobs) > 0 {
+		q.signal()
+	}
+	return restored, nil
+}
+
+// Push adds a job at the end. It returns false when the queue is full.
+func (q *jobQueue) Push(job *Job) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.jobs) >= maxQueuedJobs {
+		return false
+	}
+	q.jobs = append(q.jobs, job)
+	q.save()
+	q.signal()
+	return true
+}
+
+// Pop removes and returns the first job, waiting for one until stop is
+// closed, when it returns nil. The job stays in the journal until Done is
+// called for it.
+func (q *jobQueue) Pop(stop <-chan struct{}) *Job {
+	for {
+		q.mu.Lock()
+		if len(q.jobs) > 0 {
+			job := q.jobs[0]
+			q.jobs = q.jobs[1:]
+			// A copy, as the job changes while it runs
+			copied := *job
+			q.running = append(q.running, &copied)
+			q.save()
+			if len(q.jobs) > 0 {
+				q.signal()
+			}
+			q.mu.Unlock()
+			return job
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.ready:
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// Done drops a job taken out with Pop from the journal once it has finished
+func (q *jobQueue) Done(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, job := range q.running {
+		if job.ID == id {
+			q.running = append(q.running[:i], q.running[i+1:]...)
+			q.save()
+			return
+		}
+	}
+}
+
+// Len returns the number of queued jobs
+func (q *jobQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+// Contains reports whether a job is queued
+func (q *jobQueue) Contains(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.index(id) >= 0
+}
+
+// Jobs returns the queued jobs in the order they run
+func (q *jobQueue) Jobs() []*Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*Job(nil), q.jobs...)
+}
+
+// MoveToTop moves a queued job to the front so it runs next
+func (q *jobQueue) MoveToTop(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := q.index(id)
+	if i < 0 {
+		return fmt.Errorf("job %s is not queued", id)
+	}
+	job := q.jobs[i]
+	copy(q.jobs[1:i+1], q.jobs[:i])
+	q.jobs[0] = job
+	q.save()
+	return nil
+}
+
+// Remove takes a job out of the queue and returns it
+func (q *jobQueue) Remove(id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := q.index(id)
+	if i < 0 {
+		return nil, fmt.Errorf("job %s is not queued", id)
+	}
+	job := q.jobs[i]
+	q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+	q.save()
+	return job, nil
+}
+
+// index returns the position of a job, or -1. q.mu must be held.
+func (q *jobQueue) index(id string) int {
+	for i, job := range q.jobs {
+		if job.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// save writes the journal, readable only by the user since jobs carry
+// URLs and arguments. q.mu must be held.
+func (q *jobQueue) save() {
+	if q.path == "" {
+		return
+	}
+	data, err := json.Marshal(queueJournal{Queued: q.jobs, Running: q.running})
+	if err != nil {
+		q.logger.Warn("Failed to encode job queue", "error", err)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(q.path), 0700); err != nil {
+		q.logger.Warn("Failed to write job queue", "error", err)
+		return
+	}
+	if err := fileutil.WriteAtomic(q.path, data, 0600); err != nil {
+		q.logger.Warn("Failed to write job queue", "error", err)
+	}
+}
+
+// signal wakes a waiting Pop. q.mu must be held.
+func (q *jobQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// QueuedJobs returns the jobs waiting to run, in the order they run
+func (w *Worker) QueuedJobs() []*Job {
+	queued := w.queue.Jobs()
+	jobs := make([]*Job, len(queued))
+	for i, job := range queued {
+		copied := *job
+		jobs[i] = &copied
+	}
+	return jobs
+}
+
+// MoveJobToTop makes a queued job run next
+func (w *Worker) MoveJobToTop(id string) error {
+	if err := w.queue.MoveToTop(id); err != nil {
+		return err
+	}
+	w.logger.Info("Job moved to the top of the queue", "job_id", id)
+	return nil
+}
+
+// RemoveQueuedJob drops a job that has not started, reporting it as
+// cancelled to the backend so it is not fetched again
+func (w *Worker) RemoveQueuedJob(id string) (*Job, error) {
+	job, err := w.queue.Remove(id)
+	if err != nil {
+		return nil, err
+	}
+	job.Status = string(JobStatusCancelled)
+	w.publishJobState(job)
+	w.logger.Info("Job removed from the queue", "job_id", id)
+
+	if err := w.reportJobStatus(job); err != nil {
+		w.logger.Warn("Failed to report removed job", "job_id", id, "error", err)
+	}
+	copied := *job
+	return &copied, nil
+}
+
+// removed reports whether a job was removed from the queue
+func (w *Worker) removed(id string) bool {
+	job := w.Job(id)
+	return job != nil && JobStatus(job.Status) == JobStatusCancelled
+}
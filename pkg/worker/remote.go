@@ -0,0 +1,213 @@
+package worker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/fips"
+	"github.com/converso-empire/cli/pkg/transfer"
+)
+
+// RemoteOptions configures the connection to a worker's control API
+type RemoteOptions struct {
+	// Token is the worker's control token
+	Token string
+	// CAFile verifies the worker's certificate, for self-signed ones
+	CAFile string
+	// Insecure connects without TLS, for loopback addresses and tunnels
+	Insecure bool
+}
+
+// Remote is a client of a worker's control API
+type Remote struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// ParseRemoteAddr returns the host:port of a remote worker given as
+// tcp://host:port or host:port
+func ParseRemoteAddr(remote string) (string, error) {
+	addr := remote
+	if i := strings.Index(remote, "://"); i >= 0 {
+		if scheme := remote[:i]; scheme != "tcp" {
+			return "", fmt.Errorf("unsupported remote scheme %q: use tcp://host:port", scheme)
+		}
+		addr = remote[i+3:]
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return "", fmt.Errorf("invalid remote %q: use tcp://host:port", remote)
+	}
+	return addr, nil
+}
+
+// NewRemote creates a client of the worker control API at remote
+func NewRemote(remote string, opts RemoteOptions) (*Remote, error) {
+	addr, err := ParseRemoteAddr(remote)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Token == "" {
+		return nil, fmt.Errorf("no control token for %s", addr)
+	}
+
+	scheme := "https"
+	transport := fips.Transport()
+	if opts.Insecure {
+		scheme = "http"
+	} else if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+		}
+
+		tlsConfig := fips.TLSConfig()
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		tlsConfig.RootCAs = roots
+		custom := http.DefaultTransport.(*http.Transport).Clone()
+		custom.TLSClientConfig = tlsConfig
+		transport = custom
+	}
+
+	return &Remote{
+		baseURL: scheme + "://" + addr,
+		token:   opts.Token,
+		client:  &http.Client{Timeout: 30 * time.Second, Transport: transport},
+	}, nil
+}
+
+// Status returns the status of the remote worker
+func (r *Remote) Status() (*Status, error) {
+	var status Status
+	if err := r.get(ControlStatusPath, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Jobs returns the jobs the remote worker has queued, run or finished
+func (r *Remote) Jobs() ([]*Job, error) {
+	var jobs []*Job
+	if err := r.get(ControlJobsPath, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Job returns a job of the remote worker
+func (r *Remote) Job(id string) (*Job, error) {
+	var job Job
+	if err := r.get(ControlJobsPath+"/"+url.PathEscape(id), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Queue returns the jobs waiting to run on the remote worker, in the
+// order they run
+func (r *Remote) Queue() ([]*Job, error) {
+	var jobs []*Job
+	if err := r.get(ControlQueuePath, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// MoveToTop makes a queued job run next and returns the new queue
+func (r *Remote) MoveToTop(id string) ([]*Job, error) {
+	var jobs []*Job
+	if err := r.do(http.MethodPost, ControlQueuePath+"/"+url.PathEscape(id)+controlTopSuffix, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// RemoveQueued drops a job that has not started and returns it
+func (r *Remote) RemoveQueued(id string) (*Job, error) {
+	var job Job
+	if err := r.do(http.MethodDelete, ControlQueuePath+"/"+url.PathEscape(id), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// PauseJob pauses a running job and returns it
+func (r *Remote) PauseJob(id string) (*Job, error) {
+	var job Job
+	if err := r.do(http.MethodPost, ControlJobsPath+"/"+url.PathEscape(id)+controlPauseSuffix, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ResumeJob resumes a paused job and returns it
+func (r *Remote) ResumeJob(id string) (*Job, error) {
+	var job Job
+	if err := r.do(http.MethodPost, ControlJobsPath+"/"+url.PathEscape(id)+controlResumeSuffix, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FetchFile downloads the file of a completed job to dest, resuming an
+// earlier interrupted fetch. The worker must have worker.sync set to
+// direct.
+func (r *Remote) FetchFile(id, dest string, progress transfer.Progress) error {
+	req, err := http.NewRequest(http.MethodGet, r.baseURL+ControlJobsPath+"/"+url.PathEscape(id)+controlFileSuffix, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+r.token)
+
+	// Files can take longer than the API timeout
+	client := *r.client
+	client.Timeout = 0
+	return transfer.Fetch(&client, req, dest, progress)
+}
+
+// get fetches a control API path and decodes the JSON response into out
+func (r *Remote) get(path string, out interface{}) error {
+	return r.do(http.MethodGet, path, out)
+}
+
+// do sends a control API request and decodes the JSON response into out
+func (r *Remote) do(method, path string, out interface{}) error {
+	req, err := http.NewRequest(method, r.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+r.token)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("worker unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &body) != nil || body.Error == "" {
+			body.Error = strings.TrimSpace(string(data))
+		}
+		return fmt.Errorf("worker returned HTTP %d: %s", resp.StatusCode, body.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// ScheduledTask is a task run periodically by the scheduler
+type ScheduledTask struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs tasks at fixed intervals
+type Scheduler struct {
+	logger telemetry.Logger
+	tasks  []*ScheduledTask
+}
+
+// NewScheduler creates a new scheduler
+func NewScheduler(logger telemetry.Logger) *Scheduler {
+	return &Scheduler{logger: logger}
+}
+
+// Add registers a task. Tasks run once when the scheduler starts and then
+// every Interval.
+func (s *Scheduler) Add(task *ScheduledTask) {
+	s.tasks = append(s.tasks, task)
+}
+
+// Run runs the registered tasks until ctx is cancelled. A failing task is
+// logged and retried at its next interval.
+func (s *Scheduler) Run(ctx context.Context) error {
+	done := make(chan struct{})
+	for _, task := range s.tasks {
+		go func(task *ScheduledTask) {
+			defer func() { done <- struct{}{} }()
+			s.runTask(ctx, task)
+		}(task)
+	}
+
+	for range s.tasks {
+		<-done
+	}
+	return ctx.Err()
+}
+
+// runTask runs a single task on its interval
+func (s *Scheduler) runTask(ctx context.Context, task *ScheduledTask) {
+	ticker := time.NewTicker(task.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.logger.Info("Running scheduled task", "task", task.Name)
+		if err := task.Run(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("Scheduled task failed", "task", task.Name, "error", err)
+		}
+		s.logger.Info("Next scheduled run", "task", task.Name, "at", time.Now().Add(task.Interval).Format(time.RFC3339))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/fileutil"
+	"github.com/converso-empire/cli/pkg/telemetry"
+)
+
+// Status is the state a running worker publishes for 'converso worker status'
+type Status struct {
+	PID         int        `json:"pid"`
+	RunID       string     `json:"run_id,omitempty"`
+	Running     bool       `json:"running"`
+	StartedAt   time.Time  `json:"started_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	QueueSize   int        `json:"queue_size"`
+	Paused      bool       `json:"paused"`
+	PauseReason string     `json:"pause_reason,omitempty"`
+	Power       PowerState `json:"power"`
+}
+
+// StatusPath returns the location of the worker status file
+func StatusPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir, "worker-status.json")
+}
+
+// ReadStatus reads the status published by a worker. It returns nil when no
+// worker has run yet.
+func ReadStatus(path string) (*Status, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read worker status: %w", err)
+	}
+
+	var status Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse worker status: %w", err)
+	}
+	return &status, nil
+}
+
+// Stale reports whether the status has not been refreshed for several check
+// intervals, meaning the worker exited without cleaning up
+func (s *Status) Stale(interval time.Duration) bool {
+	if interval <= 0 {
+		interval = config.DefaultPowerCheckInterval
+	}
+	return time.Since(s.UpdatedAt) > 3*interval
+}
+
+// Status returns the current worker status
+func (w *Worker) Status() *Status {
+	return w.snapshot(w.IsRunning())
+}
+
+// snapshot builds the worker status. It does not take w.mu, which Stop holds
+// while waiting for the goroutines that publish the status.
+func (w *Worker) snapshot(running bool) *Status {
+	w.powerMu.RLock()
+	defer w.powerMu.RUnlock()
+
+	return &Status{
+		PID:         os.Getpid(),
+		RunID:       telemetry.RunID(),
+		Running:     running,
+		StartedAt:   w.startedAt,
+		UpdatedAt:   time.Now(),
+		QueueSize:   w.queue.Len(),
+		Paused:      w.pauseReason != "",
+		PauseReason: w.pauseReason,
+		Power:       w.power,
+	}
+}
+
+// writeStatus publishes the worker status to the status file
+func (w *Worker) writeStatus(status *Status) {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		w.logger.Warn("Failed to encode worker status", "error", err)
+		return
+	}
+
+	path := StatusPath(w.config)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		w.logger.Warn("Failed to write worker status", "error", err)
+		return
+	}
+	if err := fileutil.WriteAtomic(path, data, 0644); err != nil {
+		w.logger.Warn("Failed to write worker status", "error", err)
+	}
+}
@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/httpclient"
+	"github.com/converso-empire/cli/pkg/transfer"
+)
+
+// JobFile returns the file a completed job downloaded, or "" when it has
+// none
+func JobFile(job *Job) string {
+	if job.Result == nil || !job.Result.Success || JobStatus(job.Status) != JobStatusCompleted {
+		return ""
+	}
+	return bridge.DecodeDownloadResult(job.Result.Data).FilePath
+}
+
+// syncJobFile makes the file of a completed job available to other devices
+// when worker.sync uploads to the backend
+func (w *Worker) syncJobFile(job *Job) {
+	if w.config.Worker.Sync != config.SyncBackend {
+		return
+	}
+	path := JobFile(job)
+	if path == "" {
+		return
+	}
+	if err := w.uploadJobFile(job, path); err != nil {
+		w.logger.Error("Failed to upload job file", "job_id", job.ID, "file", path, "error", err)
+		return
+	}
+	w.logger.Info("Job file uploaded", "job_id", job.ID, "file", path)
+}
+
+// uploadJobFile uploads a job's file to the backend with its checksum
+func (w *Worker) uploadJobFile(job *Job, path string) error {
+	tokens, err := w.tokens.Tokens(w.ctx)
+	if err != nil {
+		return fmt.Errorf("authentication required: %w", err)
+	}
+
+	checksum, err := transfer.FileSHA256(path)
+	if err != nil {
+		return fmt.Errorf("failed to checksum file: %w", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(httpclient.UploadJobFile.Method, httpclient.UploadJobFile.URL(w.config, job.ID), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(path)}))
+	req.Header.Set(transfer.ChecksumHeader, checksum)
+
+	// Uploads can take longer than the API timeout
+	resp, err := httpclient.New(w.config, w.logger, 0).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/converso-empire/cli/pkg/bridge"
+	"github.com/converso-empire/cli/pkg/hwaccel"
+)
+
+// transcodeSuffix follows a download's job ID to name its transcode job
+const transcodeSuffix = "-transcode"
+
+// chainTranscode queues the conversion of a finished download when a
+// transcode rule matches it. The conversion runs next as a job chained to
+// the download, whose progress then covers both.
+func (w *Worker) chainTranscode(job *Job) {
+	if job.Command != "download" || job.ParentID != "" {
+		return
+	}
+	path := JobFile(job)
+	if path == "" {
+		return
+	}
+
+	fields := bridge.Fields(job.Result.Data)
+	codec := fields.String("vcodec")
+	if codec == "" {
+		codec = fields.String("codec")
+	}
+	device := job.Device
+	if device == "" {
+		device = w.deviceName
+	}
+	rule := w.config.Transcode.Match(codec, filepath.Ext(path), device, job.Module)
+	if rule == nil {
+		return
+	}
+	options, err := rule.Options(w.config.Presets)
+	if err != nil {
+		w.logger.Error("Invalid transcode rule", "job_id", job.ID, "error", err)
+		return
+	}
+
+	args := options.Args()
+	args["input"] = path
+	args["output_dir"] = filepath.Dir(path)
+	if w.config.HWAccel {
+		caps, err := hwaccel.Load(hwaccel.DefaultPath(w.config))
+		if err != nil {
+			w.logger.Warn("Transcoding with software encoders", "job_id", job.ID, "error", err)
+		} else if encoders := caps.Args(); encoders != nil {
+			args[hwaccel.ArgEncoders] = encoders
+		}
+	}
+	next := &Job{
+		ID:        job.ID + transcodeSuffix,
+		Type:      "transcode",
+		Module:    "convert",
+		Command:   "convert",
+		Args:      args,
+		CreatedAt: time.Now(),
+		Priority:  job.Priority,
+		Status:    string(JobStatusPending),
+		Device:    job.Device,
+		ParentID:  job.ID,
+	}
+	if !w.queue.Push(next) {
+		w.logger.Warn("Job queue full, skipping transcode", "job_id", job.ID)
+		return
+	}
+	w.queue.MoveToTop(next.ID)
+	w.trackJob(next)
+
+	job.Next = next.ID
+	job.Progress = chainProgress(1, &bridge.ProgressEvent{
+		Stage:      "queued",
+		Current:    100,
+		Total:      100,
+		Percentage: 100,
+		Message:    fmt.Sprintf("Downloaded, transcoding with job %s next", next.ID),
+		Timestamp:  time.Now(),
+	})
+	w.logger.Info("Transcode queued", "job_id", job.ID, "transcode_job_id", next.ID, "codec", codec, "options", args)
+}
+
+// reportChainProgress shows the progress of a chained job on the job it
+// follows, as progress of the whole chain
+func (w *Worker) reportChainProgress(job *Job, progress *bridge.ProgressEvent) {
+	if job.ParentID == "" {
+		return
+	}
+	parent := w.Job(job.ParentID)
+	if parent == nil {
+		return
+	}
+	parent.Progress = chainProgress(2, progress)
+	w.trackJob(parent)
+	if err := w.reportJobProgress(parent); err != nil {
+		w.logger.Debug("Failed to report chain progress", "job_id", parent.ID, "error", err)
+	}
+}
+
+// finishChain reports the outcome of a chained job on the job it follows
+func (w *Worker) finishChain(job *Job) {
+	if job.ParentID == "" {
+		return
+	}
+	progress := &bridge.ProgressEvent{Stage: "completed", Current: 100, Total: 100, Percentage: 100, Timestamp: time.Now()}
+	if JobStatus(job.Status) == JobStatusCompleted {
+		progress.Message = "Transcoded"
+		if path := bridge.Fields(job.Result.Data).String("file_path"); path != "" {
+			progress.Message = "Transcoded to " + path
+		}
+	} else {
+		progress.Stage = "failed"
+		progress.Message = "Transcode failed: " + job.Result.Error
+	}
+	w.reportChainProgress(job, progress)
+}
+
+// chainProgress returns the progress of one step of a download and its
+// transcode as progress of both: Overall covers the chain, while
+// Percentage stays the step's own
+func chainProgress(step int, progress *bridge.ProgressEvent) *bridge.ProgressEvent {
+	combined := *progress
+	overall := progress.Overall
+	if overall == 0 {
+		overall = progress.Percentage
+	}
+	combined.Overall = (float64(step-1)*100 + overall) / 2
+	combined.StageIndex, combined.StageCount = step, 2
+	return &combined
+}
@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"sync"
 	"time"
 
 	"github.com/converso-empire/cli/pkg/auth"
 	"github.com/converso-empire/cli/pkg/bridge"
 	"github.com/converso-empire/cli/pkg/config"
+	"github.com/converso-empire/cli/pkg/events"
+	"github.com/converso-empire/cli/pkg/httpclient"
+	"github.com/converso-empire/cli/pkg/stats"
 	"github.com/converso-empire/cli/pkg/telemetry"
 )
 
@@ -19,12 +23,41 @@ type Worker struct {
 	config     *config.Config
 	logger     telemetry.Logger
 	httpClient *http.Client
-	authTokens *auth.AuthTokens
-	jobQueue   chan *Job
+	// tokens refreshes the credentials for the backend as they expire
+	tokens *auth.TokenProvider
+	// deviceName is the registered name of this device, which jobs are
+	// routed to
+	deviceName string
+	queue      *jobQueue
 	running    bool
 	mu         sync.RWMutex
 	wg         sync.WaitGroup
 	stopCh     chan struct{}
+	startedAt  time.Time
+	// ctx ends when the worker stops, cutting short the token refreshes
+	// it is waiting on
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Power and network state; heavy jobs wait while pauseReason is set and
+	// resumeCh is closed when they may run again
+	powerMu     sync.RWMutex
+	power       PowerState
+	pauseReason string
+	resumeCh    chan struct{}
+
+	// Checks reported by the readiness endpoint
+	readinessChecks []ReadinessCheck
+
+	// Jobs seen by this worker, for the control API, in the order they
+	// were queued
+	jobsMu    sync.RWMutex
+	jobs      map[string]*Job
+	jobsOrder []string
+	// controls pause and resume the running jobs
+	controls map[string]*jobControl
+	// progressSaved is when the progress file was last written
+	progressSaved time.Time
 }
 
 // Job represents a background job
@@ -40,6 +73,17 @@ type Job struct {
 	Status      string                 `json:"status"`
 	Progress    *bridge.ProgressEvent  `json:"progress,omitempty"`
 	Result      *bridge.ModuleResponse `json:"result,omitempty"`
+	// RunID is the worker run that processed the job
+	RunID string `json:"run_id,omitempty"`
+	// Device is the registered device the job is routed to. Jobs without
+	// one go to the first worker that asks; the backend then sets it.
+	Device string `json:"device,omitempty"`
+	// ParentID is the job a chained job follows, such as the download a
+	// transcode converts, and Next the job chained after this one. Chained
+	// jobs are created by the worker and reported to the backend through
+	// the job they follow.
+	ParentID string `json:"parent_id,omitempty"`
+	Next     string `json:"next,omitempty"`
 }
 
 // JobStatus represents job status
@@ -48,6 +92,7 @@ type JobStatus string
 const (
 	JobStatusPending   JobStatus = "pending"
 	JobStatusRunning   JobStatus = "running"
+	JobStatusPaused    JobStatus = "paused"
 	JobStatusCompleted JobStatus = "completed"
 	JobStatusFailed    JobStatus = "failed"
 	JobStatusCancelled JobStatus = "cancelled"
@@ -55,13 +100,20 @@ const (
 
 // NewWorker creates a new background worker
 func NewWorker(cfg *config.Config, logger telemetry.Logger) *Worker {
-	return &Worker{
+	w := &Worker{
 		config:     cfg,
 		logger:     logger,
-		httpClient: &http.Client{Timeout: 30 * time.Second},
-		jobQueue:   make(chan *Job, 100),
+		httpClient: httpclient.New(cfg, logger, 30*time.Second),
+		tokens:     auth.NewTokenProvider(cfg, logger),
+		queue:      newJobQueue(QueuePath(cfg), logger),
 		stopCh:     make(chan struct{}),
+		jobs:       make(map[string]*Job),
+		controls:   make(map[string]*jobControl),
 	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	w.AddReadinessCheck("auth", w.checkAuth)
+	w.AddReadinessCheck("backend", w.checkBackend)
+	return w
 }
 
 // Start starts the background worker
@@ -72,13 +124,31 @@ func (w *Worker) Start() error {
 	if w.running {
 		return fmt.Errorf("worker is already running")
 	}
+	if err := w.config.Transcode.Validate(w.config.Presets); err != nil {
+		return fmt.Errorf("invalid transcode rules: %w", err)
+	}
 
-	// Load authentication tokens
-	tokens, err := w.loadAuthTokens()
-	if err != nil {
+	// Check the authentication tokens, which are refreshed as they expire
+	if _, err := w.tokens.Tokens(w.ctx); err != nil {
 		return fmt.Errorf("failed to load authentication tokens: %w", err)
 	}
-	w.authTokens = tokens
+	w.deviceName = w.loadDeviceName()
+
+	// Queue the jobs left by the previous run again
+	restored, err := w.queue.Restore()
+	if err != nil {
+		w.logger.Warn("Failed to restore the job queue", "error", err)
+	}
+	for _, job := range restored {
+		w.trackJob(job)
+	}
+	if len(restored) > 0 {
+		w.logger.Info("Resuming queued jobs", "count", len(restored))
+	}
+
+	// Check power state before taking jobs so pauses apply from the start
+	w.startedAt = time.Now()
+	w.checkPower()
 
 	w.running = true
 	w.wg.Add(3)
@@ -89,8 +159,16 @@ func (w *Worker) Start() error {
 	// Start job processing goroutine
 	go w.processJobs()
 
-	// Start status reporting goroutine
-	go w.reportStatus()
+	// Start status reporting goroutine unless policy disables telemetry
+	if w.config.Policy.TelemetryDisabled() {
+		w.logger.Info("Worker status reports disabled by policy")
+	} else {
+		w.wg.Add(1)
+		go w.reportStatus()
+	}
+
+	// Start power and network monitoring goroutine
+	go w.monitorPower()
 
 	w.logger.Info("Background worker started")
 	return nil
@@ -107,7 +185,12 @@ func (w *Worker) Stop() error {
 
 	w.running = false
 	close(w.stopCh)
+	w.cancel()
 	w.wg.Wait()
+	w.writeStatus(w.snapshot(false))
+	w.jobsMu.Lock()
+	w.saveProgress(true)
+	w.jobsMu.Unlock()
 
 	w.logger.Info("Background worker stopped")
 	return nil
@@ -134,17 +217,19 @@ func (w *Worker) pollJobs() {
 
 // fetchJobs fetches jobs from the backend API
 func (w *Worker) fetchJobs() error {
-	if w.authTokens == nil || w.authTokens.IsExpired() {
-		return fmt.Errorf("authentication required")
+	tokens, err := w.tokens.Tokens(w.ctx)
+	if err != nil {
+		return fmt.Errorf("authentication required: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/api/v1/jobs/pending", w.config.APIEndpoint)
-	req, err := http.NewRequest("GET", url, nil)
+	// Ask only for jobs routed to this device or to any device
+	pendingURL := httpclient.PendingJobs.URL(w.config) + "?device=" + url.QueryEscape(w.deviceName)
+	req, err := http.NewRequest(httpclient.PendingJobs.Method, pendingURL, nil)
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+w.authTokens.AccessToken)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := w.httpClient.Do(req)
@@ -162,16 +247,19 @@ func (w *Worker) fetchJobs() error {
 		return err
 	}
 
-	// Add jobs to queue
+	// Add jobs to queue. The backend lists a job until the worker reports
+	// on it, so jobs already queued or removed are skipped.
 	for _, job := range jobs {
-		select {
-		case w.jobQueue <- &job:
-			w.logger.Info("Job added to queue", "job_id", job.ID, "module", job.Module)
-		case <-w.stopCh:
-			return nil
-		default:
+		job := job
+		if w.queue.Contains(job.ID) || w.removed(job.ID) {
+			continue
+		}
+		if !w.queue.Push(&job) {
 			w.logger.Warn("Job queue full, skipping job", "job_id", job.ID)
+			continue
 		}
+		w.trackJob(&job)
+		w.logger.Info("Job added to queue", "job_id", job.ID, "module", job.Module)
 	}
 
 	return nil
@@ -182,12 +270,17 @@ func (w *Worker) processJobs() {
 	defer w.wg.Done()
 
 	for {
-		select {
-		case job := <-w.jobQueue:
-			w.processJob(job)
-		case <-w.stopCh:
+		job := w.queue.Pop(w.stopCh)
+		if job == nil {
 			return
 		}
+		// A job taken out but not run stays in the journal, so the next
+		// run resumes it
+		if w.isHeavy(job) && !w.waitForResume(job) {
+			return
+		}
+		w.processJob(job)
+		w.queue.Done(job.ID)
 	}
 }
 
@@ -197,6 +290,7 @@ func (w *Worker) processJob(job *Job) {
 
 	// Update job status
 	job.Status = string(JobStatusRunning)
+	job.RunID = telemetry.RunID()
 	job.Progress = &bridge.ProgressEvent{
 		Stage:      "starting",
 		Current:    0,
@@ -205,6 +299,7 @@ func (w *Worker) processJob(job *Job) {
 		Message:    "Starting job...",
 		Timestamp:  time.Now(),
 	}
+	w.publishJobState(job)
 
 	// Report job start
 	if err := w.reportJobStatus(job); err != nil {
@@ -217,13 +312,17 @@ func (w *Worker) processJob(job *Job) {
 	go func() {
 		for progress := range progressChan {
 			job.Progress = progress
+			w.trackJob(job)
 			w.reportJobProgress(job)
+			w.reportChainProgress(job, progress)
 		}
 	}()
 
 	// Here you would integrate with the plugin system
 	// For now, simulate job execution
-	result, err := w.executeJob(job, progressChan)
+	controls := w.startControl(job.ID)
+	result, err := w.executeJob(job, progressChan, controls)
+	w.endControl(job.ID)
 	close(progressChan)
 
 	if err != nil {
@@ -238,7 +337,11 @@ func (w *Worker) processJob(job *Job) {
 		job.Status = string(JobStatusCompleted)
 		job.Result = result
 		w.logger.Info("Job completed", "job_id", job.ID)
+		w.syncJobFile(job)
+		w.chainTranscode(job)
 	}
+	w.finishChain(job)
+	w.publishJobState(job)
 
 	// Report final status
 	if err := w.reportJobStatus(job); err != nil {
@@ -246,8 +349,98 @@ func (w *Worker) processJob(job *Job) {
 	}
 }
 
-// executeJob executes a job (placeholder implementation)
-func (w *Worker) executeJob(job *Job, progressChan chan<- *bridge.ProgressEvent) (*bridge.ModuleResponse, error) {
+// publishJobState publishes the current status of a job
+func (w *Worker) publishJobState(job *Job) {
+	w.trackJob(job)
+
+	state := events.Job{ID: job.ID, Module: job.Module, Command: job.Command, Status: job.Status}
+	if job.Result != nil && !job.Result.Success {
+		state.Error = job.Result.Error
+	}
+	events.Publish(events.JobStateChanged, state)
+}
+
+// isHeavy reports whether a job is paused by power and network conditions
+func (w *Worker) isHeavy(job *Job) bool {
+	for _, command := range w.config.Worker.HeavyCommands {
+		if command == job.Command {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForResume blocks while heavy jobs are paused. It returns false if the
+// worker is stopped while waiting.
+func (w *Worker) waitForResume(job *Job) bool {
+	logged := false
+	for {
+		w.powerMu.RLock()
+		reason, resume := w.pauseReason, w.resumeCh
+		w.powerMu.RUnlock()
+
+		if reason == "" {
+			return true
+		}
+		if !logged {
+			w.logger.Info("Job waiting for power or network conditions", "job_id", job.ID, "reason", reason)
+			logged = true
+		}
+
+		select {
+		case <-resume:
+		case <-w.stopCh:
+			return false
+		}
+	}
+}
+
+// monitorPower periodically re-checks the power and network state
+func (w *Worker) monitorPower() {
+	defer w.wg.Done()
+
+	interval := w.config.Worker.CheckInterval
+	if interval <= 0 {
+		interval = config.DefaultPowerCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.checkPower()
+		case <-w.stopCh:
+			return
+		}
+	}
+}
+
+// checkPower detects the power and network state, pausing or resuming heavy
+// jobs as needed, and publishes the worker status
+func (w *Worker) checkPower() {
+	state := DetectPowerState()
+	reason := PauseReason(w.config.Worker, state)
+
+	w.powerMu.Lock()
+	previous := w.pauseReason
+	w.power, w.pauseReason = state, reason
+	switch {
+	case reason != "" && previous == "":
+		w.resumeCh = make(chan struct{})
+		w.logger.Warn("Pausing heavy jobs", "reason", reason)
+	case reason == "" && previous != "":
+		close(w.resumeCh)
+		w.logger.Info("Resuming heavy jobs", "was", previous)
+	}
+	w.powerMu.Unlock()
+
+	w.writeStatus(w.snapshot(true))
+}
+
+// executeJob executes a job (placeholder implementation). Pauses take
+// effect between stages.
+func (w *Worker) executeJob(job *Job, progressChan chan<- *bridge.ProgressEvent, controls <-chan *bridge.Control) (*bridge.ModuleResponse, error) {
 	// Simulate job execution with progress
 	stages := []struct {
 		stage     string
@@ -262,12 +455,17 @@ func (w *Worker) executeJob(job *Job, progressChan chan<- *bridge.ProgressEvent)
 	}
 
 	for _, stage := range stages {
+		if !w.honorControls(controls) {
+			return nil, fmt.Errorf("worker stopped while the job was paused")
+		}
+
 		// Send progress update
 		progressChan <- &bridge.ProgressEvent{
 			Stage:      stage.stage,
 			Current:    int64(stage.progress),
 			Total:      100,
 			Percentage: float64(stage.progress),
+			Overall:    float64(stage.progress),
 			Message:    stage.message,
 			Timestamp:  time.Now(),
 		}
@@ -313,14 +511,26 @@ func (w *Worker) reportStatus() {
 
 // reportWorkerStatus reports worker status to backend
 func (w *Worker) reportWorkerStatus() error {
-	if w.authTokens == nil || w.authTokens.IsExpired() {
-		return fmt.Errorf("authentication required")
+	tokens, err := w.tokens.Tokens(w.ctx)
+	if err != nil {
+		return fmt.Errorf("authentication required: %w", err)
 	}
 
+	current := w.snapshot(true)
 	status := map[string]interface{}{
-		"status":     "running",
-		"queue_size": len(w.jobQueue),
-		"timestamp":  time.Now().Format(time.RFC3339),
+		"status":       "running",
+		"queue_size":   current.QueueSize,
+		"paused":       current.Paused,
+		"pause_reason": current.PauseReason,
+		"power":        current.Power,
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}
+
+	// Include per-module runtime statistics
+	if modules, err := stats.NewStore(stats.DefaultPath(w.config)).Summaries(); err != nil {
+		w.logger.Warn("Failed to read module stats", "error", err)
+	} else {
+		status["modules"] = modules
 	}
 
 	data, err := json.Marshal(status)
@@ -328,13 +538,12 @@ func (w *Worker) reportWorkerStatus() error {
 		return err
 	}
 
-	url := fmt.Sprintf("%s/api/v1/worker/status", w.config.APIEndpoint)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	req, err := http.NewRequest(httpclient.WorkerStatus.Method, httpclient.WorkerStatus.URL(w.config), bytes.NewReader(data))
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+w.authTokens.AccessToken)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := w.httpClient.Do(req)
@@ -352,22 +561,25 @@ func (w *Worker) reportWorkerStatus() error {
 
 // reportJobStatus reports job status to backend
 func (w *Worker) reportJobStatus(job *Job) error {
-	if w.authTokens == nil || w.authTokens.IsExpired() {
-		return fmt.Errorf("authentication required")
+	if job.ParentID != "" {
+		return nil
+	}
+	tokens, err := w.tokens.Tokens(w.ctx)
+	if err != nil {
+		return fmt.Errorf("authentication required: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/api/v1/jobs/%s/status", w.config.APIEndpoint, job.ID)
 	data, err := json.Marshal(job)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("PUT", url, bytes.NewReader(data))
+	req, err := http.NewRequest(httpclient.JobStatus.Method, httpclient.JobStatus.URL(w.config, job.ID), bytes.NewReader(data))
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+w.authTokens.AccessToken)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := w.httpClient.Do(req)
@@ -385,22 +597,25 @@ func (w *Worker) reportJobStatus(job *Job) error {
 
 // reportJobProgress reports job progress to backend
 func (w *Worker) reportJobProgress(job *Job) error {
-	if w.authTokens == nil || w.authTokens.IsExpired() {
-		return fmt.Errorf("authentication required")
+	if job.ParentID != "" {
+		return nil
+	}
+	tokens, err := w.tokens.Tokens(w.ctx)
+	if err != nil {
+		return fmt.Errorf("authentication required: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/api/v1/jobs/%s/progress", w.config.APIEndpoint, job.ID)
 	data, err := json.Marshal(job.Progress)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	req, err := http.NewRequest(httpclient.JobProgress.Method, httpclient.JobProgress.URL(w.config, job.ID), bytes.NewReader(data))
 	if err != nil {
 		return err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+w.authTokens.AccessToken)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := w.httpClient.Do(req)
@@ -416,14 +631,22 @@ func (w *Worker) reportJobProgress(job *Job) error {
 	return nil
 }
 
-// loadAuthTokens loads authentication tokens from storage
-func (w *Worker) loadAuthTokens() (*auth.AuthTokens, error) {
-	// This would integrate with the auth storage system
-	// For now, return a placeholder
-	return &auth.AuthTokens{
-		AccessToken: "placeholder",
-		ExpiresAt:   time.Now().Add(1 * time.Hour),
-	}, nil
+// loadAuthTokens loads authentication tokens from storage, or from the
+// environment in headless mode, refreshing them when they are about to
+// expire
+func (w *Worker) loadAuthTokens(ctx context.Context) (*auth.AuthTokens, error) {
+	authManager := auth.NewAuthManager(auth.NewStorage(w.config, w.logger), w.logger)
+	return authManager.ValidTokens(ctx, auth.NewOAuth2Client(w.config, w.logger).RefreshTokens)
+}
+
+// loadDeviceName returns the name this device was registered with at
+// login, or the hostname like login's default
+func (w *Worker) loadDeviceName() string {
+	device, err := auth.NewStorage(w.config, w.logger).RetrieveDevice()
+	if err != nil || device.Name == "" {
+		return auth.GetDeviceName()
+	}
+	return device.Name
 }
 
 // IsRunning returns whether the worker is running
@@ -437,5 +660,5 @@ func (w *Worker) IsRunning() bool {
 func (w *Worker) GetQueueSize() int {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
-	return len(w.jobQueue)
+	return w.queue.Len()
 }
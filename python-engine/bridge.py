@@ -6,22 +6,63 @@ This module provides the bridge between Go CLI and Python modules.
 It handles JSON IPC communication and progress event streaming.
 """
 
+import base64
+import gzip
 import json
+import logging
 import sys
 import os
+import queue
 import time
 import signal
-from typing import Dict, Any, Optional, Callable, Generator
-from dataclasses import dataclass, asdict
+import tempfile
+import threading
+from contextlib import contextmanager
+from datetime import datetime, timezone
+from typing import Dict, Any, List, Optional, Callable, Generator, Tuple, Union
+from dataclasses import dataclass, asdict, field
 from enum import Enum
 
 
+# Bridge protocol version and frame encodings understood by this bridge.
+# Version 2 keeps reading stdin after the request for answers to prompts,
+# version 3 also for control messages such as pause and cancel.
+PROTOCOL_VERSION = 3
+SUPPORTED_ENCODINGS = ["gzip"]
+
+# Set by the CLI's process pool to keep the module serving requests until
+# stdin closes
+PERSISTENT_ENV = "CONVERSO_PERSISTENT"
+
+
 class MessageType(Enum):
     """Message types for IPC communication"""
     REQUEST = "request"
     RESPONSE = "response"
     PROGRESS = "progress"
     ERROR = "error"
+    HELLO = "hello"
+    KEEPALIVE = "keepalive"
+    STAGES = "stages"
+    PROMPT = "prompt"
+    ANSWER = "answer"
+    PING = "ping"
+    PONG = "pong"
+    CONTROL = "control"
+    LOG = "log"
+
+
+# Log levels of log frames, from the most to the least verbose
+LOG_LEVELS = ["debug", "info", "warn", "error"]
+
+
+class PromptError(Exception):
+    """Raised when a prompt gets no answer: the CLI runs non-interactively,
+    the user did not answer in time, or input was closed"""
+
+
+class CommandCancelled(Exception):
+    """Raised at a checkpoint once the user cancelled the command"""
 
 
 @dataclass
@@ -32,6 +73,11 @@ class ModuleRequest:
     auth_token: str
     device_token: str
     timeout: int
+    compression: Optional[Dict[str, Any]] = None
+    secrets: Dict[str, str] = field(default_factory=dict)
+    run_id: str = ""
+    cookies: List[Dict[str, Any]] = field(default_factory=list)
+    log_level: str = ""
 
 
 @dataclass
@@ -41,6 +87,17 @@ class ModuleResponse:
     data: Dict[str, Any]
     error: Optional[str] = None
     progress: Optional[Dict[str, Any]] = None
+    items: Optional[List[Dict[str, Any]]] = None
+    cookies: Optional[List[Dict[str, Any]]] = None
+
+
+@dataclass
+class ItemResult:
+    """Outcome of a single item in a batch operation"""
+    id: str
+    success: bool
+    data: Dict[str, Any] = field(default_factory=dict)
+    error: Optional[str] = None
 
 
 @dataclass
@@ -51,7 +108,8 @@ class ProgressEvent:
     total: int
     percentage: float
     message: str
-    timestamp: float
+    timestamp: str
+    item: Optional[str] = None
 
 
 class IPCBridge:
@@ -60,23 +118,56 @@ class IPCBridge:
     def __init__(self):
         self.auth_token = None
         self.device_token = None
+        self.secrets = {}  # Credentials set with `converso secrets set`
         self.timeout = 300  # Default 5 minutes
+        self.compression = None  # Negotiated response compression
+        self.run_id = ""  # CLI run the request belongs to
+        self.cookies = []  # The module's cookie jar kept by the CLI
+        self.cookies_changed = False
+        # Lowest level of log frames the CLI wants; empty when the CLI
+        # predates log frames, which then go to stderr
+        self.log_level = ""
+        self._prompts = 0  # Numbers prompts without an explicit ID
+        self.persistent = os.environ.get(PERSISTENT_ENV) == "1"
+        self._write_lock = threading.Lock()
+        # Controls of the running command, applied by the stdin reader
+        self.rate_limit = 0  # Bytes per second, 0 for no limit
+        self.on_control = None  # Called with each control's action and frame
+        self._resumed = threading.Event()
+        self._resumed.set()
+        self._cancelled = threading.Event()
+        self._lines = queue.Queue()
+        self._reader = None
+    
+    def send_hello(self):
+        """Announce protocol version and supported frame encodings, and
+        confirm serving requests persistently when asked to"""
+        hello = {
+            "type": MessageType.HELLO.value,
+            "protocol": PROTOCOL_VERSION,
+            "encodings": SUPPORTED_ENCODINGS
+        }
+        if self.persistent:
+            hello["persistent"] = True
+        self._write_frame(hello)
+    
+    def _write_frame(self, message: Dict[str, Any]):
+        """Write a JSON frame to stdout, compressing it when negotiated"""
+        output = json.dumps(message)
+        if self.compression and len(output) > self.compression.get("threshold", 0):
+            payload = base64.b64encode(gzip.compress(output.encode("utf-8"))).decode("ascii")
+            output = json.dumps({"encoding": "gzip", "payload": payload})
+        with self._write_lock:
+            sys.stdout.write(output + '\n')
+            sys.stdout.flush()
         
     def read_request(self) -> ModuleRequest:
         """Read request from stdin"""
         try:
-            line = sys.stdin.readline().strip()
+            line = self._read_line()
             if not line:
                 raise EOFError("No input received")
-                
-            data = json.loads(line)
-            return ModuleRequest(
-                command=data.get('command', ''),
-                args=data.get('args', {}),
-                auth_token=data.get('auth_token', ''),
-                device_token=data.get('device_token', ''),
-                timeout=data.get('timeout', 300)
-            )
+            return self._parse_request(json.loads(line))
         except json.JSONDecodeError as e:
             self.send_error(f"Failed to parse JSON request: {e}")
             sys.exit(1)
@@ -84,34 +175,335 @@ class IPCBridge:
             self.send_error(f"Failed to read request: {e}")
             sys.exit(1)
     
+    def next_request(self) -> Optional[ModuleRequest]:
+        """Wait for the next request of a persistent process, answering the
+        CLI's health checks meanwhile. Returns None once stdin closes."""
+        while True:
+            line = self._read_line()
+            if not line:
+                return None
+            try:
+                data = json.loads(line)
+                if data.get("type") == MessageType.PING.value:
+                    self._write_frame({"type": MessageType.PONG.value})
+                    continue
+                return self._parse_request(data)
+            except Exception as e:
+                self.send_error(f"Failed to read request: {e}")
+    
+    def _read_line(self) -> Optional[str]:
+        """Return the next line from the CLI other than a control message,
+        or None once stdin closed"""
+        if self._reader is None:
+            self._reader = threading.Thread(target=self._read_stdin, daemon=True)
+            self._reader.start()
+        line = self._lines.get()
+        if line is None:
+            # Later reads see the end of input too
+            self._lines.put(None)
+        return line
+    
+    def _read_stdin(self):
+        """Read stdin in the background, applying control messages as they
+        arrive so they reach the command while it runs"""
+        for line in iter(sys.stdin.readline, ""):
+            if not line.strip():
+                continue
+            try:
+                data = json.loads(line)
+            except ValueError:
+                data = None
+            if isinstance(data, dict) and data.get("type") == MessageType.CONTROL.value:
+                self._apply_control(data)
+                continue
+            self._lines.put(line)
+        self._lines.put(None)
+    
+    def _apply_control(self, control: Dict[str, Any]):
+        """Apply a control message from the CLI"""
+        action = control.get("action")
+        if action == "pause":
+            self._resumed.clear()
+        elif action == "resume":
+            self._resumed.set()
+        elif action == "cancel":
+            self._cancelled.set()
+            self._resumed.set()
+        elif action == "set_rate_limit":
+            self.rate_limit = int(control.get("rate_limit") or 0)
+        
+        if self.on_control:
+            try:
+                self.on_control(action, control)
+            except Exception as e:
+                print(f"Control handler failed: {e}", file=sys.stderr)
+    
+    def reset_controls(self):
+        """Forget the controls of the previous command"""
+        self.rate_limit = 0
+        self._cancelled.clear()
+        self._resumed.set()
+    
+    @property
+    def paused(self) -> bool:
+        """Whether the user paused the command"""
+        return not self._resumed.is_set()
+    
+    @property
+    def cancelled(self) -> bool:
+        """Whether the user cancelled the command"""
+        return self._cancelled.is_set()
+    
+    def checkpoint(self):
+        """Wait while the command is paused and raise CommandCancelled once
+        it is cancelled. send_progress calls it, so commands reporting
+        progress pause and stop between reports; others call it where they
+        can stop safely."""
+        while not self._resumed.wait(15.0):
+            self.send_keepalive("Paused")
+        if self._cancelled.is_set():
+            raise CommandCancelled("Cancelled by the user")
+    
+    @contextmanager
+    def controlled_process(self, process):
+        """Suspend, resume and terminate a child process, such as FFmpeg,
+        along with the command
+        
+        Usage:
+            with self.bridge.controlled_process(process):
+                process.wait()
+        """
+        previous = self.on_control
+        
+        def control(action, frame):
+            if action == "pause" and hasattr(signal, "SIGSTOP"):
+                process.send_signal(signal.SIGSTOP)
+            elif action == "resume" and hasattr(signal, "SIGCONT"):
+                process.send_signal(signal.SIGCONT)
+            elif action == "cancel":
+                if hasattr(signal, "SIGCONT"):
+                    process.send_signal(signal.SIGCONT)
+                process.terminate()
+            if previous:
+                previous(action, frame)
+        
+        self.on_control = control
+        try:
+            yield process
+        finally:
+            self.on_control = previous
+    
+    def _parse_request(self, data: Dict[str, Any]) -> ModuleRequest:
+        """Build a request from its decoded frame"""
+        if data.get("encoding") == "gzip":
+            data = json.loads(gzip.decompress(base64.b64decode(data["payload"])))
+        
+        self.compression = data.get("compression")
+        return ModuleRequest(
+            command=data.get('command', ''),
+            args=data.get('args', {}),
+            auth_token=data.get('auth_token', ''),
+            device_token=data.get('device_token', ''),
+            timeout=data.get('timeout', 300),
+            compression=self.compression,
+            secrets=data.get('secrets') or {},
+            run_id=data.get('run_id', ''),
+            cookies=data.get('cookies') or [],
+            log_level=data.get('log_level', '')
+        )
+    
     def send_response(self, response: ModuleResponse):
         """Send response to stdout"""
         try:
-            output = json.dumps(asdict(response))
-            sys.stdout.write(output + '\n')
-            sys.stdout.flush()
+            self._write_frame(asdict(response))
         except Exception as e:
             self.send_error(f"Failed to send response: {e}")
             sys.exit(1)
     
-    def send_progress(self, stage: str, current: int, total: int, message: str = ""):
-        """Send progress event"""
+    def send_progress(self, stage: str, current: int, total: int, message: str = "", item: Optional[str] = None):
+        """Send progress event.
+
+        Batch commands that process items concurrently pass the item ID so the
+        CLI can render one bar per item. Paused commands wait here until
+        resumed, and cancelled ones raise CommandCancelled.
+        """
+        self.checkpoint()
         progress = ProgressEvent(
+            item=item,
             stage=stage,
             current=current,
             total=total,
             percentage=(current / total * 100) if total > 0 else 0,
             message=message,
-            timestamp=time.time()
+            timestamp=datetime.now(timezone.utc).isoformat()
         )
         
-        response = ModuleResponse(
-            success=True,
-            data={},
-            progress=asdict(progress)
-        )
+        self._write_frame({"type": MessageType.PROGRESS.value, **asdict(progress)})
+    
+    def declare_stages(self, stages: List[Union[str, Tuple[str, float]]]):
+        """Declare the stages of a multi-stage operation and their weights.
+
+        Stages are given in order, either as names (weight 1) or as
+        (name, weight) tuples. Progress for each stage is then reported from
+        0 to 100 and the CLI combines it into one overall percentage.
+        """
+        declared = []
+        for stage in stages:
+            if isinstance(stage, str):
+                declared.append({"name": stage, "weight": 1.0})
+            else:
+                name, weight = stage
+                declared.append({"name": name, "weight": float(weight)})
         
-        self.send_response(response)
+        self._write_frame({"type": MessageType.STAGES.value, "stages": declared})
+    
+    def prompt(self, message: str, choices: Optional[List[str]] = None,
+               default: Optional[str] = None, secret: bool = False,
+               timeout: int = 0, prompt_id: Optional[str] = None) -> str:
+        """Ask the user a question and wait for the answer.
+
+        With choices, the answer is one of them. When nobody can answer,
+        such as in headless mode or a worker, or the user does not answer
+        within timeout seconds (default 5 minutes), the default is returned;
+        without a default, PromptError is raised.
+
+        Usage:
+            account = self.bridge.prompt("Which account?", choices=["alice", "bob"])
+        """
+        self._prompts += 1
+        frame = {
+            "type": MessageType.PROMPT.value,
+            "id": prompt_id or f"prompt-{self._prompts}",
+            "message": message,
+        }
+        if choices:
+            frame["choices"] = list(choices)
+        if default is not None:
+            frame["default"] = default
+        if secret:
+            frame["secret"] = True
+        if timeout:
+            frame["timeout"] = timeout
+        self._write_frame(frame)
+        
+        # The CLI closes stdin when it cannot answer prompts at all
+        line = self._read_line()
+        if not line:
+            if default is not None:
+                return default
+            raise PromptError(f"Cannot ask {message!r}: not running interactively")
+        
+        answer = json.loads(line)
+        if answer.get("error"):
+            raise PromptError(answer["error"])
+        return answer.get("value", "")
+    
+    def log(self, message: str, level: str = "info", **fields):
+        """Send a log line to the CLI, which logs it and shows it with
+        --verbose. Logs never mix with progress or the response.
+
+        Usage:
+            self.bridge.log("Retrying with another client", level="warn", client="web")
+        """
+        level = {"warning": "warn", "critical": "error"}.get(level, level)
+        if level not in LOG_LEVELS:
+            level = "info"
+        if not self.log_level:
+            print(f"{level}: {message}", file=sys.stderr)
+            return
+        if LOG_LEVELS.index(level) < LOG_LEVELS.index(self.log_level):
+            return
+        
+        frame = {"type": MessageType.LOG.value, "level": level, "message": message}
+        if fields:
+            frame["fields"] = {
+                key: value if isinstance(value, (str, int, float, bool, type(None))) else str(value)
+                for key, value in fields.items()
+            }
+        self._write_frame(frame)
+    
+    def send_keepalive(self, message: str = ""):
+        """Send keepalive event to show the module is still working"""
+        self._write_frame({"type": MessageType.KEEPALIVE.value, "message": message})
+    
+    @contextmanager
+    def keepalive(self, message: str = "", interval: float = 15.0):
+        """Emit keepalive events in the background during a silent phase
+        
+        Usage:
+            with self.bridge.keepalive("Merging streams..."):
+                run_ffmpeg()
+        """
+        stop = threading.Event()
+        
+        def beat():
+            while not stop.wait(interval):
+                self.send_keepalive(message)
+        
+        thread = threading.Thread(target=beat, daemon=True)
+        thread.start()
+        try:
+            yield
+        finally:
+            stop.set()
+            thread.join()
+    
+    def get_cookies(self, domain: Optional[str] = None) -> List[Dict[str, Any]]:
+        """Return the module's cookies, or those of domain and its subdomains"""
+        if not domain:
+            return list(self.cookies)
+        domain = domain.lstrip(".").lower()
+        return [
+            cookie for cookie in self.cookies
+            if cookie["domain"].lstrip(".").lower() == domain
+            or cookie["domain"].lower().endswith("." + domain)
+        ]
+    
+    def set_cookies(self, cookies: List[Dict[str, Any]]):
+        """Replace the module's cookie jar; the CLI stores it after the run.
+
+        Cookies are dicts with domain, path, name, value, expires (Unix time,
+        0 for session cookies), secure and http_only.
+        """
+        self.cookies = list(cookies)
+        self.cookies_changed = True
+    
+    @contextmanager
+    def cookie_file(self):
+        """Write the cookie jar to a temporary Netscape cookies.txt file
+        
+        Tools like yt-dlp read and update cookies in this format. Changes
+        they make to the file are kept in the jar afterwards.
+        
+        Usage:
+            with self.bridge.cookie_file() as path:
+                run(["yt-dlp", "--cookies", path, url])
+        """
+        fd, path = tempfile.mkstemp(prefix="converso-cookies-", suffix=".txt")
+        try:
+            lines = ["# Netscape HTTP Cookie File\n"]
+            for cookie in self.cookies:
+                domain = cookie["domain"]
+                if cookie.get("http_only"):
+                    domain = "#HttpOnly_" + domain
+                lines.append("\t".join([
+                    domain,
+                    "TRUE" if cookie["domain"].startswith(".") else "FALSE",
+                    cookie.get("path") or "/",
+                    "TRUE" if cookie.get("secure") else "FALSE",
+                    str(cookie.get("expires") or 0),
+                    cookie["name"],
+                    cookie.get("value", ""),
+                ]) + "\n")
+            written = "".join(lines)
+            with os.fdopen(fd, "w") as f:
+                f.write(written)
+            yield path
+            with open(path) as f:
+                if f.read() != written:
+                    self.set_cookies(read_cookie_file(path))
+        finally:
+            os.unlink(path)
     
     def send_error(self, error: str):
         """Send error response"""
@@ -122,9 +514,11 @@ class IPCBridge:
         )
         self.send_response(response)
     
-    def validate_auth(self) -> bool:
+    def validate_auth(self, required: bool = True) -> bool:
         """Validate authentication tokens"""
         if not self.auth_token:
+            if not required:
+                return True
             self.send_error("Authentication required")
             return False
         
@@ -145,43 +539,93 @@ class IPCBridge:
         if timeout_seconds > 0:
             signal.signal(signal.SIGALRM, self.handle_timeout)
             signal.alarm(timeout_seconds)
+    
+    def clear_timeout(self):
+        """Cancel the execution timeout once a request is done"""
+        signal.alarm(0)
+
+
+class BridgeLogHandler(logging.Handler):
+    """Routes records of Python's logging module to the CLI as log frames"""
+    
+    def __init__(self, bridge: IPCBridge):
+        super().__init__()
+        self.bridge = bridge
+    
+    def emit(self, record: logging.LogRecord):
+        try:
+            self.bridge.log(self.format(record), level=record.levelname.lower(), logger=record.name)
+        except Exception:
+            self.handleError(record)
 
 
 class ModuleBase:
     """Base class for all Python modules"""
     
+    # Modules calling Converso APIs with the user's token set this, and
+    # requires_auth in their manifest
+    requires_auth = False
+    
     def __init__(self):
         self.bridge = IPCBridge()
+        self.bridge.send_hello()
         self.commands = {}
+        # Log records of the module and its libraries reach the CLI
+        logging.getLogger().addHandler(BridgeLogHandler(self.bridge))
     
     def register_command(self, name: str, handler: Callable):
         """Register a command handler"""
         self.commands[name] = handler
     
     def run(self):
-        """Main execution loop"""
+        """Main execution loop. Persistent processes, kept by the CLI's
+        process pool, serve requests until the CLI closes stdin."""
+        if not self.bridge.persistent:
+            self.serve(self.bridge.read_request())
+            return
+        
+        while True:
+            request = self.bridge.next_request()
+            if request is None:
+                return
+            self.serve(request)
+            self.bridge.clear_timeout()
+    
+    def serve(self, request: ModuleRequest):
+        """Handle a single request and send its response"""
         try:
-            # Read request
-            request = self.bridge.read_request()
-            
             # Set timeout
             self.bridge.set_timeout(request.timeout)
             
             # Store tokens
             self.bridge.auth_token = request.auth_token
             self.bridge.device_token = request.device_token
+            self.bridge.secrets = request.secrets
+            self.bridge.run_id = request.run_id
+            self.bridge.cookies = request.cookies
+            self.bridge.cookies_changed = False
+            self.bridge.reset_controls()
+            self.bridge.log_level = request.log_level
+            if request.log_level:
+                logging.getLogger().setLevel(request.log_level.upper().replace("WARN", "WARNING"))
             
             # Validate authentication
-            if not self.bridge.validate_auth():
+            if not self.bridge.validate_auth(self.requires_auth):
                 return
             
             # Handle command
             if request.command in self.commands:
                 try:
                     result = self.commands[request.command](request.args)
-                    response = ModuleResponse(success=True, data=result)
+                    items = result.pop("items", None) if isinstance(result, dict) else None
+                    if items is not None:
+                        response = create_batch_response(items, result)
+                    else:
+                        response = ModuleResponse(success=True, data=result)
                 except Exception as e:
                     response = ModuleResponse(success=False, data={}, error=str(e))
+                if self.bridge.cookies_changed:
+                    response.cookies = self.bridge.cookies
             else:
                 response = ModuleResponse(
                     success=False, 
@@ -214,6 +658,32 @@ def progress_generator(total: int, message: str = "") -> Generator[int, None, No
             sys.stdout.flush()
 
 
+def read_cookie_file(path: str) -> List[Dict[str, Any]]:
+    """Read cookies from a Netscape cookies.txt file"""
+    cookies = []
+    with open(path) as f:
+        for line in f:
+            line = line.rstrip("\r\n")
+            http_only = line.startswith("#HttpOnly_")
+            if http_only:
+                line = line[len("#HttpOnly_"):]
+            if not line.strip() or line.startswith("#"):
+                continue
+            fields = line.split("\t")
+            if len(fields) != 7:
+                continue
+            cookies.append({
+                "domain": fields[0],
+                "path": fields[2],
+                "name": fields[5],
+                "value": fields[6],
+                "expires": int(fields[4] or 0),
+                "secure": fields[3].upper() == "TRUE",
+                "http_only": http_only,
+            })
+    return cookies
+
+
 def validate_request(request: ModuleRequest) -> Optional[str]:
     """Validate module request"""
     if not request.command:
@@ -235,6 +705,51 @@ def create_success_response(data: Dict[str, Any]) -> ModuleResponse:
     return ModuleResponse(success=True, data=data)
 
 
+def create_batch_response(items: List[Any], data: Optional[Dict[str, Any]] = None) -> ModuleResponse:
+    """Create response carrying per-item results of a batch operation
+
+    The response succeeds when at least one item succeeded; the CLI reports
+    a partial failure when only some of the items failed.
+    """
+    results = [asdict(item) if isinstance(item, ItemResult) else item for item in items]
+    failed = [item for item in results if not item.get("success")]
+
+    if results and len(failed) == len(results):
+        return ModuleResponse(
+            success=False,
+            data=data or {},
+            error=f"All {len(results)} items failed",
+            items=results
+        )
+
+    return ModuleResponse(success=True, data=data or {}, items=results)
+
+
+def stop_batch(args: Dict[str, Any], item: ItemResult) -> bool:
+    """Whether a batch should stop after item because the CLI requested fail_fast
+
+    Modules that stop early report the number of items they did not attempt
+    as "skipped" in their result data.
+    """
+    return bool(args.get("fail_fast")) and not item.success
+
+
+def completion_values(options: Dict[str, str], prefix: str = "") -> Dict[str, Any]:
+    """Build the result of a complete command from values and their descriptions
+
+    The CLI runs the module's complete command with the module command, the
+    argument or flag being completed ("arg"), the positional arguments given
+    so far and the typed prefix.
+    """
+    return {
+        "values": [
+            {"value": value, "description": description}
+            for value, description in options.items()
+            if value.startswith(prefix)
+        ]
+    }
+
+
 # Utility functions for common operations
 def get_auth_header(auth_token: str) -> Dict[str, str]:
     """Get authorization header"""
@@ -255,12 +770,103 @@ def format_size(bytes_size: int) -> str:
     return f"{bytes_size:.1f} {units[i]}"
 
 
+@contextmanager
+def partial_output(path: str):
+    """Remove the file written at path when the block fails or the command
+    is cancelled, so interrupted downloads and conversions leave no partial
+    files behind. A file that existed before is left alone.
+    
+    Usage:
+        with partial_output(output_path):
+            run_ffmpeg(output_path)
+    """
+    existed = os.path.exists(path)
+    try:
+        yield path
+    except BaseException:
+        if not existed:
+            try:
+                os.remove(path)
+            except OSError:
+                pass
+        raise
+
+
 def check_ffmpeg() -> bool:
     """Check if FFmpeg is available"""
     import shutil
     return shutil.which("ffmpeg") is not None
 
 
+# Conversion codec names mapped to FFmpeg encoders
+VIDEO_ENCODERS = {
+    "h264": "libx264",
+    "h265": "libx265",
+    "hevc": "libx265",
+    "vp9": "libvpx-vp9",
+    "av1": "libaom-av1",
+}
+AUDIO_ENCODERS = {
+    "aac": "aac",
+    "mp3": "libmp3lame",
+    "opus": "libopus",
+    "vorbis": "libvorbis",
+    "flac": "flac",
+}
+# Codec names the CLI passes hardware encoders under, for their aliases
+HARDWARE_CODECS = {"hevc": "h265"}
+
+
+def hardware_encoder(options: Dict[str, Any]) -> Optional[str]:
+    """Return the hardware encoder the CLI detected for the options' codec, if any"""
+    codec = (options.get("codec") or "").lower()
+    return (options.get("hw_encoders") or {}).get(HARDWARE_CODECS.get(codec, codec))
+
+
+def software_options(options: Dict[str, Any]) -> Dict[str, Any]:
+    """Return the options without hardware encoders, to retry a failed hardware encode"""
+    return {key: value for key, value in options.items() if key != "hw_encoders"}
+
+
+def conversion_output_path(input_path: str, output_dir: str, options: Dict[str, Any]) -> str:
+    """Return the output path for converting input_path with options"""
+    stem, ext = os.path.splitext(os.path.basename(input_path))
+    container = options.get("container") or ext.lstrip(".")
+    output_path = os.path.join(output_dir, f"{stem}.{container}")
+    if os.path.abspath(output_path) == os.path.abspath(input_path):
+        output_path = os.path.join(output_dir, f"{stem}.converted.{container}")
+    return output_path
+
+
+def ffmpeg_conversion_args(input_path: str, output_path: str, options: Dict[str, Any]) -> List[str]:
+    """Build FFmpeg arguments for a conversion preset (codec, bitrate, resolution, container),
+    encoding with the hardware encoder passed in hw_encoders for the codec when there is one"""
+    args = ["ffmpeg", "-y", "-hide_banner", "-i", input_path]
+    
+    codec = (options.get("codec") or "").lower()
+    bitrate = options.get("bitrate")
+    resolution = options.get("resolution")
+    
+    if codec in AUDIO_ENCODERS:
+        args += ["-vn", "-c:a", AUDIO_ENCODERS[codec]]
+        if bitrate:
+            args += ["-b:a", bitrate]
+    else:
+        if codec:
+            args += ["-c:v", hardware_encoder(options) or VIDEO_ENCODERS.get(codec, codec)]
+        if bitrate:
+            args += ["-b:v", bitrate]
+        if resolution:
+            if resolution.endswith("p"):
+                args += ["-vf", f"scale=-2:{resolution[:-1]}"]
+            else:
+                width, height = resolution.split("x", 1)
+                args += ["-vf", f"scale={width}:{height}"]
+    
+    args.append(output_path)
+    return args
+
+
 if __name__ == "__main__":
     # Example usage
     module = ModuleBase()
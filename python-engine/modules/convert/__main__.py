@@ -0,0 +1,244 @@
+#!/usr/bin/env python3
+"""
+Converso CLI Convert Module
+
+This module converts media files with FFmpeg using the codec, bitrate,
+resolution and container options of a conversion preset, and extracts
+still frames and GIF clips.
+"""
+
+import os
+import subprocess
+import sys
+from pathlib import Path
+from typing import Dict, Any, Optional
+
+# Add the parent directory to the path to import the bridge
+sys.path.insert(0, str(Path(__file__).parent.parent.parent))
+
+from bridge import ModuleBase, ItemResult, CommandCancelled, stop_batch, completion_values, check_ffmpeg, conversion_output_path, ffmpeg_conversion_args, format_size, partial_output, hardware_encoder, software_options
+
+# Upper bound on frames extracted by one request
+MAX_FRAMES = 1000
+
+# Shell completion values for conversion options
+COMPLETIONS = {
+    "codec": {
+        "h264": "H.264 / AVC video",
+        "h265": "H.265 / HEVC video",
+        "vp9": "VP9 video",
+        "av1": "AV1 video",
+        "aac": "AAC audio",
+        "mp3": "MP3 audio",
+        "opus": "Opus audio",
+        "flac": "FLAC lossless audio",
+    },
+    "container": {
+        "mp4": "MPEG-4",
+        "mkv": "Matroska",
+        "webm": "WebM",
+        "mov": "QuickTime",
+        "mp3": "MP3 audio",
+        "m4a": "MPEG-4 audio",
+        "ogg": "Ogg audio",
+        "flac": "FLAC audio",
+    },
+    "resolution": {
+        "2160p": "3840x2160",
+        "1440p": "2560x1440",
+        "1080p": "1920x1080",
+        "720p": "1280x720",
+        "480p": "854x480",
+        "360p": "640x360",
+    },
+}
+
+
+class ConvertModule(ModuleBase):
+    """Media conversion module backed by FFmpeg"""
+    
+    def __init__(self):
+        super().__init__()
+        self.register_command("convert", self.convert)
+        self.register_command("frames", self.frames)
+        self.register_command("complete", self.complete)
+    
+    def convert(self, args: Dict[str, Any]) -> Dict[str, Any]:
+        """Convert a media file"""
+        input_path = args.get("input")
+        if not input_path:
+            raise ValueError("input is required")
+        if not os.path.isfile(input_path):
+            raise ValueError(f"Input file not found: {input_path}")
+        
+        if not check_ffmpeg():
+            raise ValueError("FFmpeg is required for conversion. Please install FFmpeg.")
+        
+        output_dir = args.get("output_dir") or os.path.dirname(input_path)
+        Path(output_dir).mkdir(parents=True, exist_ok=True)
+        output_path = conversion_output_path(input_path, output_dir, args)
+        
+        self.bridge.send_progress("converting", 0, 100, f"Converting {os.path.basename(input_path)}...")
+        duration = self._probe_duration(input_path)
+        with partial_output(output_path):
+            try:
+                self._run_ffmpeg(ffmpeg_conversion_args(input_path, output_path, args), duration)
+            except ValueError as e:
+                # Hardware encoders can fail where they were detected, e.g. when busy
+                encoder = hardware_encoder(args)
+                if not encoder:
+                    raise
+                self.bridge.log("Hardware encoder failed, retrying with software", level="warn", encoder=encoder, error=str(e))
+                args = software_options(args)
+                self._run_ffmpeg(ffmpeg_conversion_args(input_path, output_path, args), duration)
+        self.bridge.send_progress("converting", 100, 100, "Conversion completed!")
+        
+        return {
+            "input": input_path,
+            "file_path": output_path,
+            "file_size": format_size(os.path.getsize(output_path)),
+            "codec": args.get("codec"),
+            "bitrate": args.get("bitrate"),
+            "resolution": args.get("resolution"),
+            "container": args.get("container"),
+            "hw_encoder": hardware_encoder(args),
+            "status": "completed"
+        }
+    
+    def complete(self, args: Dict[str, Any]) -> Dict[str, Any]:
+        """Offer shell completion values for conversion options"""
+        return completion_values(COMPLETIONS.get(args.get("arg"), {}), args.get("prefix", ""))
+    
+    def frames(self, args: Dict[str, Any]) -> Dict[str, Any]:
+        """Extract still frames or GIF clips at timestamps or fixed intervals"""
+        input_path = args.get("input")
+        if not input_path:
+            raise ValueError("input is required")
+        if not os.path.isfile(input_path):
+            raise ValueError(f"Input file not found: {input_path}")
+        
+        if not check_ffmpeg():
+            raise ValueError("FFmpeg is required for frame extraction. Please install FFmpeg.")
+        
+        output_dir = args.get("output_dir") or os.path.dirname(input_path)
+        Path(output_dir).mkdir(parents=True, exist_ok=True)
+        
+        gif = bool(args.get("gif"))
+        ext = "gif" if gif else args.get("format", "jpg")
+        template = args.get("template") or "{name}_{time}.{ext}"
+        timestamps = self._frame_timestamps(input_path, args.get("timestamps") or [], float(args.get("every") or 0))
+        
+        items = []
+        for index, timestamp in enumerate(timestamps, start=1):
+            output_path = os.path.join(output_dir, _render_template(template, input_path, index, timestamp, ext))
+            self.bridge.send_progress("extracting", index - 1, len(timestamps), f"Extracting {os.path.basename(output_path)}...")
+            
+            if gif:
+                ffmpeg_args = _gif_args(input_path, output_path, timestamp, float(args.get("duration") or 3), int(args.get("fps") or 10), int(args.get("width") or 480))
+            else:
+                ffmpeg_args = ["ffmpeg", "-y", "-loglevel", "error", "-ss", str(timestamp), "-i", input_path, "-frames:v", "1", "-q:v", "2", output_path]
+            
+            result = subprocess.run(ffmpeg_args, capture_output=True, text=True)
+            if result.returncode != 0 or not os.path.isfile(output_path):
+                items.append(ItemResult(id=os.path.basename(output_path), success=False, error=result.stderr.strip() or "no frame extracted"))
+            else:
+                items.append(ItemResult(id=os.path.basename(output_path), success=True, data={"file_path": output_path, "timestamp": timestamp}))
+            
+            if stop_batch(args, items[-1]):
+                break
+        
+        self.bridge.send_progress("extracting", len(timestamps), len(timestamps), "Extraction completed!")
+        return {"items": items, "output_dir": output_dir, "count": len(items), "skipped": len(timestamps) - len(items)}
+    
+    def _frame_timestamps(self, input_path: str, timestamps: list, every: float) -> list:
+        """Resolve explicit timestamps, or an interval starting at the first one"""
+        if every <= 0:
+            if not timestamps:
+                raise ValueError("timestamps or every is required")
+            return [float(t) for t in timestamps]
+        
+        duration = self._probe_duration(input_path)
+        if not duration:
+            raise ValueError("Could not determine media duration for interval extraction")
+        
+        start = float(timestamps[0]) if timestamps else 0.0
+        count = int((duration - start) / every) + 1 if duration > start else 0
+        if count > MAX_FRAMES:
+            raise ValueError(f"Interval would extract {count} frames, the limit is {MAX_FRAMES}")
+        return [round(start + i * every, 3) for i in range(count)]
+    
+    def _run_ffmpeg(self, ffmpeg_args: list, duration: Optional[float]):
+        """Run FFmpeg, reporting progress from its -progress output"""
+        self.bridge.log("Running FFmpeg", level="debug", args=" ".join(ffmpeg_args))
+        if not duration:
+            self.bridge.log("Media duration unknown, progress is not reported", level="warn")
+        process = subprocess.Popen(
+            ffmpeg_args[:1] + ["-loglevel", "error", "-progress", "pipe:1", "-nostats"] + ffmpeg_args[1:],
+            stdout=subprocess.PIPE,
+            stderr=subprocess.PIPE,
+            text=True
+        )
+        
+        with self.bridge.controlled_process(process):
+            try:
+                for line in process.stdout:
+                    key, _, value = line.strip().partition("=")
+                    if key == "out_time_us" and duration and value.isdigit():
+                        percent = min(99, int(int(value) / 1_000_000 / duration * 100))
+                        self.bridge.send_progress("converting", percent, 100, f"Converting... {percent}%")
+            except CommandCancelled:
+                process.kill()
+                process.wait()
+                raise
+            _, stderr = process.communicate()
+        
+        # A cancelled FFmpeg fails; report the cancellation instead
+        self.bridge.checkpoint()
+        if process.returncode != 0:
+            last_line = stderr.strip().splitlines()[-1] if stderr.strip() else "unknown error"
+            raise ValueError(f"FFmpeg failed: {last_line}")
+    
+    def _probe_duration(self, input_path: str) -> Optional[float]:
+        """Return the media duration in seconds, if ffprobe can determine it"""
+        try:
+            result = subprocess.run(
+                ["ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "csv=p=0", input_path],
+                capture_output=True,
+                text=True,
+                timeout=30
+            )
+            return float(result.stdout.strip())
+        except (OSError, ValueError, subprocess.SubprocessError):
+            return None
+
+
+def _render_template(template: str, input_path: str, index: int, timestamp: float, ext: str) -> str:
+    """Render an output file name template"""
+    hours, rest = divmod(int(timestamp), 3600)
+    minutes, seconds = divmod(rest, 60)
+    values = {
+        "name": os.path.splitext(os.path.basename(input_path))[0],
+        "index": f"{index:03d}",
+        "time": f"{hours:02d}-{minutes:02d}-{seconds:02d}",
+        "seconds": f"{timestamp:g}",
+        "ext": ext,
+    }
+    for key, value in values.items():
+        template = template.replace("{" + key + "}", value)
+    return template
+
+
+def _gif_args(input_path: str, output_path: str, start: float, duration: float, fps: int, width: int) -> list:
+    """Build FFmpeg arguments for a palette-optimised GIF clip"""
+    filters = f"fps={fps},scale={width}:-1:flags=lanczos,split[s0][s1];[s0]palettegen[p];[s1][p]paletteuse"
+    return ["ffmpeg", "-y", "-loglevel", "error", "-ss", str(start), "-t", str(duration), "-i", input_path, "-vf", filters, output_path]
+
+
+def main():
+    """Main entry point"""
+    module = ConvertModule()
+    module.run()
+
+
+if __name__ == "__main__":
+    main()
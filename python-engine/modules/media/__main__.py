@@ -0,0 +1,310 @@
+#!/usr/bin/env python3
+"""
+Converso CLI Media Module
+
+This module reads and writes media tags (title, artist, album and cover art)
+using mutagen when it is installed, falling back to FFmpeg otherwise, and
+inspects media files with ffprobe.
+"""
+
+import json
+import mimetypes
+import os
+import subprocess
+import sys
+from pathlib import Path
+from typing import Dict, Any, List, Optional, Tuple
+
+# Add the parent directory to the path to import the bridge
+sys.path.insert(0, str(Path(__file__).parent.parent.parent))
+
+from bridge import ModuleBase, ItemResult, stop_batch, check_ffmpeg
+
+try:
+    import mutagen
+    from mutagen.flac import FLAC, Picture
+    from mutagen.id3 import ID3, APIC
+    from mutagen.mp3 import MP3
+    from mutagen.mp4 import MP4, MP4Cover
+except ImportError:
+    mutagen = None
+
+TAG_FIELDS = ["title", "artist", "album"]
+
+
+class MediaModule(ModuleBase):
+    """Media tagging module backed by mutagen or FFmpeg"""
+    
+    def __init__(self):
+        super().__init__()
+        self.register_command("read_tags", self.read_tags)
+        self.register_command("write_tags", self.write_tags)
+        self.register_command("probe", self.probe)
+    
+    def read_tags(self, args: Dict[str, Any]) -> Dict[str, Any]:
+        """Read the tags of one or more files"""
+        files = args.get("files") or []
+        if not files:
+            raise ValueError("files is required")
+        
+        items = []
+        for index, path in enumerate(files):
+            self.bridge.send_progress("reading", index, len(files), f"Reading {os.path.basename(path)}...")
+            try:
+                tags, has_cover = self._read(path)
+                items.append(ItemResult(id=path, success=True, data={"tags": tags, "has_cover": has_cover}))
+            except Exception as e:
+                items.append(ItemResult(id=path, success=False, error=str(e)))
+        
+        return {"items": items}
+    
+    def write_tags(self, args: Dict[str, Any]) -> Dict[str, Any]:
+        """Write tags and cover art to one or more files"""
+        entries = args.get("entries") or []
+        if not entries:
+            raise ValueError("entries is required")
+        
+        items = []
+        for index, entry in enumerate(entries):
+            path = entry.get("file", "")
+            self.bridge.send_progress("writing", index, len(entries), f"Tagging {os.path.basename(path)}...")
+            try:
+                tags = {k: v for k, v in (entry.get("tags") or {}).items() if k in TAG_FIELDS}
+                self._write(path, tags, entry.get("cover"))
+                items.append(ItemResult(id=path, success=True, data={"file_path": path}))
+            except Exception as e:
+                items.append(ItemResult(id=path, success=False, error=str(e)))
+            
+            if stop_batch(args, items[-1]):
+                break
+        
+        self.bridge.send_progress("writing", len(entries), len(entries), "Tagging completed!")
+        return {"items": items, "skipped": len(entries) - len(items)}
+    
+    def probe(self, args: Dict[str, Any]) -> Dict[str, Any]:
+        """Inspect the container and streams of a file with ffprobe"""
+        path = args.get("file")
+        if not path:
+            raise ValueError("file is required")
+        if not os.path.isfile(path):
+            raise ValueError(f"File not found: {path}")
+        if not check_ffmpeg():
+            raise ValueError("FFmpeg is required for probing. Please install FFmpeg.")
+        
+        result = subprocess.run(
+            ["ffprobe", "-v", "error", "-show_format", "-show_streams", "-of", "json", path],
+            capture_output=True,
+            text=True,
+            timeout=60
+        )
+        if result.returncode != 0:
+            raise ValueError(f"ffprobe failed: {result.stderr.strip()}")
+        
+        info = json.loads(result.stdout or "{}")
+        fmt = info.get("format", {})
+        
+        return {
+            "file": path,
+            "format": fmt.get("format_name", ""),
+            "format_long": fmt.get("format_long_name", ""),
+            "duration": _float(fmt.get("duration")),
+            "bit_rate": _int(fmt.get("bit_rate")),
+            "size": _int(fmt.get("size")),
+            "streams": [_stream_info(stream) for stream in info.get("streams", [])]
+        }
+    
+    def _read(self, path: str) -> Tuple[Dict[str, str], bool]:
+        """Return the text tags and whether cover art is embedded"""
+        if mutagen is not None:
+            easy = mutagen.File(path, easy=True)
+            if easy is not None:
+                tags = {field: (easy.get(field) or [""])[0] for field in TAG_FIELDS}
+                return tags, self._mutagen_has_cover(path)
+        
+        if not check_ffmpeg():
+            raise ValueError("Unsupported file format for mutagen and FFmpeg is not installed")
+        return self._ffprobe_read(path)
+    
+    def _write(self, path: str, tags: Dict[str, str], cover: Optional[str]):
+        """Write text tags and cover art"""
+        if mutagen is not None:
+            easy = mutagen.File(path, easy=True)
+            if easy is not None:
+                if easy.tags is None:
+                    easy.add_tags()
+                for field, value in tags.items():
+                    if value:
+                        easy[field] = value
+                    elif field in easy:
+                        del easy[field]
+                easy.save()
+                if cover:
+                    self._mutagen_write_cover(path, cover)
+                return
+        
+        if not check_ffmpeg():
+            raise ValueError("Unsupported file format for mutagen and FFmpeg is not installed")
+        self._ffmpeg_write(path, tags, cover)
+    
+    def _mutagen_has_cover(self, path: str) -> bool:
+        """Check for embedded cover art with mutagen"""
+        audio = mutagen.File(path)
+        if isinstance(audio, FLAC):
+            return bool(audio.pictures)
+        if isinstance(audio, MP4):
+            return bool(audio.tags and audio.tags.get("covr"))
+        if audio is not None and audio.tags is not None and hasattr(audio.tags, "getall"):
+            return bool(audio.tags.getall("APIC"))
+        return False
+    
+    def _mutagen_write_cover(self, path: str, cover: str):
+        """Embed cover art with mutagen"""
+        with open(cover, "rb") as f:
+            image = f.read()
+        mime = mimetypes.guess_type(cover)[0] or "image/jpeg"
+        
+        audio = mutagen.File(path)
+        if isinstance(audio, MP3):
+            tags = ID3(path)
+            tags.delall("APIC")
+            tags.add(APIC(encoding=3, mime=mime, type=3, desc="Cover", data=image))
+            tags.save(path)
+        elif isinstance(audio, MP4):
+            image_format = MP4Cover.FORMAT_PNG if mime == "image/png" else MP4Cover.FORMAT_JPEG
+            audio.tags["covr"] = [MP4Cover(image, imageformat=image_format)]
+            audio.save()
+        elif isinstance(audio, FLAC):
+            picture = Picture()
+            picture.type = 3
+            picture.mime = mime
+            picture.data = image
+            audio.clear_pictures()
+            audio.add_picture(picture)
+            audio.save()
+        else:
+            raise ValueError(f"Cover art is not supported for {os.path.splitext(path)[1] or 'this format'}")
+    
+    def _ffprobe_read(self, path: str) -> Tuple[Dict[str, str], bool]:
+        """Read tags with ffprobe"""
+        result = subprocess.run(
+            ["ffprobe", "-v", "error", "-show_entries", "format_tags:stream_disposition=attached_pic", "-of", "json", path],
+            capture_output=True,
+            text=True
+        )
+        if result.returncode != 0:
+            raise ValueError(f"ffprobe failed: {result.stderr.strip()}")
+        
+        info = json.loads(result.stdout or "{}")
+        format_tags = {k.lower(): v for k, v in info.get("format", {}).get("tags", {}).items()}
+        tags = {field: format_tags.get(field, "") for field in TAG_FIELDS}
+        has_cover = any(s.get("disposition", {}).get("attached_pic") == 1 for s in info.get("streams", []))
+        return tags, has_cover
+    
+    def _ffprobe_streams(self, path: str) -> List[Dict[str, Any]]:
+        """List the streams of a file with their type and disposition"""
+        result = subprocess.run(
+            ["ffprobe", "-v", "error", "-show_entries", "stream=index,codec_type:stream_disposition=attached_pic", "-of", "json", path],
+            capture_output=True,
+            text=True
+        )
+        if result.returncode != 0:
+            raise ValueError(f"ffprobe failed: {result.stderr.strip()}")
+        return json.loads(result.stdout or "{}").get("streams", [])
+    
+    def _ffmpeg_write(self, path: str, tags: Dict[str, str], cover: Optional[str]):
+        """Rewrite the file with FFmpeg, copying every stream and replacing
+        tags. The original is only replaced when no stream was lost."""
+        stem, ext = os.path.splitext(path)
+        temp_path = f"{stem}.tagging{ext}"
+        
+        streams = self._ffprobe_streams(path)
+        covers = [s for s in streams if s.get("disposition", {}).get("attached_pic") == 1]
+        videos = [s for s in streams if s.get("codec_type") == "video" and s not in covers]
+        expected = len(streams)
+        
+        if cover and videos:
+            raise ValueError("Cover art can only be embedded in audio files; this file has a video stream")
+        
+        args = ["ffmpeg", "-y", "-v", "error", "-i", path]
+        if cover:
+            args += ["-i", cover]
+        args += ["-map", "0"]
+        if cover:
+            # The new cover replaces any embedded one and follows the
+            # remaining streams, so it is the only video stream
+            for stream in covers:
+                args += ["-map", f"-0:{stream['index']}"]
+            args += ["-map", "1", "-disposition:v:0", "attached_pic"]
+            expected += 1 - len(covers)
+        args += ["-c", "copy"]
+        for field, value in tags.items():
+            args += ["-metadata", f"{field}={value}"]
+        args.append(temp_path)
+        
+        try:
+            result = subprocess.run(args, capture_output=True, text=True)
+            if result.returncode != 0:
+                raise ValueError(f"FFmpeg failed: {result.stderr.strip()}")
+            written = len(self._ffprobe_streams(temp_path))
+            if written != expected:
+                raise ValueError(f"FFmpeg wrote {written} of {expected} streams; the original was left unchanged")
+        except Exception:
+            if os.path.exists(temp_path):
+                os.remove(temp_path)
+            raise
+        os.replace(temp_path, path)
+
+def _stream_info(stream: Dict[str, Any]) -> Dict[str, Any]:
+    """Summarise an ffprobe stream"""
+    info = {
+        "index": stream.get("index"),
+        "type": stream.get("codec_type", ""),
+        "codec": stream.get("codec_name", ""),
+        "bit_rate": _int(stream.get("bit_rate")),
+        "language": stream.get("tags", {}).get("language", ""),
+    }
+    if stream.get("disposition", {}).get("attached_pic") == 1:
+        info["type"] = "cover"
+    if info["type"] == "video":
+        info["width"] = stream.get("width", 0)
+        info["height"] = stream.get("height", 0)
+        info["fps"] = _frame_rate(stream.get("avg_frame_rate") or stream.get("r_frame_rate"))
+    elif info["type"] == "audio":
+        info["channels"] = stream.get("channels", 0)
+        info["channel_layout"] = stream.get("channel_layout", "")
+        info["sample_rate"] = _int(stream.get("sample_rate"))
+    return info
+
+
+def _frame_rate(value: Optional[str]) -> float:
+    """Convert an ffprobe frame rate such as 30000/1001 to frames per second"""
+    if not value or "/" not in value:
+        return _float(value)
+    num, den = value.split("/", 1)
+    return round(_float(num) / _float(den), 3) if _float(den) else 0.0
+
+
+def _int(value: Any) -> int:
+    """Parse an ffprobe integer field, returning 0 when missing"""
+    try:
+        return int(value)
+    except (TypeError, ValueError):
+        return 0
+
+
+def _float(value: Any) -> float:
+    """Parse an ffprobe float field, returning 0 when missing"""
+    try:
+        return float(value)
+    except (TypeError, ValueError):
+        return 0.0
+
+
+def main():
+    """Main entry point"""
+    module = MediaModule()
+    module.run()
+
+
+if __name__ == "__main__":
+    main()
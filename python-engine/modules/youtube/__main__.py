@@ -9,6 +9,7 @@ and exposes it through the Python bridge for the Go CLI.
 import sys
 import os
 import json
+import subprocess
 import time
 from pathlib import Path
 from typing import Dict, Any, Optional
@@ -16,7 +17,37 @@ from typing import Dict, Any, Optional
 # Add the parent directory to the path to import the bridge
 sys.path.insert(0, str(Path(__file__).parent.parent.parent))
 
-from bridge import ModuleBase, ModuleRequest, ModuleResponse, ProgressEvent, validate_request, create_error_response, create_success_response, format_size, check_ffmpeg
+from bridge import ModuleBase, ModuleRequest, ModuleResponse, ProgressEvent, validate_request, create_error_response, create_success_response, format_size, check_ffmpeg, conversion_output_path, ffmpeg_conversion_args, partial_output, hardware_encoder, software_options, completion_values
+
+# Download stages and their share of the overall work
+DOWNLOAD_STAGES = [
+    ("analyzing", 1),
+    ("preparing", 1),
+    ("downloading", 7),
+    ("processing", 1),
+]
+
+# Recently used URLs offered by shell completion
+RECENT_FILE = Path(__file__).parent / "recent.json"
+MAX_RECENT = 20
+
+# Shell completion values for download options
+COMPLETIONS = {
+    "mode": {
+        "best": "Best available quality",
+        "audio": "Audio only",
+        "video": "Video only",
+        "merge": "Best video and audio merged",
+        "progressive": "Single file with video and audio",
+    },
+    "container": {
+        "mp4": "MPEG-4",
+        "webm": "WebM",
+        "mkv": "Matroska",
+        "mp3": "MP3 audio",
+        "m4a": "MPEG-4 audio",
+    },
+}
 
 
 class YouTubeModule(ModuleBase):
@@ -27,6 +58,9 @@ class YouTubeModule(ModuleBase):
         self.register_command("download", self.download)
         self.register_command("list_formats", self.list_formats)
         self.register_command("info", self.get_info)
+        self.register_command("channel_videos", self.channel_videos)
+        self.register_command("playlist_videos", self.playlist_videos)
+        self.register_command("complete", self.complete)
     
     def download(self, args: Dict[str, Any]) -> Dict[str, Any]:
         """Download YouTube video/audio"""
@@ -39,6 +73,7 @@ class YouTubeModule(ModuleBase):
         format_id = args.get("format_id")
         container = args.get("container", "mp4")
         output_dir = args.get("output_dir", os.path.expanduser("~/Downloads/Converso_YT"))
+        postprocess = args.get("postprocess")
         
         # Validate FFmpeg
         if not check_ffmpeg():
@@ -47,13 +82,46 @@ class YouTubeModule(ModuleBase):
         # Create output directory
         Path(output_dir).mkdir(parents=True, exist_ok=True)
         
-        # Simulate download process with progress
-        self.bridge.send_progress("preparing", 0, 100, "Preparing download...")
+        # Conversion presets add a post-processing stage
+        stages = DOWNLOAD_STAGES + [("converting", 2)] if postprocess else DOWNLOAD_STAGES
+        self.bridge.declare_stages(stages)
         
         # Here you would integrate with the existing yt.py code
         # For now, we'll simulate the process
         result = self._simulate_download(url, mode, format_id, container, output_dir)
+        result.update(_network_result(args))
+        # Members-only and age-restricted videos need a signed-in session
+        result["signed_in"] = bool(self.bridge.get_cookies("youtube.com"))
+        _remember_url(url, result.get("title"))
+        
+        if postprocess:
+            result = self._postprocess(result, postprocess)
+        return result
+    
+    def _postprocess(self, result: Dict[str, Any], options: Dict[str, Any]) -> Dict[str, Any]:
+        """Convert the downloaded file with a conversion preset"""
+        self.bridge.send_progress("converting", 0, 100, "Applying conversion preset...")
+        
+        source = result.get("file_path")
+        result["postprocess"] = options
+        if source and os.path.isfile(source):
+            output_path = conversion_output_path(source, os.path.dirname(source), options)
+            with partial_output(output_path), self.bridge.keepalive("Converting with FFmpeg..."):
+                completed = subprocess.run(ffmpeg_conversion_args(source, output_path, options), capture_output=True, text=True)
+                # A conversion cancelled meanwhile is not kept
+                self.bridge.checkpoint()
+                encoder = hardware_encoder(options)
+                if completed.returncode != 0 and encoder:
+                    self.bridge.log("Hardware encoder failed, retrying with software", level="warn", encoder=encoder, error=completed.stderr.strip()[-200:])
+                    completed = subprocess.run(ffmpeg_conversion_args(source, output_path, software_options(options)), capture_output=True, text=True)
+                    self.bridge.checkpoint()
+                if completed.returncode != 0:
+                    raise ValueError(f"Post-processing failed: {completed.stderr.strip()[-200:]}")
+            result["source_file_path"] = source
+            result["file_path"] = output_path
+            result["file_size"] = format_size(os.path.getsize(output_path))
         
+        self.bridge.send_progress("converting", 100, 100, "Conversion completed!")
         return result
     
     def list_formats(self, args: Dict[str, Any]) -> Dict[str, Any]:
@@ -68,6 +136,7 @@ class YouTubeModule(ModuleBase):
         # Here you would integrate with the existing yt.py code
         # For now, we'll simulate the response
         formats = self._simulate_list_formats(url)
+        _remember_url(url)
         
         return {
             "url": url,
@@ -87,27 +156,79 @@ class YouTubeModule(ModuleBase):
         # Here you would integrate with the existing yt.py code
         # For now, we'll simulate the response
         info = self._simulate_get_info(url)
+        _remember_url(url, info.get("title"))
         
         return info
     
+    def channel_videos(self, args: Dict[str, Any]) -> Dict[str, Any]:
+        """List the most recent uploads of a channel"""
+        channel = args.get("channel")
+        if not channel:
+            raise ValueError("Channel is required")
+        limit = int(args.get("limit", 30))
+        
+        self.bridge.send_progress("fetching", 0, 100, "Fetching channel uploads...")
+        
+        # Here you would integrate with the existing yt.py code
+        # For now, we'll simulate the response
+        videos = self._simulate_channel_videos(channel, limit)
+        
+        return {
+            "channel": channel,
+            "title": "Sample Channel",
+            "videos": videos,
+            "total_count": len(videos)
+        }
+    
+    def playlist_videos(self, args: Dict[str, Any]) -> Dict[str, Any]:
+        """List the videos of a playlist in playlist order"""
+        playlist = args.get("playlist")
+        if not playlist:
+            raise ValueError("Playlist is required")
+        
+        self.bridge.send_progress("fetching", 0, 100, "Fetching playlist...")
+        
+        # Here you would integrate with the existing yt.py code
+        # For now, we'll simulate the response
+        videos = self._simulate_playlist_videos(playlist)
+        
+        return {
+            "playlist": playlist,
+            "title": "Sample Playlist",
+            "videos": videos,
+            "total_count": len(videos)
+        }
+    
+    def complete(self, args: Dict[str, Any]) -> Dict[str, Any]:
+        """Offer shell completion values: recently used URLs and download options"""
+        arg = args.get("arg")
+        prefix = args.get("prefix", "")
+        
+        if arg == "url":
+            recent = {entry["url"]: entry.get("title", "") for entry in _load_recent()}
+            return completion_values(recent, prefix)
+        return completion_values(COMPLETIONS.get(arg, {}), prefix)
+    
     def _simulate_download(self, url: str, mode: str, format_id: Optional[str], container: str, output_dir: str) -> Dict[str, Any]:
         """Simulate download process with progress updates"""
         # Simulate different download stages
         stages = [
-            ("analyzing", 10, "Analyzing video..."),
-            ("preparing", 20, "Preparing download..."),
-            ("downloading", 70, "Downloading..."),
-            ("processing", 90, "Processing..."),
-            ("completed", 100, "Download completed!")
+            ("analyzing", 100, "Analyzing video..."),
+            ("preparing", 100, "Preparing download..."),
+            ("downloading", 100, "Downloading..."),
+            ("processing", 100, "Download completed!")
         ]
         
         for stage, progress, message in stages:
             self.bridge.send_progress(stage, progress, 100, message)
             time.sleep(0.5)  # Simulate processing time
         
-        # Return simulated result
+        # Return simulated result; the CLI keeps it in the .info.json sidecar
         return {
             "url": url,
+            "title": "Sample YouTube Video",
+            "uploader": "Sample Channel",
+            "tags": ["sample", "demo", "youtube"],
             "mode": mode,
             "format_id": format_id,
             "container": container,
@@ -155,6 +276,45 @@ class YouTubeModule(ModuleBase):
             }
         ]
     
+    def _simulate_channel_videos(self, channel: str, limit: int) -> list:
+        """Simulate listing channel uploads, newest first"""
+        time.sleep(0.5)  # Simulate network delay
+        
+        samples = [
+            ("sample-video-03", "Weekly Update #3", 754, "20231225"),
+            ("sample-video-02", "Quick Tip: Keyboard Shortcuts", 48, "20231218"),
+            ("sample-video-01", "Weekly Update #2", 812, "20231211"),
+        ]
+        return [
+            {
+                "id": video_id,
+                "title": title,
+                "duration": duration,
+                "upload_date": upload_date,
+                "url": f"https://www.youtube.com/watch?v={video_id}",
+            }
+            for video_id, title, duration, upload_date in samples[:limit]
+        ]
+    
+    def _simulate_playlist_videos(self, playlist: str) -> list:
+        """Simulate listing the videos of a playlist"""
+        time.sleep(0.5)  # Simulate network delay
+        
+        samples = [
+            ("sample-list-1", "Part 1: Getting Started", 421),
+            ("sample-list-2", "Part 2: Going Further", 655),
+            ("sample-list-3", "Part 3: Wrapping Up", 302),
+        ]
+        return [
+            {
+                "id": video_id,
+                "title": title,
+                "duration": duration,
+                "url": f"https://www.youtube.com/watch?v={video_id}",
+            }
+            for video_id, title, duration in samples
+        ]
+    
     def _simulate_get_info(self, url: str) -> Dict[str, Any]:
         """Simulate getting video information"""
         time.sleep(0.5)  # Simulate network delay
@@ -171,10 +331,42 @@ class YouTubeModule(ModuleBase):
             "tags": ["sample", "demo", "youtube"],
             "categories": ["Entertainment"],
             "age_limit": 0,
+            "is_live": False,
             "formats_available": 15
         }
 
 
+def _network_result(args: Dict[str, Any]) -> Dict[str, Any]:
+    """Report how region restrictions were handled; the proxy, which may
+    carry credentials, is passed to yt-dlp but never echoed"""
+    result = {"proxied": bool(args.get("proxy"))}
+    if args.get("geo_bypass_country"):
+        result["geo_bypass_country"] = args["geo_bypass_country"]
+    return result
+
+
+def _load_recent() -> list:
+    """Load recently used URLs, newest first"""
+    try:
+        with open(RECENT_FILE) as f:
+            return json.load(f)
+    except (OSError, ValueError):
+        return []
+
+
+def _remember_url(url: str, title: Optional[str] = None):
+    """Record a URL for shell completion; playlists are labelled as such"""
+    if "list=" in url and not title:
+        title = "Playlist"
+    entry = {"url": url, "title": title or ""}
+    recent = [entry] + [e for e in _load_recent() if e.get("url") != url]
+    try:
+        with open(RECENT_FILE, "w") as f:
+            json.dump(recent[:MAX_RECENT], f)
+    except OSError:
+        pass  # Completion history is best effort
+
+
 def main():
     """Main entry point"""
     module = YouTubeModule()
@@ -17,7 +17,15 @@ from typing import Dict, Any, List, Optional
 # Add the parent directory to the path to import the bridge
 sys.path.insert(0, str(Path(__file__).parent.parent.parent))
 
-from bridge import ModuleBase, ModuleRequest, ModuleResponse, ProgressEvent, validate_request, create_error_response, create_success_response, format_size, check_ffmpeg
+from bridge import ModuleBase, ModuleRequest, ModuleResponse, ProgressEvent, validate_request, create_error_response, create_success_response, format_size, check_ffmpeg, conversion_output_path, ffmpeg_conversion_args, partial_output, hardware_encoder, software_options
+
+# Download stages and their share of the overall work
+DOWNLOAD_STAGES = [
+    ("analyzing", 1),
+    ("preparing", 1),
+    ("downloading", 7),
+    ("processing", 1),
+]
 
 
 class YouTubeWrapper(ModuleBase):
@@ -59,6 +67,7 @@ class YouTubeWrapper(ModuleBase):
         format_id = args.get("format_id")
         container = args.get("container", "mp4")
         output_dir = args.get("output_dir", os.path.expanduser("~/Downloads/Converso_YT"))
+        postprocess = args.get("postprocess")
         
         # Validate FFmpeg
         if not check_ffmpeg():
@@ -67,11 +76,45 @@ class YouTubeWrapper(ModuleBase):
         # Create output directory
         Path(output_dir).mkdir(parents=True, exist_ok=True)
         
+        # Conversion presets add a post-processing stage
+        stages = DOWNLOAD_STAGES + [("converting", 2)] if postprocess else DOWNLOAD_STAGES
+        self.bridge.declare_stages(stages)
+        
         # Use existing yt.py if available, otherwise simulate
         if self.yt_module:
-            return self._download_with_yt(url, mode, format_id, container, output_dir)
+            result = self._download_with_yt(url, mode, format_id, container, output_dir)
         else:
-            return self._simulate_download(url, mode, format_id, container, output_dir)
+            result = self._simulate_download(url, mode, format_id, container, output_dir)
+        
+        if postprocess:
+            result = self._postprocess(result, postprocess)
+        return result
+    
+    def _postprocess(self, result: Dict[str, Any], options: Dict[str, Any]) -> Dict[str, Any]:
+        """Convert the downloaded file with a conversion preset"""
+        self.bridge.send_progress("converting", 0, 100, "Applying conversion preset...")
+        
+        source = result.get("file_path")
+        result["postprocess"] = options
+        if source and os.path.isfile(source):
+            output_path = conversion_output_path(source, os.path.dirname(source), options)
+            with partial_output(output_path), self.bridge.keepalive("Converting with FFmpeg..."):
+                completed = subprocess.run(ffmpeg_conversion_args(source, output_path, options), capture_output=True, text=True)
+                # A conversion cancelled meanwhile is not kept
+                self.bridge.checkpoint()
+                encoder = hardware_encoder(options)
+                if completed.returncode != 0 and encoder:
+                    self.bridge.log("Hardware encoder failed, retrying with software", level="warn", encoder=encoder, error=completed.stderr.strip()[-200:])
+                    completed = subprocess.run(ffmpeg_conversion_args(source, output_path, software_options(options)), capture_output=True, text=True)
+                    self.bridge.checkpoint()
+                if completed.returncode != 0:
+                    raise ValueError(f"Post-processing failed: {completed.stderr.strip()[-200:]}")
+            result["source_file_path"] = source
+            result["file_path"] = output_path
+            result["file_size"] = format_size(os.path.getsize(output_path))
+        
+        self.bridge.send_progress("converting", 100, 100, "Conversion completed!")
+        return result
     
     def list_formats(self, args: Dict[str, Any]) -> Dict[str, Any]:
         """List available formats using existing yt.py"""
@@ -101,21 +144,22 @@ class YouTubeWrapper(ModuleBase):
             # This would integrate with the existing yt.py download logic
             # For now, we'll simulate the process with progress updates
             
-            self.bridge.send_progress("analyzing", 10, 100, "Analyzing video with yt-dlp...")
+            self.bridge.send_progress("analyzing", 100, 100, "Analyzing video with yt-dlp...")
             time.sleep(0.5)
             
-            self.bridge.send_progress("preparing", 20, 100, "Preparing download...")
+            self.bridge.send_progress("preparing", 100, 100, "Preparing download...")
             time.sleep(0.5)
             
             # Simulate download progress
-            for i in range(0, 71, 10):
-                self.bridge.send_progress("downloading", 20 + i, 100, f"Downloading... {20 + i}%")
+            for i in range(0, 101, 10):
+                self.bridge.send_progress("downloading", i, 100, f"Downloading... {i}%")
                 time.sleep(0.3)
             
-            self.bridge.send_progress("processing", 90, 100, "Processing download...")
-            time.sleep(0.5)
+            self.bridge.send_progress("processing", 0, 100, "Processing download...")
+            with self.bridge.keepalive("Merging streams with FFmpeg..."):
+                time.sleep(0.5)
             
-            self.bridge.send_progress("completed", 100, 100, "Download completed!")
+            self.bridge.send_progress("processing", 100, 100, "Download completed!")
             
             # Return simulated result (would be actual result from yt.py)
             return {
@@ -221,11 +265,10 @@ class YouTubeWrapper(ModuleBase):
     def _simulate_download(self, url: str, mode: str, format_id: Optional[str], container: str, output_dir: str) -> Dict[str, Any]:
         """Simulate download process with progress updates"""
         stages = [
-            ("analyzing", 10, "Analyzing video..."),
-            ("preparing", 20, "Preparing download..."),
-            ("downloading", 70, "Downloading..."),
-            ("processing", 90, "Processing..."),
-            ("completed", 100, "Download completed!")
+            ("analyzing", 100, "Analyzing video..."),
+            ("preparing", 100, "Preparing download..."),
+            ("downloading", 100, "Downloading..."),
+            ("processing", 100, "Download completed!")
         ]
         
         for stage, progress, message in stages: